@@ -0,0 +1,121 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AdaptiveTimeoutOptions configures an AdaptiveTimeout.
+type AdaptiveTimeoutOptions struct {
+	// Multiplier is the factor k applied to the smoothed latency. Defaults to 3.
+	Multiplier float64
+	// Alpha is the weight of a new sample in the moving average. Defaults to 0.2.
+	Alpha float64
+	// Min and Max bound the computed timeout. Default to 100ms and 30s.
+	Min time.Duration
+	Max time.Duration
+	// Initial is the timeout for hosts without samples. Defaults to Max.
+	Initial time.Duration
+	// OnTimeout observes the smoothed latency and the timeout computed for a
+	// request; latency is 0 for hosts without samples.
+	OnTimeout func(host string, latency, timeout time.Duration)
+}
+
+// AdaptiveTimeout tracks an exponentially weighted moving average (EWMA) of
+// the latency of each host and derives request deadlines from it, so slow
+// hosts get realistic deadlines while fast hosts fail quickly.
+// It is safe for concurrent use.
+type AdaptiveTimeout struct {
+	options *AdaptiveTimeoutOptions
+	mu      sync.Mutex
+	latency map[string]time.Duration
+}
+
+// NewAdaptiveTimeout creates an AdaptiveTimeout without samples.
+func NewAdaptiveTimeout(opts ...func(*AdaptiveTimeoutOptions)) *AdaptiveTimeout {
+	options := applyOptions(&AdaptiveTimeoutOptions{
+		Multiplier: 3,
+		Alpha:      0.2,
+		Min:        100 * time.Millisecond,
+		Max:        30 * time.Second,
+	}, opts...)
+	if options.Initial <= 0 {
+		options.Initial = options.Max
+	}
+
+	return &AdaptiveTimeout{options: options, latency: map[string]time.Duration{}}
+}
+
+// Latency returns the smoothed latency of host and whether it has samples.
+func (a *AdaptiveTimeout) Latency(host string) (time.Duration, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	latency, ok := a.latency[host]
+	return latency, ok
+}
+
+// Timeout returns the timeout for the next request to host:
+// Multiplier times the smoothed latency, bounded by Min and Max.
+func (a *AdaptiveTimeout) Timeout(host string) time.Duration {
+	latency, ok := a.Latency(host)
+	if !ok {
+		return a.options.Initial
+	}
+	timeout := time.Duration(float64(latency) * a.options.Multiplier)
+	return min(max(timeout, a.options.Min), a.options.Max)
+}
+
+func (a *AdaptiveTimeout) observe(host string, sample time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if latency, ok := a.latency[host]; ok {
+		sample = time.Duration(a.options.Alpha*float64(sample) + (1-a.options.Alpha)*float64(latency))
+	}
+	a.latency[host] = sample
+}
+
+// Middleware returns middleware that applies the adaptive timeout to every
+// request and records the time until the response headers arrived. A request
+// that hits the adaptive deadline is recorded with the timeout as its
+// latency, so the deadline grows for a host that became slower. The deadline
+// also covers reading the body and is released when the body is closed.
+//
+// Example:
+//
+//	timeouts := fetch.NewAdaptiveTimeout(func(o *fetch.AdaptiveTimeoutOptions) {
+//	    o.Min = 200 * time.Millisecond
+//	    o.Max = 10 * time.Second
+//	})
+//	dispatcher.Use(timeouts.Middleware())
+func (a *AdaptiveTimeout) Middleware() Middleware {
+	return bypassDryRun(func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			host := req.URL.Host
+			timeout := a.Timeout(host)
+			if a.options.OnTimeout != nil {
+				latency, _ := a.Latency(host)
+				a.options.OnTimeout(host, latency, timeout)
+			}
+
+			ctx, cancel := context.WithTimeout(req.Context(), timeout)
+			start := time.Now()
+			resp, err := next.Handle(client, req.WithContext(ctx))
+
+			switch {
+			case err == nil:
+				a.observe(host, time.Since(start))
+				resp.Body = &cleanupReadCloser{ReadCloser: resp.Body, cleanup: cancel}
+				return resp, nil
+			case errors.Is(err, context.DeadlineExceeded) && req.Context().Err() == nil:
+				a.observe(host, timeout)
+			}
+			cancel()
+			return resp, err
+		})
+	})
+}
@@ -0,0 +1,84 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptiveTimeout_Timeout(t *testing.T) {
+	timeouts := NewAdaptiveTimeout(func(o *AdaptiveTimeoutOptions) {
+		o.Alpha = 0.5
+		o.Min = 10 * time.Millisecond
+		o.Max = time.Second
+	})
+
+	tests := []struct {
+		name        string
+		sample      time.Duration
+		wantLatency time.Duration
+		wantTimeout time.Duration
+	}{
+		{name: "first sample", sample: 100 * time.Millisecond, wantLatency: 100 * time.Millisecond, wantTimeout: 300 * time.Millisecond},
+		{name: "smoothed", sample: 300 * time.Millisecond, wantLatency: 200 * time.Millisecond, wantTimeout: 600 * time.Millisecond},
+		{name: "capped at max", sample: 2 * time.Second, wantLatency: 1100 * time.Millisecond, wantTimeout: time.Second},
+		{name: "decays toward new samples", sample: 0, wantLatency: 550 * time.Millisecond, wantTimeout: time.Second},
+	}
+
+	assert.Equal(t, time.Second, timeouts.Timeout("api"))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeouts.observe("api", tt.sample)
+			latency, ok := timeouts.Latency("api")
+			require.True(t, ok)
+			assert.Equal(t, tt.wantLatency, latency)
+			assert.Equal(t, tt.wantTimeout, timeouts.Timeout("api"))
+		})
+	}
+
+	timeouts.observe("fast", time.Millisecond)
+	assert.Equal(t, 10*time.Millisecond, timeouts.Timeout("fast"))
+}
+
+func TestAdaptiveTimeout_Middleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delay, _ := strconv.Atoi(r.URL.Query().Get("delay"))
+		select {
+		case <-time.After(time.Duration(delay) * time.Millisecond):
+		case <-r.Context().Done():
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var observed []time.Duration
+	timeouts := NewAdaptiveTimeout(func(o *AdaptiveTimeoutOptions) {
+		o.Min = 100 * time.Millisecond
+		o.Max = 5 * time.Second
+		o.Initial = 200 * time.Millisecond
+		o.OnTimeout = func(host string, latency, timeout time.Duration) { observed = append(observed, timeout) }
+	})
+	dispatcher := NewDispatcher(nil, timeouts.Middleware())
+
+	resp := dispatcher.NewRequest().Get(server.URL + "?delay=0")
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "ok", resp.String())
+
+	resp = dispatcher.NewRequest().Get(server.URL + "?delay=500")
+	require.ErrorIs(t, resp.Error, context.DeadlineExceeded)
+	resp.Close()
+
+	require.Len(t, observed, 2)
+	assert.Equal(t, 200*time.Millisecond, observed[0])
+	assert.Equal(t, 100*time.Millisecond, observed[1])
+
+	latency, _ := timeouts.Latency(server.Listener.Addr().String())
+	assert.Greater(t, latency, 9*time.Millisecond, "timeout recorded as a sample")
+}
@@ -0,0 +1,107 @@
+package fetch
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ArchiveOptions configures how a streamed archive body is sent.
+type ArchiveOptions struct {
+	ContentType      string
+	ProgressInterval time.Duration
+	ProgressCallback func(written int64)
+}
+
+// BodyTar creates middleware that streams a tar archive built by the caller as
+// the request body. The build function writes entries to the tar.Writer, which
+// is flushed and closed by the middleware once build returns.
+// The archive is never held in memory: it is produced through an io.Pipe and
+// sent with chunked transfer encoding, so the body cannot be replayed.
+// Automatically sets Content-Type to application/x-tar.
+func BodyTar(build func(*tar.Writer) error, opts ...func(*ArchiveOptions)) Middleware {
+	return archiveBody(func(w io.Writer) error {
+		tw := tar.NewWriter(w)
+		if err := build(tw); err != nil {
+			return err
+		}
+		return tw.Close()
+	}, append([]func(*ArchiveOptions){
+		func(o *ArchiveOptions) {
+			o.ContentType = "application/x-tar"
+		},
+	}, opts...)...)
+}
+
+// BodyZip creates middleware that streams a zip archive built by the caller as
+// the request body. See BodyTar for the streaming semantics.
+// Automatically sets Content-Type to application/zip.
+func BodyZip(build func(*zip.Writer) error, opts ...func(*ArchiveOptions)) Middleware {
+	return archiveBody(func(w io.Writer) error {
+		zw := zip.NewWriter(w)
+		if err := build(zw); err != nil {
+			return err
+		}
+		return zw.Close()
+	}, append([]func(*ArchiveOptions){
+		func(o *ArchiveOptions) {
+			o.ContentType = "application/zip"
+		},
+	}, opts...)...)
+}
+
+func archiveBody(write func(io.Writer) error, opts ...func(*ArchiveOptions)) Middleware {
+	options := applyOptions(&ArchiveOptions{}, opts...)
+
+	return func(handler Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			if write == nil {
+				return handler.Handle(client, req)
+			}
+
+			pr, pw := io.Pipe()
+			req.Body = pr
+			req.GetBody = nil
+			req.ContentLength = -1
+			req.TransferEncoding = []string{"chunked"}
+
+			if options.ContentType != "" {
+				req.Header.Set("Content-Type", options.ContentType)
+			}
+
+			var w io.Writer = pw
+			var progress *callbackWriter
+			if options.ProgressCallback != nil {
+				interval := options.ProgressInterval
+
+				if interval <= 0 {
+					interval = 1 * time.Second
+				}
+
+				progress = &callbackWriter{
+					Writer:   pw,
+					lastTime: time.Now(),
+					interval: interval,
+					callback: options.ProgressCallback,
+				}
+				w = progress
+			}
+
+			go func() {
+				err := write(w)
+				if err == nil && progress != nil {
+					progress.callback(progress.written)
+				}
+				pw.CloseWithError(err)
+			}()
+
+			resp, err := handler.Handle(client, req)
+			// Unblock the writer if the body was not fully consumed.
+			pr.Close()
+
+			return resp, err
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package fetch
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyTar(t *testing.T) {
+	var capturedContentType string
+	var capturedTransferEncoding []string
+	files := map[string]string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedContentType = r.Header.Get("Content-Type")
+		capturedTransferEncoding = r.TransferEncoding
+
+		tr := tar.NewReader(r.Body)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			content, err := io.ReadAll(tr)
+			require.NoError(t, err)
+			files[hdr.Name] = string(content)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var lastWritten int64
+	resp := NewDispatcher(nil).NewRequest().Tar(func(tw *tar.Writer) error {
+		for name, content := range map[string]string{"a.txt": "alpha", "b.txt": "bravo"} {
+			if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(content))}); err != nil {
+				return err
+			}
+			if _, err := tw.Write([]byte(content)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(o *ArchiveOptions) {
+		o.ProgressCallback = func(written int64) { lastWritten = written }
+	}).Post(server.URL)
+	defer resp.Close()
+
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "application/x-tar", capturedContentType)
+	assert.Equal(t, []string{"chunked"}, capturedTransferEncoding)
+	assert.Equal(t, map[string]string{"a.txt": "alpha", "b.txt": "bravo"}, files)
+	assert.Positive(t, lastWritten)
+}
+
+func TestBodyZip(t *testing.T) {
+	var capturedContentType string
+	var capturedBody []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedContentType = r.Header.Get("Content-Type")
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		capturedBody = body
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp := NewDispatcher(nil).NewRequest().Zip(func(zw *zip.Writer) error {
+		f, err := zw.Create("hello.txt")
+		if err != nil {
+			return err
+		}
+		_, err = f.Write([]byte("hello zip"))
+		return err
+	}).Post(server.URL)
+	defer resp.Close()
+
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "application/zip", capturedContentType)
+
+	zr, err := zip.NewReader(bytes.NewReader(capturedBody), int64(len(capturedBody)))
+	require.NoError(t, err)
+	require.Len(t, zr.File, 1)
+	assert.Equal(t, "hello.txt", zr.File[0].Name)
+}
+
+func TestBodyTar_BuildError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	buildErr := errors.New("build failed")
+	resp := NewDispatcher(nil).NewRequest().Tar(func(tw *tar.Writer) error {
+		return buildErr
+	}).Post(server.URL)
+	defer resp.Close()
+
+	assert.ErrorIs(t, resp.Error, buildErr)
+}
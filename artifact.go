@@ -0,0 +1,184 @@
+package fetch
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrArtifactVerification is matched by errors.Is for every ArtifactError.
+var ErrArtifactVerification = errors.New("artifact verification failed")
+
+// ArtifactError reports a downloaded artifact that failed verification.
+// Nothing is written to the destination path in that case.
+type ArtifactError struct {
+	Path     string
+	Reason   string
+	Expected string
+	Actual   string
+}
+
+// Error returns the error message.
+func (e *ArtifactError) Error() string {
+	if e.Expected != "" {
+		return fmt.Sprintf("artifact %s: %s: expected %s, got %s", e.Path, e.Reason, e.Expected, e.Actual)
+	}
+	return fmt.Sprintf("artifact %s: %s", e.Path, e.Reason)
+}
+
+// Is reports whether target is ErrArtifactVerification.
+func (e *ArtifactError) Is(target error) bool {
+	return target == ErrArtifactVerification
+}
+
+// SignatureVerifier checks a detached signature against the digest of the
+// artifact computed with Hash while it is downloaded.
+type SignatureVerifier interface {
+	Hash() crypto.Hash
+	VerifyDigest(digest []byte) error
+}
+
+type ed25519phVerifier struct {
+	key       ed25519.PublicKey
+	signature []byte
+}
+
+// Ed25519phVerifier verifies an Ed25519ph signature (RFC 8032), i.e. an
+// Ed25519 signature over the SHA-512 digest of the artifact, which lets the
+// artifact be verified without holding it in memory.
+func Ed25519phVerifier(key ed25519.PublicKey, signature []byte) SignatureVerifier {
+	return ed25519phVerifier{key: key, signature: signature}
+}
+
+func (v ed25519phVerifier) Hash() crypto.Hash { return crypto.SHA512 }
+
+func (v ed25519phVerifier) VerifyDigest(digest []byte) error {
+	return ed25519.VerifyWithOptions(v.key, digest, v.signature, &ed25519.Options{Hash: crypto.SHA512})
+}
+
+type ecdsaVerifier struct {
+	key       *ecdsa.PublicKey
+	signature []byte
+}
+
+// ECDSAVerifier verifies an ASN.1 encoded ECDSA signature over the SHA-256
+// digest of the artifact, as produced by cosign sign-blob.
+func ECDSAVerifier(key *ecdsa.PublicKey, signature []byte) SignatureVerifier {
+	return ecdsaVerifier{key: key, signature: signature}
+}
+
+func (v ecdsaVerifier) Hash() crypto.Hash { return crypto.SHA256 }
+
+func (v ecdsaVerifier) VerifyDigest(digest []byte) error {
+	if !ecdsa.VerifyASN1(v.key, digest, v.signature) {
+		return errors.New("invalid ECDSA signature")
+	}
+	return nil
+}
+
+// ArtifactOptions configures SaveVerified. At least one of Digest and
+// Verifier must be set.
+type ArtifactOptions struct {
+	// Algorithm of Digest. Defaults to SHA256.
+	Algorithm HashAlgorithm
+	// Digest is the expected hex encoded digest of the artifact.
+	Digest string
+	// Verifier checks a detached signature of the artifact.
+	Verifier SignatureVerifier
+	// Mode is the permission of the written file. Defaults to 0o644.
+	Mode os.FileMode
+}
+
+// SaveVerified streams the response body into a temp file next to path while
+// hashing it, verifies the expected digest and signature, and only then
+// renames the file to path, so path never holds an unverified or partial
+// artifact. A mismatch fails with an ArtifactError.
+//
+// Example:
+//
+//	err := resp.SaveVerified("/usr/local/bin/tool", func(o *fetch.ArtifactOptions) {
+//	    o.Digest = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+//	    o.Verifier = fetch.Ed25519phVerifier(releaseKey, signature)
+//	    o.Mode = 0o755
+//	})
+func (r *Response) SaveVerified(path string, opts ...func(*ArtifactOptions)) (err error) {
+	if r.Error != nil {
+		return r.Error
+	}
+	defer r.Close()
+
+	options := applyOptions(&ArtifactOptions{Algorithm: SHA256, Mode: 0o644}, opts...)
+	if options.Digest == "" && options.Verifier == nil {
+		return errors.New("save verified: no digest or verifier")
+	}
+
+	var digestHash, signatureHash hash.Hash
+	writers := []io.Writer{}
+	if options.Digest != "" {
+		if digestHash = newHash(options.Algorithm); digestHash == nil {
+			return fmt.Errorf("save verified: unsupported algorithm %q", options.Algorithm)
+		}
+		writers = append(writers, digestHash)
+	}
+	if options.Verifier != nil {
+		switch options.Verifier.Hash() {
+		case crypto.SHA256:
+			signatureHash = sha256.New()
+		case crypto.SHA512:
+			signatureHash = sha512.New()
+		default:
+			return fmt.Errorf("save verified: unsupported signature hash %v", options.Verifier.Hash())
+		}
+		writers = append(writers, signatureHash)
+	}
+
+	fd, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			fd.Close()
+			os.Remove(fd.Name())
+		}
+	}()
+
+	if _, err = io.Copy(io.MultiWriter(append(writers, fd)...), r.getInternalReader()); err != nil {
+		return err
+	}
+
+	if digestHash != nil {
+		actual := hex.EncodeToString(digestHash.Sum(nil))
+		expected := strings.ToLower(options.Digest)
+		if subtle.ConstantTimeCompare([]byte(actual), []byte(expected)) != 1 {
+			return &ArtifactError{Path: path, Reason: string(options.Algorithm) + " digest mismatch", Expected: expected, Actual: actual}
+		}
+	}
+	if signatureHash != nil {
+		if verifyErr := options.Verifier.VerifyDigest(signatureHash.Sum(nil)); verifyErr != nil {
+			return &ArtifactError{Path: path, Reason: "signature verification failed: " + verifyErr.Error()}
+		}
+	}
+
+	if err = fd.Chmod(options.Mode); err != nil {
+		return err
+	}
+	if err = fd.Sync(); err != nil {
+		return err
+	}
+	if err = fd.Close(); err != nil {
+		return err
+	}
+	return os.Rename(fd.Name(), path)
+}
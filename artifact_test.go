@@ -0,0 +1,84 @@
+package fetch
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponse_SaveVerified(t *testing.T) {
+	content := []byte("release binary")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	sha256Sum := sha256.Sum256(content)
+	sha512Sum := sha512.Sum512(content)
+
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edSignature, err := edPrivate.Sign(nil, sha512Sum[:], &ed25519.Options{Hash: crypto.SHA512})
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecSignature, err := ecdsa.SignASN1(rand.Reader, ecKey, sha256Sum[:])
+	require.NoError(t, err)
+
+	otherPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		options func(*ArtifactOptions)
+		wantErr error
+	}{
+		{name: "digest", options: func(o *ArtifactOptions) { o.Digest = hex.EncodeToString(sha256Sum[:]) }},
+		{name: "digest mismatch", options: func(o *ArtifactOptions) { o.Digest = hex.EncodeToString(make([]byte, 32)) }, wantErr: ErrArtifactVerification},
+		{name: "ed25519ph signature", options: func(o *ArtifactOptions) { o.Verifier = Ed25519phVerifier(edPublic, edSignature) }},
+		{name: "wrong ed25519 key", options: func(o *ArtifactOptions) { o.Verifier = Ed25519phVerifier(otherPublic, edSignature) }, wantErr: ErrArtifactVerification},
+		{name: "ecdsa signature and digest", options: func(o *ArtifactOptions) {
+			o.Digest = hex.EncodeToString(sha256Sum[:])
+			o.Verifier = ECDSAVerifier(&ecKey.PublicKey, ecSignature)
+		}},
+		{name: "bad ecdsa signature", options: func(o *ArtifactOptions) { o.Verifier = ECDSAVerifier(&ecKey.PublicKey, edSignature) }, wantErr: ErrArtifactVerification},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			target := filepath.Join(dir, "tool")
+			require.NoError(t, os.WriteFile(target, []byte("previous"), 0o600))
+
+			resp := NewDispatcher(nil).NewRequest().Get(server.URL)
+			err := resp.SaveVerified(target, tt.options)
+
+			data, readErr := os.ReadFile(target)
+			require.NoError(t, readErr)
+			entries, _ := os.ReadDir(dir)
+			assert.Len(t, entries, 1, "temp file left behind")
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Equal(t, "previous", string(data))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, content, data)
+		})
+	}
+}
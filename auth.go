@@ -0,0 +1,155 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+
+	"github.com/rockcookies/go-fetch/internal/utils"
+)
+
+var reauthKey = utils.NewContextKey[bool]("reauth")
+
+var errReauthPanicked = errors.New("reauthenticate panicked")
+
+// reauthCall is a Reauthenticate call shared by the requests it renews
+// credentials for.
+type reauthCall struct {
+	done chan struct{}
+	err  error
+}
+
+// AuthOrchestrator supplies credentials for requests and renews them when
+// the server rejects a request. Implementations may refresh an OAuth token,
+// prompt for credentials on a terminal or run a device-code flow.
+type AuthOrchestrator interface {
+	// Authorize applies the current credentials to req, e.g. by setting the
+	// Authorization header. It is called before every attempt.
+	Authorize(req *http.Request) error
+	// Reauthenticate obtains new credentials after resp rejected a request.
+	// Requests sent from within Reauthenticate with ctx bypass the
+	// orchestrator, so it can use the same client without recursing.
+	Reauthenticate(ctx context.Context, resp *http.Response) error
+}
+
+// AuthOptions configures the Reauth middleware.
+type AuthOptions struct {
+	// StatusCodes that trigger re-authentication. Defaults to 401 and 403.
+	StatusCodes []int
+}
+
+// Reauth creates middleware that authorizes requests with the orchestrator and,
+// when a response has one of AuthOptions.StatusCodes, re-authenticates and
+// replays the original request once. A second rejection is returned as is.
+//
+// Concurrent requests rejected with the same credentials share a single
+// Reauthenticate call: only the first one runs it, the others wait and then
+// replay with the renewed credentials.
+//
+// The request body is buffered in memory so it can be replayed.
+//
+// Example:
+//
+//	dispatcher.Use(fetch.Reauth(&tokenRefresher{source: oauthConfig.TokenSource(ctx, token)}))
+func Reauth(orchestrator AuthOrchestrator, opts ...func(*AuthOptions)) Middleware {
+	options := applyOptions(&AuthOptions{
+		StatusCodes: []int{http.StatusUnauthorized, http.StatusForbidden},
+	}, opts...)
+
+	var (
+		mu         sync.Mutex
+		generation uint64
+		inflight   *reauthCall
+	)
+
+	authorize := func(req *http.Request) (uint64, error) {
+		mu.Lock()
+		gen := generation
+		mu.Unlock()
+		return gen, orchestrator.Authorize(req)
+	}
+
+	// reauthenticate renews the credentials seen by a rejected request. The
+	// lock is not held while the orchestrator runs, so other requests are
+	// authorized meanwhile and the orchestrator may send requests itself.
+	reauthenticate := func(ctx context.Context, resp *http.Response, seen uint64) error {
+		mu.Lock()
+		if generation != seen {
+			// Another request already renewed the credentials.
+			mu.Unlock()
+			return nil
+		}
+		call := inflight
+		if call != nil {
+			mu.Unlock()
+			select {
+			case <-call.done:
+				return call.err
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		call = &reauthCall{done: make(chan struct{})}
+		inflight = call
+		mu.Unlock()
+
+		defer func() {
+			mu.Lock()
+			if call.err == nil {
+				generation++
+			}
+			inflight = nil
+			mu.Unlock()
+			close(call.done)
+		}()
+		// A panicking orchestrator fails the waiting requests too.
+		call.err = errReauthPanicked
+		call.err = orchestrator.Reauthenticate(reauthKey.WithValue(ctx, true), resp)
+		return call.err
+	}
+
+	return bypassDryRun(func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			if skip, _ := reauthKey.GetValue(req.Context()); skip {
+				return next.Handle(client, req)
+			}
+
+			body, err := readRequestBody(req)
+			if err != nil {
+				return nil, err
+			}
+			if body != nil {
+				closeReplacedBody(req)
+				setBufferedBody(req, body)
+			}
+
+			gen, err := authorize(req)
+			if err != nil {
+				return nil, err
+			}
+
+			resp, err := next.Handle(client, req)
+			if err != nil || !slices.Contains(options.StatusCodes, resp.StatusCode) {
+				return resp, err
+			}
+
+			err = reauthenticate(req.Context(), resp, gen)
+			drainAndClose(resp)
+			if err != nil {
+				return nil, err
+			}
+
+			retry := req.Clone(req.Context())
+			if body != nil {
+				setBufferedBody(retry, body)
+			}
+			if _, err := authorize(retry); err != nil {
+				return nil, err
+			}
+
+			return next.Handle(client, retry)
+		})
+	})
+}
@@ -0,0 +1,223 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testOrchestrator struct {
+	mu      sync.Mutex
+	token   string
+	next    string
+	err     error
+	calls   atomic.Int32
+	refresh func(ctx context.Context)
+}
+
+func (o *testOrchestrator) Authorize(req *http.Request) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	req.Header.Set("Authorization", "Bearer "+o.token)
+	return nil
+}
+
+func (o *testOrchestrator) Reauthenticate(ctx context.Context, resp *http.Response) error {
+	o.calls.Add(1)
+	if o.refresh != nil {
+		o.refresh(ctx)
+	}
+	if o.err != nil {
+		return o.err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.token = o.next
+	return nil
+}
+
+func newAuthServer(valid string, bodies *[]string) *httptest.Server {
+	var mu sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if bodies != nil {
+			data, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			*bodies = append(*bodies, string(data))
+			mu.Unlock()
+		}
+		if r.Header.Get("Authorization") != "Bearer "+valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+}
+
+func TestReauth(t *testing.T) {
+	errDenied := errors.New("denied")
+
+	tests := []struct {
+		name       string
+		token      string
+		next       string
+		err        error
+		wantStatus int
+		wantCalls  int32
+		wantErr    error
+	}{
+		{name: "valid token", token: "good", next: "good", wantStatus: 200, wantCalls: 0},
+		{name: "refreshed token", token: "stale", next: "good", wantStatus: 200, wantCalls: 1},
+		{name: "replayed only once", token: "stale", next: "still-bad", wantStatus: 401, wantCalls: 1},
+		{name: "reauth fails", token: "stale", err: errDenied, wantCalls: 1, wantErr: errDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bodies []string
+			server := newAuthServer("good", &bodies)
+			defer server.Close()
+
+			orchestrator := &testOrchestrator{token: tt.token, next: tt.next, err: tt.err}
+			resp := NewDispatcher(nil).NewRequest().
+				Body(strings.NewReader("payload")).
+				Use(Reauth(orchestrator)).
+				Post(server.URL)
+			defer resp.Close()
+
+			assert.Equal(t, tt.wantCalls, orchestrator.calls.Load())
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, resp.Error, tt.wantErr)
+				return
+			}
+			require.NoError(t, resp.Error)
+			assert.Equal(t, tt.wantStatus, resp.RawResponse.StatusCode)
+			for _, body := range bodies {
+				assert.Equal(t, "payload", body)
+			}
+		})
+	}
+}
+
+func TestReauth_ConcurrentDedup(t *testing.T) {
+	server := newAuthServer("good", nil)
+	defer server.Close()
+
+	orchestrator := &testOrchestrator{
+		token:   "stale",
+		next:    "good",
+		refresh: func(context.Context) { time.Sleep(20 * time.Millisecond) },
+	}
+	dispatcher := NewDispatcher(nil, Reauth(orchestrator))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := dispatcher.NewRequest().Get(server.URL)
+			defer resp.Close()
+			assert.Equal(t, "ok", resp.String())
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), orchestrator.calls.Load())
+}
+
+func TestReauth_NoRecursion(t *testing.T) {
+	server := newAuthServer("good", nil)
+	defer server.Close()
+
+	orchestrator := &testOrchestrator{token: "stale", next: "good"}
+	dispatcher := NewDispatcher(nil, Reauth(orchestrator))
+
+	var innerStatus int
+	orchestrator.refresh = func(ctx context.Context) {
+		// A request made while re-authenticating must not trigger another round.
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		resp, err := dispatcher.Do(req)
+		if assert.NoError(t, err) {
+			innerStatus = resp.StatusCode
+			resp.Body.Close()
+		}
+	}
+
+	resp := dispatcher.NewRequest().Get(server.URL)
+	defer resp.Close()
+
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "ok", resp.String())
+	assert.Equal(t, http.StatusUnauthorized, innerStatus)
+	assert.Equal(t, int32(1), orchestrator.calls.Load())
+}
+
+func TestReauth_RequestsDuringReauthenticate(t *testing.T) {
+	server := newAuthServer("good", nil)
+	defer server.Close()
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer public.Close()
+
+	orchestrator := &testOrchestrator{token: "stale", next: "good"}
+	dispatcher := NewDispatcher(nil, Reauth(orchestrator))
+
+	var inner string
+	orchestrator.refresh = func(context.Context) {
+		// Requests outside the bypass context are still authorized.
+		resp := dispatcher.NewRequest().Get(public.URL)
+		defer resp.Close()
+		inner = resp.String()
+	}
+
+	done := make(chan *Response)
+	go func() { done <- dispatcher.NewRequest().Get(server.URL) }()
+	select {
+	case resp := <-done:
+		defer resp.Close()
+		require.NoError(t, resp.Error)
+		assert.Equal(t, "ok", resp.String())
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reauthenticate blocked requests authorized meanwhile")
+	}
+	assert.Equal(t, "Bearer stale", inner)
+}
+
+type closeTrackingBody struct {
+	io.Reader
+	closed atomic.Bool
+}
+
+func (b *closeTrackingBody) Close() error {
+	b.closed.Store(true)
+	return nil
+}
+
+func TestReauth_ClosesOriginalBody(t *testing.T) {
+	server := newAuthServer("good", nil)
+	defer server.Close()
+
+	original := &closeTrackingBody{Reader: strings.NewReader("payload")}
+	req, err := http.NewRequest(http.MethodPost, server.URL, original)
+	require.NoError(t, err)
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("payload")), nil
+	}
+
+	resp, err := NewDispatcher(nil, Reauth(&testOrchestrator{token: "good"})).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, original.closed.Load())
+}
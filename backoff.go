@@ -0,0 +1,134 @@
+package fetch
+
+import (
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Backoff computes the delay before a retry. attempt starts at 0 for the first
+// retry and previous is the delay returned for the prior attempt (0 initially),
+// which lets stateless implementations such as decorrelated jitter be shared
+// between concurrent requests.
+type Backoff interface {
+	Delay(attempt int, previous time.Duration) time.Duration
+}
+
+// BackoffFunc is an adapter to allow ordinary functions to be used as Backoff.
+type BackoffFunc func(attempt int, previous time.Duration) time.Duration
+
+// Delay calls the underlying function.
+func (f BackoffFunc) Delay(attempt int, previous time.Duration) time.Duration {
+	return f(attempt, previous)
+}
+
+// RandomSource returns pseudo-random numbers in [0, 1).
+// A nil RandomSource uses the global math/rand/v2 generator.
+type RandomSource func() float64
+
+// SeededRandom returns a deterministic RandomSource, useful in tests.
+// It is safe for concurrent use.
+func SeededRandom(seed uint64) RandomSource {
+	var mu sync.Mutex
+	rnd := rand.New(rand.NewPCG(seed, seed))
+
+	return func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return rnd.Float64()
+	}
+}
+
+func (r RandomSource) float64() float64 {
+	if r == nil {
+		return rand.Float64()
+	}
+	return r()
+}
+
+// between returns a random duration in [lo, hi).
+func (r RandomSource) between(lo, hi time.Duration) time.Duration {
+	if hi <= lo {
+		return lo
+	}
+	return lo + time.Duration(r.float64()*float64(hi-lo))
+}
+
+// ConstantBackoff always waits delay.
+func ConstantBackoff(delay time.Duration) Backoff {
+	return BackoffFunc(func(int, time.Duration) time.Duration {
+		return delay
+	})
+}
+
+// ExponentialBackoff waits base * 2^attempt.
+func ExponentialBackoff(base time.Duration) Backoff {
+	return BackoffFunc(func(attempt int, _ time.Duration) time.Duration {
+		return exponential(base, attempt)
+	})
+}
+
+// FullJitterBackoff waits a random duration between 0 and
+// min(limit, base * 2^attempt), the "full jitter" strategy.
+func FullJitterBackoff(base, limit time.Duration, rnd RandomSource) Backoff {
+	return BackoffFunc(func(attempt int, _ time.Duration) time.Duration {
+		return rnd.between(0, min(limit, exponential(base, attempt)))
+	})
+}
+
+// DecorrelatedJitterBackoff waits a random duration between base and three
+// times the previous delay, capped at limit, as recommended by AWS.
+func DecorrelatedJitterBackoff(base, limit time.Duration, rnd RandomSource) Backoff {
+	return BackoffFunc(func(_ int, previous time.Duration) time.Duration {
+		upper := max(previous, base)
+		if upper > math.MaxInt64/3 {
+			upper = limit
+		} else {
+			upper *= 3
+		}
+		return min(limit, rnd.between(base, upper))
+	})
+}
+
+// FibonacciBackoff waits base multiplied by the Fibonacci number of the
+// attempt: base, base, 2*base, 3*base, 5*base, ...
+func FibonacciBackoff(base time.Duration) Backoff {
+	return BackoffFunc(func(attempt int, _ time.Duration) time.Duration {
+		a, b := int64(1), int64(1)
+		for i := 0; i < attempt; i++ {
+			a, b = b, a+b
+			if b < 0 {
+				return time.Duration(math.MaxInt64)
+			}
+		}
+		if base > 0 && a > math.MaxInt64/int64(base) {
+			return time.Duration(math.MaxInt64)
+		}
+		return base * time.Duration(a)
+	})
+}
+
+// CapBackoff limits the delays of b to limit.
+func CapBackoff(b Backoff, limit time.Duration) Backoff {
+	return BackoffFunc(func(attempt int, previous time.Duration) time.Duration {
+		return min(limit, b.Delay(attempt, previous))
+	})
+}
+
+// JitterBackoff randomizes the delays of b by up to ±fraction of their value.
+// For example a fraction of 0.2 turns 1s into a delay between 800ms and 1.2s.
+func JitterBackoff(b Backoff, fraction float64, rnd RandomSource) Backoff {
+	return BackoffFunc(func(attempt int, previous time.Duration) time.Duration {
+		delay := b.Delay(attempt, previous)
+		spread := time.Duration(float64(delay) * fraction)
+		return max(0, rnd.between(delay-spread, delay+spread))
+	})
+}
+
+func exponential(base time.Duration, attempt int) time.Duration {
+	if attempt >= 62 || base > math.MaxInt64>>attempt {
+		return time.Duration(math.MaxInt64)
+	}
+	return base << attempt
+}
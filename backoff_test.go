@@ -0,0 +1,108 @@
+package fetch
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffStrategies(t *testing.T) {
+	tests := []struct {
+		name     string
+		backoff  Backoff
+		expected []time.Duration
+	}{
+		{
+			name:     "constant",
+			backoff:  ConstantBackoff(time.Second),
+			expected: []time.Duration{time.Second, time.Second, time.Second},
+		},
+		{
+			name:     "exponential",
+			backoff:  ExponentialBackoff(100 * time.Millisecond),
+			expected: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond},
+		},
+		{
+			name:     "fibonacci",
+			backoff:  FibonacciBackoff(time.Second),
+			expected: []time.Duration{1 * time.Second, 1 * time.Second, 2 * time.Second, 3 * time.Second, 5 * time.Second, 8 * time.Second},
+		},
+		{
+			name:     "capped exponential",
+			backoff:  CapBackoff(ExponentialBackoff(time.Second), 3*time.Second),
+			expected: []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var previous time.Duration
+			for attempt, want := range tt.expected {
+				previous = tt.backoff.Delay(attempt, previous)
+				assert.Equal(t, want, previous, "attempt %d", attempt)
+			}
+		})
+	}
+}
+
+func TestBackoffOverflow(t *testing.T) {
+	assert.Equal(t, time.Duration(math.MaxInt64), ExponentialBackoff(time.Second).Delay(100, 0))
+	assert.Equal(t, time.Duration(math.MaxInt64), FibonacciBackoff(time.Second).Delay(200, 0))
+}
+
+func TestJitteredBackoffBounds(t *testing.T) {
+	base, limit := 100*time.Millisecond, 2*time.Second
+
+	tests := []struct {
+		name    string
+		backoff Backoff
+		check   func(t *testing.T, attempt int, previous, delay time.Duration)
+	}{
+		{
+			name:    "full jitter",
+			backoff: FullJitterBackoff(base, limit, SeededRandom(1)),
+			check: func(t *testing.T, attempt int, _, delay time.Duration) {
+				assert.GreaterOrEqual(t, delay, time.Duration(0))
+				assert.LessOrEqual(t, delay, min(limit, exponential(base, attempt)))
+			},
+		},
+		{
+			name:    "decorrelated jitter",
+			backoff: DecorrelatedJitterBackoff(base, limit, SeededRandom(2)),
+			check: func(t *testing.T, _ int, previous, delay time.Duration) {
+				assert.GreaterOrEqual(t, delay, base)
+				assert.LessOrEqual(t, delay, min(limit, max(previous, base)*3))
+			},
+		},
+		{
+			name:    "jitter wrapper",
+			backoff: JitterBackoff(ConstantBackoff(time.Second), 0.2, SeededRandom(3)),
+			check: func(t *testing.T, _ int, _, delay time.Duration) {
+				assert.GreaterOrEqual(t, delay, 800*time.Millisecond)
+				assert.LessOrEqual(t, delay, 1200*time.Millisecond)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var previous time.Duration
+			for attempt := 0; attempt < 50; attempt++ {
+				delay := tt.backoff.Delay(attempt, previous)
+				tt.check(t, attempt, previous, delay)
+				previous = delay
+			}
+		})
+	}
+}
+
+func TestSeededRandomIsDeterministic(t *testing.T) {
+	a := FullJitterBackoff(time.Second, time.Minute, SeededRandom(42))
+	b := FullJitterBackoff(time.Second, time.Minute, SeededRandom(42))
+
+	for attempt := 0; attempt < 10; attempt++ {
+		assert.Equal(t, a.Delay(attempt, 0), b.Delay(attempt, 0))
+	}
+}
@@ -110,8 +110,16 @@ func BodyGetBytes(getBytes func() ([]byte, error), opts ...func(*BodyOptions)) M
 // BodyJSON creates middleware that marshals data to JSON and sets it as the request body.
 // Accepts string, []byte, or any marshallable type.
 // Automatically sets Content-Type to application/json.
+// When SetCanonicalJSONEncoding(true) applies to the request the body is
+// encoded with CanonicalJSON.
 func BodyJSON(data any, opts ...func(*BodyOptions)) Middleware {
-	return BodyGetBytes(func() ([]byte, error) {
+	opts = append([]func(*BodyOptions){
+		func(o *BodyOptions) {
+			o.ContentType = "application/json"
+		},
+	}, opts...)
+
+	plain := BodyGetBytes(func() ([]byte, error) {
 		switch v := data.(type) {
 		case string:
 			return []byte(v), nil
@@ -125,13 +133,23 @@ func BodyJSON(data any, opts ...func(*BodyOptions)) Middleware {
 				return nil, err
 			}
 
-			return buf.Bytes(), nil
+			return bytes.Clone(buf.Bytes()), nil
 		}
-	}, append([]func(*BodyOptions){
-		func(o *BodyOptions) {
-			o.ContentType = "application/json"
-		},
-	}, opts...)...)
+	}, opts...)
+	canonical := BodyGetBytes(func() ([]byte, error) {
+		return CanonicalJSON(data)
+	}, opts...)
+
+	return func(next Handler) Handler {
+		plainHandler, canonicalHandler := plain(next), canonical(next)
+
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			if enabled, _ := canonicalJSONKey.GetValue(req.Context()); enabled {
+				return canonicalHandler.Handle(client, req)
+			}
+			return plainHandler.Handle(client, req)
+		})
+	}
 }
 
 // BodyXML creates middleware that marshals data to XML and sets it as the request body.
@@ -0,0 +1,212 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/rockcookies/go-fetch/internal/utils"
+)
+
+var (
+	bodyLimitPolicyKey = utils.NewContextKey[BodyLimitPolicy]("body_limit_policy")
+	bodyLimitStateKey  = utils.NewContextKey[*bodyLimitState]("body_limit_state")
+)
+
+// ErrResponseTooLarge is matched by errors.Is for every ResponseLimitError.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// ResponseLimitError reports a response body exceeding BodyLimitPolicy.FailAt.
+type ResponseLimitError struct {
+	Limit  int64
+	Actual int64
+}
+
+// Error returns the error message.
+func (e *ResponseLimitError) Error() string {
+	return fmt.Sprintf("response body too large: %d bytes exceeds limit of %d", e.Actual, e.Limit)
+}
+
+// Is reports whether target is ErrResponseTooLarge.
+func (e *ResponseLimitError) Is(target error) bool {
+	return target == ErrResponseTooLarge
+}
+
+// BodyLimitPolicy holds the size thresholds applied to a response body.
+// A zero or negative threshold disables that level.
+type BodyLimitPolicy struct {
+	// WarnAt reports bodies larger than this to ResponseBodyLimitOptions.OnWarn.
+	WarnAt int64
+	// TruncateAt ends the body after this many bytes with a silent EOF;
+	// Response.BodyTruncated reports that it happened.
+	TruncateAt int64
+	// FailAt fails bodies larger than this with a ResponseLimitError, up
+	// front when Content-Length announces it and otherwise while reading.
+	FailAt int64
+}
+
+func (p BodyLimitPolicy) enabled() bool {
+	return p.WarnAt > 0 || p.TruncateAt > 0 || p.FailAt > 0
+}
+
+// ResponseBodyLimitOptions configures ResponseBodyLimit.
+type ResponseBodyLimitOptions struct {
+	// Policy applies to responses no ContentTypes entry matches.
+	Policy BodyLimitPolicy
+	// ContentTypes maps media types such as "application/json", or wildcards
+	// such as "image/*", to their own policy.
+	ContentTypes map[string]BodyLimitPolicy
+	// OnWarn is called once per response whose body grows beyond WarnAt,
+	// e.g. to log it or record a metric. size is the announced
+	// Content-Length or the bytes read so far.
+	OnWarn func(resp *http.Response, size int64)
+}
+
+// ResponseBodyLimit creates middleware applying a BodyLimitPolicy to
+// response bodies: crossing WarnAt is reported, TruncateAt returns the body
+// cut short, and FailAt fails with a ResponseLimitError. The policy is chosen
+// by SetResponseBodyLimit on the request, then by the response's media type,
+// then Policy.
+//
+// Example:
+//
+//	dispatcher.Use(fetch.ResponseBodyLimit(func(o *fetch.ResponseBodyLimitOptions) {
+//	    o.Policy = fetch.BodyLimitPolicy{WarnAt: 1 << 20, FailAt: 10 << 20}
+//	    o.ContentTypes = map[string]fetch.BodyLimitPolicy{
+//	        "text/*": {TruncateAt: 64 << 10},
+//	    }
+//	    o.OnWarn = func(resp *http.Response, size int64) {
+//	        slog.Warn("large response", "url", resp.Request.URL, "size", size)
+//	    }
+//	}))
+func ResponseBodyLimit(opts ...func(*ResponseBodyLimitOptions)) Middleware {
+	options := applyOptions(&ResponseBodyLimitOptions{}, opts...)
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			state := &bodyLimitState{}
+			req = req.WithContext(bodyLimitStateKey.WithValue(req.Context(), state))
+
+			resp, err := next.Handle(client, req)
+			if err != nil || resp.Body == nil || resp.Body == http.NoBody || req.Method == http.MethodHead {
+				return resp, err
+			}
+
+			// The override may be set by request middlewares running after this one.
+			if resp.Request != nil {
+				req = resp.Request
+			}
+			policy, ok := bodyLimitPolicyKey.GetValue(req.Context())
+			if !ok {
+				policy = options.policyFor(resp.Header.Get("Content-Type"))
+			}
+			if !policy.enabled() {
+				return resp, nil
+			}
+
+			if policy.FailAt > 0 && resp.ContentLength > policy.FailAt {
+				drainAndClose(resp)
+				return nil, &ResponseLimitError{Limit: policy.FailAt, Actual: resp.ContentLength}
+			}
+
+			body := &policyLimitedBody{ReadCloser: resp.Body, policy: policy, state: state}
+			if options.OnWarn != nil {
+				body.warn = func(size int64) { options.OnWarn(resp, size) }
+			}
+			if policy.WarnAt > 0 && resp.ContentLength > policy.WarnAt {
+				body.warnOnce(resp.ContentLength)
+			}
+			resp.Body = body
+			return resp, nil
+		})
+	}
+}
+
+// SetResponseBodyLimit creates middleware overriding the policy of
+// ResponseBodyLimit for a request, e.g. to allow an export endpoint a larger
+// body than the dispatcher default.
+func SetResponseBodyLimit(policy BodyLimitPolicy) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			req = req.WithContext(bodyLimitPolicyKey.WithValue(req.Context(), policy))
+			return next.Handle(client, req)
+		})
+	}
+}
+
+// BodyTruncated reports whether ResponseBodyLimit cut the body short at
+// BodyLimitPolicy.TruncateAt.
+func (r *Response) BodyTruncated() bool {
+	if r.RawResponse == nil || r.RawResponse.Request == nil {
+		return false
+	}
+	state, ok := bodyLimitStateKey.GetValue(r.RawResponse.Request.Context())
+	return ok && state.truncated.Load()
+}
+
+func (o *ResponseBodyLimitOptions) policyFor(contentType string) BodyLimitPolicy {
+	if len(o.ContentTypes) == 0 || contentType == "" {
+		return o.Policy
+	}
+	media := mediaType(contentType)
+	if policy, ok := o.ContentTypes[media]; ok {
+		return policy
+	}
+	if major, _, ok := strings.Cut(media, "/"); ok {
+		if policy, ok := o.ContentTypes[major+"/*"]; ok {
+			return policy
+		}
+	}
+	return o.Policy
+}
+
+type bodyLimitState struct {
+	truncated atomic.Bool
+}
+
+// policyLimitedBody enforces a BodyLimitPolicy while the body is read.
+type policyLimitedBody struct {
+	io.ReadCloser
+	policy BodyLimitPolicy
+	state  *bodyLimitState
+	warn   func(size int64)
+	warned bool
+	read   int64
+}
+
+func (b *policyLimitedBody) Read(p []byte) (int, error) {
+	if limit := b.policy.TruncateAt; limit > 0 {
+		if b.read >= limit {
+			// Peek one byte to tell a body of exactly limit bytes from a longer one.
+			var probe [1]byte
+			if n, _ := io.ReadFull(b.ReadCloser, probe[:]); n > 0 {
+				b.state.truncated.Store(true)
+			}
+			return 0, io.EOF
+		}
+		if remaining := limit - b.read; int64(len(p)) > remaining {
+			p = p[:remaining]
+		}
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if limit := b.policy.FailAt; limit > 0 && b.read > limit {
+		return 0, &ResponseLimitError{Limit: limit, Actual: b.read}
+	}
+	if b.policy.WarnAt > 0 && b.read > b.policy.WarnAt {
+		b.warnOnce(b.read)
+	}
+	return n, err
+}
+
+func (b *policyLimitedBody) warnOnce(size int64) {
+	if b.warned || b.warn == nil {
+		return
+	}
+	b.warned = true
+	b.warn(size)
+}
@@ -0,0 +1,76 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseBodyLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+		body := strings.Repeat("x", 100)
+		if r.URL.Query().Get("chunked") != "" {
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		query         string
+		override      *BodyLimitPolicy
+		wantLen       int
+		wantTruncated bool
+		wantWarn      int64
+		wantErr       error
+	}{
+		{name: "under limits", query: "type=application/json", wantLen: 100},
+		{name: "text truncated", query: "type=text/plain", wantLen: 40, wantTruncated: true},
+		{name: "image warns", query: "type=image/png", wantLen: 100, wantWarn: 100},
+		{name: "octet stream fails up front", query: "type=application/octet-stream", wantErr: ErrResponseTooLarge},
+		{name: "octet stream fails while reading", query: "type=application/octet-stream&chunked=1", wantErr: ErrResponseTooLarge},
+		{name: "request override", query: "type=application/octet-stream", override: &BodyLimitPolicy{TruncateAt: 100}, wantLen: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warned int64
+			dispatcher := NewDispatcher(nil, ResponseBodyLimit(func(o *ResponseBodyLimitOptions) {
+				o.Policy = BodyLimitPolicy{FailAt: 50}
+				o.ContentTypes = map[string]BodyLimitPolicy{
+					"application/json": {FailAt: 1000},
+					"text/*":           {TruncateAt: 40},
+					"image/*":          {WarnAt: 10},
+				}
+				o.OnWarn = func(resp *http.Response, size int64) { warned = size }
+			}))
+
+			req := dispatcher.NewRequest()
+			if tt.override != nil {
+				req.Use(SetResponseBodyLimit(*tt.override))
+			}
+			resp := req.Get(server.URL + "?" + tt.query)
+			if tt.wantErr != nil {
+				if resp.Error == nil {
+					_, err := resp.RawResponse.Body.Read(make([]byte, 200))
+					assert.ErrorIs(t, err, tt.wantErr)
+					resp.Close()
+					return
+				}
+				assert.ErrorIs(t, resp.Error, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, resp.Error)
+			assert.Len(t, resp.Bytes(), tt.wantLen)
+			assert.Equal(t, tt.wantTruncated, resp.BodyTruncated())
+			assert.Equal(t, tt.wantWarn, warned)
+		})
+	}
+}
@@ -0,0 +1,466 @@
+package fetch
+
+import (
+	"bytes"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheEntry is a stored response.
+type CacheEntry struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       []byte      `json:"body,omitempty"`
+	StoredAt   time.Time   `json:"stored_at"`
+	// Expires is the end of the freshness lifetime. After it the entry is
+	// stale and only served when the origin fails (stale-if-error), the
+	// request allows it (max-stale) or the origin revalidates it.
+	Expires time.Time `json:"expires"`
+	// StaleIfError is the stale-if-error window announced by the origin.
+	StaleIfError time.Duration `json:"stale_if_error,omitempty"`
+	// Vary lists the request headers the responses for a URL vary on. Such an
+	// entry holds no response; the variants are stored under keys including
+	// the request values of these headers.
+	Vary []string `json:"vary,omitempty"`
+}
+
+// CacheRecord is a cache entry with its key, as persisted by
+// MemoryCacheStore.Entries.
+type CacheRecord struct {
+	Key   string      `json:"key"`
+	Entry *CacheEntry `json:"entry"`
+}
+
+// CacheStore stores cache entries by key. Implementations must be safe for concurrent use.
+type CacheStore interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry)
+}
+
+// MemoryCacheStore is an in-memory CacheStore.
+type MemoryCacheStore struct {
+	mu      sync.RWMutex
+	entries map[string]*CacheEntry
+}
+
+// NewMemoryCacheStore creates an empty in-memory cache store.
+func NewMemoryCacheStore() *MemoryCacheStore {
+	return &MemoryCacheStore{entries: map[string]*CacheEntry{}}
+}
+
+// Get returns the entry stored under key.
+func (s *MemoryCacheStore) Get(key string) (*CacheEntry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.entries[key]
+	return entry, ok
+}
+
+// Set stores entry under key.
+func (s *MemoryCacheStore) Set(key string, entry *CacheEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = entry
+}
+
+// Entries returns all entries sorted by key, e.g. to keep a warmed cache
+// across restarts.
+func (s *MemoryCacheStore) Entries() []CacheRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := make([]CacheRecord, 0, len(s.entries))
+	for _, key := range slices.Sorted(maps.Keys(s.entries)) {
+		records = append(records, CacheRecord{Key: key, Entry: s.entries[key]})
+	}
+	return records
+}
+
+// Load adds previously persisted entries, replacing entries with the same key.
+func (s *MemoryCacheStore) Load(records []CacheRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, record := range records {
+		if record.Entry != nil {
+			s.entries[record.Key] = record.Entry
+		}
+	}
+}
+
+// CacheOptions configures the cache middleware.
+type CacheOptions struct {
+	Store CacheStore
+	// StaleIfError is how long after expiry a stale entry may still be served
+	// when the origin returns 5xx or a network error. The larger of this value
+	// and the origin's stale-if-error directive is used.
+	StaleIfError time.Duration
+	// BodyMaxSize is the largest response body that is stored; larger
+	// responses are passed through without being buffered. Defaults to 1MB.
+	BodyMaxSize int64
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// cacheStatusHeader carries the CacheStatus of responses handled by the cache middleware.
+const cacheStatusHeader = "X-Fetch-Cache"
+
+// CacheStatus reports how the Cache middleware handled a response.
+type CacheStatus string
+
+const (
+	// CacheMiss means the response came from the origin.
+	CacheMiss CacheStatus = "miss"
+	// CacheHit means a fresh entry was served without contacting the origin.
+	CacheHit CacheStatus = "hit"
+	// CacheStale means an expired entry was served, either because the
+	// origin failed (stale-if-error) or the request allowed it (max-stale).
+	CacheStale CacheStatus = "stale"
+	// CacheRevalidated means the origin confirmed the entry with 304 Not Modified.
+	CacheRevalidated CacheStatus = "revalidated"
+)
+
+// Cache creates middleware that caches successful GET responses according to
+// their Cache-Control max-age and serves them while fresh. Responses are
+// stored per value of the request headers listed in their Vary header.
+// Requests carrying credentials in an Authorization or Cookie header bypass
+// the cache, and responses marked private or no-store are not stored. Expired entries
+// with an ETag or Last-Modified validator are revalidated with a conditional
+// request. When the origin fails with 5xx or a network error and a stale
+// entry is within the stale-if-error window, the stale response is returned
+// instead of the failure.
+//
+// The request directives no-cache, no-store, max-stale and only-if-cached are
+// honored, see NoCache, NoStore, MaxStale and OnlyIfCached. Decisions are made
+// when the client sends the request, so directives added by request
+// middlewares apply even when Cache is a dispatcher middleware; place Cache
+// after middlewares that replace the client's transport.
+//
+// Cached responses can be recognized with Response.CacheStatus.
+func Cache(opts ...func(*CacheOptions)) Middleware {
+	options := applyOptions(&CacheOptions{
+		BodyMaxSize: 1024 * 1024, // 1MB
+	}, opts...)
+	if options.Store == nil {
+		options.Store = NewMemoryCacheStore()
+	}
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			client.Transport = &cacheTransport{base: client.Transport, options: options}
+			return next.Handle(client, req)
+		})
+	}
+}
+
+type cacheTransport struct {
+	base    http.RoundTripper
+	options *CacheOptions
+}
+
+func (t *cacheTransport) unwrap() http.RoundTripper { return t.base }
+
+func (t *cacheTransport) rewrap(base http.RoundTripper) http.RoundTripper {
+	clone := *t
+	clone.base = base
+	return &clone
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestDirectives := parseCacheControl(req.Header.Get("Cache-Control"))
+	if _, noStore := requestDirectives["no-store"]; noStore || req.Method != http.MethodGet || hasCredentials(req) {
+		return t.roundTrip(req)
+	}
+
+	entry, cached := t.lookup(req)
+	now := t.options.Now()
+
+	if _, noCache := requestDirectives["no-cache"]; cached && !noCache {
+		if now.Before(entry.Expires) {
+			return entry.response(req, CacheHit), nil
+		}
+		if maxStale, ok := requestDirectives["max-stale"]; ok {
+			if maxStale == "" || now.Before(entry.Expires.Add(directiveSeconds(requestDirectives, "max-stale"))) {
+				return entry.response(req, CacheStale), nil
+			}
+		}
+	}
+
+	if _, onlyIfCached := requestDirectives["only-if-cached"]; onlyIfCached {
+		return (&CacheEntry{StatusCode: http.StatusGatewayTimeout, Header: http.Header{}}).response(req, CacheMiss), nil
+	}
+
+	outgoing := req
+	if cached && req.Header.Get("If-None-Match") == "" && req.Header.Get("If-Modified-Since") == "" {
+		etag, lastModified := entry.Header.Get("ETag"), entry.Header.Get("Last-Modified")
+		if etag != "" || lastModified != "" {
+			outgoing = req.Clone(req.Context())
+			if etag != "" {
+				outgoing.Header.Set("If-None-Match", etag)
+			}
+			if lastModified != "" {
+				outgoing.Header.Set("If-Modified-Since", lastModified)
+			}
+		}
+	}
+
+	resp, err := t.roundTrip(outgoing)
+
+	if err != nil || resp.StatusCode >= 500 {
+		if cached && now.Before(entry.Expires.Add(max(t.options.StaleIfError, entry.StaleIfError))) {
+			drainAndClose(resp)
+			return entry.response(req, CacheStale), nil
+		}
+		return resp, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && outgoing != req {
+		drainAndClose(resp)
+
+		refreshed := *entry
+		refreshed.Header = entry.Header.Clone()
+		for _, name := range []string{"Cache-Control", "Date", "ETag", "Expires", "Last-Modified"} {
+			if values := resp.Header.Values(name); len(values) > 0 {
+				refreshed.Header[name] = values
+			}
+		}
+		t.store(req, &refreshed, now)
+		return refreshed.response(req, CacheRevalidated), nil
+	}
+
+	resp.Header.Set(cacheStatusHeader, string(CacheMiss))
+	if resp.StatusCode != http.StatusOK || !storable(resp.Header) || resp.ContentLength > t.options.BodyMaxSize {
+		return resp, nil
+	}
+
+	original := resp.Body
+	body, err := io.ReadAll(io.LimitReader(original, t.options.BodyMaxSize+1))
+	if err != nil {
+		original.Close()
+		return nil, err
+	}
+	if int64(len(body)) > t.options.BodyMaxSize {
+		// Too large to store: hand out what was read followed by the rest.
+		resp.Body = &cleanupReadCloser{
+			ReadCloser: io.NopCloser(io.MultiReader(bytes.NewReader(body), original)),
+			cleanup:    func() { original.Close() },
+		}
+		return resp, nil
+	}
+	original.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := resp.Header.Clone()
+	header.Del(cacheStatusHeader)
+	t.store(req, &CacheEntry{StatusCode: resp.StatusCode, Header: header, Body: body}, now)
+
+	return resp, nil
+}
+
+// roundTrip sends req to the origin. The cache status header is removed from
+// the response, so an origin cannot pass its responses off as cache hits.
+func (t *cacheTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	resp, err := base.RoundTrip(req)
+	if resp != nil {
+		resp.Header.Del(cacheStatusHeader)
+	}
+	return resp, err
+}
+
+// lookup returns the entry stored for req, following the Vary entry of its
+// URL to the variant matching the request headers.
+func (t *cacheTransport) lookup(req *http.Request) (*CacheEntry, bool) {
+	key := cacheKey(req)
+	entry, ok := t.options.Store.Get(key)
+	if ok && len(entry.Vary) > 0 {
+		entry, ok = t.options.Store.Get(varyKey(key, entry.Vary, req))
+	}
+	if ok && len(entry.Vary) > 0 {
+		return nil, false
+	}
+	return entry, ok
+}
+
+// store saves entry for req with its freshness taken from its Cache-Control
+// header, unless the header forbids storing. A response with a Vary header
+// is stored as a variant next to a Vary entry listing the header names.
+func (t *cacheTransport) store(req *http.Request, entry *CacheEntry, now time.Time) {
+	if !storable(entry.Header) {
+		return
+	}
+	directives := parseCacheControl(entry.Header.Get("Cache-Control"))
+
+	entry.StoredAt = now
+	entry.Expires = now.Add(directiveSeconds(directives, "max-age"))
+	entry.StaleIfError = directiveSeconds(directives, "stale-if-error")
+
+	key := cacheKey(req)
+	if vary := varyHeaders(entry.Header); len(vary) > 0 {
+		t.options.Store.Set(key, &CacheEntry{StoredAt: now, Vary: vary})
+		key = varyKey(key, vary, req)
+	}
+	t.options.Store.Set(key, entry)
+}
+
+// storable reports whether a response with header may be stored by a shared
+// cache: it is not private, not no-store and does not vary on everything.
+func storable(header http.Header) bool {
+	directives := parseCacheControl(header.Get("Cache-Control"))
+	if _, noStore := directives["no-store"]; noStore {
+		return false
+	}
+	if _, private := directives["private"]; private {
+		return false
+	}
+	return !slices.Contains(varyHeaders(header), "*")
+}
+
+// hasCredentials reports whether req carries credentials whose responses
+// must not be shared with other callers.
+func hasCredentials(req *http.Request) bool {
+	return req.Header.Get("Authorization") != "" || req.Header.Get("Cookie") != ""
+}
+
+func cacheKey(req *http.Request) string {
+	return req.Method + " " + req.URL.String()
+}
+
+// varyHeaders returns the sorted canonical header names listed in Vary.
+func varyHeaders(header http.Header) []string {
+	var names []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// varyKey returns the key of the variant of key selected by the request
+// values of the vary headers.
+func varyKey(key string, vary []string, req *http.Request) string {
+	var b strings.Builder
+	b.WriteString(key)
+	for _, name := range vary {
+		b.WriteString("\n" + name + ": " + strings.Join(req.Header.Values(name), ", "))
+	}
+	return b.String()
+}
+
+func (e *CacheEntry) response(req *http.Request, status CacheStatus) *http.Response {
+	header := e.Header.Clone()
+	header.Set(cacheStatusHeader, string(status))
+
+	return &http.Response{
+		Status:        strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// parseCacheControl parses a Cache-Control header into lower-cased directives.
+func parseCacheControl(value string) map[string]string {
+	directives := map[string]string{}
+	for _, part := range strings.Split(value, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name == "" {
+			continue
+		}
+		directives[strings.ToLower(name)] = strings.Trim(arg, `"`)
+	}
+	return directives
+}
+
+func directiveSeconds(directives map[string]string, name string) time.Duration {
+	seconds, err := strconv.Atoi(directives[name])
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// NoCache creates request middleware that makes Cache revalidate with the
+// origin instead of serving a stored response.
+func NoCache() Middleware {
+	return addCacheDirective("no-cache")
+}
+
+// NoStore creates request middleware that makes Cache bypass the store:
+// nothing is served from it and the response is not stored.
+func NoStore() Middleware {
+	return addCacheDirective("no-store")
+}
+
+// MaxStale creates request middleware that lets Cache serve a response that
+// expired at most d ago without contacting the origin.
+func MaxStale(d time.Duration) Middleware {
+	return addCacheDirective("max-stale=" + strconv.Itoa(int(d/time.Second)))
+}
+
+// OnlyIfCached creates request middleware that makes Cache answer from the
+// store only; without a usable entry the response is 504 Gateway Timeout.
+func OnlyIfCached() Middleware {
+	return addCacheDirective("only-if-cached")
+}
+
+func addCacheDirective(directive string) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			value := directive
+			if existing := req.Header.Get("Cache-Control"); existing != "" {
+				value = existing + ", " + directive
+			}
+			req.Header.Set("Cache-Control", value)
+			return next.Handle(client, req)
+		})
+	}
+}
+
+// CacheStatus returns how the Cache middleware handled the response, or ""
+// when the response did not pass through a cache.
+func (r *Response) CacheStatus() CacheStatus {
+	if r.Error != nil {
+		return ""
+	}
+	return CacheStatus(r.Header.Get(cacheStatusHeader))
+}
+
+// FromCache reports whether the response was served by the Cache middleware.
+func (r *Response) FromCache() bool {
+	switch r.CacheStatus() {
+	case CacheHit, CacheStale, CacheRevalidated:
+		return true
+	}
+	return false
+}
+
+// IsStale reports whether the response is a stale cache entry, served because
+// the origin failed or the request allowed stale responses.
+func (r *Response) IsStale() bool {
+	return r.CacheStatus() == CacheStale
+}
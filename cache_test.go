@@ -0,0 +1,332 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_StaleIfError(t *testing.T) {
+	tests := []struct {
+		name          string
+		cacheControl  string
+		staleIfError  time.Duration
+		elapsed       time.Duration
+		failure       int
+		wantStatus    int
+		wantFromCache bool
+		wantStale     bool
+	}{
+		{
+			name:          "fresh entry is served without origin",
+			cacheControl:  "max-age=60",
+			elapsed:       30 * time.Second,
+			wantStatus:    http.StatusOK,
+			wantFromCache: true,
+		},
+		{
+			name:          "stale entry served on 5xx within configured window",
+			cacheControl:  "max-age=60",
+			staleIfError:  time.Minute,
+			elapsed:       90 * time.Second,
+			failure:       http.StatusBadGateway,
+			wantStatus:    http.StatusOK,
+			wantFromCache: true,
+			wantStale:     true,
+		},
+		{
+			name:          "stale entry served within origin stale-if-error window",
+			cacheControl:  "max-age=60, stale-if-error=120",
+			elapsed:       150 * time.Second,
+			failure:       http.StatusServiceUnavailable,
+			wantStatus:    http.StatusOK,
+			wantFromCache: true,
+			wantStale:     true,
+		},
+		{
+			name:         "error returned outside window",
+			cacheControl: "max-age=60",
+			staleIfError: time.Minute,
+			elapsed:      10 * time.Minute,
+			failure:      http.StatusBadGateway,
+			wantStatus:   http.StatusBadGateway,
+		},
+		{
+			name:         "no-store is never cached",
+			cacheControl: "no-store",
+			staleIfError: time.Hour,
+			elapsed:      time.Second,
+			failure:      http.StatusInternalServerError,
+			wantStatus:   http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var failing atomic.Bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if failing.Load() {
+					w.WriteHeader(tt.failure)
+					return
+				}
+				w.Header().Set("Cache-Control", tt.cacheControl)
+				w.Write([]byte("payload"))
+			}))
+			defer server.Close()
+
+			now := time.Now()
+			cache := Cache(func(o *CacheOptions) {
+				o.StaleIfError = tt.staleIfError
+				o.Now = func() time.Time { return now }
+			})
+			dispatcher := NewDispatcher(nil, cache)
+
+			first := dispatcher.NewRequest().Get(server.URL)
+			require.NoError(t, first.Error)
+			assert.Equal(t, "payload", first.String())
+			assert.False(t, first.FromCache())
+
+			failing.Store(tt.failure != 0)
+			now = now.Add(tt.elapsed)
+
+			second := dispatcher.NewRequest().Get(server.URL)
+			defer second.Close()
+			require.NoError(t, second.Error)
+			assert.Equal(t, tt.wantStatus, second.RawResponse.StatusCode)
+			assert.Equal(t, tt.wantFromCache, second.FromCache())
+			assert.Equal(t, tt.wantStale, second.IsStale())
+			if tt.wantFromCache {
+				assert.Equal(t, "payload", second.String())
+			}
+		})
+	}
+}
+
+func TestCache_NetworkError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=0, stale-if-error=60")
+		w.Write([]byte("payload"))
+	}))
+
+	dispatcher := NewDispatcher(nil, Cache())
+	first := dispatcher.NewRequest().Get(server.URL)
+	require.NoError(t, first.Error)
+	first.Close()
+
+	server.Close()
+
+	second := dispatcher.NewRequest().Get(server.URL)
+	defer second.Close()
+	require.NoError(t, second.Error)
+	assert.True(t, second.IsStale())
+	assert.Equal(t, "payload", second.String())
+}
+
+func TestParseCacheControl(t *testing.T) {
+	directives := parseCacheControl(`max-age=60, No-Cache, private="x"`)
+	assert.Equal(t, map[string]string{"max-age": "60", "no-cache": "", "private": "x"}, directives)
+	assert.Equal(t, time.Minute, directiveSeconds(directives, "max-age"))
+	assert.Zero(t, directiveSeconds(directives, "no-cache"))
+}
+
+func TestCache_RequestDirectives(t *testing.T) {
+	const etag = `"v1"`
+
+	tests := []struct {
+		name       string
+		elapsed    time.Duration
+		directives []Middleware
+		wantStatus int
+		wantCache  CacheStatus
+		wantOrigin int32
+	}{
+		{name: "fresh hit", elapsed: 30 * time.Second, wantStatus: http.StatusOK, wantCache: CacheHit},
+		{name: "no-cache revalidates", elapsed: 30 * time.Second, directives: []Middleware{NoCache()}, wantStatus: http.StatusOK, wantCache: CacheRevalidated, wantOrigin: 1},
+		{name: "expired entry revalidates", elapsed: 2 * time.Minute, wantStatus: http.StatusOK, wantCache: CacheRevalidated, wantOrigin: 1},
+		{name: "max-stale serves stale", elapsed: 90 * time.Second, directives: []Middleware{MaxStale(time.Minute)}, wantStatus: http.StatusOK, wantCache: CacheStale},
+		{name: "max-stale exceeded", elapsed: 3 * time.Minute, directives: []Middleware{MaxStale(time.Minute)}, wantStatus: http.StatusOK, wantCache: CacheRevalidated, wantOrigin: 1},
+		{name: "only-if-cached without usable entry", elapsed: 2 * time.Minute, directives: []Middleware{OnlyIfCached()}, wantStatus: http.StatusGatewayTimeout, wantCache: CacheMiss},
+		{name: "only-if-cached with max-stale", elapsed: 2 * time.Minute, directives: []Middleware{OnlyIfCached(), MaxStale(time.Hour)}, wantStatus: http.StatusOK, wantCache: CacheStale},
+		{name: "no-store bypasses", elapsed: 30 * time.Second, directives: []Middleware{NoStore()}, wantStatus: http.StatusOK, wantOrigin: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var origin atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				origin.Add(1)
+				w.Header().Set("Cache-Control", "max-age=60")
+				w.Header().Set("ETag", etag)
+				if r.Header.Get("If-None-Match") == etag {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Write([]byte("payload"))
+			}))
+			defer server.Close()
+
+			now := time.Now()
+			dispatcher := NewDispatcher(nil, Cache(func(o *CacheOptions) {
+				o.Now = func() time.Time { return now }
+			}))
+
+			first := dispatcher.NewRequest().Get(server.URL)
+			require.NoError(t, first.Error)
+			assert.Equal(t, CacheMiss, first.CacheStatus())
+			first.Close()
+
+			origin.Store(0)
+			now = now.Add(tt.elapsed)
+
+			resp := dispatcher.NewRequest().Use(tt.directives...).Get(server.URL)
+			defer resp.Close()
+
+			require.NoError(t, resp.Error)
+			assert.Equal(t, tt.wantStatus, resp.RawResponse.StatusCode)
+			assert.Equal(t, tt.wantCache, resp.CacheStatus())
+			assert.Equal(t, tt.wantOrigin, origin.Load())
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, "payload", resp.String())
+			}
+		})
+	}
+}
+
+func TestCache_SharedResponses(t *testing.T) {
+	tests := []struct {
+		name         string
+		cacheControl string
+		vary         string
+		body         string
+		bodyMaxSize  int64
+		requests     []http.Header
+		wantBodies   []string
+		wantHits     int32
+	}{
+		{
+			name:         "variants per vary header",
+			cacheControl: "max-age=60",
+			vary:         "Accept-Language",
+			requests: []http.Header{
+				{"Accept-Language": {"en"}},
+				{"Accept-Language": {"fr"}},
+				{"Accept-Language": {"en"}},
+				{"Accept-Language": {"fr"}},
+			},
+			wantBodies: []string{"en", "fr", "en", "fr"},
+			wantHits:   2,
+		},
+		{
+			name:         "vary star is not stored",
+			cacheControl: "max-age=60",
+			vary:         "*",
+			requests:     []http.Header{{}, {}},
+			wantBodies:   []string{"", ""},
+			wantHits:     2,
+		},
+		{
+			name:         "authorization bypasses the cache",
+			cacheControl: "max-age=60",
+			requests: []http.Header{
+				{"Authorization": {"Bearer alice"}},
+				{"Authorization": {"Bearer bob"}},
+			},
+			wantBodies: []string{"Bearer alice", "Bearer bob"},
+			wantHits:   2,
+		},
+		{
+			name:         "cookie bypasses the cache",
+			cacheControl: "max-age=60",
+			requests:     []http.Header{{"Cookie": {"session=a"}}, {"Cookie": {"session=b"}}},
+			wantBodies:   []string{"session=a", "session=b"},
+			wantHits:     2,
+		},
+		{
+			name:         "private is not stored",
+			cacheControl: "private, max-age=60",
+			requests:     []http.Header{{}, {}},
+			wantBodies:   []string{"", ""},
+			wantHits:     2,
+		},
+		{
+			name:         "body above max size is not stored",
+			cacheControl: "max-age=60",
+			body:         "a large payload",
+			bodyMaxSize:  4,
+			requests:     []http.Header{{}, {}},
+			wantBodies:   []string{"a large payload", "a large payload"},
+			wantHits:     2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hits.Add(1)
+				w.Header().Set("Cache-Control", tt.cacheControl)
+				if tt.vary != "" {
+					w.Header().Set("Vary", tt.vary)
+				}
+				if tt.body != "" {
+					w.Write([]byte(tt.body))
+					return
+				}
+				w.Write([]byte(r.Header.Get("Accept-Language") + r.Header.Get("Authorization") + r.Header.Get("Cookie")))
+			}))
+			defer server.Close()
+
+			dispatcher := NewDispatcher(nil, Cache(func(o *CacheOptions) {
+				if tt.bodyMaxSize > 0 {
+					o.BodyMaxSize = tt.bodyMaxSize
+				}
+			}))
+			for i, header := range tt.requests {
+				resp := dispatcher.NewRequest().UseFuncs(func(req *http.Request) {
+					for name, values := range header {
+						req.Header[name] = values
+					}
+				}).Get(server.URL)
+				require.NoError(t, resp.Error)
+				assert.Equal(t, tt.wantBodies[i], resp.String(), "request %d", i)
+				resp.Close()
+			}
+			assert.Equal(t, tt.wantHits, hits.Load())
+		})
+	}
+}
+
+func TestCache_OriginCannotSpoofStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Fetch-Cache", string(CacheHit))
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write([]byte("payload"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		want   CacheStatus
+	}{
+		{name: "cacheable request", method: http.MethodGet, want: CacheMiss},
+		{name: "bypassed request", method: http.MethodPost, want: ""},
+	}
+
+	dispatcher := NewDispatcher(nil, Cache())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := dispatcher.NewRequest().Send(tt.method, server.URL)
+			require.NoError(t, resp.Error)
+			defer resp.Close()
+
+			assert.Equal(t, tt.want, resp.CacheStatus())
+			assert.False(t, resp.FromCache())
+		})
+	}
+}
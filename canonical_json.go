@@ -0,0 +1,186 @@
+package fetch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/rockcookies/go-fetch/internal/utils"
+)
+
+var canonicalJSONKey = utils.NewContextKey[bool]("canonical_json")
+
+// SetCanonicalJSONEncoding creates middleware that toggles canonical encoding
+// for JSON request bodies built with BodyJSON, see CanonicalJSON. Added to a
+// dispatcher it sets the default; added to a request it overrides the
+// dispatcher. Signing middlewares should add SetCanonicalJSONEncoding(true)
+// in front of the chain they return, so the signed bytes are reproducible.
+func SetCanonicalJSONEncoding(canonical bool) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			req = req.WithContext(canonicalJSONKey.WithValue(req.Context(), canonical))
+			return next.Handle(client, req)
+		})
+	}
+}
+
+// CanonicalJSON sets the request body to the canonical JSON encoding of data.
+// Unlike JSON it always encodes canonically, regardless of SetCanonicalJSONEncoding.
+func (r *Request) CanonicalJSON(data any, opts ...func(*BodyOptions)) *Request {
+	return r.Use(SetCanonicalJSONEncoding(true), BodyJSON(data, opts...))
+}
+
+// CanonicalJSON encodes v in the canonical form used by signature schemes
+// and content-addressed APIs, following RFC 8785: object keys sorted by their
+// UTF-16 code units, no insignificant whitespace, no HTML escaping and numbers
+// serialized like ECMAScript does, e.g. 1e+21, 0.1 and 100 rather than 1E21,
+// 1.0e-1 or 1.0e2. Numbers are IEEE 754 doubles, so integers beyond 2^53 lose
+// precision; send such values as strings. Strings and []byte are treated as
+// JSON text and re-encoded canonically.
+func CanonicalJSON(v any) ([]byte, error) {
+	var raw []byte
+	switch data := v.(type) {
+	case string:
+		raw = []byte(data)
+	case []byte:
+		raw = data
+	default:
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(v); err != nil {
+			return nil, err
+		}
+		raw = buf.Bytes()
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := writeCanonicalJSON(&out, value); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func writeCanonicalJSON(out *bytes.Buffer, value any) error {
+	switch v := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.SortFunc(keys, compareUTF16)
+
+		out.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			writeCanonicalString(out, key)
+			out.WriteByte(':')
+			if err := writeCanonicalJSON(out, v[key]); err != nil {
+				return err
+			}
+		}
+		out.WriteByte('}')
+	case []any:
+		out.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			if err := writeCanonicalJSON(out, item); err != nil {
+				return err
+			}
+		}
+		out.WriteByte(']')
+	case string:
+		writeCanonicalString(out, v)
+	case json.Number:
+		number, err := canonicalNumber(v)
+		if err != nil {
+			return err
+		}
+		out.WriteString(number)
+	case bool:
+		out.WriteString(strconv.FormatBool(v))
+	case nil:
+		out.WriteString("null")
+	default:
+		return fmt.Errorf("canonical json: unexpected %T", value)
+	}
+	return nil
+}
+
+// writeCanonicalString writes s as a JSON string the way RFC 8785 requires:
+// only quotation mark, reverse solidus and control characters are escaped,
+// using the short forms where JSON has them.
+func writeCanonicalString(out *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+
+	out.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			out.WriteString(`\"`)
+		case '\\':
+			out.WriteString(`\\`)
+		case '\b':
+			out.WriteString(`\b`)
+		case '\f':
+			out.WriteString(`\f`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\r':
+			out.WriteString(`\r`)
+		case '\t':
+			out.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				out.WriteString(`\u00`)
+				out.WriteByte(hex[r>>4])
+				out.WriteByte(hex[r&0xf])
+				continue
+			}
+			out.WriteRune(r)
+		}
+	}
+	out.WriteByte('"')
+}
+
+// compareUTF16 orders strings by their UTF-16 code units, as RFC 8785
+// requires for object keys.
+func compareUTF16(a, b string) int {
+	return slices.Compare(utf16.Encode([]rune(a)), utf16.Encode([]rune(b)))
+}
+
+// canonicalNumber formats n like the ECMAScript Number to String conversion.
+func canonicalNumber(n json.Number) (string, error) {
+	f, err := strconv.ParseFloat(n.String(), 64)
+	if err != nil || math.IsInf(f, 0) {
+		return "", fmt.Errorf("canonical json: invalid number %s", n)
+	}
+	if f == 0 {
+		return "0", nil
+	}
+	if abs := math.Abs(f); abs >= 1e21 || abs < 1e-6 {
+		s := strconv.FormatFloat(f, 'e', -1, 64)
+		mantissa, exponent, _ := strings.Cut(s, "e")
+		sign := exponent[:1]
+		exponent = strings.TrimLeft(exponent[1:], "0")
+		return mantissa + "e" + sign + exponent, nil
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64), nil
+}
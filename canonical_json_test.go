@@ -0,0 +1,81 @@
+package fetch
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    any
+		expected string
+		wantErr  bool
+	}{
+		{name: "sorted nested keys", input: map[string]any{"b": 1, "a": map[string]any{"z": true, "y": nil}}, expected: `{"a":{"y":null,"z":true},"b":1}`},
+		{name: "struct fields sorted", input: struct {
+			Zeta  string `json:"zeta"`
+			Alpha []int  `json:"alpha"`
+		}{Zeta: "<&>", Alpha: []int{3, 1}}, expected: `{"alpha":[3,1],"zeta":"<&>"}`},
+		{name: "numbers", input: `[1.0, 1e2, 0.1, -0, 1e21, 1e-7, 123456789012345678, 2.5E+3]`, expected: `[1,100,0.1,0,1e+21,1e-7,123456789012345680,2500]`},
+		{name: "keys in UTF-16 order", input: `{"\ufb01":2,"\ud83d\ude00":1,"\u20ac":3,"a":4}`, expected: "{\"a\":4,\"\u20ac\":3,\"\U0001F600\":1,\"\ufb01\":2}"},
+		{name: "whitespace removed from text", input: []byte("{ \"b\" : [ 1 , 2 ] ,\n \"a\" : \"x\" }"), expected: `{"a":"x","b":[1,2]}`},
+		{name: "line separators unescaped", input: "\"a\u2028b\"", expected: "\"a\u2028b\""},
+		{name: "escaped backslash before u2028", input: `{"a":"\\u2028"}`, expected: `{"a":"\\u2028"}`},
+		{name: "control characters", input: `"\t\n\u0001\u001f\\\""`, expected: `"\t\n\u0001\u001f\\\""`},
+		{name: "invalid text", input: "{", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CanonicalJSON(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(got))
+			assert.True(t, json.Valid(got))
+		})
+	}
+}
+
+func TestSetCanonicalJSONEncoding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	data := map[string]any{"b": "<x>", "a": 1.50}
+
+	tests := []struct {
+		name       string
+		dispatcher []Middleware
+		request    func(*Request) *Request
+		expected   string
+	}{
+		{name: "default encoding", request: func(r *Request) *Request { return r.JSON(data) }, expected: "{\"a\":1.5,\"b\":\"\\u003cx\\u003e\"}\n"},
+		{name: "per request", request: func(r *Request) *Request { return r.CanonicalJSON(data) }, expected: `{"a":1.5,"b":"<x>"}`},
+		{name: "enforced by dispatcher", dispatcher: []Middleware{SetCanonicalJSONEncoding(true)}, request: func(r *Request) *Request { return r.JSON(data) }, expected: `{"a":1.5,"b":"<x>"}`},
+		{name: "request overrides dispatcher", dispatcher: []Middleware{SetCanonicalJSONEncoding(true)}, request: func(r *Request) *Request {
+			return r.Use(SetCanonicalJSONEncoding(false)).JSON(data)
+		}, expected: "{\"a\":1.5,\"b\":\"\\u003cx\\u003e\"}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := tt.request(NewDispatcher(nil, tt.dispatcher...).NewRequest()).Post(server.URL)
+			defer resp.Close()
+
+			require.NoError(t, resp.Error)
+			assert.Equal(t, tt.expected, resp.String())
+		})
+	}
+}
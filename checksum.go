@@ -0,0 +1,189 @@
+package fetch
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/rockcookies/go-fetch/internal/utils"
+)
+
+var checksumsKey = utils.NewContextKey[*Checksums]("checksums")
+
+// HashAlgorithm names a checksum algorithm supported by ComputeChecksums.
+type HashAlgorithm string
+
+// Supported checksum algorithms.
+const (
+	MD5    HashAlgorithm = "md5"
+	SHA1   HashAlgorithm = "sha1"
+	SHA256 HashAlgorithm = "sha256"
+)
+
+func newHash(alg HashAlgorithm) hash.Hash {
+	switch alg {
+	case MD5:
+		return md5.New()
+	case SHA1:
+		return sha1.New()
+	case SHA256:
+		return sha256.New()
+	default:
+		return nil
+	}
+}
+
+// Checksums holds the hashes of the request and response bodies computed
+// while they were streamed. Response digests are only complete once the
+// response body has been fully read.
+type Checksums struct {
+	mu       sync.Mutex
+	request  map[HashAlgorithm]hash.Hash
+	response map[HashAlgorithm]hash.Hash
+}
+
+func newHashes(algorithms []HashAlgorithm) map[HashAlgorithm]hash.Hash {
+	hashes := make(map[HashAlgorithm]hash.Hash, len(algorithms))
+	for _, alg := range algorithms {
+		if h := newHash(alg); h != nil {
+			hashes[alg] = h
+		}
+	}
+	return hashes
+}
+
+// Request returns the hex encoded digest of the request body, or an empty
+// string if the algorithm was not computed.
+func (c *Checksums) Request(alg HashAlgorithm) string {
+	return c.sum(false, alg)
+}
+
+// Response returns the hex encoded digest of the response body read so far,
+// or an empty string if the algorithm was not computed.
+func (c *Checksums) Response(alg HashAlgorithm) string {
+	return c.sum(true, alg)
+}
+
+func (c *Checksums) hashes(response bool) map[HashAlgorithm]hash.Hash {
+	if response {
+		return c.response
+	}
+	return c.request
+}
+
+func (c *Checksums) sum(response bool, alg HashAlgorithm) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h, ok := c.hashes(response)[alg]
+	if !ok {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *Checksums) write(response bool, p []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, h := range c.hashes(response) {
+		h.Write(p)
+	}
+}
+
+// ChecksumOptions selects the algorithms computed for each body.
+type ChecksumOptions struct {
+	Request  []HashAlgorithm
+	Response []HashAlgorithm
+}
+
+// ComputeChecksums creates middleware that hashes the request and response
+// bodies on the fly as they are streamed, without extra buffering.
+// The result is available from Response.Checksums.
+//
+// Example:
+//
+//	resp := req.Use(fetch.ComputeChecksums(func(o *fetch.ChecksumOptions) {
+//	    o.Response = []fetch.HashAlgorithm{fetch.SHA256}
+//	})).Get(url)
+//	data := resp.Bytes()
+//	digest := resp.Checksums().Response(fetch.SHA256)
+func ComputeChecksums(opts ...func(*ChecksumOptions)) Middleware {
+	options := applyOptions(&ChecksumOptions{}, opts...)
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			checksums := &Checksums{
+				request:  newHashes(options.Request),
+				response: newHashes(options.Response),
+			}
+
+			if len(checksums.request) > 0 {
+				if req.Body != nil && req.Body != http.NoBody {
+					req.Body = &checksumReader{ReadCloser: req.Body, checksums: checksums}
+				}
+
+				if getBody := req.GetBody; getBody != nil {
+					req.GetBody = func() (io.ReadCloser, error) {
+						body, err := getBody()
+						if err != nil {
+							return nil, err
+						}
+
+						// The body is being replayed: start the digests over.
+						checksums.mu.Lock()
+						checksums.request = newHashes(options.Request)
+						checksums.mu.Unlock()
+
+						return &checksumReader{ReadCloser: body, checksums: checksums}, nil
+					}
+				}
+			}
+
+			req = req.WithContext(checksumsKey.WithValue(req.Context(), checksums))
+			resp, err := next.Handle(client, req)
+			if err != nil || resp == nil {
+				return resp, err
+			}
+
+			body := resp.Body
+			if body == nil {
+				body = http.NoBody
+			}
+			resp.Body = &checksumReader{ReadCloser: body, checksums: checksums, response: true}
+
+			return resp, nil
+		})
+	}
+}
+
+// checksumReader tees everything read from the wrapped body into hashes.
+type checksumReader struct {
+	io.ReadCloser
+	checksums *Checksums
+	response  bool
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.checksums.write(r.response, p[:n])
+	}
+	return n, err
+}
+
+// Checksums returns the body checksums computed by ComputeChecksums,
+// or nil if the middleware was not used.
+func (r *Response) Checksums() *Checksums {
+	if r.RawResponse == nil || r.RawResponse.Request == nil {
+		return nil
+	}
+
+	checksums, _ := checksumsKey.GetValue(r.RawResponse.Request.Context())
+	return checksums
+}
@@ -0,0 +1,107 @@
+package fetch
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func hexSHA256(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func hexMD5(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestComputeChecksums(t *testing.T) {
+	const requestBody = "request payload"
+	const responseBody = "response payload"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.Write([]byte(responseBody))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		useBodyBytes bool
+		wrapResponse bool
+	}{
+		{name: "streamed body"},
+		{name: "replayable body", useBodyBytes: true},
+		{name: "response body wrapped by another middleware", wrapResponse: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dispatcher := NewDispatcher(nil)
+			if tt.wrapResponse {
+				dispatcher.Use(func(next Handler) Handler {
+					return HandlerFunc(func(client *http.Client, r *http.Request) (*http.Response, error) {
+						resp, err := next.Handle(client, r)
+						if err == nil {
+							resp.Body = struct{ io.ReadCloser }{resp.Body}
+						}
+						return resp, err
+					})
+				})
+			}
+			req := dispatcher.NewRequest()
+			if tt.useBodyBytes {
+				req.Use(BodyGetBytes(func() ([]byte, error) { return []byte(requestBody), nil }), func(next Handler) Handler {
+					return HandlerFunc(func(client *http.Client, r *http.Request) (*http.Response, error) {
+						body, err := r.GetBody()
+						if err != nil {
+							return nil, err
+						}
+						r.Body = body
+						return next.Handle(client, r)
+					})
+				})
+			} else {
+				req.Body(strings.NewReader(requestBody))
+			}
+
+			resp := req.Use(ComputeChecksums(func(o *ChecksumOptions) {
+				o.Request = []HashAlgorithm{SHA256, MD5}
+				o.Response = []HashAlgorithm{SHA256}
+			})).Post(server.URL)
+			defer resp.Close()
+
+			require.NoError(t, resp.Error)
+			assert.Equal(t, responseBody, resp.String())
+
+			checksums := resp.Checksums()
+			require.NotNil(t, checksums)
+			assert.Equal(t, hexSHA256(requestBody), checksums.Request(SHA256))
+			assert.Equal(t, hexMD5(requestBody), checksums.Request(MD5))
+			assert.Equal(t, hexSHA256(responseBody), checksums.Response(SHA256))
+			assert.Empty(t, checksums.Response(SHA1))
+		})
+	}
+}
+
+func TestResponse_Checksums_NotEnabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	resp := NewDispatcher(nil).NewRequest().Get(server.URL)
+	defer resp.Close()
+
+	require.NoError(t, resp.Error)
+	assert.Nil(t, resp.Checksums())
+}
@@ -0,0 +1,343 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is matched by errors.Is for every CircuitOpenError.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// CircuitOpenError reports a request rejected without being sent because the
+// circuit of its host is open.
+type CircuitOpenError struct {
+	Host string
+	// RetryIn is the time until the circuit lets a probe request through.
+	RetryIn time.Duration
+}
+
+// Error returns the error message.
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit open for %s, retry in %s", e.Host, e.RetryIn.Round(time.Millisecond))
+}
+
+// Is reports whether target is ErrCircuitOpen.
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// CircuitState is the state of the circuit of one host.
+type CircuitState int
+
+const (
+	// CircuitClosed lets all requests through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects all requests with a CircuitOpenError.
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of probe requests through to
+	// find out whether the host recovered.
+	CircuitHalfOpen
+)
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// CircuitBreakerOptions configures a CircuitBreaker.
+type CircuitBreakerOptions struct {
+	// FailureThreshold is the number of consecutive failures that opens the
+	// circuit. Defaults to 5.
+	FailureThreshold int
+	// OpenTimeout is the time an open circuit rejects requests before it
+	// becomes half-open. Defaults to 30s.
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the number of probe requests let through while
+	// half-open; all of them must succeed to close the circuit and any
+	// failure opens it again. Defaults to 1.
+	HalfOpenProbes int
+	// IsFailure classifies a request outcome. Defaults to network errors
+	// other than cancellation and 5xx responses.
+	IsFailure func(resp *http.Response, err error) bool
+	// OnStateChange is called after the circuit of host changed state, e.g.
+	// to emit metrics. It must not block.
+	OnStateChange func(host string, from, to CircuitState)
+}
+
+// CircuitBreaker stops sending requests to hosts that keep failing, so a
+// flapping upstream gets time to recover instead of more traffic. Each host
+// has its own circuit. It is safe for concurrent use.
+type CircuitBreaker struct {
+	options *CircuitBreakerOptions
+	now     func() time.Time
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	state     CircuitState
+	failures  int
+	openedAt  time.Time
+	probes    int
+	successes int
+}
+
+type circuitTransition struct {
+	host     string
+	from, to CircuitState
+}
+
+// NewCircuitBreaker creates a CircuitBreaker with all circuits closed.
+//
+// Example:
+//
+//	breaker := fetch.NewCircuitBreaker(func(o *fetch.CircuitBreakerOptions) {
+//	    o.FailureThreshold = 3
+//	    o.OpenTimeout = 10 * time.Second
+//	    o.OnStateChange = func(host string, from, to fetch.CircuitState) {
+//	        circuitState.WithLabelValues(host).Set(float64(to))
+//	    }
+//	})
+//	dispatcher.Use(breaker.Middleware())
+func NewCircuitBreaker(opts ...func(*CircuitBreakerOptions)) *CircuitBreaker {
+	options := applyOptions(&CircuitBreakerOptions{
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+		HalfOpenProbes:   1,
+		IsFailure:        defaultCircuitFailure,
+	}, opts...)
+
+	return &CircuitBreaker{options: options, now: time.Now, circuits: map[string]*circuit{}}
+}
+
+func defaultCircuitFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode >= 500
+}
+
+// State returns the state of the circuit of host.
+func (b *CircuitBreaker) State(host string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[host]
+	if !ok {
+		return CircuitClosed
+	}
+	if c.state == CircuitOpen && b.now().Sub(c.openedAt) >= b.options.OpenTimeout {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+// CircuitEntry is the persisted state of the circuit of one host.
+type CircuitEntry struct {
+	Host     string       `json:"host"`
+	State    CircuitState `json:"state"`
+	Failures int          `json:"failures,omitempty"`
+	OpenedAt time.Time    `json:"opened_at,omitempty"`
+}
+
+// Entries returns the circuits that are open or counting failures, sorted by
+// host, e.g. to persist them across restarts. Half-open circuits are
+// reported as open, since their probes do not survive a restart.
+func (b *CircuitBreaker) Entries() []CircuitEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entries := make([]CircuitEntry, 0, len(b.circuits))
+	for host, c := range b.circuits {
+		switch {
+		case c.state != CircuitClosed:
+			entries = append(entries, CircuitEntry{Host: host, State: CircuitOpen, OpenedAt: c.openedAt})
+		case c.failures > 0:
+			entries = append(entries, CircuitEntry{Host: host, State: CircuitClosed, Failures: c.failures})
+		}
+	}
+	slices.SortFunc(entries, func(a, b CircuitEntry) int { return strings.Compare(a.Host, b.Host) })
+	return entries
+}
+
+// Load restores previously persisted circuits, replacing the circuits of the
+// same hosts. OnStateChange is not called.
+func (b *CircuitBreaker) Load(entries []CircuitEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, entry := range entries {
+		c := &circuit{state: CircuitClosed, failures: entry.Failures}
+		if entry.State != CircuitClosed {
+			c.state, c.failures, c.openedAt = CircuitOpen, 0, entry.OpenedAt
+		}
+		b.circuits[entry.Host] = c
+	}
+}
+
+// Middleware returns middleware that rejects requests to hosts with an open
+// circuit with a CircuitOpenError and records the outcome of the others.
+// Requests canceled by the caller are not counted. Add it before Retry so
+// that one exhausted request counts as one failure.
+func (b *CircuitBreaker) Middleware() Middleware {
+	return bypassDryRun(func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			host := req.URL.Host
+			probe, err := b.allow(host)
+			if err != nil {
+				return nil, err
+			}
+
+			// The probe slot is released unless the outcome is recorded, so a
+			// panicking handler cannot keep the circuit half-open forever.
+			recorded := false
+			defer func() {
+				if !recorded {
+					b.release(host, probe)
+				}
+			}()
+
+			resp, err := next.Handle(client, req)
+			if err != nil && req.Context().Err() != nil {
+				return resp, err
+			}
+			b.record(req.Context(), host, probe, resp, err)
+			recorded = true
+			return resp, err
+		})
+	})
+}
+
+// allow reports whether a request to host may be sent and whether it is a
+// half-open probe.
+func (b *CircuitBreaker) allow(host string) (bool, error) {
+	var transitions []circuitTransition
+	defer func() { b.notify(transitions) }()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[host]
+	if !ok {
+		c = &circuit{}
+		b.circuits[host] = c
+	}
+
+	switch c.state {
+	case CircuitClosed:
+		return false, nil
+	case CircuitOpen:
+		elapsed := b.now().Sub(c.openedAt)
+		if elapsed < b.options.OpenTimeout {
+			return false, &CircuitOpenError{Host: host, RetryIn: b.options.OpenTimeout - elapsed}
+		}
+		transitions = append(transitions, b.transition(host, c, CircuitHalfOpen))
+	}
+
+	if c.probes >= b.options.HalfOpenProbes-c.successes {
+		return false, &CircuitOpenError{Host: host}
+	}
+	c.probes++
+	return true, nil
+}
+
+// record counts the outcome of a request to host and publishes
+// CircuitOpened on the EventBus of ctx when it opens the circuit.
+func (b *CircuitBreaker) record(ctx context.Context, host string, probe bool, resp *http.Response, err error) {
+	failure := b.options.IsFailure(resp, err)
+
+	var transitions []circuitTransition
+	defer func() {
+		b.notify(transitions)
+		b.publish(ctx, transitions, resp, err)
+	}()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuits[host]
+	if probe {
+		c.probes--
+	}
+
+	switch {
+	case c.state == CircuitClosed && failure:
+		c.failures++
+		if c.failures >= b.options.FailureThreshold {
+			transitions = append(transitions, b.transition(host, c, CircuitOpen))
+		}
+	case c.state == CircuitClosed:
+		c.failures = 0
+	case c.state == CircuitHalfOpen && probe && failure:
+		transitions = append(transitions, b.transition(host, c, CircuitOpen))
+	case c.state == CircuitHalfOpen && probe:
+		c.successes++
+		if c.successes >= b.options.HalfOpenProbes {
+			transitions = append(transitions, b.transition(host, c, CircuitClosed))
+		}
+	}
+	// Outcomes of requests sent before the circuit opened are ignored.
+}
+
+// release frees the probe slot of a request that was not counted.
+func (b *CircuitBreaker) release(host string, probe bool) {
+	if !probe {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.circuits[host].probes--
+}
+
+// transition moves c to state; b.mu must be held.
+func (b *CircuitBreaker) transition(host string, c *circuit, state CircuitState) circuitTransition {
+	t := circuitTransition{host: host, from: c.state, to: state}
+	c.state = state
+	c.failures, c.successes = 0, 0
+	if state == CircuitOpen {
+		c.openedAt = b.now()
+	}
+	return t
+}
+
+// publish sends CircuitOpened for the transitions opening a circuit.
+func (b *CircuitBreaker) publish(ctx context.Context, transitions []circuitTransition, resp *http.Response, err error) {
+	bus := EventBusFromContext(ctx)
+	if bus == nil {
+		return
+	}
+	for _, t := range transitions {
+		if t.to != CircuitOpen {
+			continue
+		}
+		opened := CircuitOpened{Time: b.now(), Host: t.host, Err: err}
+		if resp != nil {
+			opened.StatusCode = resp.StatusCode
+		}
+		bus.Publish(opened)
+	}
+}
+
+func (b *CircuitBreaker) notify(transitions []circuitTransition) {
+	if b.options.OnStateChange == nil {
+		return
+	}
+	for _, t := range transitions {
+		b.options.OnStateChange(t.host, t.from, t.to)
+	}
+}
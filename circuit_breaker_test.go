@@ -0,0 +1,227 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	host := serverURL.Host
+
+	type change struct{ from, to CircuitState }
+	var changes []change
+	breaker := NewCircuitBreaker(func(o *CircuitBreakerOptions) {
+		o.FailureThreshold = 3
+		o.OpenTimeout = time.Minute
+		o.OnStateChange = func(h string, from, to CircuitState) {
+			assert.Equal(t, host, h)
+			changes = append(changes, change{from, to})
+		}
+	})
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+	dispatcher := NewDispatcher(nil, breaker.Middleware())
+
+	get := func() *Response {
+		resp := dispatcher.NewRequest().Get(server.URL)
+		resp.Close()
+		return resp
+	}
+
+	for range 3 {
+		require.NoError(t, get().Error)
+	}
+	assert.Equal(t, CircuitOpen, breaker.State(host))
+	assert.Equal(t, int32(3), calls.Load())
+
+	resp := get()
+	var openErr *CircuitOpenError
+	require.ErrorAs(t, resp.Error, &openErr)
+	assert.ErrorIs(t, resp.Error, ErrCircuitOpen)
+	assert.Equal(t, host, openErr.Host)
+	assert.Equal(t, time.Minute, openErr.RetryIn)
+	assert.Equal(t, int32(3), calls.Load())
+
+	// A failed probe opens the circuit again.
+	now = now.Add(time.Minute)
+	assert.Equal(t, CircuitHalfOpen, breaker.State(host))
+	require.NoError(t, get().Error)
+	assert.Equal(t, CircuitOpen, breaker.State(host))
+	assert.ErrorIs(t, get().Error, ErrCircuitOpen)
+
+	// A successful probe closes it.
+	now = now.Add(time.Minute)
+	status.Store(http.StatusOK)
+	require.NoError(t, get().Error)
+	assert.Equal(t, CircuitClosed, breaker.State(host))
+
+	assert.Equal(t, []change{
+		{CircuitClosed, CircuitOpen},
+		{CircuitOpen, CircuitHalfOpen},
+		{CircuitHalfOpen, CircuitOpen},
+		{CircuitOpen, CircuitHalfOpen},
+		{CircuitHalfOpen, CircuitClosed},
+	}, changes)
+}
+
+// recordOutcome records a failed or successful request to host.
+func recordOutcome(breaker *CircuitBreaker, host string, probe, failure bool) {
+	if failure {
+		breaker.record(context.Background(), host, probe, nil, errors.New("connection refused"))
+		return
+	}
+	breaker.record(context.Background(), host, probe, &http.Response{StatusCode: http.StatusOK}, nil)
+}
+
+func TestCircuitBreaker_Record(t *testing.T) {
+	tests := []struct {
+		name     string
+		outcomes []bool
+		want     CircuitState
+	}{
+		{name: "success resets failures", outcomes: []bool{true, true, false, true, true}, want: CircuitClosed},
+		{name: "consecutive failures open", outcomes: []bool{false, true, true, true}, want: CircuitOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker := NewCircuitBreaker(func(o *CircuitBreakerOptions) { o.FailureThreshold = 3 })
+			for _, failure := range tt.outcomes {
+				probe, err := breaker.allow("example.com")
+				require.NoError(t, err)
+				recordOutcome(breaker, "example.com", probe, failure)
+			}
+			assert.Equal(t, tt.want, breaker.State("example.com"))
+		})
+	}
+}
+
+func TestCircuitBreaker_HalfOpenProbes(t *testing.T) {
+	breaker := NewCircuitBreaker(func(o *CircuitBreakerOptions) {
+		o.FailureThreshold = 1
+		o.HalfOpenProbes = 2
+	})
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+
+	_, err := breaker.allow("h")
+	require.NoError(t, err)
+	recordOutcome(breaker, "h", false, true)
+	now = now.Add(breaker.options.OpenTimeout)
+
+	first, err := breaker.allow("h")
+	require.NoError(t, err)
+	second, err := breaker.allow("h")
+	require.NoError(t, err)
+	assert.True(t, first && second)
+	_, err = breaker.allow("h")
+	assert.ErrorIs(t, err, ErrCircuitOpen, "probe limit reached")
+
+	recordOutcome(breaker, "h", true, false)
+	assert.Equal(t, CircuitHalfOpen, breaker.State("h"))
+	recordOutcome(breaker, "h", true, false)
+	assert.Equal(t, CircuitClosed, breaker.State("h"))
+}
+
+func TestCircuitBreaker_CanceledNotCounted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	breaker := NewCircuitBreaker(func(o *CircuitBreakerOptions) { o.FailureThreshold = 1 })
+	dispatcher := NewDispatcher(nil, breaker.Middleware())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	_, err = dispatcher.Do(req)
+	require.Error(t, err)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, CircuitClosed, breaker.State(serverURL.Host))
+}
+
+func TestCircuitBreaker_PanicReleasesProbe(t *testing.T) {
+	breaker := NewCircuitBreaker(func(o *CircuitBreakerOptions) {
+		o.FailureThreshold = 1
+		o.HalfOpenProbes = 1
+	})
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+
+	_, err := breaker.allow("h")
+	require.NoError(t, err)
+	recordOutcome(breaker, "h", false, true)
+	now = now.Add(breaker.options.OpenTimeout)
+
+	handler := breaker.Middleware()(HandlerFunc(func(*http.Client, *http.Request) (*http.Response, error) {
+		panic("boom")
+	}))
+	req, err := http.NewRequest(http.MethodGet, "http://h/", nil)
+	require.NoError(t, err)
+	assert.PanicsWithValue(t, "boom", func() {
+		resp, err := handler.Handle(http.DefaultClient, req)
+		assert.Nil(t, resp)
+		assert.NoError(t, err)
+	})
+
+	probe, err := breaker.allow("h")
+	require.NoError(t, err, "probe slot should be released after a panic")
+	assert.True(t, probe)
+}
+
+func TestCircuitBreaker_PublishesCircuitOpened(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	bus := NewEventBus()
+	var opened []CircuitOpened
+	SubscribeTo(bus, func(e CircuitOpened) { opened = append(opened, e) })
+
+	breaker := NewCircuitBreaker(func(o *CircuitBreakerOptions) { o.FailureThreshold = 2 })
+	dispatcher := NewDispatcher(nil, bus.Middleware(), breaker.Middleware())
+
+	for range 3 {
+		resp := dispatcher.NewRequest().Get(server.URL)
+		resp.Close()
+	}
+
+	require.Len(t, opened, 1)
+	assert.Equal(t, serverURL.Host, opened[0].Host)
+	assert.Equal(t, http.StatusServiceUnavailable, opened[0].StatusCode)
+	assert.NoError(t, opened[0].Err)
+	assert.False(t, opened[0].Time.IsZero())
+}
+
+func TestCircuitState_String(t *testing.T) {
+	assert.Equal(t, "closed", CircuitClosed.String())
+	assert.Equal(t, "open", CircuitOpen.String())
+	assert.Equal(t, "half-open", CircuitHalfOpen.String())
+	assert.Equal(t, "CircuitState(7)", CircuitState(7).String())
+}
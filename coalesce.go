@@ -0,0 +1,187 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CoalesceOptions configures the Coalesce middleware.
+type CoalesceOptions struct {
+	// Methods are coalesced by method and URL alone. Defaults to GET and HEAD.
+	Methods []string
+	// KeyHeader marks other requests as safe to coalesce: requests with the
+	// same method, URL and value of this header share one upstream call.
+	// Defaults to Idempotency-Key; empty disables it.
+	KeyHeader string
+	// VaryHeaders are part of the key, so requests that differ in these
+	// headers, e.g. Accept, are never merged. The Authorization and Cookie
+	// headers are always part of the key.
+	VaryHeaders []string
+	// Key replaces the key derivation; requests for which it reports false
+	// are not coalesced.
+	Key func(req *http.Request) (string, bool)
+	// Timeout bounds the shared call, which does not end when the caller
+	// that started it gives up. Defaults to 30s.
+	Timeout time.Duration
+}
+
+// coalesceCredentialHeaders are part of every default key, so responses are
+// never shared between callers with different credentials.
+var coalesceCredentialHeaders = []string{"Authorization", "Cookie"}
+
+type coalescedCall struct {
+	done chan struct{}
+	resp *http.Response
+	body []byte
+	err  error
+}
+
+// response returns a private copy of the shared response.
+func (c *coalescedCall) response() *http.Response {
+	if c.resp == nil {
+		return nil
+	}
+
+	resp := *c.resp
+	resp.Header = c.resp.Header.Clone()
+	resp.Body = io.NopCloser(bytes.NewReader(c.body))
+	return &resp
+}
+
+// do performs the shared call with a context that outlives the caller that
+// started it, buffering the response body.
+func (c *coalescedCall) do(next Handler, client *http.Client, req *http.Request, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(req.Context()), timeout)
+	defer cancel()
+
+	c.resp, c.err = next.Handle(client, req.WithContext(ctx))
+	if c.err != nil {
+		return
+	}
+	c.body, c.err = io.ReadAll(c.resp.Body)
+	c.resp.Body.Close()
+	if c.err != nil {
+		c.resp = nil
+	}
+}
+
+// Coalesce creates middleware that merges identical in-flight requests into
+// a single upstream call whose response is shared by all callers. GET and HEAD
+// requests are merged by method and URL; any other request only when it carries
+// an Idempotency-Key header, so concurrent double-submits of the same operation
+// cause one side effect instead of several.
+//
+// The key header must already be set when the request reaches the middleware,
+// so place it after any middleware that adds the header. Share one Coalesce
+// middleware between the requests that should be merged.
+//
+// Shared response bodies are buffered in memory. The shared call runs
+// detached from the context of the caller that started it, bounded by
+// Timeout, so a caller whose context is canceled stops waiting without
+// affecting the other callers. A panic in the shared call is returned to
+// every caller as a PanicError.
+//
+// Example:
+//
+//	coalesce := fetch.Coalesce()
+//
+//	req.UseFuncs(func(r *http.Request) {
+//	    r.Header.Set("Idempotency-Key", orderID)
+//	}).Use(coalesce).JSON(order).Post(url)
+func Coalesce(opts ...func(*CoalesceOptions)) Middleware {
+	options := applyOptions(&CoalesceOptions{
+		Methods:   []string{http.MethodGet, http.MethodHead},
+		KeyHeader: "Idempotency-Key",
+		Timeout:   30 * time.Second,
+	}, opts...)
+
+	var (
+		mu    sync.Mutex
+		calls = map[string]*coalescedCall{}
+	)
+
+	coalesceKey := options.Key
+	if coalesceKey == nil {
+		coalesceKey = func(req *http.Request) (string, bool) {
+			var key strings.Builder
+			key.WriteString(req.Method + " " + req.URL.String())
+			for _, name := range append(slices.Clone(coalesceCredentialHeaders), options.VaryHeaders...) {
+				key.WriteString("\n" + name + ": " + strings.Join(req.Header.Values(name), ", "))
+			}
+			if slices.Contains(options.Methods, req.Method) {
+				return key.String(), true
+			}
+			if options.KeyHeader == "" {
+				return "", false
+			}
+			if value := req.Header.Get(options.KeyHeader); value != "" {
+				return key.String() + "\n" + value, true
+			}
+			return "", false
+		}
+	}
+
+	return bypassDryRun(func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			key, ok := coalesceKey(req)
+			if !ok {
+				return next.Handle(client, req)
+			}
+
+			mu.Lock()
+			call, ok := calls[key]
+			if !ok {
+				call = &coalescedCall{done: make(chan struct{})}
+				calls[key] = call
+				go func() {
+					defer func() {
+						if value := recover(); value != nil {
+							call.resp, call.err = nil, &PanicError{Value: value, Stack: debug.Stack()}
+						}
+						mu.Lock()
+						delete(calls, key)
+						mu.Unlock()
+						close(call.done)
+					}()
+					call.do(next, client, req, options.Timeout)
+				}()
+			}
+			mu.Unlock()
+
+			select {
+			case <-call.done:
+				return call.response(), call.err
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			}
+		})
+	})
+}
+
+// Dedupe creates Coalesce middleware that merges concurrent identical GET
+// requests only, keyed by URL, credentials and the Accept, Accept-Encoding
+// and Accept-Language headers, so responses are never
+// shared between callers with different credentials or content negotiation.
+// Every caller gets its own copy of the body. Options are applied on top,
+// e.g. to vary on more headers or to supply a Key function.
+//
+// Example:
+//
+//	dedupe := fetch.Dedupe(func(o *fetch.CoalesceOptions) {
+//	    o.VaryHeaders = append(o.VaryHeaders, "X-Tenant")
+//	})
+//	dispatcher.Use(dedupe)
+func Dedupe(opts ...func(*CoalesceOptions)) Middleware {
+	return Coalesce(append([]func(*CoalesceOptions){func(o *CoalesceOptions) {
+		o.Methods = []string{http.MethodGet}
+		o.KeyHeader = ""
+		o.VaryHeaders = []string{"Accept", "Accept-Encoding", "Accept-Language"}
+	}}, opts...)...)
+}
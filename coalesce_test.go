@@ -0,0 +1,248 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoalesce(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		keys      []string
+		wantCalls int32
+	}{
+		{name: "identical GETs", method: http.MethodGet, keys: []string{"", "", ""}, wantCalls: 1},
+		{name: "POST without key", method: http.MethodPost, keys: []string{"", "", ""}, wantCalls: 3},
+		{name: "POST with same key", method: http.MethodPost, keys: []string{"k1", "k1", "k1"}, wantCalls: 1},
+		{name: "POST with different keys", method: http.MethodPost, keys: []string{"k1", "k2", "k1"}, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			release := make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				<-release
+				w.Header().Set("X-Key", r.Header.Get("Idempotency-Key"))
+				w.Write([]byte("created"))
+			}))
+			defer server.Close()
+
+			coalesce := Coalesce()
+			dispatcher := NewDispatcher(nil)
+
+			var wg sync.WaitGroup
+			for _, key := range tt.keys {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					resp := dispatcher.NewRequest().UseFuncs(func(r *http.Request) {
+						if key != "" {
+							r.Header.Set("Idempotency-Key", key)
+						}
+					}).Use(coalesce).Send(tt.method, server.URL)
+					defer resp.Close()
+
+					if assert.NoError(t, resp.Error) {
+						assert.Equal(t, "created", resp.String())
+						assert.Equal(t, key, resp.Header.Get("X-Key"))
+					}
+				}()
+			}
+
+			// Give every request time to join an in-flight call.
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+			wg.Wait()
+
+			assert.Equal(t, tt.wantCalls, calls.Load())
+		})
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	ignoreQuery := func(o *CoalesceOptions) {
+		o.Key = func(r *http.Request) (string, bool) { return r.URL.Path, r.Method == http.MethodGet }
+	}
+
+	tests := []struct {
+		name      string
+		method    string
+		auth      []string
+		queries   []string
+		opts      []func(*CoalesceOptions)
+		wantCalls int32
+	}{
+		{name: "same credentials", method: http.MethodGet, auth: []string{"a", "a", "a"}, queries: []string{"", "", ""}, wantCalls: 1},
+		{name: "different credentials", method: http.MethodGet, auth: []string{"a", "b", "a"}, queries: []string{"", "", ""}, wantCalls: 2},
+		{name: "POST not deduplicated", method: http.MethodPost, auth: []string{"a", "a", "a"}, queries: []string{"", "", ""}, wantCalls: 3},
+		{name: "custom key", method: http.MethodGet, auth: []string{"a", "a", "a"}, queries: []string{"?x=1", "?x=2", "?x=3"}, opts: []func(*CoalesceOptions){ignoreQuery}, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			release := make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				<-release
+				w.Write([]byte("payload"))
+			}))
+			defer server.Close()
+
+			dedupe := Dedupe(tt.opts...)
+			dispatcher := NewDispatcher(nil)
+
+			var wg sync.WaitGroup
+			for i, auth := range tt.auth {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					resp := dispatcher.NewRequest().UseFuncs(func(r *http.Request) {
+						r.Header.Set("Authorization", auth)
+					}).Use(dedupe).Send(tt.method, server.URL+tt.queries[i])
+					defer resp.Close()
+
+					if assert.NoError(t, resp.Error) {
+						assert.Equal(t, "payload", resp.String())
+					}
+				}()
+			}
+
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+			wg.Wait()
+
+			assert.Equal(t, tt.wantCalls, calls.Load())
+		})
+	}
+}
+
+func TestCoalesce_Credentials(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		w.Write([]byte(r.Header.Get("Authorization") + r.Header.Get("Cookie")))
+	}))
+	defer server.Close()
+
+	coalesce := Coalesce()
+	dispatcher := NewDispatcher(nil)
+
+	credentials := []struct{ header, value string }{
+		{"Authorization", "alice"},
+		{"Authorization", "bob"},
+		{"Cookie", "session=a"},
+		{"Authorization", "alice"},
+	}
+	var wg sync.WaitGroup
+	for _, c := range credentials {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := dispatcher.NewRequest().UseFuncs(func(r *http.Request) {
+				r.Header.Set(c.header, c.value)
+			}).Use(coalesce).Get(server.URL)
+			defer resp.Close()
+
+			if assert.NoError(t, resp.Error) {
+				assert.Equal(t, c.value, resp.String())
+			}
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestCoalesce_StartingCallerCanceled(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		w.Write([]byte("shared"))
+	}))
+	defer server.Close()
+
+	coalesce := Coalesce()
+	dispatcher := NewDispatcher(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan *Response)
+	go func() {
+		first <- dispatcher.NewRequest().UseFuncs(func(r *http.Request) {
+			*r = *r.WithContext(ctx)
+		}).Use(coalesce).Get(server.URL)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	second := make(chan *Response)
+	go func() {
+		second <- dispatcher.NewRequest().Use(coalesce).Get(server.URL)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	canceled := <-first
+	assert.ErrorIs(t, canceled.Error, context.Canceled)
+
+	close(release)
+	resp := <-second
+	defer resp.Close()
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "shared", resp.String())
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestCoalesce_Panic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	var panicking atomic.Bool
+	panicking.Store(true)
+	boom := func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			if panicking.Load() {
+				panic("boom")
+			}
+			return next.Handle(client, req)
+		})
+	}
+
+	coalesce := Coalesce()
+	dispatcher := NewDispatcher(nil)
+
+	resp := dispatcher.NewRequest().Use(coalesce, boom).Get(server.URL)
+	assert.ErrorIs(t, resp.Error, ErrPanic)
+
+	// The failed call must not stay in flight for later callers.
+	panicking.Store(false)
+	done := make(chan *Response)
+	go func() { done <- dispatcher.NewRequest().Use(coalesce, boom).Get(server.URL) }()
+	select {
+	case resp := <-done:
+		defer resp.Close()
+		require.NoError(t, resp.Error)
+		assert.Equal(t, "ok", resp.String())
+	case <-time.After(time.Second):
+		t.Fatal("request after panic hung")
+	}
+}
@@ -0,0 +1,53 @@
+// Package compat adapts a fetch.Dispatcher to the interfaces of popular
+// community HTTP clients, so code written against them can move to this
+// package one call site at a time. The adapters mirror the method sets of
+// those libraries without importing them.
+package compat
+
+import (
+	"net/http"
+
+	"github.com/rockcookies/go-fetch"
+)
+
+// Doer is the single-method client interface used by heimdall and many other
+// libraries.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// NewDoer returns a Doer sending requests through dispatcher with the
+// additional middlewares.
+//
+// Example:
+//
+//	var doer heimdall.Doer = compat.NewDoer(dispatcher)
+//	client := httpclient.NewClient(httpclient.WithHTTPClient(doer))
+func NewDoer(dispatcher *fetch.Dispatcher, middlewares ...fetch.Middleware) Doer {
+	return &doer{dispatcher: dispatcher, middlewares: middlewares}
+}
+
+type doer struct {
+	dispatcher  *fetch.Dispatcher
+	middlewares []fetch.Middleware
+}
+
+func (d *doer) Do(req *http.Request) (*http.Response, error) {
+	return d.dispatcher.Do(req, d.middlewares...)
+}
+
+// NewTransport returns an http.RoundTripper sending requests through
+// dispatcher, for libraries that only accept an *http.Client. Redirects are
+// followed by the dispatcher's client.
+func NewTransport(dispatcher *fetch.Dispatcher, middlewares ...fetch.Middleware) http.RoundTripper {
+	return &transport{doer{dispatcher: dispatcher, middlewares: middlewares}}
+}
+
+type transport struct {
+	doer
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request it was given.
+	return t.Do(req.Clone(req.Context()))
+}
@@ -0,0 +1,57 @@
+package compat
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rockcookies/go-fetch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEchoServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		w.Header().Set("X-Content-Type", r.Header.Get("Content-Type"))
+		w.Header().Set("X-Trace", r.Header.Get("X-Trace"))
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func traceMiddleware(next fetch.Handler) fetch.Handler {
+	return fetch.HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+		req.Header.Set("X-Trace", "fetch")
+		return next.Handle(client, req)
+	})
+}
+
+func TestNewDoer(t *testing.T) {
+	server := newEchoServer(t)
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	resp, err := NewDoer(fetch.NewDispatcher(nil), traceMiddleware).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "fetch", resp.Header.Get("X-Trace"))
+}
+
+func TestNewTransport(t *testing.T) {
+	server := newEchoServer(t)
+	client := &http.Client{Transport: NewTransport(fetch.NewDispatcher(nil), traceMiddleware)}
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "fetch", resp.Header.Get("X-Trace"))
+	assert.Empty(t, req.Header.Get("X-Trace"), "caller's request must not be modified")
+}
@@ -0,0 +1,63 @@
+package compat
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/rockcookies/go-fetch"
+)
+
+// HeimdallClient mirrors the request methods of heimdall's httpclient.Client
+// interface. Retries, timeouts and circuit breaking are configured as
+// middlewares on the dispatcher instead of heimdall plugins, so AddPlugin is
+// not provided.
+type HeimdallClient struct {
+	doer
+}
+
+// NewHeimdallClient returns a HeimdallClient sending requests through
+// dispatcher with the additional middlewares.
+//
+// Example:
+//
+//	client := compat.NewHeimdallClient(dispatcher)
+//	resp, err := client.Get("https://api.example.com/users", http.Header{"Accept": {"application/json"}})
+func NewHeimdallClient(dispatcher *fetch.Dispatcher, middlewares ...fetch.Middleware) *HeimdallClient {
+	return &HeimdallClient{doer{dispatcher: dispatcher, middlewares: middlewares}}
+}
+
+// Get sends a GET request with the given headers.
+func (c *HeimdallClient) Get(url string, headers http.Header) (*http.Response, error) {
+	return c.send(http.MethodGet, url, nil, headers)
+}
+
+// Post sends a POST request with the given body and headers.
+func (c *HeimdallClient) Post(url string, body io.Reader, headers http.Header) (*http.Response, error) {
+	return c.send(http.MethodPost, url, body, headers)
+}
+
+// Put sends a PUT request with the given body and headers.
+func (c *HeimdallClient) Put(url string, body io.Reader, headers http.Header) (*http.Response, error) {
+	return c.send(http.MethodPut, url, body, headers)
+}
+
+// Patch sends a PATCH request with the given body and headers.
+func (c *HeimdallClient) Patch(url string, body io.Reader, headers http.Header) (*http.Response, error) {
+	return c.send(http.MethodPatch, url, body, headers)
+}
+
+// Delete sends a DELETE request with the given headers.
+func (c *HeimdallClient) Delete(url string, headers http.Header) (*http.Response, error) {
+	return c.send(http.MethodDelete, url, nil, headers)
+}
+
+func (c *HeimdallClient) send(method, url string, body io.Reader, headers http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range headers {
+		req.Header[name] = append(req.Header[name], values...)
+	}
+	return c.Do(req)
+}
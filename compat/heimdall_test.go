@@ -0,0 +1,51 @@
+package compat
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rockcookies/go-fetch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeimdallClient(t *testing.T) {
+	server := newEchoServer(t)
+	client := NewHeimdallClient(fetch.NewDispatcher(nil), traceMiddleware)
+	headers := http.Header{"Content-Type": {"text/plain"}}
+
+	tests := []struct {
+		name   string
+		method string
+		call   func() (*http.Response, error)
+		body   string
+	}{
+		{name: "get", method: "GET", call: func() (*http.Response, error) { return client.Get(server.URL, headers) }},
+		{name: "post", method: "POST", body: "created", call: func() (*http.Response, error) {
+			return client.Post(server.URL, strings.NewReader("created"), headers)
+		}},
+		{name: "put", method: "PUT", body: "replaced", call: func() (*http.Response, error) {
+			return client.Put(server.URL, strings.NewReader("replaced"), headers)
+		}},
+		{name: "patch", method: "PATCH", body: "patched", call: func() (*http.Response, error) {
+			return client.Patch(server.URL, strings.NewReader("patched"), headers)
+		}},
+		{name: "delete", method: "DELETE", call: func() (*http.Response, error) { return client.Delete(server.URL, headers) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.call()
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tt.method, resp.Header.Get("X-Method"))
+			assert.Equal(t, "text/plain", resp.Header.Get("X-Content-Type"))
+			assert.Equal(t, "fetch", resp.Header.Get("X-Trace"))
+			assert.Equal(t, tt.body, string(body))
+		})
+	}
+}
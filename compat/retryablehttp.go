@@ -0,0 +1,89 @@
+package compat
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rockcookies/go-fetch"
+)
+
+// RetryableClient mirrors the convenience methods of go-retryablehttp's
+// Client. Requests are plain *http.Request values and retries are configured
+// as middlewares on the dispatcher.
+type RetryableClient struct {
+	doer
+}
+
+// NewRetryableClient returns a RetryableClient sending requests through
+// dispatcher with the additional middlewares.
+//
+// Example:
+//
+//	client := compat.NewRetryableClient(dispatcher)
+//	resp, err := client.Post(url, "application/json", payload)
+//
+//	// Libraries expecting an *http.Client:
+//	sdk := thirdparty.New(client.StandardClient())
+func NewRetryableClient(dispatcher *fetch.Dispatcher, middlewares ...fetch.Middleware) *RetryableClient {
+	return &RetryableClient{doer{dispatcher: dispatcher, middlewares: middlewares}}
+}
+
+// Get sends a GET request.
+func (c *RetryableClient) Get(url string) (*http.Response, error) {
+	return c.send(http.MethodGet, url, "", nil)
+}
+
+// Head sends a HEAD request.
+func (c *RetryableClient) Head(url string) (*http.Response, error) {
+	return c.send(http.MethodHead, url, "", nil)
+}
+
+// Post sends a POST request with the given Content-Type. Like retryablehttp,
+// body may be nil, a []byte, string, *bytes.Buffer, *bytes.Reader, any
+// io.Reader, or a func() (io.Reader, error) called once per attempt.
+func (c *RetryableClient) Post(url, bodyType string, body any) (*http.Response, error) {
+	return c.send(http.MethodPost, url, bodyType, body)
+}
+
+// PostForm sends a POST request with data URL-encoded as the body.
+func (c *RetryableClient) PostForm(url string, data url.Values) (*http.Response, error) {
+	return c.Post(url, "application/x-www-form-urlencoded", data.Encode())
+}
+
+// StandardClient returns an *http.Client sending requests through the
+// dispatcher, for code that only accepts the standard client.
+func (c *RetryableClient) StandardClient() *http.Client {
+	return &http.Client{Transport: &transport{c.doer}}
+}
+
+func (c *RetryableClient) send(method, url, bodyType string, body any) (*http.Response, error) {
+	var reader io.Reader
+	var middlewares []fetch.Middleware
+	switch body := body.(type) {
+	case nil:
+	case []byte:
+		reader = bytes.NewReader(body)
+	case string:
+		reader = strings.NewReader(body)
+	case func() (io.Reader, error):
+		middlewares = append(middlewares, fetch.BodyGetReader(body))
+	case io.Reader:
+		reader = body
+	default:
+		return nil, fmt.Errorf("compat: cannot handle body of type %T", body)
+	}
+
+	// http.NewRequest makes in-memory bodies replayable for retries.
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	if bodyType != "" {
+		req.Header.Set("Content-Type", bodyType)
+	}
+	return c.dispatcher.Do(req, append(middlewares, c.middlewares...)...)
+}
@@ -0,0 +1,77 @@
+package compat
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/rockcookies/go-fetch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryableClient_Post(t *testing.T) {
+	server := newEchoServer(t)
+	client := NewRetryableClient(fetch.NewDispatcher(nil))
+
+	tests := []struct {
+		name    string
+		body    any
+		want    string
+		wantErr string
+	}{
+		{name: "nil", body: nil, want: ""},
+		{name: "bytes", body: []byte("bytes"), want: "bytes"},
+		{name: "string", body: "string", want: "string"},
+		{name: "buffer", body: bytes.NewBufferString("buffer"), want: "buffer"},
+		{name: "reader", body: io.NopCloser(strings.NewReader("reader")), want: "reader"},
+		{name: "reader func", body: func() (io.Reader, error) { return strings.NewReader("func"), nil }, want: "func"},
+		{name: "unsupported", body: 42, wantErr: "cannot handle body of type int"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.Post(server.URL, "text/plain", tt.body)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body, _ := io.ReadAll(resp.Body)
+			assert.Equal(t, tt.want, string(body))
+			assert.Equal(t, "text/plain", resp.Header.Get("X-Content-Type"))
+		})
+	}
+}
+
+func TestRetryableClient(t *testing.T) {
+	server := newEchoServer(t)
+	client := NewRetryableClient(fetch.NewDispatcher(nil), traceMiddleware)
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "GET", resp.Header.Get("X-Method"))
+	assert.Equal(t, "fetch", resp.Header.Get("X-Trace"))
+
+	resp, err = client.Head(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "HEAD", resp.Header.Get("X-Method"))
+
+	resp, err = client.PostForm(server.URL, url.Values{"a": {"1"}})
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "a=1", string(body))
+	assert.Equal(t, "application/x-www-form-urlencoded", resp.Header.Get("X-Content-Type"))
+
+	resp, err = client.StandardClient().Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "fetch", resp.Header.Get("X-Trace"))
+}
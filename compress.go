@@ -0,0 +1,144 @@
+package fetch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// CompressionOptions configures request body compression.
+type CompressionOptions struct {
+	// MinSize is the smallest body, in bytes, that is compressed. Defaults to 1024.
+	MinSize int
+	// Level is the gzip compression level. Defaults to gzip.DefaultCompression.
+	Level int
+	// Hosts holds per-host capability hints keyed by host name. true forces
+	// compression for the host, false disables it. Hosts without a hint are
+	// compressed until they answer 415 Unsupported Media Type.
+	Hosts map[string]bool
+	// SkipContentTypes lists media types, or type prefixes ending in "/",
+	// whose bodies are already compressed and are sent as-is. Defaults to
+	// images, audio, video and common archive formats.
+	SkipContentTypes []string
+}
+
+// CompressRequest creates middleware that gzip-compresses request bodies and
+// sets Content-Encoding: gzip. Bodies smaller than MinSize, bodies that already
+// carry a Content-Encoding and already-compressed content types are left alone.
+//
+// Compression of requests is negotiated as described in RFC 7694: when a host
+// without a capability hint rejects a compressed body with 415, the host is
+// remembered as unsupported and the request is re-sent uncompressed.
+//
+// The middleware reads the body, so it must be added after the body middleware:
+//
+//	req.JSON(payload).Use(fetch.CompressRequest())
+func CompressRequest(opts ...func(*CompressionOptions)) Middleware {
+	options := applyOptions(&CompressionOptions{
+		MinSize: 1024,
+		Level:   gzip.DefaultCompression,
+		SkipContentTypes: []string{
+			"image/",
+			"video/",
+			"audio/",
+			"application/gzip",
+			"application/zip",
+			"application/x-7z-compressed",
+			"application/x-bzip2",
+			"application/x-xz",
+			"application/zstd",
+		},
+	}, opts...)
+
+	var unsupported sync.Map
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			host := req.URL.Hostname()
+			hint, hinted := options.Hosts[host]
+			if hinted && !hint {
+				return next.Handle(client, req)
+			}
+			if _, ok := unsupported.Load(host); ok && !hinted {
+				return next.Handle(client, req)
+			}
+			if req.Header.Get("Content-Encoding") != "" || skipContentType(req.Header.Get("Content-Type"), options.SkipContentTypes) {
+				return next.Handle(client, req)
+			}
+
+			body, err := readRequestBody(req)
+			if err != nil {
+				return nil, err
+			}
+			closeReplacedBody(req)
+			if len(body) < options.MinSize {
+				setBufferedBody(req, body)
+				return next.Handle(client, req)
+			}
+
+			compressed, err := gzipBytes(body, options.Level)
+			if err != nil {
+				return nil, err
+			}
+
+			plain := req.Clone(req.Context())
+
+			setBufferedBody(req, compressed)
+			req.Header.Set("Content-Encoding", "gzip")
+
+			resp, err := next.Handle(client, req)
+			if err != nil || hinted || resp.StatusCode != http.StatusUnsupportedMediaType {
+				return resp, err
+			}
+
+			// The server does not accept compressed requests: remember and retry plain.
+			unsupported.Store(host, struct{}{})
+			drainAndClose(resp)
+
+			setBufferedBody(plain, body)
+			return next.Handle(client, plain)
+		})
+	}
+}
+
+func gzipBytes(data []byte, level int) ([]byte, error) {
+	var buf bytes.Buffer
+
+	zw, err := gzip.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func skipContentType(contentType string, skip []string) bool {
+	if contentType == "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+
+	for _, s := range skip {
+		if strings.HasSuffix(s, "/") && strings.HasPrefix(mediaType, s) {
+			return true
+		}
+		if mediaType == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,154 @@
+package fetch
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressRequest(t *testing.T) {
+	large := strings.Repeat("a", 2048)
+
+	tests := []struct {
+		name         string
+		body         string
+		contentType  string
+		opts         []func(*CompressionOptions)
+		wantEncoding string
+	}{
+		{
+			name:         "large body is compressed",
+			body:         large,
+			contentType:  "application/json",
+			wantEncoding: "gzip",
+		},
+		{
+			name:         "small body is sent as-is",
+			body:         "small",
+			contentType:  "application/json",
+			wantEncoding: "",
+		},
+		{
+			name:         "compressed content type is skipped",
+			body:         large,
+			contentType:  "image/png",
+			wantEncoding: "",
+		},
+		{
+			name:        "host hint disables compression",
+			body:        large,
+			contentType: "application/json",
+			opts: []func(*CompressionOptions){
+				func(o *CompressionOptions) { o.Hosts = map[string]bool{"127.0.0.1": false} },
+			},
+			wantEncoding: "",
+		},
+		{
+			name:        "custom min size",
+			body:        "tiny body",
+			contentType: "text/plain",
+			opts: []func(*CompressionOptions){
+				func(o *CompressionOptions) { o.MinSize = 1 },
+			},
+			wantEncoding: "gzip",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotEncoding, gotBody string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotEncoding = r.Header.Get("Content-Encoding")
+
+				var reader io.Reader = r.Body
+				if gotEncoding == "gzip" {
+					zr, err := gzip.NewReader(r.Body)
+					require.NoError(t, err)
+					reader = zr
+				}
+				body, err := io.ReadAll(reader)
+				require.NoError(t, err)
+				gotBody = string(body)
+			}))
+			defer server.Close()
+
+			resp := NewDispatcher(nil).NewRequest().
+				Body(strings.NewReader(tt.body), func(o *BodyOptions) { o.ContentType = tt.contentType }).
+				Use(CompressRequest(tt.opts...)).
+				Post(server.URL)
+			defer resp.Close()
+
+			require.NoError(t, resp.Error)
+			assert.Equal(t, tt.wantEncoding, gotEncoding)
+			assert.Equal(t, tt.body, gotBody)
+		})
+	}
+}
+
+func TestCompressRequest_ClosesOriginalBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "compressed", body: strings.Repeat("a", 2048)},
+		{name: "below min size", body: "small"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := &closeTrackingBody{Reader: strings.NewReader(tt.body)}
+			req, err := http.NewRequest(http.MethodPost, server.URL, original)
+			require.NoError(t, err)
+			req.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(strings.NewReader(tt.body)), nil
+			}
+
+			resp, err := NewDispatcher(nil, CompressRequest()).Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.True(t, original.closed.Load())
+		})
+	}
+}
+
+func TestCompressRequest_UnsupportedHost(t *testing.T) {
+	var compressedCalls, plainCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "" {
+			compressedCalls.Add(1)
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		plainCalls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	body := strings.Repeat("b", 4096)
+	dispatcher := NewDispatcher(nil)
+	compress := CompressRequest()
+
+	for i := 0; i < 2; i++ {
+		resp := dispatcher.NewRequest().Body(strings.NewReader(body)).Use(compress).Post(server.URL)
+		require.NoError(t, resp.Error)
+		assert.Equal(t, http.StatusOK, resp.RawResponse.StatusCode)
+		assert.Equal(t, body, resp.String())
+	}
+
+	assert.Equal(t, int32(1), compressedCalls.Load(), "host should be remembered as unsupported")
+	assert.Equal(t, int32(2), plainCalls.Load())
+}
@@ -0,0 +1,288 @@
+package fetch
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrGoAway is matched by errors.Is for every GoAwayError.
+var ErrGoAway = errors.New("server sent GOAWAY")
+
+// GoAwayError reports a request that failed because the server shut down its
+// HTTP/2 connection with a GOAWAY frame. Requests the server did not process
+// are retried by the transport itself, so this error means the request may
+// have reached the server.
+type GoAwayError struct {
+	Host string
+	Err  error
+}
+
+// Error returns the error message.
+func (e *GoAwayError) Error() string {
+	return e.Host + " sent GOAWAY: " + e.Err.Error()
+}
+
+// Is reports whether target is ErrGoAway.
+func (e *GoAwayError) Is(target error) bool {
+	return target == ErrGoAway
+}
+
+// Unwrap returns the transport error.
+func (e *GoAwayError) Unwrap() error {
+	return e.Err
+}
+
+// ConnectionShutdownReason tells why a connection ended.
+type ConnectionShutdownReason string
+
+const (
+	// ShutdownGoAway means the server sent an HTTP/2 GOAWAY frame.
+	ShutdownGoAway ConnectionShutdownReason = "goaway"
+	// ShutdownConnectionClose means the server answered with
+	// "Connection: close".
+	ShutdownConnectionClose ConnectionShutdownReason = "connection_close"
+	// ShutdownRotated means the ConnectionLifecycle closed the connection
+	// after MaxRequests requests or MaxAge.
+	ShutdownRotated ConnectionShutdownReason = "rotated"
+)
+
+// ConnectionShutdownStats counts the connection shutdowns of one host.
+type ConnectionShutdownStats struct {
+	GoAway          uint64 `json:"goaway"`
+	ConnectionClose uint64 `json:"connection_close"`
+	Rotated         uint64 `json:"rotated"`
+}
+
+// ConnectionLifecycleOptions configures a ConnectionLifecycle.
+type ConnectionLifecycleOptions struct {
+	// MaxRequests closes a connection after it carried this many requests.
+	// Zero means no limit.
+	MaxRequests int
+	// MaxAge closes a connection once it was first used this long ago.
+	// Zero means no limit.
+	MaxAge time.Duration
+}
+
+// ConnectionLifecycle counts the connections servers shut down with GOAWAY or
+// "Connection: close" per host and rotates connections after a number of
+// requests or a maximum age, which some load balancers require to spread
+// traffic evenly across their backends. Every shutdown is published as a
+// ConnectionShutdown event on the request's EventBus, and requests failing
+// because of a GOAWAY return a GoAwayError.
+//
+// A rotated connection is closed once the body of the response that reached
+// the limit was closed. Connections are only rotated when the client's
+// transport is an *http.Transport, whose dialers are wrapped to know when a
+// connection closes. HTTP/2 connections are shared by concurrent requests
+// and are left open; only their shutdowns are counted. It is safe for
+// concurrent use.
+type ConnectionLifecycle struct {
+	options    *ConnectionLifecycleOptions
+	now        func() time.Time
+	transports transportCache
+
+	mu    sync.Mutex
+	conns map[*lifecycleConn]*connectionUsage
+	stats map[string]*ConnectionShutdownStats
+}
+
+type connectionUsage struct {
+	first    time.Time
+	requests int
+}
+
+// NewConnectionLifecycle creates a ConnectionLifecycle with the given options.
+//
+// Example:
+//
+//	lifecycle := fetch.NewConnectionLifecycle(func(o *fetch.ConnectionLifecycleOptions) {
+//	    o.MaxRequests = 1000
+//	    o.MaxAge = 5 * time.Minute
+//	})
+//	dispatcher.Use(bus.Middleware(), lifecycle.Middleware())
+func NewConnectionLifecycle(opts ...func(*ConnectionLifecycleOptions)) *ConnectionLifecycle {
+	return &ConnectionLifecycle{
+		options: applyOptions(&ConnectionLifecycleOptions{}, opts...),
+		now:     time.Now,
+		conns:   map[*lifecycleConn]*connectionUsage{},
+		stats:   map[string]*ConnectionShutdownStats{},
+	}
+}
+
+// Middleware creates middleware tracking the connections of requests.
+func (l *ConnectionLifecycle) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			if _, ok := client.Transport.(*http.Transport); ok || client.Transport == nil {
+				l.transports.apply(client, l.trackConnections)
+			}
+			client.Transport = &connectionLifecycleTransport{base: client.Transport, lifecycle: l}
+			return next.Handle(client, req)
+		})
+	}
+}
+
+// Stats returns the connection shutdown counts per host name.
+func (l *ConnectionLifecycle) Stats() map[string]ConnectionShutdownStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := make(map[string]ConnectionShutdownStats, len(l.stats))
+	for host, s := range l.stats {
+		stats[host] = *s
+	}
+	return stats
+}
+
+// trackConnections wraps the dialers of transport so its connections are
+// forgotten when they close.
+func (l *ConnectionLifecycle) trackConnections(transport *http.Transport) {
+	track := func(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dial(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return &lifecycleConn{Conn: conn, lifecycle: l}, nil
+		}
+	}
+
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+	transport.DialContext = track(dial)
+	if transport.DialTLSContext != nil {
+		transport.DialTLSContext = track(transport.DialTLSContext)
+	}
+}
+
+// lifecycleConn forgets the usage of the connection when it closes.
+type lifecycleConn struct {
+	net.Conn
+	lifecycle *ConnectionLifecycle
+}
+
+func (c *lifecycleConn) Close() error {
+	c.lifecycle.forget(c)
+	return c.Conn.Close()
+}
+
+// trackedConn returns the lifecycleConn conn was dialed as, or nil when the
+// connection is not tracked.
+func trackedConn(conn net.Conn) *lifecycleConn {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	tracked, _ := conn.(*lifecycleConn)
+	return tracked
+}
+
+// use counts a request on conn and reports whether the connection reached
+// its limits.
+func (l *ConnectionLifecycle) use(conn *lifecycleConn) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	usage, ok := l.conns[conn]
+	if !ok {
+		usage = &connectionUsage{first: now}
+		l.conns[conn] = usage
+	}
+	usage.requests++
+
+	return (l.options.MaxRequests > 0 && usage.requests >= l.options.MaxRequests) ||
+		(l.options.MaxAge > 0 && now.Sub(usage.first) >= l.options.MaxAge)
+}
+
+func (l *ConnectionLifecycle) forget(conn *lifecycleConn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.conns, conn)
+}
+
+// shutdown counts the shutdown of a connection to the host of req and
+// publishes it.
+func (l *ConnectionLifecycle) shutdown(req *http.Request, reason ConnectionShutdownReason, err error) {
+	host := req.URL.Hostname()
+
+	l.mu.Lock()
+	stats, ok := l.stats[host]
+	if !ok {
+		stats = &ConnectionShutdownStats{}
+		l.stats[host] = stats
+	}
+	switch reason {
+	case ShutdownGoAway:
+		stats.GoAway++
+	case ShutdownConnectionClose:
+		stats.ConnectionClose++
+	case ShutdownRotated:
+		stats.Rotated++
+	}
+	l.mu.Unlock()
+
+	if bus := EventBusFromContext(req.Context()); bus != nil {
+		bus.Publish(ConnectionShutdown{Time: l.now(), Request: req, Host: host, Reason: reason, Err: err})
+	}
+}
+
+type connectionLifecycleTransport struct {
+	base      http.RoundTripper
+	lifecycle *ConnectionLifecycle
+}
+
+func (t *connectionLifecycleTransport) unwrap() http.RoundTripper { return t.base }
+
+func (t *connectionLifecycleTransport) rewrap(base http.RoundTripper) http.RoundTripper {
+	clone := *t
+	clone.base = base
+	return &clone
+}
+
+func (t *connectionLifecycleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	var conn net.Conn
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) { conn = info.Conn },
+	}
+	resp, err := base.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	if err != nil {
+		if isGoAway(err) {
+			t.lifecycle.shutdown(req, ShutdownGoAway, err)
+			return resp, &GoAwayError{Host: req.URL.Hostname(), Err: err}
+		}
+		return resp, err
+	}
+
+	tracked := trackedConn(conn)
+	switch {
+	case resp.Close:
+		t.lifecycle.shutdown(req, ShutdownConnectionClose, nil)
+	case tracked == nil || req.Close:
+	case t.lifecycle.use(tracked) && resp.ProtoMajor < 2:
+		t.lifecycle.shutdown(req, ShutdownRotated, nil)
+		resp.Body = &cleanupReadCloser{ReadCloser: resp.Body, cleanup: func() { tracked.Close() }}
+	}
+	return resp, nil
+}
+
+// isGoAway reports whether err comes from an HTTP/2 connection the server
+// shut down with GOAWAY. The HTTP/2 transport does not export its error
+// types, so the message is matched.
+func isGoAway(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "http2:") && strings.Contains(msg, "GOAWAY")
+}
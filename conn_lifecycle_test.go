@@ -0,0 +1,181 @@
+package fetch
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// connCountingServer returns a server counting the distinct client
+// connections its requests arrive on.
+func connCountingServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, func() int) {
+	var mu sync.Mutex
+	addrs := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		addrs[r.RemoteAddr] = true
+		mu.Unlock()
+		if handler != nil {
+			handler(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(addrs)
+	}
+}
+
+func TestConnectionLifecycle_MaxRequests(t *testing.T) {
+	server, conns := connCountingServer(t, nil)
+
+	lifecycle := NewConnectionLifecycle(func(o *ConnectionLifecycleOptions) {
+		o.MaxRequests = 2
+	})
+	bus := NewEventBus()
+	var events []ConnectionShutdown
+	SubscribeTo(bus, func(e ConnectionShutdown) { events = append(events, e) })
+	dispatcher := NewDispatcher(&http.Client{Transport: &http.Transport{}}, bus.Middleware(), lifecycle.Middleware())
+
+	for range 6 {
+		resp := dispatcher.NewRequest().Get(server.URL)
+		require.NoError(t, resp.Error)
+		resp.Close()
+	}
+
+	assert.Equal(t, 3, conns())
+	assert.Equal(t, map[string]ConnectionShutdownStats{"127.0.0.1": {Rotated: 3}}, lifecycle.Stats())
+	require.Len(t, events, 3)
+	assert.Equal(t, ShutdownRotated, events[0].Reason)
+	assert.Equal(t, "127.0.0.1", events[0].Host)
+}
+
+func TestConnectionLifecycle_MaxAge(t *testing.T) {
+	server, conns := connCountingServer(t, nil)
+
+	now := time.Now()
+	lifecycle := NewConnectionLifecycle(func(o *ConnectionLifecycleOptions) {
+		o.MaxAge = time.Minute
+	})
+	lifecycle.now = func() time.Time { return now }
+	dispatcher := NewDispatcher(&http.Client{Transport: &http.Transport{}}, lifecycle.Middleware())
+
+	get := func() {
+		resp := dispatcher.NewRequest().Get(server.URL)
+		require.NoError(t, resp.Error)
+		resp.Close()
+	}
+
+	get()
+	get()
+	assert.Equal(t, 1, conns())
+
+	now = now.Add(time.Minute)
+	get() // reaches the age and closes the connection
+	get()
+	assert.Equal(t, 2, conns())
+	assert.Equal(t, uint64(1), lifecycle.Stats()["127.0.0.1"].Rotated)
+}
+
+func TestConnectionLifecycle_ConnectionClose(t *testing.T) {
+	server, _ := connCountingServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Connection", "close")
+	})
+
+	lifecycle := NewConnectionLifecycle()
+	bus := NewEventBus()
+	var reasons []ConnectionShutdownReason
+	SubscribeTo(bus, func(e ConnectionShutdown) { reasons = append(reasons, e.Reason) })
+	dispatcher := NewDispatcher(nil, bus.Middleware(), lifecycle.Middleware())
+
+	for range 2 {
+		resp := dispatcher.NewRequest().Get(server.URL)
+		require.NoError(t, resp.Error)
+		resp.Close()
+	}
+
+	assert.Equal(t, []ConnectionShutdownReason{ShutdownConnectionClose, ShutdownConnectionClose}, reasons)
+	assert.Equal(t, uint64(2), lifecycle.Stats()["127.0.0.1"].ConnectionClose)
+}
+
+func TestConnectionLifecycle_GoAway(t *testing.T) {
+	goAway := errors.New(`http2: server sent GOAWAY and closed the connection; LastStreamID=1, ErrCode=NO_ERROR, debug=""`)
+	tests := []struct {
+		name       string
+		err        error
+		wantGoAway bool
+	}{
+		{name: "goaway", err: goAway, wantGoAway: true},
+		{name: "graceful goaway", err: errors.New("http2: Transport received Server's graceful shutdown GOAWAY"), wantGoAway: true},
+		{name: "other error", err: errors.New("connection refused")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failing := transportFunc(func(*http.Request) (*http.Response, error) { return nil, tt.err })
+			lifecycle := NewConnectionLifecycle()
+			dispatcher := NewDispatcherWithTransport(failing, lifecycle.Middleware())
+
+			resp := dispatcher.NewRequest().Get("https://api.example.com/items")
+			require.Error(t, resp.Error)
+			assert.ErrorIs(t, resp.Error, tt.err)
+			assert.Equal(t, tt.wantGoAway, errors.Is(resp.Error, ErrGoAway))
+
+			var goAwayErr *GoAwayError
+			if tt.wantGoAway {
+				require.ErrorAs(t, resp.Error, &goAwayErr)
+				assert.Equal(t, "api.example.com", goAwayErr.Host)
+				assert.Equal(t, uint64(1), lifecycle.Stats()["api.example.com"].GoAway)
+			} else {
+				assert.Empty(t, lifecycle.Stats())
+			}
+		})
+	}
+}
+
+func TestConnectionLifecycle_ForgetsClosedConnections(t *testing.T) {
+	tests := []struct {
+		name  string
+		close func(server *httptest.Server, dispatcher *Dispatcher)
+	}{
+		{name: "server closes idle connection", close: func(server *httptest.Server, _ *Dispatcher) {
+			server.CloseClientConnections()
+		}},
+		{name: "request closes connection", close: func(server *httptest.Server, dispatcher *Dispatcher) {
+			resp := dispatcher.NewRequest().Use(CloseConnection()).Get(server.URL)
+			resp.Close()
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := connCountingServer(t, nil)
+			lifecycle := NewConnectionLifecycle(func(o *ConnectionLifecycleOptions) {
+				o.MaxRequests = 100
+			})
+			dispatcher := NewDispatcher(&http.Client{Transport: &http.Transport{}}, lifecycle.Middleware())
+			tracked := func() int {
+				lifecycle.mu.Lock()
+				defer lifecycle.mu.Unlock()
+				return len(lifecycle.conns)
+			}
+
+			for range 3 {
+				resp := dispatcher.NewRequest().Get(server.URL)
+				require.NoError(t, resp.Error)
+				resp.Close()
+			}
+			require.Equal(t, 1, tracked())
+
+			tt.close(server, dispatcher)
+			assert.Eventually(t, func() bool { return tracked() == 0 }, time.Second, time.Millisecond)
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package fetch
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rockcookies/go-fetch/internal/utils"
+)
+
+var connectionStateKey = utils.NewContextKey[*connectionState]("connection_state")
+
+type connectionState struct {
+	closed atomic.Bool
+}
+
+// ConnectionPolicyOptions configures ConnectionPolicy.
+type ConnectionPolicyOptions struct {
+	// ServerErrors closes the connection after any 5xx response. Defaults to true.
+	ServerErrors bool
+	// AuthFailures closes the connection after 401 and 403 responses.
+	AuthFailures bool
+	// Hosts never reuse connections: requests to these hosts and their
+	// subdomains are sent with "Connection: close".
+	Hosts []string
+	// Close decides for other responses whether their connection is closed.
+	Close func(resp *http.Response) bool
+}
+
+// ConnectionPolicy creates middleware that stops reusing connections to a
+// host after a response matching the policy: the next request to that host
+// is sent with "Connection: close", so the connection it is sent on is closed
+// once it is answered instead of being returned to the pool. Requests to
+// Hosts always carry "Connection: close". Every redirect hop is checked;
+// Response.ConnectionClosed reports the outcome of the final one.
+//
+// HTTP/2 connections are shared by concurrent requests and are left open.
+//
+// Example:
+//
+//	dispatcher.Use(fetch.ConnectionPolicy(func(o *fetch.ConnectionPolicyOptions) {
+//	    o.AuthFailures = true
+//	    o.Hosts = []string{"legacy.example.com"}
+//	}))
+func ConnectionPolicy(opts ...func(*ConnectionPolicyOptions)) Middleware {
+	policy := &connectionPolicy{
+		options:  applyOptions(&ConnectionPolicyOptions{ServerErrors: true}, opts...),
+		draining: map[string]bool{},
+	}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			req = req.WithContext(connectionStateKey.WithValue(req.Context(), &connectionState{}))
+			client.Transport = &connectionPolicyTransport{base: client.Transport, policy: policy}
+			return next.Handle(client, req)
+		})
+	}
+}
+
+// CloseConnection creates middleware that sends the request with
+// "Connection: close", so its connection is not reused afterwards.
+func CloseConnection() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			req.Close = true
+			return next.Handle(client, req)
+		})
+	}
+}
+
+// ConnectionClosed reports whether the connection that carried the response
+// is not reused, because the request or the server asked for "Connection:
+// close", as ConnectionPolicy does for hosts it drains.
+func (r *Response) ConnectionClosed() bool {
+	if r.RawResponse == nil {
+		return false
+	}
+	if r.RawResponse.Close || (r.RawResponse.Request != nil && r.RawResponse.Request.Close) {
+		return true
+	}
+	if r.RawResponse.Request == nil {
+		return false
+	}
+	state, ok := connectionStateKey.GetValue(r.RawResponse.Request.Context())
+	return ok && state.closed.Load()
+}
+
+// connectionPolicy tracks the hosts whose next request closes its connection.
+type connectionPolicy struct {
+	options *ConnectionPolicyOptions
+
+	mu       sync.Mutex
+	draining map[string]bool
+}
+
+// drain makes the next request to host close its connection.
+func (p *connectionPolicy) drain(host string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.draining[host] = true
+}
+
+// takeDrain reports whether the next request to host closes its connection
+// and clears the mark.
+func (p *connectionPolicy) takeDrain(host string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.draining[host] {
+		return false
+	}
+	delete(p.draining, host)
+	return true
+}
+
+func (p *connectionPolicy) shouldClose(resp *http.Response) bool {
+	switch {
+	case p.options.ServerErrors && resp.StatusCode >= 500:
+		return true
+	case p.options.AuthFailures && (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden):
+		return true
+	case p.options.Close != nil:
+		return p.options.Close(resp)
+	}
+	return false
+}
+
+type connectionPolicyTransport struct {
+	base   http.RoundTripper
+	policy *connectionPolicy
+}
+
+func (t *connectionPolicyTransport) unwrap() http.RoundTripper { return t.base }
+
+func (t *connectionPolicyTransport) rewrap(base http.RoundTripper) http.RoundTripper {
+	clone := *t
+	clone.base = base
+	return &clone
+}
+
+func (t *connectionPolicyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	state, _ := connectionStateKey.GetValue(req.Context())
+	if state != nil {
+		state.closed.Store(false)
+	}
+
+	options := t.policy.options
+	if !req.Close && ((len(options.Hosts) > 0 && hostAllowed(req.URL.Hostname(), options.Hosts)) || t.policy.takeDrain(req.URL.Host)) {
+		req = req.Clone(req.Context())
+		req.Close = true
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	closed := req.Close || resp.Close
+	if !closed && resp.ProtoMajor < 2 && t.policy.shouldClose(resp) {
+		t.policy.drain(req.URL.Host)
+	}
+	if state != nil {
+		state.closed.Store(closed)
+	}
+	return resp, nil
+}
@@ -0,0 +1,89 @@
+package fetch
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionPolicy(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []func(*ConnectionPolicyOptions)
+		path string
+		// wantClosed lists ConnectionClosed of the first request and of two
+		// following requests to /ok.
+		wantClosed []bool
+		wantConns  int32
+	}{
+		{name: "ok reuses connection", path: "/ok", wantClosed: []bool{false, false, false}, wantConns: 1},
+		{name: "server error drains connection", path: "/fail", wantClosed: []bool{false, true, false}, wantConns: 2},
+		{name: "server errors disabled", path: "/fail", opts: []func(*ConnectionPolicyOptions){func(o *ConnectionPolicyOptions) { o.ServerErrors = false }}, wantClosed: []bool{false, false, false}, wantConns: 1},
+		{name: "auth failure kept by default", path: "/auth", wantClosed: []bool{false, false, false}, wantConns: 1},
+		{name: "auth failure drains connection", path: "/auth", opts: []func(*ConnectionPolicyOptions){func(o *ConnectionPolicyOptions) { o.AuthFailures = true }}, wantClosed: []bool{false, true, false}, wantConns: 2},
+		{name: "host flag", path: "/ok", opts: []func(*ConnectionPolicyOptions){func(o *ConnectionPolicyOptions) { o.Hosts = []string{"127.0.0.1"} }}, wantClosed: []bool{true, true, true}, wantConns: 3},
+		{name: "custom decision", path: "/drain", opts: []func(*ConnectionPolicyOptions){func(o *ConnectionPolicyOptions) {
+			o.Close = func(resp *http.Response) bool { return resp.Header.Get("X-Drain") == "1" }
+		}}, wantClosed: []bool{false, true, false}, wantConns: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conns atomic.Int32
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/fail":
+					w.WriteHeader(http.StatusBadGateway)
+				case "/auth":
+					w.WriteHeader(http.StatusUnauthorized)
+				case "/drain":
+					w.Header().Set("X-Drain", "1")
+				}
+				w.Write([]byte("body"))
+			}))
+			server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					conns.Add(1)
+				}
+			}
+			server.Start()
+			defer server.Close()
+
+			dispatcher := NewDispatcherWithTransport(&http.Transport{})
+			dispatcher.Use(ConnectionPolicy(tt.opts...))
+
+			for i, path := range []string{tt.path, "/ok", "/ok"} {
+				resp := dispatcher.NewRequest().Get(server.URL + path)
+				require.NoError(t, resp.Error)
+				assert.Equal(t, "body", resp.String())
+				assert.Equal(t, tt.wantClosed[i], resp.ConnectionClosed(), "request %d", i)
+			}
+			assert.Equal(t, tt.wantConns, conns.Load())
+		})
+	}
+}
+
+func TestCloseConnection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.ToLower(r.Header.Get("Connection"))))
+	}))
+	defer server.Close()
+
+	dispatcher := NewDispatcher(nil)
+
+	resp := dispatcher.NewRequest().Use(CloseConnection()).Get(server.URL)
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "close", resp.String())
+	assert.True(t, resp.ConnectionClosed())
+
+	kept := dispatcher.NewRequest().Get(server.URL)
+	require.NoError(t, kept.Error)
+	assert.False(t, kept.ConnectionClosed())
+	kept.Close()
+}
@@ -0,0 +1,105 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ConsulOptions configures a ConsulResolver.
+type ConsulOptions struct {
+	// Dispatcher sends the requests to the Consul agent. Defaults to
+	// NewDispatcher(nil).
+	Dispatcher *Dispatcher
+	// Token is sent as X-Consul-Token when set.
+	Token string
+	// Datacenter queries another datacenter than the agent's.
+	Datacenter string
+	// Tag only returns service instances with this tag.
+	Tag string
+}
+
+// ConsulResolver resolves services from the health endpoint of a Consul
+// agent. Only instances passing their health checks are returned, weighted
+// by their passing weight. It is a reference adapter using the HTTP API
+// directly, without the Consul client library.
+type ConsulResolver struct {
+	address string
+	options *ConsulOptions
+}
+
+// NewConsulResolver creates a resolver querying the Consul agent at address.
+//
+// Example:
+//
+//	consul := fetch.NewConsulResolver("http://127.0.0.1:8500", func(o *fetch.ConsulOptions) {
+//	    o.Token = os.Getenv("CONSUL_HTTP_TOKEN")
+//	    o.Tag = "v2"
+//	})
+//	endpoints, err := consul.Resolve(ctx, "payments")
+func NewConsulResolver(address string, opts ...func(*ConsulOptions)) *ConsulResolver {
+	options := applyOptions(&ConsulOptions{}, opts...)
+	if options.Dispatcher == nil {
+		options.Dispatcher = NewDispatcher(nil)
+	}
+	return &ConsulResolver{address: strings.TrimSuffix(normalize(address), "/"), options: options}
+}
+
+type consulServiceEntry struct {
+	Node struct {
+		Address string
+	}
+	Service struct {
+		Address string
+		Port    int
+		Weights struct {
+			Passing int
+		}
+	}
+}
+
+// Resolve returns the healthy instances of service.
+func (r *ConsulResolver) Resolve(ctx context.Context, service string) ([]ServiceEndpoint, error) {
+	query := url.Values{"passing": {"true"}}
+	if r.options.Datacenter != "" {
+		query.Set("dc", r.options.Datacenter)
+	}
+	if r.options.Tag != "" {
+		query.Set("tag", r.options.Tag)
+	}
+	endpoint := r.address + "/v1/health/service/" + url.PathEscape(service) + "?" + query.Encode()
+
+	resp := r.options.Dispatcher.NewRequest().
+		UseFuncs(func(req *http.Request) {
+			*req = *req.WithContext(ctx)
+			req.Header.Set("Accept", "application/json")
+			if r.options.Token != "" {
+				req.Header.Set("X-Consul-Token", r.options.Token)
+			}
+		}).
+		Get(endpoint)
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	defer resp.Close()
+
+	if resp.RawResponse.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("consul: %s", resp.RawResponse.Status)
+	}
+	var entries []consulServiceEntry
+	if err := resp.JSON(&entries); err != nil {
+		return nil, fmt.Errorf("consul: %w", err)
+	}
+
+	endpoints := make([]ServiceEndpoint, 0, len(entries))
+	for _, entry := range entries {
+		host := entry.Service.Address
+		if host == "" {
+			host = entry.Node.Address
+		}
+		endpoints = append(endpoints, ServiceEndpoint{Host: host, Port: entry.Service.Port, Weight: entry.Service.Weights.Passing})
+	}
+	return endpoints, nil
+}
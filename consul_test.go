@@ -0,0 +1,46 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsulResolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/health/service/payments":
+			assert.Equal(t, "true", r.URL.Query().Get("passing"))
+			assert.Equal(t, "eu-1", r.URL.Query().Get("dc"))
+			assert.Equal(t, "v2", r.URL.Query().Get("tag"))
+			assert.Equal(t, "secret", r.Header.Get("X-Consul-Token"))
+			w.Write([]byte(`[
+				{"Node": {"Address": "10.0.0.1"}, "Service": {"Address": "", "Port": 8080, "Weights": {"Passing": 3}}},
+				{"Node": {"Address": "10.0.0.2"}, "Service": {"Address": "10.1.0.2", "Port": 8081, "Weights": {"Passing": 1}}}
+			]`))
+		default:
+			http.Error(w, "unknown", http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	consul := NewConsulResolver(server.URL+"/", func(o *ConsulOptions) {
+		o.Token = "secret"
+		o.Datacenter = "eu-1"
+		o.Tag = "v2"
+	})
+
+	endpoints, err := consul.Resolve(context.Background(), "payments")
+	require.NoError(t, err)
+	assert.Equal(t, []ServiceEndpoint{
+		{Host: "10.0.0.1", Port: 8080, Weight: 3},
+		{Host: "10.1.0.2", Port: 8081, Weight: 1},
+	}, endpoints)
+
+	_, err = consul.Resolve(context.Background(), "billing")
+	assert.ErrorContains(t, err, "403 Forbidden")
+}
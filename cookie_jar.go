@@ -0,0 +1,77 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrCookieJarNotFound is returned for requests selecting a cookie jar name
+// that was not registered.
+var ErrCookieJarNotFound = errors.New("cookie jar not found")
+
+// RegisterCookieJar stores jar under name, so requests can select it with
+// Request.UseCookieJar. Separate jars keep the session cookies of different
+// logical identities apart on one dispatcher. This operation is safe for
+// concurrent use.
+//
+// Example:
+//
+//	adminJar, _ := cookiejar.New(nil)
+//	userJar, _ := cookiejar.New(nil)
+//	dispatcher.RegisterCookieJar("admin", adminJar)
+//	dispatcher.RegisterCookieJar("user", userJar)
+//
+//	dispatcher.NewRequest().UseCookieJar("admin").Post(loginURL)
+func (d *Dispatcher) RegisterCookieJar(name string, jar http.CookieJar) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.jars == nil {
+		d.jars = map[string]http.CookieJar{}
+	}
+	d.jars[name] = jar
+}
+
+// CookieJar returns the jar registered under name.
+func (d *Dispatcher) CookieJar(name string) (http.CookieJar, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	jar, ok := d.jars[name]
+	return jar, ok
+}
+
+// SetCookieJar creates middleware that sends the request with jar instead of
+// the client's jar. The jar is used for every redirect hop as well. A nil
+// jar disables cookie handling for the request.
+func SetCookieJar(jar http.CookieJar) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			client.Jar = jar
+			return next.Handle(client, req)
+		})
+	}
+}
+
+// SetCookieJar sends this request with jar. See SetCookieJar.
+func (r *Request) SetCookieJar(jar http.CookieJar) *Request {
+	return r.Use(SetCookieJar(jar))
+}
+
+// UseCookieJar sends this request with the jar registered under name on the
+// dispatcher. The jar is looked up when the request is sent; an unknown name
+// fails the request with ErrCookieJarNotFound.
+func (r *Request) UseCookieJar(name string) *Request {
+	dispatcher := r.dispatcher
+	return r.Use(func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			jar, ok := dispatcher.CookieJar(name)
+			if !ok {
+				return nil, fmt.Errorf("use %q: %w", name, ErrCookieJarNotFound)
+			}
+			client.Jar = jar
+			return next.Handle(client, req)
+		})
+	})
+}
@@ -0,0 +1,55 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCookieJars(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: r.URL.Query().Get("user"), Path: "/"})
+			// Cookies set on a redirect response must land in the same jar.
+			http.Redirect(w, r, "/whoami", http.StatusFound)
+		case "/whoami":
+			if cookie, err := r.Cookie("session"); err == nil {
+				w.Write([]byte(cookie.Value))
+			}
+		}
+	}))
+	defer server.Close()
+
+	newJar := func() http.CookieJar {
+		jar, err := cookiejar.New(nil)
+		require.NoError(t, err)
+		return jar
+	}
+
+	dispatcher := NewDispatcher(nil)
+	dispatcher.RegisterCookieJar("admin", newJar())
+	dispatcher.RegisterCookieJar("user", newJar())
+
+	assert.Equal(t, "root", dispatcher.NewRequest().UseCookieJar("admin").Get(server.URL+"/login?user=root").String())
+	assert.Equal(t, "alice", dispatcher.NewRequest().UseCookieJar("user").Get(server.URL+"/login?user=alice").String())
+
+	assert.Equal(t, "root", dispatcher.NewRequest().UseCookieJar("admin").Get(server.URL+"/whoami").String())
+	assert.Equal(t, "alice", dispatcher.NewRequest().UseCookieJar("user").Get(server.URL+"/whoami").String())
+	assert.Equal(t, "", dispatcher.NewRequest().Get(server.URL+"/whoami").String())
+
+	adhoc := newJar()
+	assert.Equal(t, "bob", dispatcher.NewRequest().SetCookieJar(adhoc).Get(server.URL+"/login?user=bob").String())
+	assert.Equal(t, "bob", dispatcher.NewRequest().SetCookieJar(adhoc).Get(server.URL+"/whoami").String())
+
+	clone := dispatcher.Clone()
+	_, ok := clone.CookieJar("admin")
+	assert.True(t, ok)
+
+	missing := dispatcher.NewRequest().UseCookieJar("guest").Get(server.URL + "/whoami")
+	assert.ErrorIs(t, missing.Error, ErrCookieJarNotFound)
+}
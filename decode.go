@@ -0,0 +1,48 @@
+package fetch
+
+import (
+	"bytes"
+	"io"
+	"slices"
+)
+
+// DecodeInto decodes the JSON body into primary and, when alsoRaw is not nil,
+// stores a copy of the raw body in it, reading the body only once. The body
+// stays buffered, so Bytes and String keep working afterwards.
+//
+// Example:
+//
+//	var user User
+//	var raw []byte
+//	if err := resp.DecodeInto(&user, &raw); err != nil {
+//	    log.Printf("bad payload: %s", raw)
+//	}
+func (r *Response) DecodeInto(primary any, alsoRaw *[]byte) error {
+	data := r.Bytes()
+	if r.Error != nil {
+		return r.Error
+	}
+	if alsoRaw != nil {
+		*alsoRaw = slices.Clone(data)
+	}
+	return r.JSONTargets(primary)
+}
+
+// JSONTargets decodes the JSON body into every target from a single read,
+// e.g. a typed struct and a map[string]any holding the fields the struct does
+// not declare. Strict decoding, see SetStrictJSONDecoding, only applies to
+// the first target. The body stays buffered like with DecodeInto.
+func (r *Response) JSONTargets(targets ...any) error {
+	data := r.Bytes()
+	if r.Error != nil {
+		return r.Error
+	}
+
+	for i, target := range targets {
+		err := decodeJSON(bytes.NewReader(data), target, i == 0 && r.strictJSON())
+		if err != nil && err != io.EOF {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponse_DecodeInto(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		strict   bool
+		wantUser user
+		wantErr  bool
+	}{
+		{name: "typed and raw", body: `{"name":"ada","extra":1}`, wantUser: user{Name: "ada"}},
+		{name: "empty body", body: ``},
+		{name: "invalid json keeps raw", body: `{"name":`, wantErr: true},
+		{name: "strict primary", body: `{"name":"ada","extra":1}`, strict: true, wantUser: user{Name: "ada"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			resp := NewDispatcher(nil, SetStrictJSONDecoding(tt.strict)).NewRequest().Get(server.URL)
+			defer resp.Close()
+
+			var u user
+			var raw []byte
+			err := resp.DecodeInto(&u, &raw)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantUser, u)
+			}
+			if tt.body != "" {
+				assert.Equal(t, tt.body, string(raw))
+			}
+			assert.Equal(t, tt.body, resp.String())
+		})
+	}
+}
+
+func TestResponse_JSONTargets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"ada","extra":{"a":1}}`))
+	}))
+	defer server.Close()
+
+	resp := NewDispatcher(nil, SetStrictJSONDecoding(true)).NewRequest().Get(server.URL)
+	defer resp.Close()
+
+	var typed struct {
+		Name  string `json:"name"`
+		Extra any    `json:"extra"`
+	}
+	var loose map[string]any
+	require.NoError(t, resp.JSONTargets(&typed, &loose))
+
+	assert.Equal(t, "ada", typed.Name)
+	assert.Equal(t, map[string]any{"name": "ada", "extra": map[string]any{"a": 1.0}}, loose)
+}
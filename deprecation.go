@@ -0,0 +1,182 @@
+package fetch
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Deprecation describes the deprecation state an upstream announced for an
+// endpoint through the Deprecation (RFC 9745), Sunset (RFC 8594) and Link
+// headers.
+type Deprecation struct {
+	// Deprecated is true when the response carried a Deprecation header.
+	Deprecated bool
+	// Date is when the endpoint was or will be deprecated. It is zero when
+	// the header did not carry a date, e.g. the legacy "Deprecation: true".
+	Date time.Time
+	// Sunset is when the endpoint stops responding, zero when unannounced.
+	Sunset time.Time
+	// Links are the targets of Link headers with rel="deprecation",
+	// usually human-readable migration notes.
+	Links []string
+	// SunsetLinks are the targets of Link headers with rel="sunset".
+	SunsetLinks []string
+}
+
+// ParseDeprecation reads the deprecation headers of h. It reports false when
+// h announces neither a deprecation nor a sunset.
+func ParseDeprecation(h http.Header) (Deprecation, bool) {
+	var d Deprecation
+
+	if value := strings.TrimSpace(h.Get("Deprecation")); value != "" {
+		d.Deprecated = value != "false"
+		d.Date = parseDeprecationDate(value)
+	}
+	if value := strings.TrimSpace(h.Get("Sunset")); value != "" {
+		d.Sunset, _ = http.ParseTime(value)
+	}
+
+	for _, link := range parseLinkHeader(h.Values("Link")) {
+		for _, rel := range strings.Fields(strings.ToLower(link.params["rel"])) {
+			switch rel {
+			case "deprecation":
+				d.Links = append(d.Links, link.target)
+			case "sunset":
+				d.SunsetLinks = append(d.SunsetLinks, link.target)
+			}
+		}
+	}
+
+	return d, d.Deprecated || !d.Sunset.IsZero()
+}
+
+// parseDeprecationDate accepts the structured field date "@<unix seconds>" of
+// RFC 9745 as well as the HTTP-date of earlier drafts.
+func parseDeprecationDate(value string) time.Time {
+	if seconds, ok := strings.CutPrefix(value, "@"); ok {
+		if unix, err := strconv.ParseInt(seconds, 10, 64); err == nil {
+			return time.Unix(unix, 0).UTC()
+		}
+		return time.Time{}
+	}
+	date, _ := http.ParseTime(value)
+	return date
+}
+
+// Deprecation returns the deprecation state announced by the response.
+// See ParseDeprecation.
+func (r *Response) Deprecation() (Deprecation, bool) {
+	if r.RawResponse == nil {
+		return Deprecation{}, false
+	}
+	return ParseDeprecation(r.RawResponse.Header)
+}
+
+// DeprecationOptions configures ReportDeprecations.
+type DeprecationOptions struct {
+	// Endpoint names the endpoint of a request; deprecations are reported
+	// once per name. Defaults to the method, host and path, e.g.
+	// "GET api.example.com/v1/users".
+	Endpoint func(req *http.Request) string
+	// OnDeprecatedEndpoint is called the first time an endpoint announces a
+	// deprecation or sunset, e.g. to log a warning.
+	OnDeprecatedEndpoint func(endpoint string, d Deprecation)
+	// OnDeprecatedResponse is called for every response announcing a
+	// deprecation or sunset, e.g. to count them in a metric.
+	OnDeprecatedResponse func(endpoint string, d Deprecation)
+}
+
+// ReportDeprecations creates middleware that watches responses for
+// deprecation and sunset announcements, so teams notice upstream API
+// sunsets before they break. Responses are passed through unchanged.
+//
+// Example:
+//
+//	dispatcher.Use(fetch.ReportDeprecations(func(o *fetch.DeprecationOptions) {
+//	    o.OnDeprecatedEndpoint = func(endpoint string, d fetch.Deprecation) {
+//	        slog.Warn("deprecated endpoint", "endpoint", endpoint, "sunset", d.Sunset, "docs", d.Links)
+//	    }
+//	    o.OnDeprecatedResponse = func(endpoint string, d fetch.Deprecation) {
+//	        deprecatedCalls.WithLabelValues(endpoint).Inc()
+//	    }
+//	}))
+func ReportDeprecations(opts ...func(*DeprecationOptions)) Middleware {
+	options := applyOptions(&DeprecationOptions{Endpoint: deprecationEndpoint}, opts...)
+	var reported sync.Map
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			resp, err := next.Handle(client, req)
+			if err != nil {
+				return resp, err
+			}
+
+			d, ok := ParseDeprecation(resp.Header)
+			if !ok {
+				return resp, nil
+			}
+
+			endpoint := options.Endpoint(req)
+			if options.OnDeprecatedResponse != nil {
+				options.OnDeprecatedResponse(endpoint, d)
+			}
+			if _, loaded := reported.LoadOrStore(endpoint, struct{}{}); !loaded && options.OnDeprecatedEndpoint != nil {
+				options.OnDeprecatedEndpoint(endpoint, d)
+			}
+			return resp, nil
+		})
+	}
+}
+
+func deprecationEndpoint(req *http.Request) string {
+	return req.Method + " " + req.URL.Host + req.URL.Path
+}
+
+type linkValue struct {
+	target string
+	params map[string]string
+}
+
+// parseLinkHeader parses RFC 8288 Link header values such as
+// `<https://example.com/docs>; rel="deprecation"; type="text/html"`.
+func parseLinkHeader(values []string) []linkValue {
+	var links []linkValue
+	for _, value := range values {
+		for value != "" {
+			start := strings.IndexByte(value, '<')
+			if start < 0 {
+				break
+			}
+			end := strings.IndexByte(value[start:], '>')
+			if end < 0 {
+				break
+			}
+			link := linkValue{target: value[start+1 : start+end], params: map[string]string{}}
+			value = value[start+end+1:]
+
+			// Parameters run until the next comma outside a quoted string.
+			quoted, cut := false, len(value)
+			for i := 0; i < len(value); i++ {
+				if value[i] == '"' {
+					quoted = !quoted
+				} else if value[i] == ',' && !quoted {
+					cut = i
+					break
+				}
+			}
+			for _, param := range strings.Split(value[:cut], ";") {
+				name, val, _ := strings.Cut(param, "=")
+				name = strings.ToLower(strings.TrimSpace(name))
+				if name != "" {
+					link.params[name] = strings.Trim(strings.TrimSpace(val), `"`)
+				}
+			}
+			links = append(links, link)
+			value = value[min(cut+1, len(value)):]
+		}
+	}
+	return links
+}
@@ -0,0 +1,105 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDeprecation(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   http.Header
+		expected Deprecation
+		ok       bool
+	}{
+		{name: "none", header: http.Header{}},
+		{
+			name:     "structured date",
+			header:   http.Header{"Deprecation": {"@1688169599"}},
+			expected: Deprecation{Deprecated: true, Date: time.Unix(1688169599, 0).UTC()},
+			ok:       true,
+		},
+		{
+			name:     "legacy true",
+			header:   http.Header{"Deprecation": {"true"}},
+			expected: Deprecation{Deprecated: true},
+			ok:       true,
+		},
+		{
+			name:     "legacy http date",
+			header:   http.Header{"Deprecation": {"Sun, 11 Nov 2018 23:59:59 GMT"}},
+			expected: Deprecation{Deprecated: true, Date: time.Date(2018, 11, 11, 23, 59, 59, 0, time.UTC)},
+			ok:       true,
+		},
+		{
+			name:     "sunset only",
+			header:   http.Header{"Sunset": {"Wed, 11 Nov 2026 23:59:59 GMT"}},
+			expected: Deprecation{Sunset: time.Date(2026, 11, 11, 23, 59, 59, 0, time.UTC)},
+			ok:       true,
+		},
+		{
+			name: "links",
+			header: http.Header{
+				"Deprecation": {"@0"},
+				"Link": {
+					`<https://example.com/next>; rel="next", <https://example.com/deprecation>; rel="deprecation"; type="text/html"`,
+					`<https://example.com/sunset>; title="a, b"; rel="sunset"`,
+				},
+			},
+			expected: Deprecation{
+				Deprecated:  true,
+				Date:        time.Unix(0, 0).UTC(),
+				Links:       []string{"https://example.com/deprecation"},
+				SunsetLinks: []string{"https://example.com/sunset"},
+			},
+			ok: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := ParseDeprecation(tt.header)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, d)
+		})
+	}
+}
+
+func TestReportDeprecations(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/users" {
+			w.Header().Set("Deprecation", "@1688169599")
+			w.Header().Set("Sunset", "Wed, 11 Nov 2026 23:59:59 GMT")
+		}
+	}))
+	defer server.Close()
+
+	var endpoints []string
+	responses := 0
+	dispatcher := NewDispatcher(nil, ReportDeprecations(func(o *DeprecationOptions) {
+		o.OnDeprecatedEndpoint = func(endpoint string, d Deprecation) { endpoints = append(endpoints, endpoint) }
+		o.OnDeprecatedResponse = func(endpoint string, d Deprecation) { responses++ }
+	}))
+
+	for _, path := range []string{"/v1/users", "/v1/users", "/v2/users"} {
+		resp := dispatcher.NewRequest().Get(server.URL + path)
+		require.NoError(t, resp.Error)
+		resp.Close()
+	}
+
+	host := server.Listener.Addr().String()
+	assert.Equal(t, []string{"GET " + host + "/v1/users"}, endpoints)
+	assert.Equal(t, 2, responses)
+
+	resp := dispatcher.NewRequest().Get(server.URL + "/v1/users")
+	require.NoError(t, resp.Error)
+	defer resp.Close()
+	d, ok := resp.Deprecation()
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2026, 11, 11, 23, 59, 59, 0, time.UTC), d.Sunset)
+}
@@ -0,0 +1,199 @@
+package fetch
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// DiagnosticReport is a structured description of a single traced request,
+// similar to the output of "curl -v".
+type DiagnosticReport struct {
+	URL         string
+	StatusCode  int
+	Proto       string
+	Proxy       string
+	ResolvedIPs []string
+	RemoteAddr  string
+	ConnReused  bool
+	ConnWasIdle bool
+	TLS         *TLSReport
+	Timings     DiagnosticTimings
+}
+
+// TLSReport summarizes the negotiated TLS connection state.
+type TLSReport struct {
+	Version      string
+	CipherSuite  string
+	ALPN         string
+	ServerName   string
+	Resumed      bool
+	Certificates []CertificateSummary
+}
+
+// CertificateSummary describes one certificate of the peer chain.
+type CertificateSummary struct {
+	Subject   string
+	Issuer    string
+	DNSNames  []string
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+// DiagnosticTimings holds the duration of each request phase.
+// Phases that did not happen, such as DNS for an IP literal or TLS on a
+// reused connection, are zero.
+type DiagnosticTimings struct {
+	DNS          time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+	FirstByte    time.Duration
+	Total        time.Duration
+}
+
+// Diagnose performs a GET request to rawURL through the dispatcher with full
+// connection tracing and returns a report of what happened on the wire.
+// The response body is read and discarded so the total timing covers the
+// whole exchange. The report is returned even when the request fails, with
+// as much information as was collected.
+func (d *Dispatcher) Diagnose(ctx context.Context, rawURL string) (*DiagnosticReport, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, &InvalidRequestError{err: err}
+	}
+
+	report := &DiagnosticReport{URL: req.URL.String()}
+
+	var (
+		mu                               sync.Mutex
+		dnsStart, connectStart, tlsStart time.Time
+		start                            = time.Now()
+	)
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			dnsStart = time.Now()
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			report.Timings.DNS = time.Since(dnsStart)
+			for _, addr := range info.Addrs {
+				report.ResolvedIPs = append(report.ResolvedIPs, addr.String())
+			}
+		},
+		ConnectStart: func(string, string) {
+			mu.Lock()
+			defer mu.Unlock()
+			connectStart = time.Now()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				report.Timings.Connect = time.Since(connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			mu.Lock()
+			defer mu.Unlock()
+			tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				report.Timings.TLSHandshake = time.Since(tlsStart)
+				report.TLS = buildTLSReport(state)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			report.ConnReused = info.Reused
+			report.ConnWasIdle = info.WasIdle
+			if info.Conn != nil {
+				report.RemoteAddr = info.Conn.RemoteAddr().String()
+			}
+		},
+		GotFirstResponseByte: func() {
+			mu.Lock()
+			defer mu.Unlock()
+			report.Timings.FirstByte = time.Since(start)
+		},
+	}
+
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+	report.Proxy = proxyFor(d.Client(), req)
+
+	resp, err := d.Do(req)
+	if err != nil {
+		mu.Lock()
+		defer mu.Unlock()
+		report.Timings.Total = time.Since(start)
+		return report, err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(io.Discard, resp.Body)
+
+	mu.Lock()
+	defer mu.Unlock()
+	report.StatusCode = resp.StatusCode
+	report.Proto = resp.Proto
+	report.Timings.Total = time.Since(start)
+	if report.TLS == nil && resp.TLS != nil {
+		report.TLS = buildTLSReport(*resp.TLS)
+	}
+
+	return report, err
+}
+
+func buildTLSReport(state tls.ConnectionState) *TLSReport {
+	report := &TLSReport{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ALPN:        state.NegotiatedProtocol,
+		ServerName:  state.ServerName,
+		Resumed:     state.DidResume,
+	}
+
+	for _, cert := range state.PeerCertificates {
+		report.Certificates = append(report.Certificates, CertificateSummary{
+			Subject:   cert.Subject.String(),
+			Issuer:    cert.Issuer.String(),
+			DNSNames:  cert.DNSNames,
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+		})
+	}
+
+	return report
+}
+
+func proxyFor(client *http.Client, req *http.Request) string {
+	if client == nil {
+		return ""
+	}
+
+	transport, ok := client.Transport.(*http.Transport)
+	if client.Transport == nil {
+		transport, ok = http.DefaultTransport.(*http.Transport)
+	}
+	if !ok || transport.Proxy == nil {
+		return ""
+	}
+
+	proxyURL, err := transport.Proxy(req)
+	if err != nil || proxyURL == nil {
+		return ""
+	}
+
+	return proxyURL.Redacted()
+}
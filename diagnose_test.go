@@ -0,0 +1,77 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcher_Diagnose(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	t.Run("plain HTTP", func(t *testing.T) {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		report, err := NewDispatcher(server.Client()).Diagnose(context.Background(), server.URL)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, report.StatusCode)
+		assert.Equal(t, "HTTP/1.1", report.Proto)
+		assert.NotEmpty(t, report.RemoteAddr)
+		assert.Nil(t, report.TLS)
+		assert.Positive(t, report.Timings.Total)
+		assert.Positive(t, report.Timings.FirstByte)
+	})
+
+	t.Run("TLS", func(t *testing.T) {
+		server := httptest.NewTLSServer(handler)
+		defer server.Close()
+
+		report, err := NewDispatcher(server.Client()).Diagnose(context.Background(), server.URL)
+		require.NoError(t, err)
+
+		require.NotNil(t, report.TLS)
+		assert.NotEmpty(t, report.TLS.Version)
+		assert.NotEmpty(t, report.TLS.CipherSuite)
+		assert.NotEmpty(t, report.TLS.Certificates)
+		assert.Positive(t, report.Timings.TLSHandshake)
+	})
+
+	t.Run("proxy in use", func(t *testing.T) {
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		proxyURL, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+		report, err := NewDispatcher(client).Diagnose(context.Background(), "http://example.invalid/")
+		require.NoError(t, err)
+		assert.Equal(t, server.URL, report.Proxy)
+	})
+
+	t.Run("invalid URL", func(t *testing.T) {
+		_, err := NewDispatcher(nil).Diagnose(context.Background(), "://bad")
+		var ire *InvalidRequestError
+		assert.ErrorAs(t, err, &ire)
+	})
+
+	t.Run("connection error returns partial report", func(t *testing.T) {
+		server := httptest.NewServer(handler)
+		serverURL := server.URL
+		server.Close()
+
+		report, err := NewDispatcher(nil).Diagnose(context.Background(), serverURL)
+		assert.Error(t, err)
+		require.NotNil(t, report)
+		assert.Zero(t, report.StatusCode)
+	})
+}
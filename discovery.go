@@ -0,0 +1,301 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServiceEndpoint is a live instance of a service.
+type ServiceEndpoint struct {
+	Host string
+	Port int
+	// Weight is the share of requests relative to the other endpoints.
+	// Values below 1 count as 1.
+	Weight int
+}
+
+// Resolver resolves a service name to its live endpoints, e.g. from DNS SRV
+// records or a service registry such as Consul.
+type Resolver interface {
+	Resolve(ctx context.Context, service string) ([]ServiceEndpoint, error)
+}
+
+// ResolverFunc is an adapter to allow ordinary functions to be used as Resolver.
+type ResolverFunc func(ctx context.Context, service string) ([]ServiceEndpoint, error)
+
+// Resolve calls f(ctx, service).
+func (f ResolverFunc) Resolve(ctx context.Context, service string) ([]ServiceEndpoint, error) {
+	return f(ctx, service)
+}
+
+// SRVOptions configures an SRVResolver.
+type SRVOptions struct {
+	// Service and Proto build the queried name _service._proto.name as in
+	// net.LookupSRV. When both are empty the service name is queried as is.
+	Service string
+	Proto   string
+	// Resolver performs the lookups. Defaults to net.DefaultResolver.
+	Resolver *net.Resolver
+}
+
+// SRVResolver resolves services from DNS SRV records (RFC 2782). Only the
+// targets with the lowest priority are returned, weighted by their SRV weight.
+type SRVResolver struct {
+	options *SRVOptions
+}
+
+// NewSRVResolver creates a resolver for DNS SRV records.
+//
+// Example:
+//
+//	srv := fetch.NewSRVResolver(func(o *fetch.SRVOptions) {
+//	    o.Service, o.Proto = "https", "tcp"
+//	})
+//	endpoints, err := srv.Resolve(ctx, "payments.example.com")
+func NewSRVResolver(opts ...func(*SRVOptions)) *SRVResolver {
+	return &SRVResolver{options: applyOptions(&SRVOptions{Resolver: net.DefaultResolver}, opts...)}
+}
+
+// Resolve looks up the SRV records of service.
+func (r *SRVResolver) Resolve(ctx context.Context, service string) ([]ServiceEndpoint, error) {
+	_, records, err := r.options.Resolver.LookupSRV(ctx, r.options.Service, r.options.Proto, service)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoints []ServiceEndpoint
+	for _, record := range records {
+		// Records are sorted by priority; a lower value is preferred.
+		if record.Priority != records[0].Priority {
+			break
+		}
+		endpoints = append(endpoints, ServiceEndpoint{
+			Host:   strings.TrimSuffix(record.Target, "."),
+			Port:   int(record.Port),
+			Weight: int(record.Weight),
+		})
+	}
+	return endpoints, nil
+}
+
+// ResolverProviderOptions configures a ResolverProvider.
+type ResolverProviderOptions struct {
+	// Scheme of the upstream URLs. Defaults to "http".
+	Scheme string
+	// TTL is how long a resolution is used before the service is resolved
+	// again. Defaults to 30s.
+	TTL time.Duration
+	// ErrorTTL is how long a failed resolution is used before the service is
+	// resolved again. Defaults to 1s.
+	ErrorTTL time.Duration
+	// Timeout bounds a single resolution. Defaults to 5s.
+	Timeout time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// ResolverProvider is a HostProvider resolving a service with a Resolver,
+// so a LoadBalancer follows the live endpoints of the service. Resolutions
+// are cached for TTL; a failed resolution keeps the previous endpoints and is
+// retried after ErrorTTL. It is safe for concurrent use.
+type ResolverProvider struct {
+	resolver Resolver
+	service  string
+	options  *ResolverProviderOptions
+
+	mu        sync.Mutex
+	upstreams []Upstream
+	expires   time.Time
+	err       error
+	resolving chan struct{}
+}
+
+// NewResolverProvider creates a HostProvider for service.
+//
+// Example:
+//
+//	provider := fetch.NewResolverProvider(fetch.NewSRVResolver(), "_http._tcp.payments.example.com")
+//	lb := fetch.NewLoadBalancer(provider)
+func NewResolverProvider(resolver Resolver, service string, opts ...func(*ResolverProviderOptions)) *ResolverProvider {
+	return &ResolverProvider{
+		resolver: resolver,
+		service:  service,
+		options: applyOptions(&ResolverProviderOptions{
+			Scheme:   "http",
+			TTL:      30 * time.Second,
+			ErrorTTL: time.Second,
+			Timeout:  5 * time.Second,
+			Now:      time.Now,
+		}, opts...),
+	}
+}
+
+// Upstreams returns the endpoints of the service, resolving it when the
+// cached resolution expired. Concurrent callers share one resolution, which
+// runs without holding the lock; while it runs, callers that already have
+// endpoints get the previous ones.
+func (p *ResolverProvider) Upstreams() []Upstream {
+	p.mu.Lock()
+	if p.options.Now().Before(p.expires) || (p.resolving != nil && p.upstreams != nil) {
+		defer p.mu.Unlock()
+		return p.upstreams
+	}
+
+	done := p.resolving
+	if done == nil {
+		done = make(chan struct{})
+		p.resolving = done
+		p.mu.Unlock()
+		p.resolve(done)
+	} else {
+		p.mu.Unlock()
+		<-done
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.upstreams
+}
+
+// resolve resolves the service, stores the outcome and closes done.
+func (p *ResolverProvider) resolve(done chan struct{}) {
+	var (
+		upstreams []Upstream
+		err       error
+	)
+	defer func() {
+		p.mu.Lock()
+		now := p.options.Now()
+		if err != nil {
+			p.err, p.expires = err, now.Add(p.options.ErrorTTL)
+		} else {
+			p.upstreams, p.expires, p.err = upstreams, now.Add(p.options.TTL), nil
+		}
+		p.resolving = nil
+		p.mu.Unlock()
+		close(done)
+	}()
+	// A panicking resolver must not leave waiting callers blocked.
+	err = fmt.Errorf("resolve %s: resolver panicked", p.service)
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.options.Timeout)
+	defer cancel()
+
+	endpoints, resolveErr := p.resolver.Resolve(ctx, p.service)
+	if resolveErr != nil {
+		err = fmt.Errorf("resolve %s: %w", p.service, resolveErr)
+		return
+	}
+
+	upstreams = make([]Upstream, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		host := endpoint.Host
+		if endpoint.Port > 0 {
+			host = net.JoinHostPort(host, strconv.Itoa(endpoint.Port))
+		}
+		upstreams = append(upstreams, Upstream{URL: p.options.Scheme + "://" + host, Weight: endpoint.Weight})
+	}
+	err = nil
+}
+
+// Err returns the error of the last resolution, or nil when it succeeded.
+func (p *ResolverProvider) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// DiscoveryOptions configures the Discovery middleware.
+type DiscoveryOptions struct {
+	// Resolvers maps URL schemes to the resolver of their services, e.g.
+	// "consul" for URLs like consul://payments/charges.
+	Resolvers map[string]Resolver
+	// Provider configures the ResolverProvider of every service, e.g. the
+	// scheme of the endpoints or the TTL of resolutions.
+	Provider func(*ResolverProviderOptions)
+	// LoadBalancer configures the LoadBalancer of every service, e.g. to set
+	// a shared HealthChecker.
+	LoadBalancer func(*LoadBalancerOptions)
+}
+
+// Discovery creates middleware resolving the host of URLs whose scheme has a
+// resolver to the live endpoints of that service at request time. Each
+// service gets a ResolverProvider feeding its own LoadBalancer, so requests
+// are spread over the endpoints with their weights and unhealthy endpoints
+// are skipped. Requests with other schemes pass through unchanged. When a
+// service has no endpoints the request fails with ErrNoUpstream, wrapping
+// the resolution error if there was one.
+//
+// Example:
+//
+//	health := fetch.NewHealthChecker()
+//	defer health.Close()
+//	dispatcher.Use(fetch.Discovery(func(o *fetch.DiscoveryOptions) {
+//	    o.Resolvers = map[string]fetch.Resolver{
+//	        "consul": fetch.NewConsulResolver("http://127.0.0.1:8500"),
+//	    }
+//	    o.LoadBalancer = func(o *fetch.LoadBalancerOptions) {
+//	        o.HealthChecker = health
+//	        o.MaxAttempts = 2
+//	    }
+//	}))
+//	dispatcher.NewRequest().Post("consul://payments/charges")
+func Discovery(opts ...func(*DiscoveryOptions)) Middleware {
+	options := applyOptions(&DiscoveryOptions{}, opts...)
+
+	type service struct {
+		provider *ResolverProvider
+		balancer Middleware
+	}
+	var (
+		mu       sync.Mutex
+		services = map[string]*service{}
+	)
+	lookup := func(resolver Resolver, scheme, name string) *service {
+		mu.Lock()
+		defer mu.Unlock()
+
+		key := scheme + "://" + name
+		if s, ok := services[key]; ok {
+			return s
+		}
+		var providerOpts []func(*ResolverProviderOptions)
+		if options.Provider != nil {
+			providerOpts = append(providerOpts, options.Provider)
+		}
+		var balancerOpts []func(*LoadBalancerOptions)
+		if options.LoadBalancer != nil {
+			balancerOpts = append(balancerOpts, options.LoadBalancer)
+		}
+		provider := NewResolverProvider(resolver, name, providerOpts...)
+		s := &service{provider: provider, balancer: NewLoadBalancer(provider, balancerOpts...).Middleware()}
+		services[key] = s
+		return s
+	}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			scheme := strings.ToLower(req.URL.Scheme)
+			resolver, ok := options.Resolvers[scheme]
+			if !ok {
+				return next.Handle(client, req)
+			}
+
+			s := lookup(resolver, scheme, req.URL.Hostname())
+			resp, err := s.balancer(next).Handle(client, req)
+			if errors.Is(err, ErrNoUpstream) {
+				if resolveErr := s.provider.Err(); resolveErr != nil {
+					err = fmt.Errorf("%w: %w", ErrNoUpstream, resolveErr)
+				}
+			}
+			return resp, err
+		})
+	}
+}
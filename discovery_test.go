@@ -0,0 +1,184 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serviceEndpoint(t *testing.T, server *httptest.Server) ServiceEndpoint {
+	t.Helper()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+	return ServiceEndpoint{Host: u.Hostname(), Port: port}
+}
+
+func TestResolverProvider(t *testing.T) {
+	now := time.Unix(0, 0)
+	var (
+		calls      int
+		endpoints  = []ServiceEndpoint{{Host: "10.0.0.1", Port: 80, Weight: 2}}
+		resolveErr error
+	)
+	provider := NewResolverProvider(ResolverFunc(func(ctx context.Context, service string) ([]ServiceEndpoint, error) {
+		assert.Equal(t, "payments", service)
+		calls++
+		return endpoints, resolveErr
+	}), "payments", func(o *ResolverProviderOptions) {
+		o.Scheme = "https"
+		o.TTL = time.Minute
+		o.Now = func() time.Time { return now }
+	})
+
+	want := []Upstream{{URL: "https://10.0.0.1:80", Weight: 2}}
+	assert.Equal(t, want, provider.Upstreams())
+	assert.Equal(t, want, provider.Upstreams())
+	assert.Equal(t, 1, calls, "cached for the TTL")
+
+	// A failed resolution keeps the previous endpoints.
+	now = now.Add(time.Minute)
+	resolveErr = errors.New("registry down")
+	assert.Equal(t, want, provider.Upstreams())
+	assert.ErrorIs(t, provider.Err(), resolveErr)
+
+	// The failure is cached for ErrorTTL before resolving again.
+	resolveErr = nil
+	assert.Equal(t, want, provider.Upstreams())
+	assert.Equal(t, 2, calls)
+	now = now.Add(time.Second)
+	endpoints = []ServiceEndpoint{{Host: "10.0.0.2"}}
+	assert.Equal(t, []Upstream{{URL: "https://10.0.0.2"}}, provider.Upstreams())
+	assert.NoError(t, provider.Err())
+}
+
+func TestResolverProvider_Concurrent(t *testing.T) {
+	tests := []struct {
+		name   string
+		cached bool
+	}{
+		{name: "first resolution is waited for", cached: false},
+		{name: "previous endpoints are served meanwhile", cached: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Unix(0, 0)
+			var (
+				calls atomic.Int32
+				block atomic.Bool
+			)
+			block.Store(!tt.cached)
+			release := make(chan struct{})
+			provider := NewResolverProvider(ResolverFunc(func(ctx context.Context, service string) ([]ServiceEndpoint, error) {
+				calls.Add(1)
+				if block.Load() {
+					<-release
+				}
+				return []ServiceEndpoint{{Host: "10.0.0.1"}}, nil
+			}), "payments", func(o *ResolverProviderOptions) {
+				o.Now = func() time.Time { return now }
+			})
+			want := []Upstream{{URL: "http://10.0.0.1"}}
+			if tt.cached {
+				require.Equal(t, want, provider.Upstreams())
+				calls.Store(0)
+				block.Store(true)
+				now = now.Add(time.Hour)
+			}
+
+			var wg sync.WaitGroup
+			results := make(chan []Upstream, 5)
+			for range 5 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					results <- provider.Upstreams()
+				}()
+			}
+
+			// The lock is not held while resolving.
+			require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+			assert.NoError(t, provider.Err())
+			if tt.cached {
+				for range 4 {
+					assert.Equal(t, want, <-results)
+				}
+			}
+
+			close(release)
+			wg.Wait()
+			close(results)
+			for upstreams := range results {
+				assert.Equal(t, want, upstreams)
+			}
+			assert.Equal(t, int32(1), calls.Load())
+		})
+	}
+}
+
+func TestDiscovery(t *testing.T) {
+	healthyA, healthyB := true, true
+	a := newUpstream("a", &healthyA)
+	defer a.Close()
+	b := newUpstream("b", &healthyB)
+	defer b.Close()
+
+	health := NewHealthChecker(func(o *HealthCheckOptions) {
+		o.FailureThreshold = 1
+		o.Interval = time.Hour
+	})
+	defer health.Close()
+
+	registry := map[string][]ServiceEndpoint{
+		"payments": {serviceEndpoint(t, a), serviceEndpoint(t, b)},
+	}
+	dispatcher := NewDispatcher(nil, Discovery(func(o *DiscoveryOptions) {
+		o.Resolvers = map[string]Resolver{
+			"consul": ResolverFunc(func(ctx context.Context, service string) ([]ServiceEndpoint, error) {
+				if service == "broken" {
+					return nil, errors.New("registry down")
+				}
+				return registry[service], nil
+			}),
+		}
+		o.LoadBalancer = func(o *LoadBalancerOptions) { o.HealthChecker = health }
+	}))
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		resp := dispatcher.NewRequest().Get("consul://payments/charges")
+		require.NoError(t, resp.Error)
+		got = append(got, resp.String())
+	}
+	assert.ElementsMatch(t, []string{"a", "a", "b", "b"}, got)
+
+	// An unhealthy endpoint is skipped once the health checker saw it fail.
+	healthyA = false
+	for i := 0; i < 2; i++ {
+		dispatcher.NewRequest().Get("consul://payments/charges")
+	}
+	healthyA = true
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, "b", dispatcher.NewRequest().Get("consul://payments/charges").String())
+	}
+
+	resp := dispatcher.NewRequest().Get("consul://unknown/")
+	assert.ErrorIs(t, resp.Error, ErrNoUpstream)
+	resp = dispatcher.NewRequest().Get("consul://broken/")
+	assert.ErrorIs(t, resp.Error, ErrNoUpstream)
+	assert.ErrorContains(t, resp.Error, "registry down")
+
+	// Other schemes pass through.
+	assert.Equal(t, "b", dispatcher.NewRequest().Get(b.URL).String())
+}
@@ -2,6 +2,8 @@
 package fetch
 
 import (
+	"fmt"
+	"maps"
 	"net/http"
 	"slices"
 	"sync"
@@ -12,9 +14,13 @@ import (
 // It wraps an http.Client and applies middleware chains to requests.
 // All methods are safe for concurrent use.
 type Dispatcher struct {
-	lock        sync.Mutex
-	client      *http.Client
-	middlewares []Middleware
+	lock         sync.Mutex
+	client       *http.Client
+	middlewares  []Middleware
+	interceptors []interceptor
+	profiles     map[string]*Profile
+	active       string
+	jars         map[string]http.CookieJar
 }
 
 // NewDispatcher creates a new Dispatcher with the given HTTP client and middleware.
@@ -29,7 +35,7 @@ func NewDispatcher(client *http.Client, middlewares ...Middleware) *Dispatcher {
 
 	return &Dispatcher{
 		client:      client,
-		middlewares: middlewares,
+		middlewares: slices.Clone(middlewares),
 	}
 }
 
@@ -43,12 +49,16 @@ func NewDispatcherWithTransport(transport http.RoundTripper, middlewares ...Midd
 
 	return &Dispatcher{
 		client:      client,
-		middlewares: middlewares,
+		middlewares: slices.Clone(middlewares),
 	}
 }
 
 // Client returns the underlying HTTP client.
+// This operation is safe for concurrent use.
 func (d *Dispatcher) Client() *http.Client {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
 	return d.client
 }
 
@@ -67,38 +77,78 @@ func (d *Dispatcher) SetClient(client *http.Client) {
 }
 
 // Middlewares returns the current middleware chain.
+// The returned slice is a copy: later calls to Use do not modify it, and
+// changing it does not affect the dispatcher.
+// This operation is safe for concurrent use.
 func (d *Dispatcher) Middlewares() []Middleware {
-	return d.middlewares
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return slices.Clone(d.middlewares)
 }
 
 // Use appends middleware to the dispatcher's middleware chain.
+// The chain is copied on write, so requests already in flight and snapshots
+// returned by Middlewares keep the chain they started with, while requests
+// created earlier with NewRequest pick up the change when they are sent.
 // This operation is safe for concurrent use.
 func (d *Dispatcher) Use(middlewares ...Middleware) {
 	d.lock.Lock()
 	defer d.lock.Unlock()
 
-	d.middlewares = append(d.middlewares, middlewares...)
+	d.middlewares = append(slices.Clip(d.middlewares), middlewares...)
 }
 
 // Clone creates a shallow copy of the Dispatcher.
 // The HTTP client is cloned, and middlewares are copied.
 func (d *Dispatcher) Clone() *Dispatcher {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
 	return &Dispatcher{
-		client:      cloneClient(d.client),
-		middlewares: slices.Clone(d.middlewares),
+		client:       cloneClient(d.client),
+		middlewares:  slices.Clone(d.middlewares),
+		interceptors: d.interceptors,
+		profiles:     maps.Clone(d.profiles),
+		active:       d.active,
+		jars:         maps.Clone(d.jars),
 	}
 }
 
 // Do executes the HTTP request with the dispatcher's middleware chain
 // plus any additional middlewares provided.
+// When a profile is active, its client and middlewares are used as well.
+// Interceptors run first, then the profile, dispatcher and request middlewares.
+// A request left with only GetBody, as set by BodyGetReader and BodyGetBytes,
+// is sent with the body GetBody opens.
 func (d *Dispatcher) Do(req *http.Request, middlewares ...Middleware) (*http.Response, error) {
+	d.lock.Lock()
 	client := cloneClient(d.client)
+	base := d.middlewares
+	if profile := d.profiles[d.active]; profile != nil {
+		if profile.Client != nil {
+			client = cloneClient(profile.Client)
+		}
+		base = slices.Concat(profile.Middlewares, base)
+	}
+	if len(d.interceptors) > 0 {
+		base = slices.Concat(interceptorMiddlewares(d.interceptors), base)
+	}
+	d.lock.Unlock()
 
 	var handler Handler = HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+		// Body middlewares may only provide GetBody; open it for the first attempt.
+		if req.Body == nil && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("get request body: %w", err)
+			}
+			req.Body = body
+		}
 		return client.Do(req)
 	})
 
-	middlewares = slices.Concat(d.middlewares, middlewares)
+	middlewares = slices.Concat(base, middlewares)
 	handler = compose(middlewares...)(handler)
 	return handler.Handle(client, req)
 }
@@ -1,8 +1,12 @@
 package fetch
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -387,3 +391,145 @@ func TestDispatcher_Middlewares(t *testing.T) {
 		})
 	}
 }
+
+func headerMiddleware(name, value string) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			req.Header.Add(name, value)
+			return next.Handle(client, req)
+		})
+	}
+}
+
+func TestDispatcher_LayeredConfiguration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.RawQuery + "|" + r.Header.Get("X-Layer")))
+	}))
+	defer server.Close()
+
+	defaults := []Middleware{
+		SetURLOptions(func(o *URLOptions) { o.QueryParams.Set("page", "1") }),
+	}
+	dispatcher := NewDispatcher(nil, defaults...)
+	defaults[0] = Skip()
+
+	// The request is created before the dispatcher defaults change.
+	req := dispatcher.NewRequest().Use(
+		SetURLOptions(func(o *URLOptions) { o.QueryParams.Set("q", "go") }),
+		PrepareURLMiddleware(),
+	)
+	dispatcher.Use(headerMiddleware("X-Layer", "dispatcher"))
+
+	resp := req.Get(server.URL)
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "page=1&q=go|dispatcher", resp.String())
+
+	// Request overrides do not leak into the dispatcher defaults.
+	resp = dispatcher.NewRequest().Use(PrepareURLMiddleware()).Get(server.URL)
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "page=1|dispatcher", resp.String())
+}
+
+func TestDispatcher_MiddlewaresSnapshot(t *testing.T) {
+	dispatcher := NewDispatcher(nil, Skip(), Skip())
+	snapshot := dispatcher.Middlewares()
+
+	dispatcher.Use(headerMiddleware("X-A", "a"))
+	assert.Len(t, snapshot, 2)
+	assert.Len(t, dispatcher.Middlewares(), 3)
+
+	clone := dispatcher.Clone()
+	clone.Use(headerMiddleware("X-B", "b"))
+	assert.Len(t, dispatcher.Middlewares(), 3)
+	assert.Len(t, clone.Middlewares(), 4)
+
+	// Writing to a snapshot leaves the chain alone.
+	snapshot = dispatcher.Middlewares()
+	snapshot[2] = Skip()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-A")))
+	}))
+	defer server.Close()
+	assert.Equal(t, "a", dispatcher.NewRequest().Get(server.URL).String())
+}
+
+// TestDispatcher_ConcurrentMutation is meant to be run with -race.
+func TestDispatcher_ConcurrentMutation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	dispatcher := NewDispatcher(nil, SetURLOptions(func(o *URLOptions) { o.QueryParams.Set("v", "1") }))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			dispatcher.Use(Skip())
+		}()
+		go func() {
+			defer wg.Done()
+			_ = dispatcher.Middlewares()
+			_ = dispatcher.Client()
+		}()
+		go func() {
+			defer wg.Done()
+			dispatcher.SetClient(&http.Client{Timeout: 5 * time.Second})
+			_ = dispatcher.Clone()
+		}()
+		go func() {
+			defer wg.Done()
+			resp := dispatcher.NewRequest().
+				Use(SetURLOptions(func(o *URLOptions) { o.QueryParams.Set("r", "1") }), PrepareURLMiddleware()).
+				Get(server.URL)
+			defer resp.Close()
+			assert.NoError(t, resp.Error)
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, dispatcher.Middlewares(), 9)
+}
+
+func TestDispatcher_Do_GetBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	errBody := errors.New("body unavailable")
+	tests := []struct {
+		name       string
+		middleware Middleware
+		want       string
+		wantErr    error
+	}{
+		{name: "bytes", middleware: BodyGetBytes(func() ([]byte, error) { return []byte("payload"), nil }), want: "payload"},
+		{name: "reader", middleware: BodyGetReader(func() (io.Reader, error) { return strings.NewReader("payload"), nil }), want: "payload"},
+		{name: "error", middleware: BodyGetReader(func() (io.Reader, error) { return nil, errBody }), wantErr: errBody},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, server.URL, nil)
+			require.NoError(t, err)
+
+			resp, err := NewDispatcher(nil).Do(req, tt.middleware)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(body))
+		})
+	}
+}
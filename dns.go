@@ -0,0 +1,156 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rockcookies/go-fetch/internal/utils"
+)
+
+// ErrDNSTimeout is matched by errors.Is when name resolution exceeded its timeout.
+var ErrDNSTimeout = errors.New("dns timeout")
+
+var dnsTimeoutKey = utils.NewContextKey[time.Duration]("dns_timeout")
+
+// DNSTimeoutError reports a host whose resolution exceeded the DNS timeout.
+type DNSTimeoutError struct {
+	Host  string
+	Limit time.Duration
+}
+
+// Error returns the error message.
+func (e *DNSTimeoutError) Error() string {
+	return fmt.Sprintf("resolve %s: no answer within %s", e.Host, e.Limit)
+}
+
+// Is reports whether target is ErrDNSTimeout.
+func (e *DNSTimeoutError) Is(target error) bool {
+	return target == ErrDNSTimeout
+}
+
+// Timeout reports true, so the error is recognized like other net timeouts.
+func (e *DNSTimeoutError) Timeout() bool {
+	return true
+}
+
+// DialError reports a host that was resolved but none of whose addresses
+// accepted a connection. Addrs lists the attempted addresses in order.
+type DialError struct {
+	Host  string
+	Addrs []string
+	Err   error
+}
+
+// Error returns the error message.
+func (e *DialError) Error() string {
+	return fmt.Sprintf("dial %s (tried %s): %v", e.Host, strings.Join(e.Addrs, ", "), e.Err)
+}
+
+// Unwrap returns the error of the last attempted address.
+func (e *DialError) Unwrap() error {
+	return e.Err
+}
+
+// DNSOptions configures ResolveDNS.
+type DNSOptions struct {
+	// Timeout bounds name resolution only; connecting is bounded by the
+	// dialer and the request deadline as before. Zero means no DNS timeout
+	// unless a request sets one with SetDNSTimeout.
+	Timeout time.Duration
+	// Resolver resolves host names. Defaults to net.DefaultResolver.
+	Resolver *net.Resolver
+}
+
+// ResolveDNS returns a client option that resolves host names itself before
+// dialing, so resolution can be bounded separately from connecting. A
+// resolution exceeding the timeout fails with DNSTimeoutError, and when no
+// resolved address accepts a connection the error is a DialError listing the
+// addresses attempted. Requests can override the timeout with SetDNSTimeout.
+//
+// Example:
+//
+//	dispatcher.Use(fetch.SetClientOptions(fetch.ResolveDNS(func(o *fetch.DNSOptions) {
+//	    o.Timeout = 2 * time.Second
+//	})), fetch.PrepareClientMiddleware())
+func ResolveDNS(opts ...func(*DNSOptions)) func(*http.Client) {
+	options := applyOptions(&DNSOptions{Resolver: net.DefaultResolver}, opts...)
+
+	var transports transportCache
+
+	return func(c *http.Client) {
+		transports.apply(c, func(transport *http.Transport) {
+			dial := transport.DialContext
+			if dial == nil {
+				dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+			}
+
+			transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				host, port, err := net.SplitHostPort(addr)
+				if err != nil {
+					return nil, err
+				}
+				if net.ParseIP(host) != nil {
+					return dial(ctx, network, addr)
+				}
+
+				addrs, err := resolveHost(ctx, options, host)
+				if err != nil {
+					return nil, err
+				}
+
+				dialErr := &DialError{Host: host}
+				for _, ip := range addrs {
+					target := net.JoinHostPort(ip, port)
+					dialErr.Addrs = append(dialErr.Addrs, target)
+
+					conn, err := dial(ctx, network, target)
+					if err == nil {
+						return conn, nil
+					}
+					dialErr.Err = err
+					if ctx.Err() != nil {
+						break
+					}
+				}
+				return nil, dialErr
+			}
+		})
+	}
+}
+
+func resolveHost(ctx context.Context, options *DNSOptions, host string) ([]string, error) {
+	timeout := options.Timeout
+	if d, ok := dnsTimeoutKey.GetValue(ctx); ok {
+		timeout = d
+	}
+
+	lookupCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		lookupCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	addrs, err := options.Resolver.LookupHost(lookupCtx, host)
+	if err != nil && ctx.Err() == nil && errors.Is(lookupCtx.Err(), context.DeadlineExceeded) {
+		return nil, &DNSTimeoutError{Host: host, Limit: timeout}
+	}
+	return addrs, err
+}
+
+// SetDNSTimeout creates middleware that overrides the DNS timeout of
+// ResolveDNS for the request; zero disables it. It has no effect on clients
+// without the ResolveDNS option.
+func SetDNSTimeout(timeout time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			req = req.WithContext(dnsTimeoutKey.WithValue(req.Context(), timeout))
+			return next.Handle(client, req)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package fetch
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveDNS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	// hangingResolver answers localhost from the hosts file and never
+	// answers other names.
+	hangingResolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+
+	tests := []struct {
+		name       string
+		url        string
+		timeout    time.Duration
+		request    []Middleware
+		wantBody   string
+		wantDNS    bool
+		wantDial   bool
+		maxElapsed time.Duration
+	}{
+		{name: "resolves host", url: "http://localhost:" + serverURL.Port(), timeout: time.Second, wantBody: "ok"},
+		{name: "ip literal skips resolution", url: server.URL, timeout: time.Nanosecond, wantBody: "ok"},
+		{name: "client timeout", url: "http://slow.invalid/", timeout: 50 * time.Millisecond, wantDNS: true, maxElapsed: 2 * time.Second},
+		{name: "request overrides client timeout", url: "http://slow.invalid/", timeout: time.Minute, request: []Middleware{SetDNSTimeout(50 * time.Millisecond)}, wantDNS: true, maxElapsed: 2 * time.Second},
+		{name: "connect failure lists addresses", url: "http://localhost:" + strconv.Itoa(closedPort), timeout: time.Second, wantDial: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dispatcher := NewDispatcher(nil,
+				SetClientOptions(ResolveDNS(func(o *DNSOptions) {
+					o.Timeout = tt.timeout
+					o.Resolver = hangingResolver
+				})),
+				PrepareClientMiddleware(),
+			)
+
+			start := time.Now()
+			resp := dispatcher.NewRequest().Use(tt.request...).Get(tt.url)
+			defer resp.Close()
+
+			switch {
+			case tt.wantDNS:
+				require.ErrorIs(t, resp.Error, ErrDNSTimeout)
+				var netErr net.Error
+				require.ErrorAs(t, resp.Error, &netErr)
+				assert.True(t, netErr.Timeout())
+				assert.Less(t, time.Since(start), tt.maxElapsed)
+			case tt.wantDial:
+				var dialErr *DialError
+				require.ErrorAs(t, resp.Error, &dialErr)
+				assert.Equal(t, "localhost", dialErr.Host)
+				assert.Contains(t, dialErr.Addrs, "127.0.0.1:"+strconv.Itoa(closedPort))
+				assert.NotErrorIs(t, resp.Error, ErrDNSTimeout)
+			default:
+				require.NoError(t, resp.Error)
+				assert.Equal(t, tt.wantBody, resp.String())
+			}
+		})
+	}
+}
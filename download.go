@@ -0,0 +1,179 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ConflictPolicy decides what DownloadToDir does when the file already exists.
+type ConflictPolicy int
+
+const (
+	// ConflictRename saves as "name (1).ext", "name (2).ext", ... instead.
+	ConflictRename ConflictPolicy = iota
+	// ConflictOverwrite replaces the existing file.
+	ConflictOverwrite
+	// ConflictError fails with an error matching os.ErrExist.
+	ConflictError
+)
+
+// DownloadOptions configures DownloadToDir.
+type DownloadOptions struct {
+	// Conflict is applied when the file already exists. Defaults to ConflictRename.
+	Conflict ConflictPolicy
+	// DefaultName is used when neither Content-Disposition nor the URL
+	// suggest a file name. Defaults to "download".
+	DefaultName string
+}
+
+// FileName returns the file name suggested by the Content-Disposition header
+// as defined in RFC 6266, preferring the RFC 5987 encoded filename* parameter,
+// and falls back to the last segment of the request URL. Directory components
+// are removed so the result is safe to join with a directory. It returns ""
+// when no name is available.
+func (r *Response) FileName() string {
+	if r.Error != nil {
+		return ""
+	}
+
+	if disposition := r.Header.Get("Content-Disposition"); disposition != "" {
+		// mime decodes filename* and lets it take precedence over filename.
+		if _, params, err := mime.ParseMediaType(disposition); err == nil {
+			if name := sanitizeFileName(params["filename"]); name != "" {
+				return name
+			}
+		}
+	}
+
+	if r.RawResponse != nil && r.RawResponse.Request != nil {
+		return sanitizeFileName(path.Base(r.RawResponse.Request.URL.Path))
+	}
+	return ""
+}
+
+// DownloadToDir saves the response body into dir under the name returned by
+// FileName and returns the path of the written file. The content only
+// reaches that path once the body was read completely; a failed download
+// removes what it wrote and leaves an existing file untouched.
+//
+// Example:
+//
+//	path, err := resp.DownloadToDir("downloads", func(o *fetch.DownloadOptions) {
+//	    o.Conflict = fetch.ConflictError
+//	})
+func (r *Response) DownloadToDir(dir string, opts ...func(*DownloadOptions)) (string, error) {
+	if r.Error != nil {
+		return "", r.Error
+	}
+	defer r.Close()
+
+	options := applyOptions(&DownloadOptions{
+		Conflict:    ConflictRename,
+		DefaultName: "download",
+	}, opts...)
+
+	name := r.FileName()
+	if name == "" {
+		name = sanitizeFileName(options.DefaultName)
+	}
+	if name == "" {
+		return "", errors.New("download: no file name")
+	}
+
+	target, reserved, err := reserveDownloadFile(dir, name, options.Conflict)
+	if err != nil {
+		return "", err
+	}
+
+	// The body is written to a temporary file that only replaces the target
+	// once complete, so a failed download leaves no partial file behind and
+	// keeps an existing file intact.
+	fd, err := os.CreateTemp(dir, ".download-*.tmp")
+	if err != nil {
+		if reserved {
+			os.Remove(target)
+		}
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			fd.Close()
+			os.Remove(fd.Name())
+			if reserved {
+				os.Remove(target)
+			}
+		}
+	}()
+
+	if _, err = io.Copy(fd, r.getInternalReader()); err != nil {
+		return "", fmt.Errorf("download: %w", err)
+	}
+	if err = fd.Chmod(0o644); err != nil {
+		return "", err
+	}
+	if err = fd.Close(); err != nil {
+		return "", err
+	}
+	if err = os.Rename(fd.Name(), target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// reserveDownloadFile returns the path the download is saved to. Unless the
+// policy overwrites, an empty file is created there so concurrent downloads
+// cannot claim the same name; reserved reports whether it was.
+func reserveDownloadFile(dir, name string, policy ConflictPolicy) (target string, reserved bool, err error) {
+	target = filepath.Join(dir, name)
+
+	switch policy {
+	case ConflictOverwrite:
+		return target, false, nil
+	case ConflictError:
+		return target, true, createEmptyFile(target)
+	}
+
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		err := createEmptyFile(target)
+		if !errors.Is(err, os.ErrExist) {
+			return target, err == nil, err
+		}
+		target = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", base, i, ext))
+	}
+}
+
+// createEmptyFile creates path, failing with os.ErrExist when it exists.
+func createEmptyFile(path string) error {
+	fd, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o666)
+	if err != nil {
+		return err
+	}
+	return fd.Close()
+}
+
+// sanitizeFileName strips directory components and control characters so
+// the name cannot escape the download directory.
+func sanitizeFileName(name string) string {
+	name = strings.ReplaceAll(name, `\`, "/")
+	name = path.Base(name)
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+
+	if name == "." || name == ".." || name == "/" {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,139 @@
+package fetch
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseFileName(t *testing.T) {
+	tests := []struct {
+		name        string
+		disposition string
+		path        string
+		expected    string
+	}{
+		{name: "plain filename", disposition: `attachment; filename="report.pdf"`, path: "/x", expected: "report.pdf"},
+		{name: "encoded filename wins", disposition: `attachment; filename="fallback.txt"; filename*=UTF-8''%E2%82%AC%20rates.txt`, path: "/x", expected: "€ rates.txt"},
+		{name: "path traversal", disposition: `attachment; filename="../../etc/passwd"`, path: "/x", expected: "passwd"},
+		{name: "windows traversal", disposition: `attachment; filename="..\\..\\boot.ini"`, path: "/x", expected: "boot.ini"},
+		{name: "url fallback", path: "/files/archive.zip", expected: "archive.zip"},
+		{name: "dot dot only", disposition: `attachment; filename=".."`, path: "/", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.disposition != "" {
+					w.Header().Set("Content-Disposition", tt.disposition)
+				}
+			}))
+			defer server.Close()
+
+			resp := NewDispatcher(nil).NewRequest().Get(server.URL + tt.path)
+			defer resp.Close()
+
+			require.NoError(t, resp.Error)
+			assert.Equal(t, tt.expected, resp.FileName())
+		})
+	}
+}
+
+func TestResponseDownloadToDir(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Disposition", `attachment; filename="data.csv"`)
+		w.Write([]byte("new"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		policy   ConflictPolicy
+		wantFile string
+		wantErr  error
+		wantOld  string
+	}{
+		{name: "rename", policy: ConflictRename, wantFile: "data (1).csv", wantOld: "old"},
+		{name: "overwrite", policy: ConflictOverwrite, wantFile: "data.csv", wantOld: "new"},
+		{name: "error", policy: ConflictError, wantErr: os.ErrExist, wantOld: "old"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "data.csv"), []byte("old"), 0o600))
+
+			resp := NewDispatcher(nil).NewRequest().Get(server.URL)
+			target, err := resp.DownloadToDir(dir, func(o *DownloadOptions) { o.Conflict = tt.policy })
+
+			old, readErr := os.ReadFile(filepath.Join(dir, "data.csv"))
+			require.NoError(t, readErr)
+			assert.Equal(t, tt.wantOld, string(old))
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, filepath.Join(dir, tt.wantFile), target)
+
+			data, err := os.ReadFile(target)
+			require.NoError(t, err)
+			assert.Equal(t, "new", string(data))
+		})
+	}
+}
+
+func TestResponseDownloadToDir_BodyError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Disposition", `attachment; filename="data.csv"`)
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("partial"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		policy ConflictPolicy
+	}{
+		{name: "rename", policy: ConflictRename},
+		{name: "overwrite", policy: ConflictOverwrite},
+		{name: "error", policy: ConflictError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			existing := filepath.Join(dir, "data.csv")
+			require.NoError(t, os.WriteFile(existing, []byte("old"), 0o600))
+			if tt.policy == ConflictError {
+				require.NoError(t, os.Remove(existing))
+			}
+
+			resp := NewDispatcher(nil).NewRequest().Get(server.URL)
+			_, err := resp.DownloadToDir(dir, func(o *DownloadOptions) { o.Conflict = tt.policy })
+			assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			var names []string
+			for _, entry := range entries {
+				names = append(names, entry.Name())
+			}
+			if tt.policy == ConflictError {
+				assert.Empty(t, names, "no partial file should be left")
+				return
+			}
+			assert.Equal(t, []string{"data.csv"}, names, "no partial file should be left")
+			data, err := os.ReadFile(existing)
+			require.NoError(t, err)
+			assert.Equal(t, "old", string(data))
+		})
+	}
+}
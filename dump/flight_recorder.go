@@ -0,0 +1,269 @@
+package dump
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	fetch "github.com/rockcookies/go-fetch"
+)
+
+// FlightRecorderOptions configures a FlightRecorder.
+type FlightRecorderOptions struct {
+	Logger        *slog.Logger
+	ContextLogger func(ctx context.Context) *slog.Logger
+	LogLevel      slog.Level
+	// Size is the number of records kept in the ring buffer.
+	Size int
+	// BodyMaxSize is the number of request and response body bytes captured per record.
+	BodyMaxSize int64
+	// Failed decides which exchanges trigger a dump of the recent records.
+	Failed func(req *http.Request, resp *http.Response, err error) bool
+}
+
+// DefaultFlightRecorderOptions returns sensible default options for the flight recorder.
+// Keeps the last 50 exchanges with up to 4KB of each body and dumps them at
+// error level when a request fails with a network error or a 5xx status.
+func DefaultFlightRecorderOptions() *FlightRecorderOptions {
+	return &FlightRecorderOptions{
+		Logger:      slog.Default(),
+		LogLevel:    slog.LevelError,
+		Size:        50,
+		BodyMaxSize: 1024 * 4, // 4KB
+		Failed: func(req *http.Request, resp *http.Response, err error) bool {
+			return err != nil || resp.StatusCode >= 500
+		},
+	}
+}
+
+// Record summarizes one request/response exchange.
+type Record struct {
+	Time           time.Time
+	Duration       time.Duration
+	Method         string
+	URL            string
+	Status         int
+	RequestHeader  http.Header
+	RequestBody    string
+	ResponseHeader http.Header
+	ResponseBody   string
+	Error          string
+}
+
+// FlightRecorder keeps summaries of the most recent exchanges in a ring
+// buffer and logs them only when a request fails or DumpRecent is called,
+// giving post-mortem context without always-on verbose logging.
+// It is safe for concurrent use.
+type FlightRecorder struct {
+	options *FlightRecorderOptions
+	mu      sync.Mutex
+	records []*Record
+	next    int
+}
+
+// NewFlightRecorder creates a FlightRecorder. If options is nil,
+// DefaultFlightRecorderOptions is used.
+func NewFlightRecorder(options *FlightRecorderOptions) *FlightRecorder {
+	if options == nil {
+		options = DefaultFlightRecorderOptions()
+	}
+	return &FlightRecorder{options: options}
+}
+
+// Middleware returns middleware that records every attempt at the transport
+// level, so bodies set by request middlewares and retried attempts are
+// captured too. Bodies are captured while they are read, which leaves
+// streaming intact; an exchange with a response body is recorded, and a
+// failure dumped, when that body is closed.
+//
+// Example:
+//
+//	recorder := dump.NewFlightRecorder(nil)
+//	dispatcher.Use(recorder.Middleware())
+func (f *FlightRecorder) Middleware() fetch.Middleware {
+	return func(next fetch.Handler) fetch.Handler {
+		return fetch.HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			c := *client
+			c.Transport = &flightTransport{next: client.Transport, recorder: f}
+			return next.Handle(&c, req)
+		})
+	}
+}
+
+// Recent returns the recorded exchanges, oldest first.
+func (f *FlightRecorder) Recent() []Record {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	records := make([]Record, 0, len(f.records))
+	for i := range f.records {
+		if r := f.records[(f.next+i)%len(f.records)]; r != nil {
+			records = append(records, *r)
+		}
+	}
+	return records
+}
+
+// DumpRecent logs the recorded exchanges, oldest first.
+func (f *FlightRecorder) DumpRecent(ctx context.Context) {
+	logger := resolveLogger(ctx, f.options.Logger, f.options.ContextLogger)
+	if !logger.Enabled(ctx, f.options.LogLevel) {
+		return
+	}
+
+	records := f.Recent()
+	for i, r := range records {
+		attrs := []slog.Attr{
+			slog.Int("index", i),
+			slog.Int("total", len(records)),
+			slog.Time("time", r.Time),
+			slog.Duration("duration", r.Duration),
+			slog.Group("http",
+				slog.String("method", r.Method),
+				slog.String("url", r.URL),
+				slog.Int("status", r.Status),
+			),
+			slog.Group("request_headers", getHeaderAttrs(r.RequestHeader, nil)...),
+			slog.String("request_body", r.RequestBody),
+			slog.Group("response_headers", getHeaderAttrs(r.ResponseHeader, nil)...),
+			slog.String("response_body", r.ResponseBody),
+		}
+		if r.Error != "" {
+			attrs = append(attrs, slog.String("error", r.Error))
+		}
+		logger.LogAttrs(ctx, f.options.LogLevel, "HTTP flight record", attrs...)
+	}
+}
+
+func (f *FlightRecorder) add(r *Record) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	size := max(f.options.Size, 1)
+	if len(f.records) < size {
+		f.records = append(f.records, r)
+		return
+	}
+	f.records[f.next] = r
+	f.next = (f.next + 1) % size
+}
+
+type flightTransport struct {
+	next     http.RoundTripper
+	recorder *FlightRecorder
+}
+
+func (t *flightTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	options := t.recorder.options
+
+	record := &Record{
+		Time:          time.Now(),
+		Method:        req.Method,
+		URL:           req.URL.Redacted(),
+		RequestHeader: req.Header.Clone(),
+	}
+
+	var requestBody *capturingBody
+	if req.Body != nil && req.Body != http.NoBody {
+		requestBody = &capturingBody{ReadCloser: req.Body, limit: options.BodyMaxSize}
+		req = req.Clone(req.Context())
+		req.Body = requestBody
+	}
+
+	resp, err := next.RoundTrip(req)
+	record.Duration = time.Since(record.Time)
+	if requestBody != nil {
+		record.RequestBody = requestBody.String()
+	}
+
+	finish := func() {
+		t.recorder.add(record)
+		if options.Failed != nil && options.Failed(req, resp, err) {
+			t.recorder.DumpRecent(req.Context())
+		}
+	}
+
+	if err != nil {
+		record.Error = err.Error()
+		finish()
+		return resp, err
+	}
+
+	record.Status = resp.StatusCode
+	record.ResponseHeader = resp.Header.Clone()
+	if resp.Body == nil || resp.Body == http.NoBody {
+		finish()
+		return resp, nil
+	}
+
+	// The exchange is recorded once the caller is done with the body, so the
+	// response is returned without waiting for any of it.
+	resp.Body = &recordingBody{
+		capturingBody: &capturingBody{ReadCloser: resp.Body, limit: options.BodyMaxSize},
+		done: func(body string, readErr error) {
+			record.ResponseBody = body
+			if readErr != nil {
+				record.Error = readErr.Error()
+			}
+			finish()
+		},
+	}
+	return resp, nil
+}
+
+// capturingBody keeps the first limit bytes read through it.
+type capturingBody struct {
+	io.ReadCloser
+	limit int64
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	err   error
+}
+
+func (c *capturingBody) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+
+	c.mu.Lock()
+	if room := c.limit - int64(c.buf.Len()); room > 0 {
+		c.buf.Write(p[:min(int64(n), room)])
+	}
+	if err != nil && err != io.EOF && c.err == nil {
+		c.err = err
+	}
+	c.mu.Unlock()
+
+	return n, err
+}
+
+func (c *capturingBody) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.String()
+}
+
+// recordingBody calls done with the captured bytes and the first read error
+// when the body is closed.
+type recordingBody struct {
+	*capturingBody
+	once sync.Once
+	done func(body string, readErr error)
+}
+
+func (b *recordingBody) Close() error {
+	err := b.capturingBody.Close()
+	b.once.Do(func() {
+		b.capturingBody.mu.Lock()
+		body, readErr := b.capturingBody.buf.String(), b.capturingBody.err
+		b.capturingBody.mu.Unlock()
+		b.done(body, readErr)
+	})
+	return err
+}
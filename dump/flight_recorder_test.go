@@ -0,0 +1,117 @@
+package dump
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	fetch "github.com/rockcookies/go-fetch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlightRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, _ := strconv.Atoi(r.URL.Query().Get("status"))
+		w.WriteHeader(status)
+		w.Write([]byte("response body " + r.URL.Query().Get("status")))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		statuses    []int
+		wantLogged  int
+		wantRecords []string
+	}{
+		{name: "successes are not logged", statuses: []int{200, 201}, wantLogged: 0, wantRecords: []string{"200", "201"}},
+		{name: "failure dumps recent records", statuses: []int{200, 201, 500}, wantLogged: 3, wantRecords: []string{"200", "201", "500"}},
+		{name: "ring buffer keeps the last records", statuses: []int{200, 201, 202, 203, 502}, wantLogged: 3, wantRecords: []string{"202", "203", "502"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			options := DefaultFlightRecorderOptions()
+			options.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+			options.Size = 3
+			options.BodyMaxSize = 10
+			recorder := NewFlightRecorder(options)
+
+			dispatcher := fetch.NewDispatcher(nil, recorder.Middleware())
+			for _, status := range tt.statuses {
+				resp := dispatcher.NewRequest().Body(strings.NewReader("request payload")).
+					Post(server.URL + "?status=" + strconv.Itoa(status))
+				require.NoError(t, resp.Error)
+				assert.Equal(t, "response body "+strconv.Itoa(status), resp.String())
+				resp.Close()
+			}
+
+			assert.Equal(t, tt.wantLogged, strings.Count(logs.String(), "HTTP flight record"))
+
+			records := recorder.Recent()
+			require.Len(t, records, len(tt.wantRecords))
+			for i, record := range records {
+				assert.Equal(t, tt.wantRecords[i], strconv.Itoa(record.Status))
+				assert.Equal(t, http.MethodPost, record.Method)
+				assert.Equal(t, "request pa", record.RequestBody)
+				assert.Equal(t, "response b", record.ResponseBody)
+			}
+		})
+	}
+}
+
+func TestFlightRecorder_DumpRecent(t *testing.T) {
+	var logs bytes.Buffer
+	options := DefaultFlightRecorderOptions()
+	options.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	recorder := NewFlightRecorder(options)
+
+	resp := fetch.NewDispatcher(nil, recorder.Middleware()).NewRequest().Get("http://127.0.0.1:1/")
+	require.Error(t, resp.Error)
+
+	assert.Equal(t, 1, strings.Count(logs.String(), "HTTP flight record"))
+	assert.Contains(t, logs.String(), "error=")
+
+	logs.Reset()
+	recorder.DumpRecent(context.Background())
+	assert.Contains(t, logs.String(), "http.url=http://127.0.0.1:1/")
+}
+
+func TestFlightRecorder_Streaming(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: first\n"))
+		w.(http.Flusher).Flush()
+		<-release
+		w.Write([]byte("data: second\n"))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	options := DefaultFlightRecorderOptions()
+	options.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	recorder := NewFlightRecorder(options)
+
+	resp := fetch.NewDispatcher(nil, recorder.Middleware()).NewRequest().Get(server.URL)
+	require.NoError(t, resp.Error)
+
+	// The first event arrives while the server is still holding the stream.
+	line, err := bufio.NewReader(resp.RawResponse.Body).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "data: first\n", line)
+	assert.Empty(t, recorder.Recent())
+
+	require.NoError(t, resp.RawResponse.Body.Close())
+	records := recorder.Recent()
+	require.Len(t, records, 1)
+	assert.Equal(t, http.StatusOK, records[0].Status)
+	assert.Contains(t, records[0].ResponseBody, "data: first\n")
+}
@@ -0,0 +1,226 @@
+package dump
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JSONHandlerOptions configures a JSONHandler.
+type JSONHandlerOptions struct {
+	// Level is the minimum level logged. Nil logs info and above.
+	Level slog.Leveler
+	// Compact writes each record on a single line instead of indented.
+	Compact bool
+	// Exclude lists keys left out of the output, as dotted paths through
+	// groups such as "time" or "response_headers.Date". Matching ignores case.
+	Exclude []string
+}
+
+// JSONHandler is a slog.Handler that writes records as JSON with stable
+// output: object keys are sorted at every level, times are formatted as
+// RFC 3339 in UTC and durations as strings. Combined with Exclude for
+// volatile keys, dumps of the same exchange diff cleanly across runs.
+// It is safe for concurrent use.
+type JSONHandler struct {
+	w       io.Writer
+	mu      *sync.Mutex
+	options JSONHandlerOptions
+	attrs   map[string]any
+	groups  []string
+}
+
+// NewJSONHandler creates a JSONHandler writing to w. Nil options use the
+// defaults.
+//
+// Example:
+//
+//	handler := dump.NewJSONHandler(os.Stderr, &dump.JSONHandlerOptions{
+//	    Level:   slog.LevelDebug,
+//	    Exclude: []string{"time", "duration", "duration_ms", "response_headers.Date"},
+//	})
+//	opts := dump.DefaultOptions()
+//	opts.Logger = slog.New(handler)
+//	client.Transport = dump.NewRoundTripperWithOptions(client.Transport, opts)
+func NewJSONHandler(w io.Writer, opts *JSONHandlerOptions) *JSONHandler {
+	h := &JSONHandler{w: w, mu: &sync.Mutex{}, attrs: map[string]any{}}
+	if opts != nil {
+		h.options = *opts
+	}
+	return h
+}
+
+// Enabled reports whether the handler logs records at level.
+func (h *JSONHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.options.Level != nil {
+		minLevel = h.options.Level.Level()
+	}
+	return level >= minLevel
+}
+
+// Handle writes the record as one JSON document followed by a newline.
+func (h *JSONHandler) Handle(_ context.Context, r slog.Record) error {
+	root := cloneObject(h.attrs)
+	if !r.Time.IsZero() {
+		h.set(root, nil, slog.Time(slog.TimeKey, r.Time))
+	}
+	h.set(root, nil, slog.Any(slog.LevelKey, r.Level))
+	h.set(root, nil, slog.String(slog.MessageKey, r.Message))
+
+	obj := h.group(root, h.groups)
+	r.Attrs(func(a slog.Attr) bool {
+		h.set(obj, h.groups, a)
+		return true
+	})
+	pruneEmpty(root)
+
+	var (
+		data []byte
+		err  error
+	)
+	if h.options.Compact {
+		data, err = json.Marshal(root)
+	} else {
+		data, err = json.MarshalIndent(root, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err = h.w.Write(append(data, '\n'))
+	return err
+}
+
+// WithAttrs returns a handler that includes attrs in every record.
+func (h *JSONHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := h.clone()
+	obj := clone.group(clone.attrs, clone.groups)
+	for _, a := range attrs {
+		clone.set(obj, clone.groups, a)
+	}
+	return clone
+}
+
+// WithGroup returns a handler that nests subsequent attrs under name.
+func (h *JSONHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := h.clone()
+	clone.groups = append(slices.Clip(clone.groups), name)
+	return clone
+}
+
+func (h *JSONHandler) clone() *JSONHandler {
+	clone := *h
+	clone.attrs = cloneObject(h.attrs)
+	return &clone
+}
+
+// group returns the object at path below obj, creating it when missing.
+func (h *JSONHandler) group(obj map[string]any, path []string) map[string]any {
+	for _, name := range path {
+		child, ok := obj[name].(map[string]any)
+		if !ok {
+			child = map[string]any{}
+			obj[name] = child
+		}
+		obj = child
+	}
+	return obj
+}
+
+// set stores a into obj, which lives at path, unless it is excluded.
+func (h *JSONHandler) set(obj map[string]any, path []string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		if a.Key == "" {
+			for _, attr := range attrs {
+				h.set(obj, path, attr)
+			}
+			return
+		}
+		path = append(slices.Clip(path), a.Key)
+		if h.excluded(path) {
+			return
+		}
+		child := h.group(obj, []string{a.Key})
+		for _, attr := range attrs {
+			h.set(child, path, attr)
+		}
+		return
+	}
+
+	if h.excluded(append(slices.Clip(path), a.Key)) {
+		return
+	}
+	obj[a.Key] = jsonValue(a.Value)
+}
+
+func (h *JSONHandler) excluded(path []string) bool {
+	if len(h.options.Exclude) == 0 {
+		return false
+	}
+	key := strings.Join(path, ".")
+	for _, exclude := range h.options.Exclude {
+		if strings.EqualFold(exclude, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindTime:
+		return v.Time().UTC().Format(time.RFC3339Nano)
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindAny:
+		switch value := v.Any().(type) {
+		case error:
+			return value.Error()
+		case slog.Level:
+			return value.String()
+		}
+		return v.Any()
+	default:
+		return v.Any()
+	}
+}
+
+func cloneObject(obj map[string]any) map[string]any {
+	clone := make(map[string]any, len(obj))
+	for key, value := range obj {
+		if child, ok := value.(map[string]any); ok {
+			value = cloneObject(child)
+		}
+		clone[key] = value
+	}
+	return clone
+}
+
+// pruneEmpty removes empty groups, as slog.JSONHandler does.
+func pruneEmpty(obj map[string]any) {
+	for key, value := range obj {
+		if child, ok := value.(map[string]any); ok {
+			pruneEmpty(child)
+			if len(child) == 0 {
+				delete(obj, key)
+			}
+		}
+	}
+}
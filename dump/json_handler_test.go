@@ -0,0 +1,139 @@
+package dump
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONHandler(t *testing.T) {
+	at := time.Date(2024, 5, 1, 14, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+
+	tests := []struct {
+		name    string
+		options *JSONHandlerOptions
+		logger  func(*slog.Logger) *slog.Logger
+		attrs   []slog.Attr
+		want    string
+	}{
+		{
+			name:    "sorted keys and utc time",
+			options: &JSONHandlerOptions{Compact: true},
+			attrs: []slog.Attr{
+				slog.String("zeta", "z"),
+				slog.Duration("duration", 1500*time.Millisecond),
+				slog.Group("headers", slog.String("X-B", "2"), slog.String("X-A", "1")),
+				slog.Any("error", errors.New("boom")),
+			},
+			want: `{"duration":"1.5s","error":"boom","headers":{"X-A":"1","X-B":"2"},"level":"INFO","msg":"done","time":"2024-05-01T12:30:00Z","zeta":"z"}`,
+		},
+		{
+			name:    "exclusions ignore case",
+			options: &JSONHandlerOptions{Compact: true, Exclude: []string{"time", "headers.date", "body"}},
+			attrs: []slog.Attr{
+				slog.Group("headers", slog.String("Date", "now"), slog.String("Accept", "*/*")),
+				slog.Group("body", slog.String("content", "secret")),
+			},
+			want: `{"headers":{"Accept":"*/*"},"level":"INFO","msg":"done"}`,
+		},
+		{
+			name:    "groups and empty groups",
+			options: &JSONHandlerOptions{Compact: true, Exclude: []string{"time"}},
+			logger: func(l *slog.Logger) *slog.Logger {
+				return l.With("service", "api").WithGroup("http").With("method", "GET")
+			},
+			attrs: []slog.Attr{slog.Int("status", 200), slog.Group("empty")},
+			want:  `{"http":{"method":"GET","status":200},"level":"INFO","msg":"done","service":"api"}`,
+		},
+		{
+			name:    "indented by default",
+			options: &JSONHandlerOptions{Exclude: []string{"time"}},
+			attrs:   []slog.Attr{slog.Bool("ok", true)},
+			want:    "{\n  \"level\": \"INFO\",\n  \"msg\": \"done\",\n  \"ok\": true\n}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewJSONHandler(&buf, tt.options))
+			if tt.logger != nil {
+				logger = tt.logger(logger)
+			}
+
+			record := slog.NewRecord(at, slog.LevelInfo, "done", 0)
+			record.AddAttrs(tt.attrs...)
+			require.NoError(t, logger.Handler().Handle(context.Background(), record))
+			assert.Equal(t, tt.want+"\n", buf.String())
+		})
+	}
+}
+
+func TestJSONHandler_Level(t *testing.T) {
+	var buf bytes.Buffer
+	handler := NewJSONHandler(&buf, nil)
+	assert.False(t, handler.Enabled(context.Background(), slog.LevelDebug))
+	assert.True(t, handler.Enabled(context.Background(), slog.LevelInfo))
+
+	handler = NewJSONHandler(&buf, &JSONHandlerOptions{Level: slog.LevelDebug})
+	assert.True(t, handler.Enabled(context.Background(), slog.LevelDebug))
+}
+
+func TestJSONHandler_StableDumps(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, name := range []string{"X-C", "X-A", "X-D", "X-B"} {
+			w.Header().Set(name, "v")
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	dumpOnce := func() string {
+		var buf bytes.Buffer
+		opts := DefaultOptions()
+		opts.Logger = slog.New(NewJSONHandler(&buf, &JSONHandlerOptions{
+			Level:   slog.LevelDebug,
+			Compact: true,
+			Exclude: []string{"time", "duration", "duration_ms", "response_headers.Date"},
+		}))
+		rt := NewRoundTripperWithOptions(http.DefaultTransport, opts)
+
+		req := httptest.NewRequest(http.MethodGet, server.URL, nil)
+		req.RequestURI = ""
+		for _, name := range []string{"X-Z", "X-Y", "X-X"} {
+			req.Header.Set(name, "v")
+		}
+		resp, err := rt.RoundTrip(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return buf.String()
+	}
+
+	first := dumpOnce()
+	assert.NotContains(t, first, `"Date"`)
+	assert.Equal(t, 1, strings.Count(first, "\n"))
+	for range 5 {
+		assert.Equal(t, first, dumpOnce())
+	}
+}
+
+func TestGetHeaderAttrs_Sorted(t *testing.T) {
+	header := http.Header{"X-C": {"3"}, "X-A": {"1"}, "X-B": {"2", "22"}}
+	attrs := getHeaderAttrs(header, nil)
+	require.Len(t, attrs, 3)
+
+	var keys []string
+	for _, attr := range attrs {
+		keys = append(keys, attr.(slog.Attr).Key)
+	}
+	assert.Equal(t, []string{"X-A", "X-B", "X-C"}, keys)
+}
@@ -5,7 +5,9 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/rockcookies/go-fetch/internal/utils"
@@ -16,6 +18,7 @@ import (
 type Options struct {
 	Skippers             []func(req *http.Request) bool
 	Logger               *slog.Logger
+	ContextLogger        func(ctx context.Context) *slog.Logger
 	LogLevel             slog.Level
 	LogLevelFunc         func(req *http.Request, status int) slog.Level
 	Filters              []Filter
@@ -27,6 +30,9 @@ type Options struct {
 	ResponseBodyMaxSize  int64
 	ResponseHeaderFilter func(key string, value []string) []any
 	ResponseAttrs        func(*http.Response, time.Duration) []slog.Attr
+	// Timings adds a "timings" group with the DNS, connect, TLS, got-conn and
+	// first-byte phases of the request, measured with net/http/httptrace.
+	Timings bool
 }
 
 // DefaultOptions returns sensible default options for the dump middleware.
@@ -70,6 +76,35 @@ func SkipDump(ctx context.Context) context.Context {
 	return skipKey.WithValue(ctx, true)
 }
 
+var loggerKey = utils.NewContextKey[*slog.Logger]("fetch_dump_logger")
+
+// WithLogger returns a context carrying a request-scoped logger.
+// Set Options.ContextLogger to LoggerFromContext so client logs inherit
+// the fields of that logger, such as tenant or trace IDs.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return loggerKey.WithValue(ctx, logger)
+}
+
+// LoggerFromContext returns the logger stored by WithLogger, or nil.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	logger, _ := loggerKey.GetValue(ctx)
+	return logger
+}
+
+// resolveLogger picks the request-scoped logger when available, then the
+// configured logger, then slog.Default.
+func resolveLogger(ctx context.Context, configured *slog.Logger, contextLogger func(ctx context.Context) *slog.Logger) *slog.Logger {
+	if contextLogger != nil {
+		if logger := contextLogger(ctx); logger != nil {
+			return logger
+		}
+	}
+	if configured != nil {
+		return configured
+	}
+	return slog.Default()
+}
+
 // NewRoundTripper creates a new dump RoundTripper with dynamic options.
 // The optionsFunc is called for each request to determine logging behavior.
 func NewRoundTripper(next http.RoundTripper, optionsFunc func(req *http.Request) *Options) *RoundTripper {
@@ -119,6 +154,12 @@ func (rt *RoundTripper) RoundTrip(req *http.Request) (resp *http.Response, err e
 		}
 	}
 
+	var timings *phaseTimings
+	if options.Timings {
+		timings = &phaseTimings{}
+		req = timings.trace(req)
+	}
+
 	start := time.Now()
 
 	defer func() {
@@ -145,10 +186,7 @@ func (rt *RoundTripper) RoundTrip(req *http.Request) (resp *http.Response, err e
 			}
 		}
 
-		logger := options.Logger
-		if logger == nil {
-			logger = slog.Default()
-		}
+		logger := resolveLogger(req.Context(), options.Logger, options.ContextLogger)
 
 		level := options.LogLevel
 		if options.LogLevelFunc != nil {
@@ -174,6 +212,10 @@ func (rt *RoundTripper) RoundTrip(req *http.Request) (resp *http.Response, err e
 			slog.Group("request_body", getDrainedBodyAttrs(requestBody)...),
 		}
 
+		if timings != nil {
+			attrs = append(attrs, slog.Group("timings", timings.attrs()...))
+		}
+
 		if options.RequestAttrs != nil {
 			attrs = append(attrs, options.RequestAttrs(req)...)
 		}
@@ -262,7 +304,7 @@ func getDrainedBodyAttrs(db *drainedBody) []any {
 
 func getHeaderAttrs(header http.Header, filter func(key string, value []string) []any) []any {
 	attrs := make([]any, 0, len(header))
-	for key := range header {
+	for _, key := range slices.Sorted(maps.Keys(header)) {
 		vals := header.Values(key)
 
 		if filter != nil {
@@ -632,3 +632,74 @@ func TestRequestURL(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveLogger(t *testing.T) {
+	configured := slog.New(slog.NewTextHandler(io.Discard, nil))
+	scoped := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctxWithLogger := WithLogger(context.Background(), scoped)
+
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		configured    *slog.Logger
+		contextLogger func(ctx context.Context) *slog.Logger
+		expected      *slog.Logger
+	}{
+		{
+			name:          "context logger wins",
+			ctx:           ctxWithLogger,
+			configured:    configured,
+			contextLogger: LoggerFromContext,
+			expected:      scoped,
+		},
+		{
+			name:          "falls back to configured logger",
+			ctx:           context.Background(),
+			configured:    configured,
+			contextLogger: LoggerFromContext,
+			expected:      configured,
+		},
+		{
+			name:       "context logger not enabled",
+			ctx:        ctxWithLogger,
+			configured: configured,
+			expected:   configured,
+		},
+		{
+			name:     "falls back to default logger",
+			ctx:      context.Background(),
+			expected: slog.Default(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Same(t, tt.expected, resolveLogger(tt.ctx, tt.configured, tt.contextLogger))
+		})
+	}
+}
+
+func TestRoundTripperContextLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var configuredBuf, scopedBuf bytes.Buffer
+	opts := DefaultOptions()
+	opts.Logger = slog.New(slog.NewTextHandler(&configuredBuf, nil))
+	opts.ContextLogger = LoggerFromContext
+
+	scoped := slog.New(slog.NewTextHandler(&scopedBuf, nil)).With("tenant", "acme")
+
+	rt := NewRoundTripperWithOptions(http.DefaultTransport, opts)
+	req := httptest.NewRequest("GET", server.URL, nil)
+	req = req.WithContext(WithLogger(req.Context(), scoped))
+
+	resp, err := rt.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	assert.Empty(t, configuredBuf.String())
+	assert.Contains(t, scopedBuf.String(), "tenant=acme")
+}
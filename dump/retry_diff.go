@@ -0,0 +1,241 @@
+package dump
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	fetch "github.com/rockcookies/go-fetch"
+	"github.com/rockcookies/go-fetch/internal/utils"
+)
+
+// RetryDiffOptions configures the retry diff middleware.
+type RetryDiffOptions struct {
+	Logger          *slog.Logger
+	ContextLogger   func(ctx context.Context) *slog.Logger
+	LogLevel        slog.Level
+	Headers         []string
+	BodySnippetSize int
+	// BodyMaxSize is the number of body bytes buffered per attempt for the
+	// hash; longer bodies are hashed over this prefix and the rest streams
+	// through. Zero uses 1MB.
+	BodyMaxSize int64
+}
+
+// defaultRetryDiffBodyMaxSize is used when RetryDiffOptions.BodyMaxSize is zero.
+const defaultRetryDiffBodyMaxSize = 1024 * 1024 // 1MB
+
+// DefaultRetryDiffOptions returns sensible default options for the retry diff middleware.
+// Compares Content-Type, Content-Length and Retry-After and keeps a 256 byte body snippet.
+func DefaultRetryDiffOptions() *RetryDiffOptions {
+	return &RetryDiffOptions{
+		Logger:          slog.Default(),
+		LogLevel:        slog.LevelDebug,
+		Headers:         []string{"Content-Type", "Content-Length", "Retry-After"},
+		BodySnippetSize: 256,
+		BodyMaxSize:     defaultRetryDiffBodyMaxSize,
+	}
+}
+
+// attemptSnapshot captures the parts of a single attempt that are compared.
+type attemptSnapshot struct {
+	status  int
+	headers map[string]string
+	hash    string
+	snippet string
+	err     string
+}
+
+type retryTracker struct {
+	mu       sync.Mutex
+	attempts int
+	first    *attemptSnapshot
+	last     *attemptSnapshot
+}
+
+func (t *retryTracker) record(s *attemptSnapshot) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.attempts++
+	if t.first == nil {
+		t.first = s
+	}
+	t.last = s
+}
+
+// snapshot returns the attempt count and the first and last attempts.
+// Attempts may still be recorded concurrently, e.g. by losing hedged requests
+// finishing after the handler returned.
+func (t *retryTracker) snapshot() (attempts int, first, last *attemptSnapshot) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.attempts, t.first, t.last
+}
+
+var retryTrackerKey = utils.NewContextKey[*retryTracker]("fetch_dump_retry_tracker")
+
+// RetryDiff returns a middleware that records every attempt made for a request
+// and, when more than one attempt was needed, logs a compact summary comparing
+// the first and the final response. Intermittent upstream errors are otherwise
+// hidden by a successful retry.
+//
+// The middleware must be placed outside (before) the retry middleware so that
+// all attempts share the same tracker. Attempts are observed at the transport
+// level, therefore response bodies are buffered up to BodyMaxSize to compute
+// their hash.
+func RetryDiff(options *RetryDiffOptions) fetch.Middleware {
+	if options == nil {
+		options = DefaultRetryDiffOptions()
+	}
+
+	return func(next fetch.Handler) fetch.Handler {
+		return fetch.HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			tracker := &retryTracker{}
+			req = req.WithContext(retryTrackerKey.WithValue(req.Context(), tracker))
+
+			c := *client
+			c.Transport = &attemptRecorder{next: client.Transport, options: options}
+
+			resp, err := next.Handle(&c, req)
+
+			if attempts, first, last := tracker.snapshot(); attempts > 1 {
+				logRetryDiff(req.Context(), options, attempts, first, last)
+			}
+
+			return resp, err
+		})
+	}
+}
+
+type attemptRecorder struct {
+	next    http.RoundTripper
+	options *RetryDiffOptions
+}
+
+func (r *attemptRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := r.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	tracker, ok := retryTrackerKey.GetValue(req.Context())
+	if !ok {
+		return next.RoundTrip(req)
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		tracker.record(&attemptSnapshot{err: err.Error()})
+		return resp, err
+	}
+
+	snapshot := &attemptSnapshot{
+		status:  resp.StatusCode,
+		headers: make(map[string]string, len(r.options.Headers)),
+	}
+	for _, key := range r.options.Headers {
+		snapshot.headers[key] = resp.Header.Get(key)
+	}
+
+	if err := r.snapshotBody(resp, snapshot); err != nil {
+		resp.Body.Close()
+		snapshot.err = err.Error()
+		tracker.record(snapshot)
+		return nil, err
+	}
+
+	tracker.record(snapshot)
+	return resp, nil
+}
+
+// snapshotBody hashes the first BodyMaxSize bytes of the response body and
+// puts them back in front of the rest.
+func (r *attemptRecorder) snapshotBody(resp *http.Response, snapshot *attemptSnapshot) error {
+	if resp.Body == nil || resp.Body == http.NoBody {
+		return nil
+	}
+
+	limit := r.options.BodyMaxSize
+	if limit <= 0 {
+		limit = defaultRetryDiffBodyMaxSize
+	}
+	head, err := io.ReadAll(io.LimitReader(resp.Body, limit))
+	if err != nil {
+		return err
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+
+	sum := sha256.Sum256(head)
+	snapshot.hash = hex.EncodeToString(sum[:])
+
+	snippet := head
+	if r.options.BodySnippetSize >= 0 && len(snippet) > r.options.BodySnippetSize {
+		snippet = snippet[:r.options.BodySnippetSize]
+	}
+	snapshot.snippet = string(snippet)
+	return nil
+}
+
+func logRetryDiff(ctx context.Context, options *RetryDiffOptions, attempts int, first, last *attemptSnapshot) {
+	logger := resolveLogger(ctx, options.Logger, options.ContextLogger)
+
+	if !logger.Enabled(ctx, options.LogLevel) {
+		return
+	}
+
+	changed := []string{}
+	if first.status != last.status {
+		changed = append(changed, "status")
+	}
+	if first.err != last.err {
+		changed = append(changed, "error")
+	}
+	for _, key := range options.Headers {
+		if first.headers[key] != last.headers[key] {
+			changed = append(changed, key)
+		}
+	}
+	if first.hash != last.hash {
+		changed = append(changed, "body")
+	}
+
+	logger.LogAttrs(ctx, options.LogLevel, "HTTP request retried",
+		slog.Int("attempts", attempts),
+		slog.Any("changed", changed),
+		slog.Group("first", getSnapshotAttrs(first, options.Headers)...),
+		slog.Group("final", getSnapshotAttrs(last, options.Headers)...),
+	)
+}
+
+func getSnapshotAttrs(s *attemptSnapshot, headers []string) []any {
+	if s.err != "" && s.status == 0 {
+		return []any{slog.String("error", s.err)}
+	}
+
+	attrs := []any{
+		slog.Int("status", s.status),
+		slog.String("body_sha256", s.hash),
+		slog.String("body_snippet", s.snippet),
+	}
+
+	for _, key := range headers {
+		if value := s.headers[key]; value != "" {
+			attrs = append(attrs, slog.String(key, value))
+		}
+	}
+
+	if s.err != "" {
+		attrs = append(attrs, slog.String("error", s.err))
+	}
+
+	return attrs
+}
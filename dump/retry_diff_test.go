@@ -0,0 +1,195 @@
+package dump
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+
+	fetch "github.com/rockcookies/go-fetch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// retryTwice is a minimal retry middleware used to drive RetryDiff in tests.
+func retryTwice(next fetch.Handler) fetch.Handler {
+	return fetch.HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+		resp, err := next.Handle(client, req)
+		if err == nil && resp.StatusCode < 500 {
+			return resp, nil
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return next.Handle(client, req)
+	})
+}
+
+func TestRetryDiff(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		shouldLog  bool
+		wantInLogs []string
+	}{
+		{
+			name:      "single attempt is not logged",
+			statuses:  []int{200},
+			shouldLog: false,
+		},
+		{
+			name:       "retried attempt logs diff",
+			statuses:   []int{503, 200},
+			shouldLog:  true,
+			wantInLogs: []string{"HTTP request retried", "attempts=2", "first.status=503", "final.status=200", "status", "body"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				i := int(calls.Add(1)) - 1
+				w.WriteHeader(tt.statuses[i])
+				w.Write([]byte(http.StatusText(tt.statuses[i])))
+			}))
+			defer server.Close()
+
+			var logBuf bytes.Buffer
+			options := DefaultRetryDiffOptions()
+			options.Logger = slog.New(slog.NewTextHandler(&logBuf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+			dispatcher := fetch.NewDispatcher(nil, RetryDiff(options), retryTwice)
+			resp := dispatcher.NewRequest().Get(server.URL)
+			require.NoError(t, resp.Error)
+			assert.Equal(t, "OK", resp.String())
+
+			logOutput := logBuf.String()
+			if !tt.shouldLog {
+				assert.Empty(t, logOutput)
+				return
+			}
+			for _, want := range tt.wantInLogs {
+				assert.Contains(t, logOutput, want)
+			}
+		})
+	}
+}
+
+func TestRetryDiffTransportError(t *testing.T) {
+	var calls atomic.Int32
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if calls.Add(1) == 1 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return &http.Response{StatusCode: 200, Header: http.Header{}, Body: io.NopCloser(bytes.NewReader(nil))}, nil
+	})
+
+	var logBuf bytes.Buffer
+	options := DefaultRetryDiffOptions()
+	options.Logger = slog.New(slog.NewTextHandler(&logBuf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	dispatcher := fetch.NewDispatcherWithTransport(transport, RetryDiff(options), retryTwice)
+	resp := dispatcher.NewRequest().Get("http://example.com")
+	require.NoError(t, resp.Error)
+
+	logOutput := logBuf.String()
+	assert.Contains(t, logOutput, "first.error")
+	assert.Contains(t, logOutput, "final.status=200")
+}
+
+func TestRetryDiffLateAttempts(t *testing.T) {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Header: http.Header{}, Body: http.NoBody}, nil
+	})
+
+	// hedged keeps sending attempts after it returned, like a losing hedge.
+	var wg sync.WaitGroup
+	hedged := func(next fetch.Handler) fetch.Handler {
+		return fetch.HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 10 {
+					resp, err := next.Handle(client, req)
+					if err == nil {
+						resp.Body.Close()
+					}
+				}
+			}()
+			first, err := next.Handle(client, req)
+			require.NoError(t, err)
+			first.Body.Close()
+			return next.Handle(client, req)
+		})
+	}
+
+	options := DefaultRetryDiffOptions()
+	options.Logger = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	dispatcher := fetch.NewDispatcherWithTransport(transport, RetryDiff(options), hedged)
+	resp := dispatcher.NewRequest().Get("http://example.com")
+	require.NoError(t, resp.Error)
+	assert.Equal(t, http.StatusOK, resp.RawResponse.StatusCode)
+	resp.Close()
+	wg.Wait()
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRetryDiffBodies(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        io.ReadCloser
+		bodyMaxSize int64
+		wantBody    string
+		wantErr     bool
+	}{
+		{name: "body above max size streams through", body: io.NopCloser(strings.NewReader("0123456789")), bodyMaxSize: 4, wantBody: "0123456789"},
+		{name: "body read error", body: &closeTracker{Reader: iotest.ErrReader(io.ErrUnexpectedEOF)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				return &http.Response{StatusCode: 200, Header: http.Header{}, Body: tt.body}, nil
+			})
+			options := DefaultRetryDiffOptions()
+			options.BodyMaxSize = tt.bodyMaxSize
+
+			req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+			req = req.WithContext(retryTrackerKey.WithValue(req.Context(), &retryTracker{}))
+			resp, err := (&attemptRecorder{next: transport, options: options}).RoundTrip(req)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Nil(t, resp)
+				assert.True(t, tt.body.(*closeTracker).closed)
+				return
+			}
+			require.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantBody, string(body))
+		})
+	}
+}
+
+type closeTracker struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeTracker) Close() error {
+	c.closed = true
+	return nil
+}
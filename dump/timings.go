@@ -0,0 +1,111 @@
+package dump
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// phaseTimings records connection phases of one request through httptrace.
+type phaseTimings struct {
+	mu        sync.Mutex
+	start     time.Time
+	dnsStart  time.Time
+	dns       time.Duration
+	connStart time.Time
+	connect   time.Duration
+	tlsStart  time.Time
+	tls       time.Duration
+	gotConn   time.Duration
+	reused    bool
+	addr      string
+	firstByte time.Duration
+}
+
+// trace returns req with a client trace feeding t.
+func (t *phaseTimings) trace(req *http.Request) *http.Request {
+	t.start = time.Now()
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.dns = time.Since(t.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if t.connStart.IsZero() {
+				t.connStart = time.Now()
+			}
+		},
+		ConnectDone: func(_, _ string, err error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			if err == nil {
+				t.connect = time.Since(t.connStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.tls = time.Since(t.tlsStart)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.gotConn = time.Since(t.start)
+			t.reused = info.Reused
+			if info.Conn != nil {
+				t.addr = info.Conn.RemoteAddr().String()
+			}
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.firstByte = time.Since(t.start)
+		},
+	}
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+// attrs returns the recorded phases; phases that did not happen, such as
+// DNS and connect on a reused connection, are omitted.
+func (t *phaseTimings) attrs() []any {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	attrs := []any{slog.Bool("reused", t.reused)}
+	if t.addr != "" {
+		attrs = append(attrs, slog.String("remote_addr", t.addr))
+	}
+	for _, phase := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"dns", t.dns},
+		{"connect", t.connect},
+		{"tls", t.tls},
+		{"got_conn", t.gotConn},
+		{"first_byte", t.firstByte},
+	} {
+		if phase.d > 0 {
+			attrs = append(attrs, slog.String(phase.name, formatDuration(phase.d)))
+		}
+	}
+	return attrs
+}
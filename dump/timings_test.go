@@ -0,0 +1,66 @@
+package dump
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTripperTimings(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		timings  bool
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "first request on new connection",
+			timings:  true,
+			contains: []string{"timings.reused=false", "timings.connect=", "timings.tls=", "timings.got_conn=", "timings.first_byte=", "timings.remote_addr="},
+		},
+		{
+			name:     "second request reuses connection",
+			timings:  true,
+			contains: []string{"timings.reused=true", "timings.first_byte="},
+			excludes: []string{"timings.tls=", "timings.connect="},
+		},
+		{
+			name:     "disabled",
+			excludes: []string{"timings."},
+		},
+	}
+
+	transport := server.Client().Transport
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logBuf bytes.Buffer
+			opts := DefaultOptions()
+			opts.Logger = slog.New(slog.NewTextHandler(&logBuf, nil))
+			opts.Timings = tt.timings
+
+			client := &http.Client{Transport: NewRoundTripperWithOptions(transport, opts)}
+			resp, err := client.Get(server.URL)
+			require.NoError(t, err)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+
+			for _, s := range tt.contains {
+				assert.Contains(t, logBuf.String(), s)
+			}
+			for _, s := range tt.excludes {
+				assert.NotContains(t, logBuf.String(), s)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrUnexpectedStatus is matched by errors.Is for every UnexpectedStatusError.
+var ErrUnexpectedStatus = errors.New("unexpected status")
+
+// UnexpectedStatusError reports a response status that an endpoint did not declare.
+type UnexpectedStatusError struct {
+	Endpoint   string
+	StatusCode int
+	Expected   []int
+}
+
+// Error returns the error message.
+func (e *UnexpectedStatusError) Error() string {
+	return fmt.Sprintf("endpoint %s: unexpected status %d, expected one of %v", e.Endpoint, e.StatusCode, e.Expected)
+}
+
+// Is reports whether target is ErrUnexpectedStatus.
+func (e *UnexpectedStatusError) Is(target error) bool {
+	return target == ErrUnexpectedStatus
+}
+
+// Endpoint declares the status codes a group of requests is expected to return.
+type Endpoint struct {
+	Name     string
+	Match    Matcher
+	Expected []int
+	// Policy is applied by EndpointRegistry.PolicyMiddleware; see SetPolicy.
+	Policy *ResiliencePolicy
+
+	unexpected atomic.Uint64
+}
+
+// Unexpected returns how many responses with undeclared statuses were seen.
+func (e *Endpoint) Unexpected() uint64 {
+	return e.unexpected.Load()
+}
+
+// EndpointOptions configures the endpoint registry.
+type EndpointOptions struct {
+	// OnUnexpected is called with the error for every undeclared status.
+	OnUnexpected func(req *http.Request, err *UnexpectedStatusError)
+	// FailOnUnexpected turns undeclared statuses into request errors.
+	// The response body is closed in that case.
+	FailOnUnexpected bool
+}
+
+// EndpointRegistry holds endpoint contracts and checks responses against them.
+// It is safe for concurrent use.
+type EndpointRegistry struct {
+	mu        sync.RWMutex
+	endpoints []*Endpoint
+	options   *EndpointOptions
+}
+
+// NewEndpointRegistry creates an empty endpoint registry.
+func NewEndpointRegistry(opts ...func(*EndpointOptions)) *EndpointRegistry {
+	return &EndpointRegistry{options: applyOptions(&EndpointOptions{}, opts...)}
+}
+
+// Register declares an endpoint and the status codes it may return.
+// Endpoints are matched in registration order; the first match wins.
+func (r *EndpointRegistry) Register(name string, match Matcher, expected ...int) *Endpoint {
+	endpoint := &Endpoint{Name: name, Match: match, Expected: expected}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.endpoints = append(r.endpoints, endpoint)
+	return endpoint
+}
+
+// Endpoint returns the registered endpoint with the given name, or nil.
+func (r *EndpointRegistry) Endpoint(name string) *Endpoint {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, endpoint := range r.endpoints {
+		if endpoint.Name == name {
+			return endpoint
+		}
+	}
+	return nil
+}
+
+func (r *EndpointRegistry) match(req *http.Request) *Endpoint {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, endpoint := range r.endpoints {
+		if endpoint.Match(req) {
+			return endpoint
+		}
+	}
+	return nil
+}
+
+// Middleware returns middleware that checks every response status against the
+// endpoint matching the request. Requests matching no endpoint are not checked.
+//
+// Example:
+//
+//	registry := fetch.NewEndpointRegistry(func(o *fetch.EndpointOptions) {
+//	    o.OnUnexpected = func(req *http.Request, err *fetch.UnexpectedStatusError) {
+//	        alert(err)
+//	    }
+//	})
+//	registry.Register("create-user", fetch.MatchPathPrefix("/users"), 201, 409)
+//	dispatcher.Use(registry.Middleware())
+func (r *EndpointRegistry) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			resp, err := next.Handle(client, req)
+			if err != nil {
+				return resp, err
+			}
+
+			endpoint := r.match(req)
+			if endpoint == nil || slices.Contains(endpoint.Expected, resp.StatusCode) {
+				return resp, nil
+			}
+
+			endpoint.unexpected.Add(1)
+			statusErr := &UnexpectedStatusError{
+				Endpoint:   endpoint.Name,
+				StatusCode: resp.StatusCode,
+				Expected:   endpoint.Expected,
+			}
+
+			if r.options.OnUnexpected != nil {
+				r.options.OnUnexpected(req, statusErr)
+			}
+
+			if r.options.FailOnUnexpected {
+				drainAndClose(resp)
+				return nil, statusErr
+			}
+
+			return resp, nil
+		})
+	}
+}
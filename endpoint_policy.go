@@ -0,0 +1,164 @@
+package fetch
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rockcookies/go-fetch/internal/utils"
+)
+
+var endpointPolicyKey = utils.NewContextKey[map[string]*ResiliencePolicy]("endpoint_policy")
+
+// ResiliencePolicy bundles the resilience middlewares of an endpoint, so they
+// are declared next to the endpoint instead of scattered over the chain.
+// Zero fields are not applied.
+type ResiliencePolicy struct {
+	// Timeout bounds the whole exchange including retries with TotalTimeout.
+	Timeout time.Duration
+	// Breaker rejects requests while the circuit of the host is open. Share
+	// one breaker between endpoints of the same upstream.
+	Breaker *CircuitBreaker
+	// Retry enables retries with the given options; nil disables them. Use an
+	// empty slice for the defaults of Retry.
+	Retry []func(*RetryOptions)
+	// Hedge enables hedging with the given options; nil disables it. Each
+	// retry attempt is hedged separately.
+	Hedge []func(*HedgeOptions)
+	// Middlewares run innermost, after the retries are set up, e.g. a rate
+	// limiter.
+	Middlewares []Middleware
+}
+
+// String describes the policy, e.g. "timeout=2s breaker retry(count=3)".
+func (p *ResiliencePolicy) String() string {
+	if p == nil {
+		return "none"
+	}
+
+	var parts []string
+	if p.Timeout > 0 {
+		parts = append(parts, "timeout="+p.Timeout.String())
+	}
+	if p.Breaker != nil {
+		parts = append(parts, fmt.Sprintf("breaker(threshold=%d,open=%s)", p.Breaker.options.FailureThreshold, p.Breaker.options.OpenTimeout))
+	}
+	if p.Retry != nil {
+		parts = append(parts, fmt.Sprintf("retry(count=%d)", newRetryOptions(p.Retry...).Count))
+	}
+	if p.Hedge != nil {
+		parts = append(parts, fmt.Sprintf("hedge(delay=%s)", applyOptions(&HedgeOptions{Delay: defaultHedgeDelay}, p.Hedge...).Delay))
+	}
+	if len(p.Middlewares) > 0 {
+		parts = append(parts, fmt.Sprintf("middlewares=%d", len(p.Middlewares)))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, " ")
+}
+
+// middleware returns the policy as one middleware: timeout outermost, then
+// the breaker, so an exhausted retry counts as one failure, then hedging,
+// whose transport the retries wrap, then the retries.
+func (p *ResiliencePolicy) middleware() Middleware {
+	var middlewares []Middleware
+	if p.Timeout > 0 {
+		middlewares = append(middlewares, TotalTimeout(p.Timeout))
+	}
+	if p.Breaker != nil {
+		middlewares = append(middlewares, p.Breaker.Middleware())
+	}
+	if p.Hedge != nil {
+		middlewares = append(middlewares, Hedge(p.Hedge...))
+	}
+	if p.Retry != nil {
+		middlewares = append(middlewares, Retry(p.Retry...))
+	}
+	return compose(append(middlewares, p.Middlewares...)...)
+}
+
+// SetPolicy attaches a resilience policy to the endpoint and returns it. Set
+// policies before the registry serves requests.
+//
+// Example:
+//
+//	registry.Register("search", fetch.MatchPathPrefix("/search"), 200).SetPolicy(&fetch.ResiliencePolicy{
+//	    Timeout: 2 * time.Second,
+//	    Breaker: searchBreaker,
+//	    Retry:   []func(*fetch.RetryOptions){func(o *fetch.RetryOptions) { o.Count = 2 }},
+//	})
+func (e *Endpoint) SetPolicy(policy *ResiliencePolicy) *Endpoint {
+	e.Policy = policy
+	return e
+}
+
+// PolicyMiddleware returns middleware applying the resilience policy of the
+// endpoint matching each request. Requests matching no endpoint, or an
+// endpoint without policy, pass through unchanged. Do not combine policies
+// with retries on the dispatcher, or attempts multiply.
+//
+// Policies can be replaced per environment with OverrideEndpointPolicy in a
+// Profile, since profile middlewares run first.
+func (r *EndpointRegistry) PolicyMiddleware() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			endpoint := r.match(req)
+			if endpoint == nil {
+				return next.Handle(client, req)
+			}
+
+			policy := endpoint.Policy
+			if overrides, ok := endpointPolicyKey.GetValue(req.Context()); ok {
+				if override, ok := overrides[endpoint.Name]; ok {
+					policy = override
+				}
+			}
+			if policy == nil {
+				return next.Handle(client, req)
+			}
+			return policy.middleware()(next).Handle(client, req)
+		})
+	}
+}
+
+// Describe returns one line per registered endpoint with its policy, in
+// registration order, e.g. for logging the effective configuration at start.
+func (r *EndpointRegistry) Describe() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lines := make([]string, 0, len(r.endpoints))
+	for _, endpoint := range r.endpoints {
+		lines = append(lines, fmt.Sprintf("%s: expected %v, policy %s", endpoint.Name, endpoint.Expected, endpoint.Policy))
+	}
+	return lines
+}
+
+// OverrideEndpointPolicy creates middleware replacing the policy of the named
+// endpoint for the request; nil removes it. It is meant for the middlewares
+// of a Profile, so each environment can tune its policies.
+//
+// Example:
+//
+//	dispatcher.RegisterProfile("staging", &fetch.Profile{
+//	    Middlewares: []fetch.Middleware{
+//	        fetch.OverrideEndpointPolicy("search", &fetch.ResiliencePolicy{Timeout: 10 * time.Second}),
+//	    },
+//	})
+func OverrideEndpointPolicy(name string, policy *ResiliencePolicy) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			overrides, _ := endpointPolicyKey.GetValue(req.Context())
+			overrides = maps.Clone(overrides)
+			if overrides == nil {
+				overrides = map[string]*ResiliencePolicy{}
+			}
+			overrides[name] = policy
+			req = req.WithContext(endpointPolicyKey.WithValue(req.Context(), overrides))
+			return next.Handle(client, req)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpointRegistry_PolicyMiddleware(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	fast := func(o *RetryOptions) { o.Count = 2; o.Backoff = ConstantBackoff(time.Millisecond) }
+	registry := NewEndpointRegistry()
+	registry.Register("flaky", MatchPathPrefix("/flaky"), 200).SetPolicy(&ResiliencePolicy{
+		Retry: []func(*RetryOptions){fast},
+	})
+	registry.Register("slow", MatchPathPrefix("/slow"), 200).SetPolicy(&ResiliencePolicy{
+		Timeout: 50 * time.Millisecond,
+	})
+	registry.Register("plain", MatchPathPrefix("/plain"), 200)
+
+	tests := []struct {
+		name      string
+		path      string
+		profile   []Middleware
+		wantCalls int32
+		wantErr   error
+	}{
+		{name: "retry policy", path: "/flaky", wantCalls: 3},
+		{name: "timeout policy", path: "/slow", wantCalls: 1, wantErr: ErrTotalTimeout},
+		{name: "no policy", path: "/plain", wantCalls: 1},
+		{name: "unregistered", path: "/other", wantCalls: 1},
+		{name: "overridden per environment", path: "/flaky", profile: []Middleware{
+			OverrideEndpointPolicy("flaky", &ResiliencePolicy{Retry: []func(*RetryOptions){fast, func(o *RetryOptions) { o.Count = 1 }}}),
+		}, wantCalls: 2},
+		{name: "override removes policy", path: "/flaky", profile: []Middleware{OverrideEndpointPolicy("flaky", nil)}, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls.Store(0)
+			dispatcher := NewDispatcher(nil, registry.PolicyMiddleware())
+			dispatcher.RegisterProfile("env", &Profile{Middlewares: tt.profile})
+			require.NoError(t, dispatcher.ActivateProfile("env"))
+
+			resp := dispatcher.NewRequest().Get(server.URL + tt.path)
+			resp.Close()
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, resp.Error, tt.wantErr)
+			} else {
+				require.NoError(t, resp.Error)
+			}
+			assert.Equal(t, tt.wantCalls, calls.Load())
+		})
+	}
+}
+
+func TestEndpointRegistry_Describe(t *testing.T) {
+	registry := NewEndpointRegistry()
+	registry.Register("search", MatchPathPrefix("/search"), 200).SetPolicy(&ResiliencePolicy{
+		Timeout:     2 * time.Second,
+		Breaker:     NewCircuitBreaker(),
+		Retry:       []func(*RetryOptions){},
+		Hedge:       []func(*HedgeOptions){func(o *HedgeOptions) { o.Delay = 50 * time.Millisecond }},
+		Middlewares: []Middleware{Recover()},
+	})
+	registry.Register("users", MatchPathPrefix("/users"), 200, 404)
+
+	assert.Equal(t, []string{
+		"search: expected [200], policy timeout=2s breaker(threshold=5,open=30s) retry(count=3) hedge(delay=50ms) middlewares=1",
+		"users: expected [200 404], policy none",
+	}, registry.Describe())
+	assert.Equal(t, "none", (&ResiliencePolicy{}).String())
+}
@@ -0,0 +1,74 @@
+package fetch
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpointRegistry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, _ := strconv.Atoi(r.URL.Query().Get("status"))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		path           string
+		status         int
+		failOnUnexpect bool
+		wantUnexpected uint64
+		wantHook       bool
+		wantErr        bool
+	}{
+		{name: "declared status", path: "/users", status: 201, wantUnexpected: 0},
+		{name: "undeclared status", path: "/users", status: 500, wantUnexpected: 1, wantHook: true},
+		{name: "undeclared status fails", path: "/users", status: 400, failOnUnexpect: true, wantUnexpected: 1, wantHook: true, wantErr: true},
+		{name: "unregistered endpoint is ignored", path: "/other", status: 500, wantUnexpected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hookErr *UnexpectedStatusError
+			registry := NewEndpointRegistry(func(o *EndpointOptions) {
+				o.FailOnUnexpected = tt.failOnUnexpect
+				o.OnUnexpected = func(req *http.Request, err *UnexpectedStatusError) { hookErr = err }
+			})
+			registry.Register("users", MatchPathPrefix("/users"), 200, 201)
+
+			resp := NewDispatcher(nil, registry.Middleware()).NewRequest().
+				Get(server.URL + tt.path + "?status=" + strconv.Itoa(tt.status))
+			defer resp.Close()
+
+			assert.Equal(t, tt.wantUnexpected, registry.Endpoint("users").Unexpected())
+
+			if tt.wantHook {
+				require.NotNil(t, hookErr)
+				assert.Equal(t, "users", hookErr.Endpoint)
+				assert.Equal(t, tt.status, hookErr.StatusCode)
+			} else {
+				assert.Nil(t, hookErr)
+			}
+
+			if tt.wantErr {
+				assert.ErrorIs(t, resp.Error, ErrUnexpectedStatus)
+				return
+			}
+			require.NoError(t, resp.Error)
+			assert.Equal(t, tt.status, resp.RawResponse.StatusCode)
+		})
+	}
+}
+
+func TestUnexpectedStatusError(t *testing.T) {
+	err := &UnexpectedStatusError{Endpoint: "users", StatusCode: 500, Expected: []int{200}}
+	assert.Equal(t, "endpoint users: unexpected status 500, expected one of [200]", err.Error())
+	assert.True(t, errors.Is(err, ErrUnexpectedStatus))
+	assert.Nil(t, NewEndpointRegistry().Endpoint("missing"))
+}
@@ -0,0 +1,246 @@
+// Package errfmt renders failed requests as human-friendly messages for
+// command-line tools.
+package errfmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rockcookies/go-fetch"
+)
+
+// DefaultTemplate is the template used by Render unless Options.Template is set.
+// It is executed with a Details value.
+var DefaultTemplate = template.Must(template.New("errfmt").Parse(
+	`{{with .Method}}{{.}} {{end}}{{with .URL}}{{.}}{{else}}request{{end}} failed: {{if .StatusCode}}{{.Status}}{{else}}{{.Err}}{{end}}
+{{- if .Elapsed}}
+  elapsed:    {{.Elapsed}}{{if gt .Attempts 1}} ({{.Attempts}} attempts){{end}}
+{{- else if gt .Attempts 1}}
+  attempts:   {{.Attempts}}
+{{- end}}
+{{- if .ServerMessage}}
+  server:     {{.ServerMessage}}
+{{- end}}
+{{- if .CorrelationID}}
+  request id: {{.CorrelationID}}
+{{- end}}
+{{- range .Hints}}
+hint: {{.}}
+{{- end}}
+`))
+
+// Details is the information rendered for a failed request.
+type Details struct {
+	Method string
+	URL    string
+	// StatusCode and Status are empty when no response was received.
+	StatusCode int
+	Status     string
+	// Err is the transport or middleware error, if any.
+	Err      error
+	Elapsed  time.Duration
+	Attempts int
+	// ServerMessage is the error message decoded from the response body, or
+	// the start of a textual body.
+	ServerMessage string
+	CorrelationID string
+	Hints         []string
+}
+
+// Hint adds advice to the rendered message when When reports true.
+type Hint struct {
+	When func(d *Details) bool
+	Text string
+}
+
+// StatusHint returns a Hint shown for responses with the given status.
+func StatusHint(status int, text string) Hint {
+	return Hint{When: func(d *Details) bool { return d.StatusCode == status }, Text: text}
+}
+
+// ErrorHint returns a Hint shown when the request failed with an error matching target.
+func ErrorHint(target error, text string) Hint {
+	return Hint{When: func(d *Details) bool { return errors.Is(d.Err, target) }, Text: text}
+}
+
+// Options configures Render.
+type Options struct {
+	// Template renders Details. Defaults to DefaultTemplate.
+	Template *template.Template
+	Hints    []Hint
+	// CorrelationHeaders are response headers searched in order for a request ID.
+	CorrelationHeaders []string
+	// Elapsed is the time the request took. Defaults to the longest
+	// middleware timing of the response, see fetch.Timings.
+	Elapsed time.Duration
+	// Attempts is the number of attempts made, e.g. reported by a retry policy.
+	Attempts int
+	// MaxMessage truncates ServerMessage to this many bytes. Defaults to 300.
+	MaxMessage int
+}
+
+func defaultOptions() *Options {
+	return &Options{
+		CorrelationHeaders: []string{"X-Request-Id", "X-Correlation-Id", "Request-Id", "X-Amzn-Requestid", "Traceparent"},
+		MaxMessage:         300,
+	}
+}
+
+// Render formats the failure of resp, which may hold an error or an error
+// status. The response body is buffered, so it can still be read afterwards.
+//
+// Example:
+//
+//	resp := dispatcher.NewRequest().Get(url)
+//	defer resp.Close()
+//	if resp.Error != nil || resp.RawResponse.StatusCode >= 400 {
+//	    fmt.Fprint(os.Stderr, errfmt.Render(resp, func(o *errfmt.Options) {
+//	        o.Hints = append(o.Hints, errfmt.StatusHint(http.StatusUnauthorized, "did you set AUTH_TOKEN?"))
+//	    }))
+//	    os.Exit(1)
+//	}
+func Render(resp *fetch.Response, opts ...func(*Options)) string {
+	options := defaultOptions()
+	for _, opt := range opts {
+		opt(options)
+	}
+	return render(Extract(resp, options), options)
+}
+
+// RenderError formats an error returned without a fetch.Response, such as
+// the *url.Error of a plain http.Client.
+func RenderError(err error, opts ...func(*Options)) string {
+	return Render(&fetch.Response{Error: err}, opts...)
+}
+
+// Extract collects the Details of resp without rendering them.
+func Extract(resp *fetch.Response, options *Options) *Details {
+	if options == nil {
+		options = defaultOptions()
+	}
+
+	d := &Details{Err: resp.Error, Elapsed: options.Elapsed, Attempts: options.Attempts}
+
+	if req := resp.RawRequest; req != nil && req.URL != nil {
+		d.Method = req.Method
+		d.URL = req.URL.Redacted()
+	}
+	var urlErr *url.Error
+	if errors.As(resp.Error, &urlErr) {
+		if d.Method == "" {
+			d.Method = strings.ToUpper(urlErr.Op)
+		}
+		if d.URL == "" {
+			d.URL = urlErr.URL
+		}
+		d.Err = urlErr.Err
+	}
+
+	if d.Elapsed == 0 {
+		for _, timing := range resp.Timings() {
+			d.Elapsed = max(d.Elapsed, timing.Total)
+		}
+	}
+	d.Elapsed = d.Elapsed.Round(time.Millisecond)
+
+	if resp.Error == nil && resp.RawResponse != nil {
+		d.StatusCode = resp.RawResponse.StatusCode
+		d.Status = fmt.Sprintf("%d %s", d.StatusCode, http.StatusText(d.StatusCode))
+
+		for _, name := range options.CorrelationHeaders {
+			if id := resp.Header.Get(name); id != "" {
+				d.CorrelationID = id
+				break
+			}
+		}
+
+		d.ServerMessage = serverMessage(resp.Header.Get("Content-Type"), resp.Bytes(), options.MaxMessage)
+	}
+
+	for _, hint := range options.Hints {
+		if hint.When(d) {
+			d.Hints = append(d.Hints, hint.Text)
+		}
+	}
+	return d
+}
+
+func render(d *Details, options *Options) string {
+	tmpl := options.Template
+	if tmpl == nil {
+		tmpl = DefaultTemplate
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, d); err != nil {
+		return fmt.Sprintf("%s %s failed (rendering error: %v)", d.Method, d.URL, err)
+	}
+	return buf.String()
+}
+
+// serverMessage decodes the error message of a JSON body, including RFC 9457
+// problem details, or returns the start of a textual body.
+func serverMessage(contentType string, body []byte, limit int) string {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return ""
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") || json.Valid(body) {
+		var payload map[string]any
+		if json.Unmarshal(body, &payload) == nil {
+			if message := jsonMessage(payload); message != "" {
+				return truncate(message, limit)
+			}
+		}
+	}
+
+	if (!strings.HasPrefix(mediaType, "text/") && !json.Valid(body)) || !utf8.Valid(body) {
+		return ""
+	}
+	return truncate(strings.Join(strings.Fields(string(body)), " "), limit)
+}
+
+func jsonMessage(payload map[string]any) string {
+	if title, ok := payload["title"].(string); ok {
+		if detail, ok := payload["detail"].(string); ok && detail != "" {
+			return title + ": " + detail
+		}
+		return title
+	}
+
+	for _, key := range []string{"message", "error_description", "detail", "error", "msg"} {
+		switch value := payload[key].(type) {
+		case string:
+			if value != "" {
+				return value
+			}
+		case map[string]any:
+			if message := jsonMessage(value); message != "" {
+				return message
+			}
+		}
+	}
+	return ""
+}
+
+func truncate(s string, limit int) string {
+	if limit <= 0 || len(s) <= limit {
+		return s
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
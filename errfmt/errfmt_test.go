@@ -0,0 +1,124 @@
+package errfmt
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/rockcookies/go-fetch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		header      map[string]string
+		status      int
+		opts        func(*Options)
+		contains    []string
+		excludes    []string
+	}{
+		{
+			name:        "problem details with correlation id and hint",
+			contentType: "application/problem+json",
+			body:        `{"title":"Unauthorized","detail":"token expired"}`,
+			header:      map[string]string{"X-Request-Id": "req-42"},
+			status:      http.StatusUnauthorized,
+			opts: func(o *Options) {
+				o.Hints = []Hint{StatusHint(http.StatusUnauthorized, "did you set AUTH_TOKEN?"), StatusHint(http.StatusNotFound, "check the id")}
+			},
+			contains: []string{"GET ", "/users failed: 401 Unauthorized", "server:     Unauthorized: token expired", "request id: req-42", "hint: did you set AUTH_TOKEN?"},
+			excludes: []string{"check the id"},
+		},
+		{
+			name:        "nested error object",
+			contentType: "application/json",
+			body:        `{"error":{"code":7,"message":"quota exceeded"}}`,
+			status:      http.StatusTooManyRequests,
+			opts:        func(o *Options) { o.Attempts = 3 },
+			contains:    []string{"429 Too Many Requests", "server:     quota exceeded", "attempts:   3"},
+		},
+		{
+			name:        "text body collapsed and truncated",
+			contentType: "text/plain",
+			body:        "upstream\n\n   unavailable " + strings.Repeat("x", 50),
+			status:      http.StatusBadGateway,
+			opts:        func(o *Options) { o.MaxMessage = 24 },
+			contains:    []string{"server:     upstream unavailable xxx…"},
+		},
+		{
+			name:        "binary body omitted",
+			contentType: "application/octet-stream",
+			body:        "\x00\x01",
+			status:      http.StatusInternalServerError,
+			excludes:    []string{"server:"},
+		},
+		{
+			name:     "custom template",
+			status:   http.StatusForbidden,
+			opts:     func(o *Options) { o.Template = template.Must(template.New("t").Parse("{{.StatusCode}} {{.Method}}")) },
+			contains: []string{"403 GET"},
+			excludes: []string{"failed"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				for k, v := range tt.header {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			resp := fetch.NewDispatcher(nil).NewRequest().Get(server.URL + "/users")
+			defer resp.Close()
+			require.NoError(t, resp.Error)
+
+			var opts []func(*Options)
+			if tt.opts != nil {
+				opts = append(opts, tt.opts)
+			}
+			out := Render(resp, opts...)
+
+			for _, s := range tt.contains {
+				assert.Contains(t, out, s)
+			}
+			for _, s := range tt.excludes {
+				assert.NotContains(t, out, s)
+			}
+			assert.Equal(t, tt.body, resp.String(), "body stays readable")
+		})
+	}
+}
+
+func TestRender_TransportError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	resp := fetch.NewDispatcher(nil).NewRequest().UseFuncs(func(r *http.Request) {
+		*r = *r.WithContext(ctx)
+	}).Get("http://127.0.0.1:1/health")
+
+	out := Render(resp, func(o *Options) {
+		o.Hints = []Hint{ErrorHint(context.Canceled, "the command was interrupted")}
+	})
+	assert.Contains(t, out, "GET http://127.0.0.1:1/health failed: ")
+	assert.Contains(t, out, "context canceled")
+	assert.Contains(t, out, "hint: the command was interrupted")
+
+	plain := RenderError(errors.New("boom"))
+	assert.Equal(t, "request failed: boom\n", plain)
+}
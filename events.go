@@ -0,0 +1,224 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rockcookies/go-fetch/internal/utils"
+)
+
+var eventBusKey = utils.NewContextKey[*EventBus]("event_bus")
+
+// Event is a client lifecycle event published on an EventBus.
+type Event interface {
+	// EventTime returns when the event happened.
+	EventTime() time.Time
+}
+
+// RequestQueued is published when a Queue accepted a request.
+type RequestQueued struct {
+	Time    time.Time
+	Request *http.Request
+}
+
+// RequestStarted is published when a request enters the EventBus middleware.
+type RequestStarted struct {
+	Time    time.Time
+	Request *http.Request
+}
+
+// RetryScheduled is published when a failed attempt will be retried after Delay.
+// StatusCode is zero when the attempt failed with Err.
+type RetryScheduled struct {
+	Time       time.Time
+	Request    *http.Request
+	Attempt    int
+	Delay      time.Duration
+	StatusCode int
+	Err        error
+}
+
+// ResponseReceived is published when a request passing the EventBus
+// middleware completed, with either a status code or an error.
+type ResponseReceived struct {
+	Time       time.Time
+	Request    *http.Request
+	StatusCode int
+	Duration   time.Duration
+	Err        error
+}
+
+// CacheHitEvent is published when the Cache middleware answered a request
+// from its store; Status tells whether the entry was fresh, stale or revalidated.
+type CacheHitEvent struct {
+	Time    time.Time
+	Request *http.Request
+	Status  CacheStatus
+}
+
+// CircuitOpened is published by circuit breakers when they stop sending
+// requests to Host after failures. The last failure either had StatusCode or
+// failed with Err.
+type CircuitOpened struct {
+	Time       time.Time
+	Host       string
+	StatusCode int
+	Err        error
+}
+
+// ConnectionShutdown is published by a ConnectionLifecycle when a connection
+// to Host ends: the server sent GOAWAY or "Connection: close", or the
+// lifecycle rotated it. Err is the GOAWAY error, if any.
+type ConnectionShutdown struct {
+	Time    time.Time
+	Request *http.Request
+	Host    string
+	Reason  ConnectionShutdownReason
+	Err     error
+}
+
+// EventTime returns when the event happened.
+func (e RequestQueued) EventTime() time.Time { return e.Time }
+
+// EventTime returns when the event happened.
+func (e RequestStarted) EventTime() time.Time { return e.Time }
+
+// EventTime returns when the event happened.
+func (e RetryScheduled) EventTime() time.Time { return e.Time }
+
+// EventTime returns when the event happened.
+func (e ResponseReceived) EventTime() time.Time { return e.Time }
+
+// EventTime returns when the event happened.
+func (e CacheHitEvent) EventTime() time.Time { return e.Time }
+
+// EventTime returns when the event happened.
+func (e CircuitOpened) EventTime() time.Time { return e.Time }
+
+// EventTime returns when the event happened.
+func (e ConnectionShutdown) EventTime() time.Time { return e.Time }
+
+// EventBus delivers client lifecycle events to subscribers, so dashboards
+// and policies can observe the client without adding middlewares of their
+// own. Subscribers are called synchronously in registration order by the
+// goroutine publishing the event and must not block. It is safe for concurrent use.
+type EventBus struct {
+	mu          sync.RWMutex
+	subscribers []*eventSubscriber
+}
+
+type eventSubscriber struct {
+	fn func(Event)
+}
+
+// NewEventBus creates an EventBus without subscribers.
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe registers fn for every event and returns a function removing it.
+//
+// Example:
+//
+//	bus := fetch.NewEventBus()
+//	dispatcher.Use(bus.Middleware())
+//	unsubscribe := bus.Subscribe(func(e fetch.Event) {
+//	    switch e := e.(type) {
+//	    case fetch.ResponseReceived:
+//	        latency.Observe(e.Duration.Seconds())
+//	    case fetch.CacheHitEvent:
+//	        cacheHits.Inc()
+//	    }
+//	})
+//	defer unsubscribe()
+func (b *EventBus) Subscribe(fn func(Event)) (unsubscribe func()) {
+	sub := &eventSubscriber{fn: fn}
+
+	b.mu.Lock()
+	b.subscribers = append(b.subscribers, sub)
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		for i, s := range b.subscribers {
+			if s == sub {
+				b.subscribers = append(b.subscribers[:i:i], b.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// SubscribeTo registers fn for events of type E only.
+//
+// Example:
+//
+//	fetch.SubscribeTo(bus, func(e fetch.RetryScheduled) {
+//	    log.Printf("retrying %s in %s", e.Request.URL, e.Delay)
+//	})
+func SubscribeTo[E Event](b *EventBus, fn func(E)) (unsubscribe func()) {
+	return b.Subscribe(func(e Event) {
+		if typed, ok := e.(E); ok {
+			fn(typed)
+		}
+	})
+}
+
+// Publish delivers e to all subscribers. A nil EventBus discards the event.
+func (b *EventBus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	for _, sub := range subscribers {
+		sub.fn(e)
+	}
+}
+
+// Middleware creates middleware publishing RequestStarted, ResponseReceived
+// and CacheHitEvent events. It also makes the bus available to later middlewares
+// and policies through EventBusFromContext.
+func (b *EventBus) Middleware() Middleware {
+	return bypassDryRun(func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			req = req.WithContext(WithEventBus(req.Context(), b))
+
+			start := time.Now()
+			b.Publish(RequestStarted{Time: start, Request: req})
+
+			resp, err := next.Handle(client, req)
+
+			received := ResponseReceived{Time: time.Now(), Request: req, Duration: time.Since(start), Err: err}
+			if resp != nil {
+				received.StatusCode = resp.StatusCode
+				switch status := CacheStatus(resp.Header.Get(cacheStatusHeader)); status {
+				case CacheHit, CacheStale, CacheRevalidated:
+					b.Publish(CacheHitEvent{Time: received.Time, Request: req, Status: status})
+				}
+			}
+			b.Publish(received)
+
+			return resp, err
+		})
+	})
+}
+
+// WithEventBus returns a context carrying bus, see EventBusFromContext.
+func WithEventBus(ctx context.Context, bus *EventBus) context.Context {
+	return eventBusKey.WithValue(ctx, bus)
+}
+
+// EventBusFromContext returns the EventBus of a request, or nil. Policies
+// publish their events on it, e.g. a circuit breaker publishing CircuitOpened.
+func EventBusFromContext(ctx context.Context) *EventBus {
+	bus, _ := eventBusKey.GetValue(ctx)
+	return bus
+}
@@ -0,0 +1,109 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventBus_Middleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	bus := NewEventBus()
+	var mu sync.Mutex
+	var events []string
+	bus.Subscribe(func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch e := e.(type) {
+		case RequestStarted:
+			events = append(events, "started")
+		case ResponseReceived:
+			events = append(events, "received "+http.StatusText(e.StatusCode))
+		case CacheHitEvent:
+			events = append(events, "cache "+string(e.Status))
+		}
+	})
+
+	var fromContext *EventBus
+	dispatcher := NewDispatcher(nil, bus.Middleware(), Cache())
+	for range 2 {
+		resp := dispatcher.NewRequest().UseFuncs(func(r *http.Request) {
+			fromContext = EventBusFromContext(r.Context())
+		}).Get(server.URL)
+		require.NoError(t, resp.Error)
+		resp.Close()
+	}
+
+	assert.Same(t, bus, fromContext)
+	assert.Equal(t, []string{"started", "received OK", "started", "cache hit", "received OK"}, events)
+}
+
+func TestEventBus_Subscribe(t *testing.T) {
+	bus := NewEventBus()
+
+	var all, circuits atomic.Int32
+	unsubscribe := bus.Subscribe(func(Event) { all.Add(1) })
+	SubscribeTo(bus, func(e CircuitOpened) {
+		assert.Equal(t, "api.example.com", e.Host)
+		circuits.Add(1)
+	})
+
+	bus.Publish(CircuitOpened{Time: time.Now(), Host: "api.example.com"})
+	bus.Publish(RequestStarted{Time: time.Now()})
+	unsubscribe()
+	bus.Publish(CircuitOpened{Time: time.Now(), Host: "api.example.com"})
+
+	assert.Equal(t, int32(2), all.Load())
+	assert.Equal(t, int32(2), circuits.Load())
+
+	var nilBus *EventBus
+	assert.NotPanics(t, func() { nilBus.Publish(RequestStarted{}) })
+}
+
+func TestEventBus_Queue(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	bus := NewEventBus()
+	var queued, retries atomic.Int32
+	var retry RetryScheduled
+	SubscribeTo(bus, func(RequestQueued) { queued.Add(1) })
+	SubscribeTo(bus, func(e RetryScheduled) {
+		retry = e
+		retries.Add(1)
+	})
+
+	queue := NewDispatcher(nil).NewQueue(func(o *QueueOptions) {
+		o.Events = bus
+		o.Backoff = ConstantBackoff(time.Millisecond)
+	})
+	req, err := http.NewRequest(http.MethodPost, server.URL, nil)
+	require.NoError(t, err)
+	require.NoError(t, queue.Enqueue(req))
+	require.NoError(t, queue.Close(context.Background()))
+
+	assert.Equal(t, int32(1), queued.Load())
+	assert.Equal(t, int32(1), retries.Load())
+	assert.Equal(t, 1, retry.Attempt)
+	assert.Equal(t, http.StatusServiceUnavailable, retry.StatusCode)
+	assert.Equal(t, time.Millisecond, retry.Delay)
+}
@@ -0,0 +1,301 @@
+// Package expr evaluates small boolean expressions over requests and
+// responses, so filters, retry conditions and redaction rules can live in
+// configuration files and change without recompiling services.
+//
+// Expressions combine literals, variables and function calls:
+//
+//	method == "GET" && host in ["api.example.com", "cdn.example.com"]
+//	status >= 500 || error != ""
+//	path matches "^/v[12]/users/" && !startsWith(header("Authorization"), "Basic ")
+//
+// Literals are double-quoted strings, numbers, true, false and lists in
+// brackets. Operators are ||, &&, !, ==, !=, <, <=, >, >=, in (list
+// membership) and matches (regular expression), with the usual precedence
+// and parentheses for grouping.
+//
+// Variables:
+//
+//	method, url, scheme, host, path, query  parts of the request
+//	status                                  response status code, 0 without response
+//	error                                   error message, "" without error
+//
+// Functions:
+//
+//	header(name)           first value of a request header
+//	response_header(name)  first value of a response header
+//	query_param(name)      first value of a query parameter
+//	lower(s), upper(s)
+//	contains(s, sub), startsWith(s, prefix), endsWith(s, suffix)
+//
+// Additional variables, such as the header name of a redaction rule, are
+// declared with Options.Vars and supplied through Env.Vars.
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Env holds the values an expression is evaluated against. Any field may be
+// nil.
+type Env struct {
+	Request  *http.Request
+	Response *http.Response
+	Err      error
+	// Vars supplies the variables declared with Options.Vars.
+	Vars map[string]any
+}
+
+// Options configures Compile.
+type Options struct {
+	// Vars declares additional variable names supplied through Env.Vars.
+	Vars []string
+}
+
+// Program is a compiled expression. It is safe for concurrent use.
+type Program struct {
+	source string
+	eval   evalFunc
+}
+
+type evalFunc func(env *Env) (any, error)
+
+// Compile parses src. Unknown variables and functions, wrong argument counts
+// and invalid regular expression literals are reported here rather than at
+// evaluation.
+func Compile(src string, opts ...func(*Options)) (*Program, error) {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, fmt.Errorf("expr %q: %w", src, err)
+	}
+	p := &parser{tokens: tokens, vars: options.Vars}
+	eval, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("expr %q: %w", src, err)
+	}
+	return &Program{source: src, eval: eval}, nil
+}
+
+// MustCompile is like Compile but panics on error.
+func MustCompile(src string, opts ...func(*Options)) *Program {
+	p, err := Compile(src, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// String returns the source of the expression.
+func (p *Program) String() string {
+	return p.source
+}
+
+// Eval evaluates the expression. The result is a string, float64, bool or
+// []any.
+func (p *Program) Eval(env Env) (any, error) {
+	v, err := p.eval(&env)
+	if err != nil {
+		return nil, fmt.Errorf("expr %q: %w", p.source, err)
+	}
+	return v, nil
+}
+
+// Bool evaluates the expression and requires a boolean result.
+func (p *Program) Bool(env Env) (bool, error) {
+	v, err := p.Eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expr %q: result is %s, not bool", p.source, typeName(v))
+	}
+	return b, nil
+}
+
+var variables = map[string]func(env *Env) any{
+	"method": func(env *Env) any { return requestField(env, func(r *http.Request) string { return r.Method }) },
+	"url":    func(env *Env) any { return requestField(env, func(r *http.Request) string { return r.URL.String() }) },
+	"scheme": func(env *Env) any { return requestField(env, func(r *http.Request) string { return r.URL.Scheme }) },
+	"host":   func(env *Env) any { return requestField(env, func(r *http.Request) string { return r.URL.Hostname() }) },
+	"path":   func(env *Env) any { return requestField(env, func(r *http.Request) string { return r.URL.Path }) },
+	"query":  func(env *Env) any { return requestField(env, func(r *http.Request) string { return r.URL.RawQuery }) },
+	"status": func(env *Env) any {
+		if env.Response == nil {
+			return float64(0)
+		}
+		return float64(env.Response.StatusCode)
+	},
+	"error": func(env *Env) any {
+		if env.Err == nil {
+			return ""
+		}
+		return env.Err.Error()
+	},
+}
+
+func requestField(env *Env, field func(*http.Request) string) string {
+	if env.Request == nil || env.Request.URL == nil {
+		return ""
+	}
+	return field(env.Request)
+}
+
+type function struct {
+	args int
+	call func(env *Env, args []any) (any, error)
+}
+
+var functions = map[string]function{
+	"header": {1, func(env *Env, args []any) (any, error) {
+		name, err := stringArg(args[0])
+		if err != nil || env.Request == nil {
+			return "", err
+		}
+		return env.Request.Header.Get(name), nil
+	}},
+	"response_header": {1, func(env *Env, args []any) (any, error) {
+		name, err := stringArg(args[0])
+		if err != nil || env.Response == nil {
+			return "", err
+		}
+		return env.Response.Header.Get(name), nil
+	}},
+	"query_param": {1, func(env *Env, args []any) (any, error) {
+		name, err := stringArg(args[0])
+		if err != nil || env.Request == nil || env.Request.URL == nil {
+			return "", err
+		}
+		return env.Request.URL.Query().Get(name), nil
+	}},
+	"lower":      {1, stringFunc(strings.ToLower)},
+	"upper":      {1, stringFunc(strings.ToUpper)},
+	"contains":   {2, stringPredicate(strings.Contains)},
+	"startsWith": {2, stringPredicate(strings.HasPrefix)},
+	"endsWith":   {2, stringPredicate(strings.HasSuffix)},
+}
+
+func stringFunc(f func(string) string) func(*Env, []any) (any, error) {
+	return func(_ *Env, args []any) (any, error) {
+		s, err := stringArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return f(s), nil
+	}
+}
+
+func stringPredicate(f func(s, arg string) bool) func(*Env, []any) (any, error) {
+	return func(_ *Env, args []any) (any, error) {
+		s, err := stringArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		arg, err := stringArg(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return f(s, arg), nil
+	}
+}
+
+func stringArg(v any) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("expected string argument, got %s", typeName(v))
+	}
+	return s, nil
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "bool"
+	case []any:
+		return "list"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func compare(op string, left, right any) (any, error) {
+	switch op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		list, ok := right.([]any)
+		if !ok {
+			return nil, fmt.Errorf("right operand of in is %s, not list", typeName(right))
+		}
+		return slices.ContainsFunc(list, func(item any) bool { return equal(left, item) }), nil
+	}
+
+	switch l := left.(type) {
+	case float64:
+		if r, ok := right.(float64); ok {
+			return order(op, l, r), nil
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			return order(op, l, r), nil
+		}
+	}
+	return nil, fmt.Errorf("cannot compare %s %s %s", typeName(left), op, typeName(right))
+}
+
+func equal(left, right any) bool {
+	switch l := left.(type) {
+	case []any:
+		r, ok := right.([]any)
+		return ok && slices.EqualFunc(l, r, equal)
+	default:
+		return left == right
+	}
+}
+
+func order[T float64 | string](op string, l, r T) bool {
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	default:
+		return l >= r
+	}
+}
+
+func matches(left, right any, re *regexp.Regexp) (any, error) {
+	s, ok := left.(string)
+	if !ok {
+		return nil, fmt.Errorf("left operand of matches is %s, not string", typeName(left))
+	}
+	if re == nil {
+		pattern, ok := right.(string)
+		if !ok {
+			return nil, errors.New("right operand of matches is not a string")
+		}
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			return nil, err
+		}
+	}
+	return re.MatchString(s), nil
+}
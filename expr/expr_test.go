@@ -0,0 +1,113 @@
+package expr
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgram_Eval(t *testing.T) {
+	req := &http.Request{
+		Method: "POST",
+		URL:    &url.URL{Scheme: "https", Host: "api.example.com:8443", Path: "/v2/users/7", RawQuery: "page=3"},
+		Header: http.Header{"Authorization": {"Bearer abc"}},
+	}
+	env := Env{
+		Request:  req,
+		Response: &http.Response{StatusCode: 503, Header: http.Header{"Retry-After": {"5"}}},
+		Err:      errors.New("connection reset by peer"),
+	}
+
+	tests := []struct {
+		name     string
+		src      string
+		expected any
+	}{
+		{name: "variables", src: `method == "POST" && scheme == "https" && host == "api.example.com"`, expected: true},
+		{name: "status comparison", src: `status >= 500 && status < 600`, expected: true},
+		{name: "in list", src: `status in [502, 503, 504]`, expected: true},
+		{name: "not in list", src: `!(method in ["GET", "HEAD"])`, expected: true},
+		{name: "matches", src: `path matches "^/v[12]/users/\\d+$"`, expected: true},
+		{name: "functions", src: `startsWith(header("Authorization"), "Bearer ") && query_param("page") == "3"`, expected: true},
+		{name: "response header", src: `response_header("Retry-After") == "5"`, expected: true},
+		{name: "error", src: `contains(error, "reset")`, expected: true},
+		{name: "string functions", src: `upper(lower(method))`, expected: "POST"},
+		{name: "precedence", src: `false && true || true`, expected: true},
+		{name: "short circuit", src: `true || missing_var_is_never_evaluated == 1`, expected: nil},
+		{name: "number", src: `-1.5`, expected: -1.5},
+		{name: "list", src: `["a", 1, true]`, expected: []any{"a", 1.0, true}},
+		{name: "string order", src: `"a" < "b"`, expected: true},
+		{name: "query", src: `query`, expected: "page=3"},
+		{name: "url", src: `url`, expected: "https://api.example.com:8443/v2/users/7?page=3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, err := Compile(tt.src)
+			if tt.expected == nil {
+				assert.ErrorContains(t, err, "unknown variable")
+				return
+			}
+			require.NoError(t, err)
+			v, err := program.Eval(env)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, v)
+		})
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		src     string
+		wantErr string
+	}{
+		{src: `method ==`, wantErr: "unexpected end of expression"},
+		{src: `method == "GET`, wantErr: "unterminated string"},
+		{src: `methd == "GET"`, wantErr: `unknown variable "methd"`},
+		{src: `hdr("X")`, wantErr: `unknown function "hdr"`},
+		{src: `header("a", "b")`, wantErr: "header takes 1 arguments, got 2"},
+		{src: `path matches "("`, wantErr: "invalid regular expression"},
+		{src: `status == 1 )`, wantErr: `unexpected ")"`},
+		{src: `status # 1`, wantErr: `unexpected '#'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Compile(tt.src)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestProgram_Bool(t *testing.T) {
+	tests := []struct {
+		src     string
+		env     Env
+		wantErr string
+	}{
+		{src: `method`, wantErr: "result is string, not bool"},
+		{src: `status && true`, wantErr: "operand of && is number, not bool"},
+		{src: `status < "5"`, wantErr: "cannot compare number < string"},
+		{src: `status in 5`, wantErr: "right operand of in is number, not list"},
+		{src: `lower(status)`, wantErr: "expected string argument, got number"},
+		{src: `tenant == "acme"`, env: Env{}, wantErr: "variable tenant not set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			program, err := Compile(tt.src, func(o *Options) { o.Vars = []string{"tenant"} })
+			require.NoError(t, err)
+			_, err = program.Bool(tt.env)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+
+	ok, err := MustCompile(`tenant == "acme"`, func(o *Options) { o.Vars = []string{"tenant"} }).Bool(Env{Vars: map[string]any{"tenant": "acme"}})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Panics(t, func() { MustCompile(`(`) })
+}
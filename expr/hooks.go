@@ -0,0 +1,107 @@
+package expr
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/rockcookies/go-fetch"
+	"github.com/rockcookies/go-fetch/dump"
+)
+
+// Matcher compiles src into a fetch.Matcher, e.g. to scope middleware with
+// fetch.When. Requests whose evaluation fails do not match.
+//
+// Example:
+//
+//	internal, err := expr.Matcher(cfg.InternalRequests) // `host matches "\\.internal$"`
+//	if err != nil {
+//	    return err
+//	}
+//	dispatcher.Use(fetch.When(internal, serviceAuth))
+func Matcher(src string) (fetch.Matcher, error) {
+	program, err := Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	return func(req *http.Request) bool {
+		ok, _ := program.Bool(Env{Request: req})
+		return ok
+	}, nil
+}
+
+// DumpFilter compiles src into a dump.Filter deciding which exchanges are
+// logged. status is 0 for requests that failed without a response.
+//
+// Example:
+//
+//	filter, err := expr.DumpFilter(`status >= 400 || startsWith(path, "/admin")`)
+func DumpFilter(src string) (dump.Filter, error) {
+	program, err := Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	return func(req *http.Request, status int) bool {
+		env := Env{Request: req}
+		if status != 0 {
+			env.Response = &http.Response{StatusCode: status, Request: req}
+		}
+		ok, _ := program.Bool(env)
+		return ok
+	}, nil
+}
+
+// RetryCondition compiles src into a predicate deciding whether a request
+// outcome should be retried. The request variables are read from
+// resp.Request when there is a response.
+//
+// Example:
+//
+//	retry, err := expr.RetryCondition(`status == 429 || status >= 502 || contains(error, "reset")`)
+func RetryCondition(src string) (func(req *http.Request, resp *http.Response, err error) bool, error) {
+	program, err := Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	return func(req *http.Request, resp *http.Response, err error) bool {
+		if resp != nil && resp.Request != nil {
+			req = resp.Request
+		}
+		ok, _ := program.Bool(Env{Request: req, Response: resp, Err: err})
+		return ok
+	}, nil
+}
+
+// Redacted replaces header values matched by a HeaderRedactor.
+const Redacted = "[REDACTED]"
+
+// HeaderRedactor compiles src into a header filter for
+// dump.Options.RequestHeaderFilter and ResponseHeaderFilter. The expression
+// sees the variables name (canonical header name) and value (the values
+// joined by ", "); headers it accepts are logged as Redacted.
+//
+// Example:
+//
+//	redact, err := expr.HeaderRedactor(`lower(name) in ["authorization", "cookie"] || startsWith(name, "X-Secret-")`)
+//	if err != nil {
+//	    return err
+//	}
+//	options := dump.DefaultOptions()
+//	options.RequestHeaderFilter = redact
+func HeaderRedactor(src string) (func(key string, values []string) []any, error) {
+	program, err := Compile(src, func(o *Options) { o.Vars = []string{"name", "value"} })
+	if err != nil {
+		return nil, err
+	}
+	return func(key string, values []string) []any {
+		redact, err := program.Bool(Env{Vars: map[string]any{"name": key, "value": strings.Join(values, ", ")}})
+		switch {
+		case redact || err != nil:
+			return []any{slog.String(key, Redacted)}
+		case len(values) == 1:
+			return []any{slog.String(key, values[0])}
+		default:
+			return []any{slog.Any(key, values)}
+		}
+	}, nil
+}
@@ -0,0 +1,84 @@
+package expr
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rockcookies/go-fetch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatcher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Scoped")))
+	}))
+	defer server.Close()
+
+	matcher, err := Matcher(`startsWith(path, "/internal/")`)
+	require.NoError(t, err)
+
+	dispatcher := fetch.NewDispatcher(nil, fetch.When(matcher, func(next fetch.Handler) fetch.Handler {
+		return fetch.HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			req.Header.Set("X-Scoped", "yes")
+			return next.Handle(client, req)
+		})
+	}))
+
+	assert.Equal(t, "yes", dispatcher.NewRequest().Get(server.URL+"/internal/jobs").String())
+	assert.Equal(t, "", dispatcher.NewRequest().Get(server.URL+"/public").String())
+
+	_, err = Matcher(`path ==`)
+	assert.Error(t, err)
+}
+
+func TestDumpFilter(t *testing.T) {
+	filter, err := DumpFilter(`status >= 400 || method == "DELETE"`)
+	require.NoError(t, err)
+
+	get, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	del, _ := http.NewRequest(http.MethodDelete, "http://example.com", nil)
+	assert.True(t, filter(get, 500))
+	assert.False(t, filter(get, 200))
+	assert.False(t, filter(get, 0))
+	assert.True(t, filter(del, 0))
+}
+
+func TestRetryCondition(t *testing.T) {
+	retry, err := RetryCondition(`method in ["GET", "PUT"] && (status == 429 || status >= 502 || contains(error, "reset"))`)
+	require.NoError(t, err)
+
+	get, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	post, _ := http.NewRequest(http.MethodPost, "http://example.com", nil)
+
+	assert.True(t, retry(get, &http.Response{StatusCode: 503, Request: get}, nil))
+	assert.False(t, retry(get, &http.Response{StatusCode: 500, Request: get}, nil))
+	assert.True(t, retry(get, nil, errors.New("read: connection reset by peer")))
+	assert.False(t, retry(post, &http.Response{StatusCode: 503, Request: post}, nil))
+}
+
+func TestHeaderRedactor(t *testing.T) {
+	redact, err := HeaderRedactor(`lower(name) in ["authorization", "cookie"] || startsWith(name, "X-Secret-") || contains(value, "token=")`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		key      string
+		values   []string
+		expected []any
+	}{
+		{key: "Authorization", values: []string{"Bearer abc"}, expected: []any{slog.String("Authorization", Redacted)}},
+		{key: "X-Secret-Key", values: []string{"k"}, expected: []any{slog.String("X-Secret-Key", Redacted)}},
+		{key: "X-Debug", values: []string{"a", "token=1"}, expected: []any{slog.String("X-Debug", Redacted)}},
+		{key: "Accept", values: []string{"application/json"}, expected: []any{slog.String("Accept", "application/json")}},
+		{key: "Via", values: []string{"a", "b"}, expected: []any{slog.Any("Via", []string{"a", "b"})}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			assert.Equal(t, tt.expected, redact(tt.key, tt.values))
+		})
+	}
+}
@@ -0,0 +1,375 @@
+package expr
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOp
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value any
+	pos   int
+}
+
+var operators = []string{"||", "&&", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ","}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '"':
+			end := i + 1
+			for end < len(src) && src[end] != '"' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			value, err := strconv.Unquote(src[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at offset %d: %w", i, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: src[i : end+1], value: value, pos: i})
+			i = end + 1
+
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			end := i + 1
+			for end < len(src) && (src[end] >= '0' && src[end] <= '9' || src[end] == '.') {
+				end++
+			}
+			value, err := strconv.ParseFloat(src[i:end], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at offset %d", src[i:end], i)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: src[i:end], value: value, pos: i})
+			i = end
+
+		case c == '_' || unicode.IsLetter(c):
+			end := i + 1
+			for end < len(src) && (src[end] == '_' || unicode.IsLetter(rune(src[end])) || unicode.IsDigit(rune(src[end]))) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: src[i:end], pos: i})
+			i = end
+
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at offset %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(src)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	vars   []string
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the operator or keyword text.
+func (p *parser) accept(text string) bool {
+	t := p.peek()
+	if (t.kind == tokenOp || t.kind == tokenIdent) && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return p.unexpected()
+	}
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	if t.kind == tokenEOF {
+		return errors.New("unexpected end of expression")
+	}
+	return fmt.Errorf("unexpected %q at offset %d", t.text, t.pos)
+}
+
+func (p *parser) parse() (evalFunc, error) {
+	eval, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, p.unexpected()
+	}
+	return eval, nil
+}
+
+func (p *parser) parseOr() (evalFunc, error) {
+	return p.parseLogical("||", p.parseAnd)
+}
+
+func (p *parser) parseAnd() (evalFunc, error) {
+	return p.parseLogical("&&", p.parseNot)
+}
+
+// parseLogical parses operand { op operand } with short-circuit evaluation.
+func (p *parser) parseLogical(op string, operand func() (evalFunc, error)) (evalFunc, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(op) {
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		short := op == "||"
+		left = func(env *Env) (any, error) {
+			lv, err := boolOperand(op, l, env)
+			if err != nil || lv == short {
+				return lv, err
+			}
+			return boolOperand(op, right, env)
+		}
+	}
+	return left, nil
+}
+
+func boolOperand(op string, eval evalFunc, env *Env) (bool, error) {
+	v, err := eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("operand of %s is %s, not bool", op, typeName(v))
+	}
+	return b, nil
+}
+
+func (p *parser) parseNot() (evalFunc, error) {
+	if !p.accept("!") {
+		return p.parseComparison()
+	}
+	operand, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return func(env *Env) (any, error) {
+		b, err := boolOperand("!", operand, env)
+		return !b, err
+	}, nil
+}
+
+var comparisonOperators = []string{"==", "!=", "<", "<=", ">", ">=", "in", "matches"}
+
+func (p *parser) parseComparison() (evalFunc, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	if (t.kind != tokenOp && t.kind != tokenIdent) || !slices.Contains(comparisonOperators, t.text) {
+		return left, nil
+	}
+	p.next()
+
+	start, rightToken := p.pos, p.peek()
+	right, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	if t.text == "matches" {
+		var re *regexp.Regexp
+		// A literal pattern is compiled once, here.
+		if rightToken.kind == tokenString && p.pos == start+1 {
+			if re, err = regexp.Compile(rightToken.value.(string)); err != nil {
+				return nil, fmt.Errorf("invalid regular expression at offset %d: %w", rightToken.pos, err)
+			}
+		}
+		return func(env *Env) (any, error) {
+			lv, err := left(env)
+			if err != nil {
+				return nil, err
+			}
+			rv, err := right(env)
+			if err != nil {
+				return nil, err
+			}
+			return matches(lv, rv, re)
+		}, nil
+	}
+
+	return func(env *Env) (any, error) {
+		lv, err := left(env)
+		if err != nil {
+			return nil, err
+		}
+		rv, err := right(env)
+		if err != nil {
+			return nil, err
+		}
+		return compare(t.text, lv, rv)
+	}, nil
+}
+
+func (p *parser) parsePrimary() (evalFunc, error) {
+	if p.peek().kind == tokenEOF {
+		return nil, p.unexpected()
+	}
+	t := p.next()
+	switch t.kind {
+	case tokenNumber, tokenString:
+		return constant(t.value), nil
+
+	case tokenOp:
+		switch t.text {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expect(")")
+		case "[":
+			items, err := p.parseArgs("]")
+			if err != nil {
+				return nil, err
+			}
+			return func(env *Env) (any, error) {
+				return evalAll(items, env)
+			}, nil
+		}
+
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return constant(true), nil
+		case "false":
+			return constant(false), nil
+		}
+		if p.accept("(") {
+			return p.parseCall(t)
+		}
+		if variable, ok := variables[t.text]; ok {
+			return func(env *Env) (any, error) { return variable(env), nil }, nil
+		}
+		if slices.Contains(p.vars, t.text) {
+			name := t.text
+			return func(env *Env) (any, error) {
+				v, ok := env.Vars[name]
+				if !ok {
+					return nil, fmt.Errorf("variable %s not set", name)
+				}
+				return v, nil
+			}, nil
+		}
+		return nil, fmt.Errorf("unknown variable %q at offset %d", t.text, t.pos)
+	}
+
+	p.pos--
+	return nil, p.unexpected()
+}
+
+func (p *parser) parseCall(name token) (evalFunc, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at offset %d", name.text, name.pos)
+	}
+	args, err := p.parseArgs(")")
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != fn.args {
+		return nil, fmt.Errorf("%s takes %d arguments, got %d", name.text, fn.args, len(args))
+	}
+	return func(env *Env) (any, error) {
+		values, err := evalAll(args, env)
+		if err != nil {
+			return nil, err
+		}
+		return fn.call(env, values)
+	}, nil
+}
+
+// parseArgs parses a comma-separated list up to the closing token.
+func (p *parser) parseArgs(closing string) ([]evalFunc, error) {
+	var args []evalFunc
+	if p.accept(closing) {
+		return args, nil
+	}
+	for {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.accept(closing) {
+			return args, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func evalAll(evals []evalFunc, env *Env) ([]any, error) {
+	values := make([]any, len(evals))
+	for i, eval := range evals {
+		v, err := eval(env)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func constant(v any) evalFunc {
+	return func(*Env) (any, error) { return v, nil }
+}
@@ -0,0 +1,149 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/rockcookies/go-fetch/internal/utils"
+)
+
+var fallbackKey = utils.NewContextKey[FallbackInfo]("fallback")
+
+// FallbackInfo describes why a response was served by the fallback handler.
+type FallbackInfo struct {
+	// StatusCode is the status of the primary response, zero when the primary
+	// request failed with an error.
+	StatusCode int
+	// Err is the error of the primary request, if any.
+	Err error
+}
+
+// FallbackOptions configures the Fallback middleware.
+type FallbackOptions struct {
+	// Condition decides whether the primary outcome is degraded and the
+	// fallback handler is used instead. resp is nil when err is not.
+	// Defaults to network errors and 5xx responses; canceled requests and
+	// expired deadlines do not fall back.
+	Condition func(resp *http.Response, err error) bool
+}
+
+// Fallback creates middleware that serves a request through fallback when the
+// primary request fails or its response matches Condition, e.g. to try a
+// secondary region or to serve a cached stub. Like Retry it works at the
+// transport level, after the request middlewares have set the body. The body
+// of the degraded primary response is drained and closed, and the request
+// body is replayed through GetBody; bodies without GetBody are buffered in
+// memory first. fallback receives a client using the transport the
+// middleware wrapped.
+//
+// Responses served by fallback can be told apart with Response.Fallback.
+//
+// Example:
+//
+//	secondary := fetch.HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+//	    req = req.Clone(req.Context())
+//	    req.URL.Host = "eu.api.example.com"
+//	    req.Host = ""
+//	    return client.Do(req)
+//	})
+//	dispatcher.Use(fetch.Fallback(secondary))
+func Fallback(fallback Handler, opts ...func(*FallbackOptions)) Middleware {
+	options := applyOptions(&FallbackOptions{Condition: defaultFallbackCondition}, opts...)
+
+	return bypassDryRun(func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			fallbackClient := *client
+			client.Transport = &fallbackTransport{
+				base:     client.Transport,
+				client:   &fallbackClient,
+				fallback: fallback,
+				options:  options,
+			}
+			return next.Handle(client, req)
+		})
+	})
+}
+
+type fallbackTransport struct {
+	base     http.RoundTripper
+	client   *http.Client
+	fallback Handler
+	options  *FallbackOptions
+}
+
+func (t *fallbackTransport) unwrap() http.RoundTripper { return t.base }
+
+func (t *fallbackTransport) rewrap(base http.RoundTripper) http.RoundTripper {
+	clone := *t
+	clone.base = base
+	return &clone
+}
+
+func (t *fallbackTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	ctx := req.Context()
+	req = req.Clone(ctx)
+	if _, err := EnsureReplayableBody(req, -1); err != nil {
+		return nil, err
+	}
+
+	resp, err := base.RoundTrip(req)
+	if ctx.Err() != nil || !t.options.Condition(resp, err) {
+		return resp, err
+	}
+
+	info := FallbackInfo{Err: err}
+	if resp != nil {
+		info.StatusCode = resp.StatusCode
+	}
+	drainAndClose(resp)
+
+	req = req.Clone(fallbackKey.WithValue(ctx, info))
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = body
+	}
+
+	resp, err = t.fallback.Handle(t.client, req)
+	if resp != nil {
+		// Stubs may return responses without the request; keep the
+		// annotation reachable through Response.Fallback.
+		if resp.Request == nil {
+			resp.Request = req
+		} else if _, ok := fallbackKey.GetValue(resp.Request.Context()); !ok {
+			resp.Request = resp.Request.WithContext(req.Context())
+		}
+	}
+	return resp, err
+}
+
+func defaultFallbackCondition(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode >= 500
+}
+
+// Fallback returns why the response was served by the Fallback middleware.
+// The boolean is false for responses of the primary request.
+//
+// Example:
+//
+//	resp := dispatcher.NewRequest().Get(url)
+//	if info, ok := resp.Fallback(); ok {
+//	    log.Printf("served from fallback, primary returned %d: %v", info.StatusCode, info.Err)
+//	}
+func (r *Response) Fallback() (FallbackInfo, bool) {
+	if r.RawResponse == nil || r.RawResponse.Request == nil {
+		return FallbackInfo{}, false
+	}
+	return fallbackKey.GetValue(r.RawResponse.Request.Context())
+}
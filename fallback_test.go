@@ -0,0 +1,128 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type transportFunc func(*http.Request) (*http.Response, error)
+
+func (f transportFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestFallback(t *testing.T) {
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte("secondary:" + string(body)))
+	}))
+	defer secondary.Close()
+
+	toSecondary := HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+		req = req.Clone(req.Context())
+		req.URL.Host = strings.TrimPrefix(secondary.URL, "http://")
+		req.Host = ""
+		return client.Do(req)
+	})
+
+	tests := []struct {
+		name         string
+		status       int
+		condition    func(resp *http.Response, err error) bool
+		wantBody     string
+		wantFallback bool
+		wantStatus   int
+	}{
+		{name: "healthy primary", status: http.StatusOK, wantBody: "primary"},
+		{name: "server error", status: http.StatusBadGateway, wantBody: "secondary:payload", wantFallback: true, wantStatus: http.StatusBadGateway},
+		{name: "client error is kept", status: http.StatusNotFound, wantBody: "primary"},
+		{
+			name:   "custom condition",
+			status: http.StatusNotFound,
+			condition: func(resp *http.Response, err error) bool {
+				return err != nil || resp.StatusCode == http.StatusNotFound
+			},
+			wantBody:     "secondary:payload",
+			wantFallback: true,
+			wantStatus:   http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.Copy(io.Discard, r.Body)
+				w.WriteHeader(tt.status)
+				w.Write([]byte("primary"))
+			}))
+			defer primary.Close()
+
+			dispatcher := NewDispatcher(nil, Fallback(toSecondary, func(o *FallbackOptions) {
+				if tt.condition != nil {
+					o.Condition = tt.condition
+				}
+			}))
+			// A stream body without GetBody must reach the fallback intact.
+			resp := dispatcher.NewRequest().Body(io.MultiReader(strings.NewReader("payload"))).Post(primary.URL)
+			require.NoError(t, resp.Error)
+			assert.Equal(t, tt.wantBody, resp.String())
+
+			info, ok := resp.Fallback()
+			assert.Equal(t, tt.wantFallback, ok)
+			assert.Equal(t, tt.wantStatus, info.StatusCode)
+			assert.NoError(t, info.Err)
+		})
+	}
+}
+
+func TestFallback_NetworkErrorAndStub(t *testing.T) {
+	dialErr := errors.New("connection refused")
+	failing := transportFunc(func(*http.Request) (*http.Response, error) {
+		return nil, dialErr
+	})
+
+	stub := HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode:    http.StatusOK,
+			ContentLength: -1,
+			Header:        http.Header{"Content-Type": {"application/json"}},
+			Body:          io.NopCloser(strings.NewReader(`{"items":[]}`)),
+		}, nil
+	})
+
+	dispatcher := NewDispatcherWithTransport(failing, Fallback(stub))
+	resp := dispatcher.NewRequest().Get("http://primary.invalid/items")
+	require.NoError(t, resp.Error)
+	assert.Equal(t, `{"items":[]}`, resp.String())
+
+	info, ok := resp.Fallback()
+	require.True(t, ok)
+	assert.Zero(t, info.StatusCode)
+	assert.ErrorIs(t, info.Err, dialErr)
+}
+
+func TestFallback_CanceledRequest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	stub := HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+		called = true
+		return nil, errors.New("unexpected")
+	})
+	failing := transportFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, req.Context().Err()
+	})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://primary.invalid", nil)
+	require.NoError(t, err)
+	_, err = NewDispatcherWithTransport(failing, Fallback(stub)).Do(req)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, called)
+}
@@ -0,0 +1,224 @@
+package fetch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ErrNoFixture is matched by errors.Is when FixtureTransport has no fixture
+// for a request and no fallback transport.
+var ErrNoFixture = errors.New("no fixture for request")
+
+// fixtureLatencyHeader sets the latency of a single fixture. It is removed
+// from the response.
+const fixtureLatencyHeader = "X-Fixture-Latency"
+
+// FixtureOptions configures a FixtureTransport.
+type FixtureOptions struct {
+	// Latency delays every response, e.g. ParetoLatency for a realistic
+	// tail. A fixture's X-Fixture-Latency header such as "250ms" overrides
+	// it for that fixture.
+	Latency LatencyDistribution
+	// Random drives Latency. Use SeededRandom for reproducible runs; nil
+	// uses the global generator.
+	Random RandomSource
+	// Fallback handles requests without a fixture. When nil such requests
+	// fail with ErrNoFixture.
+	Fallback http.RoundTripper
+}
+
+// FixtureTransport is an http.RoundTripper serving responses from a fixtures
+// directory, so SDK examples and documentation tests run offline while
+// exercising the complete client. A request is answered by the file
+// <dir>/<METHOD>/<path>.http, where the root path maps to index.http. A
+// directory or file named like {id} matches any path segment and exposes it
+// to templates as .Params.id; exact names win.
+//
+// Fixtures are raw HTTP responses as accepted by ReadResponse and are
+// executed as a text/template with the request's Method, Path, Host, Query,
+// Header, Params and Body before being parsed, so their bodies should not
+// declare a Content-Length. Files are read on every request, so fixtures can
+// be edited while a demo runs. It is safe for concurrent use.
+type FixtureTransport struct {
+	dir     string
+	options *FixtureOptions
+}
+
+// NewFixtureTransport creates a FixtureTransport serving fixtures from dir.
+//
+// Example layout:
+//
+//	fixtures/GET/index.http
+//	fixtures/GET/users/{id}.http
+//	fixtures/POST/users.http
+//
+// with fixtures/GET/users/{id}.http holding:
+//
+//	HTTP/1.1 200 OK
+//	Content-Type: application/json
+//	X-Fixture-Latency: 120ms
+//
+//	{"id": "{{.Params.id}}", "name": "Ada"}
+//
+// Example:
+//
+//	transport := fetch.NewFixtureTransport("fixtures", func(o *fetch.FixtureOptions) {
+//	    o.Latency = fetch.NormalLatency(80*time.Millisecond, 20*time.Millisecond)
+//	})
+//	dispatcher := fetch.NewDispatcherWithTransport(transport)
+func NewFixtureTransport(dir string, opts ...func(*FixtureOptions)) *FixtureTransport {
+	return &FixtureTransport{dir: dir, options: applyOptions(&FixtureOptions{}, opts...)}
+}
+
+// RoundTrip answers req from its fixture.
+func (t *FixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	file, params, err := t.find(req)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	if file == "" {
+		if t.options.Fallback != nil {
+			return t.options.Fallback.RoundTrip(req)
+		}
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("%w: %s %s", ErrNoFixture, req.Method, req.URL.Redacted())
+	}
+
+	data, err := newMockRequestData(req, params)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(filepath.Base(file)).Option("missingkey=zero").Parse(string(raw))
+	if err != nil {
+		return nil, fmt.Errorf("fixture %s: %w", file, err)
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return nil, fmt.Errorf("fixture %s: %w", file, err)
+	}
+
+	resp, err := ReadResponse(&rendered, req)
+	if err != nil {
+		return nil, fmt.Errorf("fixture %s: %w", file, err)
+	}
+
+	var latency time.Duration
+	if t.options.Latency != nil {
+		latency = t.options.Latency(t.options.Random)
+	}
+	if value := resp.Header.Get(fixtureLatencyHeader); value != "" {
+		resp.Header.Del(fixtureLatencyHeader)
+		if latency, err = time.ParseDuration(value); err != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("fixture %s: %w", file, err)
+		}
+	}
+	if err := sleepContext(req.Context(), latency); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// find returns the fixture file of req and the values of its wildcard
+// segments, or an empty file name when there is none.
+func (t *FixtureTransport) find(req *http.Request) (string, map[string]string, error) {
+	var segments []string
+	if path := strings.Trim(req.URL.Path, "/"); path != "" {
+		segments = strings.Split(path, "/")
+	}
+	method := strings.ToUpper(req.Method)
+	if !isFixtureName(method) {
+		return "", nil, nil
+	}
+	for _, segment := range segments {
+		if !isFixtureName(segment) {
+			return "", nil, nil
+		}
+	}
+	if len(segments) == 0 {
+		segments = []string{"index"}
+	}
+
+	params := map[string]string{}
+	file, err := findFixture(filepath.Join(t.dir, method), segments, params)
+	return file, params, err
+}
+
+// isFixtureName reports whether name can be used as a single file name below
+// the fixtures directory without escaping it.
+func isFixtureName(name string) bool {
+	if name == "" || name == "." || name == ".." {
+		return false
+	}
+	return !strings.ContainsAny(name, "/\\\x00")
+}
+
+// findFixture matches segments against dir, preferring exact names over
+// wildcards, and records the wildcard values in params.
+func findFixture(dir string, segments []string, params map[string]string) (string, error) {
+	segment, last := segments[0], len(segments) == 1
+
+	exact := filepath.Join(dir, segment)
+	if last {
+		exact += ".http"
+	}
+	if info, err := os.Stat(exact); err == nil && info.IsDir() != last {
+		if last {
+			return exact, nil
+		}
+		if file, err := findFixture(exact, segments[1:], params); file != "" || err != nil {
+			return file, err
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if last {
+			if entry.IsDir() || !strings.HasSuffix(name, ".http") {
+				continue
+			}
+			name = strings.TrimSuffix(name, ".http")
+		} else if !entry.IsDir() {
+			continue
+		}
+		if !strings.HasPrefix(name, "{") || !strings.HasSuffix(name, "}") {
+			continue
+		}
+
+		key := name[1 : len(name)-1]
+		if last {
+			params[key] = segment
+			return filepath.Join(dir, entry.Name()), nil
+		}
+		file, err := findFixture(filepath.Join(dir, entry.Name()), segments[1:], params)
+		if file != "" || err != nil {
+			params[key] = segment
+			return file, err
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,155 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFixtures(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestFixtureTransport(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"GET/index.http":                "HTTP/1.1 200 OK\n\nhome",
+		"GET/users/{id}.http":           "HTTP/1.1 200 OK\nContent-Type: application/json\n\n{\"id\":\"{{.Params.id}}\",\"q\":\"{{.Query.q}}\"}",
+		"GET/users/me.http":             "HTTP/1.1 200 OK\n\nme",
+		"GET/orgs/{org}/repos/{r}.http": "HTTP/1.1 200 OK\n\n{{.Params.org}}/{{.Params.r}}",
+		"POST/users.http":               "HTTP/1.1 201 Created\n\n{{.Header.Authorization}} created {{.Body}}",
+		"DELETE/users/{id}.http":        "HTTP/1.1 204 No Content\n\n",
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		body       string
+		wantStatus int
+		wantBody   string
+		wantErr    error
+	}{
+		{name: "root", method: http.MethodGet, url: "/", wantStatus: 200, wantBody: "home"},
+		{name: "wildcard with query", method: http.MethodGet, url: "/users/42?q=x", wantStatus: 200, wantBody: `{"id":"42","q":"x"}`},
+		{name: "exact wins", method: http.MethodGet, url: "/users/me", wantStatus: 200, wantBody: "me"},
+		{name: "nested wildcards", method: http.MethodGet, url: "/orgs/acme/repos/fetch", wantStatus: 200, wantBody: "acme/fetch"},
+		{name: "body template", method: http.MethodPost, url: "/users", body: "ada", wantStatus: 201, wantBody: "Bearer t created ada"},
+		{name: "no content", method: http.MethodDelete, url: "/users/1", wantStatus: 204, wantBody: ""},
+		{name: "missing", method: http.MethodGet, url: "/teams", wantErr: ErrNoFixture},
+		{name: "wrong method", method: http.MethodPut, url: "/users/1", wantErr: ErrNoFixture},
+		{name: "traversal", method: http.MethodGet, url: "/../GET/index", wantErr: ErrNoFixture},
+	}
+
+	dispatcher := NewDispatcherWithTransport(NewFixtureTransport(dir))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := dispatcher.NewRequest().UseFuncs(func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer t")
+			})
+			if tt.body != "" {
+				req = req.Body(strings.NewReader(tt.body))
+			}
+			resp := req.Send(tt.method, "https://api.example.com"+tt.url)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, resp.Error, tt.wantErr)
+				return
+			}
+			require.NoError(t, resp.Error)
+			assert.Equal(t, tt.wantStatus, resp.RawResponse.StatusCode)
+			assert.Equal(t, tt.wantBody, resp.String())
+		})
+	}
+}
+
+func TestFixtureTransport_Latency(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"GET/fast.http": "HTTP/1.1 200 OK\n\nfast",
+		"GET/slow.http": "HTTP/1.1 200 OK\nX-Fixture-Latency: 40ms\n\nslow",
+		"GET/bad.http":  "HTTP/1.1 200 OK\nX-Fixture-Latency: soon\n\nbad",
+	})
+	transport := NewFixtureTransport(dir, func(o *FixtureOptions) {
+		o.Latency = FixedLatency(10 * time.Millisecond)
+	})
+	dispatcher := NewDispatcherWithTransport(transport)
+
+	start := time.Now()
+	resp := dispatcher.NewRequest().Get("http://demo/fast")
+	require.NoError(t, resp.Error)
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+
+	start = time.Now()
+	resp = dispatcher.NewRequest().Get("http://demo/slow")
+	require.NoError(t, resp.Error)
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	assert.Empty(t, resp.RawResponse.Header.Get("X-Fixture-Latency"))
+	assert.Equal(t, "slow", resp.String())
+
+	resp = dispatcher.NewRequest().Get("http://demo/bad")
+	assert.Error(t, resp.Error)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://demo/slow", nil)
+	require.NoError(t, err)
+	_, err = dispatcher.Do(req)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestFixtureTransport_Fallback(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{"GET/known.http": "HTTP/1.1 200 OK\n\nknown"})
+	fallbackErr := errors.New("live upstream")
+	transport := NewFixtureTransport(dir, func(o *FixtureOptions) {
+		o.Fallback = transportFunc(func(*http.Request) (*http.Response, error) { return nil, fallbackErr })
+	})
+	dispatcher := NewDispatcherWithTransport(transport)
+
+	assert.Equal(t, "known", dispatcher.NewRequest().Get("http://demo/known").String())
+	assert.ErrorIs(t, dispatcher.NewRequest().Get("http://demo/unknown").Error, fallbackErr)
+}
+
+func TestFixtureTransport_RejectsEscapingNames(t *testing.T) {
+	dir := writeFixtures(t, map[string]string{
+		"GET/index.http":         "HTTP/1.1 200 OK\n\nhome",
+		"GET/users/{id}.http":    "HTTP/1.1 200 OK\n\n{{.Params.id}}",
+		"GET/secret/index.http":  "HTTP/1.1 200 OK\n\nsecret",
+		"GET/secret/{name}.http": "HTTP/1.1 200 OK\n\nsecret",
+	})
+	transport := NewFixtureTransport(filepath.Join(dir, "GET", "users"))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{name: "parent method", method: "..", path: "/index"},
+		{name: "method with separator", method: "../../GET/secret", path: "/index"},
+		{name: "method with backslash", method: `..\GET`, path: "/index"},
+		{name: "current method", method: ".", path: "/index"},
+		{name: "dot segment", method: http.MethodGet, path: "/./index"},
+		{name: "backslash segment", method: http.MethodGet, path: `/..\secret/index`},
+		{name: "nul segment", method: http.MethodGet, path: "/index\x00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &http.Request{Method: tt.method, URL: &url.URL{Scheme: "http", Host: "demo", Path: tt.path}, Header: http.Header{}}
+			resp, err := transport.RoundTrip(req)
+			assert.ErrorIs(t, err, ErrNoFixture)
+			assert.Nil(t, resp)
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package fetch
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/rockcookies/go-fetch/internal/utils"
+)
+
+var dialFanOutKey = utils.NewContextKey[[]func(*DialFanOutOptions)]("dial_fan_out")
+
+// DialFanOutOptions configures how DialFanOut spreads connection attempts
+// over the addresses of a host.
+type DialFanOutOptions struct {
+	// HappyEyeballs races the addresses as described in RFC 8305: addresses
+	// alternate between IPv6 and IPv4, and the next one is tried once the
+	// previous attempt failed or FallbackDelay passed without an answer.
+	// When disabled the addresses are tried one after another. Defaults to true.
+	HappyEyeballs bool
+	// FallbackDelay is the time an attempt gets before the next address is
+	// tried in parallel. Defaults to 300ms.
+	FallbackDelay time.Duration
+	// MaxParallel limits the attempts in flight at once; zero means no limit.
+	MaxParallel int
+	// Resolver resolves host names. Defaults to net.DefaultResolver.
+	Resolver *net.Resolver
+}
+
+// DialFanOut returns a client option that resolves host names itself and
+// controls the connection attempts over the resolved addresses, so broken
+// IPv6 connectivity can be worked around or diagnosed. Requests can adjust
+// the options with SetDialFanOut; Trace reports the attempts and the address
+// family that won. When no address accepts a connection the error is a
+// DialError listing the addresses attempted.
+//
+// Example:
+//
+//	dispatcher.Use(fetch.SetClientOptions(fetch.DialFanOut(func(o *fetch.DialFanOutOptions) {
+//	    o.FallbackDelay = 100 * time.Millisecond
+//	    o.MaxParallel = 2
+//	})), fetch.PrepareClientMiddleware())
+func DialFanOut(opts ...func(*DialFanOutOptions)) func(*http.Client) {
+	options := applyOptions(&DialFanOutOptions{
+		HappyEyeballs: true,
+		FallbackDelay: 300 * time.Millisecond,
+		Resolver:      net.DefaultResolver,
+	}, opts...)
+
+	var transports transportCache
+
+	return func(c *http.Client) {
+		transports.apply(c, func(transport *http.Transport) {
+			dial := transport.DialContext
+			if dial == nil {
+				dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+			}
+
+			transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				host, port, err := net.SplitHostPort(addr)
+				if err != nil {
+					return nil, err
+				}
+
+				options := options
+				if overrides, ok := dialFanOutKey.GetValue(ctx); ok {
+					copied := *options
+					options = applyOptions(&copied, overrides...)
+				}
+
+				var addrs []net.IPAddr
+				if ip := net.ParseIP(host); ip != nil {
+					addrs = []net.IPAddr{{IP: ip}}
+				} else if addrs, err = options.Resolver.LookupIPAddr(ctx, host); err != nil {
+					return nil, err
+				}
+
+				var targets []string
+				for _, ip := range interleaveFamilies(addrs, network) {
+					targets = append(targets, net.JoinHostPort(ip.String(), port))
+				}
+				if len(targets) == 0 {
+					return nil, &DialError{Host: host, Err: &net.AddrError{Err: "no suitable address", Addr: host}}
+				}
+
+				recorder, _ := traceRecorderKey.GetValue(ctx)
+				return fanOutDial(ctx, host, targets, options, func(ctx context.Context, target string) (net.Conn, error) {
+					start := time.Now()
+					conn, err := dial(ctx, network, target)
+					if recorder != nil {
+						recorder.addAttempt(DialAttempt{Addr: target, Duration: time.Since(start), Err: err})
+					}
+					return conn, err
+				})
+			}
+		})
+	}
+}
+
+// SetDialFanOut creates middleware adjusting the options of DialFanOut for
+// the request, on top of the client's options. It only affects connections
+// dialed for the request; pooled connections are reused as before. It has no
+// effect on clients without the DialFanOut option.
+//
+// Example:
+//
+//	resp := dispatcher.NewRequest().
+//	    Use(fetch.SetDialFanOut(func(o *fetch.DialFanOutOptions) { o.HappyEyeballs = false })).
+//	    Get(url)
+func SetDialFanOut(opts ...func(*DialFanOutOptions)) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			overrides, _ := dialFanOutKey.GetValue(req.Context())
+			overrides = append(overrides[:len(overrides):len(overrides)], opts...)
+			req = req.WithContext(dialFanOutKey.WithValue(req.Context(), overrides))
+			return next.Handle(client, req)
+		})
+	}
+}
+
+// interleaveFamilies orders addrs for network, alternating between the
+// address families starting with the family of the first address.
+func interleaveFamilies(addrs []net.IPAddr, network string) []net.IP {
+	var first, second []net.IP
+	for _, addr := range addrs {
+		v4 := addr.IP.To4() != nil
+		if (network == "tcp4" && !v4) || (network == "tcp6" && v4) {
+			continue
+		}
+		if len(first) == 0 || (first[0].To4() != nil) == v4 {
+			first = append(first, addr.IP)
+		} else {
+			second = append(second, addr.IP)
+		}
+	}
+
+	ordered := make([]net.IP, 0, len(first)+len(second))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			ordered = append(ordered, first[i])
+		}
+		if i < len(second) {
+			ordered = append(ordered, second[i])
+		}
+	}
+	return ordered
+}
+
+type dialResult struct {
+	conn net.Conn
+	err  error
+}
+
+// fanOutDial connects to the first of targets that answers, starting the
+// attempts according to options. Connections that win after another one
+// already did are closed.
+func fanOutDial(ctx context.Context, host string, targets []string, options *DialFanOutOptions, dial func(context.Context, string) (net.Conn, error)) (net.Conn, error) {
+	parallel := options.MaxParallel
+	if !options.HappyEyeballs {
+		parallel = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialResult, len(targets))
+	dialErr := &DialError{Host: host}
+	running := 0
+	var fallback *time.Timer
+	var fallbackC <-chan time.Time
+
+	start := func() {
+		target := targets[len(dialErr.Addrs)]
+		dialErr.Addrs = append(dialErr.Addrs, target)
+		running++
+		go func() {
+			conn, err := dial(ctx, target)
+			results <- dialResult{conn: conn, err: err}
+		}()
+
+		if fallback != nil {
+			fallback.Stop()
+		}
+		fallbackC = nil
+		if options.HappyEyeballs && len(dialErr.Addrs) < len(targets) {
+			fallback = time.NewTimer(options.FallbackDelay)
+			fallbackC = fallback.C
+		}
+	}
+	more := func() bool {
+		return len(dialErr.Addrs) < len(targets) && (parallel <= 0 || running < parallel)
+	}
+
+	start()
+	for running > 0 {
+		select {
+		case result := <-results:
+			running--
+			if result.err == nil {
+				if fallback != nil {
+					fallback.Stop()
+				}
+				go func(pending int) {
+					for range pending {
+						if late := <-results; late.conn != nil {
+							late.conn.Close()
+						}
+					}
+				}(running)
+				return result.conn, nil
+			}
+			dialErr.Err = result.err
+			if ctx.Err() == nil && more() {
+				start()
+			}
+		case <-fallbackC:
+			fallbackC = nil
+			if more() {
+				start()
+			}
+		}
+	}
+	return nil, dialErr
+}
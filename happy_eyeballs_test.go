@@ -0,0 +1,200 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterleaveFamilies(t *testing.T) {
+	addrs := []net.IPAddr{
+		{IP: net.ParseIP("2001:db8::1")},
+		{IP: net.ParseIP("2001:db8::2")},
+		{IP: net.ParseIP("192.0.2.1")},
+		{IP: net.ParseIP("2001:db8::3")},
+	}
+
+	tests := []struct {
+		network string
+		want    []string
+	}{
+		{network: "tcp", want: []string{"2001:db8::1", "192.0.2.1", "2001:db8::2", "2001:db8::3"}},
+		{network: "tcp4", want: []string{"192.0.2.1"}},
+		{network: "tcp6", want: []string{"2001:db8::1", "2001:db8::2", "2001:db8::3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.network, func(t *testing.T) {
+			var got []string
+			for _, ip := range interleaveFamilies(addrs, tt.network) {
+				got = append(got, ip.String())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFanOutDial(t *testing.T) {
+	targets := []string{"[2001:db8::1]:80", "192.0.2.1:80", "[2001:db8::2]:80"}
+
+	// behaviour maps targets to the time until they answer and whether they fail.
+	type outcome struct {
+		after time.Duration
+		fail  bool
+	}
+
+	tests := []struct {
+		name      string
+		options   DialFanOutOptions
+		behaviour map[string]outcome
+		want      string
+		wantErr   bool
+		wantAddrs []string
+	}{
+		{
+			name:    "hanging ipv6 falls back to ipv4",
+			options: DialFanOutOptions{HappyEyeballs: true, FallbackDelay: 10 * time.Millisecond},
+			behaviour: map[string]outcome{
+				"[2001:db8::1]:80": {after: time.Second},
+				"192.0.2.1:80":     {},
+			},
+			want:      "192.0.2.1:80",
+			wantAddrs: []string{"[2001:db8::1]:80", "192.0.2.1:80"},
+		},
+		{
+			name:    "failure starts next attempt immediately",
+			options: DialFanOutOptions{HappyEyeballs: true, FallbackDelay: time.Minute},
+			behaviour: map[string]outcome{
+				"[2001:db8::1]:80": {fail: true},
+				"192.0.2.1:80":     {},
+			},
+			want:      "192.0.2.1:80",
+			wantAddrs: []string{"[2001:db8::1]:80", "192.0.2.1:80"},
+		},
+		{
+			name:    "disabled tries addresses in order",
+			options: DialFanOutOptions{HappyEyeballs: false, FallbackDelay: time.Millisecond},
+			behaviour: map[string]outcome{
+				"[2001:db8::1]:80": {after: 50 * time.Millisecond, fail: true},
+				"192.0.2.1:80":     {},
+			},
+			want:      "192.0.2.1:80",
+			wantAddrs: []string{"[2001:db8::1]:80", "192.0.2.1:80"},
+		},
+		{
+			name:    "max parallel holds back fallback",
+			options: DialFanOutOptions{HappyEyeballs: true, FallbackDelay: time.Millisecond, MaxParallel: 1},
+			behaviour: map[string]outcome{
+				"[2001:db8::1]:80": {after: 50 * time.Millisecond},
+				"192.0.2.1:80":     {},
+			},
+			want:      "[2001:db8::1]:80",
+			wantAddrs: []string{"[2001:db8::1]:80"},
+		},
+		{
+			name:    "all attempts fail",
+			options: DialFanOutOptions{HappyEyeballs: true, FallbackDelay: time.Millisecond},
+			behaviour: map[string]outcome{
+				"[2001:db8::1]:80": {fail: true},
+				"192.0.2.1:80":     {fail: true},
+				"[2001:db8::2]:80": {fail: true},
+			},
+			wantErr:   true,
+			wantAddrs: targets,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var closed []string
+			dial := func(ctx context.Context, target string) (net.Conn, error) {
+				o := tt.behaviour[target]
+				select {
+				case <-time.After(o.after):
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+				if o.fail {
+					return nil, errors.New("connection refused")
+				}
+				client, server := net.Pipe()
+				server.Close()
+				return &namedConn{Conn: client, name: target, onClose: func() {
+					mu.Lock()
+					defer mu.Unlock()
+					closed = append(closed, target)
+				}}, nil
+			}
+
+			options := tt.options
+			conn, err := fanOutDial(context.Background(), "example.com", targets, &options, dial)
+
+			if tt.wantErr {
+				var dialErr *DialError
+				require.ErrorAs(t, err, &dialErr)
+				assert.Equal(t, tt.wantAddrs, dialErr.Addrs)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, conn.(*namedConn).name)
+			conn.Close()
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, []string{tt.want}, closed)
+		})
+	}
+}
+
+type namedConn struct {
+	net.Conn
+	name    string
+	onClose func()
+}
+
+func (c *namedConn) Close() error {
+	c.onClose()
+	return c.Conn.Close()
+}
+
+func TestDialFanOut_Trace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	dispatcher := NewDispatcher(nil,
+		SetClientOptions(DialFanOut(func(o *DialFanOutOptions) { o.FallbackDelay = 10 * time.Millisecond })),
+		PrepareClientMiddleware(),
+	)
+
+	resp := dispatcher.NewRequest().
+		Use(Trace(), SetDialFanOut(func(o *DialFanOutOptions) { o.HappyEyeballs = false })).
+		Get("http://localhost:" + serverURL.Port())
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "ok", resp.String())
+
+	info := resp.TraceInfo()
+	require.NotNil(t, info)
+	assert.Equal(t, FamilyIPv4, info.Family)
+	assert.Equal(t, serverURL.Host, info.RemoteAddr)
+	assert.False(t, info.Reused)
+	require.NotEmpty(t, info.DialAttempts)
+	last := info.DialAttempts[len(info.DialAttempts)-1]
+	assert.Equal(t, serverURL.Host, last.Addr)
+	assert.NoError(t, last.Err)
+
+	assert.Nil(t, dispatcher.NewRequest().Get(server.URL).TraceInfo())
+}
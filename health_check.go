@@ -0,0 +1,234 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// HealthCheckOptions configures a HealthChecker.
+type HealthCheckOptions struct {
+	// FailureThreshold is the number of consecutive failed requests after
+	// which a host is marked unhealthy. Defaults to 3.
+	FailureThreshold int
+	// IsFailure classifies the outcome of an observed request. Defaults to
+	// network errors other than cancellation and 5xx responses.
+	IsFailure func(resp *http.Response, err error) bool
+	// Interval is the time between probes of an unhealthy host. Defaults to 10s.
+	Interval time.Duration
+	// Timeout bounds a single probe. Defaults to 5s.
+	Timeout time.Duration
+	// NewProbe builds the health-check request for a host, e.g. a GET of
+	// /healthz. Defaults to GET / over the scheme the host was observed with.
+	NewProbe func(ctx context.Context, scheme, host string) (*http.Request, error)
+	// IsHealthy classifies the outcome of a probe. Defaults to 2xx and 3xx
+	// responses.
+	IsHealthy func(resp *http.Response, err error) bool
+	// Transport sends the probes. Defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+	// OnChange is called after a host became unhealthy or recovered, e.g.
+	// to emit metrics. It must not block.
+	OnChange func(host string, healthy bool)
+}
+
+// HealthChecker tracks the health of upstream hosts passively: a host is
+// marked unhealthy after FailureThreshold consecutive failed requests and is
+// then probed every Interval until a probe succeeds. LoadBalancer and
+// StickySession skip hosts it reports unhealthy when set in their options.
+// It is safe for concurrent use; Close stops the probes.
+type HealthChecker struct {
+	options *HealthCheckOptions
+	ctx     context.Context
+	cancel  context.CancelFunc
+	probes  sync.WaitGroup
+
+	mu    sync.Mutex
+	hosts map[string]*hostHealth
+}
+
+type hostHealth struct {
+	scheme    string
+	failures  int
+	unhealthy bool
+	probing   bool
+}
+
+// NewHealthChecker creates a HealthChecker with all hosts healthy.
+//
+// Example:
+//
+//	health := fetch.NewHealthChecker(func(o *fetch.HealthCheckOptions) {
+//	    o.Interval = 5 * time.Second
+//	    o.NewProbe = func(ctx context.Context, scheme, host string) (*http.Request, error) {
+//	        return http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+host+"/healthz", nil)
+//	    }
+//	})
+//	defer health.Close()
+//	lb := fetch.NewLoadBalancer(provider, func(o *fetch.LoadBalancerOptions) {
+//	    o.HealthChecker = health
+//	})
+func NewHealthChecker(opts ...func(*HealthCheckOptions)) *HealthChecker {
+	options := applyOptions(&HealthCheckOptions{
+		FailureThreshold: 3,
+		IsFailure:        defaultCircuitFailure,
+		Interval:         10 * time.Second,
+		Timeout:          5 * time.Second,
+		NewProbe:         defaultHealthProbe,
+		IsHealthy:        defaultHealthy,
+	}, opts...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &HealthChecker{options: options, ctx: ctx, cancel: cancel, hosts: map[string]*hostHealth{}}
+}
+
+func defaultHealthProbe(ctx context.Context, scheme, host string) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+host+"/", nil)
+}
+
+func defaultHealthy(resp *http.Response, err error) bool {
+	return err == nil && resp.StatusCode < 400
+}
+
+// Healthy reports whether host is healthy. Unknown hosts are healthy.
+func (h *HealthChecker) Healthy(host string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state, ok := h.hosts[host]
+	return !ok || !state.unhealthy
+}
+
+// Unhealthy returns the hosts currently marked unhealthy, sorted.
+func (h *HealthChecker) Unhealthy() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var hosts []string
+	for host, state := range h.hosts {
+		if state.unhealthy {
+			hosts = append(hosts, host)
+		}
+	}
+	slices.Sort(hosts)
+	return hosts
+}
+
+// Observe records the outcome of a request to req.URL.Host. A success marks
+// an unhealthy host healthy again.
+func (h *HealthChecker) Observe(req *http.Request, resp *http.Response, err error) {
+	if req.Context().Err() != nil {
+		return
+	}
+	h.record(req.URL.Scheme, req.URL.Host, !h.options.IsFailure(resp, err))
+}
+
+// Middleware returns middleware observing every request it sends, for
+// health tracking of requests not routed by a LoadBalancer or StickySession.
+func (h *HealthChecker) Middleware() Middleware {
+	return bypassDryRun(func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			resp, err := next.Handle(client, req)
+			h.Observe(req, resp, err)
+			return resp, err
+		})
+	})
+}
+
+// Close stops probing and waits for running probes to return.
+func (h *HealthChecker) Close() {
+	h.cancel()
+	h.probes.Wait()
+}
+
+func (h *HealthChecker) record(scheme, host string, success bool) {
+	h.mu.Lock()
+	state, ok := h.hosts[host]
+	if !ok {
+		state = &hostHealth{}
+		h.hosts[host] = state
+	}
+	if scheme != "" {
+		state.scheme = scheme
+	}
+
+	changed := false
+	switch {
+	case success:
+		state.failures = 0
+		changed = state.unhealthy
+		state.unhealthy = false
+	case !state.unhealthy:
+		state.failures++
+		if state.failures >= h.options.FailureThreshold {
+			state.unhealthy, changed = true, true
+			if !state.probing && h.ctx.Err() == nil {
+				state.probing = true
+				h.probes.Add(1)
+				go h.probe(host)
+			}
+		}
+	}
+	h.mu.Unlock()
+
+	if changed && h.options.OnChange != nil {
+		h.options.OnChange(host, success)
+	}
+}
+
+// probe checks an unhealthy host every Interval until it recovers, by probe
+// or by an observed request, or the checker is closed.
+func (h *HealthChecker) probe(host string) {
+	defer h.probes.Done()
+
+	ticker := time.NewTicker(h.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		h.mu.Lock()
+		state := h.hosts[host]
+		scheme, unhealthy := state.scheme, state.unhealthy
+		if !unhealthy {
+			state.probing = false
+		}
+		h.mu.Unlock()
+		if !unhealthy {
+			return
+		}
+		if scheme == "" {
+			scheme = "http"
+		}
+
+		if h.check(scheme, host) {
+			h.mu.Lock()
+			state.probing = false
+			h.mu.Unlock()
+			h.record(scheme, host, true)
+			return
+		}
+	}
+}
+
+func (h *HealthChecker) check(scheme, host string) bool {
+	ctx, cancel := context.WithTimeout(h.ctx, h.options.Timeout)
+	defer cancel()
+
+	req, err := h.options.NewProbe(ctx, scheme, host)
+	if err != nil {
+		return false
+	}
+	transport := h.options.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(req)
+	drainAndClose(resp)
+	return h.ctx.Err() == nil && h.options.IsHealthy(resp, err)
+}
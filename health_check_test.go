@@ -0,0 +1,135 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthChecker(t *testing.T) {
+	var healthy atomic.Bool
+	var probes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" {
+			probes.Add(1)
+		}
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	host := mustParseURL(t, server.URL).Host
+
+	var mu sync.Mutex
+	var changes []bool
+	health := NewHealthChecker(func(o *HealthCheckOptions) {
+		o.FailureThreshold = 2
+		o.Interval = 10 * time.Millisecond
+		o.NewProbe = func(ctx context.Context, scheme, host string) (*http.Request, error) {
+			return http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+host+"/healthz", nil)
+		}
+		o.OnChange = func(h string, ok bool) {
+			assert.Equal(t, host, h)
+			mu.Lock()
+			changes = append(changes, ok)
+			mu.Unlock()
+		}
+	})
+	defer health.Close()
+
+	dispatcher := NewDispatcher(nil, health.Middleware())
+	dispatcher.NewRequest().Get(server.URL)
+	assert.True(t, health.Healthy(host))
+	dispatcher.NewRequest().Get(server.URL)
+	assert.False(t, health.Healthy(host))
+	assert.Equal(t, []string{host}, health.Unhealthy())
+
+	// Probes keep failing while the host is down.
+	require.Eventually(t, func() bool { return probes.Load() >= 2 }, time.Second, 5*time.Millisecond)
+	assert.False(t, health.Healthy(host))
+
+	healthy.Store(true)
+	require.Eventually(t, func() bool { return health.Healthy(host) }, time.Second, 5*time.Millisecond)
+	assert.Empty(t, health.Unhealthy())
+
+	mu.Lock()
+	assert.Equal(t, []bool{false, true}, changes)
+	mu.Unlock()
+}
+
+func TestHealthChecker_ObservedSuccessRecovers(t *testing.T) {
+	health := NewHealthChecker(func(o *HealthCheckOptions) {
+		o.FailureThreshold = 1
+		o.Interval = time.Hour
+	})
+	req := httptest.NewRequest(http.MethodGet, "http://api.example.com/", nil)
+
+	health.Observe(req, &http.Response{StatusCode: http.StatusBadGateway}, nil)
+	assert.False(t, health.Healthy("api.example.com"))
+	health.Observe(req, &http.Response{StatusCode: http.StatusOK}, nil)
+	assert.True(t, health.Healthy("api.example.com"))
+
+	// Close returns without waiting for the next probe.
+	health.Observe(req, nil, assert.AnError)
+	done := make(chan struct{})
+	go func() {
+		health.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close did not stop the probe")
+	}
+}
+
+func TestHealthChecker_Integrations(t *testing.T) {
+	healthyA, healthyB := false, true
+	a := newUpstream("a", &healthyA)
+	defer a.Close()
+	b := newUpstream("b", &healthyB)
+	defer b.Close()
+
+	health := NewHealthChecker(func(o *HealthCheckOptions) {
+		o.FailureThreshold = 1
+		o.Interval = time.Hour
+	})
+	defer health.Close()
+
+	lb := NewLoadBalancer(StaticHosts(Upstream{URL: a.URL}, Upstream{URL: b.URL}), func(o *LoadBalancerOptions) {
+		o.HealthChecker = health
+	})
+	dispatcher := NewDispatcher(nil, lb.Middleware())
+	for i := 0; i < 4; i++ {
+		dispatcher.NewRequest().Get("/")
+	}
+	assert.Equal(t, []string{mustParseURL(t, a.URL).Host}, health.Unhealthy())
+	assert.Equal(t, map[string]bool{a.URL: false, b.URL: true}, lb.Healthy())
+
+	// The sticky session tries the unhealthy backend last.
+	session, err := NewStickySession([]string{a.URL, b.URL}, func(o *StickyOptions) {
+		o.HealthChecker = health
+	})
+	require.NoError(t, err)
+	healthyA = true
+	resp := NewDispatcher(nil, session.Middleware()).NewRequest().Get("/")
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "b", resp.String())
+}
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+	return u
+}
@@ -0,0 +1,250 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/rockcookies/go-fetch/internal/utils"
+)
+
+var hedgeKey = utils.NewContextKey[int]("hedge")
+
+// defaultHedgeDelay is the default HedgeOptions.Delay.
+const defaultHedgeDelay = 100 * time.Millisecond
+
+// HedgeOptions configures the Hedge middleware.
+type HedgeOptions struct {
+	// Delay is how long an attempt may take before a hedged duplicate is
+	// sent, typically around the 95th percentile latency. Defaults to 100ms.
+	Delay time.Duration
+	// MaxHedges is the number of duplicates sent in addition to the
+	// original request. Defaults to 1.
+	MaxHedges int
+	// Hosts are base URLs the duplicates are sent to in turn, e.g. replicas
+	// of the upstream; their scheme and host replace those of the request.
+	// When empty the duplicates go to the same host. Redirects are followed
+	// on the host they point to.
+	Hosts []string
+	// Match selects the requests that are hedged. Only idempotent requests
+	// are safe to send twice. Defaults to GET, HEAD and OPTIONS requests.
+	Match Matcher
+	// IsSuccess decides whether an outcome ends the race. Defaults to
+	// responses below 500.
+	IsSuccess func(resp *http.Response, err error) bool
+}
+
+// Hedge creates middleware cutting tail latency by hedging: when an attempt
+// has not answered after Delay, a duplicate is sent to the next of Hosts, or
+// to the same host, while the first keeps running. The first successful
+// response is returned and the other attempts are canceled. An attempt that
+// fails before Delay sends the next duplicate at once; when all attempts
+// fail, the outcome of the last one is returned.
+//
+// The middleware wraps the client's transport, so every attempt carries the
+// final request body, which is buffered for the duplicates. Responses served
+// by a duplicate can be recognized with Response.Hedged. Hedging applies to
+// the requests selected by Match, so it can be enabled per endpoint with
+// When or a ResiliencePolicy.
+//
+// Example:
+//
+//	dispatcher.Use(fetch.Hedge(func(o *fetch.HedgeOptions) {
+//	    o.Delay = 50 * time.Millisecond
+//	    o.Hosts = []string{"https://replica-2.example.com"}
+//	}))
+func Hedge(opts ...func(*HedgeOptions)) Middleware {
+	options := applyOptions(&HedgeOptions{
+		Delay:     defaultHedgeDelay,
+		MaxHedges: 1,
+		Match:     MatchMethod(http.MethodGet, http.MethodHead, http.MethodOptions),
+		IsSuccess: defaultHedgeSuccess,
+	}, opts...)
+
+	var (
+		hosts    []*url.URL
+		hostsErr error
+	)
+	for _, raw := range options.Hosts {
+		u, err := url.Parse(normalize(raw))
+		if err != nil {
+			hostsErr = &InvalidRequestError{err: err}
+			break
+		}
+		hosts = append(hosts, u)
+	}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			if hostsErr != nil {
+				return nil, hostsErr
+			}
+			client.Transport = &hedgeTransport{base: client.Transport, options: options, hosts: hosts}
+			return next.Handle(client, req)
+		})
+	}
+}
+
+func defaultHedgeSuccess(resp *http.Response, err error) bool {
+	return err == nil && resp.StatusCode < 500
+}
+
+type hedgeTransport struct {
+	base    http.RoundTripper
+	options *HedgeOptions
+	hosts   []*url.URL
+}
+
+func (t *hedgeTransport) unwrap() http.RoundTripper { return t.base }
+
+func (t *hedgeTransport) rewrap(base http.RoundTripper) http.RoundTripper {
+	clone := *t
+	clone.base = base
+	return &clone
+}
+
+type hedgeResult struct {
+	attempt int
+	resp    *http.Response
+	err     error
+}
+
+func (t *hedgeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if t.options.MaxHedges < 1 || !t.options.Match(req) {
+		return base.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	req = req.Clone(ctx)
+	if _, err := EnsureReplayableBody(req, -1); err != nil {
+		return nil, err
+	}
+
+	results := make(chan hedgeResult, t.options.MaxHedges+1)
+	var cancels []context.CancelFunc
+	launch := func(attempt int) error {
+		attemptCtx, cancel := context.WithCancel(hedgeKey.WithValue(ctx, attempt))
+		outgoing := req.Clone(attemptCtx)
+		if attempt > 0 {
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					cancel()
+					return err
+				}
+				outgoing.Body = body
+			}
+			// Only the original hop is sent to the hosts; a redirected
+			// request carries the response that caused it.
+			if len(t.hosts) > 0 && req.Response == nil {
+				host := t.hosts[(attempt-1)%len(t.hosts)]
+				outgoing.URL.Scheme = host.Scheme
+				outgoing.URL.Host = host.Host
+				outgoing.Host = ""
+			}
+		}
+		cancels = append(cancels, cancel)
+		go func() {
+			resp, err := base.RoundTrip(outgoing)
+			results <- hedgeResult{attempt: attempt, resp: resp, err: err}
+		}()
+		return nil
+	}
+
+	// finish cancels the attempts except winner and discards their outcomes.
+	finish := func(winner, pending int) {
+		for i, cancel := range cancels {
+			if i != winner {
+				cancel()
+			}
+		}
+		go func() {
+			for ; pending > 0; pending-- {
+				drainAndClose((<-results).resp)
+			}
+		}()
+	}
+
+	if err := launch(0); err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(t.options.Delay)
+	defer timer.Stop()
+
+	launched, pending := 1, 1
+	var last hedgeResult
+	for {
+		select {
+		case <-timer.C:
+			if launched <= t.options.MaxHedges {
+				if err := launch(launched); err == nil {
+					launched++
+					pending++
+				}
+				timer.Reset(t.options.Delay)
+			}
+			continue
+		case <-ctx.Done():
+			finish(-1, pending)
+			return nil, ctx.Err()
+		case result := <-results:
+			pending--
+			if t.options.IsSuccess(result.resp, result.err) {
+				drainAndClose(last.resp)
+				finish(result.attempt, pending)
+				return t.deliver(result, cancels[result.attempt])
+			}
+			drainAndClose(last.resp)
+			last = result
+		}
+
+		if pending > 0 {
+			continue
+		}
+		if launched > t.options.MaxHedges {
+			break
+		}
+		// Every running attempt failed early; hedge at once.
+		if err := launch(launched); err != nil {
+			break
+		}
+		launched++
+		pending++
+		timer.Reset(t.options.Delay)
+	}
+
+	finish(last.attempt, 0)
+	return t.deliver(last, cancels[last.attempt])
+}
+
+// deliver returns the outcome of an attempt, releasing its context once the
+// body is closed.
+func (t *hedgeTransport) deliver(result hedgeResult, cancel context.CancelFunc) (*http.Response, error) {
+	if result.resp == nil || result.resp.Body == nil {
+		cancel()
+		return result.resp, result.err
+	}
+	result.resp.Body = &cleanupReadCloser{ReadCloser: result.resp.Body, cleanup: cancel}
+	return result.resp, result.err
+}
+
+// Hedged reports whether the response was served by a duplicate sent by the
+// Hedge middleware rather than by the original request.
+//
+// Example:
+//
+//	if resp.Hedged() {
+//	    hedgeWins.Inc()
+//	}
+func (r *Response) Hedged() bool {
+	if r.RawResponse == nil || r.RawResponse.Request == nil {
+		return false
+	}
+	attempt, _ := hedgeKey.GetValue(r.RawResponse.Request.Context())
+	return attempt > 0
+}
@@ -0,0 +1,132 @@
+package fetch
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDelayedServer(name string, delay time.Duration, status int, canceled *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			if canceled != nil {
+				canceled.Add(1)
+			}
+			return
+		}
+		body, _ := readRequestBody(r)
+		w.WriteHeader(status)
+		w.Write([]byte(name + string(body)))
+	}))
+}
+
+func TestHedge(t *testing.T) {
+	var canceled atomic.Int32
+	slow := newDelayedServer("slow", time.Second, http.StatusOK, &canceled)
+	defer slow.Close()
+	fast := newDelayedServer("fast", 0, http.StatusOK, nil)
+	defer fast.Close()
+	failing := newDelayedServer("failing", 0, http.StatusBadGateway, nil)
+	defer failing.Close()
+	medium := newDelayedServer("medium", 60*time.Millisecond, http.StatusOK, nil)
+	defer medium.Close()
+
+	tests := []struct {
+		name       string
+		url        string
+		hosts      []string
+		method     string
+		wantBody   string
+		wantHedged bool
+		wantStatus int
+	}{
+		{name: "original answers first", url: fast.URL, hosts: []string{slow.URL}, method: http.MethodGet, wantBody: "fast", wantStatus: 200},
+		{name: "hedge wins", url: slow.URL, hosts: []string{fast.URL}, method: http.MethodGet, wantBody: "fast", wantHedged: true, wantStatus: 200},
+		{name: "early failure hedges at once", url: failing.URL, hosts: []string{fast.URL}, method: http.MethodGet, wantBody: "fast", wantHedged: true, wantStatus: 200},
+		{name: "all fail", url: failing.URL, method: http.MethodGet, wantBody: "failing", wantHedged: true, wantStatus: 502},
+		{name: "not idempotent", url: medium.URL, hosts: []string{fast.URL}, method: http.MethodPost, wantBody: "medium", wantStatus: 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dispatcher := NewDispatcher(nil, Hedge(func(o *HedgeOptions) {
+				o.Delay = 20 * time.Millisecond
+				o.Hosts = tt.hosts
+			}))
+			resp := dispatcher.NewRequest().Send(tt.method, tt.url)
+			require.NoError(t, resp.Error)
+			assert.Equal(t, tt.wantStatus, resp.RawResponse.StatusCode)
+			assert.Equal(t, tt.wantBody, resp.String())
+			assert.Equal(t, tt.wantHedged, resp.Hedged())
+		})
+	}
+
+	// The losing attempt to the slow host was canceled.
+	assert.Eventually(t, func() bool { return canceled.Load() >= 1 }, time.Second, 5*time.Millisecond)
+}
+
+func TestHedge_ReplaysBody(t *testing.T) {
+	slow := newDelayedServer("slow", time.Second, http.StatusOK, nil)
+	defer slow.Close()
+	fast := newDelayedServer("fast", 0, http.StatusOK, nil)
+	defer fast.Close()
+
+	dispatcher := NewDispatcher(nil, Hedge(func(o *HedgeOptions) {
+		o.Delay = 10 * time.Millisecond
+		o.Hosts = []string{fast.URL}
+		o.Match = MatchMethod(http.MethodPut)
+	}))
+	resp := dispatcher.NewRequest().Body(strings.NewReader(":payload")).Put(slow.URL)
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "fast:payload", resp.String())
+	assert.True(t, resp.Hedged())
+}
+
+func TestHedge_ClosesFailedResponse(t *testing.T) {
+	var calls, closed atomic.Int32
+	transport := transportFunc(func(req *http.Request) (*http.Response, error) {
+		status := http.StatusOK
+		if calls.Add(1) == 1 {
+			status = http.StatusServiceUnavailable
+		}
+		body := &cleanupReadCloser{ReadCloser: io.NopCloser(strings.NewReader("body")), cleanup: func() { closed.Add(1) }}
+		return &http.Response{StatusCode: status, Header: http.Header{}, Body: body, Request: req}, nil
+	})
+
+	dispatcher := NewDispatcher(&http.Client{Transport: transport}, Hedge(func(o *HedgeOptions) {
+		o.Delay = time.Second
+	}))
+	resp := dispatcher.NewRequest().Get("http://example.com/")
+	require.NoError(t, resp.Error)
+	assert.Equal(t, http.StatusOK, resp.RawResponse.StatusCode)
+	assert.Equal(t, int32(1), closed.Load(), "failed response closed")
+
+	resp.Close()
+	assert.Equal(t, int32(2), closed.Load())
+}
+
+func TestHedge_RedirectKeepsHost(t *testing.T) {
+	replica := newDelayedServer("replica", 0, http.StatusOK, nil)
+	defer replica.Close()
+	target := newDelayedServer("target", 60*time.Millisecond, http.StatusOK, nil)
+	defer target.Close()
+	origin := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer origin.Close()
+
+	dispatcher := NewDispatcher(nil, Hedge(func(o *HedgeOptions) {
+		o.Delay = 20 * time.Millisecond
+		o.Hosts = []string{replica.URL}
+	}))
+	resp := dispatcher.NewRequest().Get(origin.URL)
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "target", resp.String())
+}
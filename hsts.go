@@ -0,0 +1,166 @@
+package fetch
+
+import (
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HSTSEntry is a known HSTS host as defined in RFC 6797.
+type HSTSEntry struct {
+	Host              string    `json:"host"`
+	Expires           time.Time `json:"expires"`
+	IncludeSubdomains bool      `json:"include_subdomains"`
+}
+
+// HSTSOptions configures an HSTSStore.
+type HSTSOptions struct {
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// HSTSStore remembers the Strict-Transport-Security policies of the hosts a
+// client talked to. It is safe for concurrent use.
+type HSTSStore struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	entries map[string]HSTSEntry
+}
+
+// NewHSTSStore creates an empty in-memory HSTS store.
+func NewHSTSStore(opts ...func(*HSTSOptions)) *HSTSStore {
+	options := applyOptions(&HSTSOptions{Now: time.Now}, opts...)
+	return &HSTSStore{now: options.Now, entries: map[string]HSTSEntry{}}
+}
+
+// Entries returns the unexpired entries sorted by host, e.g. to persist them.
+func (s *HSTSStore) Entries() []HSTSEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	entries := make([]HSTSEntry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		if now.Before(entry.Expires) {
+			entries = append(entries, entry)
+		}
+	}
+	slices.SortFunc(entries, func(a, b HSTSEntry) int { return strings.Compare(a.Host, b.Host) })
+	return entries
+}
+
+// Load adds previously persisted entries, replacing entries for the same host.
+func (s *HSTSStore) Load(entries []HSTSEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range entries {
+		entry.Host = strings.ToLower(entry.Host)
+		s.entries[entry.Host] = entry
+	}
+}
+
+// Known reports whether requests to host must use HTTPS, either because of
+// an entry for the host itself or for a parent domain with includeSubDomains.
+func (s *HSTSStore) Known(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for domain, exact := host, true; domain != ""; exact = false {
+		if entry, ok := s.entries[domain]; ok && now.Before(entry.Expires) && (exact || entry.IncludeSubdomains) {
+			return true
+		}
+		_, domain, _ = strings.Cut(domain, ".")
+	}
+	return false
+}
+
+// Middleware returns middleware that upgrades http:// requests to known HSTS
+// hosts to https:// and records the Strict-Transport-Security header of
+// responses received over HTTPS. A max-age of 0 removes the host. IP address
+// hosts are ignored, as required by RFC 6797.
+//
+// Example:
+//
+//	hsts := fetch.NewHSTSStore()
+//	hsts.Load(saved)
+//	dispatcher.Use(hsts.Middleware())
+func (s *HSTSStore) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			if req.URL.Scheme == "http" && s.Known(req.URL.Hostname()) {
+				upgraded := *req.URL
+				upgraded.Scheme = "https"
+				if upgraded.Port() == "80" {
+					upgraded.Host = upgraded.Hostname()
+				}
+				req.URL = &upgraded
+			}
+
+			resp, err := next.Handle(client, req)
+			if err != nil {
+				return resp, err
+			}
+
+			final := req.URL
+			if resp.Request != nil {
+				final = resp.Request.URL
+			}
+			if header := resp.Header.Get("Strict-Transport-Security"); header != "" && final.Scheme == "https" {
+				s.record(final.Hostname(), header)
+			}
+			return resp, nil
+		})
+	}
+}
+
+func (s *HSTSStore) record(host, header string) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if net.ParseIP(host) != nil {
+		return
+	}
+
+	maxAge, includeSubdomains, ok := parseSTS(header)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if maxAge == 0 {
+		delete(s.entries, host)
+		return
+	}
+	s.entries[host] = HSTSEntry{
+		Host:              host,
+		Expires:           s.now().Add(time.Duration(maxAge) * time.Second),
+		IncludeSubdomains: includeSubdomains,
+	}
+}
+
+// parseSTS parses a Strict-Transport-Security header value. ok is false when
+// the required max-age directive is missing or invalid.
+func parseSTS(header string) (maxAge int64, includeSubdomains, ok bool) {
+	for _, directive := range strings.Split(header, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "max-age":
+			seconds, err := strconv.ParseInt(strings.Trim(strings.TrimSpace(value), `"`), 10, 64)
+			if err != nil || seconds < 0 {
+				return 0, false, false
+			}
+			maxAge, ok = seconds, true
+		case "includesubdomains":
+			includeSubdomains = true
+		}
+	}
+	return maxAge, includeSubdomains, ok
+}
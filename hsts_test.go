@@ -0,0 +1,94 @@
+package fetch
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type hstsTransport struct {
+	requested []string
+	sts       map[string]string
+}
+
+func (t *hstsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requested = append(t.requested, req.URL.String())
+	header := http.Header{}
+	if sts, ok := t.sts[req.URL.Host]; ok {
+		header.Set("Strict-Transport-Security", sts)
+	}
+	return &http.Response{StatusCode: http.StatusOK, Header: header, Body: http.NoBody, Request: req}, nil
+}
+
+func TestHSTSStore(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := NewHSTSStore(func(o *HSTSOptions) { o.Now = func() time.Time { return now } })
+
+	transport := &hstsTransport{sts: map[string]string{
+		"example.com":    "max-age=3600; includeSubDomains",
+		"plain.test":     "max-age=3600",
+		"insecure.test":  "max-age=3600",
+		"gone.test":      "max-age=0",
+		"127.0.0.1:8443": "max-age=3600",
+		"invalid.test":   "includeSubDomains",
+		"quoted.test":    `max-age="60"`,
+	}}
+	dispatcher := NewDispatcherWithTransport(transport, store.Middleware())
+
+	for _, u := range []string{
+		"https://example.com/",
+		"https://plain.test/",
+		"http://insecure.test/",
+		"https://127.0.0.1:8443/",
+		"https://invalid.test/",
+		"https://quoted.test/",
+	} {
+		resp := dispatcher.NewRequest().Get(u)
+		require.NoError(t, resp.Error)
+		resp.Close()
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "http://example.com/a?b=1", want: "https://example.com/a?b=1"},
+		{url: "http://example.com:80/", want: "https://example.com/"},
+		{url: "http://example.com:8080/", want: "https://example.com:8080/"},
+		{url: "http://api.example.com/", want: "https://api.example.com/"},
+		{url: "http://sub.plain.test/", want: "http://sub.plain.test/"},
+		{url: "http://PLAIN.test/", want: "https://PLAIN.test/"},
+		{url: "http://insecure.test/", want: "http://insecure.test/"},
+		{url: "http://invalid.test/", want: "http://invalid.test/"},
+		{url: "http://127.0.0.1:8443/", want: "http://127.0.0.1:8443/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			transport.requested = nil
+			resp := dispatcher.NewRequest().Get(tt.url)
+			require.NoError(t, resp.Error)
+			resp.Close()
+			assert.Equal(t, []string{tt.want}, transport.requested)
+		})
+	}
+
+	assert.Equal(t, []HSTSEntry{
+		{Host: "example.com", Expires: now.Add(time.Hour), IncludeSubdomains: true},
+		{Host: "plain.test", Expires: now.Add(time.Hour)},
+		{Host: "quoted.test", Expires: now.Add(time.Minute)},
+	}, store.Entries())
+
+	now = now.Add(2 * time.Minute)
+	assert.False(t, store.Known("quoted.test"))
+
+	store.Load([]HSTSEntry{{Host: "Gone.Test", Expires: now.Add(time.Hour)}})
+	assert.True(t, store.Known("gone.test"))
+
+	resp := dispatcher.NewRequest().Get("https://gone.test/")
+	require.NoError(t, resp.Error)
+	assert.False(t, store.Known("gone.test"))
+}
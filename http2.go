@@ -0,0 +1,306 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// HTTP2Settings tunes the HTTP/2 connections to a host.
+type HTTP2Settings struct {
+	// HeaderTableSize caps the HPACK dynamic tables used to compress request
+	// headers and decompress response headers. Larger tables save more bytes
+	// on repetitive headers such as tokens and cookies at the cost of memory
+	// per connection. Zero keeps the default of 4096 bytes. Requires Go 1.24;
+	// older toolchains keep the default.
+	HeaderTableSize int
+	// MaxConcurrentStreams limits the requests in flight to the host; further
+	// requests wait for a slot or their context. A request holds its slot
+	// until its response body is closed. Zero means no limit.
+	MaxConcurrentStreams int
+}
+
+// HTTP2TuningOptions configures an HTTP2Tuning.
+type HTTP2TuningOptions struct {
+	// Default applies to hosts without an entry in Hosts.
+	Default HTTP2Settings
+	// Hosts holds per-host settings by host name without port. Each of them
+	// gets its own connection pool.
+	Hosts map[string]HTTP2Settings
+}
+
+// HTTP2HeaderStats estimates the request header compression of one host.
+type HTTP2HeaderStats struct {
+	// Requests is the number of HTTP/2 requests.
+	Requests uint64 `json:"requests"`
+	// RawBytes is the size of the header names and values sent.
+	RawBytes int64 `json:"raw_bytes"`
+	// EncodedBytes is the estimated HPACK encoded size of the headers.
+	EncodedBytes int64 `json:"encoded_bytes"`
+}
+
+// Saved returns the estimated number of header bytes HPACK saved.
+func (s HTTP2HeaderStats) Saved() int64 {
+	return s.RawBytes - s.EncodedBytes
+}
+
+// HTTP2Tuning applies HTTP/2 header table sizes and stream limits per host
+// and estimates the header bytes saved by HPACK compression, for high-QPS
+// gateways where header overhead and stream limits affect throughput. The
+// estimate replays the headers the client sent against a model of the
+// encoder's dynamic table, ignoring Huffman coding and headers the transport
+// adds itself, such as User-Agent. It is safe for concurrent use.
+type HTTP2Tuning struct {
+	options *HTTP2TuningOptions
+
+	mu    sync.Mutex
+	hosts map[string]*http2Host
+}
+
+type http2Host struct {
+	settings  HTTP2Settings
+	transport http.RoundTripper
+	streams   chan struct{}
+
+	mu    sync.Mutex
+	table hpackTable
+	stats HTTP2HeaderStats
+}
+
+// NewHTTP2Tuning creates an HTTP2Tuning with the given options.
+//
+// Example:
+//
+//	tuning := fetch.NewHTTP2Tuning(func(o *fetch.HTTP2TuningOptions) {
+//	    o.Default = fetch.HTTP2Settings{HeaderTableSize: 16 << 10}
+//	    o.Hosts = map[string]fetch.HTTP2Settings{
+//	        "api.example.com": {HeaderTableSize: 64 << 10, MaxConcurrentStreams: 250},
+//	    }
+//	})
+//	dispatcher.Use(fetch.SetClientOptions(tuning.ClientOption()), fetch.PrepareClientMiddleware())
+func NewHTTP2Tuning(opts ...func(*HTTP2TuningOptions)) *HTTP2Tuning {
+	options := applyOptions(&HTTP2TuningOptions{}, opts...)
+	return &HTTP2Tuning{options: options, hosts: map[string]*http2Host{}}
+}
+
+// ClientOption returns a client option that routes requests through
+// transports tuned per host.
+func (t *HTTP2Tuning) ClientOption() func(*http.Client) {
+	return func(c *http.Client) {
+		var base *http.Transport
+		if tr, ok := c.Transport.(*http.Transport); ok {
+			base = tr
+		} else {
+			base = http.DefaultTransport.(*http.Transport)
+		}
+		c.Transport = &http2TuningTransport{tuning: t, base: base}
+	}
+}
+
+// Stats returns the header compression estimates per host.
+func (t *HTTP2Tuning) Stats() map[string]HTTP2HeaderStats {
+	t.mu.Lock()
+	hosts := make(map[string]*http2Host, len(t.hosts))
+	for name, host := range t.hosts {
+		hosts[name] = host
+	}
+	t.mu.Unlock()
+
+	stats := make(map[string]HTTP2HeaderStats, len(hosts))
+	for name, host := range hosts {
+		host.mu.Lock()
+		stats[name] = host.stats
+		host.mu.Unlock()
+	}
+	return stats
+}
+
+// host returns the state of the host, creating its transport from base on
+// first use.
+func (t *HTTP2Tuning) host(name string, base *http.Transport) *http2Host {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if host, ok := t.hosts[name]; ok {
+		return host
+	}
+
+	settings, ok := t.options.Hosts[name]
+	if !ok {
+		settings = t.options.Default
+	}
+	transport := base.Clone()
+	applyHTTP2Settings(transport, settings)
+
+	host := &http2Host{settings: settings, transport: transport, table: hpackTable{max: 4096}}
+	if settings.HeaderTableSize > 0 {
+		host.table.max = settings.HeaderTableSize
+	}
+	if settings.MaxConcurrentStreams > 0 {
+		host.streams = make(chan struct{}, settings.MaxConcurrentStreams)
+	}
+	t.hosts[name] = host
+	return host
+}
+
+type http2TuningTransport struct {
+	tuning *HTTP2Tuning
+	base   *http.Transport
+}
+
+func (t *http2TuningTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := t.tuning.host(req.URL.Hostname(), t.base)
+
+	release := func() {}
+	if host.streams != nil {
+		if err := acquireStream(req.Context(), host.streams); err != nil {
+			return nil, err
+		}
+		var once sync.Once
+		release = func() { once.Do(func() { <-host.streams }) }
+	}
+
+	resp, err := host.transport.RoundTrip(req)
+	if err != nil {
+		release()
+		return resp, err
+	}
+	if resp.ProtoMajor == 2 {
+		host.record(req)
+	}
+	resp.Body = &cleanupReadCloser{ReadCloser: resp.Body, cleanup: release}
+	return resp, nil
+}
+
+func acquireStream(ctx context.Context, streams chan struct{}) error {
+	select {
+	case streams <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// record adds the estimated header compression of req.
+func (h *http2Host) record(req *http.Request) {
+	path := req.URL.RequestURI()
+	fields := [][2]string{
+		{":method", req.Method},
+		{":scheme", req.URL.Scheme},
+		{":authority", req.URL.Host},
+		{":path", path},
+	}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if hopHeader(name) {
+			continue
+		}
+		for _, value := range values {
+			fields = append(fields, [2]string{name, value})
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.stats.Requests++
+	for _, field := range fields {
+		h.stats.RawBytes += int64(len(field[0]) + len(field[1]))
+		h.stats.EncodedBytes += int64(h.table.encode(field[0], field[1]))
+	}
+}
+
+// hopHeader reports connection-specific headers that HTTP/2 does not send.
+func hopHeader(name string) bool {
+	switch name {
+	case "connection", "keep-alive", "proxy-connection", "transfer-encoding", "upgrade", "host":
+		return true
+	}
+	return false
+}
+
+// hpackTable models the dynamic table of an HPACK encoder that indexes every
+// field, as the Go HTTP/2 transport does.
+type hpackTable struct {
+	max     int
+	size    int
+	entries [][2]string
+}
+
+// encode returns the estimated encoded size of a header field and adds it to
+// the table: one byte for an indexed field, otherwise a literal with the name
+// indexed when possible.
+func (t *hpackTable) encode(name, value string) int {
+	nameIndexed := false
+	for _, entry := range hpackStaticTable {
+		if entry[0] == name {
+			if entry[1] == value {
+				return 1
+			}
+			nameIndexed = true
+		}
+	}
+	for _, entry := range t.entries {
+		if entry[0] == name {
+			if entry[1] == value {
+				return 1
+			}
+			nameIndexed = true
+		}
+	}
+
+	size := 1 + hpackStringSize(value)
+	if !nameIndexed {
+		size += hpackStringSize(name)
+	}
+	t.add(name, value)
+	return size
+}
+
+func (t *hpackTable) add(name, value string) {
+	entrySize := 32 + len(name) + len(value)
+	for len(t.entries) > 0 && t.size+entrySize > t.max {
+		oldest := t.entries[len(t.entries)-1]
+		t.entries = t.entries[:len(t.entries)-1]
+		t.size -= 32 + len(oldest[0]) + len(oldest[1])
+	}
+	if entrySize > t.max {
+		return
+	}
+	t.entries = append([][2]string{{name, value}}, t.entries...)
+	t.size += entrySize
+}
+
+// hpackStringSize returns the size of a string literal with its length prefix.
+func hpackStringSize(s string) int {
+	n := len(s)
+	size := 1
+	if n >= 127 {
+		for n -= 127; n >= 128; n >>= 7 {
+			size++
+		}
+		size++
+	}
+	return size + len(s)
+}
+
+// hpackStaticTable is the static table of RFC 7541, Appendix A.
+var hpackStaticTable = [][2]string{
+	{":authority", ""}, {":method", "GET"}, {":method", "POST"}, {":path", "/"},
+	{":path", "/index.html"}, {":scheme", "http"}, {":scheme", "https"}, {":status", "200"},
+	{":status", "204"}, {":status", "206"}, {":status", "304"}, {":status", "400"},
+	{":status", "404"}, {":status", "500"}, {"accept-charset", ""}, {"accept-encoding", "gzip, deflate"},
+	{"accept-language", ""}, {"accept-ranges", ""}, {"accept", ""}, {"access-control-allow-origin", ""},
+	{"age", ""}, {"allow", ""}, {"authorization", ""}, {"cache-control", ""},
+	{"content-disposition", ""}, {"content-encoding", ""}, {"content-language", ""}, {"content-length", ""},
+	{"content-location", ""}, {"content-range", ""}, {"content-type", ""}, {"cookie", ""},
+	{"date", ""}, {"etag", ""}, {"expect", ""}, {"expires", ""},
+	{"from", ""}, {"host", ""}, {"if-match", ""}, {"if-modified-since", ""},
+	{"if-none-match", ""}, {"if-range", ""}, {"if-unmodified-since", ""}, {"last-modified", ""},
+	{"link", ""}, {"location", ""}, {"max-forwards", ""}, {"proxy-authenticate", ""},
+	{"proxy-authorization", ""}, {"range", ""}, {"referer", ""}, {"refresh", ""},
+	{"retry-after", ""}, {"server", ""}, {"set-cookie", ""}, {"strict-transport-security", ""},
+	{"transfer-encoding", ""}, {"user-agent", ""}, {"vary", ""}, {"via", ""},
+	{"www-authenticate", ""},
+}
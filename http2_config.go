@@ -0,0 +1,19 @@
+//go:build go1.24
+
+package fetch
+
+import "net/http"
+
+// applyHTTP2Settings configures the HTTP/2 header tables of transport.
+func applyHTTP2Settings(transport *http.Transport, settings HTTP2Settings) {
+	if settings.HeaderTableSize <= 0 {
+		return
+	}
+	config := &http.HTTP2Config{}
+	if transport.HTTP2 != nil {
+		*config = *transport.HTTP2
+	}
+	config.MaxEncoderHeaderTableSize = settings.HeaderTableSize
+	config.MaxDecoderHeaderTableSize = settings.HeaderTableSize
+	transport.HTTP2 = config
+}
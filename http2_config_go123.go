@@ -0,0 +1,9 @@
+//go:build !go1.24
+
+package fetch
+
+import "net/http"
+
+// applyHTTP2Settings is a no-op before Go 1.24, whose transport does not
+// expose the HTTP/2 header table sizes.
+func applyHTTP2Settings(transport *http.Transport, settings HTTP2Settings) {}
@@ -0,0 +1,105 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHTTP2Server(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	server := httptest.NewUnstartedServer(handler)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTP2Tuning_HeaderStats(t *testing.T) {
+	server := newHTTP2Server(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})
+
+	tuning := NewHTTP2Tuning(func(o *HTTP2TuningOptions) {
+		o.Default = HTTP2Settings{HeaderTableSize: 16 << 10}
+	})
+	dispatcher := NewDispatcher(server.Client(), SetClientOptions(tuning.ClientOption()), PrepareClientMiddleware())
+
+	token := "Bearer " + strings.Repeat("t", 200)
+	for range 5 {
+		resp := dispatcher.NewRequest().UseFuncs(func(req *http.Request) {
+			req.Header.Set("Authorization", token)
+		}).Get(server.URL + "/items")
+		require.NoError(t, resp.Error)
+		assert.Equal(t, "HTTP/2.0", resp.String())
+	}
+
+	stats := tuning.Stats()
+	require.Len(t, stats, 1)
+	for _, s := range stats {
+		assert.Equal(t, uint64(5), s.Requests)
+		assert.Greater(t, s.RawBytes, int64(5*len(token)))
+		assert.Greater(t, s.Saved(), int64(4*len(token)), "repeated token is indexed")
+	}
+}
+
+func TestHTTP2Tuning_MaxConcurrentStreams(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	server := newHTTP2Server(t, func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	})
+
+	tuning := NewHTTP2Tuning(func(o *HTTP2TuningOptions) {
+		o.Hosts = map[string]HTTP2Settings{"127.0.0.1": {MaxConcurrentStreams: 1}}
+	})
+	dispatcher := NewDispatcher(server.Client(), SetClientOptions(tuning.ClientOption()), PrepareClientMiddleware())
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := dispatcher.NewRequest().Get(server.URL)
+			assert.NoError(t, resp.Error)
+			resp.Close()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), peak.Load())
+}
+
+func TestHPACKTable(t *testing.T) {
+	table := hpackTable{max: 100}
+
+	assert.Equal(t, 1, table.encode(":method", "GET"), "static match")
+	assert.Equal(t, 1+1+len("/a"), table.encode(":path", "/a"), "static name")
+	assert.Equal(t, 1, table.encode(":path", "/a"), "dynamic match")
+	assert.Equal(t, 1+1+len("x-id")+1+len("1"), table.encode("x-id", "1"), "new name")
+	assert.Equal(t, 32+len(":path/a")+32+len("x-id1"), table.size)
+
+	// A third entry evicts the oldest one.
+	table.encode("x-other", "2")
+	assert.Len(t, table.entries, 2)
+	assert.Equal(t, 1+1+len("/a"), table.encode(":path", "/a"))
+
+	// Entries larger than the table are not indexed.
+	long := strings.Repeat("v", 200)
+	table.encode("x-long", long)
+	assert.Empty(t, table.entries)
+	assert.Equal(t, 1+hpackStringSize("x-long")+hpackStringSize(long), table.encode("x-long", long))
+	assert.Equal(t, 202, hpackStringSize(long))
+}
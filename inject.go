@@ -0,0 +1,108 @@
+package fetch
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rockcookies/go-fetch/internal/utils"
+)
+
+var injectedHeadersKey = utils.NewContextKey[map[string]string]("injected_headers")
+
+// InjectedHeader returns the value generated for headerName by RequestID,
+// Timestamp or Nonce earlier in the chain. Signing middleware placed after
+// the injectors can use it to include exactly the values that are sent.
+func InjectedHeader(req *http.Request, headerName string) (string, bool) {
+	values, _ := injectedHeadersKey.GetValue(req.Context())
+	value, ok := values[http.CanonicalHeaderKey(headerName)]
+	return value, ok
+}
+
+// InjectHeader creates middleware that sets headerName to a value produced by
+// generate for every request and records it in the request context.
+// If generate fails, the request is not sent and the error is returned.
+func InjectHeader(headerName string, generate func() (string, error)) Middleware {
+	key := http.CanonicalHeaderKey(headerName)
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			value, err := generate()
+			if err != nil {
+				return nil, fmt.Errorf("generate %s header: %w", key, err)
+			}
+
+			req.Header.Set(key, value)
+
+			existing, _ := injectedHeadersKey.GetValue(req.Context())
+			values := maps.Clone(existing)
+			if values == nil {
+				values = map[string]string{}
+			}
+			values[key] = value
+			req = req.WithContext(injectedHeadersKey.WithValue(req.Context(), values))
+
+			return next.Handle(client, req)
+		})
+	}
+}
+
+// RequestID creates middleware that sets a unique request identifier header.
+// If generator is nil a random UUID (version 4) is used.
+//
+// Example:
+//
+//	dispatcher.Use(fetch.RequestID("X-Request-ID", nil))
+func RequestID(headerName string, generator func() string) Middleware {
+	if generator == nil {
+		return InjectHeader(headerName, newUUID)
+	}
+
+	return InjectHeader(headerName, func() (string, error) {
+		return generator(), nil
+	})
+}
+
+// Timestamp creates middleware that sets headerName to the current time
+// formatted with layout. An empty layout sends Unix seconds.
+func Timestamp(headerName string, layout string) Middleware {
+	return InjectHeader(headerName, func() (string, error) {
+		now := time.Now().UTC()
+		if layout == "" {
+			return strconv.FormatInt(now.Unix(), 10), nil
+		}
+		return now.Format(layout), nil
+	})
+}
+
+// Nonce creates middleware that sets headerName to a random hex string of
+// the given length, generated with crypto/rand. A negative length fails
+// every request.
+func Nonce(headerName string, length int) Middleware {
+	return InjectHeader(headerName, func() (string, error) {
+		if length < 0 {
+			return "", fmt.Errorf("nonce length %d is negative", length)
+		}
+		buf := make([]byte, (length+1)/2)
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(buf)[:length], nil
+	})
+}
+
+func newUUID() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", err
+	}
+
+	u[6] = (u[6] & 0x0f) | 0x40 // version 4
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16]), nil
+}
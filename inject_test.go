@@ -0,0 +1,157 @@
+package fetch
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderInjectors(t *testing.T) {
+	tests := []struct {
+		name       string
+		middleware Middleware
+		header     string
+		validate   func(t *testing.T, value string)
+	}{
+		{
+			name:       "request id with default generator",
+			middleware: RequestID("X-Request-ID", nil),
+			header:     "X-Request-ID",
+			validate: func(t *testing.T, value string) {
+				assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), value)
+			},
+		},
+		{
+			name:       "request id with custom generator",
+			middleware: RequestID("x-correlation-id", func() string { return "abc" }),
+			header:     "X-Correlation-Id",
+			validate: func(t *testing.T, value string) {
+				assert.Equal(t, "abc", value)
+			},
+		},
+		{
+			name:       "timestamp unix seconds",
+			middleware: Timestamp("X-Timestamp", ""),
+			header:     "X-Timestamp",
+			validate: func(t *testing.T, value string) {
+				seconds, err := strconv.ParseInt(value, 10, 64)
+				require.NoError(t, err)
+				assert.InDelta(t, time.Now().Unix(), seconds, 5)
+			},
+		},
+		{
+			name:       "timestamp with layout",
+			middleware: Timestamp("X-Date", time.RFC3339),
+			header:     "X-Date",
+			validate: func(t *testing.T, value string) {
+				_, err := time.Parse(time.RFC3339, value)
+				assert.NoError(t, err)
+			},
+		},
+		{
+			name:       "nonce with odd length",
+			middleware: Nonce("X-Nonce", 15),
+			header:     "X-Nonce",
+			validate: func(t *testing.T, value string) {
+				assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{15}$`), value)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := tt.middleware(HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+				value := req.Header.Get(tt.header)
+				tt.validate(t, value)
+
+				injected, ok := InjectedHeader(req, tt.header)
+				assert.True(t, ok)
+				assert.Equal(t, value, injected)
+				return &http.Response{StatusCode: 200}, nil
+			}))
+
+			req, err := http.NewRequest("GET", "http://example.com", nil)
+			require.NoError(t, err)
+
+			_, err = handler.Handle(&http.Client{}, req)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestNonce_Length(t *testing.T) {
+	tests := []struct {
+		name    string
+		length  int
+		want    string
+		wantErr bool
+	}{
+		{name: "even", length: 8, want: `^[0-9a-f]{8}$`},
+		{name: "zero", length: 0, want: `^$`},
+		{name: "negative", length: -1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value string
+			handler := Nonce("X-Nonce", tt.length)(HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+				value = req.Header.Get("X-Nonce")
+				return &http.Response{StatusCode: 200}, nil
+			}))
+
+			req, err := http.NewRequest("GET", "http://example.com", nil)
+			require.NoError(t, err)
+
+			_, err = handler.Handle(&http.Client{}, req)
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "negative")
+				return
+			}
+			require.NoError(t, err)
+			assert.Regexp(t, regexp.MustCompile(tt.want), value)
+		})
+	}
+}
+
+func TestInjectHeader_Chained(t *testing.T) {
+	chain := compose(RequestID("X-Request-ID", func() string { return "id-1" }), Nonce("X-Nonce", 8))
+
+	handler := chain(HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+		id, ok := InjectedHeader(req, "X-Request-ID")
+		assert.True(t, ok)
+		assert.Equal(t, "id-1", id)
+
+		_, ok = InjectedHeader(req, "X-Nonce")
+		assert.True(t, ok)
+
+		_, ok = InjectedHeader(req, "X-Missing")
+		assert.False(t, ok)
+		return &http.Response{StatusCode: 200}, nil
+	}))
+
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	require.NoError(t, err)
+
+	_, err = handler.Handle(&http.Client{}, req)
+	require.NoError(t, err)
+}
+
+func TestInjectHeader_GeneratorError(t *testing.T) {
+	genErr := errors.New("no entropy")
+	handler := InjectHeader("X-Nonce", func() (string, error) { return "", genErr })(HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+		t.Fatal("next handler must not be called")
+		return nil, nil
+	}))
+
+	req, err := http.NewRequest("GET", "http://example.com", nil)
+	require.NoError(t, err)
+
+	_, err = handler.Handle(&http.Client{}, req)
+	assert.ErrorIs(t, err, genErr)
+}
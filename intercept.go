@@ -0,0 +1,107 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ErrInterceptedNetwork is matched by errors.Is for every InterceptedNetworkError.
+var ErrInterceptedNetwork = errors.New("network intercepted")
+
+// InterceptedNetworkError reports a response that looks like it came from a
+// captive portal or an intercepting proxy rather than the requested server.
+// Evidence lists the observations that led to the verdict.
+type InterceptedNetworkError struct {
+	URL      string
+	Evidence []string
+}
+
+// Error returns the error message.
+func (e *InterceptedNetworkError) Error() string {
+	return fmt.Sprintf("request to %s intercepted by the network: %s", e.URL, strings.Join(e.Evidence, "; "))
+}
+
+// Is reports whether target is ErrInterceptedNetwork.
+func (e *InterceptedNetworkError) Is(target error) bool {
+	return target == ErrInterceptedNetwork
+}
+
+// InterceptOptions configures the DetectInterception middleware.
+type InterceptOptions struct {
+	// TrustedIssuers are the expected issuers of the server certificate,
+	// matched against the issuer common name and organizations. When empty
+	// the issuer is not checked.
+	TrustedIssuers []string
+	// ExpectJSON reports whether a request must not be answered with HTML.
+	// Defaults to requests whose Accept header mentions json.
+	ExpectJSON func(req *http.Request) bool
+}
+
+// DetectInterception creates middleware that fails with an
+// InterceptedNetworkError when a response shows signs of interception:
+// a 511 Network Authentication Required status, an HTML page answering a
+// JSON request, or a certificate from an unexpected issuer. Such responses
+// are closed. A redirect to another host is reported as extra evidence.
+//
+// Example:
+//
+//	resp := dispatcher.NewRequest().Use(fetch.DetectInterception()).Get(url)
+//	if errors.Is(resp.Error, fetch.ErrInterceptedNetwork) {
+//	    fmt.Println("Sign in to the network in your browser and try again.")
+//	}
+func DetectInterception(opts ...func(*InterceptOptions)) Middleware {
+	options := applyOptions(&InterceptOptions{
+		ExpectJSON: func(req *http.Request) bool {
+			return strings.Contains(strings.ToLower(req.Header.Get("Accept")), "json")
+		},
+	}, opts...)
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			resp, err := next.Handle(client, req)
+			if err != nil {
+				return resp, err
+			}
+
+			var evidence []string
+			if resp.StatusCode == http.StatusNetworkAuthenticationRequired {
+				evidence = append(evidence, "status 511 Network Authentication Required")
+			}
+			if options.ExpectJSON != nil && options.ExpectJSON(req) && mediaType(resp.Header.Get("Content-Type")) == "text/html" {
+				evidence = append(evidence, "HTML response to a JSON request")
+			}
+			if issuer, ok := untrustedIssuer(resp, options.TrustedIssuers); !ok {
+				evidence = append(evidence, "certificate issued by unexpected "+issuer)
+			}
+			if len(evidence) == 0 {
+				return resp, nil
+			}
+
+			if resp.Request != nil && resp.Request.URL.Host != req.URL.Host {
+				evidence = append(evidence, "redirected to "+resp.Request.URL.Host)
+			}
+			drainAndClose(resp)
+			return nil, &InterceptedNetworkError{URL: req.URL.Redacted(), Evidence: evidence}
+		})
+	}
+}
+
+// untrustedIssuer returns the issuer of the leaf certificate and whether it
+// is one of the trusted issuers. Responses without TLS are not checked.
+func untrustedIssuer(resp *http.Response, trusted []string) (string, bool) {
+	if len(trusted) == 0 || resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		return "", true
+	}
+
+	issuer := resp.TLS.PeerCertificates[0].Issuer
+	names := append([]string{issuer.CommonName}, issuer.Organization...)
+	for _, name := range names {
+		if name != "" && slices.Contains(trusted, name) {
+			return "", true
+		}
+	}
+	return fmt.Sprintf("%q", issuer.String()), false
+}
@@ -0,0 +1,97 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectInterception(t *testing.T) {
+	portal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<form>Accept the terms</form>"))
+	}))
+	defer portal.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/511":
+			w.WriteHeader(http.StatusNetworkAuthenticationRequired)
+		case "/portal":
+			http.Redirect(w, r, portal.URL+"/login", http.StatusFound)
+		case "/html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		path         string
+		accept       string
+		wantEvidence []string
+	}{
+		{name: "json response", path: "/ok", accept: "application/json"},
+		{name: "html without json expectation", path: "/html", accept: "text/html"},
+		{name: "status 511", path: "/511", wantEvidence: []string{"status 511 Network Authentication Required"}},
+		{name: "html on json endpoint", path: "/html", accept: "application/json", wantEvidence: []string{"HTML response to a JSON request"}},
+		{name: "portal redirect", path: "/portal", accept: "application/json", wantEvidence: []string{"HTML response to a JSON request", "redirected to " + portal.Listener.Addr().String()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := NewDispatcher(nil).NewRequest().
+				UseFuncs(func(r *http.Request) { r.Header.Set("Accept", tt.accept) }).
+				Use(DetectInterception()).
+				Get(server.URL + tt.path)
+			defer resp.Close()
+
+			if tt.wantEvidence == nil {
+				require.NoError(t, resp.Error)
+				return
+			}
+			require.ErrorIs(t, resp.Error, ErrInterceptedNetwork)
+			var interceptErr *InterceptedNetworkError
+			require.ErrorAs(t, resp.Error, &interceptErr)
+			assert.Equal(t, tt.wantEvidence, interceptErr.Evidence)
+		})
+	}
+}
+
+func TestDetectInterception_Issuer(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		trusted []string
+		wantErr bool
+	}{
+		{name: "issuer not checked"},
+		{name: "trusted issuer", trusted: []string{"Acme Co"}},
+		{name: "unexpected issuer", trusted: []string{"Let's Encrypt"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := NewDispatcher(server.Client(), DetectInterception(func(o *InterceptOptions) {
+				o.TrustedIssuers = tt.trusted
+			})).NewRequest().Get(server.URL)
+			defer resp.Close()
+
+			if !tt.wantErr {
+				require.NoError(t, resp.Error)
+				return
+			}
+			require.ErrorIs(t, resp.Error, ErrInterceptedNetwork)
+			assert.Contains(t, resp.Error.Error(), "Acme Co")
+		})
+	}
+}
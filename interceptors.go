@@ -0,0 +1,125 @@
+package fetch
+
+import (
+	"slices"
+	"sync"
+)
+
+// InterceptorRegistry holds named middlewares that dispatchers attached with
+// UseInterceptors apply to all their requests, so platform teams can enforce
+// organization-wide policies such as User-Agent stamping, trace propagation
+// or an SSRF guard from one place. Dispatchers take a snapshot when the
+// registry is attached; later changes do not affect them.
+// It is safe for concurrent use.
+type InterceptorRegistry struct {
+	mu      sync.RWMutex
+	entries []interceptor
+}
+
+type interceptor struct {
+	name       string
+	middleware Middleware
+}
+
+// NewInterceptorRegistry creates an empty InterceptorRegistry.
+func NewInterceptorRegistry() *InterceptorRegistry {
+	return &InterceptorRegistry{}
+}
+
+// Register adds a middleware under name, replacing a middleware registered
+// under the same name in place. Interceptors run in registration order before
+// the middlewares of the dispatcher and its profiles.
+//
+// Example:
+//
+//	platform := fetch.NewInterceptorRegistry()
+//	platform.Register("user-agent", stampUserAgent("acme-platform/1.0"))
+//	platform.Register("ssrf-guard", ssrfGuard())
+func (r *InterceptorRegistry) Register(name string, middleware Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := interceptor{name: name, middleware: middleware}
+	if i := slices.IndexFunc(r.entries, func(e interceptor) bool { return e.name == name }); i >= 0 {
+		r.entries = slices.Clone(r.entries)
+		r.entries[i] = entry
+		return
+	}
+	r.entries = append(slices.Clip(r.entries), entry)
+}
+
+// Unregister removes the middleware registered under name.
+func (r *InterceptorRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = slices.DeleteFunc(slices.Clone(r.entries), func(e interceptor) bool { return e.name == name })
+}
+
+// Names returns the names of the registered middlewares in order.
+func (r *InterceptorRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, len(r.entries))
+	for i, e := range r.entries {
+		names[i] = e.name
+	}
+	return names
+}
+
+// snapshot returns the registered middlewares. The slice is never modified
+// in place, so callers may keep it.
+func (r *InterceptorRegistry) snapshot() []interceptor {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clip(r.entries)
+}
+
+// UseInterceptors attaches a snapshot of the middlewares registered in
+// registry to the dispatcher, replacing the ones attached before.
+// Interceptors run before the profile, dispatcher and request middlewares.
+// This operation is safe for concurrent use.
+//
+// Example:
+//
+//	dispatcher := fetch.NewDispatcher(nil)
+//	dispatcher.UseInterceptors(platform)
+func (d *Dispatcher) UseInterceptors(registry *InterceptorRegistry) {
+	entries := registry.snapshot()
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.interceptors = entries
+}
+
+// DisableInterceptors opts the dispatcher out of the named interceptors it
+// took from its registry, or out of all of them when no names are given.
+// This operation is safe for concurrent use.
+//
+// Example:
+//
+//	internal := fetch.NewDispatcher(nil)
+//	internal.UseInterceptors(platform)
+//	internal.DisableInterceptors("ssrf-guard") // talks to cluster-local services
+func (d *Dispatcher) DisableInterceptors(names ...string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if len(names) == 0 {
+		d.interceptors = nil
+		return
+	}
+	d.interceptors = slices.DeleteFunc(slices.Clone(d.interceptors), func(e interceptor) bool {
+		return slices.Contains(names, e.name)
+	})
+}
+
+func interceptorMiddlewares(entries []interceptor) []Middleware {
+	middlewares := make([]Middleware, len(entries))
+	for i, e := range entries {
+		middlewares[i] = e.middleware
+	}
+	return middlewares
+}
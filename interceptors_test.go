@@ -0,0 +1,115 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// appendHeader returns middleware appending value to the X-Chain header.
+func appendHeader(value string) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			req.Header.Add("X-Chain", value)
+			return next.Handle(client, req)
+		})
+	}
+}
+
+func TestInterceptorRegistry(t *testing.T) {
+	registry := NewInterceptorRegistry()
+	registry.Register("a", appendHeader("a"))
+	registry.Register("b", appendHeader("b"))
+	registry.Register("a", appendHeader("a2"))
+	assert.Equal(t, []string{"a", "b"}, registry.Names())
+
+	snapshot := registry.snapshot()
+	registry.Register("c", appendHeader("c"))
+	registry.Unregister("a")
+	assert.Equal(t, []string{"b", "c"}, registry.Names())
+	assert.Len(t, snapshot, 2, "snapshots are not modified")
+	assert.Equal(t, "a", snapshot[0].name)
+}
+
+func TestDispatcher_Interceptors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Join(r.Header.Values("X-Chain"), ",")))
+	}))
+	defer server.Close()
+
+	registry := NewInterceptorRegistry()
+	registry.Register("ua", appendHeader("ua"))
+	registry.Register("guard", appendHeader("guard"))
+	attached := NewDispatcher(nil, appendHeader("dispatcher"))
+	attached.UseInterceptors(registry)
+
+	tests := []struct {
+		name       string
+		dispatcher func() *Dispatcher
+		want       string
+	}{
+		{
+			name:       "not attached",
+			dispatcher: func() *Dispatcher { return NewDispatcher(nil, appendHeader("dispatcher")) },
+			want:       "dispatcher,request",
+		},
+		{
+			name:       "interceptors run first",
+			dispatcher: func() *Dispatcher { return attached },
+			want:       "ua,guard,dispatcher,request",
+		},
+		{
+			name: "with transport and profile",
+			dispatcher: func() *Dispatcher {
+				d := NewDispatcherWithTransport(http.DefaultTransport, appendHeader("dispatcher"))
+				d.UseInterceptors(registry)
+				d.RegisterProfile("p", &Profile{Middlewares: []Middleware{appendHeader("profile")}})
+				require.NoError(t, d.ActivateProfile("p"))
+				return d
+			},
+			want: "ua,guard,profile,dispatcher,request",
+		},
+		{
+			name: "opt out of one",
+			dispatcher: func() *Dispatcher {
+				d := NewDispatcher(nil, appendHeader("dispatcher"))
+				d.UseInterceptors(registry)
+				d.DisableInterceptors("guard")
+				return d
+			},
+			want: "ua,dispatcher,request",
+		},
+		{
+			name: "opt out of all",
+			dispatcher: func() *Dispatcher {
+				d := NewDispatcher(nil, appendHeader("dispatcher"))
+				d.UseInterceptors(registry)
+				d.DisableInterceptors()
+				return d
+			},
+			want: "dispatcher,request",
+		},
+		{
+			name: "clone keeps opt out",
+			dispatcher: func() *Dispatcher {
+				d := NewDispatcher(nil, appendHeader("dispatcher"))
+				d.UseInterceptors(registry)
+				d.DisableInterceptors("ua")
+				return d.Clone()
+			},
+			want: "guard,dispatcher,request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := tt.dispatcher().NewRequest().Use(appendHeader("request")).Get(server.URL)
+			require.NoError(t, resp.Error)
+			assert.Equal(t, tt.want, resp.String())
+		})
+	}
+}
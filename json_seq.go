@@ -0,0 +1,238 @@
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// recordSeparator starts every record of an application/json-seq body.
+const recordSeparator = 0x1e
+
+// ErrMalformedJSONSeq is matched by errors.Is for every JSONSeqError.
+var ErrMalformedJSONSeq = errors.New("malformed JSON sequence")
+
+// JSONSeqError reports a malformed record of a JSON sequence in strict mode.
+type JSONSeqError struct {
+	// Index is the position of the record in the sequence, starting at 0.
+	Index  int
+	Reason string
+}
+
+// Error returns the error message.
+func (e *JSONSeqError) Error() string {
+	return fmt.Sprintf("malformed JSON sequence record %d: %s", e.Index, e.Reason)
+}
+
+// Is reports whether target is ErrMalformedJSONSeq.
+func (e *JSONSeqError) Is(target error) bool {
+	return target == ErrMalformedJSONSeq
+}
+
+// JSONSeqOptions configures Response.JSONSeq.
+type JSONSeqOptions struct {
+	// Strict fails with a JSONSeqError on malformed records: data before the
+	// first record separator, records that are not valid JSON and
+	// application/json-seq records not ending with a line feed, which may
+	// have been truncated. By default such records are skipped, as RFC 7464
+	// recommends.
+	Strict bool
+}
+
+// JSONSeq returns an iterator over the records of a streamed JSON response.
+// application/json-seq bodies (RFC 7464) are split at the record separator;
+// other bodies, such as application/stream+json and NDJSON, are split at
+// line feeds. Records are yielded as they arrive, so long-lived streams of
+// incremental updates can be processed without buffering the body. Blank
+// records are skipped.
+//
+// Iteration stops at the end of the body, after the first error, or when the
+// caller stops. Canceling ctx interrupts a blocked read and yields the
+// context error. The response is closed when iteration stops.
+//
+// Example:
+//
+//	for record, err := range resp.JSONSeq(ctx) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    var patch []PatchOp
+//	    if err := json.Unmarshal(record, &patch); err != nil {
+//	        return err
+//	    }
+//	    apply(patch)
+//	}
+func (r *Response) JSONSeq(ctx context.Context, opts ...func(*JSONSeqOptions)) iter.Seq2[json.RawMessage, error] {
+	options := applyOptions(&JSONSeqOptions{}, opts...)
+
+	return func(yield func(json.RawMessage, error) bool) {
+		if r.Error != nil {
+			yield(nil, r.Error)
+			return
+		}
+		// The body is closed without draining it, since streams may not end.
+		// Closing it also unblocks a read waiting for the next record.
+		body := r.RawResponse.Body
+		defer body.Close()
+		stop := context.AfterFunc(ctx, func() { body.Close() })
+		defer stop()
+
+		reader := bufio.NewReader(r.getInternalReader())
+		if mediaType(r.Header.Get("Content-Type")) == "application/json-seq" {
+			readJSONSeq(ctx, reader, options.Strict, yield)
+		} else {
+			readJSONLines(ctx, reader, options.Strict, yield)
+		}
+	}
+}
+
+// readJSONSeq yields the records of an RFC 7464 sequence. The body is read
+// line by line so a record is yielded as soon as it is complete JSON followed
+// by a line feed, without waiting for the separator of the next record.
+func readJSONSeq(ctx context.Context, reader *bufio.Reader, strict bool, yield func(json.RawMessage, error) bool) {
+	var (
+		record   []byte
+		started  bool // a separator was read
+		complete bool // record was yielded; only whitespace may follow
+		index    int
+	)
+
+	// malformed skips a record or, in strict mode, stops with an error.
+	malformed := func(reason string) bool {
+		if strict {
+			yield(nil, &JSONSeqError{Index: index, Reason: reason})
+			return false
+		}
+		return true
+	}
+
+	// finish handles the end of the current record at a separator or EOF.
+	finish := func() bool {
+		if !started || complete {
+			return true
+		}
+		if len(bytes.TrimSpace(record)) == 0 {
+			return true
+		}
+		defer func() { index++ }()
+		if !json.Valid(record) {
+			return malformed("invalid JSON")
+		}
+		return malformed("missing line feed, record may be truncated")
+	}
+
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			yield(nil, ctxErr)
+			return
+		}
+		if readErr != nil && readErr != io.EOF {
+			yield(nil, readErr)
+			return
+		}
+
+		parts := bytes.Split(line, []byte{recordSeparator})
+		for i, part := range parts {
+			if i > 0 {
+				if !finish() {
+					return
+				}
+				record, started, complete = nil, true, false
+			}
+
+			switch {
+			case len(bytes.TrimSpace(part)) == 0:
+				record = append(record, part...)
+			case !started:
+				if !malformed("data before the first record separator") {
+					return
+				}
+			case complete:
+				if !malformed("data after the record") {
+					return
+				}
+			default:
+				record = append(record, part...)
+			}
+		}
+
+		if started && !complete && bytes.HasSuffix(record, []byte{'\n'}) {
+			if trimmed := bytes.TrimSpace(record); len(trimmed) > 0 && json.Valid(trimmed) {
+				complete = true
+				index++
+				if !yield(json.RawMessage(trimmed), nil) {
+					return
+				}
+			}
+		}
+
+		if readErr == io.EOF {
+			finish()
+			return
+		}
+	}
+}
+
+// readJSONLines yields the records of a newline delimited JSON body.
+func readJSONLines(ctx context.Context, reader *bufio.Reader, strict bool, yield func(json.RawMessage, error) bool) {
+	for index := 0; ; {
+		line, readErr := reader.ReadBytes('\n')
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			yield(nil, ctxErr)
+			return
+		}
+		if readErr != nil && readErr != io.EOF {
+			yield(nil, readErr)
+			return
+		}
+
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			switch {
+			case json.Valid(trimmed):
+				if !yield(json.RawMessage(trimmed), nil) {
+					return
+				}
+			case strict:
+				yield(nil, &JSONSeqError{Index: index, Reason: "invalid JSON"})
+				return
+			}
+			index++
+		}
+
+		if readErr == io.EOF {
+			return
+		}
+	}
+}
+
+// DecodeJSONSeq returns an iterator decoding the records of a streamed JSON
+// response into values of type T. See Response.JSONSeq. A record that does
+// not decode into T is yielded as an error and ends the iteration.
+//
+// Example:
+//
+//	for event, err := range fetch.DecodeJSONSeq[Event](ctx, resp) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    handle(event)
+//	}
+func DecodeJSONSeq[T any](ctx context.Context, r *Response, opts ...func(*JSONSeqOptions)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for record, err := range r.JSONSeq(ctx, opts...) {
+			var value T
+			if err == nil {
+				err = json.Unmarshal(record, &value)
+			}
+			if !yield(value, err) || err != nil {
+				return
+			}
+		}
+	}
+}
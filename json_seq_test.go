@@ -0,0 +1,168 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func collectJSONSeq(t *testing.T, resp *Response, opts ...func(*JSONSeqOptions)) ([]string, error) {
+	t.Helper()
+	var records []string
+	for record, err := range resp.JSONSeq(context.Background(), opts...) {
+		if err != nil {
+			return records, err
+		}
+		records = append(records, string(record))
+	}
+	return records, nil
+}
+
+func TestResponse_JSONSeq(t *testing.T) {
+	strict := func(o *JSONSeqOptions) { o.Strict = true }
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		opts        []func(*JSONSeqOptions)
+		want        []string
+		wantErr     string
+	}{
+		{
+			name:        "json-seq",
+			contentType: "application/json-seq",
+			body:        "\x1e{\"op\":\"add\"}\n\x1e[1,2]\n\x1e\"text\"\n\x1e42\n",
+			want:        []string{`{"op":"add"}`, `[1,2]`, `"text"`, `42`},
+		},
+		{
+			name:        "json-seq skips malformed records",
+			contentType: "application/json-seq",
+			body:        "junk\x1e{\"a\":1}\n\x1e\x1e{broken\n\x1e{\"b\":2}\n\x1e12",
+			want:        []string{`{"a":1}`, `{"b":2}`},
+		},
+		{
+			name:        "strict preamble",
+			contentType: "application/json-seq",
+			body:        "junk\x1e{\"a\":1}\n",
+			opts:        []func(*JSONSeqOptions){strict},
+			wantErr:     "record 0: data before the first record separator",
+		},
+		{
+			name:        "strict invalid record",
+			contentType: "application/json-seq",
+			body:        "\x1e{\"a\":1}\n\x1e{broken\n",
+			opts:        []func(*JSONSeqOptions){strict},
+			want:        []string{`{"a":1}`},
+			wantErr:     "record 1: invalid JSON",
+		},
+		{
+			name:        "strict truncated record",
+			contentType: "application/json-seq",
+			body:        "\x1e{\"a\":1}\n\x1e12",
+			opts:        []func(*JSONSeqOptions){strict},
+			want:        []string{`{"a":1}`},
+			wantErr:     "record 1: missing line feed",
+		},
+		{
+			name:        "stream+json",
+			contentType: "application/stream+json",
+			body:        "{\"a\":1}\r\n\n{\"b\":2}\n{\"c\":3}",
+			want:        []string{`{"a":1}`, `{"b":2}`, `{"c":3}`},
+		},
+		{
+			name:        "stream+json strict",
+			contentType: "application/stream+json; charset=utf-8",
+			body:        "{\"a\":1}\nnope\n",
+			opts:        []func(*JSONSeqOptions){strict},
+			want:        []string{`{"a":1}`},
+			wantErr:     "record 1: invalid JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				io.WriteString(w, tt.body)
+			}))
+			defer server.Close()
+
+			resp := NewDispatcher(nil).NewRequest().Get(server.URL)
+			records, err := collectJSONSeq(t, resp, tt.opts...)
+			assert.Equal(t, tt.want, records)
+			if tt.wantErr != "" {
+				assert.ErrorIs(t, err, ErrMalformedJSONSeq)
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestResponse_JSONSeq_Streaming(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json-seq")
+		io.WriteString(w, "\x1e{\"n\":1}\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resp := NewDispatcher(nil).NewRequest().Get(server.URL)
+	var got []int
+	var iterErr error
+	for event, err := range DecodeJSONSeq[struct{ N int }](ctx, resp) {
+		if err != nil {
+			iterErr = err
+			break
+		}
+		got = append(got, event.N)
+		// The first record arrives before the body is complete.
+		time.AfterFunc(10*time.Millisecond, cancel)
+	}
+	assert.Equal(t, []int{1}, got)
+	assert.ErrorIs(t, iterErr, context.Canceled)
+}
+
+func TestDecodeJSONSeq(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		io.WriteString(w, "{\"id\":1}\n{\"id\":\"two\"}\n{\"id\":3}\n")
+	}))
+	defer server.Close()
+
+	var ids []int
+	var decodeErr error
+	for item, err := range DecodeJSONSeq[struct{ ID int }](context.Background(), NewDispatcher(nil).NewRequest().Get(server.URL)) {
+		if err != nil {
+			decodeErr = err
+			break
+		}
+		ids = append(ids, item.ID)
+	}
+	assert.Equal(t, []int{1}, ids)
+	var typeErr *json.UnmarshalTypeError
+	assert.ErrorAs(t, decodeErr, &typeErr)
+
+	resp := &Response{Error: io.ErrUnexpectedEOF}
+	for _, err := range resp.JSONSeq(context.Background()) {
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	}
+}
@@ -0,0 +1,140 @@
+package fetch
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Language is a language tag with an optional quality value used to build
+// the Accept-Language header. A zero Q is treated as 1 and omitted.
+type Language struct {
+	Tag string
+	Q   float64
+}
+
+// SetLanguage creates middleware that sets the Accept-Language header to a single tag.
+func SetLanguage(tag string) Middleware {
+	return SetLanguages(Language{Tag: tag})
+}
+
+// SetLanguages creates middleware that sets the Accept-Language header from
+// the given languages, in order, with their q-values.
+//
+// Example:
+//
+//	req.Use(fetch.SetLanguages(
+//	    fetch.Language{Tag: "fr-CH"},
+//	    fetch.Language{Tag: "fr", Q: 0.9},
+//	    fetch.Language{Tag: "en", Q: 0.8},
+//	))
+//	// Accept-Language: fr-CH, fr;q=0.9, en;q=0.8
+func SetLanguages(languages ...Language) Middleware {
+	value := formatAcceptLanguage(languages)
+	if value == "" {
+		return skip
+	}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			req.Header.Set("Accept-Language", value)
+			return next.Handle(client, req)
+		})
+	}
+}
+
+func formatAcceptLanguage(languages []Language) string {
+	parts := make([]string, 0, len(languages))
+	for _, lang := range languages {
+		if lang.Tag == "" {
+			continue
+		}
+
+		if lang.Q <= 0 || lang.Q >= 1 {
+			parts = append(parts, lang.Tag)
+			continue
+		}
+
+		parts = append(parts, lang.Tag+";q="+strconv.FormatFloat(lang.Q, 'f', -1, 64))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// parseLanguageList splits a comma separated language header into tags,
+// dropping any parameters such as q-values.
+func parseLanguageList(value string) []string {
+	var tags []string
+	for _, part := range strings.Split(value, ",") {
+		tag, _, _ := strings.Cut(part, ";")
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+// Language returns the first language tag of the Content-Language response header.
+// Returns an empty string if the header is absent or the response has an error.
+func (r *Response) Language() string {
+	if r.Error != nil {
+		return ""
+	}
+
+	tags := parseLanguageList(r.Header.Get("Content-Language"))
+	if len(tags) == 0 {
+		return ""
+	}
+
+	return tags[0]
+}
+
+// LocalizedMessage selects a message from a decoded error payload keyed by
+// language tag. Candidates are tried in order: the response Content-Language,
+// then the languages of the request Accept-Language header. A candidate
+// matches exactly (case-insensitive) or by its primary subtag, so "fr-CH"
+// matches "fr". Returns an empty string if nothing matches.
+//
+// Example:
+//
+//	var payload struct {
+//	    Messages map[string]string `json:"messages"`
+//	}
+//	if err := resp.JSON(&payload); err == nil {
+//	    msg := resp.LocalizedMessage(payload.Messages)
+//	}
+func (r *Response) LocalizedMessage(messages map[string]string) string {
+	if len(messages) == 0 {
+		return ""
+	}
+
+	candidates := parseLanguageList(r.Header.Get("Content-Language"))
+	if r.RawRequest != nil {
+		candidates = append(candidates, parseLanguageList(r.RawRequest.Header.Get("Accept-Language"))...)
+	}
+
+	return selectLocalized(messages, candidates)
+}
+
+func selectLocalized(messages map[string]string, candidates []string) string {
+	for _, candidate := range candidates {
+		if candidate == "*" {
+			continue
+		}
+
+		primary, _, _ := strings.Cut(candidate, "-")
+		for tag, message := range messages {
+			if strings.EqualFold(tag, candidate) {
+				return message
+			}
+		}
+		for tag, message := range messages {
+			if strings.EqualFold(tag, primary) {
+				return message
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,170 @@
+package fetch
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetLanguages(t *testing.T) {
+	tests := []struct {
+		name      string
+		languages []Language
+		expected  string
+	}{
+		{
+			name:      "single language",
+			languages: []Language{{Tag: "en"}},
+			expected:  "en",
+		},
+		{
+			name: "multiple languages with q-values",
+			languages: []Language{
+				{Tag: "fr-CH"},
+				{Tag: "fr", Q: 0.9},
+				{Tag: "en", Q: 0.75},
+			},
+			expected: "fr-CH, fr;q=0.9, en;q=0.75",
+		},
+		{
+			name:      "empty tags are ignored",
+			languages: []Language{{Tag: ""}, {Tag: "de", Q: 1}},
+			expected:  "de",
+		},
+		{
+			name:      "no languages leaves header unset",
+			languages: nil,
+			expected:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := SetLanguages(tt.languages...)(HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+				assert.Equal(t, tt.expected, req.Header.Get("Accept-Language"))
+				return &http.Response{StatusCode: 200}, nil
+			}))
+
+			req, err := http.NewRequest("GET", "http://example.com", nil)
+			require.NoError(t, err)
+
+			_, err = handler.Handle(&http.Client{}, req)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSetLanguage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "ja", r.Header.Get("Accept-Language"))
+		w.Header().Set("Content-Language", "ja, en")
+	}))
+	defer server.Close()
+
+	resp := NewDispatcher(nil).NewRequest().Use(SetLanguage("ja")).Get(server.URL)
+	defer resp.Close()
+
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "ja", resp.Language())
+}
+
+func TestResponse_Language(t *testing.T) {
+	tests := []struct {
+		name     string
+		resp     *Response
+		expected string
+	}{
+		{
+			name:     "no header",
+			resp:     &Response{Header: http.Header{}},
+			expected: "",
+		},
+		{
+			name:     "single tag",
+			resp:     &Response{Header: http.Header{"Content-Language": {"de-DE"}}},
+			expected: "de-DE",
+		},
+		{
+			name:     "multiple tags returns first",
+			resp:     &Response{Header: http.Header{"Content-Language": {" mi, en"}}},
+			expected: "mi",
+		},
+		{
+			name:     "response with error",
+			resp:     &Response{Error: errors.New("boom"), Header: http.Header{"Content-Language": {"en"}}},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.resp.Language())
+		})
+	}
+}
+
+func TestResponse_LocalizedMessage(t *testing.T) {
+	messages := map[string]string{
+		"en": "not found",
+		"fr": "introuvable",
+		"de": "nicht gefunden",
+	}
+
+	tests := []struct {
+		name            string
+		contentLanguage string
+		acceptLanguage  string
+		messages        map[string]string
+		expected        string
+	}{
+		{
+			name:            "content language wins",
+			contentLanguage: "de",
+			acceptLanguage:  "fr",
+			messages:        messages,
+			expected:        "nicht gefunden",
+		},
+		{
+			name:           "falls back to accept language",
+			acceptLanguage: "es;q=0.9, fr;q=0.8",
+			messages:       messages,
+			expected:       "introuvable",
+		},
+		{
+			name:           "matches primary subtag",
+			acceptLanguage: "en-GB",
+			messages:       messages,
+			expected:       "not found",
+		},
+		{
+			name:           "no match",
+			acceptLanguage: "ja",
+			messages:       messages,
+			expected:       "",
+		},
+		{
+			name:     "no messages",
+			messages: nil,
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &http.Request{Header: http.Header{}}
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			resp := &Response{Header: http.Header{}, RawRequest: req}
+			if tt.contentLanguage != "" {
+				resp.Header.Set("Content-Language", tt.contentLanguage)
+			}
+
+			assert.Equal(t, tt.expected, resp.LocalizedMessage(tt.messages))
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrRequestTooLarge is matched by errors.Is for every RequestLimitError.
+var ErrRequestTooLarge = errors.New("request too large")
+
+// RequestLimitError reports an outgoing request that exceeds a size ceiling.
+// Part is "url", "header" or "body".
+type RequestLimitError struct {
+	Part   string
+	Limit  int64
+	Actual int64
+}
+
+// Error returns the error message.
+func (e *RequestLimitError) Error() string {
+	return fmt.Sprintf("request %s too large: %d bytes exceeds limit of %d", e.Part, e.Actual, e.Limit)
+}
+
+// Is reports whether target is ErrRequestTooLarge.
+func (e *RequestLimitError) Is(target error) bool {
+	return target == ErrRequestTooLarge
+}
+
+// RequestLimitOptions configures the RequestLimits middleware.
+// A zero or negative limit disables that check.
+type RequestLimitOptions struct {
+	// MaxURLLength is the maximum length of the URL. Defaults to 8192,
+	// a common server limit beyond which 414 responses are likely.
+	MaxURLLength int
+	// MaxHeaderBytes is the maximum size of the header lines as written on
+	// the wire. Defaults to 64 KiB, beyond which 431 responses are likely.
+	MaxHeaderBytes int
+	// MaxBodyBytes is the maximum size of the body. Defaults to 0 (no limit).
+	MaxBodyBytes int64
+}
+
+// RequestLimits creates middleware that rejects oversized outgoing requests
+// with a RequestLimitError before they are sent. Bodies with a known
+// Content-Length are checked up front; streamed bodies fail while being read
+// once they exceed the limit.
+//
+// The request is inspected when it reaches the middleware, so place it after
+// middlewares that set headers and the body.
+//
+// Example:
+//
+//	dispatcher.NewRequest().JSON(payload).Use(fetch.RequestLimits(func(o *fetch.RequestLimitOptions) {
+//	    o.MaxBodyBytes = 10 << 20
+//	})).Post(url)
+func RequestLimits(opts ...func(*RequestLimitOptions)) Middleware {
+	options := applyOptions(&RequestLimitOptions{
+		MaxURLLength:   8192,
+		MaxHeaderBytes: 64 << 10,
+	}, opts...)
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			if limit := options.MaxURLLength; limit > 0 {
+				if n := len(req.URL.String()); n > limit {
+					return nil, &RequestLimitError{Part: "url", Limit: int64(limit), Actual: int64(n)}
+				}
+			}
+
+			if limit := options.MaxHeaderBytes; limit > 0 {
+				if n := headerWireSize(req.Header); n > limit {
+					return nil, &RequestLimitError{Part: "header", Limit: int64(limit), Actual: int64(n)}
+				}
+			}
+
+			if limit := options.MaxBodyBytes; limit > 0 {
+				if req.ContentLength > limit {
+					return nil, &RequestLimitError{Part: "body", Limit: limit, Actual: req.ContentLength}
+				}
+				if req.Body != nil && req.Body != http.NoBody {
+					req.Body = &limitedBody{ReadCloser: req.Body, limit: limit}
+				}
+				if getBody := req.GetBody; getBody != nil {
+					req.GetBody = func() (io.ReadCloser, error) {
+						body, err := getBody()
+						if err != nil {
+							return nil, err
+						}
+						return &limitedBody{ReadCloser: body, limit: limit}, nil
+					}
+				}
+			}
+
+			return next.Handle(client, req)
+		})
+	}
+}
+
+// headerWireSize returns the size of the header lines "Name: value\r\n".
+func headerWireSize(header http.Header) int {
+	size := 0
+	for name, values := range header {
+		for _, value := range values {
+			size += len(name) + len(value) + 4
+		}
+	}
+	return size
+}
+
+// limitedBody fails with a RequestLimitError once more than limit bytes are read.
+type limitedBody struct {
+	io.ReadCloser
+	limit int64
+	read  int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if b.read > b.limit {
+		return 0, &RequestLimitError{Part: "body", Limit: b.limit, Actual: b.read}
+	}
+	return n, err
+}
@@ -0,0 +1,75 @@
+package fetch
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLimits(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		io.Copy(io.Discard, r.Body)
+	}))
+	defer server.Close()
+
+	limits := RequestLimits(func(o *RequestLimitOptions) {
+		o.MaxURLLength = 100
+		o.MaxHeaderBytes = 200
+		o.MaxBodyBytes = 10
+	})
+
+	tests := []struct {
+		name      string
+		path      string
+		header    string
+		body      io.Reader
+		wantPart  string
+		wantCalls int32
+	}{
+		{name: "within limits", path: "/ok", body: strings.NewReader("0123456789"), wantCalls: 1},
+		{name: "url too long", path: "/" + strings.Repeat("u", 100), wantPart: "url"},
+		{name: "header too large", path: "/ok", header: strings.Repeat("h", 200), wantPart: "header"},
+		{name: "known body too large", path: "/ok", body: strings.NewReader("01234567890"), wantPart: "body"},
+		// The request may already be on the wire when the streamed body exceeds the limit.
+		{name: "streamed body too large", path: "/ok", body: io.MultiReader(strings.NewReader(strings.Repeat("b", 100))), wantPart: "body", wantCalls: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls.Store(0)
+
+			req := NewDispatcher(nil).NewRequest().UseFuncs(func(r *http.Request) {
+				if tt.header != "" {
+					r.Header.Set("X-Large", tt.header)
+				}
+			})
+			if tt.body != nil {
+				req = req.Body(tt.body, func(o *BodyOptions) { o.AutoSetContentLength = true })
+			}
+			resp := req.Use(limits).Post(server.URL + tt.path)
+			defer resp.Close()
+
+			if tt.wantPart == "" {
+				require.NoError(t, resp.Error)
+				assert.Equal(t, tt.wantCalls, calls.Load())
+				return
+			}
+
+			require.ErrorIs(t, resp.Error, ErrRequestTooLarge)
+			var limitErr *RequestLimitError
+			require.ErrorAs(t, resp.Error, &limitErr)
+			assert.Equal(t, tt.wantPart, limitErr.Part)
+			if tt.wantCalls >= 0 {
+				assert.Equal(t, tt.wantCalls, calls.Load())
+			}
+		})
+	}
+}
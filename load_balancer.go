@@ -0,0 +1,314 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrNoUpstream is returned by the LoadBalancer middleware when its
+// HostProvider has no upstreams.
+var ErrNoUpstream = errors.New("no upstream available")
+
+// Upstream is a backend of a LoadBalancer.
+type Upstream struct {
+	// URL is the base URL of the backend; its scheme and host replace those
+	// of the request.
+	URL string
+	// Weight is the share of requests relative to the other upstreams.
+	// Values below 1 count as 1.
+	Weight int
+}
+
+// HostProvider supplies the upstreams of a LoadBalancer. It is called for
+// every request, so implementations fed by service discovery can change the
+// set at any time; they should return a cached snapshot.
+type HostProvider interface {
+	Upstreams() []Upstream
+}
+
+// HostProviderFunc is an adapter to allow ordinary functions to be used as HostProvider.
+type HostProviderFunc func() []Upstream
+
+// Upstreams calls f().
+func (f HostProviderFunc) Upstreams() []Upstream {
+	return f()
+}
+
+// StaticHosts returns a HostProvider with a fixed set of upstreams.
+func StaticHosts(upstreams ...Upstream) HostProvider {
+	return HostProviderFunc(func() []Upstream { return upstreams })
+}
+
+// LoadBalancerOptions configures a LoadBalancer.
+type LoadBalancerOptions struct {
+	// FailureThreshold is the number of consecutive failures after which an
+	// upstream is taken out of rotation. Defaults to 3.
+	FailureThreshold int
+	// Cooldown is how long an unhealthy upstream stays out of rotation
+	// before it receives a request again. Defaults to 10s.
+	Cooldown time.Duration
+	// MaxAttempts is the number of distinct upstreams a request is sent to
+	// before its failure is returned. The request body is buffered for
+	// replays. Defaults to 1.
+	MaxAttempts int
+	// IsFailure decides whether an outcome counts against the upstream.
+	// Defaults to network errors and 5xx responses; canceled requests and
+	// expired deadlines do not count.
+	IsFailure func(resp *http.Response, err error) bool
+	// HealthChecker, when set, replaces the built-in failure tracking:
+	// upstreams it reports unhealthy are skipped, every attempt is reported
+	// to it, and FailureThreshold and Cooldown are unused.
+	HealthChecker *HealthChecker
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// LoadBalancer distributes requests across upstream hosts by smooth weighted
+// round-robin, skipping upstreams that failed repeatedly until their cooldown
+// has passed. When every upstream is unhealthy, requests are spread over all
+// of them rather than failing. It is safe for concurrent use.
+type LoadBalancer struct {
+	provider HostProvider
+	options  *LoadBalancerOptions
+
+	mu     sync.Mutex
+	states map[string]*upstreamState
+}
+
+type upstreamState struct {
+	url       *url.URL
+	current   int
+	failures  int
+	downUntil time.Time
+}
+
+// NewLoadBalancer creates a LoadBalancer over the upstreams of provider.
+//
+// Example:
+//
+//	lb := fetch.NewLoadBalancer(fetch.StaticHosts(
+//	    fetch.Upstream{URL: "https://api-1.internal", Weight: 3},
+//	    fetch.Upstream{URL: "https://api-2.internal", Weight: 1},
+//	), func(o *fetch.LoadBalancerOptions) {
+//	    o.MaxAttempts = 2
+//	})
+//	dispatcher.Use(lb.Middleware())
+//	dispatcher.NewRequest().Get("/v1/items")
+func NewLoadBalancer(provider HostProvider, opts ...func(*LoadBalancerOptions)) *LoadBalancer {
+	return &LoadBalancer{
+		provider: provider,
+		options: applyOptions(&LoadBalancerOptions{
+			FailureThreshold: 3,
+			Cooldown:         10 * time.Second,
+			MaxAttempts:      1,
+			IsFailure:        defaultLoadBalancerFailure,
+			Now:              time.Now,
+		}, opts...),
+		states: map[string]*upstreamState{},
+	}
+}
+
+func defaultLoadBalancerFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode >= 500
+}
+
+// Healthy reports for every known upstream URL whether it is in rotation.
+func (lb *LoadBalancer) Healthy() map[string]bool {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	now := lb.options.Now()
+	healthy := make(map[string]bool, len(lb.states))
+	for key, state := range lb.states {
+		healthy[key] = lb.healthyLocked(state, now)
+	}
+	return healthy
+}
+
+// Middleware returns middleware sending each request to the next upstream.
+// It wraps the client's transport, so it sees the final request body; failed
+// attempts are sent to other upstreams up to MaxAttempts. Redirects to
+// another host are followed as they are.
+func (lb *LoadBalancer) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			client.Transport = &loadBalancerTransport{base: client.Transport, lb: lb, host: req.URL.Host}
+			return next.Handle(client, req)
+		})
+	}
+}
+
+type loadBalancerTransport struct {
+	base http.RoundTripper
+	lb   *LoadBalancer
+	// host is the host of the original request.
+	host string
+}
+
+func (t *loadBalancerTransport) unwrap() http.RoundTripper { return t.base }
+
+func (t *loadBalancerTransport) rewrap(base http.RoundTripper) http.RoundTripper {
+	clone := *t
+	clone.base = base
+	return &clone
+}
+
+func (t *loadBalancerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	options := t.lb.options
+
+	// Redirects are sent where they point unless they stay on the host of
+	// the original request, as relative redirects do.
+	if req.Response != nil && req.URL.Host != t.host {
+		return base.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	req = req.Clone(ctx)
+	if options.MaxAttempts > 1 {
+		if _, err := EnsureReplayableBody(req, -1); err != nil {
+			return nil, err
+		}
+	}
+
+	tried := map[string]bool{}
+	var (
+		resp *http.Response
+		err  error
+	)
+	for attempt := 0; attempt < max(options.MaxAttempts, 1); attempt++ {
+		key, target, pickErr := t.lb.pick(tried)
+		if pickErr != nil {
+			if attempt == 0 {
+				if req.Body != nil {
+					req.Body.Close()
+				}
+				return nil, pickErr
+			}
+			break
+		}
+		tried[key] = true
+
+		outgoing := req.Clone(ctx)
+		if attempt > 0 && req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			outgoing.Body = body
+		}
+		outgoing.URL.Scheme = target.Scheme
+		outgoing.URL.Host = target.Host
+		outgoing.Host = ""
+
+		drainAndClose(resp)
+		resp, err = base.RoundTrip(outgoing)
+		if ctx.Err() != nil {
+			return resp, err
+		}
+		failed := options.IsFailure(resp, err)
+		if options.HealthChecker != nil {
+			options.HealthChecker.Observe(outgoing, resp, err)
+		} else {
+			t.lb.record(key, failed)
+		}
+		if !failed {
+			return resp, err
+		}
+	}
+	return resp, err
+}
+
+// pick selects the next upstream not in exclude by smooth weighted
+// round-robin among the healthy ones, or among all when none is healthy.
+func (lb *LoadBalancer) pick(exclude map[string]bool) (string, *url.URL, error) {
+	upstreams := lb.provider.Upstreams()
+
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	weights := make(map[string]int, len(upstreams))
+	for _, upstream := range upstreams {
+		if _, ok := lb.states[upstream.URL]; !ok {
+			u, err := url.Parse(normalize(upstream.URL))
+			if err != nil {
+				return "", nil, err
+			}
+			lb.states[upstream.URL] = &upstreamState{url: u}
+		}
+		weights[upstream.URL] = max(upstream.Weight, 1)
+	}
+	for key := range lb.states {
+		if _, ok := weights[key]; !ok {
+			delete(lb.states, key)
+		}
+	}
+
+	now := lb.options.Now()
+	candidates := make([]string, 0, len(upstreams))
+	for _, upstream := range upstreams {
+		if !exclude[upstream.URL] && lb.healthyLocked(lb.states[upstream.URL], now) && !slices.Contains(candidates, upstream.URL) {
+			candidates = append(candidates, upstream.URL)
+		}
+	}
+	if len(candidates) == 0 {
+		for _, upstream := range upstreams {
+			if !exclude[upstream.URL] && !slices.Contains(candidates, upstream.URL) {
+				candidates = append(candidates, upstream.URL)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return "", nil, ErrNoUpstream
+	}
+
+	var best *upstreamState
+	bestKey, total := "", 0
+	for _, key := range candidates {
+		state := lb.states[key]
+		state.current += weights[key]
+		total += weights[key]
+		if best == nil || state.current > best.current {
+			best, bestKey = state, key
+		}
+	}
+	best.current -= total
+	return bestKey, best.url, nil
+}
+
+func (lb *LoadBalancer) healthyLocked(state *upstreamState, now time.Time) bool {
+	if lb.options.HealthChecker != nil {
+		return lb.options.HealthChecker.Healthy(state.url.Host)
+	}
+	return state.failures < lb.options.FailureThreshold || !now.Before(state.downUntil)
+}
+
+// record updates the health of an upstream after an attempt.
+func (lb *LoadBalancer) record(key string, failed bool) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	state, ok := lb.states[key]
+	if !ok {
+		return
+	}
+	if !failed {
+		state.failures = 0
+		return
+	}
+	state.failures++
+	if state.failures >= lb.options.FailureThreshold {
+		state.downUntil = lb.options.Now().Add(lb.options.Cooldown)
+	}
+}
@@ -0,0 +1,159 @@
+package fetch
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newUpstream(name string, healthy *bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !*healthy {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte(name + string(body)))
+	}))
+}
+
+func TestLoadBalancer_Weighted(t *testing.T) {
+	healthy := true
+	a := newUpstream("a", &healthy)
+	defer a.Close()
+	b := newUpstream("b", &healthy)
+	defer b.Close()
+
+	lb := NewLoadBalancer(StaticHosts(Upstream{URL: a.URL, Weight: 3}, Upstream{URL: b.URL}))
+	dispatcher := NewDispatcher(nil, lb.Middleware())
+
+	var got []string
+	for i := 0; i < 8; i++ {
+		resp := dispatcher.NewRequest().Get("http://service/items")
+		require.NoError(t, resp.Error)
+		got = append(got, resp.String())
+	}
+	assert.Equal(t, "aabaaaba", strings.Join(got, ""))
+}
+
+func TestLoadBalancer_Health(t *testing.T) {
+	healthyA, healthyB := true, true
+	a := newUpstream("a", &healthyA)
+	defer a.Close()
+	b := newUpstream("b", &healthyB)
+	defer b.Close()
+
+	now := time.Unix(0, 0)
+	lb := NewLoadBalancer(StaticHosts(Upstream{URL: a.URL}, Upstream{URL: b.URL}), func(o *LoadBalancerOptions) {
+		o.FailureThreshold = 2
+		o.Cooldown = time.Minute
+		o.Now = func() time.Time { return now }
+	})
+	dispatcher := NewDispatcher(nil, lb.Middleware())
+	get := func() string {
+		resp := dispatcher.NewRequest().Get("/")
+		require.NoError(t, resp.Error)
+		return resp.String()
+	}
+
+	healthyA = false
+	for i := 0; i < 4; i++ {
+		get()
+	}
+	assert.Equal(t, map[string]bool{a.URL: false, b.URL: true}, lb.Healthy())
+	assert.Equal(t, "bbb", get()+get()+get())
+
+	// After the cooldown the upstream is tried again.
+	healthyA = true
+	now = now.Add(time.Minute)
+	assert.ElementsMatch(t, []string{"a", "b"}, []string{get(), get()})
+	assert.Equal(t, map[string]bool{a.URL: true, b.URL: true}, lb.Healthy())
+}
+
+func TestLoadBalancer_Failover(t *testing.T) {
+	healthyA, healthyB := false, true
+	a := newUpstream("a", &healthyA)
+	defer a.Close()
+	b := newUpstream("b", &healthyB)
+	defer b.Close()
+
+	lb := NewLoadBalancer(StaticHosts(Upstream{URL: a.URL}, Upstream{URL: b.URL}), func(o *LoadBalancerOptions) {
+		o.MaxAttempts = 2
+	})
+	dispatcher := NewDispatcher(nil, lb.Middleware())
+
+	for i := 0; i < 3; i++ {
+		resp := dispatcher.NewRequest().Body(strings.NewReader("-body")).Post("/")
+		require.NoError(t, resp.Error)
+		assert.Equal(t, "b-body", resp.String())
+	}
+
+	// With every upstream failing the last response is returned.
+	healthyB = false
+	resp := dispatcher.NewRequest().Get("/")
+	require.NoError(t, resp.Error)
+	assert.Equal(t, http.StatusBadGateway, resp.RawResponse.StatusCode)
+}
+
+func TestLoadBalancer_DynamicProvider(t *testing.T) {
+	healthy := true
+	a := newUpstream("a", &healthy)
+	defer a.Close()
+	b := newUpstream("b", &healthy)
+	defer b.Close()
+
+	var upstreams []Upstream
+	lb := NewLoadBalancer(HostProviderFunc(func() []Upstream { return upstreams }))
+	dispatcher := NewDispatcher(nil, lb.Middleware())
+
+	assert.ErrorIs(t, dispatcher.NewRequest().Get("/").Error, ErrNoUpstream)
+
+	upstreams = []Upstream{{URL: a.URL}}
+	assert.Equal(t, "a", dispatcher.NewRequest().Get("/").String())
+
+	upstreams = []Upstream{{URL: b.URL}}
+	assert.Equal(t, "b", dispatcher.NewRequest().Get("/").String())
+	assert.Equal(t, map[string]bool{b.URL: true}, lb.Healthy())
+}
+
+func TestLoadBalancer_Redirect(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("target"))
+	}))
+	defer target.Close()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/away":
+			http.Redirect(w, r, target.URL, http.StatusFound)
+		case "/relative":
+			http.Redirect(w, r, "/landing", http.StatusFound)
+		default:
+			w.Write([]byte("upstream" + r.URL.Path))
+		}
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "other host is not rewritten", path: "/away", want: "target"},
+		{name: "relative redirect stays on the upstreams", path: "/relative", want: "upstream/landing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := NewLoadBalancer(StaticHosts(Upstream{URL: upstream.URL}))
+			resp := NewDispatcher(nil, lb.Middleware()).NewRequest().Get("http://service" + tt.path)
+			require.NoError(t, resp.Error)
+			assert.Equal(t, tt.want, resp.String())
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package fetch
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Map is a decoded JSON object with weakly-typed accessors for exploratory
+// code and tests that do not want to declare structs for nested responses.
+//
+// Paths use dots for object keys and brackets for array indices:
+//
+//	m.GetString("data.items[0].name")
+type Map map[string]any
+
+// Map decodes the response body as a JSON object.
+// Numbers are kept as json.Number so integers do not lose precision.
+func (r *Response) Map() (Map, error) {
+	if r.Error != nil {
+		return nil, r.Error
+	}
+
+	decoder := json.NewDecoder(r.getInternalReader())
+	decoder.UseNumber()
+	defer r.Close()
+
+	var m Map
+	if err := decoder.Decode(&m); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// Get returns the raw value at path and whether it exists.
+func (m Map) Get(path string) (any, bool) {
+	var current any = map[string]any(m)
+
+	for _, segment := range splitPath(path) {
+		switch node := current.(type) {
+		case map[string]any:
+			if segment.isIndex {
+				return nil, false
+			}
+			value, ok := node[segment.key]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []any:
+			if !segment.isIndex || segment.index < 0 || segment.index >= len(node) {
+				return nil, false
+			}
+			current = node[segment.index]
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// GetString returns the value at path converted to a string.
+// Numbers and booleans are formatted; missing values and objects yield "".
+func (m Map) GetString(path string) string {
+	value, _ := m.Get(path)
+
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return ""
+	}
+}
+
+// GetInt returns the value at path converted to an int.
+// Numeric strings are parsed, floats are truncated, true is 1.
+// Missing or unconvertible values yield 0.
+func (m Map) GetInt(path string) int {
+	value, _ := m.Get(path)
+
+	switch v := value.(type) {
+	case json.Number:
+		return parseIntLoose(v.String())
+	case string:
+		return parseIntLoose(strings.TrimSpace(v))
+	case float64:
+		return int(v)
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	default:
+		return 0
+	}
+}
+
+// GetFloat returns the value at path converted to a float64.
+// Missing or unconvertible values yield 0.
+func (m Map) GetFloat(path string) float64 {
+	value, _ := m.Get(path)
+
+	switch v := value.(type) {
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	case string:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f
+	case float64:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	default:
+		return 0
+	}
+}
+
+// GetBool returns the value at path converted to a bool.
+// Strings are parsed with strconv.ParseBool and non-zero numbers are true.
+// Missing or unconvertible values yield false.
+func (m Map) GetBool(path string) bool {
+	value, _ := m.Get(path)
+
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		b, _ := strconv.ParseBool(strings.TrimSpace(v))
+		return b
+	case json.Number:
+		f, _ := v.Float64()
+		return f != 0
+	case float64:
+		return v != 0
+	default:
+		return false
+	}
+}
+
+// GetMap returns the object at path, or nil.
+func (m Map) GetMap(path string) Map {
+	value, _ := m.Get(path)
+	if v, ok := value.(map[string]any); ok {
+		return v
+	}
+	return nil
+}
+
+// GetSlice returns the array at path, or nil.
+func (m Map) GetSlice(path string) []any {
+	value, _ := m.Get(path)
+	if v, ok := value.([]any); ok {
+		return v
+	}
+	return nil
+}
+
+func parseIntLoose(s string) int {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return int(i)
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return int(f)
+	}
+	return 0
+}
+
+// pathSegment is either an object key or an array index.
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func splitPath(path string) []pathSegment {
+	var segments []pathSegment
+
+	for _, part := range strings.Split(path, ".") {
+		key, rest, hasIndex := strings.Cut(part, "[")
+		if key != "" || !hasIndex {
+			segments = append(segments, pathSegment{key: key})
+		}
+
+		for hasIndex {
+			var raw string
+			raw, rest, _ = strings.Cut(rest, "]")
+			index, err := strconv.Atoi(raw)
+			if err != nil {
+				index = -1
+			}
+			segments = append(segments, pathSegment{index: index, isIndex: true})
+
+			_, rest, hasIndex = strings.Cut(rest, "[")
+		}
+	}
+
+	return segments
+}
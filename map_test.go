@@ -0,0 +1,93 @@
+package fetch
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mapTestJSON = `{
+	"data": {
+		"items": [
+			{"name": "first", "count": "42", "price": 9.5, "active": "true"},
+			{"name": "second", "count": 7, "price": "3.25", "active": 0}
+		],
+		"total": 9007199254740993,
+		"ok": true
+	}
+}`
+
+func TestResponse_Map(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(mapTestJSON))
+	}))
+	defer server.Close()
+
+	resp := NewDispatcher(nil).NewRequest().Get(server.URL)
+	m, err := resp.Map()
+	require.NoError(t, err)
+
+	assert.Equal(t, "first", m.GetString("data.items[0].name"))
+	assert.Equal(t, 9007199254740993, m.GetInt("data.total"))
+	assert.Len(t, m.GetSlice("data.items"), 2)
+	assert.NotNil(t, m.GetMap("data.items[1]"))
+}
+
+func TestResponse_Map_Error(t *testing.T) {
+	resp := &Response{Error: errors.New("request error")}
+	m, err := resp.Map()
+	assert.Error(t, err)
+	assert.Nil(t, m)
+}
+
+func TestMap_Accessors(t *testing.T) {
+	resp := &Response{RawResponse: &http.Response{Body: http.NoBody}}
+	resp.buffer = bytes.NewBufferString(mapTestJSON)
+	m, err := resp.Map()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		got    any
+		expect any
+	}{
+		{name: "string from string", got: m.GetString("data.items[1].name"), expect: "second"},
+		{name: "string from number", got: m.GetString("data.items[1].count"), expect: "7"},
+		{name: "string from bool", got: m.GetString("data.ok"), expect: "true"},
+		{name: "string missing", got: m.GetString("data.missing"), expect: ""},
+		{name: "int from string", got: m.GetInt("data.items[0].count"), expect: 42},
+		{name: "int from float", got: m.GetInt("data.items[0].price"), expect: 9},
+		{name: "int from bool", got: m.GetInt("data.ok"), expect: 1},
+		{name: "int out of range index", got: m.GetInt("data.items[5].count"), expect: 0},
+		{name: "float from number", got: m.GetFloat("data.items[0].price"), expect: 9.5},
+		{name: "float from string", got: m.GetFloat("data.items[1].price"), expect: 3.25},
+		{name: "bool from string", got: m.GetBool("data.items[0].active"), expect: true},
+		{name: "bool from zero", got: m.GetBool("data.items[1].active"), expect: false},
+		{name: "bool from bool", got: m.GetBool("data.ok"), expect: true},
+		{name: "index on object", got: m.GetString("data[0]"), expect: ""},
+		{name: "key on array", got: m.GetString("data.items.name"), expect: ""},
+		{name: "invalid index", got: m.GetString("data.items[x].name"), expect: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expect, tt.got)
+		})
+	}
+}
+
+func TestMap_NestedArrays(t *testing.T) {
+	m := Map{"matrix": []any{[]any{"a", "b"}, []any{"c"}}}
+
+	value, ok := m.Get("matrix[0][1]")
+	assert.True(t, ok)
+	assert.Equal(t, "b", value)
+
+	_, ok = m.Get("matrix[1][1]")
+	assert.False(t, ok)
+}
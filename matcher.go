@@ -0,0 +1,88 @@
+package fetch
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Matcher reports whether a request matches a condition.
+type Matcher func(req *http.Request) bool
+
+// When applies middleware only to requests accepted by the matcher.
+// Other requests pass through to the next handler unchanged.
+//
+// Example:
+//
+//	dispatcher.Use(fetch.When(fetch.MatchHost("api.internal"), authMiddleware))
+func When(matcher Matcher, middleware Middleware) Middleware {
+	return func(next Handler) Handler {
+		wrapped := middleware(next)
+
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			if matcher(req) {
+				return wrapped.Handle(client, req)
+			}
+			return next.Handle(client, req)
+		})
+	}
+}
+
+// Unless applies middleware only to requests rejected by the matcher.
+func Unless(matcher Matcher, middleware Middleware) Middleware {
+	return When(func(req *http.Request) bool { return !matcher(req) }, middleware)
+}
+
+// MatchHost matches requests whose host name equals any of the given hosts.
+// The comparison is case-insensitive and ignores the port.
+func MatchHost(hosts ...string) Matcher {
+	return func(req *http.Request) bool {
+		hostname := req.URL.Hostname()
+		for _, host := range hosts {
+			if strings.EqualFold(host, hostname) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchPathPrefix matches requests whose path starts with any of the given prefixes.
+func MatchPathPrefix(prefixes ...string) Matcher {
+	return func(req *http.Request) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(req.URL.Path, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchMethod matches requests with any of the given HTTP methods, case-insensitive.
+func MatchMethod(methods ...string) Matcher {
+	return func(req *http.Request) bool {
+		for _, method := range methods {
+			if strings.EqualFold(method, req.Method) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// MatchHeader matches requests carrying the given header. If value is empty
+// the header only has to be present, otherwise one of its values must equal value.
+func MatchHeader(key, value string) Matcher {
+	return func(req *http.Request) bool {
+		values := req.Header.Values(key)
+		if value == "" {
+			return len(values) > 0
+		}
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+}
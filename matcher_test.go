@@ -0,0 +1,83 @@
+package fetch
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchers(t *testing.T) {
+	tests := []struct {
+		name     string
+		matcher  Matcher
+		method   string
+		url      string
+		header   http.Header
+		expected bool
+	}{
+		{name: "host matches", matcher: MatchHost("api.internal"), url: "http://API.internal:8080/x", expected: true},
+		{name: "host differs", matcher: MatchHost("api.internal"), url: "http://example.com/x", expected: false},
+		{name: "path prefix matches", matcher: MatchPathPrefix("/debug"), url: "http://example.com/debug/vars", expected: true},
+		{name: "path prefix differs", matcher: MatchPathPrefix("/debug"), url: "http://example.com/api", expected: false},
+		{name: "method matches", matcher: MatchMethod("post", "PUT"), method: "POST", url: "http://example.com", expected: true},
+		{name: "method differs", matcher: MatchMethod("POST"), method: "GET", url: "http://example.com", expected: false},
+		{name: "header present", matcher: MatchHeader("X-Debug", ""), url: "http://example.com", header: http.Header{"X-Debug": {"1"}}, expected: true},
+		{name: "header value matches", matcher: MatchHeader("X-Env", "dev"), url: "http://example.com", header: http.Header{"X-Env": {"prod", "dev"}}, expected: true},
+		{name: "header value differs", matcher: MatchHeader("X-Env", "dev"), url: "http://example.com", header: http.Header{"X-Env": {"prod"}}, expected: false},
+		{name: "header missing", matcher: MatchHeader("X-Debug", ""), url: "http://example.com", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = "GET"
+			}
+			req, err := http.NewRequest(method, tt.url, nil)
+			require.NoError(t, err)
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+
+			assert.Equal(t, tt.expected, tt.matcher(req))
+		})
+	}
+}
+
+func TestWhenUnless(t *testing.T) {
+	mark := func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			req.Header.Set("X-Applied", "yes")
+			return next.Handle(client, req)
+		})
+	}
+
+	tests := []struct {
+		name        string
+		middleware  Middleware
+		url         string
+		wantApplied bool
+	}{
+		{name: "when matches", middleware: When(MatchHost("api.internal"), mark), url: "http://api.internal/", wantApplied: true},
+		{name: "when does not match", middleware: When(MatchHost("api.internal"), mark), url: "http://example.com/", wantApplied: false},
+		{name: "unless matches", middleware: Unless(MatchHost("api.internal"), mark), url: "http://api.internal/", wantApplied: false},
+		{name: "unless does not match", middleware: Unless(MatchHost("api.internal"), mark), url: "http://example.com/", wantApplied: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := tt.middleware(HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+				assert.Equal(t, tt.wantApplied, req.Header.Get("X-Applied") == "yes")
+				return &http.Response{StatusCode: 200}, nil
+			}))
+
+			req, err := http.NewRequest("GET", tt.url, nil)
+			require.NoError(t, err)
+
+			_, err = handler.Handle(&http.Client{}, req)
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package fetch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// ErrNoMockRule is matched by errors.Is when MockTransport has no rule for a
+// request and no fallback transport.
+var ErrNoMockRule = errors.New("no mock rule matches request")
+
+// MockRule describes requests and the responses returned for them.
+// Empty match fields match everything.
+type MockRule struct {
+	Name   string `json:"name"`
+	Method string `json:"method"`
+	Host   string `json:"host"`
+	// Path is matched segment by segment. A segment such as {id} matches any
+	// value and exposes it to templates as .Params.id; a final * matches the
+	// remaining path.
+	Path    string            `json:"path"`
+	Query   map[string]string `json:"query"`
+	Headers map[string]string `json:"headers"`
+	// Responses are returned in sequence, one per matching request; the last
+	// response repeats once the sequence is exhausted.
+	Responses []MockResponse `json:"responses"`
+}
+
+// MockResponse is a templated response of a MockRule.
+type MockResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	// Body is a text/template executed with the request's Method, Path, Host,
+	// Query, Header, Params and Body.
+	Body string `json:"body"`
+	// Delay is a duration such as "250ms" to wait before responding.
+	Delay string `json:"delay"`
+}
+
+// MockTransport is an http.RoundTripper answering requests from declarative
+// rules, so integration environments can run without live upstreams. Rules
+// are checked in order and the first match answers. It is safe for concurrent use.
+type MockTransport struct {
+	// Fallback handles requests no rule matches. When nil such requests
+	// fail with ErrNoMockRule.
+	Fallback http.RoundTripper
+
+	mu    sync.Mutex
+	rules []*mockRule
+}
+
+type mockRule struct {
+	MockRule
+	segments  []string
+	responses []mockResponse
+	calls     int
+}
+
+type mockResponse struct {
+	status  int
+	headers map[string]*template.Template
+	body    *template.Template
+	delay   time.Duration
+}
+
+type mockRequestData struct {
+	Method string
+	Host   string
+	Path   string
+	Query  map[string]string
+	Header map[string]string
+	Params map[string]string
+	Body   string
+}
+
+// NewMockTransport compiles rules into a MockTransport.
+//
+// Example:
+//
+//	rules, err := fetch.LoadMockRules("testdata/upstream.json")
+//	if err != nil {
+//	    return err
+//	}
+//	mock, err := fetch.NewMockTransport(rules...)
+//	if err != nil {
+//	    return err
+//	}
+//	dispatcher := fetch.NewDispatcherWithTransport(mock)
+func NewMockTransport(rules ...MockRule) (*MockTransport, error) {
+	t := &MockTransport{}
+	for i, rule := range rules {
+		compiled, err := compileMockRule(rule)
+		if err != nil {
+			name := rule.Name
+			if name == "" {
+				name = "#" + strconv.Itoa(i)
+			}
+			return nil, fmt.Errorf("mock rule %s: %w", name, err)
+		}
+		t.rules = append(t.rules, compiled)
+	}
+	return t, nil
+}
+
+// LoadMockRules reads a JSON file holding an array of MockRule.
+//
+// Example file:
+//
+//	[
+//	  {
+//	    "name": "flaky user lookup",
+//	    "method": "GET",
+//	    "path": "/users/{id}",
+//	    "responses": [
+//	      {"status": 500},
+//	      {"status": 200, "headers": {"Content-Type": "application/json"},
+//	       "body": "{\"id\": \"{{.Params.id}}\"}", "delay": "50ms"}
+//	    ]
+//	  }
+//	]
+func LoadMockRules(path string) ([]MockRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []MockRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("mock rules %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+func compileMockRule(rule MockRule) (*mockRule, error) {
+	if len(rule.Responses) == 0 {
+		return nil, errors.New("no responses")
+	}
+
+	compiled := &mockRule{MockRule: rule}
+	if rule.Path != "" {
+		compiled.segments = strings.Split(strings.Trim(rule.Path, "/"), "/")
+	}
+
+	for _, response := range rule.Responses {
+		r := mockResponse{status: response.Status, headers: map[string]*template.Template{}}
+		if r.status == 0 {
+			r.status = http.StatusOK
+		}
+
+		var err error
+		if r.body, err = template.New("body").Option("missingkey=zero").Parse(response.Body); err != nil {
+			return nil, err
+		}
+		for name, value := range response.Headers {
+			if r.headers[name], err = template.New(name).Option("missingkey=zero").Parse(value); err != nil {
+				return nil, err
+			}
+		}
+		if response.Delay != "" {
+			if r.delay, err = time.ParseDuration(response.Delay); err != nil {
+				return nil, err
+			}
+		}
+		compiled.responses = append(compiled.responses, r)
+	}
+	return compiled, nil
+}
+
+// Reset restarts the response sequence of every rule.
+func (t *MockTransport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, rule := range t.rules {
+		rule.calls = 0
+	}
+}
+
+// RoundTrip answers req from the first matching rule.
+func (t *MockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rule, params, response := t.next(req)
+	if rule == nil {
+		if t.Fallback != nil {
+			return t.Fallback.RoundTrip(req)
+		}
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("%w: %s %s", ErrNoMockRule, req.Method, req.URL.Redacted())
+	}
+
+	data, err := newMockRequestData(req, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.delay > 0 {
+		timer := time.NewTimer(response.delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	header := http.Header{}
+	for name, tmpl := range response.headers {
+		var value strings.Builder
+		if err := tmpl.Execute(&value, data); err != nil {
+			return nil, fmt.Errorf("mock rule %s: %w", rule.Name, err)
+		}
+		header.Set(name, value.String())
+	}
+
+	var body bytes.Buffer
+	if err := response.body.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("mock rule %s: %w", rule.Name, err)
+	}
+
+	return &http.Response{
+		Status:        strconv.Itoa(response.status) + " " + http.StatusText(response.status),
+		StatusCode:    response.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(&body),
+		ContentLength: int64(body.Len()),
+		Request:       req,
+	}, nil
+}
+
+// newMockRequestData collects the template data of req and consumes its body.
+func newMockRequestData(req *http.Request, params map[string]string) (mockRequestData, error) {
+	data := mockRequestData{
+		Method: req.Method,
+		Host:   req.URL.Host,
+		Path:   req.URL.Path,
+		Query:  map[string]string{},
+		Header: map[string]string{},
+		Params: params,
+	}
+	for name := range req.URL.Query() {
+		data.Query[name] = req.URL.Query().Get(name)
+	}
+	for name := range req.Header {
+		data.Header[name] = req.Header.Get(name)
+	}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return data, err
+		}
+		data.Body = string(body)
+	}
+	return data, nil
+}
+
+// next finds the rule matching req and advances its response sequence.
+func (t *MockTransport) next(req *http.Request) (*mockRule, map[string]string, mockResponse) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, rule := range t.rules {
+		params, ok := rule.match(req)
+		if !ok {
+			continue
+		}
+		response := rule.responses[min(rule.calls, len(rule.responses)-1)]
+		rule.calls++
+		return rule, params, response
+	}
+	return nil, nil, mockResponse{}
+}
+
+func (r *mockRule) match(req *http.Request) (map[string]string, bool) {
+	if r.Method != "" && !strings.EqualFold(r.Method, req.Method) {
+		return nil, false
+	}
+	if r.Host != "" && !strings.EqualFold(r.Host, req.URL.Hostname()) && !strings.EqualFold(r.Host, req.URL.Host) {
+		return nil, false
+	}
+	for name, value := range r.Query {
+		if req.URL.Query().Get(name) != value {
+			return nil, false
+		}
+	}
+	for name, value := range r.Headers {
+		if req.Header.Get(name) != value {
+			return nil, false
+		}
+	}
+
+	params := map[string]string{}
+	if r.segments == nil {
+		return params, true
+	}
+
+	path := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	for i, segment := range r.segments {
+		if segment == "*" && i == len(r.segments)-1 {
+			return params, true
+		}
+		if i >= len(path) {
+			return nil, false
+		}
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params[segment[1:len(segment)-1]] = path[i]
+			continue
+		}
+		if segment != path[i] {
+			return nil, false
+		}
+	}
+	if len(path) != len(r.segments) {
+		return nil, false
+	}
+	return params, true
+}
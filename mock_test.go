@@ -0,0 +1,144 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockTransport(t *testing.T) {
+	rules := []MockRule{
+		{
+			Name:   "flaky",
+			Method: "GET",
+			Path:   "/users/{id}",
+			Responses: []MockResponse{
+				{Status: http.StatusInternalServerError},
+				{Headers: map[string]string{"X-User": "{{.Params.id}}"}, Body: `{"id":"{{.Params.id}}","q":"{{.Query.q}}"}`},
+			},
+		},
+		{
+			Name:      "echo",
+			Method:    "POST",
+			Path:      "/echo",
+			Headers:   map[string]string{"X-Mode": "echo"},
+			Responses: []MockResponse{{Status: http.StatusCreated, Body: "{{.Method}} {{.Body}}"}},
+		},
+		{
+			Name:      "static",
+			Path:      "/static/*",
+			Responses: []MockResponse{{Body: "{{.Path}}"}},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		header     string
+		body       string
+		wantStatus []int
+		wantBody   string
+		wantErr    error
+	}{
+		{name: "sequence then repeat", method: "GET", path: "/users/7?q=x", wantStatus: []int{500, 200, 200}, wantBody: `{"id":"7","q":"x"}`},
+		{name: "request body template", method: "POST", path: "/echo", header: "echo", body: "hi", wantStatus: []int{201}, wantBody: "POST hi"},
+		{name: "header mismatch", method: "POST", path: "/echo", wantErr: ErrNoMockRule},
+		{name: "wildcard", method: "GET", path: "/static/css/site.css", wantStatus: []int{200}, wantBody: "/static/css/site.css"},
+		{name: "segment count mismatch", method: "GET", path: "/users/7/posts", wantErr: ErrNoMockRule},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock, err := NewMockTransport(rules...)
+			require.NoError(t, err)
+			dispatcher := NewDispatcherWithTransport(mock)
+
+			if tt.wantErr != nil {
+				resp := dispatcher.NewRequest().Send(tt.method, "http://upstream.test"+tt.path)
+				defer resp.Close()
+				assert.ErrorIs(t, resp.Error, tt.wantErr)
+				return
+			}
+
+			for _, status := range tt.wantStatus {
+				req := dispatcher.NewRequest().UseFuncs(func(r *http.Request) {
+					if tt.header != "" {
+						r.Header.Set("X-Mode", tt.header)
+					}
+				})
+				if tt.body != "" {
+					req.Body(strings.NewReader(tt.body))
+				}
+				resp := req.Send(tt.method, "http://upstream.test"+tt.path)
+				require.NoError(t, resp.Error)
+				assert.Equal(t, status, resp.RawResponse.StatusCode)
+				if status < 300 {
+					assert.Equal(t, tt.wantBody, resp.String())
+				}
+				resp.Close()
+			}
+		})
+	}
+}
+
+func TestMockTransport_DelayAndFallback(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("live"))
+	}))
+	defer upstream.Close()
+
+	mock, err := NewMockTransport(MockRule{Path: "/slow", Responses: []MockResponse{{Body: "slow", Delay: "30ms"}}})
+	require.NoError(t, err)
+	mock.Fallback = http.DefaultTransport
+	dispatcher := NewDispatcherWithTransport(mock)
+
+	start := time.Now()
+	resp := dispatcher.NewRequest().Get(upstream.URL + "/slow")
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "slow", resp.String())
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+
+	live := dispatcher.NewRequest().Get(upstream.URL + "/other")
+	require.NoError(t, live.Error)
+	assert.Equal(t, "live", live.String())
+}
+
+func TestLoadMockRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[
+		{"name": "user", "method": "GET", "path": "/users/{id}",
+		 "responses": [{"status": 503}, {"status": 200, "body": "user {{.Params.id}}"}]}
+	]`), 0o644))
+
+	rules, err := LoadMockRules(path)
+	require.NoError(t, err)
+	mock, err := NewMockTransport(rules...)
+	require.NoError(t, err)
+	dispatcher := NewDispatcherWithTransport(mock)
+
+	first := dispatcher.NewRequest().Get("http://api.test/users/42")
+	require.NoError(t, first.Error)
+	assert.Equal(t, http.StatusServiceUnavailable, first.RawResponse.StatusCode)
+	first.Close()
+
+	second := dispatcher.NewRequest().Get("http://api.test/users/42")
+	require.NoError(t, second.Error)
+	assert.Equal(t, "user 42", second.String())
+
+	mock.Reset()
+	again := dispatcher.NewRequest().Get("http://api.test/users/42")
+	require.NoError(t, again.Error)
+	assert.Equal(t, http.StatusServiceUnavailable, again.RawResponse.StatusCode)
+	again.Close()
+
+	_, err = NewMockTransport(MockRule{Name: "bad", Responses: []MockResponse{{Delay: "soon"}}})
+	assert.ErrorContains(t, err, "mock rule bad")
+}
@@ -1,16 +1,23 @@
 package fetch
 
 import (
+	"compress/gzip"
 	"errors"
 	"io"
+	"maps"
 	"mime/multipart"
 	"net/http"
 	"net/textproto"
+	"net/url"
+	"slices"
 	"time"
 )
 
 // MultipartField represents a single field in a multipart/form-data request.
 // It can be either a form value or a file upload with progress tracking.
+// File content is gzip-compressed on the fly when CompressPart is set; the part
+// then carries Content-Encoding: gzip and progress additionally reports the
+// compressed bytes written in CompressedWritten.
 type MultipartField struct {
 	Name                    string
 	FileName                string
@@ -21,6 +28,7 @@ type MultipartField struct {
 	ProgressInterval        time.Duration
 	ProgressCallback        MultipartFieldCallbackFunc
 	Values                  []string
+	CompressPart            bool
 }
 
 // MultipartFieldProgress tracks upload progress for a multipart field.
@@ -28,15 +36,66 @@ type MultipartFieldProgress struct {
 	Name     string
 	FileName string
 	FileSize int64
-	Written  int64
+	// Written is the number of bytes read from the file so far.
+	Written int64
+	// CompressedWritten is the number of compressed bytes written to the
+	// part so far when CompressPart is set, and zero otherwise.
+	CompressedWritten int64
 }
 
 // MultipartFieldCallbackFunc is called periodically during field upload to report progress.
 type MultipartFieldCallbackFunc func(MultipartFieldProgress)
 
+// MultipartOrder controls the order in which Multipart emits parts.
+type MultipartOrder int
+
+const (
+	// MultipartOrderInsertion emits parts in the order of the fields slice.
+	MultipartOrderInsertion MultipartOrder = iota
+	// MultipartOrderFieldsFirst emits value fields before file parts,
+	// keeping the slice order within each group.
+	MultipartOrderFieldsFirst
+	// MultipartOrderFilesFirst emits file parts before value fields,
+	// keeping the slice order within each group.
+	MultipartOrderFilesFirst
+)
+
 // MultipartOptions configures multipart request creation.
 type MultipartOptions struct {
 	Boundary string
+	// Order controls field vs file part ordering. Defaults to insertion order.
+	Order MultipartOrder
+}
+
+// MultipartFormFields converts form values to value fields sorted by name,
+// so the emitted parts do not depend on map iteration order. Repeated values
+// of a name stay in their original order.
+func MultipartFormFields(form url.Values) []*MultipartField {
+	fields := make([]*MultipartField, 0, len(form))
+	for _, name := range slices.Sorted(maps.Keys(form)) {
+		fields = append(fields, &MultipartField{Name: name, Values: form[name]})
+	}
+	return fields
+}
+
+func orderMultipartFields(fields []*MultipartField, order MultipartOrder) []*MultipartField {
+	if order == MultipartOrderInsertion {
+		return fields
+	}
+
+	rank := func(mf *MultipartField) int {
+		isFile := len(mf.Values) == 0
+		if isFile == (order == MultipartOrderFilesFirst) {
+			return 0
+		}
+		return 1
+	}
+
+	ordered := slices.Clone(fields)
+	slices.SortStableFunc(ordered, func(a, b *MultipartField) int {
+		return rank(a) - rank(b)
+	})
+	return ordered
 }
 
 func createMultipartHeader(mf *MultipartField, contentType string) textproto.MIMEHeader {
@@ -58,6 +117,10 @@ func createMultipartHeader(mf *MultipartField, contentType string) textproto.MIM
 		h.Set("Content-Type", contentType)
 	}
 
+	if mf.CompressPart {
+		h.Set("Content-Encoding", "gzip")
+	}
+
 	return h
 }
 
@@ -98,6 +161,17 @@ func createMultipart(w *multipart.Writer, mf *MultipartField) error {
 		return err
 	}
 
+	// Written counts the bytes read from the file, CompressedWritten the
+	// bytes of the part body after compression.
+	var target io.Writer = pw
+	var zw *gzip.Writer
+	compressed := &countingWriter{Writer: pw}
+	if mf.CompressPart {
+		zw = gzip.NewWriter(compressed)
+		target = zw
+	}
+
+	var progress *callbackWriter
 	if mf.ProgressCallback != nil {
 		interval := mf.ProgressInterval
 
@@ -105,23 +179,59 @@ func createMultipart(w *multipart.Writer, mf *MultipartField) error {
 			interval = 1 * time.Second
 		}
 
-		pw = &callbackWriter{
-			Writer:    pw,
+		progress = &callbackWriter{
+			Writer:    target,
 			lastTime:  lastTime,
 			interval:  interval,
 			totalSize: mf.FileSize,
 			callback: func(written int64) {
-				mf.ProgressCallback(MultipartFieldProgress{
+				report := MultipartFieldProgress{
 					Name:     mf.Name,
 					FileName: mf.FileName,
 					FileSize: mf.FileSize,
 					Written:  written,
-				})
+				}
+				if mf.CompressPart {
+					report.CompressedWritten = compressed.n
+				}
+				mf.ProgressCallback(report)
 			},
 		}
+		target = progress
+	}
+
+	if err := copyPart(target, buf[:size], content, seeEOF); err != nil {
+		return err
+	}
+	if zw == nil {
+		return nil
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	// gzip buffers its output, so the final compressed size is only known
+	// after Close.
+	if progress != nil {
+		progress.callback(progress.written)
 	}
+	return nil
+}
+
+// countingWriter counts the bytes written to the underlying writer.
+type countingWriter struct {
+	io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.n += int64(n)
+	return n, err
+}
 
-	if _, err = pw.Write(buf[:size]); err != nil {
+func copyPart(w io.Writer, head []byte, content io.Reader, seeEOF bool) error {
+	if _, err := w.Write(head); err != nil {
 		return err
 	}
 
@@ -129,15 +239,18 @@ func createMultipart(w *multipart.Writer, mf *MultipartField) error {
 		return nil
 	}
 
-	_, err = io.Copy(pw, content)
+	_, err := io.Copy(w, content)
 	return err
 }
 
 // Multipart creates middleware that builds a multipart/form-data request body.
 // It streams the fields using a pipe to avoid loading everything into memory.
 // Supports progress callbacks for individual fields.
+// Parts are emitted in a deterministic order, see MultipartOptions.Order;
+// fields sharing a name are sent as separate parts in that order.
 func Multipart(fields []*MultipartField, opts ...func(*MultipartOptions)) Middleware {
 	options := applyOptions(&MultipartOptions{}, opts...)
+	fields = orderMultipartFields(fields, options.Order)
 
 	return func(handler Handler) Handler {
 		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
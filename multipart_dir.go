@@ -0,0 +1,178 @@
+package fetch
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DirUploadProgress reports the aggregate progress of the files added by
+// MultipartFilesFromDir.
+type DirUploadProgress struct {
+	Files     int
+	FilesDone int
+	TotalSize int64
+	Written   int64
+	Current   string
+}
+
+// DirFilesOptions configures MultipartFilesFromDir.
+type DirFilesOptions struct {
+	// Patterns select the files to add, matched with path.Match against the
+	// slash separated path relative to the directory; patterns without a
+	// slash also match the base name. A malformed pattern fails with
+	// path.ErrBadPattern. Defaults to all files.
+	Patterns []string
+	// ProgressInterval is the minimum time between progress reports of a
+	// file. Defaults to 1 second.
+	ProgressInterval time.Duration
+	// ProgressCallback receives the aggregate progress of all files.
+	ProgressCallback func(DirUploadProgress)
+}
+
+// MultipartFilesFromDir walks dir and returns a file field named fieldName
+// for every matching regular file, in lexical order. File names are the
+// slash separated paths relative to dir and contents are streamed from disk
+// when the request is sent. Symbolic links are followed only when they point
+// to a file inside dir.
+//
+// Example:
+//
+//	fields, err := fetch.MultipartFilesFromDir("files", "./site", func(o *fetch.DirFilesOptions) {
+//	    o.Patterns = []string{"*.html", "assets/*"}
+//	    o.ProgressCallback = func(p fetch.DirUploadProgress) {
+//	        fmt.Printf("\r%d/%d files, %d/%d bytes", p.FilesDone, p.Files, p.Written, p.TotalSize)
+//	    }
+//	})
+func MultipartFilesFromDir(fieldName, dir string, opts ...func(*DirFilesOptions)) ([]*MultipartField, error) {
+	options := applyOptions(&DirFilesOptions{ProgressInterval: time.Second}, opts...)
+	for _, pattern := range options.Patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", pattern, err)
+		}
+	}
+
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []*MultipartField
+	progress := &DirUploadProgress{}
+	written := map[*MultipartField]int64{}
+	done := map[*MultipartField]bool{}
+
+	err = filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if match, err := matchesAnyPattern(rel, options.Patterns); err != nil || !match {
+			return err
+		}
+
+		target := file
+		if entry.Type()&fs.ModeSymlink != 0 {
+			if target, err = filepath.EvalSymlinks(file); err != nil || !withinDir(root, target) {
+				return nil
+			}
+		}
+		info, err := os.Stat(target)
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		field := &MultipartField{
+			Name:             fieldName,
+			FileName:         rel,
+			FileSize:         info.Size(),
+			ProgressInterval: options.ProgressInterval,
+			GetReader: func() (io.ReadCloser, error) {
+				return os.Open(target)
+			},
+		}
+		if options.ProgressCallback != nil {
+			// Fields are written one after another, so no locking is needed.
+			field.ProgressCallback = func(p MultipartFieldProgress) {
+				if p.Written < written[field] && done[field] {
+					// The body is being replayed: the file starts over.
+					done[field] = false
+					progress.FilesDone--
+				}
+				progress.Written += p.Written - written[field]
+				written[field] = p.Written
+				progress.Current = p.FileName
+				if p.Written == p.FileSize && !done[field] {
+					done[field] = true
+					progress.FilesDone++
+				}
+				options.ProgressCallback(*progress)
+			}
+		}
+
+		progress.Files++
+		progress.TotalSize += info.Size()
+		fields = append(fields, field)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// SetFilesFromDir adds a multipart file field named fieldName for every file
+// in dir matching one of globs, or every file when no glob is given.
+// See MultipartFilesFromDir.
+func (r *Request) SetFilesFromDir(fieldName, dir string, globs ...string) *Request {
+	fields, err := MultipartFilesFromDir(fieldName, dir, func(o *DirFilesOptions) { o.Patterns = globs })
+	if err != nil {
+		return r.Use(func(Handler) Handler {
+			return HandlerFunc(func(*http.Client, *http.Request) (*http.Response, error) {
+				return nil, err
+			})
+		})
+	}
+	return r.Multipart(fields)
+}
+
+func matchesAnyPattern(rel string, patterns []string) (bool, error) {
+	if len(patterns) == 0 {
+		return true, nil
+	}
+	for _, pattern := range patterns {
+		names := []string{rel}
+		if !strings.Contains(pattern, "/") {
+			names = append(names, path.Base(rel))
+		}
+		for _, name := range names {
+			ok, err := path.Match(pattern, name)
+			if err != nil {
+				return false, fmt.Errorf("pattern %q: %w", pattern, err)
+			}
+			if ok {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func withinDir(root, target string) bool {
+	rel, err := filepath.Rel(root, target)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,140 @@
+package fetch
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newUploadDir(t *testing.T) string {
+	t.Helper()
+
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	require.NoError(t, os.WriteFile(outside, []byte("secret"), 0o600))
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("alpha"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("beta"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "c.log"), []byte("gamma"), 0o600))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "a.txt"), filepath.Join(dir, "link-in.txt")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(dir, "link-out.txt")))
+	return dir
+}
+
+func TestRequest_SetFilesFromDir(t *testing.T) {
+	dir := newUploadDir(t)
+
+	var received map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = map[string]string{}
+		mr, err := r.MultipartReader()
+		require.NoError(t, err)
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				return
+			}
+			require.NoError(t, err)
+			data, _ := io.ReadAll(part)
+			received[part.Header.Get("filename")] = string(data)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		dir      string
+		globs    []string
+		expected map[string]string
+		wantErr  bool
+	}{
+		{
+			name:     "all files",
+			dir:      dir,
+			expected: map[string]string{"a.txt": "alpha", "link-in.txt": "alpha", "sub/b.txt": "beta", "sub/c.log": "gamma"},
+		},
+		{
+			name:     "base name glob",
+			dir:      dir,
+			globs:    []string{"*.log"},
+			expected: map[string]string{"sub/c.log": "gamma"},
+		},
+		{
+			name:     "relative path glob",
+			dir:      dir,
+			globs:    []string{"sub/*.txt", "a.*"},
+			expected: map[string]string{"a.txt": "alpha", "sub/b.txt": "beta"},
+		},
+		{
+			name:    "missing dir",
+			dir:     filepath.Join(dir, "missing"),
+			wantErr: true,
+		},
+		{
+			name:    "malformed glob",
+			dir:     dir,
+			globs:   []string{"*.txt", "[a-"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received = nil
+			resp := NewDispatcher(nil).NewRequest().SetFilesFromDir("files", tt.dir, tt.globs...).Post(server.URL)
+			defer resp.Close()
+
+			if tt.wantErr {
+				require.Error(t, resp.Error)
+				assert.Nil(t, received)
+				if len(tt.globs) > 0 {
+					assert.ErrorIs(t, resp.Error, path.ErrBadPattern)
+				}
+				return
+			}
+			require.NoError(t, resp.Error)
+			assert.Equal(t, tt.expected, received)
+		})
+	}
+}
+
+func TestMultipartFilesFromDir_Progress(t *testing.T) {
+	dir := newUploadDir(t)
+
+	var reports []DirUploadProgress
+	fields, err := MultipartFilesFromDir("files", dir, func(o *DirFilesOptions) {
+		o.Patterns = []string{"*.txt"}
+		o.ProgressCallback = func(p DirUploadProgress) { reports = append(reports, p) }
+	})
+	require.NoError(t, err)
+	require.Len(t, fields, 3)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	defer server.Close()
+
+	// The second request replays the files; the progress starts over.
+	for range 2 {
+		reports = nil
+		resp := NewDispatcher(nil).NewRequest().Multipart(fields).Post(server.URL)
+		require.NoError(t, resp.Error)
+		resp.Close()
+
+		require.NotEmpty(t, reports)
+		last := reports[len(reports)-1]
+		assert.Equal(t, DirUploadProgress{Files: 3, FilesDone: 3, TotalSize: 14, Written: 14, Current: "sub/b.txt"}, last)
+		for _, report := range reports {
+			assert.LessOrEqual(t, report.FilesDone, 3)
+		}
+	}
+	assert.Equal(t, "link-in.txt", fields[1].FileName)
+}
@@ -2,6 +2,7 @@ package fetch
 
 import (
 	"bytes"
+	"compress/gzip"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -346,3 +347,134 @@ func TestMultipartWithGetReaderError(t *testing.T) {
 		})
 	}
 }
+
+func TestMultipartCompressPart(t *testing.T) {
+	content := strings.Repeat("log line\n", 200)
+
+	var partEncoding string
+	var decoded []byte
+	var compressedSize int64
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mr, err := r.MultipartReader()
+		require.NoError(t, err)
+
+		part, err := mr.NextPart()
+		require.NoError(t, err)
+		partEncoding = part.Header.Get("Content-Encoding")
+
+		raw, err := io.ReadAll(part)
+		require.NoError(t, err)
+		compressedSize = int64(len(raw))
+
+		zr, err := gzip.NewReader(bytes.NewReader(raw))
+		require.NoError(t, err)
+		decoded, err = io.ReadAll(zr)
+		require.NoError(t, err)
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var lastProgress MultipartFieldProgress
+	field := &MultipartField{
+		Name:         "log",
+		FileName:     "app.log",
+		ContentType:  "text/plain",
+		FileSize:     int64(len(content)),
+		CompressPart: true,
+		GetReader: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(content)), nil
+		},
+		ProgressCallback: func(p MultipartFieldProgress) { lastProgress = p },
+	}
+
+	handler := Multipart([]*MultipartField{field})(HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = body
+		return client.Do(req)
+	}))
+
+	req, err := http.NewRequest("POST", server.URL, nil)
+	require.NoError(t, err)
+
+	resp, err := handler.Handle(&http.Client{}, req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, "gzip", partEncoding)
+	assert.Equal(t, content, string(decoded))
+	assert.Equal(t, int64(len(content)), lastProgress.Written, "progress should count the bytes read")
+	assert.Positive(t, lastProgress.CompressedWritten)
+	assert.Less(t, lastProgress.CompressedWritten, int64(len(content)), "progress should count compressed bytes")
+	assert.Equal(t, compressedSize, lastProgress.CompressedWritten)
+}
+
+func TestMultipartOrder(t *testing.T) {
+	file := func(name string) *MultipartField {
+		return &MultipartField{
+			Name:        name,
+			FileName:    name + ".txt",
+			ContentType: "text/plain",
+			GetReader: func() (io.ReadCloser, error) {
+				return io.NopCloser(strings.NewReader(name)), nil
+			},
+		}
+	}
+	fields := []*MultipartField{
+		{Name: "tag", Values: []string{"a", "b"}},
+		file("first"),
+		{Name: "title", Values: []string{"t"}},
+		file("second"),
+		{Name: "tag", Values: []string{"c"}},
+	}
+
+	tests := []struct {
+		name     string
+		order    MultipartOrder
+		fields   []*MultipartField
+		expected []string
+	}{
+		{name: "insertion order", order: MultipartOrderInsertion, fields: fields, expected: []string{"tag=a", "tag=b", "first", "title=t", "second", "tag=c"}},
+		{name: "fields first", order: MultipartOrderFieldsFirst, fields: fields, expected: []string{"tag=a", "tag=b", "title=t", "tag=c", "first", "second"}},
+		{name: "files first", order: MultipartOrderFilesFirst, fields: fields, expected: []string{"first", "second", "tag=a", "tag=b", "title=t", "tag=c"}},
+		{name: "form values sorted by name", fields: MultipartFormFields(map[string][]string{"z": {"1", "2"}, "a": {"3"}, "m": {"4"}}), expected: []string{"a=3", "m=4", "z=1", "z=2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 3 {
+				var parts []string
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					mr, err := r.MultipartReader()
+					require.NoError(t, err)
+					for {
+						part, err := mr.NextPart()
+						if err == io.EOF {
+							break
+						}
+						require.NoError(t, err)
+						content, _ := io.ReadAll(part)
+						if name := part.FormName(); name != "" {
+							parts = append(parts, name+"="+string(content))
+						} else {
+							parts = append(parts, part.Header.Get("Name"))
+						}
+					}
+				}))
+
+				resp := NewDispatcher(nil).NewRequest().Multipart(tt.fields, func(o *MultipartOptions) {
+					o.Order = tt.order
+				}).Post(server.URL)
+				require.NoError(t, resp.Error)
+				resp.Close()
+				server.Close()
+
+				assert.Equal(t, tt.expected, parts)
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package fetch
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrPartialFailure is matched by errors.Is for every PartialFailureError.
+var ErrPartialFailure = errors.New("partial failure")
+
+// PartialFailureError reports the failed items of a multi-status response.
+type PartialFailureError struct {
+	Failed []MultiStatusItem
+	Total  int
+}
+
+// Error returns the error message.
+func (e *PartialFailureError) Error() string {
+	return fmt.Sprintf("%d of %d items failed", len(e.Failed), e.Total)
+}
+
+// Is reports whether target is ErrPartialFailure.
+func (e *PartialFailureError) Is(target error) bool {
+	return target == ErrPartialFailure
+}
+
+// MultiStatusItem is the result of a single operation of a batch.
+type MultiStatusItem struct {
+	// ID identifies the item: the href of a WebDAV response or the id of a JSON item.
+	ID         string
+	StatusCode int
+	// Body is the raw item payload: the WebDAV response element, or the body
+	// (or data) member of a JSON item.
+	Body []byte
+	// Message is the responsedescription or error message of the item, if any.
+	Message string
+	// Header holds the headers of a multipart part, or of the HTTP response
+	// embedded in it. It is nil for other formats.
+	Header http.Header
+
+	xml bool
+}
+
+// OK reports whether the item status is 2xx.
+func (i MultiStatusItem) OK() bool {
+	return i.StatusCode >= 200 && i.StatusCode <= 299
+}
+
+// Decode unmarshals the item body into v as XML when the item came from an
+// XML response or part, and as JSON otherwise. An empty body leaves v as is.
+func (i MultiStatusItem) Decode(v any) error {
+	if len(i.Body) == 0 {
+		return nil
+	}
+	if i.xml {
+		return xml.Unmarshal(i.Body, v)
+	}
+	return json.Unmarshal(i.Body, v)
+}
+
+// MultiStatus holds the per-item results of a batch response.
+type MultiStatus struct {
+	Items []MultiStatusItem
+}
+
+// Failed returns the items whose status is not 2xx.
+func (m *MultiStatus) Failed() []MultiStatusItem {
+	var failed []MultiStatusItem
+	for _, item := range m.Items {
+		if !item.OK() {
+			failed = append(failed, item)
+		}
+	}
+	return failed
+}
+
+// Err returns a PartialFailureError when any item failed, or nil.
+func (m *MultiStatus) Err() error {
+	if failed := m.Failed(); len(failed) > 0 {
+		return &PartialFailureError{Failed: failed, Total: len(m.Items)}
+	}
+	return nil
+}
+
+// MultiStatus parses a 207 Multi-Status or batch response into per-item results.
+// WebDAV multistatus XML is parsed when the Content-Type is XML; otherwise the
+// body is parsed as a JSON array of items or an object holding the items under
+// "responses", "results", "items" or "data". JSON items carry their status in
+// "status", "statusCode" or "code", their id in "id" or "href", their payload
+// in "body" or "data" and their message in "message" or "error".
+//
+// A multipart response, e.g. multipart/mixed from a batch API, yields an item
+// per part identified by its Content-ID. A part of type application/http
+// holds an embedded response providing the status, headers and body of the
+// item; other parts take the status of the whole response.
+//
+// Example:
+//
+//	batch, err := resp.MultiStatus()
+//	if err != nil {
+//	    return err
+//	}
+//	if err := batch.Err(); errors.Is(err, fetch.ErrPartialFailure) {
+//	    for _, item := range batch.Failed() {
+//	        log.Printf("%s failed: %d %s", item.ID, item.StatusCode, item.Message)
+//	    }
+//	}
+func (r *Response) MultiStatus() (*MultiStatus, error) {
+	if r.Error != nil {
+		return nil, r.Error
+	}
+
+	body := r.Bytes()
+	if r.Error != nil {
+		return nil, r.Error
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if mediaType, params, err := mime.ParseMediaType(contentType); err == nil && strings.HasPrefix(mediaType, "multipart/") {
+		return parseMultipartStatus(body, params["boundary"], r.RawResponse.StatusCode)
+	}
+	if strings.Contains(contentType, "xml") {
+		return parseDAVMultiStatus(body)
+	}
+	return parseJSONMultiStatus(body)
+}
+
+type davMultiStatus struct {
+	Responses []struct {
+		Href     []string `xml:"href"`
+		Status   string   `xml:"status"`
+		Propstat []struct {
+			Status string `xml:"status"`
+		} `xml:"propstat"`
+		Description string `xml:"responsedescription"`
+		Error       struct {
+			Inner string `xml:",innerxml"`
+		} `xml:"error"`
+		Inner []byte `xml:",innerxml"`
+	} `xml:"response"`
+}
+
+func parseDAVMultiStatus(body []byte) (*MultiStatus, error) {
+	var doc davMultiStatus
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("parse multistatus: %w", err)
+	}
+
+	result := &MultiStatus{Items: make([]MultiStatusItem, 0, len(doc.Responses))}
+	for _, resp := range doc.Responses {
+		item := MultiStatusItem{
+			ID:      strings.Join(resp.Href, " "),
+			Body:    append(append([]byte("<response>"), resp.Inner...), "</response>"...),
+			Message: strings.TrimSpace(resp.Description),
+			xml:     true,
+		}
+
+		// A response-level status applies to the whole resource; otherwise
+		// the worst propstat status represents the item.
+		if resp.Status != "" {
+			item.StatusCode = davStatusCode(resp.Status)
+		} else {
+			for _, propstat := range resp.Propstat {
+				item.StatusCode = max(item.StatusCode, davStatusCode(propstat.Status))
+			}
+		}
+		if item.Message == "" {
+			item.Message = strings.TrimSpace(resp.Error.Inner)
+		}
+
+		result.Items = append(result.Items, item)
+	}
+
+	return result, nil
+}
+
+// davStatusCode extracts the code of a status line such as "HTTP/1.1 404 Not Found".
+func davStatusCode(status string) int {
+	fields := strings.Fields(status)
+	if len(fields) < 2 {
+		return 0
+	}
+	code, _ := strconv.Atoi(fields[1])
+	return code
+}
+
+func parseJSONMultiStatus(body []byte) (*MultiStatus, error) {
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		var envelope map[string]json.RawMessage
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			return nil, fmt.Errorf("parse multistatus: %w", err)
+		}
+
+		found := false
+		for _, key := range []string{"responses", "results", "items", "data"} {
+			if raw, ok := envelope[key]; ok && json.Unmarshal(raw, &items) == nil {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.New("parse multistatus: no item list found")
+		}
+	}
+
+	result := &MultiStatus{Items: make([]MultiStatusItem, 0, len(items))}
+	for _, fields := range items {
+		item := MultiStatusItem{
+			ID:         jsonMemberString(fields, "id", "href"),
+			StatusCode: jsonMemberInt(fields, "status", "statusCode", "code"),
+			Message:    jsonMemberString(fields, "message", "error"),
+		}
+		for _, key := range []string{"body", "data"} {
+			if raw, ok := fields[key]; ok {
+				item.Body = raw
+				break
+			}
+		}
+		result.Items = append(result.Items, item)
+	}
+
+	return result, nil
+}
+
+// jsonMemberString returns the first present member as a string. Numbers are
+// kept as written and objects such as {"message": "..."} yield their message.
+func jsonMemberString(fields map[string]json.RawMessage, keys ...string) string {
+	for _, key := range keys {
+		raw, ok := fields[key]
+		if !ok {
+			continue
+		}
+
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			return s
+		}
+		var n json.Number
+		if json.Unmarshal(raw, &n) == nil {
+			return n.String()
+		}
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(raw, &obj) == nil {
+			return jsonMemberString(obj, "message")
+		}
+	}
+	return ""
+}
+
+// jsonMemberInt returns the first present member as an int, accepting numbers
+// and numeric strings.
+func jsonMemberInt(fields map[string]json.RawMessage, keys ...string) int {
+	for _, key := range keys {
+		raw, ok := fields[key]
+		if !ok {
+			continue
+		}
+
+		var n int
+		if json.Unmarshal(raw, &n) == nil {
+			return n
+		}
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			if n, err := strconv.Atoi(s); err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
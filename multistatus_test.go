@@ -0,0 +1,133 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseMultiStatus(t *testing.T) {
+	const davBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:multistatus xmlns:d="DAV:">
+  <d:response>
+    <d:href>/files/a.txt</d:href>
+    <d:status>HTTP/1.1 200 OK</d:status>
+  </d:response>
+  <d:response>
+    <d:href>/files/b.txt</d:href>
+    <d:propstat><d:prop><d:displayname/></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>
+    <d:propstat><d:prop><d:owner/></d:prop><d:status>HTTP/1.1 403 Forbidden</d:status></d:propstat>
+    <d:responsedescription>owner is protected</d:responsedescription>
+  </d:response>
+</d:multistatus>`
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantIDs     []string
+		wantStatus  []int
+		wantFailed  int
+		wantMessage string
+		wantErr     bool
+	}{
+		{
+			name:        "webdav",
+			contentType: "application/xml; charset=utf-8",
+			body:        davBody,
+			wantIDs:     []string{"/files/a.txt", "/files/b.txt"},
+			wantStatus:  []int{200, 403},
+			wantFailed:  1,
+			wantMessage: "owner is protected",
+		},
+		{
+			name:        "json envelope",
+			contentType: "application/json",
+			body:        `{"responses":[{"id":"1","status":201,"body":{"name":"a"}},{"id":2,"status":"409","error":{"message":"exists"}}]}`,
+			wantIDs:     []string{"1", "2"},
+			wantStatus:  []int{201, 409},
+			wantFailed:  1,
+			wantMessage: "exists",
+		},
+		{
+			name:        "json array",
+			contentType: "application/json",
+			body:        `[{"href":"/a","statusCode":200},{"href":"/b","code":204}]`,
+			wantIDs:     []string{"/a", "/b"},
+			wantStatus:  []int{200, 204},
+		},
+		{
+			name:        "unknown envelope",
+			contentType: "application/json",
+			body:        `{"things":[]}`,
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(http.StatusMultiStatus)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			resp := NewDispatcher(nil).NewRequest().Get(server.URL)
+			require.NoError(t, resp.Error)
+
+			batch, err := resp.MultiStatus()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var ids []string
+			var statuses []int
+			for _, item := range batch.Items {
+				ids = append(ids, item.ID)
+				statuses = append(statuses, item.StatusCode)
+			}
+			assert.Equal(t, tt.wantIDs, ids)
+			assert.Equal(t, tt.wantStatus, statuses)
+
+			if tt.wantFailed == 0 {
+				assert.NoError(t, batch.Err())
+				return
+			}
+
+			err = batch.Err()
+			assert.ErrorIs(t, err, ErrPartialFailure)
+			var partial *PartialFailureError
+			require.ErrorAs(t, err, &partial)
+			assert.Len(t, partial.Failed, tt.wantFailed)
+			assert.Equal(t, len(batch.Items), partial.Total)
+			assert.Equal(t, tt.wantMessage, partial.Failed[0].Message)
+		})
+	}
+}
+
+func TestMultiStatusItem_Decode(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		item := MultiStatusItem{Body: []byte(`{"name":"a"}`)}
+		var v struct{ Name string }
+		require.NoError(t, item.Decode(&v))
+		assert.Equal(t, "a", v.Name)
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		batch, err := parseDAVMultiStatus([]byte(`<multistatus xmlns="DAV:"><response><href>/a</href><status>HTTP/1.1 200 OK</status></response></multistatus>`))
+		require.NoError(t, err)
+		require.Len(t, batch.Items, 1)
+
+		var v struct {
+			Href string `xml:"href"`
+		}
+		require.NoError(t, batch.Items[0].Decode(&v))
+		assert.Equal(t, "/a", v.Href)
+	})
+}
@@ -0,0 +1,117 @@
+package oauthcli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DeviceCode is the response of the device authorization endpoint.
+type DeviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+// DeviceOptions configures LoginDevice.
+type DeviceOptions struct {
+	// Prompt shows the user code and verification URI. Defaults to printing
+	// them to Options.Output.
+	Prompt func(code *DeviceCode) error
+	// PollInterval overrides the polling interval. Zero uses the interval
+	// announced by the server, or 5s when it announces none.
+	PollInterval time.Duration
+}
+
+// LoginDevice runs the device authorization grant: the user enters a code on
+// another device while the token endpoint is polled until the login was
+// approved, denied or the code expired.
+func (a *Authenticator) LoginDevice(ctx context.Context, opts ...func(*DeviceOptions)) error {
+	options := &DeviceOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	form := url.Values{}
+	if len(a.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.config.Scopes, " "))
+	}
+
+	var code struct {
+		DeviceCode
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := a.postForm(ctx, a.config.DeviceAuthURL, form, &code)
+	if err != nil {
+		return err
+	}
+	if code.Error != "" || code.DeviceCode.DeviceCode == "" {
+		return &TokenError{StatusCode: status, Code: code.Error, Description: code.ErrorDescription}
+	}
+
+	if options.Prompt != nil {
+		err = options.Prompt(&code.DeviceCode)
+	} else {
+		err = a.showDeviceCode(&code.DeviceCode)
+	}
+	if err != nil {
+		return err
+	}
+
+	interval := options.PollInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+		if code.Interval > 0 {
+			interval = time.Duration(code.Interval) * time.Second
+		}
+	}
+	if code.ExpiresIn > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(code.ExpiresIn)*time.Second)
+		defer cancel()
+	}
+
+	for {
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return &TokenError{Code: "expired_token", Description: "the device code expired before the login was approved"}
+			}
+			return ctx.Err()
+		}
+
+		token, err := a.tokenRequest(ctx, url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"device_code": {code.DeviceCode.DeviceCode},
+		})
+		var tokenErr *TokenError
+		switch {
+		case err == nil:
+			return a.set(ctx, token)
+		case errors.As(err, &tokenErr) && tokenErr.Code == "authorization_pending":
+		case errors.As(err, &tokenErr) && tokenErr.Code == "slow_down":
+			interval += 5 * time.Second
+		default:
+			return err
+		}
+	}
+}
+
+func (a *Authenticator) showDeviceCode(code *DeviceCode) error {
+	if code.VerificationURIComplete != "" {
+		_, err := fmt.Fprintf(a.options.Output, "To log in, visit:\n\n  %s\n\nand confirm the code %s.\n", code.VerificationURIComplete, code.UserCode)
+		return err
+	}
+	_, err := fmt.Fprintf(a.options.Output, "To log in, visit:\n\n  %s\n\nand enter the code %s.\n", code.VerificationURI, code.UserCode)
+	return err
+}
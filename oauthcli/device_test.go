@@ -0,0 +1,49 @@
+package oauthcli
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginDevice(t *testing.T) {
+	server := newAuthServer(t)
+	var output bytes.Buffer
+	auth := New(server.config(), func(o *Options) { o.Output = &output })
+
+	err := auth.LoginDevice(context.Background(), func(o *DeviceOptions) {
+		o.PollInterval = time.Millisecond
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, output.String(), "ABCD-EFGH")
+	assert.Contains(t, output.String(), server.URL+"/activate")
+	assert.Equal(t, 3, server.devicePolls, "polls while authorization is pending")
+
+	token, err := auth.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "at-device", token.AccessToken)
+}
+
+func TestLoginDevice_Prompt(t *testing.T) {
+	server := newAuthServer(t)
+	auth := New(server.config())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var prompted *DeviceCode
+	err := auth.LoginDevice(ctx, func(o *DeviceOptions) {
+		o.Prompt = func(code *DeviceCode) error {
+			prompted = code
+			cancel()
+			return nil
+		}
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	require.NotNil(t, prompted)
+	assert.Equal(t, "ABCD-EFGH", prompted.UserCode)
+}
@@ -0,0 +1,367 @@
+// Package oauthcli implements OAuth 2.0 logins for command-line clients: the
+// authorization code flow with PKCE (RFC 7636) and the device authorization
+// grant (RFC 8628). The resulting tokens are refreshed automatically and
+// applied to requests through the fetch.Reauth middleware.
+package oauthcli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rockcookies/go-fetch"
+)
+
+// ErrLoginRequired is returned when no usable token is available and the
+// user has to log in again with LoginPKCE or LoginDevice.
+var ErrLoginRequired = errors.New("oauth login required")
+
+// Config describes the OAuth client and the authorization server endpoints.
+type Config struct {
+	ClientID string
+	// ClientSecret is sent with token requests when set. Public CLI clients
+	// usually have none.
+	ClientSecret  string
+	AuthURL       string
+	TokenURL      string
+	DeviceAuthURL string
+	Scopes        []string
+}
+
+// Token is an OAuth token set.
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+}
+
+// valid reports whether the access token can still be used at now, leaving
+// a margin for clock skew and request latency.
+func (t *Token) valid(now time.Time) bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || now.Add(30*time.Second).Before(t.Expiry))
+}
+
+// TokenError is an error response of the token or device authorization endpoint.
+type TokenError struct {
+	StatusCode  int
+	Code        string
+	Description string
+}
+
+// Error returns the error message.
+func (e *TokenError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("oauth: %s: %s", e.Code, e.Description)
+	}
+	if e.Code != "" {
+		return "oauth: " + e.Code
+	}
+	return fmt.Sprintf("oauth: token endpoint returned status %d", e.StatusCode)
+}
+
+// TokenStore persists tokens between runs, e.g. in the operating system's
+// keychain. Load returns nil without error when nothing is stored.
+type TokenStore interface {
+	Load(ctx context.Context) (*Token, error)
+	Save(ctx context.Context, token *Token) error
+}
+
+// FileStore returns a TokenStore keeping the token as JSON in a file only
+// readable by the current user.
+func FileStore(path string) TokenStore {
+	return fileStore(path)
+}
+
+type fileStore string
+
+func (s fileStore) Load(ctx context.Context) (*Token, error) {
+	data, err := os.ReadFile(string(s))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var token Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("oauth token store %s: %w", string(s), err)
+	}
+	return &token, nil
+}
+
+func (s fileStore) Save(ctx context.Context, token *Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(string(s)), 0o700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(string(s)), ".token-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), string(s))
+}
+
+// Options configures an Authenticator.
+type Options struct {
+	// Dispatcher sends the requests to the authorization server. It must not
+	// use the Authenticator's own middleware. Defaults to fetch.NewDispatcher(nil).
+	Dispatcher *fetch.Dispatcher
+	// Store persists tokens. When nil tokens only live in memory.
+	Store TokenStore
+	// Output receives the instructions shown to the user. Defaults to os.Stderr.
+	Output io.Writer
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Authenticator logs a user in and supplies its tokens to requests. It
+// implements fetch.AuthOrchestrator and is safe for concurrent use.
+type Authenticator struct {
+	config  Config
+	options *Options
+
+	mu    sync.Mutex
+	token *Token
+}
+
+var (
+	_ fetch.AuthOrchestrator = (*Authenticator)(nil)
+	_ fetch.AuthState        = (*Authenticator)(nil)
+)
+
+// New creates an Authenticator for config.
+//
+// Example:
+//
+//	auth := oauthcli.New(oauthcli.Config{
+//	    ClientID:      "my-cli",
+//	    AuthURL:       "https://auth.example.com/authorize",
+//	    TokenURL:      "https://auth.example.com/token",
+//	    DeviceAuthURL: "https://auth.example.com/device",
+//	    Scopes:        []string{"read", "offline_access"},
+//	}, func(o *oauthcli.Options) {
+//	    o.Store = oauthcli.FileStore(filepath.Join(configDir, "token.json"))
+//	})
+//	if _, err := auth.Token(ctx); errors.Is(err, oauthcli.ErrLoginRequired) {
+//	    err = auth.LoginPKCE(ctx)
+//	}
+//	dispatcher.Use(auth.Middleware())
+func New(config Config, opts ...func(*Options)) *Authenticator {
+	options := &Options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	if options.Dispatcher == nil {
+		options.Dispatcher = fetch.NewDispatcher(nil)
+	}
+	if options.Output == nil {
+		options.Output = os.Stderr
+	}
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+
+	return &Authenticator{config: config, options: options}
+}
+
+// Middleware returns the fetch.Reauth middleware authorizing requests with
+// the Authenticator's tokens and refreshing them when the server rejects one.
+func (a *Authenticator) Middleware(opts ...func(*fetch.AuthOptions)) fetch.Middleware {
+	return fetch.Reauth(a, opts...)
+}
+
+// Token returns a valid token, loading it from the store and refreshing it
+// when expired. It fails with ErrLoginRequired when the user has to log in.
+func (a *Authenticator) Token(ctx context.Context) (*Token, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token == nil && a.options.Store != nil {
+		token, err := a.options.Store.Load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		a.token = token
+	}
+	if a.token.valid(a.options.Now()) {
+		return a.token, nil
+	}
+	if err := a.refreshLocked(ctx); err != nil {
+		return nil, err
+	}
+	return a.token, nil
+}
+
+// Authorize sets the Authorization header of req.
+func (a *Authenticator) Authorize(req *http.Request) error {
+	token, err := a.Token(req.Context())
+	if err != nil {
+		return err
+	}
+
+	tokenType := token.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	req.Header.Set("Authorization", tokenType+" "+token.AccessToken)
+	return nil
+}
+
+// Reauthenticate refreshes the token after the server rejected it.
+func (a *Authenticator) Reauthenticate(ctx context.Context, resp *http.Response) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.refreshLocked(ctx)
+}
+
+// Logout forgets the token, also in the store.
+func (a *Authenticator) Logout(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.token = nil
+	if a.options.Store != nil {
+		return a.options.Store.Save(ctx, &Token{})
+	}
+	return nil
+}
+
+// MarshalAuthState returns the current token as JSON for fetch.ExportState.
+func (a *Authenticator) MarshalAuthState() ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return json.Marshal(a.token)
+}
+
+// UnmarshalAuthState restores a token returned by MarshalAuthState for
+// fetch.ImportState. The token is kept in memory; the store is not written.
+func (a *Authenticator) UnmarshalAuthState(data []byte) error {
+	var token *Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return fmt.Errorf("oauth auth state: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.token = token
+	return nil
+}
+
+func (a *Authenticator) refreshLocked(ctx context.Context) error {
+	if a.token == nil || a.token.RefreshToken == "" {
+		return ErrLoginRequired
+	}
+
+	token, err := a.tokenRequest(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {a.token.RefreshToken},
+	})
+	var tokenErr *TokenError
+	if errors.As(err, &tokenErr) && tokenErr.Code == "invalid_grant" {
+		return fmt.Errorf("%w: %w", ErrLoginRequired, err)
+	}
+	if err != nil {
+		return err
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = a.token.RefreshToken
+	}
+	return a.setLocked(ctx, token)
+}
+
+// set stores a token obtained by a login.
+func (a *Authenticator) set(ctx context.Context, token *Token) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.setLocked(ctx, token)
+}
+
+func (a *Authenticator) setLocked(ctx context.Context, token *Token) error {
+	a.token = token
+	if a.options.Store != nil {
+		return a.options.Store.Save(ctx, token)
+	}
+	return nil
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// tokenRequest posts form to the token endpoint, adding the client credentials.
+func (a *Authenticator) tokenRequest(ctx context.Context, form url.Values) (*Token, error) {
+	var body tokenResponse
+	status, err := a.postForm(ctx, a.config.TokenURL, form, &body)
+	if err != nil {
+		return nil, err
+	}
+	if body.Error != "" || status != http.StatusOK || body.AccessToken == "" {
+		return nil, &TokenError{StatusCode: status, Code: body.Error, Description: body.ErrorDescription}
+	}
+
+	token := &Token{AccessToken: body.AccessToken, TokenType: body.TokenType, RefreshToken: body.RefreshToken}
+	if body.ExpiresIn > 0 {
+		token.Expiry = a.options.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+func (a *Authenticator) postForm(ctx context.Context, endpoint string, form url.Values, v any) (int, error) {
+	form.Set("client_id", a.config.ClientID)
+	if a.config.ClientSecret != "" {
+		form.Set("client_secret", a.config.ClientSecret)
+	}
+
+	resp := a.options.Dispatcher.NewRequest().
+		Form(form).
+		UseFuncs(func(req *http.Request) {
+			*req = *req.WithContext(ctx)
+			req.Header.Set("Accept", "application/json")
+		}).
+		Post(endpoint)
+	if resp.Error != nil {
+		return 0, resp.Error
+	}
+	defer resp.Close()
+
+	if err := resp.JSON(v); err != nil {
+		return resp.RawResponse.StatusCode, &TokenError{StatusCode: resp.RawResponse.StatusCode, Description: err.Error()}
+	}
+	return resp.RawResponse.StatusCode, nil
+}
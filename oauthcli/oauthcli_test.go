@@ -0,0 +1,199 @@
+package oauthcli
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rockcookies/go-fetch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// authServer is a minimal authorization server for the tests.
+type authServer struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	challenge    string
+	devicePolls  int
+	refreshCalls int
+}
+
+func newAuthServer(t *testing.T) *authServer {
+	s := &authServer{}
+	mux := http.NewServeMux()
+
+	writeJSON := func(w http.ResponseWriter, status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "cli", r.PostForm.Get("client_id"))
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		switch r.PostForm.Get("grant_type") {
+		case "authorization_code":
+			sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+			if r.PostForm.Get("code") != "the-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != s.challenge {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"access_token": "at-1", "token_type": "bearer", "refresh_token": "rt-1", "expires_in": 3600})
+		case "refresh_token":
+			s.refreshCalls++
+			if r.PostForm.Get("refresh_token") != "rt-1" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "refresh token revoked"})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"access_token": "at-2", "expires_in": 3600})
+		case "urn:ietf:params:oauth:grant-type:device_code":
+			s.devicePolls++
+			switch {
+			case r.PostForm.Get("device_code") != "dc":
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			case s.devicePolls < 3:
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "authorization_pending"})
+			default:
+				writeJSON(w, http.StatusOK, map[string]any{"access_token": "at-device", "refresh_token": "rt-1"})
+			}
+		}
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, DeviceCode{DeviceCode: "dc", UserCode: "ABCD-EFGH", VerificationURI: s.URL + "/activate", ExpiresIn: 60})
+	})
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer at-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		io.WriteString(w, "secret")
+	})
+
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *authServer) config() Config {
+	return Config{
+		ClientID:      "cli",
+		AuthURL:       s.URL + "/authorize",
+		TokenURL:      s.URL + "/token",
+		DeviceAuthURL: s.URL + "/device",
+		Scopes:        []string{"read", "offline_access"},
+	}
+}
+
+func TestAuthenticator_Middleware(t *testing.T) {
+	server := newAuthServer(t)
+	store := FileStore(filepath.Join(t.TempDir(), "nested", "token.json"))
+	require.NoError(t, store.Save(context.Background(), &Token{AccessToken: "stale", RefreshToken: "rt-1", Expiry: time.Now().Add(time.Hour)}))
+
+	auth := New(server.config(), func(o *Options) {
+		o.Store = store
+		o.Output = io.Discard
+	})
+
+	resp := fetch.NewDispatcher(nil, auth.Middleware()).NewRequest().Get(server.URL + "/api")
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "secret", resp.String())
+
+	saved, err := store.Load(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "at-2", saved.AccessToken)
+	assert.Equal(t, "rt-1", saved.RefreshToken, "refresh token kept when the server sends none")
+}
+
+func TestAuthenticator_Token(t *testing.T) {
+	server := newAuthServer(t)
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		stored      *Token
+		wantToken   string
+		wantErr     error
+		wantRefresh int
+	}{
+		{name: "nothing stored", wantErr: ErrLoginRequired},
+		{name: "valid token used as is", stored: &Token{AccessToken: "at-1", Expiry: now.Add(time.Hour)}, wantToken: "at-1"},
+		{name: "token without expiry", stored: &Token{AccessToken: "at-1"}, wantToken: "at-1"},
+		{name: "expired token refreshed", stored: &Token{AccessToken: "at-1", RefreshToken: "rt-1", Expiry: now.Add(-time.Minute)}, wantToken: "at-2", wantRefresh: 1},
+		{name: "token about to expire refreshed", stored: &Token{AccessToken: "at-1", RefreshToken: "rt-1", Expiry: now.Add(10 * time.Second)}, wantToken: "at-2", wantRefresh: 1},
+		{name: "expired without refresh token", stored: &Token{AccessToken: "at-1", Expiry: now.Add(-time.Minute)}, wantErr: ErrLoginRequired},
+		{name: "revoked refresh token", stored: &Token{AccessToken: "at-1", RefreshToken: "revoked", Expiry: now.Add(-time.Minute)}, wantErr: ErrLoginRequired, wantRefresh: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.mu.Lock()
+			server.refreshCalls = 0
+			server.mu.Unlock()
+
+			store := FileStore(filepath.Join(t.TempDir(), "token.json"))
+			if tt.stored != nil {
+				require.NoError(t, store.Save(context.Background(), tt.stored))
+			}
+			auth := New(server.config(), func(o *Options) {
+				o.Store = store
+				o.Now = func() time.Time { return now }
+			})
+
+			token, err := auth.Token(context.Background())
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantToken, token.AccessToken)
+			}
+			assert.Equal(t, tt.wantRefresh, server.refreshCalls)
+		})
+	}
+}
+
+func TestAuthenticator_Logout(t *testing.T) {
+	store := FileStore(filepath.Join(t.TempDir(), "token.json"))
+	require.NoError(t, store.Save(context.Background(), &Token{AccessToken: "at-1"}))
+
+	auth := New(Config{ClientID: "cli"}, func(o *Options) { o.Store = store })
+	_, err := auth.Token(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, auth.Logout(context.Background()))
+	_, err = auth.Token(context.Background())
+	assert.ErrorIs(t, err, ErrLoginRequired)
+}
+
+func TestAuthenticator_AuthState(t *testing.T) {
+	auth := New(Config{ClientID: "cli"})
+	require.NoError(t, auth.set(context.Background(), &Token{AccessToken: "at-1", RefreshToken: "rt-1"}))
+
+	var state bytes.Buffer
+	require.NoError(t, fetch.ExportState(&state, func(o *fetch.StateOptions) {
+		o.Auth = map[string]fetch.AuthState{"api": auth}
+	}))
+
+	restored := New(Config{ClientID: "cli"})
+	require.NoError(t, fetch.ImportState(&state, func(o *fetch.StateOptions) {
+		o.Auth = map[string]fetch.AuthState{"api": restored}
+	}))
+	token, err := restored.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "at-1", token.AccessToken)
+	assert.Equal(t, "rt-1", token.RefreshToken)
+}
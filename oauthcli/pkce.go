@@ -0,0 +1,211 @@
+package oauthcli
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// OutOfBandRedirectURL is the redirect URI asking the authorization server
+// to display the code instead of redirecting.
+const OutOfBandRedirectURL = "urn:ietf:wg:oauth:2.0:oob"
+
+// PKCEOptions configures LoginPKCE.
+type PKCEOptions struct {
+	// ListenAddr is the loopback address of the callback listener.
+	// Defaults to 127.0.0.1:0, i.e. a random port.
+	ListenAddr string
+	// CallbackPath is the path of the redirect URI. Defaults to /callback.
+	CallbackPath string
+	// OpenBrowser opens the authorization URL. When nil the URL is only
+	// printed to Options.Output for the user to open.
+	OpenBrowser func(authURL string) error
+	// OutOfBand skips the callback listener: the user pastes the code shown
+	// by the authorization server, read with ReadCode.
+	OutOfBand bool
+	// RedirectURL is the redirect URI of the out-of-band flow.
+	// Defaults to OutOfBandRedirectURL.
+	RedirectURL string
+	// ReadCode reads the pasted code. Defaults to reading a line from os.Stdin.
+	ReadCode func(ctx context.Context) (string, error)
+}
+
+// LoginPKCE runs the authorization code flow with PKCE: the user authorizes
+// the client in a browser and the code is received on a loopback callback
+// listener, or pasted in the out-of-band mode, then exchanged for a token.
+func (a *Authenticator) LoginPKCE(ctx context.Context, opts ...func(*PKCEOptions)) error {
+	options := &PKCEOptions{
+		ListenAddr:   "127.0.0.1:0",
+		CallbackPath: "/callback",
+		RedirectURL:  OutOfBandRedirectURL,
+		ReadCode:     readLine(os.Stdin),
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	verifier, err := randomString(32)
+	if err != nil {
+		return err
+	}
+	state, err := randomString(16)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	var (
+		code        string
+		redirectURL string
+	)
+	if options.OutOfBand {
+		redirectURL = options.RedirectURL
+		if err := a.showAuthURL(a.authURL(redirectURL, state, challenge), options); err != nil {
+			return err
+		}
+		if code, err = options.ReadCode(ctx); err != nil {
+			return err
+		}
+		code = strings.TrimSpace(code)
+	} else {
+		listener, err := net.Listen("tcp", options.ListenAddr)
+		if err != nil {
+			return err
+		}
+		defer listener.Close()
+
+		redirectURL = "http://" + listener.Addr().String() + options.CallbackPath
+		codes := make(chan callbackResult, 1)
+		server := &http.Server{Handler: callbackHandler(options.CallbackPath, state, codes)}
+		go server.Serve(listener)
+		defer func() {
+			// Let the browser receive the result page before stopping.
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+		}()
+
+		if err := a.showAuthURL(a.authURL(redirectURL, state, challenge), options); err != nil {
+			return err
+		}
+
+		select {
+		case result := <-codes:
+			if result.err != nil {
+				return result.err
+			}
+			code = result.code
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	token, err := a.tokenRequest(ctx, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURL},
+		"code_verifier": {verifier},
+	})
+	if err != nil {
+		return err
+	}
+	return a.set(ctx, token)
+}
+
+func (a *Authenticator) authURL(redirectURL, state, challenge string) string {
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {a.config.ClientID},
+		"redirect_uri":          {redirectURL},
+		"state":                 {state},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+	if len(a.config.Scopes) > 0 {
+		query.Set("scope", strings.Join(a.config.Scopes, " "))
+	}
+
+	separator := "?"
+	if strings.Contains(a.config.AuthURL, "?") {
+		separator = "&"
+	}
+	return a.config.AuthURL + separator + query.Encode()
+}
+
+func (a *Authenticator) showAuthURL(authURL string, options *PKCEOptions) error {
+	if options.OpenBrowser != nil {
+		if err := options.OpenBrowser(authURL); err == nil {
+			fmt.Fprintf(a.options.Output, "Continue the login in your browser. If it did not open, visit:\n\n  %s\n\n", authURL)
+			return nil
+		}
+	}
+	fmt.Fprintf(a.options.Output, "Open this URL in your browser to log in:\n\n  %s\n\n", authURL)
+	if options.OutOfBand {
+		fmt.Fprint(a.options.Output, "Then paste the code shown: ")
+	}
+	return nil
+}
+
+type callbackResult struct {
+	code string
+	err  error
+}
+
+func callbackHandler(path, state string, results chan<- callbackResult) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		var result callbackResult
+		switch {
+		case query.Get("state") != state:
+			http.Error(w, "Login failed: state mismatch.", http.StatusBadRequest)
+			return
+		case query.Get("error") != "":
+			result.err = &TokenError{Code: query.Get("error"), Description: query.Get("error_description")}
+			http.Error(w, "Login failed: "+result.err.Error(), http.StatusBadRequest)
+		case query.Get("code") == "":
+			result.err = errors.New("oauth: callback without code")
+			http.Error(w, "Login failed: no code received.", http.StatusBadRequest)
+		default:
+			result.code = query.Get("code")
+			io.WriteString(w, "Login complete. You can close this window.\n")
+		}
+
+		select {
+		case results <- result:
+		default:
+		}
+	})
+	return mux
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func readLine(r io.Reader) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		line, err := bufio.NewReader(r).ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+		return line, nil
+	}
+}
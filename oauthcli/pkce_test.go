@@ -0,0 +1,109 @@
+package oauthcli
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginPKCE(t *testing.T) {
+	tests := []struct {
+		name       string
+		outOfBand  bool
+		callback   func(query url.Values) url.Values
+		wantErr    string
+		wantBrowse string
+	}{
+		{name: "loopback callback", wantBrowse: "Login complete"},
+		{name: "out of band code", outOfBand: true},
+		{name: "authorization denied", callback: func(q url.Values) url.Values {
+			return url.Values{"state": {q.Get("state")}, "error": {"access_denied"}}
+		}, wantErr: "oauth: access_denied", wantBrowse: "Login failed"},
+		{name: "wrong code rejected by token endpoint", callback: func(q url.Values) url.Values {
+			return url.Values{"state": {q.Get("state")}, "code": {"forged"}}
+		}, wantErr: "invalid_grant"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newAuthServer(t)
+			var output bytes.Buffer
+			auth := New(server.config(), func(o *Options) { o.Output = &output })
+
+			var authQuery url.Values
+			capture := func(authURL string) {
+				u, err := url.Parse(authURL)
+				require.NoError(t, err)
+				authQuery = u.Query()
+				server.mu.Lock()
+				server.challenge = authQuery.Get("code_challenge")
+				server.mu.Unlock()
+			}
+
+			browserPages := make(chan string, 1)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			err := auth.LoginPKCE(ctx, func(o *PKCEOptions) {
+				o.OutOfBand = tt.outOfBand
+				o.OpenBrowser = func(authURL string) error {
+					capture(authURL)
+					if tt.outOfBand {
+						return nil
+					}
+
+					callback := url.Values{"state": {authQuery.Get("state")}, "code": {"the-code"}}
+					if tt.callback != nil {
+						callback = tt.callback(authQuery)
+					}
+					go func() {
+						resp, err := http.Get(authQuery.Get("redirect_uri") + "?" + callback.Encode())
+						if err != nil {
+							browserPages <- err.Error()
+							return
+						}
+						body, _ := io.ReadAll(resp.Body)
+						resp.Body.Close()
+						browserPages <- string(body)
+					}()
+					return nil
+				}
+				o.ReadCode = func(context.Context) (string, error) { return "the-code\n", nil }
+			})
+
+			assert.Equal(t, "S256", authQuery.Get("code_challenge_method"))
+			assert.Equal(t, "read offline_access", authQuery.Get("scope"))
+			assert.Contains(t, output.String(), server.URL+"/authorize?")
+			if tt.outOfBand {
+				assert.Equal(t, OutOfBandRedirectURL, authQuery.Get("redirect_uri"))
+			} else {
+				assert.True(t, strings.HasPrefix(authQuery.Get("redirect_uri"), "http://127.0.0.1:"))
+			}
+
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				token, err := auth.Token(context.Background())
+				require.NoError(t, err)
+				assert.Equal(t, "at-1", token.AccessToken)
+			}
+			if tt.wantBrowse != "" {
+				select {
+				case page := <-browserPages:
+					assert.Contains(t, page, tt.wantBrowse)
+				case <-time.After(time.Second):
+					t.Fatal("browser did not receive a result page")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PACEvaluator evaluates the FindProxyForURL function of a proxy
+// auto-configuration script. Implementations typically wrap a JavaScript
+// engine loaded with the script returned by LoadPAC.
+type PACEvaluator interface {
+	FindProxyForURL(u *url.URL, host string) (string, error)
+}
+
+// PACEvaluatorFunc is an adapter to allow ordinary functions to be used as PACEvaluator.
+type PACEvaluatorFunc func(u *url.URL, host string) (string, error)
+
+// FindProxyForURL calls the underlying function.
+func (f PACEvaluatorFunc) FindProxyForURL(u *url.URL, host string) (string, error) {
+	return f(u, host)
+}
+
+// PACOptions configures PAC based proxy selection.
+type PACOptions struct {
+	// CacheTTL is how long the decision for a host is reused. Defaults to
+	// 5 minutes; a negative value disables caching.
+	CacheTTL time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// LoadPAC reads a PAC script from an http(s) URL, a file:// URL or a local path.
+// A nil client uses http.DefaultClient.
+func LoadPAC(ctx context.Context, client *http.Client, location string) (string, error) {
+	u, err := url.Parse(location)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		path := location
+		if err == nil && u.Scheme == "file" {
+			path = u.Path
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("load pac: %w", err)
+		}
+		return string(data), nil
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return "", fmt.Errorf("load pac: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("load pac: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("load pac: %s: unexpected status %d", location, resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("load pac: %w", err)
+	}
+	return string(data), nil
+}
+
+// ParsePACResult parses a FindProxyForURL result such as
+// "PROXY a:8080; SOCKS5 b:1080; DIRECT" into proxy URLs in order of preference.
+// DIRECT is represented by a nil URL.
+func ParsePACResult(result string) ([]*url.URL, error) {
+	var proxies []*url.URL
+
+	for _, entry := range strings.Split(result, ";") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+
+		kind := strings.ToUpper(fields[0])
+		if kind == "DIRECT" {
+			proxies = append(proxies, nil)
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid pac entry %q", strings.TrimSpace(entry))
+		}
+
+		var scheme string
+		switch kind {
+		case "PROXY", "HTTP":
+			scheme = "http"
+		case "HTTPS":
+			scheme = "https"
+		case "SOCKS", "SOCKS5":
+			scheme = "socks5"
+		default:
+			return nil, fmt.Errorf("unsupported pac entry type %q", fields[0])
+		}
+		proxies = append(proxies, &url.URL{Scheme: scheme, Host: fields[1]})
+	}
+
+	return proxies, nil
+}
+
+// maxPACDecisions bounds the cached decisions of PACProxy. Expired decisions
+// are dropped first when it is reached.
+const maxPACDecisions = 1024
+
+type pacDecision struct {
+	proxy   *url.URL
+	expires time.Time
+}
+
+// PACProxy returns a function suitable for http.Transport.Proxy that routes
+// each request through the first proxy returned by the PAC evaluator.
+// Decisions are cached per host for PACOptions.CacheTTL, for at most 1024
+// hosts. The transport
+// cannot fail over, so later entries of the PAC result are ignored.
+func PACProxy(evaluator PACEvaluator, opts ...func(*PACOptions)) func(*http.Request) (*url.URL, error) {
+	options := applyOptions(&PACOptions{
+		CacheTTL: 5 * time.Minute,
+		Now:      time.Now,
+	}, opts...)
+
+	var (
+		mu    sync.Mutex
+		cache = map[string]pacDecision{}
+	)
+
+	return func(req *http.Request) (*url.URL, error) {
+		host := req.URL.Hostname()
+		now := options.Now()
+
+		mu.Lock()
+		decision, ok := cache[host]
+		mu.Unlock()
+		if ok && now.Before(decision.expires) {
+			return decision.proxy, nil
+		}
+
+		result, err := evaluator.FindProxyForURL(req.URL, host)
+		if err != nil {
+			return nil, fmt.Errorf("pac: %w", err)
+		}
+		proxies, err := ParsePACResult(result)
+		if err != nil {
+			return nil, fmt.Errorf("pac: %w", err)
+		}
+
+		var proxy *url.URL
+		if len(proxies) > 0 {
+			proxy = proxies[0]
+		}
+
+		if options.CacheTTL > 0 {
+			mu.Lock()
+			if len(cache) >= maxPACDecisions {
+				evictPACDecisions(cache, now)
+			}
+			cache[host] = pacDecision{proxy: proxy, expires: now.Add(options.CacheTTL)}
+			mu.Unlock()
+		}
+
+		return proxy, nil
+	}
+}
+
+// evictPACDecisions removes the expired decisions of cache, and arbitrary
+// ones while it is still full.
+func evictPACDecisions(cache map[string]pacDecision, now time.Time) {
+	for host, decision := range cache {
+		if !now.Before(decision.expires) {
+			delete(cache, host)
+		}
+	}
+	for host := range cache {
+		if len(cache) < maxPACDecisions {
+			return
+		}
+		delete(cache, host)
+	}
+}
+
+// SetPAC returns a client option that selects the proxy of every request with
+// the PAC evaluator.
+//
+// Example:
+//
+//	script, err := fetch.LoadPAC(ctx, nil, "http://wpad.corp/proxy.pac")
+//	evaluator := newJSEvaluator(script) // e.g. backed by a JavaScript engine
+//	dispatcher.Use(fetch.SetClientOptions(fetch.SetPAC(evaluator)), fetch.PrepareClientMiddleware())
+func SetPAC(evaluator PACEvaluator, opts ...func(*PACOptions)) func(*http.Client) {
+	proxy := PACProxy(evaluator, opts...)
+
+	var transports transportCache
+
+	return func(c *http.Client) {
+		transports.apply(c, func(transport *http.Transport) {
+			transport.Proxy = proxy
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePACResult(t *testing.T) {
+	tests := []struct {
+		result   string
+		expected []string
+		wantErr  bool
+	}{
+		{result: "DIRECT", expected: []string{""}},
+		{result: "PROXY a:8080; DIRECT", expected: []string{"http://a:8080", ""}},
+		{result: "HTTPS a:443;SOCKS5 b:1080 ; SOCKS c:1080", expected: []string{"https://a:443", "socks5://b:1080", "socks5://c:1080"}},
+		{result: "", expected: nil},
+		{result: "PROXY", wantErr: true},
+		{result: "QUIC a:443", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.result, func(t *testing.T) {
+			proxies, err := ParsePACResult(tt.result)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var got []string
+			for _, p := range proxies {
+				if p == nil {
+					got = append(got, "")
+				} else {
+					got = append(got, p.String())
+				}
+			}
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestPACProxy_Cache(t *testing.T) {
+	var calls atomic.Int32
+	evaluator := PACEvaluatorFunc(func(u *url.URL, host string) (string, error) {
+		calls.Add(1)
+		if strings.HasSuffix(host, ".internal") {
+			return "DIRECT", nil
+		}
+		return "PROXY proxy:3128; DIRECT", nil
+	})
+
+	now := time.Unix(0, 0)
+	proxy := PACProxy(evaluator, func(o *PACOptions) {
+		o.CacheTTL = time.Minute
+		o.Now = func() time.Time { return now }
+	})
+
+	resolve := func(raw string) *url.URL {
+		req, _ := http.NewRequest(http.MethodGet, raw, nil)
+		u, err := proxy(req)
+		require.NoError(t, err)
+		return u
+	}
+
+	assert.Equal(t, "http://proxy:3128", resolve("https://example.com/a").String())
+	assert.Nil(t, resolve("http://db.internal/"))
+	assert.Equal(t, "http://proxy:3128", resolve("https://example.com/b").String())
+	assert.Equal(t, int32(2), calls.Load())
+
+	now = now.Add(2 * time.Minute)
+	resolve("https://example.com/c")
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestEvictPACDecisions(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache := map[string]pacDecision{}
+	for i := range maxPACDecisions {
+		expires := now.Add(time.Minute)
+		if i%2 == 0 {
+			expires = now
+		}
+		cache[strconv.Itoa(i)] = pacDecision{expires: expires}
+	}
+
+	evictPACDecisions(cache, now)
+	assert.Len(t, cache, maxPACDecisions/2, "expired decisions should be dropped")
+	for _, decision := range cache {
+		assert.True(t, now.Before(decision.expires))
+	}
+
+	for i := range maxPACDecisions {
+		cache["fresh"+strconv.Itoa(i)] = pacDecision{expires: now.Add(time.Minute)}
+	}
+	evictPACDecisions(cache, now)
+	assert.Len(t, cache, maxPACDecisions-1)
+}
+
+func TestSetPAC(t *testing.T) {
+	var proxied atomic.Bool
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied.Store(true)
+		w.Write([]byte("via proxy " + r.URL.Host))
+	}))
+	defer proxy.Close()
+
+	evaluator := PACEvaluatorFunc(func(u *url.URL, host string) (string, error) {
+		return "PROXY " + strings.TrimPrefix(proxy.URL, "http://"), nil
+	})
+
+	dispatcher := NewDispatcher(nil, SetClientOptions(SetPAC(evaluator)), PrepareClientMiddleware())
+	resp := dispatcher.NewRequest().Get("http://origin.example/")
+	defer resp.Close()
+
+	require.NoError(t, resp.Error)
+	assert.True(t, proxied.Load())
+	assert.Equal(t, "via proxy origin.example", resp.String())
+}
+
+func TestLoadPAC(t *testing.T) {
+	const script = `function FindProxyForURL(url, host) { return "DIRECT"; }`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/proxy.pac" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(script))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "proxy.pac")
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o600))
+
+	tests := []struct {
+		name     string
+		location string
+		wantErr  bool
+	}{
+		{name: "http", location: server.URL + "/proxy.pac"},
+		{name: "http not found", location: server.URL + "/missing.pac", wantErr: true},
+		{name: "path", location: path},
+		{name: "file url", location: "file://" + path},
+		{name: "missing file", location: filepath.Join(t.TempDir(), "missing.pac"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadPAC(context.Background(), nil, tt.location)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, script, got)
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+)
+
+// PageError reports a page that could not be fetched or decoded.
+type PageError struct {
+	// Page is the zero-based index of the page.
+	Page       int
+	Cursor     string
+	StatusCode int
+	Err        error
+}
+
+// Error returns the error message.
+func (e *PageError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("page %d (cursor %q): %v", e.Page, e.Cursor, e.Err)
+	}
+	return fmt.Sprintf("page %d (cursor %q): status %d", e.Page, e.Cursor, e.StatusCode)
+}
+
+// Unwrap returns the underlying error, if any.
+func (e *PageError) Unwrap() error {
+	return e.Err
+}
+
+// CursorPagerOptions configures a CursorPager.
+type CursorPagerOptions[T any] struct {
+	// Items decodes the items of a page, e.g. from a response envelope.
+	// Defaults to decoding the body as a JSON array of T.
+	Items func(resp *Response) ([]T, error)
+}
+
+// CursorPager fetches the pages of a cursor-paginated collection. While the
+// caller processes a page the next one is already being fetched.
+type CursorPager[T any] struct {
+	request   *Request
+	method    string
+	url       string
+	cursor    func(resp *Response, items []T) (string, error)
+	setCursor func(req *http.Request, cursor string)
+	options   *CursorPagerOptions[T]
+}
+
+// NewCursorPager creates a pager sending method requests to url built from
+// req. cursor extracts the cursor of the next page from a response and its
+// items, returning "" after the last page; setCursor adds a cursor to the
+// request of the following page, see QueryCursor. The body stays buffered
+// while the extractors run, so both can read it with Response.JSONTargets.
+//
+// Example:
+//
+//	type envelope struct {
+//	    Next string `json:"next"`
+//	}
+//	pager := fetch.NewCursorPager(dispatcher.NewRequest(), http.MethodGet, "https://api.example.com/users",
+//	    func(resp *fetch.Response, _ []User) (string, error) {
+//	        var env envelope
+//	        return env.Next, resp.JSONTargets(&env)
+//	    },
+//	    fetch.QueryCursor("cursor"),
+//	    func(o *fetch.CursorPagerOptions[User]) {
+//	        o.Items = func(resp *fetch.Response) ([]User, error) {
+//	            var env struct{ Data []User `json:"data"` }
+//	            return env.Data, resp.JSONTargets(&env)
+//	        }
+//	    },
+//	)
+//	for user, err := range pager.All(ctx) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    process(user)
+//	}
+func NewCursorPager[T any](req *Request, method, url string, cursor func(resp *Response, items []T) (string, error), setCursor func(req *http.Request, cursor string), opts ...func(*CursorPagerOptions[T])) *CursorPager[T] {
+	options := applyOptions(&CursorPagerOptions[T]{}, opts...)
+	if options.Items == nil {
+		options.Items = func(resp *Response) ([]T, error) {
+			var items []T
+			return items, resp.JSONTargets(&items)
+		}
+	}
+
+	return &CursorPager[T]{
+		request:   req,
+		method:    method,
+		url:       url,
+		cursor:    cursor,
+		setCursor: setCursor,
+		options:   options,
+	}
+}
+
+// QueryCursor returns a cursor setter that stores the cursor in the named
+// query parameter.
+func QueryCursor(name string) func(req *http.Request, cursor string) {
+	return func(req *http.Request, cursor string) {
+		query := req.URL.Query()
+		query.Set(name, cursor)
+		req.URL.RawQuery = query.Encode()
+	}
+}
+
+type page[T any] struct {
+	items []T
+	err   error
+	last  bool
+}
+
+// Pages returns an iterator over the items of every page. Iteration stops
+// after the last page, after the first error, or when the caller stops; the
+// prefetch is then canceled. When ctx ends before the last page, its error
+// is yielded.
+func (p *CursorPager[T]) Pages(ctx context.Context) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		fetchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		pages := make(chan page[T])
+		go p.fetch(fetchCtx, pages)
+
+		for page := range pages {
+			if !yield(page.items, page.err) || page.err != nil || page.last {
+				return
+			}
+		}
+
+		// The pages stopped before the last one because ctx ended.
+		if err := ctx.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// All returns an iterator over the items of all pages. An error is yielded
+// with the zero value of T and ends the iteration.
+func (p *CursorPager[T]) All(ctx context.Context) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for items, err := range p.Pages(ctx) {
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// fetch sends the pages in order to pages, fetching the next page while the
+// previous one waits to be received.
+func (p *CursorPager[T]) fetch(ctx context.Context, pages chan<- page[T]) {
+	defer close(pages)
+
+	send := func(pg page[T]) bool {
+		select {
+		case pages <- pg:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	cursor := ""
+	for index := 0; ; index++ {
+		items, next, err := p.fetchPage(ctx, index, cursor)
+		if err == nil && next != "" && next == cursor {
+			err = &PageError{Page: index, Cursor: cursor, Err: errors.New("cursor did not advance")}
+		}
+		if ctx.Err() != nil || !send(page[T]{items: items, err: err, last: next == ""}) || err != nil || next == "" {
+			return
+		}
+		cursor = next
+	}
+}
+
+func (p *CursorPager[T]) fetchPage(ctx context.Context, index int, cursor string) ([]T, string, error) {
+	resp := p.request.Clone().UseFuncs(func(req *http.Request) {
+		*req = *req.WithContext(ctx)
+		if index > 0 {
+			p.setCursor(req, cursor)
+		}
+	}).Send(p.method, p.url)
+	defer resp.Close()
+
+	if resp.Error != nil {
+		return nil, "", &PageError{Page: index, Cursor: cursor, Err: resp.Error}
+	}
+	if resp.RawResponse.StatusCode/100 != 2 {
+		return nil, "", &PageError{Page: index, Cursor: cursor, StatusCode: resp.RawResponse.StatusCode}
+	}
+
+	resp.Bytes()
+	items, err := p.options.Items(resp)
+	if err != nil {
+		return nil, "", &PageError{Page: index, Cursor: cursor, StatusCode: resp.RawResponse.StatusCode, Err: err}
+	}
+	next, err := p.cursor(resp, items)
+	if err != nil {
+		return nil, "", &PageError{Page: index, Cursor: cursor, StatusCode: resp.RawResponse.StatusCode, Err: err}
+	}
+	return items, next, nil
+}
@@ -0,0 +1,157 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorPager(t *testing.T) {
+	type envelope struct {
+		Data []int  `json:"data"`
+		Next string `json:"next"`
+	}
+
+	pages := map[string]envelope{
+		"":  {Data: []int{1, 2}, Next: "b"},
+		"b": {Data: []int{3}, Next: "c"},
+		"c": {Data: []int{4, 5}},
+	}
+
+	tests := []struct {
+		name      string
+		pages     map[string]envelope
+		failOn    string
+		stopAfter int
+		want      []int
+		wantErr   string
+		maxCalls  int32
+	}{
+		{name: "all pages", pages: pages, want: []int{1, 2, 3, 4, 5}, maxCalls: 3},
+		{name: "stop early prefetches one page", pages: pages, stopAfter: 2, want: []int{1, 2}, maxCalls: 2},
+		{name: "status error", pages: pages, failOn: "b", want: []int{1, 2}, wantErr: `page 1 (cursor "b"): status 500`},
+		{name: "cursor must advance", pages: map[string]envelope{"": {Data: []int{1}, Next: "a"}, "a": {Data: []int{2}, Next: "a"}}, want: []int{1}, wantErr: "cursor did not advance"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				cursor := r.URL.Query().Get("cursor")
+				if cursor == tt.failOn && cursor != "" {
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				json.NewEncoder(w).Encode(tt.pages[cursor])
+			}))
+			defer server.Close()
+
+			pager := NewCursorPager(NewDispatcher(nil).NewRequest(), http.MethodGet, server.URL,
+				func(resp *Response, _ []int) (string, error) {
+					var env envelope
+					return env.Next, resp.JSONTargets(&env)
+				},
+				QueryCursor("cursor"),
+				func(o *CursorPagerOptions[int]) {
+					o.Items = func(resp *Response) ([]int, error) {
+						var env envelope
+						return env.Data, resp.JSONTargets(&env)
+					}
+				},
+			)
+
+			var got []int
+			var gotErr error
+			for item, err := range pager.All(context.Background()) {
+				if err != nil {
+					gotErr = err
+					break
+				}
+				got = append(got, item)
+				if len(got) == tt.stopAfter {
+					break
+				}
+			}
+
+			assert.Equal(t, tt.want, got)
+			if tt.wantErr != "" {
+				var pageErr *PageError
+				require.ErrorAs(t, gotErr, &pageErr)
+				assert.ErrorContains(t, gotErr, tt.wantErr)
+			} else {
+				require.NoError(t, gotErr)
+			}
+			if tt.maxCalls > 0 {
+				assert.LessOrEqual(t, calls.Load(), tt.maxCalls)
+			}
+		})
+	}
+}
+
+func TestCursorPager_DefaultItems(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("after") == "" {
+			w.Header().Set("X-Next", "2")
+			w.Write([]byte(`["a","b"]`))
+			return
+		}
+		w.Write([]byte(`["c"]`))
+	}))
+	defer server.Close()
+
+	pager := NewCursorPager(NewDispatcher(nil).NewRequest(), http.MethodGet, server.URL,
+		func(resp *Response, _ []string) (string, error) { return resp.Header.Get("X-Next"), nil },
+		QueryCursor("after"),
+	)
+
+	var pages [][]string
+	for items, err := range pager.Pages(context.Background()) {
+		require.NoError(t, err)
+		pages = append(pages, items)
+	}
+	assert.Equal(t, [][]string{{"a", "b"}, {"c"}}, pages)
+}
+
+func TestCursorPager_ContextEnded(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("after") == "" {
+			w.Header().Set("X-Next", "2")
+			w.Write([]byte(`[1]`))
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+		w.Write([]byte(`[2]`))
+	}))
+	defer server.Close()
+
+	pager := NewCursorPager(NewDispatcher(nil).NewRequest(), http.MethodGet, server.URL,
+		func(resp *Response, _ []int) (string, error) { return resp.Header.Get("X-Next"), nil },
+		QueryCursor("after"),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var items []int
+	var gotErr error
+	for item, err := range pager.All(ctx) {
+		if err != nil {
+			gotErr = err
+			break
+		}
+		items = append(items, item)
+	}
+	assert.Equal(t, []int{1}, items)
+	assert.ErrorIs(t, gotErr, context.DeadlineExceeded)
+}
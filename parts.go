@@ -0,0 +1,152 @@
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Part is an element type for DecodeParts pairing the decoded value of a part
+// with its status, headers and raw body.
+type Part[T any] struct {
+	MultiStatusItem
+	Value T
+}
+
+// setItem stores item and returns the target the part body is decoded into.
+func (p *Part[T]) setItem(item MultiStatusItem) any {
+	p.MultiStatusItem = item
+	return &p.Value
+}
+
+type partTarget interface {
+	setItem(item MultiStatusItem) any
+}
+
+// DecodeParts decodes every item of a batch response, as parsed by
+// MultiStatus, into a new element of the slice out points to. Each item is
+// decoded with MultiStatusItem.Decode: as XML when its content type contains
+// "xml" and as JSON otherwise; other formats are not supported. Failed items
+// and items with an empty body are left as zero values. Use Part[T] as the
+// element type to keep the status and headers of each part next to its value.
+// When some items failed, the decoded slice is still filled and a
+// PartialFailureError is returned.
+//
+// Example:
+//
+//	var users []fetch.Part[User]
+//	err := resp.DecodeParts(&users)
+//	if err != nil && !errors.Is(err, fetch.ErrPartialFailure) {
+//	    return err
+//	}
+//	for _, part := range users {
+//	    if part.OK() {
+//	        log.Printf("%s: %s (etag %s)", part.ID, part.Value.Name, part.Header.Get("ETag"))
+//	    }
+//	}
+func (r *Response) DecodeParts(out any) error {
+	target := reflect.ValueOf(out)
+	if target.Kind() != reflect.Pointer || target.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("decode parts: want a pointer to a slice, got %T", out)
+	}
+
+	batch, err := r.MultiStatus()
+	if err != nil {
+		return err
+	}
+
+	slice := target.Elem()
+	values := reflect.MakeSlice(slice.Type(), 0, len(batch.Items))
+	for i, item := range batch.Items {
+		elem := reflect.New(slice.Type().Elem())
+		dst := elem.Interface()
+		if part, ok := dst.(partTarget); ok {
+			dst = part.setItem(item)
+		}
+		if item.OK() {
+			if err := item.Decode(dst); err != nil {
+				return fmt.Errorf("decode part %d: %w", i, err)
+			}
+		}
+		values = reflect.Append(values, elem.Elem())
+	}
+	slice.Set(values)
+
+	return batch.Err()
+}
+
+func parseMultipartStatus(body []byte, boundary string, status int) (*MultiStatus, error) {
+	if boundary == "" {
+		return nil, errors.New("parse multipart: missing boundary")
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	result := &MultiStatus{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse multipart: %w", err)
+		}
+
+		item, err := parseStatusPart(part, status)
+		if err != nil {
+			return nil, fmt.Errorf("parse multipart: part %d: %w", len(result.Items), err)
+		}
+		result.Items = append(result.Items, item)
+	}
+
+	return result, nil
+}
+
+func parseStatusPart(part *multipart.Part, status int) (MultiStatusItem, error) {
+	item := MultiStatusItem{
+		ID:         strings.Trim(part.Header.Get("Content-ID"), "<>"),
+		StatusCode: status,
+		Header:     http.Header(part.Header),
+	}
+	contentType := part.Header.Get("Content-Type")
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "application/http" {
+		body, err := io.ReadAll(part)
+		item.Body, item.xml = body, strings.Contains(contentType, "xml")
+		return item, err
+	}
+
+	// The part holds a complete response, as sent by batch APIs.
+	resp, err := http.ReadResponse(bufio.NewReader(part), nil)
+	if err != nil {
+		return item, err
+	}
+	defer resp.Body.Close()
+
+	item.StatusCode, item.Header = resp.StatusCode, resp.Header
+	if item.Body, err = io.ReadAll(resp.Body); err != nil {
+		return item, err
+	}
+	contentType = resp.Header.Get("Content-Type")
+	item.xml = strings.Contains(contentType, "xml")
+
+	if !item.OK() {
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(item.Body, &fields) == nil {
+			item.Message = jsonMemberString(fields, "message", "error")
+		}
+		if item.Message == "" {
+			item.Message = strings.TrimSpace(strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode)))
+		}
+	}
+	return item, nil
+}
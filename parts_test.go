@@ -0,0 +1,161 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const batchBody = "--batch\r\n" +
+	"Content-Type: application/http\r\n" +
+	"Content-ID: <response-1>\r\n" +
+	"\r\n" +
+	"HTTP/1.1 200 OK\r\n" +
+	"Content-Type: application/json\r\n" +
+	"ETag: \"v1\"\r\n" +
+	"\r\n" +
+	`{"id":1,"name":"alice"}` + "\r\n" +
+	"--batch\r\n" +
+	"Content-Type: application/http\r\n" +
+	"Content-ID: <response-2>\r\n" +
+	"\r\n" +
+	"HTTP/1.1 404 Not Found\r\n" +
+	"Content-Type: application/json\r\n" +
+	"\r\n" +
+	`{"error":{"message":"no such user"}}` + "\r\n" +
+	"--batch\r\n" +
+	"Content-Type: application/json\r\n" +
+	"Content-ID: response-3\r\n" +
+	"\r\n" +
+	`{"id":3,"name":"carol"}` + "\r\n" +
+	"--batch--\r\n"
+
+type partUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func newBatchServer(t *testing.T, contentType, body string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestResponseMultiStatus_Multipart(t *testing.T) {
+	server := newBatchServer(t, "multipart/mixed; boundary=batch", batchBody)
+
+	resp := NewDispatcher(nil).NewRequest().Get(server.URL)
+	require.NoError(t, resp.Error)
+
+	batch, err := resp.MultiStatus()
+	require.NoError(t, err)
+	require.Len(t, batch.Items, 3)
+
+	assert.Equal(t, "response-1", batch.Items[0].ID)
+	assert.Equal(t, http.StatusOK, batch.Items[0].StatusCode)
+	assert.Equal(t, `"v1"`, batch.Items[0].Header.Get("ETag"))
+
+	assert.Equal(t, "response-2", batch.Items[1].ID)
+	assert.Equal(t, http.StatusNotFound, batch.Items[1].StatusCode)
+	assert.Equal(t, "no such user", batch.Items[1].Message)
+
+	// A plain part takes the status of the whole response.
+	assert.Equal(t, "response-3", batch.Items[2].ID)
+	assert.Equal(t, http.StatusOK, batch.Items[2].StatusCode)
+	assert.Equal(t, "application/json", batch.Items[2].Header.Get("Content-Type"))
+}
+
+func TestResponse_DecodeParts(t *testing.T) {
+	server := newBatchServer(t, "multipart/mixed; boundary=batch", batchBody)
+
+	t.Run("with part", func(t *testing.T) {
+		resp := NewDispatcher(nil).NewRequest().Get(server.URL)
+		require.NoError(t, resp.Error)
+
+		var users []Part[partUser]
+		err := resp.DecodeParts(&users)
+		assert.ErrorIs(t, err, ErrPartialFailure)
+		require.Len(t, users, 3)
+
+		assert.True(t, users[0].OK())
+		assert.Equal(t, partUser{ID: 1, Name: "alice"}, users[0].Value)
+		assert.Equal(t, `"v1"`, users[0].Header.Get("ETag"))
+
+		assert.False(t, users[1].OK())
+		assert.Equal(t, partUser{}, users[1].Value)
+		assert.Equal(t, "no such user", users[1].Message)
+
+		assert.Equal(t, partUser{ID: 3, Name: "carol"}, users[2].Value)
+	})
+
+	t.Run("plain elements", func(t *testing.T) {
+		resp := NewDispatcher(nil).NewRequest().Get(server.URL)
+		require.NoError(t, resp.Error)
+
+		var users []*partUser
+		err := resp.DecodeParts(&users)
+		assert.ErrorIs(t, err, ErrPartialFailure)
+		require.Len(t, users, 3)
+		assert.Equal(t, "alice", users[0].Name)
+		assert.Nil(t, users[1])
+		assert.Equal(t, "carol", users[2].Name)
+	})
+
+	t.Run("json batch", func(t *testing.T) {
+		server := newBatchServer(t, "application/json", `[{"id":"a","status":200,"body":{"id":1,"name":"alice"}}]`)
+		resp := NewDispatcher(nil).NewRequest().Get(server.URL)
+		require.NoError(t, resp.Error)
+
+		var users []Part[partUser]
+		require.NoError(t, resp.DecodeParts(&users))
+		require.Len(t, users, 1)
+		assert.Equal(t, "a", users[0].ID)
+		assert.Equal(t, "alice", users[0].Value.Name)
+	})
+
+	t.Run("xml part", func(t *testing.T) {
+		body := strings.Join([]string{
+			"--b",
+			"Content-Type: application/xml",
+			"",
+			"<user><name>dave</name></user>",
+			"--b--",
+			"",
+		}, "\r\n")
+		server := newBatchServer(t, "multipart/mixed; boundary=b", body)
+		resp := NewDispatcher(nil).NewRequest().Get(server.URL)
+		require.NoError(t, resp.Error)
+
+		var users []struct {
+			Name string `xml:"name"`
+		}
+		require.NoError(t, resp.DecodeParts(&users))
+		require.Len(t, users, 1)
+		assert.Equal(t, "dave", users[0].Name)
+	})
+
+	t.Run("invalid target", func(t *testing.T) {
+		resp := NewDispatcher(nil).NewRequest().Get(server.URL)
+		require.NoError(t, resp.Error)
+
+		var users []partUser
+		assert.Error(t, resp.DecodeParts(users))
+	})
+
+	t.Run("missing boundary", func(t *testing.T) {
+		server := newBatchServer(t, "multipart/mixed", batchBody)
+		resp := NewDispatcher(nil).NewRequest().Get(server.URL)
+		require.NoError(t, resp.Error)
+
+		var users []partUser
+		assert.ErrorContains(t, resp.DecodeParts(&users), "missing boundary")
+	})
+}
@@ -0,0 +1,115 @@
+package fetch
+
+import (
+	"encoding/json"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Content types of the PATCH document formats.
+const (
+	ContentTypeJSONPatch  = "application/json-patch+json"
+	ContentTypeMergePatch = "application/merge-patch+json"
+)
+
+// PatchOperation is one operation of a JSON Patch document as defined in RFC 6902.
+type PatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	From  string `json:"from,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// MarshalJSON always includes value for add, replace and test operations,
+// even when it is null.
+func (o PatchOperation) MarshalJSON() ([]byte, error) {
+	type plain PatchOperation
+	switch o.Op {
+	case "add", "replace", "test":
+		return json.Marshal(struct {
+			plain
+			Value any `json:"value"`
+		}{plain(o), o.Value})
+	}
+	return json.Marshal(plain(o))
+}
+
+// PatchJSON sends a PATCH request to url with the operations as an
+// application/json-patch+json body.
+//
+// Example:
+//
+//	ops, _ := fetch.DiffJSON(before, after)
+//	resp := dispatcher.NewRequest().PatchJSON(url, ops)
+func (r *Request) PatchJSON(url string, ops []PatchOperation) *Response {
+	return r.JSON(ops, func(o *BodyOptions) { o.ContentType = ContentTypeJSONPatch }).Patch(url)
+}
+
+// MergePatch sends a PATCH request to url with partial as an
+// application/merge-patch+json body as defined in RFC 7396.
+//
+// Example:
+//
+//	resp := dispatcher.NewRequest().MergePatch(url, map[string]any{"name": "new", "nickname": nil})
+func (r *Request) MergePatch(url string, partial any) *Response {
+	return r.JSON(partial, func(o *BodyOptions) { o.ContentType = ContentTypeMergePatch }).Patch(url)
+}
+
+// DiffJSON computes the JSON Patch that turns the JSON encoding of from into
+// the JSON encoding of to. Objects are compared member by member; arrays
+// that differ are replaced as a whole.
+func DiffJSON(from, to any) ([]PatchOperation, error) {
+	a, err := toJSONValue(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := toJSONValue(to)
+	if err != nil {
+		return nil, err
+	}
+
+	ops := []PatchOperation{}
+	diffJSONValue(&ops, "", a, b)
+	return ops, nil
+}
+
+func toJSONValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	return value, json.Unmarshal(data, &value)
+}
+
+func diffJSONValue(ops *[]PatchOperation, path string, a, b any) {
+	objA, okA := a.(map[string]any)
+	objB, okB := b.(map[string]any)
+	if !okA || !okB {
+		if !reflect.DeepEqual(a, b) {
+			*ops = append(*ops, PatchOperation{Op: "replace", Path: path, Value: b})
+		}
+		return
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(objA)) {
+		if _, ok := objB[key]; !ok {
+			*ops = append(*ops, PatchOperation{Op: "remove", Path: path + "/" + escapeJSONPointer(key)})
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(objB)) {
+		member := path + "/" + escapeJSONPointer(key)
+		if old, ok := objA[key]; ok {
+			diffJSONValue(ops, member, old, objB[key])
+		} else {
+			*ops = append(*ops, PatchOperation{Op: "add", Path: member, Value: objB[key]})
+		}
+	}
+}
+
+// escapeJSONPointer escapes a reference token as defined in RFC 6901.
+func escapeJSONPointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
@@ -0,0 +1,118 @@
+package fetch
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffJSON(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+	}
+	type user struct {
+		Name    string            `json:"name"`
+		Tags    []string          `json:"tags"`
+		Address *address          `json:"address,omitempty"`
+		Meta    map[string]string `json:"meta,omitempty"`
+	}
+
+	tests := []struct {
+		name     string
+		from     any
+		to       any
+		expected string
+	}{
+		{
+			name:     "equal",
+			from:     user{Name: "a"},
+			to:       user{Name: "a"},
+			expected: `[]`,
+		},
+		{
+			name:     "replace scalar and array",
+			from:     user{Name: "a", Tags: []string{"x"}},
+			to:       user{Name: "b", Tags: []string{"x", "y"}},
+			expected: `[{"op":"replace","path":"/name","value":"b"},{"op":"replace","path":"/tags","value":["x","y"]}]`,
+		},
+		{
+			name:     "nested add and remove",
+			from:     user{Name: "a", Address: &address{City: "Oslo"}},
+			to:       user{Name: "a", Meta: map[string]string{"a/b": "1", "c~d": "2"}},
+			expected: `[{"op":"remove","path":"/address"},{"op":"add","path":"/meta","value":{"a/b":"1","c~d":"2"}}]`,
+		},
+		{
+			name:     "nested member and escaping",
+			from:     user{Meta: map[string]string{"a/b": "1"}},
+			to:       user{Meta: map[string]string{"a/b": "2", "c~d": "3"}},
+			expected: `[{"op":"replace","path":"/meta/a~1b","value":"2"},{"op":"add","path":"/meta/c~0d","value":"3"}]`,
+		},
+		{
+			name:     "null value is kept",
+			from:     map[string]any{"a": 1},
+			to:       map[string]any{"a": nil},
+			expected: `[{"op":"replace","path":"/a","value":null}]`,
+		},
+		{
+			name:     "root replaced",
+			from:     []int{1},
+			to:       "x",
+			expected: `[{"op":"replace","path":"","value":"x"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops, err := DiffJSON(tt.from, tt.to)
+			require.NoError(t, err)
+
+			data, err := json.Marshal(ops)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(data))
+		})
+	}
+}
+
+func TestRequest_Patch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte(r.Method + " " + r.Header.Get("Content-Type") + " " + string(body)))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		send     func(r *Request) *Response
+		expected string
+	}{
+		{
+			name: "json patch",
+			send: func(r *Request) *Response {
+				return r.PatchJSON(server.URL, []PatchOperation{{Op: "remove", Path: "/a"}, {Op: "move", From: "/b", Path: "/c"}})
+			},
+			expected: `PATCH application/json-patch+json [{"op":"remove","path":"/a"},{"op":"move","path":"/c","from":"/b"}]` + "\n",
+		},
+		{
+			name: "merge patch",
+			send: func(r *Request) *Response {
+				return r.MergePatch(server.URL, map[string]any{"nickname": nil})
+			},
+			expected: `PATCH application/merge-patch+json {"nickname":null}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := tt.send(NewDispatcher(nil).NewRequest())
+			defer resp.Close()
+
+			require.NoError(t, resp.Error)
+			assert.Equal(t, tt.expected, resp.String())
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package fetch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// PayloadTooLargeOptions configures how 413 responses are handled by SplitOnPayloadTooLarge.
+type PayloadTooLargeOptions struct {
+	// Aggregate combines the responses of the split requests into a single response.
+	// Defaults to returning the first non-2xx response, or the last response
+	// when every part succeeded. Responses that are not returned are closed.
+	Aggregate func(responses []*http.Response) (*http.Response, error)
+	// MaxDepth limits how many times a part may be split again when it is
+	// also rejected with 413. Defaults to 4.
+	MaxDepth int
+}
+
+// SplitOnPayloadTooLarge creates middleware that reacts to 413 Payload Too Large
+// responses by splitting the request body with the given function and sending
+// each part as a separate request. The results are combined by
+// PayloadTooLargeOptions.Aggregate.
+//
+// The request body is buffered in memory so it can be split and re-sent.
+// Returning fewer than two parts from split keeps the original 413 response.
+//
+// Example:
+//
+//	req.Use(fetch.SplitOnPayloadTooLarge(func(body []byte) ([][]byte, error) {
+//	    var items []json.RawMessage
+//	    if err := json.Unmarshal(body, &items); err != nil {
+//	        return nil, err
+//	    }
+//	    half := len(items) / 2
+//	    left, _ := json.Marshal(items[:half])
+//	    right, _ := json.Marshal(items[half:])
+//	    return [][]byte{left, right}, nil
+//	}))
+func SplitOnPayloadTooLarge(split func(body []byte) ([][]byte, error), opts ...func(*PayloadTooLargeOptions)) Middleware {
+	options := applyOptions(&PayloadTooLargeOptions{
+		Aggregate: aggregateFirstFailure,
+		MaxDepth:  4,
+	}, opts...)
+
+	return func(next Handler) Handler {
+		var send func(client *http.Client, req *http.Request, body []byte, depth int) (*http.Response, error)
+
+		send = func(client *http.Client, req *http.Request, body []byte, depth int) (*http.Response, error) {
+			setBufferedBody(req, body)
+
+			resp, err := next.Handle(client, req)
+			if err != nil || resp.StatusCode != http.StatusRequestEntityTooLarge || depth >= options.MaxDepth {
+				return resp, err
+			}
+
+			parts, err := split(body)
+			if err != nil {
+				resp.Body.Close()
+				return nil, fmt.Errorf("split request body: %w", err)
+			}
+			if len(parts) < 2 {
+				return resp, nil
+			}
+			drainAndClose(resp)
+
+			responses := make([]*http.Response, 0, len(parts))
+			for _, part := range parts {
+				partResp, err := send(client, req.Clone(req.Context()), part, depth+1)
+				if err != nil {
+					for _, r := range responses {
+						drainAndClose(r)
+					}
+					return nil, err
+				}
+				responses = append(responses, partResp)
+			}
+
+			return options.Aggregate(responses)
+		}
+
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			if split == nil {
+				return next.Handle(client, req)
+			}
+
+			body, err := readRequestBody(req)
+			if err != nil {
+				return nil, err
+			}
+			closeReplacedBody(req)
+
+			return send(client, req, body, 0)
+		})
+	}
+}
+
+// readRequestBody reads the full request body, preferring GetBody when set.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	var rc io.ReadCloser
+	switch {
+	case req.GetBody != nil:
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		rc = body
+	case req.Body != nil && req.Body != http.NoBody:
+		rc = req.Body
+	default:
+		return nil, nil
+	}
+	defer rc.Close()
+
+	return io.ReadAll(rc)
+}
+
+// closeReplacedBody closes the body of req when readRequestBody read the
+// content from GetBody, so the body is not leaked when it is replaced.
+func closeReplacedBody(req *http.Request) {
+	if req.GetBody != nil && req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// setBufferedBody replaces the request body with a replayable in-memory body.
+func setBufferedBody(req *http.Request, body []byte) {
+	req.ContentLength = int64(len(body))
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+}
+
+func drainAndClose(resp *http.Response) {
+	if resp == nil || resp.Body == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+func aggregateFirstFailure(responses []*http.Response) (*http.Response, error) {
+	if len(responses) == 0 {
+		return nil, errors.New("fetch: no responses to aggregate")
+	}
+
+	selected := responses[len(responses)-1]
+	for _, resp := range responses {
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			selected = resp
+			break
+		}
+	}
+
+	for _, resp := range responses {
+		if resp != selected {
+			drainAndClose(resp)
+		}
+	}
+
+	return selected, nil
+}
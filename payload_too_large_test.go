@@ -0,0 +1,176 @@
+package fetch
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func splitHalves(body []byte) ([][]byte, error) {
+	items := strings.Split(string(body), ",")
+	if len(items) < 2 {
+		return [][]byte{body}, nil
+	}
+	half := len(items) / 2
+	return [][]byte{
+		[]byte(strings.Join(items[:half], ",")),
+		[]byte(strings.Join(items[half:], ",")),
+	}, nil
+}
+
+func TestSplitOnPayloadTooLarge(t *testing.T) {
+	errSplit := errors.New("cannot split")
+	tests := []struct {
+		name         string
+		body         string
+		limit        int
+		split        func([]byte) ([][]byte, error)
+		wantStatus   int
+		wantReceived []string
+		wantErr      error
+	}{
+		{
+			name:         "small payload is sent once",
+			body:         "a,b",
+			limit:        10,
+			split:        splitHalves,
+			wantStatus:   http.StatusOK,
+			wantReceived: []string{"a,b"},
+		},
+		{
+			name:         "large payload is split",
+			body:         "a,b,c,d",
+			limit:        3,
+			split:        splitHalves,
+			wantStatus:   http.StatusOK,
+			wantReceived: []string{"a,b,c,d", "a,b", "c,d"},
+		},
+		{
+			name:         "parts are split again",
+			body:         "a,b,c,d",
+			limit:        1,
+			split:        splitHalves,
+			wantStatus:   http.StatusOK,
+			wantReceived: []string{"a,b,c,d", "a,b", "a", "b", "c,d", "c", "d"},
+		},
+		{
+			name:         "unsplittable payload keeps 413",
+			body:         "abcdef",
+			limit:        3,
+			split:        splitHalves,
+			wantStatus:   http.StatusRequestEntityTooLarge,
+			wantReceived: []string{"abcdef"},
+		},
+		{
+			name:  "split error is returned",
+			body:  "a,b,c,d",
+			limit: 3,
+			split: func([]byte) ([][]byte, error) {
+				return nil, errSplit
+			},
+			wantReceived: []string{"a,b,c,d"},
+			wantErr:      errSplit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var received []string
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+
+				mu.Lock()
+				received = append(received, string(body))
+				mu.Unlock()
+
+				if len(body) > tt.limit {
+					w.WriteHeader(http.StatusRequestEntityTooLarge)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			resp := NewDispatcher(nil).NewRequest().
+				Body(strings.NewReader(tt.body)).
+				Use(SplitOnPayloadTooLarge(tt.split)).
+				Post(server.URL)
+			defer resp.Close()
+
+			assert.Equal(t, tt.wantReceived, received)
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, resp.Error, tt.wantErr)
+				return
+			}
+			require.NoError(t, resp.Error)
+			assert.Equal(t, tt.wantStatus, resp.RawResponse.StatusCode)
+		})
+	}
+}
+
+func TestSplitOnPayloadTooLarge_ClosesOriginalBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if len(body) > 3 {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		}
+	}))
+	defer server.Close()
+
+	original := &closeTrackingBody{Reader: strings.NewReader("a,b,c,d")}
+	req, err := http.NewRequest(http.MethodPost, server.URL, original)
+	require.NoError(t, err)
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("a,b,c,d")), nil
+	}
+
+	resp, err := NewDispatcher(nil, SplitOnPayloadTooLarge(splitHalves)).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, original.closed.Load())
+}
+
+func TestSplitOnPayloadTooLarge_Aggregate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if len(body) > 3 {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	var parts []string
+	resp := NewDispatcher(nil).NewRequest().
+		Body(strings.NewReader("a,b,c,d")).
+		Use(SplitOnPayloadTooLarge(splitHalves, func(o *PayloadTooLargeOptions) {
+			o.Aggregate = func(responses []*http.Response) (*http.Response, error) {
+				for _, r := range responses {
+					b, _ := io.ReadAll(r.Body)
+					r.Body.Close()
+					parts = append(parts, string(b))
+				}
+				return &http.Response{StatusCode: http.StatusMultiStatus, Body: http.NoBody, Header: http.Header{}}, nil
+			}
+		})).
+		Post(server.URL)
+	defer resp.Close()
+
+	require.NoError(t, resp.Error)
+	assert.Equal(t, http.StatusMultiStatus, resp.RawResponse.StatusCode)
+	assert.Equal(t, []string{"a,b", "c,d"}, parts)
+}
@@ -0,0 +1,287 @@
+package fetch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrPinDrift is matched by errors.Is for every PinDriftError.
+	ErrPinDrift = errors.New("pinned content changed")
+	// ErrNotPinned is returned in PinOffline mode for URLs missing from the store.
+	ErrNotPinned = errors.New("url not pinned")
+)
+
+// PinDriftError reports remote content that no longer matches its pin.
+type PinDriftError struct {
+	URL    string
+	Pinned string
+	Actual string
+}
+
+// Error returns the error message.
+func (e *PinDriftError) Error() string {
+	return fmt.Sprintf("pinned content of %s changed: sha256 %s, pinned %s", e.URL, e.Actual, e.Pinned)
+}
+
+// Is reports whether target is ErrPinDrift.
+func (e *PinDriftError) Is(target error) bool {
+	return target == ErrPinDrift
+}
+
+// PinMode selects how a PinStore treats requests.
+type PinMode int
+
+const (
+	// PinVerify fetches every URL, pins URLs seen for the first time and
+	// fails with a PinDriftError when pinned content changed.
+	PinVerify PinMode = iota
+	// PinOffline serves pinned URLs from the store without network access
+	// and fails unpinned URLs with ErrNotPinned.
+	PinOffline
+	// PinUpdate fetches every URL and re-pins content that changed.
+	PinUpdate
+)
+
+// PinEntry is one pinned URL in the lockfile.
+type PinEntry struct {
+	URL    string    `json:"url"`
+	SHA256 string    `json:"sha256"`
+	Size   int64     `json:"size"`
+	Pinned time.Time `json:"pinned"`
+}
+
+// PinStoreOptions configures OpenPinStore.
+type PinStoreOptions struct {
+	// Mode defaults to PinVerify.
+	Mode PinMode
+	// Lockfile is the path of the lockfile. Defaults to "fetch.lock.json"
+	// inside the store directory; keep it under version control.
+	Lockfile string
+}
+
+// PinStore is a content-addressable response store for reproducible builds.
+// Bodies of successful GET responses are stored under their SHA-256 and a
+// lockfile maps each URL to the hash it was pinned to. It is safe for
+// concurrent use.
+type PinStore struct {
+	dir     string
+	options *PinStoreOptions
+
+	mu      sync.Mutex
+	entries map[string]PinEntry
+}
+
+// OpenPinStore opens or creates the store in dir and loads its lockfile.
+//
+// Example:
+//
+//	store, err := fetch.OpenPinStore(".cache/downloads", func(o *fetch.PinStoreOptions) {
+//	    o.Lockfile = "deps.lock.json"
+//	    if os.Getenv("OFFLINE") != "" {
+//	        o.Mode = fetch.PinOffline
+//	    }
+//	})
+//	if err != nil {
+//	    return err
+//	}
+//	dispatcher.Use(store.Middleware())
+func OpenPinStore(dir string, opts ...func(*PinStoreOptions)) (*PinStore, error) {
+	options := applyOptions(&PinStoreOptions{Lockfile: filepath.Join(dir, "fetch.lock.json")}, opts...)
+
+	if err := os.MkdirAll(filepath.Join(dir, "sha256"), 0o755); err != nil {
+		return nil, err
+	}
+
+	s := &PinStore{dir: dir, options: options, entries: map[string]PinEntry{}}
+	data, err := os.ReadFile(options.Lockfile)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []PinEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("pin lockfile %s: %w", options.Lockfile, err)
+	}
+	for _, entry := range entries {
+		// The hash names the blob file, so it must not be able to point
+		// outside the blob directory.
+		if !isSHA256Hex(entry.SHA256) {
+			return nil, fmt.Errorf("pin lockfile %s: invalid sha256 %q for %s", options.Lockfile, entry.SHA256, entry.URL)
+		}
+		s.entries[entry.URL] = entry
+	}
+	return s, nil
+}
+
+// isSHA256Hex reports whether sum is a lowercase hex-encoded SHA-256 hash.
+func isSHA256Hex(sum string) bool {
+	if len(sum) != hex.EncodedLen(sha256.Size) {
+		return false
+	}
+	return !strings.ContainsFunc(sum, func(r rune) bool {
+		return (r < '0' || r > '9') && (r < 'a' || r > 'f')
+	})
+}
+
+// Entries returns the pinned entries sorted by URL.
+func (s *PinStore) Entries() []PinEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sortedEntries()
+}
+
+func (s *PinStore) sortedEntries() []PinEntry {
+	entries := make([]PinEntry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, entry)
+	}
+	slices.SortFunc(entries, func(a, b PinEntry) int { return strings.Compare(a.URL, b.URL) })
+	return entries
+}
+
+// Middleware returns middleware applying the store to GET requests; other
+// methods and non-2xx responses pass through unpinned. Responses are served
+// from the stored blob once it was verified, so callers never see content
+// that does not match the lockfile.
+func (s *PinStore) Middleware() Middleware {
+	return bypassDryRun(func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			if req.Method != http.MethodGet {
+				return next.Handle(client, req)
+			}
+
+			key := req.URL.String()
+			s.mu.Lock()
+			entry, pinned := s.entries[key]
+			s.mu.Unlock()
+
+			if s.options.Mode == PinOffline {
+				if !pinned {
+					return nil, fmt.Errorf("%w: %s", ErrNotPinned, req.URL.Redacted())
+				}
+				return s.serve(req, entry)
+			}
+
+			resp, err := next.Handle(client, req)
+			if err != nil || resp.StatusCode/100 != 2 {
+				return resp, err
+			}
+
+			stored, err := s.store(resp)
+			if err != nil {
+				return nil, err
+			}
+			if pinned && stored.SHA256 != entry.SHA256 && s.options.Mode != PinUpdate {
+				return nil, &PinDriftError{URL: req.URL.Redacted(), Pinned: entry.SHA256, Actual: stored.SHA256}
+			}
+			if !pinned || stored.SHA256 != entry.SHA256 {
+				stored.URL = key
+				if err := s.pin(stored); err != nil {
+					return nil, err
+				}
+				entry = stored
+			}
+
+			served, err := s.serve(req, entry)
+			if err != nil {
+				return nil, err
+			}
+			served.StatusCode, served.Status = resp.StatusCode, resp.Status
+			served.Header = resp.Header.Clone()
+			served.Header.Set("Content-Length", strconv.FormatInt(entry.Size, 10))
+			served.Header.Del("Content-Encoding")
+			return served, nil
+		})
+	})
+}
+
+// store writes the body of resp into the blob directory and returns its entry.
+func (s *PinStore) store(resp *http.Response) (entry PinEntry, err error) {
+	defer resp.Body.Close()
+
+	fd, err := os.CreateTemp(filepath.Join(s.dir, "sha256"), ".blob-*.tmp")
+	if err != nil {
+		return entry, err
+	}
+	defer func() {
+		if err != nil {
+			fd.Close()
+			os.Remove(fd.Name())
+		}
+	}()
+
+	hash := sha256.New()
+	if entry.Size, err = io.Copy(io.MultiWriter(hash, fd), resp.Body); err != nil {
+		return entry, err
+	}
+	if err = fd.Close(); err != nil {
+		return entry, err
+	}
+
+	entry.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	entry.Pinned = time.Now().UTC()
+	return entry, os.Rename(fd.Name(), s.blobPath(entry.SHA256))
+}
+
+// pin records entry and rewrites the lockfile.
+func (s *PinStore) pin(entry PinEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[entry.URL] = entry
+	data, err := json.MarshalIndent(s.sortedEntries(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.options.Lockfile + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.options.Lockfile)
+}
+
+// serve answers req from the blob of entry after checking its hash, so a
+// corrupted store is never served.
+func (s *PinStore) serve(req *http.Request, entry PinEntry) (*http.Response, error) {
+	data, err := os.ReadFile(s.blobPath(entry.SHA256))
+	if err != nil {
+		return nil, fmt.Errorf("pinned blob of %s: %w", req.URL.Redacted(), err)
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != entry.SHA256 {
+		return nil, &PinDriftError{URL: req.URL.Redacted(), Pinned: entry.SHA256, Actual: hex.EncodeToString(sum[:])}
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Length": {strconv.Itoa(len(data))}},
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}, nil
+}
+
+func (s *PinStore) blobPath(sum string) string {
+	return filepath.Join(s.dir, "sha256", sum)
+}
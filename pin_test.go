@@ -0,0 +1,136 @@
+package fetch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinStore(t *testing.T) {
+	var content atomic.Value
+	content.Store("v1")
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content.Load().(string)))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	get := func(mode PinMode, path string) *Response {
+		store, err := OpenPinStore(dir, func(o *PinStoreOptions) { o.Mode = mode })
+		require.NoError(t, err)
+		dispatcher := NewDispatcher(nil)
+		dispatcher.Use(store.Middleware())
+		return dispatcher.NewRequest().Get(server.URL + path)
+	}
+
+	resp := get(PinVerify, "/dep.tar")
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "v1", resp.String())
+
+	sum := sha256.Sum256([]byte("v1"))
+	store, err := OpenPinStore(dir)
+	require.NoError(t, err)
+	entries := store.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, server.URL+"/dep.tar", entries[0].URL)
+	assert.Equal(t, hex.EncodeToString(sum[:]), entries[0].SHA256)
+	assert.Equal(t, int64(2), entries[0].Size)
+	assert.FileExists(t, filepath.Join(dir, "fetch.lock.json"))
+
+	resp = get(PinVerify, "/missing")
+	require.NoError(t, resp.Error)
+	assert.Equal(t, http.StatusNotFound, resp.RawResponse.StatusCode)
+	assert.Len(t, store.Entries(), 1)
+
+	content.Store("v2")
+	resp = get(PinVerify, "/dep.tar")
+	var drift *PinDriftError
+	require.ErrorAs(t, resp.Error, &drift)
+	assert.ErrorIs(t, resp.Error, ErrPinDrift)
+	assert.Equal(t, hex.EncodeToString(sum[:]), drift.Pinned)
+
+	hits.Store(0)
+	resp = get(PinOffline, "/dep.tar")
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "v1", resp.String())
+	assert.Zero(t, hits.Load())
+
+	resp = get(PinOffline, "/other")
+	assert.ErrorIs(t, resp.Error, ErrNotPinned)
+
+	resp = get(PinUpdate, "/dep.tar")
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "v2", resp.String())
+
+	resp = get(PinVerify, "/dep.tar")
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "v2", resp.String())
+}
+
+func TestPinStore_CorruptedBlob(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	store, err := OpenPinStore(dir)
+	require.NoError(t, err)
+	dispatcher := NewDispatcher(nil)
+	dispatcher.Use(store.Middleware())
+	require.NoError(t, dispatcher.NewRequest().Get(server.URL).Error)
+
+	entry := store.Entries()[0]
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sha256", entry.SHA256), []byte("tampered"), 0o644))
+
+	offline, err := OpenPinStore(dir, func(o *PinStoreOptions) { o.Mode = PinOffline })
+	require.NoError(t, err)
+	dispatcher = NewDispatcher(nil)
+	dispatcher.Use(offline.Middleware())
+	assert.ErrorIs(t, dispatcher.NewRequest().Get(server.URL).Error, ErrPinDrift)
+}
+
+func TestOpenPinStore_InvalidLockfile(t *testing.T) {
+	valid := hex.EncodeToString(make([]byte, sha256.Size))
+
+	tests := []struct {
+		name     string
+		lockfile string
+		wantErr  bool
+	}{
+		{name: "malformed json", lockfile: "{", wantErr: true},
+		{name: "path traversal", lockfile: `[{"url":"http://a/","sha256":"../../etc/passwd"}]`, wantErr: true},
+		{name: "short hash", lockfile: `[{"url":"http://a/","sha256":"abc123"}]`, wantErr: true},
+		{name: "non hex hash", lockfile: `[{"url":"http://a/","sha256":"` + strings.Repeat("z", 64) + `"}]`, wantErr: true},
+		{name: "uppercase hash", lockfile: `[{"url":"http://a/","sha256":"` + strings.ToUpper(strings.Repeat("ab", 32)) + `"}]`, wantErr: true},
+		{name: "valid hash", lockfile: `[{"url":"http://a/","sha256":"` + valid + `"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "fetch.lock.json"), []byte(tt.lockfile), 0o644))
+
+			_, err := OpenPinStore(dir)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package fetch
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PreflightError reports a CORS preflight that did not allow the request.
+type PreflightError struct {
+	URL    string
+	Reason string
+}
+
+// Error returns the error message.
+func (e *PreflightError) Error() string {
+	return fmt.Sprintf("cors preflight for %s failed: %s", e.URL, e.Reason)
+}
+
+// PreflightOptions configures the Preflight middleware.
+type PreflightOptions struct {
+	// Origin is sent as the Origin header of requests that have none.
+	// Requests without an origin are not preflighted.
+	Origin string
+	// DefaultMaxAge is used when the response has no Access-Control-Max-Age.
+	// Defaults to 5 seconds, as in browsers.
+	DefaultMaxAge time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Preflight creates middleware that emulates the CORS preflight of a browser:
+// before a request that is not CORS-safelisted, an OPTIONS request with
+// Access-Control-Request-Method and Access-Control-Request-Headers is sent
+// and the actual request only proceeds if the response allows it. Successful
+// preflights are cached per method, URL and header set for the duration of
+// Access-Control-Max-Age. A rejected preflight fails with a PreflightError.
+//
+// Headers are inspected when the request reaches the middleware, so place it
+// after middlewares that add headers.
+//
+// Example:
+//
+//	dispatcher.Use(fetch.Preflight(func(o *fetch.PreflightOptions) {
+//	    o.Origin = "https://app.example.com"
+//	}))
+func Preflight(opts ...func(*PreflightOptions)) Middleware {
+	options := applyOptions(&PreflightOptions{
+		DefaultMaxAge: 5 * time.Second,
+		Now:           time.Now,
+	}, opts...)
+
+	var (
+		mu    sync.Mutex
+		cache = map[string]time.Time{}
+	)
+
+	return bypassDryRun(func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			origin := req.Header.Get("Origin")
+			if origin == "" {
+				origin = options.Origin
+				if origin == "" {
+					return next.Handle(client, req)
+				}
+				req.Header.Set("Origin", origin)
+			}
+
+			headers := unsafeCORSHeaders(req.Header)
+			if isSimpleCORSMethod(req.Method) && len(headers) == 0 {
+				return next.Handle(client, req)
+			}
+
+			target := *req.URL
+			target.RawQuery, target.Fragment = "", ""
+			key := req.Method + " " + target.String() + " " + strings.Join(headers, ",")
+
+			mu.Lock()
+			expires, ok := cache[key]
+			mu.Unlock()
+
+			if !ok || !options.Now().Before(expires) {
+				maxAge, err := preflight(client, next, req, origin, headers, options.DefaultMaxAge)
+				if err != nil {
+					return nil, err
+				}
+				mu.Lock()
+				cache[key] = options.Now().Add(maxAge)
+				mu.Unlock()
+			}
+
+			return next.Handle(client, req)
+		})
+	})
+}
+
+func preflight(client *http.Client, next Handler, req *http.Request, origin string, headers []string, defaultMaxAge time.Duration) (time.Duration, error) {
+	probe, err := http.NewRequestWithContext(req.Context(), http.MethodOptions, req.URL.String(), nil)
+	if err != nil {
+		return 0, err
+	}
+	probe.Header.Set("Origin", origin)
+	probe.Header.Set("Access-Control-Request-Method", req.Method)
+	if len(headers) > 0 {
+		probe.Header.Set("Access-Control-Request-Headers", strings.Join(headers, ","))
+	}
+
+	resp, err := next.Handle(client, probe)
+	if err != nil {
+		return 0, err
+	}
+	defer drainAndClose(resp)
+
+	fail := func(reason string) (time.Duration, error) {
+		return 0, &PreflightError{URL: req.URL.String(), Reason: reason}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fail("status " + strconv.Itoa(resp.StatusCode))
+	}
+	if allowed := resp.Header.Get("Access-Control-Allow-Origin"); allowed != "*" && allowed != origin {
+		return fail("origin " + origin + " not allowed")
+	}
+
+	allowedMethods := headerTokens(resp.Header.Get("Access-Control-Allow-Methods"))
+	if !isSimpleCORSMethod(req.Method) && !slices.Contains(allowedMethods, "*") && !slices.Contains(allowedMethods, strings.ToLower(req.Method)) {
+		return fail("method " + req.Method + " not allowed")
+	}
+
+	allowedHeaders := headerTokens(resp.Header.Get("Access-Control-Allow-Headers"))
+	for _, header := range headers {
+		if !slices.Contains(allowedHeaders, "*") && !slices.Contains(allowedHeaders, header) {
+			return fail("header " + header + " not allowed")
+		}
+	}
+
+	maxAge := defaultMaxAge
+	if seconds, err := strconv.Atoi(resp.Header.Get("Access-Control-Max-Age")); err == nil {
+		maxAge = time.Duration(seconds) * time.Second
+	}
+	return maxAge, nil
+}
+
+func isSimpleCORSMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodPost
+}
+
+// unsafeCORSHeaders returns the sorted, lower-cased names of the request
+// headers that are not CORS-safelisted and therefore require a preflight.
+func unsafeCORSHeaders(header http.Header) []string {
+	var names []string
+	for name, values := range header {
+		lower := strings.ToLower(name)
+		switch lower {
+		case "origin", "accept", "accept-language", "content-language", "user-agent", "accept-encoding":
+			continue
+		case "content-type":
+			media := mediaType(strings.Join(values, ","))
+			if media == "application/x-www-form-urlencoded" || media == "multipart/form-data" || media == "text/plain" {
+				continue
+			}
+		}
+		names = append(names, lower)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func headerTokens(value string) []string {
+	var tokens []string
+	for _, token := range strings.Split(value, ",") {
+		if token = strings.ToLower(strings.TrimSpace(token)); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
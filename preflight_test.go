@@ -0,0 +1,105 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCORSServer(preflights *atomic.Int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			preflights.Add(1)
+			if r.Header.Get("Origin") == "https://app.example.com" {
+				w.Header().Set("Access-Control-Allow-Origin", "https://app.example.com")
+			}
+			w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, DELETE")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			w.Header().Set("Access-Control-Max-Age", "60")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(r.Method + " " + r.Header.Get("Origin")))
+	}))
+}
+
+func TestPreflight(t *testing.T) {
+	tests := []struct {
+		name           string
+		origin         string
+		method         string
+		header         http.Header
+		wantPreflights int32
+		wantErr        string
+	}{
+		{name: "simple request", origin: "https://app.example.com", method: http.MethodGet, wantPreflights: 0},
+		{name: "simple content type", origin: "https://app.example.com", method: http.MethodPost, header: http.Header{"Content-Type": {"text/plain; charset=utf-8"}}, wantPreflights: 0},
+		{name: "non-simple method", origin: "https://app.example.com", method: http.MethodPut, wantPreflights: 1},
+		{name: "non-simple header", origin: "https://app.example.com", method: http.MethodGet, header: http.Header{"Authorization": {"Bearer x"}}, wantPreflights: 1},
+		{name: "json content type", origin: "https://app.example.com", method: http.MethodPost, header: http.Header{"Content-Type": {"application/json"}}, wantPreflights: 1},
+		{name: "method not allowed", origin: "https://app.example.com", method: http.MethodPatch, wantPreflights: 1, wantErr: "method PATCH not allowed"},
+		{name: "header not allowed", origin: "https://app.example.com", method: http.MethodGet, header: http.Header{"X-Custom": {"1"}}, wantPreflights: 1, wantErr: "header x-custom not allowed"},
+		{name: "origin not allowed", origin: "https://evil.example.com", method: http.MethodDelete, wantPreflights: 1, wantErr: "not allowed"},
+		{name: "no origin", method: http.MethodDelete, wantPreflights: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var preflights atomic.Int32
+			server := newCORSServer(&preflights)
+			defer server.Close()
+
+			preflight := Preflight(func(o *PreflightOptions) { o.Origin = tt.origin })
+			resp := NewDispatcher(nil).NewRequest().UseFuncs(func(r *http.Request) {
+				for name, values := range tt.header {
+					r.Header[name] = values
+				}
+			}).Use(preflight).Send(tt.method, server.URL+"/items?id=1")
+			defer resp.Close()
+
+			assert.Equal(t, tt.wantPreflights, preflights.Load())
+			if tt.wantErr != "" {
+				var preflightErr *PreflightError
+				require.ErrorAs(t, resp.Error, &preflightErr)
+				assert.Contains(t, preflightErr.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, resp.Error)
+			assert.Equal(t, tt.method+" "+tt.origin, resp.String())
+		})
+	}
+}
+
+func TestPreflight_Cache(t *testing.T) {
+	var preflights atomic.Int32
+	server := newCORSServer(&preflights)
+	defer server.Close()
+
+	now := time.Unix(0, 0)
+	dispatcher := NewDispatcher(nil, Preflight(func(o *PreflightOptions) {
+		o.Origin = "https://app.example.com"
+		o.Now = func() time.Time { return now }
+	}))
+
+	put := func(url string) {
+		resp := dispatcher.NewRequest().Put(url)
+		defer resp.Close()
+		require.NoError(t, resp.Error)
+	}
+
+	put(server.URL + "/a?x=1")
+	put(server.URL + "/a?x=2")
+	assert.Equal(t, int32(1), preflights.Load())
+
+	put(server.URL + "/b")
+	assert.Equal(t, int32(2), preflights.Load())
+
+	now = now.Add(61 * time.Second)
+	put(server.URL + "/a")
+	assert.Equal(t, int32(3), preflights.Load())
+}
@@ -0,0 +1,69 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrProfileNotFound is returned when activating a profile that was not registered.
+var ErrProfileNotFound = errors.New("profile not found")
+
+// Profile is a complete configuration bundle that a Dispatcher can switch to
+// at runtime, e.g. one per dev, staging and prod environment.
+// Base URL, headers and rate limits are expressed as middlewares such as
+// SetURLOptions and SetHeaderOptions; TLS and proxy settings live in the
+// client's transport.
+type Profile struct {
+	// Client replaces the dispatcher's client while the profile is active.
+	// Nil keeps the dispatcher's client.
+	Client *http.Client
+	// Middlewares run before the dispatcher's own middlewares, so options set
+	// here are seen by prepare middlewares such as PrepareURLMiddleware.
+	Middlewares []Middleware
+}
+
+// RegisterProfile stores a profile under name, replacing any existing one.
+// Re-registering the active profile takes effect for the next request.
+// This operation is safe for concurrent use.
+//
+// Example:
+//
+//	dispatcher.RegisterProfile("staging", &fetch.Profile{
+//	    Client: stagingClient,
+//	    Middlewares: []fetch.Middleware{
+//	        fetch.SetURLOptions(func(o *fetch.URLOptions) { o.BaseURL = "https://staging.example.com" }),
+//	    },
+//	})
+//	dispatcher.ActivateProfile("staging")
+func (d *Dispatcher) RegisterProfile(name string, profile *Profile) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.profiles == nil {
+		d.profiles = map[string]*Profile{}
+	}
+	d.profiles[name] = profile
+}
+
+// ActivateProfile atomically switches the dispatcher to the named profile.
+// Requests already in flight keep the configuration they started with.
+// An empty name deactivates profiles. This operation is safe for concurrent use.
+func (d *Dispatcher) ActivateProfile(name string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if _, ok := d.profiles[name]; name != "" && !ok {
+		return fmt.Errorf("activate %q: %w", name, ErrProfileNotFound)
+	}
+	d.active = name
+	return nil
+}
+
+// ActiveProfile returns the name of the active profile, or "" when none is active.
+func (d *Dispatcher) ActiveProfile() string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.active
+}
@@ -0,0 +1,88 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcherProfiles(t *testing.T) {
+	newEnv := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name + ":" + r.Header.Get("X-Env")))
+		}))
+	}
+	staging := newEnv("staging")
+	defer staging.Close()
+	prod := newEnv("prod")
+	defer prod.Close()
+
+	profile := func(server *httptest.Server, env string) *Profile {
+		return &Profile{
+			Middlewares: []Middleware{
+				SetURLOptions(func(o *URLOptions) { o.BaseURL = server.URL }),
+				SetHeaderOptions(func(o *HeaderOptions) { o.Header.Set("X-Env", env) }),
+			},
+		}
+	}
+
+	dispatcher := NewDispatcher(nil, PrepareURLMiddleware(), PrepareHeaderMiddleware())
+	dispatcher.RegisterProfile("staging", profile(staging, "stg"))
+	dispatcher.RegisterProfile("prod", profile(prod, "prd"))
+
+	tests := []struct {
+		profile  string
+		expected string
+	}{
+		{profile: "staging", expected: "staging:stg"},
+		{profile: "prod", expected: "prod:prd"},
+		{profile: "staging", expected: "staging:stg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			require.NoError(t, dispatcher.ActivateProfile(tt.profile))
+			assert.Equal(t, tt.profile, dispatcher.ActiveProfile())
+
+			resp := dispatcher.NewRequest().Get("/")
+			defer resp.Close()
+
+			require.NoError(t, resp.Error)
+			assert.Equal(t, tt.expected, resp.String())
+		})
+	}
+}
+
+func TestDispatcherProfiles_Client(t *testing.T) {
+	var used bool
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		used = true
+		return http.ErrUseLastResponse
+	}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/elsewhere", http.StatusFound)
+	}))
+	defer server.Close()
+
+	dispatcher := NewDispatcher(nil)
+	dispatcher.RegisterProfile("mock", &Profile{Client: client})
+
+	err := dispatcher.ActivateProfile("missing")
+	assert.ErrorIs(t, err, ErrProfileNotFound)
+	assert.Empty(t, dispatcher.ActiveProfile())
+
+	require.NoError(t, dispatcher.ActivateProfile("mock"))
+	clone := dispatcher.Clone()
+	require.NoError(t, dispatcher.ActivateProfile(""))
+
+	resp := clone.NewRequest().Get(server.URL)
+	defer resp.Close()
+
+	require.NoError(t, resp.Error)
+	assert.True(t, used)
+	assert.Equal(t, http.StatusFound, resp.RawResponse.StatusCode)
+	assert.Equal(t, "mock", clone.ActiveProfile())
+}
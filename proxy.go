@@ -0,0 +1,170 @@
+package fetch
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hopHeaders are connection-specific headers never copied by ProxyTo.
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+	cacheStatusHeader,
+}
+
+// ProxyOptions configures Response.ProxyTo.
+type ProxyOptions struct {
+	// AllowHeaders restricts the copied headers to these names. When empty
+	// all headers except DenyHeaders and hop-by-hop headers are copied.
+	AllowHeaders []string
+	// DenyHeaders are never copied, e.g. Set-Cookie of an internal service.
+	DenyHeaders []string
+	// FlushInterval is how often the body is flushed to the client while it
+	// is copied. Negative flushes after every write. Zero flushes after every
+	// write for text/event-stream and NDJSON bodies and otherwise not at all.
+	FlushInterval time.Duration
+}
+
+// ProxyTo streams the response to w: the status code, the headers selected
+// by the options and the body, flushed for streaming formats such as SSE and
+// NDJSON, so services can act as thin gateways. It returns the number of
+// body bytes written. Errors after the status was sent can only abort the
+// body; the response is closed.
+//
+// Example:
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//	    resp := dispatcher.NewRequest().UseFuncs(func(req *http.Request) {
+//	        *req = *req.WithContext(r.Context())
+//	    }).Get(upstream + r.URL.Path)
+//	    if _, err := resp.ProxyTo(w, func(o *fetch.ProxyOptions) {
+//	        o.DenyHeaders = []string{"Set-Cookie"}
+//	    }); err != nil && resp.Error != nil {
+//	        http.Error(w, "upstream unavailable", http.StatusBadGateway)
+//	    }
+//	}
+func (r *Response) ProxyTo(w http.ResponseWriter, opts ...func(*ProxyOptions)) (int64, error) {
+	if r.Error != nil {
+		return 0, r.Error
+	}
+	defer r.Close()
+
+	options := applyOptions(&ProxyOptions{}, opts...)
+	copyProxyHeaders(w.Header(), r.RawResponse.Header, options)
+	w.WriteHeader(r.RawResponse.StatusCode)
+
+	interval := options.FlushInterval
+	if interval == 0 && isStreamingMediaType(r.RawResponse.Header.Get("Content-Type")) {
+		interval = -1
+	}
+
+	var dst io.Writer = w
+	if interval != 0 {
+		fw := &flushWriter{w: w, controller: http.NewResponseController(w), interval: interval}
+		defer fw.stop()
+		dst = fw
+	}
+
+	return io.Copy(dst, r.getInternalReader())
+}
+
+func copyProxyHeaders(dst, src http.Header, options *ProxyOptions) {
+	skip := map[string]bool{}
+	for _, name := range hopHeaders {
+		skip[textproto.CanonicalMIMEHeaderKey(name)] = true
+	}
+	for _, name := range options.DenyHeaders {
+		skip[textproto.CanonicalMIMEHeaderKey(name)] = true
+	}
+	for _, value := range src.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			skip[textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name))] = true
+		}
+	}
+
+	allow := map[string]bool{}
+	for _, name := range options.AllowHeaders {
+		allow[textproto.CanonicalMIMEHeaderKey(name)] = true
+	}
+
+	for name, values := range src {
+		if skip[name] || (len(allow) > 0 && !allow[name]) {
+			continue
+		}
+		dst[name] = append(dst[name], values...)
+	}
+}
+
+func isStreamingMediaType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/event-stream", "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return true
+	}
+	return false
+}
+
+// flushWriter flushes after every write when interval is negative, or at
+// most interval after a write otherwise.
+type flushWriter struct {
+	w          io.Writer
+	controller *http.ResponseController
+	interval   time.Duration
+
+	mu      sync.Mutex
+	pending *time.Timer
+	stopped bool
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n, err := f.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	if f.interval < 0 {
+		f.controller.Flush()
+		return n, nil
+	}
+	if f.pending == nil {
+		f.pending = time.AfterFunc(f.interval, f.flush)
+	}
+	return n, nil
+}
+
+func (f *flushWriter) flush() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.stopped {
+		f.controller.Flush()
+	}
+	f.pending = nil
+}
+
+func (f *flushWriter) stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.pending != nil {
+		f.pending.Stop()
+		f.pending = nil
+	}
+	f.stopped = true
+	f.controller.Flush()
+}
@@ -0,0 +1,124 @@
+package fetch
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponse_ProxyTo_Headers(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "internal=1")
+		w.Header().Set("X-Hop", "1")
+		w.Header().Set("Connection", "X-Hop")
+		w.Header().Set("X-Request-Id", "abc")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		name     string
+		options  func(*ProxyOptions)
+		expected map[string]string
+		absent   []string
+	}{
+		{
+			name:     "hop-by-hop headers dropped",
+			expected: map[string]string{"Content-Type": "application/json", "Set-Cookie": "internal=1", "X-Request-Id": "abc"},
+			absent:   []string{"X-Hop", "Connection", "X-Fetch-Cache"},
+		},
+		{
+			name:     "deny list",
+			options:  func(o *ProxyOptions) { o.DenyHeaders = []string{"set-cookie"} },
+			expected: map[string]string{"Content-Type": "application/json"},
+			absent:   []string{"Set-Cookie"},
+		},
+		{
+			name:     "allow list",
+			options:  func(o *ProxyOptions) { o.AllowHeaders = []string{"Content-Type", "X-Hop"} },
+			expected: map[string]string{"Content-Type": "application/json"},
+			absent:   []string{"Set-Cookie", "X-Request-Id", "X-Hop"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := NewDispatcher(nil, Cache()).NewRequest().Get(upstream.URL)
+			require.NoError(t, resp.Error)
+
+			var opts []func(*ProxyOptions)
+			if tt.options != nil {
+				opts = append(opts, tt.options)
+			}
+			recorder := httptest.NewRecorder()
+			n, err := resp.ProxyTo(recorder, opts...)
+			require.NoError(t, err)
+
+			assert.Equal(t, int64(8), n)
+			assert.Equal(t, http.StatusCreated, recorder.Code)
+			assert.Equal(t, `{"id":1}`, recorder.Body.String())
+			for name, value := range tt.expected {
+				assert.Equal(t, value, recorder.Header().Get(name))
+			}
+			for _, name := range tt.absent {
+				assert.Empty(t, recorder.Header().Values(name))
+			}
+		})
+	}
+}
+
+func TestResponse_ProxyTo_Streaming(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: first\n\n"))
+		w.(http.Flusher).Flush()
+		<-release
+		w.Write([]byte("data: second\n\n"))
+	}))
+	defer upstream.Close()
+
+	dispatcher := NewDispatcher(nil)
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := dispatcher.NewRequest().Get(upstream.URL)
+		_, err := resp.ProxyTo(w)
+		assert.NoError(t, err)
+	}))
+	defer gateway.Close()
+
+	resp, err := http.Get(gateway.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	defer close(release)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	lines := make(chan string)
+	go func() {
+		line, _ := bufio.NewReader(resp.Body).ReadString('\n')
+		lines <- line
+	}()
+
+	select {
+	case line := <-lines:
+		assert.Equal(t, "data: first\n", line)
+	case <-time.After(2 * time.Second):
+		t.Fatal("first event was not flushed before the upstream finished")
+	}
+}
+
+func TestResponse_ProxyTo_Error(t *testing.T) {
+	resp := &Response{Error: assert.AnError}
+	recorder := httptest.NewRecorder()
+
+	_, err := resp.ProxyTo(recorder)
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.False(t, recorder.Flushed)
+	assert.Equal(t, http.StatusOK, recorder.Code, "nothing written")
+}
@@ -0,0 +1,251 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQueueFull is returned by Queue.Enqueue when the queue is full and the
+	// overflow policy is OverflowReject, and passed to QueueOptions.OnComplete
+	// for requests dropped by OverflowDropOldest.
+	ErrQueueFull = errors.New("queue full")
+	// ErrQueueClosed is returned by Queue.Enqueue after Close was called.
+	ErrQueueClosed = errors.New("queue closed")
+)
+
+// OverflowPolicy decides what happens when a request is enqueued into a full queue.
+type OverflowPolicy int
+
+const (
+	// OverflowReject rejects the new request with ErrQueueFull.
+	OverflowReject OverflowPolicy = iota
+	// OverflowDropOldest drops the oldest queued request to make room.
+	OverflowDropOldest
+)
+
+// QueueOptions configures a Queue.
+type QueueOptions struct {
+	// Size is the maximum number of queued requests. Defaults to 100.
+	Size int
+	// Workers is the number of requests sent concurrently. Defaults to 1.
+	Workers int
+	// Overflow is applied when the queue is full. Defaults to OverflowReject.
+	Overflow OverflowPolicy
+	// Retries is how many times a request failing with a network error,
+	// 429 or 5xx is retried. Defaults to 3.
+	Retries int
+	// Backoff computes the delay between retries.
+	// Defaults to exponential backoff from 100ms, capped at 10s.
+	Backoff Backoff
+	// OnComplete is called once per request with the final response or error.
+	// The response body is closed after the callback returns.
+	OnComplete func(req *http.Request, resp *http.Response, err error)
+	// Events receives RequestQueued and RetryScheduled events.
+	Events *EventBus
+}
+
+// Queue sends requests in the background with fire-and-forget semantics,
+// e.g. for analytics or telemetry. Create it with Dispatcher.NewQueue.
+type Queue struct {
+	dispatcher *Dispatcher
+	options    *QueueOptions
+
+	mu     sync.Mutex
+	closed bool
+	items  chan *http.Request
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewQueue starts a background queue that sends requests through the
+// dispatcher. Call Close to flush and stop it.
+//
+// Example:
+//
+//	queue := dispatcher.NewQueue(func(o *fetch.QueueOptions) {
+//	    o.Size = 1000
+//	    o.Overflow = fetch.OverflowDropOldest
+//	})
+//	defer queue.Close(context.Background())
+//
+//	req, _ := http.NewRequest(http.MethodPost, "https://telemetry.example.com/events", body)
+//	queue.Enqueue(req)
+func (d *Dispatcher) NewQueue(opts ...func(*QueueOptions)) *Queue {
+	options := applyOptions(&QueueOptions{
+		Size:    100,
+		Workers: 1,
+		Retries: 3,
+		Backoff: CapBackoff(ExponentialBackoff(100*time.Millisecond), 10*time.Second),
+	}, opts...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		dispatcher: d,
+		options:    options,
+		items:      make(chan *http.Request, max(options.Size, 1)),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+
+	for i := 0; i < max(options.Workers, 1); i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+
+	return q
+}
+
+// Enqueue queues req for sending and returns immediately. The request body
+// is buffered so it can be retried, and the request is detached from the
+// cancellation of its context, which usually ends before it is sent.
+func (q *Queue) Enqueue(req *http.Request) error {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(context.WithoutCancel(req.Context()))
+	if body != nil {
+		setBufferedBody(req, body)
+	}
+
+	dropped, err := q.push(req)
+	// OnComplete runs without the lock, so it may enqueue again.
+	for _, r := range dropped {
+		q.complete(r, nil, ErrQueueFull)
+	}
+	if err != nil {
+		return err
+	}
+	q.options.Events.Publish(RequestQueued{Time: time.Now(), Request: req})
+	return nil
+}
+
+// push adds req to the queue and returns the requests dropped to make room.
+func (q *Queue) push(req *http.Request) ([]*http.Request, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return nil, ErrQueueClosed
+	}
+
+	var dropped []*http.Request
+	for {
+		select {
+		case q.items <- req:
+			return dropped, nil
+		default:
+		}
+
+		if q.options.Overflow != OverflowDropOldest {
+			return dropped, ErrQueueFull
+		}
+
+		select {
+		case r := <-q.items:
+			dropped = append(dropped, r)
+		default:
+		}
+	}
+}
+
+// Close stops accepting requests and waits until the queued requests were
+// sent. When ctx is done first, in-flight requests are canceled, the
+// remaining ones are dropped and ctx.Err() is returned.
+func (q *Queue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.items)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+
+	for req := range q.items {
+		if q.ctx.Err() != nil {
+			q.complete(req, nil, q.ctx.Err())
+			continue
+		}
+		resp, err := q.send(req)
+		q.complete(req, resp, err)
+	}
+}
+
+func (q *Queue) send(req *http.Request) (*http.Response, error) {
+	// The context stays alive after send returns so OnComplete can read the body.
+	ctx, cancel := context.WithCancel(req.Context())
+	stop := context.AfterFunc(q.ctx, cancel)
+	defer stop()
+
+	var delay time.Duration
+	for attempt := 0; ; attempt++ {
+		attemptReq := req.Clone(ctx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("get request body: %w", err)
+			}
+			attemptReq.Body = body
+		}
+
+		resp, err := q.dispatcher.Do(attemptReq)
+		if attempt >= q.options.Retries || !retryableQueueResult(resp, err) {
+			return resp, err
+		}
+		drainAndClose(resp)
+
+		delay = q.options.Backoff.Delay(attempt, delay)
+		if q.options.Events != nil {
+			scheduled := RetryScheduled{Time: time.Now(), Request: req, Attempt: attempt + 1, Delay: delay, Err: err}
+			if resp != nil {
+				scheduled.StatusCode = resp.StatusCode
+			}
+			q.options.Events.Publish(scheduled)
+		}
+		select {
+		case <-time.After(delay):
+		case <-q.ctx.Done():
+			return nil, q.ctx.Err()
+		}
+	}
+}
+
+func (q *Queue) complete(req *http.Request, resp *http.Response, err error) {
+	if q.options.OnComplete != nil {
+		q.options.OnComplete(req, resp, err)
+	}
+	drainAndClose(resp)
+}
+
+func retryableQueueResult(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
@@ -0,0 +1,235 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type queueResults struct {
+	mu      sync.Mutex
+	results map[string]error
+	status  map[string]int
+}
+
+func (r *queueResults) record(req *http.Request, resp *http.Response, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.results == nil {
+		r.results = map[string]error{}
+		r.status = map[string]int{}
+	}
+	id := req.URL.Query().Get("id")
+	r.results[id] = err
+	if resp != nil {
+		r.status[id] = resp.StatusCode
+	}
+}
+
+func TestQueue_RetriesAndFlush(t *testing.T) {
+	var attempts atomic.Int32
+	var bodies sync.Map
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies.Store(r.URL.Query().Get("id"), string(data))
+		if r.URL.Query().Get("id") == "flaky" && attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	var results queueResults
+	queue := NewDispatcher(nil).NewQueue(func(o *QueueOptions) {
+		o.Workers = 2
+		o.Backoff = ConstantBackoff(time.Millisecond)
+		o.OnComplete = results.record
+	})
+
+	for _, id := range []string{"a", "flaky", "b"} {
+		ctx, cancel := context.WithCancel(context.Background())
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"?id="+id, strings.NewReader("event-"+id))
+		require.NoError(t, err)
+		require.NoError(t, queue.Enqueue(req))
+		// The caller's context ending must not abort the queued request.
+		cancel()
+	}
+
+	require.NoError(t, queue.Close(context.Background()))
+
+	assert.Equal(t, map[string]int{"a": 202, "flaky": 202, "b": 202}, results.status)
+	assert.Equal(t, int32(3), attempts.Load())
+	body, _ := bodies.Load("flaky")
+	assert.Equal(t, "event-flaky", body)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	assert.ErrorIs(t, queue.Enqueue(req), ErrQueueClosed)
+}
+
+func TestQueue_Overflow(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		overflow    OverflowPolicy
+		wantErr     error
+		wantDropped string
+	}{
+		{name: "reject", overflow: OverflowReject, wantErr: ErrQueueFull},
+		{name: "drop oldest", overflow: OverflowDropOldest, wantDropped: "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var results queueResults
+			var started sync.WaitGroup
+			started.Add(1)
+			var once sync.Once
+
+			queue := NewDispatcher(nil, func(next Handler) Handler {
+				return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+					once.Do(started.Done)
+					return next.Handle(client, req)
+				})
+			}).NewQueue(func(o *QueueOptions) {
+				o.Size = 1
+				o.Overflow = tt.overflow
+				o.OnComplete = results.record
+			})
+
+			enqueue := func(id string) error {
+				req, _ := http.NewRequest(http.MethodGet, server.URL+"?id="+id, nil)
+				return queue.Enqueue(req)
+			}
+
+			// The first request occupies the worker, the second fills the queue.
+			require.NoError(t, enqueue("1"))
+			started.Wait()
+			require.NoError(t, enqueue("2"))
+
+			err := enqueue("3")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+				results.mu.Lock()
+				assert.ErrorIs(t, results.results[tt.wantDropped], ErrQueueFull)
+				results.mu.Unlock()
+			}
+
+			release <- struct{}{}
+			release <- struct{}{}
+			require.NoError(t, queue.Close(context.Background()))
+		})
+	}
+}
+
+func TestQueue_CloseTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	var results queueResults
+	queue := NewDispatcher(nil).NewQueue(func(o *QueueOptions) {
+		o.Retries = 0
+		o.OnComplete = results.record
+	})
+
+	for _, id := range []string{"1", "2"} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"?id="+id, nil)
+		require.NoError(t, queue.Enqueue(req))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, queue.Close(ctx), context.DeadlineExceeded)
+	assert.Error(t, results.results["1"])
+	assert.ErrorIs(t, results.results["2"], context.Canceled)
+}
+
+func TestQueue_OnCompleteEnqueues(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	var (
+		started  = make(chan struct{}, 1)
+		requeued = make(chan error, 1)
+		queue    *Queue
+		once     atomic.Bool
+	)
+	queue = NewDispatcher(nil, func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+			return next.Handle(client, req)
+		})
+	}).NewQueue(func(o *QueueOptions) {
+		o.Size = 1
+		o.Overflow = OverflowDropOldest
+		o.OnComplete = func(req *http.Request, resp *http.Response, err error) {
+			if errors.Is(err, ErrQueueFull) {
+				// Enqueuing from the callback must not deadlock.
+				if once.CompareAndSwap(false, true) {
+					requeued <- queue.Enqueue(req.Clone(context.Background()))
+				}
+			}
+		}
+	})
+	defer queue.Close(context.Background())
+	defer close(release)
+
+	enqueue := func(id string) error {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"?id="+id, nil)
+		return queue.Enqueue(req)
+	}
+	require.NoError(t, enqueue("1"))
+	<-started
+	require.NoError(t, enqueue("2"))
+
+	done := make(chan error, 1)
+	go func() { done <- enqueue("3") }()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Enqueue deadlocked in OnComplete")
+	}
+	assert.NoError(t, <-requeued)
+}
+
+func TestQueue_GetBodyError(t *testing.T) {
+	errBody := errors.New("body gone")
+	queue := NewDispatcher(nil).NewQueue()
+	defer queue.Close(context.Background())
+
+	req, err := http.NewRequest(http.MethodPost, "http://127.0.0.1:1/", nil)
+	require.NoError(t, err)
+	req.GetBody = func() (io.ReadCloser, error) { return nil, errBody }
+
+	resp, err := queue.send(req)
+	assert.Nil(t, resp)
+	assert.ErrorIs(t, err, errBody)
+}
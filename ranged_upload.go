@@ -0,0 +1,167 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RangeUploadError reports a range that could not be uploaded.
+type RangeUploadError struct {
+	Offset     int64
+	Length     int64
+	StatusCode int
+	Err        error
+}
+
+// Error returns the error message.
+func (e *RangeUploadError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("upload range %d-%d: %v", e.Offset, e.Offset+e.Length-1, e.Err)
+	}
+	return fmt.Sprintf("upload range %d-%d: status %d", e.Offset, e.Offset+e.Length-1, e.StatusCode)
+}
+
+// Unwrap returns the underlying error, if any.
+func (e *RangeUploadError) Unwrap() error {
+	return e.Err
+}
+
+// RangedUploadOptions configures UploadRanged.
+type RangedUploadOptions struct {
+	// Method is the HTTP method of the range requests. Defaults to PUT.
+	Method string
+	// ChunkSize is the size of each range. Defaults to 8 MiB.
+	ChunkSize int64
+	// Concurrency is the number of ranges uploaded in parallel. Defaults to 4.
+	Concurrency int
+	// Retries is how many times a range failing with a network error, 429
+	// or 5xx is retried. Defaults to 3.
+	Retries int
+	// Backoff computes the delay between retries of a range.
+	// Defaults to exponential backoff from 200ms.
+	Backoff Backoff
+	// Prepare is called for every range request, e.g. to add an upload id header.
+	Prepare func(req *http.Request)
+	// Finalize is called once all ranges were uploaded, e.g. to send the
+	// commit request of the storage protocol.
+	Finalize func(ctx context.Context) error
+}
+
+// UploadRanged uploads size bytes from r to url as parallel range requests,
+// each carrying a Content-Range header such as "bytes 0-8388607/20000000".
+// Ranges are answered with 2xx or 308 Resume Incomplete on success; failed
+// ranges are retried and the first range that keeps failing cancels the
+// others and is returned as a RangeUploadError.
+//
+// Example:
+//
+//	f, _ := os.Open("backup.tar")
+//	info, _ := f.Stat()
+//	err := dispatcher.UploadRanged(ctx, uploadURL, f, info.Size(), func(o *fetch.RangedUploadOptions) {
+//	    o.Concurrency = 8
+//	    o.Finalize = func(ctx context.Context) error { return commitUpload(ctx, uploadID) }
+//	})
+func (d *Dispatcher) UploadRanged(ctx context.Context, url string, r io.ReaderAt, size int64, opts ...func(*RangedUploadOptions)) error {
+	options := applyOptions(&RangedUploadOptions{
+		Method:      http.MethodPut,
+		ChunkSize:   8 << 20,
+		Concurrency: 4,
+		Retries:     3,
+		Backoff:     ExponentialBackoff(200 * time.Millisecond),
+	}, opts...)
+	if options.ChunkSize <= 0 {
+		return errors.New("upload ranged: chunk size must be positive")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		slots    = make(chan struct{}, max(options.Concurrency, 1))
+	)
+
+	for offset := int64(0); offset < size; offset += options.ChunkSize {
+		length := min(options.ChunkSize, size-offset)
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if err := d.uploadRange(ctx, url, r, offset, length, size, options); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if options.Finalize != nil {
+		return options.Finalize(ctx)
+	}
+	return nil
+}
+
+func (d *Dispatcher) uploadRange(ctx context.Context, url string, r io.ReaderAt, offset, length, size int64, options *RangedUploadOptions) error {
+	var delay time.Duration
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, options.Method, url, io.NewSectionReader(r, offset, length))
+		if err != nil {
+			return err
+		}
+		req.ContentLength = length
+		req.Header.Set("Content-Range", "bytes "+strconv.FormatInt(offset, 10)+"-"+strconv.FormatInt(offset+length-1, 10)+"/"+strconv.FormatInt(size, 10))
+		if options.Prepare != nil {
+			options.Prepare(req)
+		}
+
+		resp, err := d.Do(req)
+		if err == nil {
+			drainAndClose(resp)
+			if resp.StatusCode/100 == 2 || resp.StatusCode == http.StatusPermanentRedirect {
+				return nil
+			}
+		}
+
+		retryable := err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		if !retryable || attempt >= options.Retries || ctx.Err() != nil {
+			rangeErr := &RangeUploadError{Offset: offset, Length: length, Err: err}
+			if err == nil {
+				rangeErr.StatusCode = resp.StatusCode
+			}
+			return rangeErr
+		}
+
+		delay = options.Backoff.Delay(attempt, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
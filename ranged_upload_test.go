@@ -0,0 +1,84 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcher_UploadRanged(t *testing.T) {
+	payload := bytes.Repeat([]byte("0123456789"), 105)
+
+	tests := []struct {
+		name       string
+		failFirst  int
+		failStatus int
+		wantErr    bool
+		wantStatus int
+	}{
+		{name: "all ranges succeed"},
+		{name: "failed range is retried", failFirst: 2, failStatus: http.StatusServiceUnavailable},
+		{name: "client error is not retried", failFirst: 1, failStatus: http.StatusBadRequest, wantErr: true, wantStatus: http.StatusBadRequest},
+		{name: "retries exhausted", failFirst: 10, failStatus: http.StatusInternalServerError, wantErr: true, wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu       sync.Mutex
+				received = make([]byte, len(payload))
+				failures atomic.Int32
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var start, end, total int
+				_, err := fmt.Sscanf(r.Header.Get("Content-Range"), "bytes %d-%d/%d", &start, &end, &total)
+				if err != nil || total != len(payload) {
+					w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+					return
+				}
+				if start == 500 && int(failures.Add(1)) <= tt.failFirst {
+					w.WriteHeader(tt.failStatus)
+					return
+				}
+				body, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				copy(received[start:end+1], body)
+				mu.Unlock()
+				w.WriteHeader(http.StatusPermanentRedirect)
+			}))
+			defer server.Close()
+
+			var finalized bool
+			err := NewDispatcher(nil).UploadRanged(context.Background(), server.URL, bytes.NewReader(payload), int64(len(payload)), func(o *RangedUploadOptions) {
+				o.ChunkSize = 250
+				o.Concurrency = 3
+				o.Backoff = ConstantBackoff(0)
+				o.Finalize = func(ctx context.Context) error {
+					finalized = true
+					return nil
+				}
+			})
+
+			if tt.wantErr {
+				var rangeErr *RangeUploadError
+				require.ErrorAs(t, err, &rangeErr)
+				assert.Equal(t, int64(500), rangeErr.Offset)
+				assert.Equal(t, tt.wantStatus, rangeErr.StatusCode)
+				assert.False(t, finalized)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, finalized)
+			assert.Equal(t, payload, received)
+		})
+	}
+}
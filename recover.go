@@ -0,0 +1,78 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// ErrPanic is matched by errors.Is for every PanicError.
+var ErrPanic = errors.New("panic during request")
+
+// PanicError reports a panic recovered by the Recover middleware.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+// Error returns the error message.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic during request: %v", e.Value)
+}
+
+// Is reports whether target is ErrPanic.
+func (e *PanicError) Is(target error) bool {
+	return target == ErrPanic
+}
+
+// Unwrap returns the panic value when it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// RecoverOptions configures the Recover middleware.
+type RecoverOptions struct {
+	// OnPanic is called with the request and the recovered panic, e.g. to log
+	// the stack trace or report it to an error tracker.
+	OnPanic func(req *http.Request, err *PanicError)
+}
+
+// Recover creates middleware that converts panics in the middlewares and
+// handlers after it into a PanicError, so one misbehaving middleware cannot
+// crash the process. Add it first to cover the whole chain.
+//
+// Panics in goroutines started by downstream code, or while the caller reads
+// the response body, are not recovered.
+//
+// Example:
+//
+//	dispatcher := fetch.NewDispatcher(nil, fetch.Recover(func(o *fetch.RecoverOptions) {
+//	    o.OnPanic = func(req *http.Request, err *fetch.PanicError) {
+//	        slog.Error("request panicked", "url", req.URL, "panic", err.Value, "stack", string(err.Stack))
+//	    }
+//	}))
+func Recover(opts ...func(*RecoverOptions)) Middleware {
+	options := applyOptions(&RecoverOptions{}, opts...)
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (_ *http.Response, err error) {
+			defer func() {
+				value := recover()
+				if value == nil {
+					return
+				}
+				panicErr := &PanicError{Value: value, Stack: debug.Stack()}
+				if options.OnPanic != nil {
+					options.OnPanic(req, panicErr)
+				}
+				err = panicErr
+			}()
+
+			return next.Handle(client, req)
+		})
+	}
+}
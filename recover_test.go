@@ -0,0 +1,83 @@
+package fetch
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecover(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	errBoom := errors.New("boom")
+
+	tests := []struct {
+		name      string
+		panicWith any
+		wantValue any
+	}{
+		{name: "no panic"},
+		{name: "string value", panicWith: "bad middleware", wantValue: "bad middleware"},
+		{name: "error value", panicWith: errBoom, wantValue: errBoom},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hooked *PanicError
+			dispatcher := NewDispatcher(nil, Recover(func(o *RecoverOptions) {
+				o.OnPanic = func(req *http.Request, err *PanicError) { hooked = err }
+			}))
+
+			resp := dispatcher.NewRequest().UseFuncs(func(r *http.Request) {
+				if tt.panicWith != nil {
+					panic(tt.panicWith)
+				}
+			}).Get(server.URL)
+
+			if tt.panicWith == nil {
+				require.NoError(t, resp.Error)
+				assert.Equal(t, "ok", resp.String())
+				assert.Nil(t, hooked)
+				return
+			}
+
+			require.ErrorIs(t, resp.Error, ErrPanic)
+			var panicErr *PanicError
+			require.ErrorAs(t, resp.Error, &panicErr)
+			assert.Equal(t, tt.wantValue, panicErr.Value)
+			assert.Contains(t, string(panicErr.Stack), "recover_test.go")
+			assert.Same(t, panicErr, hooked)
+			if err, ok := tt.wantValue.(error); ok {
+				assert.ErrorIs(t, resp.Error, err)
+			}
+		})
+	}
+}
+
+func TestRecover_ResponseMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	dispatcher := NewDispatcher(nil, Recover())
+	_, err = dispatcher.Do(req, func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			resp, err := next.Handle(client, req)
+			io.Copy(io.Discard, resp.Body)
+			var header http.Header
+			header.Set("X-Panic", "nil map")
+			return resp, err
+		})
+	})
+	assert.ErrorIs(t, err, ErrPanic)
+}
@@ -0,0 +1,121 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrRedirectBlocked is matched by errors.Is for every RedirectError.
+var ErrRedirectBlocked = errors.New("redirect blocked")
+
+// RedirectError reports a redirect rejected by SecureRedirects.
+type RedirectError struct {
+	From   string
+	To     string
+	Reason string
+}
+
+// Error returns the error message.
+func (e *RedirectError) Error() string {
+	return fmt.Sprintf("redirect from %s to %s blocked: %s", e.From, e.To, e.Reason)
+}
+
+// Is reports whether target is ErrRedirectBlocked.
+func (e *RedirectError) Is(target error) bool {
+	return target == ErrRedirectBlocked
+}
+
+// RedirectPolicyOptions configures SecureRedirects.
+type RedirectPolicyOptions struct {
+	// MaxRedirects is the maximum number of redirects followed. Defaults to 10.
+	MaxRedirects int
+	// AllowDowngrade permits redirects from https to http.
+	AllowDowngrade bool
+	// AllowedHosts restricts redirect targets to these hosts and their
+	// subdomains. When empty every host is allowed.
+	AllowedHosts []string
+	// SensitiveHeaders are removed when a redirect leaves the origin of the
+	// original request. Defaults to Authorization, Proxy-Authorization and Cookie.
+	SensitiveHeaders []string
+	// OnRedirect is called for every redirect that passed the policy with the
+	// URLs of the original request and the redirect target. A non-nil error
+	// stops the redirect; return http.ErrUseLastResponse to receive the 3xx
+	// response instead of an error.
+	OnRedirect func(from, to *url.URL) error
+}
+
+// SecureRedirects returns a client option installing a redirect policy that
+// blocks https to http downgrades and redirects to hosts outside AllowedHosts
+// with a RedirectError, and strips credentials when a redirect crosses origins.
+// Unlike the default policy of net/http, which keeps credentials for
+// subdomains, any change of scheme, host or port counts as a new origin.
+//
+// Example:
+//
+//	dispatcher.Use(fetch.SetClientOptions(fetch.SecureRedirects(func(o *fetch.RedirectPolicyOptions) {
+//	    o.AllowedHosts = []string{"example.com"}
+//	})), fetch.PrepareClientMiddleware())
+func SecureRedirects(opts ...func(*RedirectPolicyOptions)) func(*http.Client) {
+	options := applyOptions(&RedirectPolicyOptions{
+		MaxRedirects:     10,
+		SensitiveHeaders: []string{"Authorization", "Proxy-Authorization", "Cookie"},
+	}, opts...)
+
+	return func(c *http.Client) {
+		c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			from, previous := via[0].URL, via[len(via)-1].URL
+			blocked := func(reason string) error {
+				return &RedirectError{From: previous.Redacted(), To: req.URL.Redacted(), Reason: reason}
+			}
+
+			if len(via) > options.MaxRedirects {
+				return blocked(fmt.Sprintf("stopped after %d redirects", options.MaxRedirects))
+			}
+			if !options.AllowDowngrade && previous.Scheme == "https" && req.URL.Scheme != "https" {
+				return blocked("https to " + req.URL.Scheme + " downgrade")
+			}
+			if len(options.AllowedHosts) > 0 && !hostAllowed(req.URL.Hostname(), options.AllowedHosts) {
+				return blocked("host " + req.URL.Hostname() + " not allowed")
+			}
+
+			if !sameOrigin(from, req.URL) {
+				for _, name := range options.SensitiveHeaders {
+					req.Header.Del(name)
+				}
+			}
+
+			if options.OnRedirect != nil {
+				return options.OnRedirect(from, req.URL)
+			}
+			return nil
+		}
+	}
+}
+
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) && strings.EqualFold(a.Hostname(), b.Hostname()) && effectivePort(a) == effectivePort(b)
+}
+
+func effectivePort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	if strings.EqualFold(u.Scheme, "https") {
+		return "443"
+	}
+	return "80"
+}
+
+func hostAllowed(host string, allowed []string) bool {
+	host = strings.ToLower(host)
+	for _, domain := range allowed {
+		domain = strings.ToLower(domain)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecureRedirects(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization") + "|" + r.Header.Get("Cookie")))
+	}))
+	defer target.Close()
+
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusFound)
+	}))
+	defer secure.Close()
+
+	var source *httptest.Server
+	source = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/same":
+			http.Redirect(w, r, source.URL+"/final", http.StatusFound)
+		case "/cross":
+			http.Redirect(w, r, target.URL, http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, source.URL+"/loop", http.StatusFound)
+		default:
+			w.Write([]byte(r.Header.Get("Authorization") + "|" + r.Header.Get("Cookie")))
+		}
+	}))
+	defer source.Close()
+
+	targetURL, _ := url.Parse(target.URL)
+
+	tests := []struct {
+		name     string
+		url      string
+		options  func(*RedirectPolicyOptions)
+		expected string
+		wantErr  string
+	}{
+		{name: "same origin keeps credentials", url: source.URL + "/same", expected: "Bearer t|session=1"},
+		{name: "cross origin strips credentials", url: source.URL + "/cross", expected: "|"},
+		{name: "https to http blocked", url: secure.URL, wantErr: "downgrade"},
+		{name: "downgrade allowed", url: secure.URL, options: func(o *RedirectPolicyOptions) { o.AllowDowngrade = true }, expected: "|"},
+		{name: "host not allowed", url: source.URL + "/cross", options: func(o *RedirectPolicyOptions) { o.AllowedHosts = []string{"example.com"} }, wantErr: "not allowed"},
+		{name: "host allowed", url: source.URL + "/cross", options: func(o *RedirectPolicyOptions) { o.AllowedHosts = []string{targetURL.Hostname()} }, expected: "|"},
+		{name: "too many redirects", url: source.URL + "/loop", options: func(o *RedirectPolicyOptions) { o.MaxRedirects = 3 }, wantErr: "stopped after 3 redirects"},
+		{name: "callback stops redirect", url: source.URL + "/cross", options: func(o *RedirectPolicyOptions) {
+			o.OnRedirect = func(from, to *url.URL) error { return http.ErrUseLastResponse }
+		}, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []func(*RedirectPolicyOptions)
+			if tt.options != nil {
+				opts = append(opts, tt.options)
+			}
+
+			resp := NewDispatcher(secure.Client(), SetClientOptions(SecureRedirects(opts...)), PrepareClientMiddleware()).
+				NewRequest().
+				UseFuncs(func(r *http.Request) {
+					r.Header.Set("Authorization", "Bearer t")
+					r.Header.Set("Cookie", "session=1")
+				}).
+				Get(tt.url)
+			defer resp.Close()
+
+			if tt.wantErr != "" {
+				require.ErrorIs(t, resp.Error, ErrRedirectBlocked)
+				assert.Contains(t, resp.Error.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, resp.Error)
+			if tt.expected == "" {
+				assert.Equal(t, http.StatusFound, resp.RawResponse.StatusCode)
+				return
+			}
+			assert.Equal(t, tt.expected, resp.String())
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package fetch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipFixture(t *testing.T, body string) string {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := io.WriteString(gz, body)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	return "HTTP/1.1 200 OK\r\nContent-Encoding: gzip\r\nContent-Length: " + strconv.Itoa(buf.Len()) + "\r\n\r\n" + buf.String()
+}
+
+func TestReadResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		wantStatus int
+		wantBody   string
+		wantHeader http.Header
+		wantErr    bool
+	}{
+		{
+			name:       "content length",
+			raw:        "HTTP/1.1 201 Created\r\nContent-Type: application/json\r\nContent-Length: 8\r\n\r\n{\"id\":1}trailing",
+			wantStatus: 201,
+			wantBody:   `{"id":1}`,
+			wantHeader: http.Header{"Content-Type": {"application/json"}, "Content-Length": {"8"}},
+		},
+		{
+			name:       "bare line feeds read to end",
+			raw:        "HTTP/1.1 200 OK\nX-Fixture: yes\n\nline one\nline two\n",
+			wantStatus: 200,
+			wantBody:   "line one\nline two\n",
+			wantHeader: http.Header{"X-Fixture": {"yes"}},
+		},
+		{
+			name:       "chunked",
+			raw:        "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n6\r\n world\r\n0\r\n\r\n",
+			wantStatus: 200,
+			wantBody:   "hello world",
+			wantHeader: http.Header{},
+		},
+		{
+			name:       "gzip",
+			raw:        gzipFixture(t, "compressed payload"),
+			wantStatus: 200,
+			wantBody:   "compressed payload",
+			wantHeader: http.Header{},
+		},
+		{name: "not http", raw: "garbage", wantErr: true},
+		{name: "broken gzip", raw: "HTTP/1.1 200 OK\r\nContent-Encoding: gzip\r\n\r\nplain", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := ReadResponse(strings.NewReader(tt.raw), nil)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Equal(t, tt.wantBody, string(body))
+			assert.Equal(t, tt.wantHeader, resp.Header)
+		})
+	}
+}
+
+func TestReplayTransport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.http")
+	require.NoError(t, os.WriteFile(path, []byte(gzipFixture(t, `{"id":1,"name":"ada"}`)), 0o644))
+
+	replay, err := LoadReplayTransport(path)
+	require.NoError(t, err)
+
+	dispatcher := NewDispatcherWithTransport(replay)
+	var user struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	resp := dispatcher.NewRequest().JSON(map[string]string{"q": "ada"}).Post("https://api.example.com/users")
+	require.NoError(t, resp.JSON(&user))
+	assert.Equal(t, 1, user.ID)
+	assert.Equal(t, "ada", user.Name)
+	assert.Equal(t, "https://api.example.com/users", resp.RawResponse.Request.URL.String())
+
+	// Package limits apply to replayed bodies like to live ones.
+	limited := NewDispatcherWithTransport(replay, ResponseBodyLimit(func(o *ResponseBodyLimitOptions) {
+		o.Policy = BodyLimitPolicy{FailAt: 4}
+	}))
+	_, err = io.ReadAll(limited.NewRequest().Get("https://api.example.com/users"))
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+
+	_, err = LoadReplayTransport(filepath.Join(t.TempDir(), "missing.http"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
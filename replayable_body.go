@@ -0,0 +1,99 @@
+package fetch
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// EnsureReplayableBody makes the body of req replayable by setting GetBody,
+// so middleware that reads the body does not break retries and redirects.
+// Bodies up to maxMem bytes are buffered in memory and larger ones spooled to
+// a temp file; a negative maxMem keeps every body in memory. Requests without
+// a body or with GetBody already set are left unchanged.
+//
+// The returned function removes the temp file, if any; call it once the
+// request is done, e.g. when the response body was closed. It is safe to call
+// more than once.
+//
+// Example:
+//
+//	func Sign(key []byte) fetch.Middleware {
+//	    return func(next fetch.Handler) fetch.Handler {
+//	        return fetch.HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+//	            cleanup, err := fetch.EnsureReplayableBody(req, 4<<20)
+//	            if err != nil {
+//	                return nil, err
+//	            }
+//	            body, _ := req.GetBody()
+//	            req.Header.Set("X-Signature", sign(key, body))
+//	            resp, err := next.Handle(client, req)
+//	            if err != nil {
+//	                cleanup()
+//	                return resp, err
+//	            }
+//	            resp.Body = fetch.OnClose(resp.Body, cleanup)
+//	            return resp, nil
+//	        })
+//	    }
+//	}
+func EnsureReplayableBody(req *http.Request, maxMem int64) (func(), error) {
+	noop := func() {}
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return noop, nil
+	}
+
+	if maxMem < 0 {
+		body, err := readRequestBody(req)
+		if err != nil {
+			return nil, err
+		}
+		setBufferedBody(req, body)
+		return noop, nil
+	}
+
+	head, err := io.ReadAll(io.LimitReader(req.Body, maxMem+1))
+	if err != nil {
+		req.Body.Close()
+		return nil, err
+	}
+	if int64(len(head)) <= maxMem {
+		req.Body.Close()
+		setBufferedBody(req, head)
+		return noop, nil
+	}
+
+	_, remove, err := spoolRequestBody(req, head, "")
+	if err != nil {
+		return nil, err
+	}
+	return sync.OnceFunc(remove), nil
+}
+
+// BodyBytes returns the body of req and leaves the request sendable. Bodies
+// with GetBody are read from a fresh copy; others are read and replaced with
+// a replayable in-memory body. It returns nil for requests without a body.
+// After a read error the body of a request without GetBody is lost.
+//
+// Example:
+//
+//	body, err := fetch.BodyBytes(req)
+//	if err != nil {
+//	    return nil, err
+//	}
+//	req.Header.Set("Content-MD5", contentMD5(body))
+func BodyBytes(req *http.Request) ([]byte, error) {
+	replayable := req.GetBody != nil
+	body, err := readRequestBody(req)
+	if err != nil || replayable || req.Body == nil || req.Body == http.NoBody {
+		return body, err
+	}
+	setBufferedBody(req, body)
+	return body, nil
+}
+
+// OnClose returns body with cleanup run once after it was closed, e.g. to
+// release resources tied to a request when its response body is closed.
+func OnClose(body io.ReadCloser, cleanup func()) io.ReadCloser {
+	return &cleanupReadCloser{ReadCloser: body, cleanup: sync.OnceFunc(cleanup)}
+}
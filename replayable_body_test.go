@@ -0,0 +1,149 @@
+package fetch
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamBody hides the concrete reader so http.NewRequest sets no GetBody.
+func streamBody(s string) io.Reader {
+	return io.MultiReader(strings.NewReader(s))
+}
+
+// readTwice reads the body of req and a copy from GetBody.
+func readTwice(t *testing.T, req *http.Request) (string, string) {
+	t.Helper()
+	body, err := io.ReadAll(req.Body)
+	require.NoError(t, err)
+	require.NotNil(t, req.GetBody)
+	replay, err := req.GetBody()
+	require.NoError(t, err)
+	replayed, err := io.ReadAll(replay)
+	require.NoError(t, err)
+	return string(body), string(replayed)
+}
+
+func TestEnsureReplayableBody(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		maxMem    int64
+		wantSpool bool
+	}{
+		{name: "buffered", body: "small", maxMem: 10},
+		{name: "at limit is buffered", body: "0123456789", maxMem: 10},
+		{name: "spooled", body: "larger than the limit", maxMem: 10, wantSpool: true},
+		{name: "unlimited memory", body: strings.Repeat("x", 1000), maxMem: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("TMPDIR", dir)
+
+			req, err := http.NewRequest(http.MethodPost, "http://example.com", streamBody(tt.body))
+			require.NoError(t, err)
+			require.Nil(t, req.GetBody)
+
+			cleanup, err := EnsureReplayableBody(req, tt.maxMem)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(tt.body)), req.ContentLength)
+
+			files, _ := os.ReadDir(dir)
+			if tt.wantSpool {
+				assert.Len(t, files, 1)
+			} else {
+				assert.Empty(t, files)
+			}
+
+			body, replayed := readTwice(t, req)
+			assert.Equal(t, tt.body, body)
+			assert.Equal(t, tt.body, replayed)
+
+			cleanup()
+			cleanup()
+			files, _ = os.ReadDir(dir)
+			assert.Empty(t, files)
+		})
+	}
+}
+
+func TestEnsureReplayableBody_Unchanged(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	require.NoError(t, err)
+	cleanup, err := EnsureReplayableBody(req, 10)
+	require.NoError(t, err)
+	cleanup()
+	assert.Nil(t, req.Body)
+
+	req, err = http.NewRequest(http.MethodPost, "http://example.com", strings.NewReader("replayable"))
+	require.NoError(t, err)
+	_, err = EnsureReplayableBody(req, 1)
+	require.NoError(t, err)
+	body, replayed := readTwice(t, req)
+	assert.Equal(t, "replayable", body)
+	assert.Equal(t, "replayable", replayed)
+}
+
+type failingReader struct{ err error }
+
+func (r failingReader) Read([]byte) (int, error) { return 0, r.err }
+
+func TestEnsureReplayableBody_ReadError(t *testing.T) {
+	readErr := errors.New("connection reset")
+	req, err := http.NewRequest(http.MethodPost, "http://example.com", failingReader{err: readErr})
+	require.NoError(t, err)
+
+	_, err = EnsureReplayableBody(req, 10)
+	assert.ErrorIs(t, err, readErr)
+}
+
+func TestBodyBytes(t *testing.T) {
+	t.Run("stream body is restored", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "http://example.com", streamBody("payload"))
+		require.NoError(t, err)
+
+		body, err := BodyBytes(req)
+		require.NoError(t, err)
+		assert.Equal(t, "payload", string(body))
+
+		sent, replayed := readTwice(t, req)
+		assert.Equal(t, "payload", sent)
+		assert.Equal(t, "payload", replayed)
+	})
+
+	t.Run("replayable body is not consumed", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "http://example.com", strings.NewReader("payload"))
+		require.NoError(t, err)
+
+		body, err := BodyBytes(req)
+		require.NoError(t, err)
+		assert.Equal(t, "payload", string(body))
+		sent, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "payload", string(sent))
+	})
+
+	t.Run("no body", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+		require.NoError(t, err)
+		body, err := BodyBytes(req)
+		require.NoError(t, err)
+		assert.Nil(t, body)
+	})
+}
+
+func TestOnClose(t *testing.T) {
+	calls := 0
+	body := OnClose(io.NopCloser(strings.NewReader("x")), func() { calls++ })
+	require.NoError(t, body.Close())
+	require.NoError(t, body.Close())
+	assert.Equal(t, 1, calls)
+}
@@ -1,6 +1,8 @@
 package fetch
 
 import (
+	"archive/tar"
+	"archive/zip"
 	"io"
 	"net/http"
 	"net/url"
@@ -35,6 +37,40 @@ func (r *Request) UseFuncs(funcs ...func(*http.Request)) *Request {
 	})
 }
 
+// UseResponseFuncs is a convenience method that wraps functions into
+// middleware post-processing the response of this request only, e.g. to
+// validate it or record metrics. The functions run in order once the
+// response was received; the first error closes the response and fails the
+// request. Middlewares added after UseResponseFuncs see the response first.
+//
+// Example:
+//
+//	resp := dispatcher.NewRequest().
+//	    UseResponseFuncs(func(resp *http.Response) error {
+//	        if resp.Header.Get("X-Tenant") != tenant {
+//	            return errors.New("response for wrong tenant")
+//	        }
+//	        return nil
+//	    }).
+//	    Get(url)
+func (r *Request) UseResponseFuncs(funcs ...func(*http.Response) error) *Request {
+	return r.Use(func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			resp, err := next.Handle(client, req)
+			if err != nil {
+				return resp, err
+			}
+			for _, f := range funcs {
+				if err := f(resp); err != nil {
+					drainAndClose(resp)
+					return nil, err
+				}
+			}
+			return resp, nil
+		})
+	})
+}
+
 // Body sets the request body from an io.Reader.
 // Options can configure Content-Type and automatic Content-Length.
 func (r *Request) Body(reader io.Reader, opts ...func(*BodyOptions)) *Request {
@@ -72,6 +108,24 @@ func (r *Request) Multipart(fields []*MultipartField, opts ...func(*MultipartOpt
 	return r.Use(Multipart(fields, opts...))
 }
 
+// Tar streams a tar archive built by the given function as the request body.
+// Automatically sets Content-Type to application/x-tar.
+func (r *Request) Tar(build func(*tar.Writer) error, opts ...func(*ArchiveOptions)) *Request {
+	return r.Use(BodyTar(build, opts...))
+}
+
+// Zip streams a zip archive built by the given function as the request body.
+// Automatically sets Content-Type to application/zip.
+func (r *Request) Zip(build func(*zip.Writer) error, opts ...func(*ArchiveOptions)) *Request {
+	return r.Use(BodyZip(build, opts...))
+}
+
+// SetResponseChunkHandler streams the response body to handler in chunks of
+// chunkSize bytes instead of buffering it. See ResponseChunks.
+func (r *Request) SetResponseChunkHandler(handler func(chunk []byte) error, chunkSize int) *Request {
+	return r.Use(ResponseChunks(handler, chunkSize))
+}
+
 // Do executes the HTTP request with accumulated middleware.
 func (r *Request) Do(req *http.Request) (*http.Response, error) {
 	return r.dispatcher.Do(req, r.middlewares...)
@@ -89,6 +143,16 @@ func (r *Request) Clone() *Request {
 // Send constructs and executes an HTTP request with the given method and URL.
 // Returns a Response which wraps the http.Response or any error.
 func (r *Request) Send(method string, u string) *Response {
+	req, err := newHTTPRequest(method, u)
+	if err != nil {
+		return buildResponse(req, nil, err)
+	}
+
+	resp, err := r.Do(req)
+	return buildResponse(req, resp, err)
+}
+
+func newHTTPRequest(method string, u string) (*http.Request, error) {
 	req := &http.Request{
 		Method:     method,
 		URL:        &url.URL{},
@@ -99,14 +163,13 @@ func (r *Request) Send(method string, u string) *Response {
 		Header:     make(http.Header),
 	}
 
-	if parsedURL, err := url.Parse(u); err != nil {
-		return buildResponse(req, nil, err)
-	} else {
-		req.URL = parsedURL
+	parsedURL, err := url.Parse(u)
+	if err != nil {
+		return req, err
 	}
+	req.URL = parsedURL
 
-	resp, err := r.Do(req)
-	return buildResponse(req, resp, err)
+	return req, nil
 }
 
 // Get method does GET HTTP request. It's defined in section 9.3.1 of [RFC 9110].
@@ -123,6 +123,66 @@ func TestRequest_UseFuncs(t *testing.T) {
 	}
 }
 
+func TestRequest_UseResponseFuncs(t *testing.T) {
+	tests := []struct {
+		name     string
+		funcs    []func(*http.Response) error
+		wantErr  error
+		wantTags []string
+	}{
+		{
+			name: "funcs run in order",
+			funcs: []func(*http.Response) error{
+				func(resp *http.Response) error {
+					resp.Header.Add("X-Tag", "a")
+					return nil
+				},
+				func(resp *http.Response) error {
+					resp.Header.Add("X-Tag", "b")
+					return nil
+				},
+			},
+			wantTags: []string{"a", "b"},
+		},
+		{
+			name: "error fails the request",
+			funcs: []func(*http.Response) error{
+				func(resp *http.Response) error { return assert.AnError },
+				func(resp *http.Response) error {
+					t.Error("func after error must not run")
+					return nil
+				},
+			},
+			wantErr: assert.AnError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			dispatcher := NewDispatcher(nil)
+			resp := dispatcher.NewRequest().UseResponseFuncs(tt.funcs...).Get(server.URL)
+			defer resp.Close()
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, resp.Error, tt.wantErr)
+				return
+			}
+			require.NoError(t, resp.Error)
+			assert.Equal(t, tt.wantTags, resp.Header.Values("X-Tag"))
+
+			other := dispatcher.NewRequest().Get(server.URL)
+			defer other.Close()
+			require.NoError(t, other.Error)
+			assert.Empty(t, other.Header.Values("X-Tag"))
+		})
+	}
+}
+
 func TestRequest_Body(t *testing.T) {
 	tests := []struct {
 		name         string
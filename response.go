@@ -2,7 +2,6 @@ package fetch
 
 import (
 	"bytes"
-	"encoding/json"
 	"encoding/xml"
 	"io"
 	"net/http"
@@ -87,15 +86,15 @@ func (r *Response) SaveToFile(fileName string) error {
 }
 
 // JSON decodes the response body as JSON into the provided struct.
+// See SetStrictJSONDecoding for rejecting unknown fields.
 func (r *Response) JSON(userStruct any) error {
 	if r.Error != nil {
 		return r.Error
 	}
 
-	jsonDecoder := json.NewDecoder(r.getInternalReader())
 	defer r.Close()
 
-	if err := jsonDecoder.Decode(&userStruct); err != nil && err != io.EOF {
+	if err := decodeJSON(r.getInternalReader(), &userStruct, r.strictJSON()); err != nil && err != io.EOF {
 		return err
 	}
 
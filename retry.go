@@ -0,0 +1,385 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rockcookies/go-fetch/internal/utils"
+)
+
+var (
+	retryInstalledKey = utils.NewContextKey[bool]("retry_installed")
+	retryCountKey     = utils.NewContextKey[int]("retry_count")
+	retryWaitKey      = utils.NewContextKey[time.Duration]("retry_wait")
+	retryHooksKey     = utils.NewContextKey[[]func(RetryAttempt)]("retry_hooks")
+	retryAttemptKey   = utils.NewContextKey[int]("retry_attempt")
+)
+
+// RetryAttempt describes a failed attempt that the Retry middleware retries.
+type RetryAttempt struct {
+	// Request is the failed attempt.
+	Request *http.Request
+	// Attempt is the number of the failed attempt, 1 for the first.
+	Attempt int
+	// StatusCode is zero when the attempt failed with Err.
+	StatusCode int
+	Err        error
+	// Delay is the wait before the next attempt.
+	Delay time.Duration
+	// TraceInfo describes the connection of the attempt when the Trace
+	// middleware is used, or is nil.
+	TraceInfo *TraceInfo
+}
+
+// RetryConditionFunc decides whether a request outcome is retried. resp is
+// nil when err is not.
+type RetryConditionFunc func(resp *http.Response, err error) bool
+
+// DefaultRetryCondition retries network errors, 429 Too Many Requests and
+// 5xx responses. Canceled requests and expired deadlines are not retried.
+func DefaultRetryCondition(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// RetryOptions configures the Retry middleware.
+type RetryOptions struct {
+	// Count is the maximum number of retries after the first attempt.
+	// Defaults to 3.
+	Count int
+	// Backoff computes the delay before each retry.
+	// Defaults to exponential backoff from 100ms, capped at 10s.
+	Backoff Backoff
+	// Condition decides whether an attempt is retried.
+	// Defaults to DefaultRetryCondition.
+	Condition RetryConditionFunc
+	// RespectRetryAfter waits at least as long as the Retry-After header of
+	// a retried 429 Too Many Requests or 503 Service Unavailable response
+	// asks for, up to MaxRetryAfter. Defaults to true.
+	RespectRetryAfter bool
+	// MaxRetryAfter caps the delay taken from Retry-After. Defaults to 30s.
+	MaxRetryAfter time.Duration
+	// OnRetry is called for every failed attempt before waiting for the
+	// next one. Requests can add hooks with OnRetry.
+	OnRetry func(RetryAttempt)
+}
+
+// Retry creates middleware that retries failed attempts. Every attempt is a
+// fresh round trip on the transport with the body replayed through GetBody;
+// bodies without GetBody are buffered in memory before the first attempt.
+// Each retry publishes a RetryScheduled event on the request's EventBus and
+// calls the OnRetry hooks. Response.Attempt tells which attempt produced the
+// response.
+//
+// The default condition does not look at the method, so non-idempotent
+// requests such as POST are retried as well; pass a Condition to restrict it.
+// Requests can override the count and the wait time with SetRetryCount and
+// SetRetryWaitTime.
+//
+// Example:
+//
+//	dispatcher.Use(fetch.Retry(func(o *fetch.RetryOptions) {
+//	    o.Count = 5
+//	    o.Backoff = fetch.FullJitterBackoff(200*time.Millisecond, 5*time.Second, nil)
+//	    o.Condition = func(resp *http.Response, err error) bool {
+//	        return fetch.DefaultRetryCondition(resp, err) && resp.Request.Method != http.MethodPost
+//	    }
+//	}))
+func Retry(opts ...func(*RetryOptions)) Middleware {
+	options := newRetryOptions(opts...)
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			req = req.WithContext(retryInstalledKey.WithValue(req.Context(), true))
+			client.Transport = &retryTransport{base: client.Transport, options: options}
+			return next.Handle(client, req)
+		})
+	}
+}
+
+func newRetryOptions(opts ...func(*RetryOptions)) *RetryOptions {
+	return applyOptions(&RetryOptions{
+		Count:             3,
+		Backoff:           CapBackoff(ExponentialBackoff(100*time.Millisecond), 10*time.Second),
+		Condition:         DefaultRetryCondition,
+		RespectRetryAfter: true,
+		MaxRetryAfter:     30 * time.Second,
+	}, opts...)
+}
+
+// SetRetryCount creates middleware overriding the retry count of the Retry
+// middleware for a request. Without a Retry middleware on the dispatcher it
+// installs one with default options.
+func SetRetryCount(count int) Middleware {
+	return retryOverride(func(ctx context.Context) context.Context {
+		return retryCountKey.WithValue(ctx, count)
+	})
+}
+
+// SetRetryWaitTime creates middleware replacing the backoff of the Retry
+// middleware with a constant wait for a request. Without a Retry middleware
+// on the dispatcher it installs one with default options.
+func SetRetryWaitTime(wait time.Duration) Middleware {
+	return retryOverride(func(ctx context.Context) context.Context {
+		return retryWaitKey.WithValue(ctx, wait)
+	})
+}
+
+func retryOverride(set func(ctx context.Context) context.Context) Middleware {
+	return func(next Handler) Handler {
+		retry := Retry()(next)
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			req = req.WithContext(set(req.Context()))
+			if installed, _ := retryInstalledKey.GetValue(req.Context()); installed {
+				return next.Handle(client, req)
+			}
+			return retry.Handle(client, req)
+		})
+	}
+}
+
+// OnRetry creates middleware adding a hook called for every failed attempt of
+// a request that the Retry middleware retries, after RetryOptions.OnRetry.
+// Without a Retry middleware on the dispatcher it installs one with default
+// options.
+//
+// Example:
+//
+//	dispatcher.Use(fetch.OnRetry(func(a fetch.RetryAttempt) {
+//	    log.Printf("attempt %d of %s failed (status %d, %v), retrying in %s",
+//	        a.Attempt, a.Request.URL, a.StatusCode, a.Err, a.Delay)
+//	}))
+func OnRetry(hook func(RetryAttempt)) Middleware {
+	return retryOverride(func(ctx context.Context) context.Context {
+		hooks, _ := retryHooksKey.GetValue(ctx)
+		return retryHooksKey.WithValue(ctx, append(slices.Clip(hooks), hook))
+	})
+}
+
+// AttemptCount returns the number of the attempt req is, 1 for the first.
+// Middlewares and transports below the Retry middleware see every attempt;
+// requests sent without Retry are always the first.
+func AttemptCount(req *http.Request) int {
+	if attempt, ok := retryAttemptKey.GetValue(req.Context()); ok {
+		return attempt
+	}
+	return 1
+}
+
+// SetRetryCount sets the retry count of this request. See SetRetryCount.
+func (r *Request) SetRetryCount(count int) *Request {
+	return r.Use(SetRetryCount(count))
+}
+
+// SetRetryWaitTime sets a constant wait between retries of this request.
+// See SetRetryWaitTime.
+func (r *Request) SetRetryWaitTime(wait time.Duration) *Request {
+	return r.Use(SetRetryWaitTime(wait))
+}
+
+// OnRetry adds a hook called for every retried attempt of this request.
+// See OnRetry.
+func (r *Request) OnRetry(hook func(RetryAttempt)) *Request {
+	return r.Use(OnRetry(hook))
+}
+
+type retryTransport struct {
+	base    http.RoundTripper
+	options *RetryOptions
+}
+
+func (t *retryTransport) unwrap() http.RoundTripper { return t.base }
+
+func (t *retryTransport) rewrap(base http.RoundTripper) http.RoundTripper {
+	clone := *t
+	clone.base = base
+	return &clone
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	ctx := req.Context()
+	count := t.options.Count
+	if override, ok := retryCountKey.GetValue(ctx); ok {
+		count = override
+	}
+	backoff := t.options.Backoff
+	if wait, ok := retryWaitKey.GetValue(ctx); ok {
+		backoff = ConstantBackoff(wait)
+	}
+	if count <= 0 {
+		return base.RoundTrip(req)
+	}
+
+	// The caller's request must not be modified; attempts are clones with
+	// a replayable body.
+	req = req.Clone(ctx)
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		body, err := readRequestBody(req)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		setBufferedBody(req, body)
+	}
+
+	hooks, _ := retryHooksKey.GetValue(ctx)
+	if t.options.OnRetry != nil {
+		hooks = append([]func(RetryAttempt){t.options.OnRetry}, hooks...)
+	}
+
+	var delay time.Duration
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+		req = req.WithContext(retryAttemptKey.WithValue(ctx, attempt+1))
+
+		resp, err := base.RoundTrip(req)
+		if attempt >= count || ctx.Err() != nil || !t.options.Condition(resp, err) {
+			return resp, err
+		}
+
+		delay = backoff.Delay(attempt, delay)
+		wait := delay
+		if t.options.RespectRetryAfter && resp != nil &&
+			(resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+			if after, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				wait = max(wait, min(after, t.options.MaxRetryAfter))
+			}
+		}
+
+		if bus := EventBusFromContext(ctx); bus != nil {
+			scheduled := RetryScheduled{Time: time.Now(), Request: req, Attempt: attempt + 1, Delay: wait, Err: err}
+			if resp != nil {
+				scheduled.StatusCode = resp.StatusCode
+			}
+			bus.Publish(scheduled)
+		}
+		if len(hooks) > 0 {
+			failed := RetryAttempt{Request: req, Attempt: attempt + 1, Err: err, Delay: wait}
+			if resp != nil {
+				failed.StatusCode = resp.StatusCode
+			}
+			if recorder, ok := traceRecorderKey.GetValue(ctx); ok {
+				failed.TraceInfo = recorder.snapshot()
+			}
+			for _, hook := range hooks {
+				hook(failed)
+			}
+		}
+		drainAndClose(resp)
+
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Attempt returns the number of the attempt that produced the response, 1
+// for the first. See AttemptCount.
+func (r *Response) Attempt() int {
+	if r.RawResponse == nil || r.RawResponse.Request == nil {
+		return 0
+	}
+	return AttemptCount(r.RawResponse.Request)
+}
+
+// RetryAfter returns the delay requested by the response's Retry-After
+// header, given in seconds or as an HTTP-date. A date in the past yields zero.
+// The boolean is false when the header is missing or malformed.
+//
+// Example:
+//
+//	resp := dispatcher.NewRequest().Get(url)
+//	if wait, ok := resp.RetryAfter(); ok && resp.RawResponse.StatusCode == http.StatusTooManyRequests {
+//	    time.Sleep(wait)
+//	}
+func (r *Response) RetryAfter() (time.Duration, bool) {
+	if r.RawResponse == nil {
+		return 0, false
+	}
+	return retryAfter(r.RawResponse.Header.Get("Retry-After"), time.Now())
+}
+
+// retryAfter parses a Retry-After value given in seconds or as an HTTP-date.
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// maxRetryBodyInspect bounds the body read by RetryOnBody conditions.
+const maxRetryBodyInspect = 1 << 20
+
+// RetryOnBody returns a retry condition that inspects the response body, e.g.
+// to retry while an API reports {"status": "PENDING"} for an eventually
+// consistent resource. JSON bodies are decoded into an any value as
+// encoding/json does; other bodies are passed as a string. The body is
+// restored afterwards, so the final response can still be read. Failed
+// requests, empty, undecodable and bodies over 1 MiB are not retried.
+//
+// Example:
+//
+//	pending := fetch.RetryOnBody(func(decoded any) bool {
+//	    body, ok := decoded.(map[string]any)
+//	    return ok && body["status"] == "PENDING"
+//	})
+//	dispatcher.Use(fetch.Retry(func(o *fetch.RetryOptions) {
+//	    o.Condition = func(resp *http.Response, err error) bool {
+//	        return fetch.DefaultRetryCondition(resp, err) || pending(resp, err)
+//	    }
+//	}))
+func RetryOnBody(fn func(decoded any) bool) RetryConditionFunc {
+	return func(resp *http.Response, err error) bool {
+		if err != nil || resp == nil || resp.Body == nil || resp.Body == http.NoBody {
+			return false
+		}
+
+		original := resp.Body
+		body, readErr := io.ReadAll(io.LimitReader(original, maxRetryBodyInspect+1))
+		resp.Body = &cleanupReadCloser{
+			ReadCloser: io.NopCloser(io.MultiReader(bytes.NewReader(body), original)),
+			cleanup:    func() { original.Close() },
+		}
+		if readErr != nil || len(body) == 0 || len(body) > maxRetryBodyInspect {
+			return false
+		}
+
+		media := mediaType(resp.Header.Get("Content-Type"))
+		if media != "application/json" && !strings.HasSuffix(media, "+json") {
+			return fn(string(body))
+		}
+		var decoded any
+		if json.Unmarshal(body, &decoded) != nil {
+			return false
+		}
+		return fn(decoded)
+	}
+}
@@ -0,0 +1,332 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyServer fails the first failures requests with status and echoes the
+// request body afterwards.
+func flakyServer(t *testing.T, failures int32, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if calls.Add(1) <= failures {
+			for name, values := range header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestRetry(t *testing.T) {
+	fast := func(o *RetryOptions) { o.Backoff = ConstantBackoff(time.Millisecond) }
+
+	tests := []struct {
+		name       string
+		failures   int32
+		status     int
+		opts       []func(*RetryOptions)
+		wantStatus int
+		wantCalls  int32
+	}{
+		{name: "recovers", failures: 2, status: 503, opts: []func(*RetryOptions){fast}, wantStatus: 200, wantCalls: 3},
+		{name: "gives up", failures: 10, status: 502, opts: []func(*RetryOptions){fast, func(o *RetryOptions) { o.Count = 2 }}, wantStatus: 502, wantCalls: 3},
+		{name: "client error not retried", failures: 1, status: 404, opts: []func(*RetryOptions){fast}, wantStatus: 404, wantCalls: 1},
+		{name: "custom condition", failures: 1, status: 404, opts: []func(*RetryOptions){fast, func(o *RetryOptions) {
+			o.Condition = func(resp *http.Response, err error) bool { return err == nil && resp.StatusCode == 404 }
+		}}, wantStatus: 200, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := flakyServer(t, tt.failures, tt.status, nil)
+			dispatcher := NewDispatcher(nil, Retry(tt.opts...))
+
+			// A plain reader has no GetBody, so the body must be buffered for replay.
+			resp := dispatcher.NewRequest().Body(io.MultiReader(strings.NewReader("pay"), strings.NewReader("load"))).Post(server.URL)
+			require.NoError(t, resp.Error)
+			assert.Equal(t, tt.wantStatus, resp.RawResponse.StatusCode)
+			if tt.wantStatus == 200 {
+				assert.Equal(t, "payload", resp.String())
+			}
+			resp.Close()
+			assert.Equal(t, tt.wantCalls, calls.Load())
+		})
+	}
+}
+
+func TestRetry_RetryAfterAndEvents(t *testing.T) {
+	server, calls := flakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}})
+
+	bus := NewEventBus()
+	var scheduled []RetryScheduled
+	SubscribeTo(bus, func(e RetryScheduled) { scheduled = append(scheduled, e) })
+
+	dispatcher := NewDispatcher(nil, bus.Middleware(), Retry(func(o *RetryOptions) {
+		o.Backoff = ConstantBackoff(time.Millisecond)
+		o.MaxRetryAfter = 50 * time.Millisecond
+	}))
+
+	start := time.Now()
+	resp := dispatcher.NewRequest().JSON(map[string]string{"a": "b"}).Post(server.URL)
+	require.NoError(t, resp.Error)
+	assert.Equal(t, `{"a":"b"}`, strings.TrimSpace(resp.String()))
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond, "Retry-After capped by MaxRetryAfter")
+	assert.Equal(t, int32(2), calls.Load())
+
+	require.Len(t, scheduled, 1)
+	assert.Equal(t, 1, scheduled[0].Attempt)
+	assert.Equal(t, http.StatusTooManyRequests, scheduled[0].StatusCode)
+	assert.Equal(t, 50*time.Millisecond, scheduled[0].Delay)
+}
+
+func TestRetry_HooksAndAttempt(t *testing.T) {
+	server, _ := flakyServer(t, 2, http.StatusBadGateway, nil)
+
+	var dispatcherHook, requestHook []RetryAttempt
+	dispatcher := NewDispatcher(nil, Retry(func(o *RetryOptions) {
+		o.Backoff = ConstantBackoff(time.Millisecond)
+		o.OnRetry = func(a RetryAttempt) { dispatcherHook = append(dispatcherHook, a) }
+	}))
+
+	resp := dispatcher.NewRequest().
+		Use(Trace()).
+		OnRetry(func(a RetryAttempt) { requestHook = append(requestHook, a) }).
+		Get(server.URL)
+	require.NoError(t, resp.Error)
+	assert.Equal(t, http.StatusOK, resp.RawResponse.StatusCode)
+	assert.Equal(t, 3, resp.Attempt())
+
+	require.Len(t, requestHook, 2)
+	assert.Equal(t, dispatcherHook, requestHook)
+	for i, a := range requestHook {
+		assert.Equal(t, i+1, a.Attempt)
+		assert.Equal(t, i+1, AttemptCount(a.Request))
+		assert.Equal(t, http.StatusBadGateway, a.StatusCode)
+		assert.Equal(t, time.Millisecond, a.Delay)
+		require.NotNil(t, a.TraceInfo)
+		assert.NotEmpty(t, a.TraceInfo.RemoteAddr)
+	}
+
+	// Without retries the response comes from the first attempt.
+	resp = NewDispatcher(nil).NewRequest().Get(server.URL)
+	require.NoError(t, resp.Error)
+	assert.Equal(t, 1, resp.Attempt())
+	assert.Equal(t, 0, (&Response{}).Attempt())
+}
+
+func TestOnRetry_InstallsRetry(t *testing.T) {
+	server, calls := flakyServer(t, 1, http.StatusServiceUnavailable, nil)
+
+	var attempts []int
+	resp := NewDispatcher(nil).NewRequest().
+		SetRetryWaitTime(time.Millisecond).
+		OnRetry(func(a RetryAttempt) { attempts = append(attempts, a.Attempt) }).
+		Get(server.URL)
+	require.NoError(t, resp.Error)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, []int{1}, attempts)
+	assert.Equal(t, 2, resp.Attempt())
+}
+
+func TestRetry_RequestOverrides(t *testing.T) {
+	t.Run("override dispatcher retry", func(t *testing.T) {
+		server, calls := flakyServer(t, 10, http.StatusServiceUnavailable, nil)
+		dispatcher := NewDispatcher(nil, Retry(func(o *RetryOptions) { o.Backoff = ConstantBackoff(time.Hour) }))
+
+		resp := dispatcher.NewRequest().SetRetryCount(1).SetRetryWaitTime(time.Millisecond).Get(server.URL)
+		require.NoError(t, resp.Error)
+		resp.Close()
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("disable retries", func(t *testing.T) {
+		server, calls := flakyServer(t, 10, http.StatusServiceUnavailable, nil)
+		dispatcher := NewDispatcher(nil, Retry())
+
+		resp := dispatcher.NewRequest().SetRetryCount(0).Get(server.URL)
+		require.NoError(t, resp.Error)
+		resp.Close()
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("standalone", func(t *testing.T) {
+		server, calls := flakyServer(t, 2, http.StatusBadGateway, nil)
+		dispatcher := NewDispatcher(nil)
+
+		resp := dispatcher.NewRequest().SetRetryCount(2).SetRetryWaitTime(time.Millisecond).Get(server.URL)
+		require.NoError(t, resp.Error)
+		assert.Equal(t, http.StatusOK, resp.RawResponse.StatusCode)
+		resp.Close()
+		assert.Equal(t, int32(3), calls.Load())
+	})
+}
+
+func TestDefaultRetryCondition(t *testing.T) {
+	tests := []struct {
+		name     string
+		resp     *http.Response
+		err      error
+		expected bool
+	}{
+		{name: "network error", err: errors.New("connection refused"), expected: true},
+		{name: "canceled", err: context.Canceled},
+		{name: "deadline", err: context.DeadlineExceeded},
+		{name: "ok", resp: &http.Response{StatusCode: 200}},
+		{name: "too many requests", resp: &http.Response{StatusCode: 429}, expected: true},
+		{name: "server error", resp: &http.Response{StatusCode: 500}, expected: true},
+		{name: "bad request", resp: &http.Response{StatusCode: 400}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, DefaultRetryCondition(tt.resp, tt.err))
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	d, ok := retryAfter("3", now)
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, d)
+
+	d, ok = retryAfter("Mon, 01 Jan 2024 00:00:10 GMT", now)
+	assert.True(t, ok)
+	assert.Equal(t, 10*time.Second, d)
+
+	_, ok = retryAfter("soon", now)
+	assert.False(t, ok)
+}
+
+func TestRetry_RetryAfterOnlyFor429And503(t *testing.T) {
+	server, calls := flakyServer(t, 1, http.StatusInternalServerError, http.Header{"Retry-After": {"60"}})
+	dispatcher := NewDispatcher(nil, Retry(func(o *RetryOptions) { o.Backoff = ConstantBackoff(time.Millisecond) }))
+
+	start := time.Now()
+	resp := dispatcher.NewRequest().Get(server.URL)
+	require.NoError(t, resp.Error)
+	resp.Close()
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestResponse_RetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "seconds", header: "120", want: 2 * time.Minute, wantOK: true},
+		{name: "past date", header: "Mon, 01 Jan 2024 00:00:00 GMT", want: 0, wantOK: true},
+		{name: "missing", header: ""},
+		{name: "malformed", header: "-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &Response{RawResponse: &http.Response{Header: http.Header{}}}
+			if tt.header != "" {
+				resp.RawResponse.Header.Set("Retry-After", tt.header)
+			}
+			got, ok := resp.RetryAfter()
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, ok := (&Response{}).RetryAfter()
+	assert.False(t, ok)
+}
+
+func TestRetryOnBody(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if calls.Add(1) < 3 {
+			w.Write([]byte(`{"status":"PENDING"}`))
+			return
+		}
+		w.Write([]byte(`{"status":"DONE"}`))
+	}))
+	defer server.Close()
+
+	pending := RetryOnBody(func(decoded any) bool {
+		body, ok := decoded.(map[string]any)
+		return ok && body["status"] == "PENDING"
+	})
+	dispatcher := NewDispatcher(nil, Retry(func(o *RetryOptions) {
+		o.Backoff = ConstantBackoff(time.Millisecond)
+		o.Condition = func(resp *http.Response, err error) bool {
+			return DefaultRetryCondition(resp, err) || pending(resp, err)
+		}
+	}))
+
+	var result struct{ Status string }
+	require.NoError(t, dispatcher.NewRequest().Get(server.URL).JSON(&result))
+	assert.Equal(t, "DONE", result.Status)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestRetryOnBody_Condition(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		err         error
+		want        bool
+		wantDecoded any
+	}{
+		{name: "json", contentType: "application/problem+json", body: `{"code":"RETRY"}`, want: true, wantDecoded: map[string]any{"code": "RETRY"}},
+		{name: "text", contentType: "text/plain", body: "busy", want: true, wantDecoded: "busy"},
+		{name: "invalid json", contentType: "application/json", body: "{", want: false},
+		{name: "empty", contentType: "application/json", body: "", want: false},
+		{name: "too large", contentType: "text/plain", body: strings.Repeat("x", maxRetryBodyInspect+1), want: false},
+		{name: "request error", err: errors.New("reset"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var decoded any
+			condition := RetryOnBody(func(v any) bool {
+				decoded = v
+				return true
+			})
+
+			var resp *http.Response
+			if tt.err == nil {
+				resp = &http.Response{
+					Header: http.Header{"Content-Type": {tt.contentType}},
+					Body:   io.NopCloser(strings.NewReader(tt.body)),
+				}
+			}
+			assert.Equal(t, tt.want, condition(resp, tt.err))
+			assert.Equal(t, tt.wantDecoded, decoded)
+
+			if resp != nil {
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				assert.Equal(t, tt.body, string(body), "body restored")
+				require.NoError(t, resp.Body.Close())
+			}
+		})
+	}
+}
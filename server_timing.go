@@ -0,0 +1,93 @@
+package fetch
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerTimingMetric is one metric of a W3C Server-Timing response header,
+// e.g. `db;dur=53.2;desc="Primary DB"`.
+type ServerTimingMetric struct {
+	Name        string
+	Duration    time.Duration
+	Description string
+}
+
+// ParseServerTiming parses the Server-Timing headers of h in order. Malformed
+// parameters are ignored; metrics without a dur parameter have a zero Duration.
+func ParseServerTiming(h http.Header) []ServerTimingMetric {
+	var metrics []ServerTimingMetric
+	for _, value := range h.Values("Server-Timing") {
+		for _, entry := range splitQuoted(value, ',') {
+			params := splitQuoted(entry, ';')
+			name := strings.TrimSpace(params[0])
+			if name == "" {
+				continue
+			}
+
+			metric := ServerTimingMetric{Name: name}
+			for _, param := range params[1:] {
+				key, val, _ := strings.Cut(param, "=")
+				val = strings.TrimSpace(val)
+				switch strings.ToLower(strings.TrimSpace(key)) {
+				case "dur":
+					if ms, err := strconv.ParseFloat(val, 64); err == nil && ms >= 0 {
+						metric.Duration = time.Duration(ms * float64(time.Millisecond))
+					}
+				case "desc":
+					metric.Description = unquote(val)
+				}
+			}
+			metrics = append(metrics, metric)
+		}
+	}
+	return metrics
+}
+
+// ServerTiming returns the metrics of the response's Server-Timing headers,
+// which attribute latency to phases inside the upstream such as cache or
+// database time. See ParseServerTiming.
+func (r *Response) ServerTiming() []ServerTimingMetric {
+	if r.RawResponse == nil {
+		return nil
+	}
+	return ParseServerTiming(r.RawResponse.Header)
+}
+
+// splitQuoted splits s at sep outside of double-quoted strings.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted, escaped, start := false, false, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && s[i] == '\\':
+			escaped = true
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquote removes the quotes and escapes of an HTTP quoted-string; other
+// values are returned unchanged.
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	var b strings.Builder
+	for i := 1; i < len(s)-1; i++ {
+		if s[i] == '\\' && i+1 < len(s)-1 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,73 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseServerTiming(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []string
+		expected []ServerTimingMetric
+	}{
+		{name: "none"},
+		{
+			name:   "spec example",
+			values: []string{`cache;desc="Cache Read";dur=23.2, db;dur=53, app;dur=47.2`},
+			expected: []ServerTimingMetric{
+				{Name: "cache", Description: "Cache Read", Duration: 23200 * time.Microsecond},
+				{Name: "db", Duration: 53 * time.Millisecond},
+				{Name: "app", Duration: 47200 * time.Microsecond},
+			},
+		},
+		{
+			name:   "multiple headers and quoted separators",
+			values: []string{`miss`, `edge;desc="a, b; \"c\"";dur=1.5`, `token;desc=plain`},
+			expected: []ServerTimingMetric{
+				{Name: "miss"},
+				{Name: "edge", Description: `a, b; "c"`, Duration: 1500 * time.Microsecond},
+				{Name: "token", Description: "plain"},
+			},
+		},
+		{
+			name:     "malformed parameters ignored",
+			values:   []string{`db;dur=fast;dur=-1;x, ,`},
+			expected: []ServerTimingMetric{{Name: "db"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParseServerTiming(http.Header{"Server-Timing": tt.values}))
+		})
+	}
+}
+
+func TestServerTiming_Stats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server-Timing", "db;dur=10, app;dur=5")
+	}))
+	defer server.Close()
+
+	stats := NewStats(func(o *StatsOptions) { o.ServerTiming = true })
+	dispatcher := NewDispatcher(nil, stats.Middleware())
+
+	for range 2 {
+		resp := dispatcher.NewRequest().Get(server.URL)
+		require.NoError(t, resp.Error)
+		assert.Equal(t, []ServerTimingMetric{{Name: "db", Duration: 10 * time.Millisecond}, {Name: "app", Duration: 5 * time.Millisecond}}, resp.ServerTiming())
+		resp.Close()
+	}
+
+	assert.Equal(t, map[string]ServerTimingSummary{
+		"db":  {Count: 2, Total: 20 * time.Millisecond},
+		"app": {Count: 2, Total: 10 * time.Millisecond},
+	}, stats.Snapshot().ServerTiming)
+	assert.Nil(t, NewStats().Snapshot().ServerTiming)
+}
@@ -0,0 +1,150 @@
+package fetch
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"time"
+)
+
+// LatencyDistribution draws the latency of one simulated round trip.
+type LatencyDistribution func(rnd RandomSource) time.Duration
+
+// FixedLatency always delays by d.
+func FixedLatency(d time.Duration) LatencyDistribution {
+	return func(RandomSource) time.Duration {
+		return d
+	}
+}
+
+// NormalLatency draws latencies from a normal distribution, clamped at zero.
+func NormalLatency(mean, stddev time.Duration) LatencyDistribution {
+	return func(rnd RandomSource) time.Duration {
+		// Box-Muller transform; 1-u keeps the logarithm finite.
+		u1, u2 := 1-rnd.float64(), rnd.float64()
+		z := math.Sqrt(-2*math.Log(u1)) * math.Cos(2*math.Pi*u2)
+		return max(mean+time.Duration(z*float64(stddev)), 0)
+	}
+}
+
+// ParetoLatency draws latencies from a Pareto distribution with minimum
+// scale, producing the long tail seen on real networks. Smaller shapes give
+// heavier tails; 1.5 to 3 are typical.
+func ParetoLatency(scale time.Duration, shape float64) LatencyDistribution {
+	return func(rnd RandomSource) time.Duration {
+		return time.Duration(float64(scale) / math.Pow(1-rnd.float64(), 1/shape))
+	}
+}
+
+// SimulatedTransportOptions configures NewSimulatedTransport.
+type SimulatedTransportOptions struct {
+	// Latency delays every round trip before the response is returned.
+	Latency LatencyDistribution
+	// Bandwidth caps how fast response bodies are read, in bytes per second.
+	// Zero means unlimited.
+	Bandwidth int64
+	// Random drives the latency distribution. Use SeededRandom for
+	// reproducible runs; nil uses the global generator.
+	Random RandomSource
+}
+
+// SimulatedTransport wraps a transport and imposes latency and bandwidth
+// limits on its responses, so tests can exercise timeout, retry and progress
+// handling under realistic network conditions. Delays honor the request
+// context.
+type SimulatedTransport struct {
+	base    http.RoundTripper
+	options *SimulatedTransportOptions
+}
+
+func (t *SimulatedTransport) unwrap() http.RoundTripper { return t.base }
+
+func (t *SimulatedTransport) rewrap(base http.RoundTripper) http.RoundTripper {
+	clone := *t
+	clone.base = base
+	return &clone
+}
+
+// NewSimulatedTransport wraps base, which may be a live transport or a
+// MockTransport. A nil base uses http.DefaultTransport.
+//
+// Example:
+//
+//	mock, _ := fetch.NewMockTransport(rules...)
+//	transport := fetch.NewSimulatedTransport(mock, func(o *fetch.SimulatedTransportOptions) {
+//	    o.Latency = fetch.ParetoLatency(20*time.Millisecond, 2)
+//	    o.Bandwidth = 256 << 10
+//	    o.Random = fetch.SeededRandom(42)
+//	})
+//	dispatcher := fetch.NewDispatcherWithTransport(transport)
+func NewSimulatedTransport(base http.RoundTripper, opts ...func(*SimulatedTransportOptions)) *SimulatedTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &SimulatedTransport{base: base, options: applyOptions(&SimulatedTransportOptions{}, opts...)}
+}
+
+// RoundTrip sends req through the wrapped transport after the simulated latency.
+func (t *SimulatedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.options.Latency != nil {
+		if err := sleepContext(req.Context(), t.options.Latency(t.options.Random)); err != nil {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, err
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || t.options.Bandwidth <= 0 || resp.Body == nil || resp.Body == http.NoBody {
+		return resp, err
+	}
+
+	resp.Body = &throttledBody{ReadCloser: resp.Body, ctx: req.Context(), rate: t.options.Bandwidth}
+	return resp, nil
+}
+
+// throttledBody limits reads to rate bytes per second.
+type throttledBody struct {
+	io.ReadCloser
+	ctx   context.Context
+	rate  int64
+	start time.Time
+	read  int64
+}
+
+func (b *throttledBody) Read(p []byte) (int, error) {
+	if b.start.IsZero() {
+		b.start = time.Now()
+	}
+	// Read in slices of a tenth of a second so progress stays smooth.
+	if chunk := max(b.rate/10, 1); int64(len(p)) > chunk {
+		p = p[:chunk]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+
+	due := time.Duration(float64(b.read) / float64(b.rate) * float64(time.Second))
+	if wait := due - time.Since(b.start); wait > 0 {
+		if ctxErr := sleepContext(b.ctx, wait); ctxErr != nil {
+			return n, ctxErr
+		}
+	}
+	return n, err
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,73 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyDistribution(t *testing.T) {
+	tests := []struct {
+		name     string
+		latency  LatencyDistribution
+		min, max time.Duration
+	}{
+		{name: "fixed", latency: FixedLatency(50 * time.Millisecond), min: 50 * time.Millisecond, max: 50 * time.Millisecond},
+		{name: "normal", latency: NormalLatency(100*time.Millisecond, 10*time.Millisecond), min: 0, max: 200 * time.Millisecond},
+		{name: "pareto", latency: ParetoLatency(20*time.Millisecond, 2), min: 20 * time.Millisecond, max: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := make([]time.Duration, 100)
+			rnd := SeededRandom(7)
+			for i := range first {
+				first[i] = tt.latency(rnd)
+				assert.GreaterOrEqual(t, first[i], tt.min)
+				assert.LessOrEqual(t, first[i], tt.max)
+			}
+
+			rnd = SeededRandom(7)
+			for i := range first {
+				assert.Equal(t, first[i], tt.latency(rnd), "same seed must give the same latencies")
+			}
+		})
+	}
+
+	var sum time.Duration
+	rnd := SeededRandom(1)
+	normal := NormalLatency(100*time.Millisecond, 10*time.Millisecond)
+	for range 1000 {
+		sum += normal(rnd)
+	}
+	assert.InDelta(t, float64(100*time.Millisecond), float64(sum/1000), float64(2*time.Millisecond))
+}
+
+func TestSimulatedTransport(t *testing.T) {
+	mock, err := NewMockTransport(MockRule{Responses: []MockResponse{{Body: strings.Repeat("x", 1000)}}})
+	require.NoError(t, err)
+
+	dispatcher := NewDispatcherWithTransport(NewSimulatedTransport(mock, func(o *SimulatedTransportOptions) {
+		o.Latency = FixedLatency(30 * time.Millisecond)
+		o.Bandwidth = 10000
+	}))
+
+	start := time.Now()
+	resp := dispatcher.NewRequest().Get("http://upstream.test/")
+	require.NoError(t, resp.Error)
+	assert.Len(t, resp.Bytes(), 1000)
+	// 30ms latency plus 1000 bytes at 10000 B/s.
+	assert.GreaterOrEqual(t, time.Since(start), 130*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://upstream.test/", nil)
+	require.NoError(t, err)
+	_, err = dispatcher.Do(req)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,175 @@
+package fetch
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// SpoolStats counts the request bodies handled by a BodySpool.
+type SpoolStats struct {
+	// Buffered is the number of bodies kept in memory.
+	Buffered uint64 `json:"buffered"`
+	// Spooled is the number of bodies written to a temp file.
+	Spooled uint64 `json:"spooled"`
+	// SpooledBytes is the total size of the spooled bodies.
+	SpooledBytes int64 `json:"spooled_bytes"`
+	// Active is the number of temp files currently on disk.
+	Active int64 `json:"active"`
+}
+
+// SpoolOptions configures a BodySpool.
+type SpoolOptions struct {
+	// Threshold is the largest body in bytes kept in memory; larger bodies
+	// are spooled to a temp file. Defaults to 1 MiB.
+	Threshold int64
+	// Dir is the directory of the temp files. Defaults to os.TempDir().
+	Dir string
+}
+
+// BodySpool makes streamed request bodies replayable so they can be retried
+// or redirected. It is safe for concurrent use.
+type BodySpool struct {
+	options *SpoolOptions
+	mu      sync.Mutex
+	stats   SpoolStats
+}
+
+// NewBodySpool creates a BodySpool.
+func NewBodySpool(opts ...func(*SpoolOptions)) *BodySpool {
+	options := applyOptions(&SpoolOptions{Threshold: 1 << 20}, opts...)
+	return &BodySpool{options: options}
+}
+
+// Stats returns a copy of the spool counters.
+func (s *BodySpool) Stats() SpoolStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
+
+// Middleware returns middleware that sets GetBody on requests whose body
+// cannot be replayed. Bodies up to the threshold are buffered in memory,
+// larger ones are spooled to a temp file that is removed when the response
+// body is closed, or right away if the request fails. Requests that already
+// have GetBody are passed through unchanged.
+//
+// The body is read when the request reaches the middleware, so place it
+// after middlewares that set the body and before middlewares that retry.
+//
+// Example:
+//
+//	spool := fetch.NewBodySpool(func(o *fetch.SpoolOptions) {
+//	    o.Threshold = 4 << 20
+//	})
+//	resp := dispatcher.NewRequest().Body(file).Use(spool.Middleware()).Put(url)
+func (s *BodySpool) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+				return next.Handle(client, req)
+			}
+
+			head, err := io.ReadAll(io.LimitReader(req.Body, s.options.Threshold+1))
+			if err != nil {
+				req.Body.Close()
+				return nil, err
+			}
+			if int64(len(head)) <= s.options.Threshold {
+				req.Body.Close()
+				setBufferedBody(req, head)
+				s.record(func(stats *SpoolStats) { stats.Buffered++ })
+				return next.Handle(client, req)
+			}
+
+			cleanup, err := s.spool(req, head)
+			if err != nil {
+				return nil, err
+			}
+
+			resp, err := next.Handle(client, req)
+			if err != nil {
+				cleanup()
+				return resp, err
+			}
+			resp.Body = &cleanupReadCloser{ReadCloser: resp.Body, cleanup: cleanup}
+			return resp, nil
+		})
+	}
+}
+
+// spool writes head and the rest of the request body to a temp file and
+// points the request at it. The returned function closes and removes the file.
+func (s *BodySpool) spool(req *http.Request, head []byte) (func(), error) {
+	size, remove, err := spoolRequestBody(req, head, s.options.Dir)
+	if err != nil {
+		return nil, err
+	}
+	s.record(func(stats *SpoolStats) {
+		stats.Spooled++
+		stats.SpooledBytes += size
+		stats.Active++
+	})
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			remove()
+			s.record(func(stats *SpoolStats) { stats.Active-- })
+		})
+	}, nil
+}
+
+// spoolRequestBody writes head and the rest of the request body to a temp
+// file in dir and points the request at it. It returns the body size and a
+// function closing and removing the file.
+func spoolRequestBody(req *http.Request, head []byte, dir string) (int64, func(), error) {
+	defer req.Body.Close()
+
+	fd, err := os.CreateTemp(dir, "fetch-spool-*")
+	if err != nil {
+		return 0, nil, err
+	}
+	remove := func() {
+		fd.Close()
+		os.Remove(fd.Name())
+	}
+
+	n, err := fd.Write(head)
+	if err != nil {
+		remove()
+		return 0, nil, err
+	}
+	rest, err := io.Copy(fd, req.Body)
+	if err != nil {
+		remove()
+		return 0, nil, err
+	}
+
+	size := int64(n) + rest
+	req.ContentLength = size
+	req.Body = io.NopCloser(io.NewSectionReader(fd, 0, size))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(io.NewSectionReader(fd, 0, size)), nil
+	}
+	return size, remove, nil
+}
+
+func (s *BodySpool) record(update func(*SpoolStats)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	update(&s.stats)
+}
+
+// cleanupReadCloser runs cleanup once the wrapped body is closed.
+type cleanupReadCloser struct {
+	io.ReadCloser
+	cleanup func()
+}
+
+func (c *cleanupReadCloser) Close() error {
+	err := c.ReadCloser.Close()
+	c.cleanup()
+	return err
+}
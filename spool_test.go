@@ -0,0 +1,98 @@
+package fetch
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// replayTwice sends the request, then replays it through GetBody the way a
+// retrying middleware would.
+func replayTwice(next Handler) Handler {
+	return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+		resp, err := next.Handle(client, req)
+		if err != nil {
+			return nil, err
+		}
+		drainAndClose(resp)
+
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = body
+		return next.Handle(client, req)
+	})
+}
+
+func TestBodySpool(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		body      string
+		wantStats SpoolStats
+	}{
+		{name: "small body buffered", body: "small", wantStats: SpoolStats{Buffered: 1}},
+		{name: "threshold body buffered", body: strings.Repeat("x", 16), wantStats: SpoolStats{Buffered: 1}},
+		{name: "large body spooled", body: strings.Repeat("y", 100), wantStats: SpoolStats{Spooled: 1, SpooledBytes: 100}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			spool := NewBodySpool(func(o *SpoolOptions) {
+				o.Threshold = 16
+				o.Dir = dir
+			})
+
+			resp := NewDispatcher(nil).NewRequest().
+				Body(io.MultiReader(strings.NewReader(tt.body))).
+				Use(spool.Middleware(), replayTwice).
+				Post(server.URL)
+			require.NoError(t, resp.Error)
+
+			if tt.wantStats.Spooled > 0 {
+				entries, _ := os.ReadDir(dir)
+				assert.Len(t, entries, 1)
+				assert.Equal(t, int64(1), spool.Stats().Active)
+			}
+
+			assert.Equal(t, tt.body, resp.String())
+			resp.Close()
+
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+			assert.Equal(t, tt.wantStats, spool.Stats())
+		})
+	}
+}
+
+func TestBodySpool_RemovesFileOnError(t *testing.T) {
+	dir := t.TempDir()
+	spool := NewBodySpool(func(o *SpoolOptions) {
+		o.Threshold = 4
+		o.Dir = dir
+	})
+
+	resp := NewDispatcher(nil).NewRequest().
+		Body(io.MultiReader(strings.NewReader("too large for memory"))).
+		Use(spool.Middleware()).
+		Post("http://127.0.0.1:1/")
+	require.Error(t, resp.Error)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	assert.Equal(t, int64(0), spool.Stats().Active)
+}
@@ -0,0 +1,219 @@
+package fetch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+// clientStateVersion is the format version written by ExportState.
+const clientStateVersion = 1
+
+// ErrStateVersion is returned by ImportState for state written by an
+// incompatible version.
+var ErrStateVersion = errors.New("unsupported client state version")
+
+// AuthState is implemented by auth components whose credentials can be
+// carried across a restart, such as oauthcli.Authenticator.
+type AuthState interface {
+	// MarshalAuthState returns the current credentials.
+	MarshalAuthState() ([]byte, error)
+	// UnmarshalAuthState restores credentials returned by MarshalAuthState.
+	UnmarshalAuthState(data []byte) error
+}
+
+// CookieState is a cookie exported from a cookie jar.
+type CookieState struct {
+	// Jar is the name the jar is registered under on the dispatcher; the
+	// dispatcher client's own jar has the empty name.
+	Jar   string `json:"jar,omitempty"`
+	URL   string `json:"url"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ClientState is the runtime state written by ExportState.
+type ClientState struct {
+	Version  int            `json:"version"`
+	Cookies  []CookieState  `json:"cookies,omitempty"`
+	Cache    []CacheRecord  `json:"cache,omitempty"`
+	HSTS     []HSTSEntry    `json:"hsts,omitempty"`
+	Circuits []CircuitEntry `json:"circuits,omitempty"`
+	// Auth holds the credentials of each AuthState by name, sealed with
+	// StateOptions.Encrypt when set.
+	Auth map[string][]byte `json:"auth,omitempty"`
+}
+
+// StateOptions selects the components saved by ExportState and restored by
+// ImportState. Components left nil are skipped.
+type StateOptions struct {
+	// Dispatcher provides the cookie jars: the jar of its client and the
+	// jars registered with RegisterCookieJar.
+	Dispatcher *Dispatcher
+	// CookieURLs are the URLs whose cookies are exported. http.CookieJar
+	// cannot enumerate its cookies, so only cookies sent to these URLs are
+	// saved, without their attributes; they are restored as session cookies
+	// for the same URL.
+	CookieURLs []string
+	Cache      *MemoryCacheStore
+	HSTS       *HSTSStore
+	// CircuitBreaker keeps open circuits open and failure counts across the
+	// restart, so a daemon does not hammer an upstream it learned is down.
+	CircuitBreaker *CircuitBreaker
+	// Auth are the auth components by name.
+	Auth map[string]AuthState
+	// Encrypt seals the credentials of Auth before they are written, e.g.
+	// with a key from the system keyring. Without it credentials are written
+	// in plain text.
+	Encrypt func(plaintext []byte) ([]byte, error)
+	// Decrypt opens credentials sealed by Encrypt.
+	Decrypt func(ciphertext []byte) ([]byte, error)
+}
+
+// ExportState writes the runtime state of the selected components as JSON,
+// so a long-running daemon can restart without losing its sessions, warmed
+// cache or circuit breaker learning. Restore it with ImportState. The output
+// contains cookies and, unless Encrypt is set, credentials; store it
+// accordingly.
+//
+// Example:
+//
+//	state := func(o *fetch.StateOptions) {
+//	    o.Dispatcher = dispatcher
+//	    o.CookieURLs = []string{"https://app.example.com/"}
+//	    o.Cache = cacheStore
+//	    o.HSTS = hsts
+//	    o.CircuitBreaker = breaker
+//	    o.Auth = map[string]fetch.AuthState{"api": authenticator}
+//	    o.Encrypt, o.Decrypt = seal, open
+//	}
+//
+//	// On shutdown:
+//	err := fetch.ExportState(file, state)
+//	// On start:
+//	err = fetch.ImportState(file, state)
+func ExportState(w io.Writer, opts ...func(*StateOptions)) error {
+	options := applyOptions(&StateOptions{}, opts...)
+	state := ClientState{Version: clientStateVersion}
+
+	if options.Dispatcher != nil {
+		for _, name := range stateJarNames(options.Dispatcher) {
+			jar := stateJar(options.Dispatcher, name)
+			for _, raw := range options.CookieURLs {
+				u, err := url.Parse(raw)
+				if err != nil {
+					return fmt.Errorf("export cookies: %w", err)
+				}
+				for _, cookie := range jar.Cookies(u) {
+					state.Cookies = append(state.Cookies, CookieState{Jar: name, URL: raw, Name: cookie.Name, Value: cookie.Value})
+				}
+			}
+		}
+	}
+	if options.Cache != nil {
+		state.Cache = options.Cache.Entries()
+	}
+	if options.HSTS != nil {
+		state.HSTS = options.HSTS.Entries()
+	}
+	if options.CircuitBreaker != nil {
+		state.Circuits = options.CircuitBreaker.Entries()
+	}
+	for _, name := range slices.Sorted(maps.Keys(options.Auth)) {
+		data, err := options.Auth[name].MarshalAuthState()
+		if err != nil {
+			return fmt.Errorf("export auth %q: %w", name, err)
+		}
+		if options.Encrypt != nil {
+			if data, err = options.Encrypt(data); err != nil {
+				return fmt.Errorf("export auth %q: %w", name, err)
+			}
+		}
+		if state.Auth == nil {
+			state.Auth = map[string][]byte{}
+		}
+		state.Auth[name] = data
+	}
+
+	return json.NewEncoder(w).Encode(state)
+}
+
+// ImportState restores state written by ExportState into the selected
+// components. Parts of the state without a matching component, such as
+// cookies of a jar that is not registered, are ignored.
+func ImportState(r io.Reader, opts ...func(*StateOptions)) error {
+	options := applyOptions(&StateOptions{}, opts...)
+
+	var state ClientState
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return fmt.Errorf("import state: %w", err)
+	}
+	if state.Version != clientStateVersion {
+		return fmt.Errorf("%w: %d", ErrStateVersion, state.Version)
+	}
+
+	if options.Dispatcher != nil {
+		for _, cookie := range state.Cookies {
+			jar := stateJar(options.Dispatcher, cookie.Jar)
+			if jar == nil {
+				continue
+			}
+			u, err := url.Parse(cookie.URL)
+			if err != nil {
+				return fmt.Errorf("import cookies: %w", err)
+			}
+			jar.SetCookies(u, []*http.Cookie{{Name: cookie.Name, Value: cookie.Value}})
+		}
+	}
+	if options.Cache != nil {
+		options.Cache.Load(state.Cache)
+	}
+	if options.HSTS != nil {
+		options.HSTS.Load(state.HSTS)
+	}
+	if options.CircuitBreaker != nil {
+		options.CircuitBreaker.Load(state.Circuits)
+	}
+	for _, name := range slices.Sorted(maps.Keys(options.Auth)) {
+		data, ok := state.Auth[name]
+		if !ok {
+			continue
+		}
+		if options.Decrypt != nil {
+			var err error
+			if data, err = options.Decrypt(data); err != nil {
+				return fmt.Errorf("import auth %q: %w", name, err)
+			}
+		}
+		if err := options.Auth[name].UnmarshalAuthState(data); err != nil {
+			return fmt.Errorf("import auth %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// stateJarNames returns the names of the jars of d, the client's jar first.
+func stateJarNames(d *Dispatcher) []string {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	var names []string
+	if d.client.Jar != nil {
+		names = append(names, "")
+	}
+	return append(names, slices.Sorted(maps.Keys(d.jars))...)
+}
+
+// stateJar returns the jar of d with name, or nil.
+func stateJar(d *Dispatcher, name string) http.CookieJar {
+	if name == "" {
+		return d.Client().Jar
+	}
+	jar, _ := d.CookieJar(name)
+	return jar
+}
@@ -0,0 +1,108 @@
+package fetch
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubAuthState struct{ token string }
+
+func (s *stubAuthState) MarshalAuthState() ([]byte, error) { return []byte(s.token), nil }
+
+func (s *stubAuthState) UnmarshalAuthState(data []byte) error {
+	s.token = string(data)
+	return nil
+}
+
+func newStateComponents(t *testing.T) (*Dispatcher, *MemoryCacheStore, *HSTSStore, *CircuitBreaker, *stubAuthState) {
+	t.Helper()
+	clientJar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	adminJar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+
+	dispatcher := NewDispatcher(&http.Client{Jar: clientJar})
+	dispatcher.RegisterCookieJar("admin", adminJar)
+	return dispatcher, NewMemoryCacheStore(), NewHSTSStore(), NewCircuitBreaker(func(o *CircuitBreakerOptions) {
+		o.FailureThreshold = 1
+	}), &stubAuthState{}
+}
+
+func TestExportImportState(t *testing.T) {
+	app, err := url.Parse("https://app.example.com/")
+	require.NoError(t, err)
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	openedAt := time.Now().UTC().Truncate(time.Second)
+
+	dispatcher, cache, hsts, breaker, auth := newStateComponents(t)
+	dispatcher.Client().Jar.SetCookies(app, []*http.Cookie{{Name: "sid", Value: "user"}})
+	adminJar, _ := dispatcher.CookieJar("admin")
+	adminJar.SetCookies(app, []*http.Cookie{{Name: "sid", Value: "admin"}})
+	cache.Set("GET https://app.example.com/items", &CacheEntry{StatusCode: 200, Header: http.Header{"Etag": {`"v1"`}}, Body: []byte("items"), Expires: expires})
+	hsts.Load([]HSTSEntry{{Host: "app.example.com", Expires: expires}})
+	breaker.Load([]CircuitEntry{{Host: "down.example.com", State: CircuitOpen, OpenedAt: openedAt}})
+	auth.token = "secret-token"
+
+	seal := func(b []byte) ([]byte, error) { return bytes.ToUpper(b), nil }
+	open := func(b []byte) ([]byte, error) { return bytes.ToLower(b), nil }
+
+	var state bytes.Buffer
+	require.NoError(t, ExportState(&state, func(o *StateOptions) {
+		o.Dispatcher = dispatcher
+		o.CookieURLs = []string{app.String()}
+		o.Cache, o.HSTS, o.CircuitBreaker = cache, hsts, breaker
+		o.Auth = map[string]AuthState{"api": auth}
+		o.Encrypt = seal
+	}))
+	assert.NotContains(t, state.String(), "c2VjcmV0LXRva2Vu", "credentials are sealed")
+
+	restored, restoredCache, restoredHSTS, restoredBreaker, restoredAuth := newStateComponents(t)
+	require.NoError(t, ImportState(&state, func(o *StateOptions) {
+		o.Dispatcher = restored
+		o.Cache, o.HSTS, o.CircuitBreaker = restoredCache, restoredHSTS, restoredBreaker
+		o.Auth = map[string]AuthState{"api": restoredAuth}
+		o.Decrypt = open
+	}))
+
+	cookieValue := func(jar http.CookieJar) string {
+		cookies := jar.Cookies(app)
+		require.Len(t, cookies, 1)
+		return cookies[0].Value
+	}
+	assert.Equal(t, "user", cookieValue(restored.Client().Jar))
+	restoredAdmin, _ := restored.CookieJar("admin")
+	assert.Equal(t, "admin", cookieValue(restoredAdmin))
+
+	entry, ok := restoredCache.Get("GET https://app.example.com/items")
+	require.True(t, ok)
+	assert.Equal(t, "items", string(entry.Body))
+	assert.Equal(t, `"v1"`, entry.Header.Get("ETag"))
+	assert.True(t, restoredHSTS.Known("app.example.com"))
+	assert.Equal(t, CircuitOpen, restoredBreaker.State("down.example.com"))
+	assert.Equal(t, []CircuitEntry{{Host: "down.example.com", State: CircuitOpen, OpenedAt: openedAt}}, restoredBreaker.Entries())
+	assert.Equal(t, "secret-token", restoredAuth.token)
+}
+
+func TestImportState_Errors(t *testing.T) {
+	err := ImportState(strings.NewReader(`{"version":99}`))
+	assert.ErrorIs(t, err, ErrStateVersion)
+
+	err = ImportState(strings.NewReader(`{"version":`))
+	assert.Error(t, err)
+
+	decryptErr := errors.New("wrong key")
+	err = ImportState(strings.NewReader(`{"version":1,"auth":{"api":"eA=="}}`), func(o *StateOptions) {
+		o.Auth = map[string]AuthState{"api": &stubAuthState{}}
+		o.Decrypt = func([]byte) ([]byte, error) { return nil, decryptErr }
+	})
+	assert.ErrorIs(t, err, decryptErr)
+}
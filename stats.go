@@ -0,0 +1,262 @@
+package fetch
+
+import (
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultSizeBuckets returns the default upper bounds in bytes of the size
+// histogram buckets. Each call returns a new slice.
+func DefaultSizeBuckets() []int64 {
+	return []int64{0, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20}
+}
+
+// SizeHistogram counts sizes into buckets. Counts[i] is the number of sizes
+// <= Bounds[i] and greater than the previous bound; the last element of
+// Counts holds sizes above every bound.
+type SizeHistogram struct {
+	Bounds []int64  `json:"bounds"`
+	Counts []uint64 `json:"counts"`
+	Count  uint64   `json:"count"`
+	Sum    int64    `json:"sum"`
+}
+
+func newSizeHistogram(bounds []int64) SizeHistogram {
+	return SizeHistogram{Bounds: bounds, Counts: make([]uint64, len(bounds)+1)}
+}
+
+func (h *SizeHistogram) observe(size int64) {
+	i, _ := slices.BinarySearch(h.Bounds, size)
+	h.Counts[i]++
+	h.Count++
+	h.Sum += size
+}
+
+func (h SizeHistogram) clone() SizeHistogram {
+	h.Bounds = slices.Clone(h.Bounds)
+	h.Counts = slices.Clone(h.Counts)
+	return h
+}
+
+// StatsSnapshot is a point-in-time copy of the collected stats.
+// It can be encoded as JSON for health or debug endpoints.
+type StatsSnapshot struct {
+	Requests      uint64            `json:"requests"`
+	Errors        uint64            `json:"errors"`
+	RequestSizes  SizeHistogram     `json:"request_sizes"`
+	ResponseSizes SizeHistogram     `json:"response_sizes"`
+	ContentTypes  map[string]uint64 `json:"content_types"`
+	StatusClasses map[string]uint64 `json:"status_classes"`
+	// ServerTiming aggregates the Server-Timing metrics of responses by name
+	// when StatsOptions.ServerTiming is set.
+	ServerTiming map[string]ServerTimingSummary `json:"server_timing,omitempty"`
+}
+
+// ServerTimingSummary aggregates the durations reported for one Server-Timing
+// metric.
+type ServerTimingSummary struct {
+	Count uint64        `json:"count"`
+	Total time.Duration `json:"total_ns"`
+}
+
+// StatsOptions configures a Stats collector.
+type StatsOptions struct {
+	// SizeBuckets are the ascending histogram bucket bounds in bytes.
+	// Defaults to DefaultSizeBuckets().
+	SizeBuckets []int64
+	// ServerTiming aggregates the Server-Timing metrics of responses into
+	// StatsSnapshot.ServerTiming.
+	ServerTiming bool
+}
+
+// Stats collects request and response size histograms and counts responses
+// by content type and status class, without any metrics dependency.
+// It is safe for concurrent use.
+//
+// The stats are cumulative since NewStats or the last Reset; there is no
+// built-in window. For a rolling view, call Snapshot and then Reset at the
+// end of every window, e.g. from a time.Ticker.
+type Stats struct {
+	mu           sync.Mutex
+	buckets      []int64
+	serverTiming bool
+	snapshot     StatsSnapshot
+}
+
+// NewStats creates an empty Stats collector.
+func NewStats(opts ...func(*StatsOptions)) *Stats {
+	options := applyOptions(&StatsOptions{SizeBuckets: DefaultSizeBuckets()}, opts...)
+
+	s := &Stats{buckets: slices.Clone(options.SizeBuckets), serverTiming: options.ServerTiming}
+	s.Reset()
+	return s
+}
+
+// Reset clears all collected stats.
+func (s *Stats) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshot = StatsSnapshot{
+		RequestSizes:  newSizeHistogram(s.buckets),
+		ResponseSizes: newSizeHistogram(s.buckets),
+		ContentTypes:  map[string]uint64{},
+		StatusClasses: map[string]uint64{},
+	}
+	if s.serverTiming {
+		s.snapshot.ServerTiming = map[string]ServerTimingSummary{}
+	}
+}
+
+// Snapshot returns a copy of the stats collected since the last Reset.
+func (s *Stats) Snapshot() StatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := s.snapshot
+	snapshot.RequestSizes = snapshot.RequestSizes.clone()
+	snapshot.ResponseSizes = snapshot.ResponseSizes.clone()
+	snapshot.ContentTypes = maps.Clone(snapshot.ContentTypes)
+	snapshot.StatusClasses = maps.Clone(snapshot.StatusClasses)
+	snapshot.ServerTiming = maps.Clone(snapshot.ServerTiming)
+	return snapshot
+}
+
+// Middleware returns middleware that records every request into the collector.
+// Request sizes are counted as the client sends the body; if a later
+// middleware replaces the client's transport, Content-Length is used instead.
+// Response sizes are recorded once the body has been read to the end or closed.
+//
+// Example:
+//
+//	stats := fetch.NewStats()
+//	dispatcher.Use(stats.Middleware())
+//
+//	http.HandleFunc("/debug/http-client", func(w http.ResponseWriter, r *http.Request) {
+//	    json.NewEncoder(w).Encode(stats.Snapshot())
+//	})
+func (s *Stats) Middleware() Middleware {
+	return bypassDryRun(func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			// The body is usually attached by request middlewares running after
+			// this one, so it is counted where the client sends it.
+			var sent atomic.Int64
+			sent.Store(-1)
+			client.Transport = &countingTransport{base: client.Transport, sent: &sent}
+
+			resp, err := next.Handle(client, req)
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			s.snapshot.Requests++
+			if size := sent.Load(); size >= 0 {
+				s.snapshot.RequestSizes.observe(size)
+			} else {
+				s.snapshot.RequestSizes.observe(max(req.ContentLength, 0))
+			}
+
+			if err != nil {
+				s.snapshot.Errors++
+				return resp, err
+			}
+
+			s.snapshot.StatusClasses[strconv.Itoa(resp.StatusCode/100)+"xx"]++
+			s.snapshot.ContentTypes[mediaType(resp.Header.Get("Content-Type"))]++
+			if s.serverTiming {
+				for _, metric := range ParseServerTiming(resp.Header) {
+					summary := s.snapshot.ServerTiming[metric.Name]
+					summary.Count++
+					summary.Total += metric.Duration
+					s.snapshot.ServerTiming[metric.Name] = summary
+				}
+			}
+
+			resp.Body = &countingReadCloser{ReadCloser: resp.Body, done: func(n int64) {
+				s.mu.Lock()
+				defer s.mu.Unlock()
+				s.snapshot.ResponseSizes.observe(n)
+			}}
+			return resp, nil
+		})
+	})
+}
+
+func mediaType(contentType string) string {
+	if contentType == "" {
+		return "unknown"
+	}
+	media, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "unknown"
+	}
+	return media
+}
+
+// countingTransport records the size of the request body sent through it.
+type countingTransport struct {
+	base http.RoundTripper
+	sent *atomic.Int64
+}
+
+func (t *countingTransport) unwrap() http.RoundTripper { return t.base }
+
+func (t *countingTransport) rewrap(base http.RoundTripper) http.RoundTripper {
+	clone := *t
+	clone.base = base
+	return &clone
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if req.Body == nil || req.Body == http.NoBody {
+		t.sent.Store(0)
+		return base.RoundTrip(req)
+	}
+
+	body := &countingReadCloser{ReadCloser: req.Body, done: t.sent.Store}
+	req = req.Clone(req.Context())
+	req.Body = body
+	resp, err := base.RoundTrip(req)
+	body.finish()
+	return resp, err
+}
+
+// countingReadCloser counts the bytes read and reports the total once on EOF or Close.
+type countingReadCloser struct {
+	io.ReadCloser
+	n    atomic.Int64
+	done func(n int64)
+	once sync.Once
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n.Add(int64(n))
+	if err == io.EOF {
+		c.finish()
+	}
+	return n, err
+}
+
+func (c *countingReadCloser) Close() error {
+	c.finish()
+	return c.ReadCloser.Close()
+}
+
+func (c *countingReadCloser) finish() {
+	if c.done != nil {
+		c.once.Do(func() { c.done(c.n.Load()) })
+	}
+}
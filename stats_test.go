@@ -0,0 +1,102 @@
+package fetch
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+		status, _ := strconv.Atoi(r.URL.Query().Get("status"))
+		w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+		w.WriteHeader(status)
+		w.Write([]byte(strings.Repeat("x", size)))
+	}))
+	defer server.Close()
+
+	stats := NewStats(func(o *StatsOptions) { o.SizeBuckets = []int64{10, 100} })
+	dispatcher := NewDispatcher(nil, stats.Middleware())
+
+	requests := []struct {
+		body   string
+		size   int
+		status int
+		ctype  string
+	}{
+		{size: 5, status: 200, ctype: "application/json; charset=utf-8"},
+		{body: strings.Repeat("y", 50), size: 50, status: 201, ctype: "application/json"},
+		{size: 500, status: 404, ctype: "text/html"},
+		{size: 0, status: 500},
+	}
+
+	for _, r := range requests {
+		resp := dispatcher.NewRequest().Body(strings.NewReader(r.body)).
+			Post(server.URL + "?size=" + strconv.Itoa(r.size) + "&status=" + strconv.Itoa(r.status) + "&type=" + url.QueryEscape(r.ctype))
+		require.NoError(t, resp.Error)
+		_ = resp.String()
+	}
+
+	resp := dispatcher.NewRequest().Get("http://127.0.0.1:1/")
+	require.Error(t, resp.Error)
+
+	snapshot := stats.Snapshot()
+	assert.Equal(t, uint64(5), snapshot.Requests)
+	assert.Equal(t, uint64(1), snapshot.Errors)
+	assert.Equal(t, []uint64{4, 1, 0}, snapshot.RequestSizes.Counts)
+	assert.Equal(t, int64(50), snapshot.RequestSizes.Sum)
+	assert.Equal(t, []uint64{2, 1, 1}, snapshot.ResponseSizes.Counts)
+	assert.Equal(t, int64(555), snapshot.ResponseSizes.Sum)
+	assert.Equal(t, map[string]uint64{"application/json": 2, "text/html": 1, "unknown": 1}, snapshot.ContentTypes)
+	assert.Equal(t, map[string]uint64{"2xx": 2, "4xx": 1, "5xx": 1}, snapshot.StatusClasses)
+
+	_, err := json.Marshal(snapshot)
+	assert.NoError(t, err)
+
+	stats.Reset()
+	snapshot = stats.Snapshot()
+	assert.Zero(t, snapshot.Requests)
+	assert.Equal(t, []uint64{0, 0, 0}, snapshot.ResponseSizes.Counts)
+	assert.Empty(t, snapshot.ContentTypes)
+}
+
+func TestStats_DefaultSizeBuckets(t *testing.T) {
+	DefaultSizeBuckets()[0] = 42
+	assert.Equal(t, int64(0), DefaultSizeBuckets()[0], "each call should return a new slice")
+
+	stats := NewStats()
+	snapshot := stats.Snapshot()
+	assert.Equal(t, DefaultSizeBuckets(), snapshot.RequestSizes.Bounds)
+
+	snapshot.RequestSizes.Bounds[0] = 42
+	assert.Equal(t, DefaultSizeBuckets(), stats.Snapshot().RequestSizes.Bounds, "snapshots should not share bounds")
+}
+
+func TestSizeHistogram(t *testing.T) {
+	tests := []struct {
+		size     int64
+		expected []uint64
+	}{
+		{size: 0, expected: []uint64{1, 0, 0}},
+		{size: 10, expected: []uint64{1, 0, 0}},
+		{size: 11, expected: []uint64{0, 1, 0}},
+		{size: 100, expected: []uint64{0, 1, 0}},
+		{size: 101, expected: []uint64{0, 0, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.FormatInt(tt.size, 10), func(t *testing.T) {
+			h := newSizeHistogram([]int64{10, 100})
+			h.observe(tt.size)
+			assert.Equal(t, tt.expected, h.Counts)
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package fetch
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StickyOptions configures session affinity.
+type StickyOptions struct {
+	// Cookie is the response cookie that designates the backend, e.g. a load
+	// balancer affinity cookie. It is sent back on pinned requests.
+	Cookie string
+	// Header is the response header that designates the backend. Its value
+	// is sent back in the same header on pinned requests.
+	Header string
+	// TTL is how long a pin lasts without being refreshed. Defaults to 10 minutes.
+	TTL time.Duration
+	// HealthChecker, when set, is told the outcome of every attempt, and
+	// backends it reports unhealthy are tried last.
+	HealthChecker *HealthChecker
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// StickySession routes requests across several base URLs, keeping their
+// paths as a prefix of the request path, and pins them to
+// one backend once a response carries the designated affinity cookie or
+// header. It is safe for concurrent use; use one StickySession per logical
+// user session.
+type StickySession struct {
+	backends []*url.URL
+	options  *StickyOptions
+
+	mu      sync.Mutex
+	pinned  int
+	value   string
+	expires time.Time
+	next    int
+}
+
+// NewStickySession creates a sticky session over the given base URLs.
+// Without Cookie or Header in the options no response pins a backend and
+// requests are spread round-robin.
+//
+// Example:
+//
+//	session, err := fetch.NewStickySession([]string{
+//	    "https://app-1.example.com",
+//	    "https://app-2.example.com",
+//	}, func(o *fetch.StickyOptions) { o.Cookie = "SERVERID" })
+//	dispatcher.Use(session.Middleware())
+//	dispatcher.NewRequest().Get("/cart")
+func NewStickySession(baseURLs []string, opts ...func(*StickyOptions)) (*StickySession, error) {
+	if len(baseURLs) == 0 {
+		return nil, errors.New("sticky session: no base URLs")
+	}
+
+	backends := make([]*url.URL, 0, len(baseURLs))
+	for _, raw := range baseURLs {
+		u, err := url.Parse(normalize(raw))
+		if err != nil {
+			return nil, err
+		}
+		backends = append(backends, u)
+	}
+
+	return &StickySession{
+		backends: backends,
+		options: applyOptions(&StickyOptions{
+			TTL: 10 * time.Minute,
+			Now: time.Now,
+		}, opts...),
+		pinned: -1,
+	}, nil
+}
+
+// Pinned returns the base URL the session is pinned to, or "" when unpinned.
+func (s *StickySession) Pinned() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pinnedLocked() < 0 {
+		return ""
+	}
+	return s.backends[s.pinned].String()
+}
+
+func (s *StickySession) pinnedLocked() int {
+	if s.pinned >= 0 && !s.options.Now().Before(s.expires) {
+		s.pinned, s.value = -1, ""
+	}
+	return s.pinned
+}
+
+// order returns the backends to try: the pinned one first, otherwise
+// starting at the next round-robin position, together with the pinned
+// backend, or -1, and its affinity value. Unhealthy backends move to the end,
+// so the pinned backend is not necessarily first.
+func (s *StickySession) order() (order []int, pinned int, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pinned = s.pinnedLocked()
+	value = s.value
+	start := pinned
+	if start < 0 {
+		start = s.next
+		s.next = (s.next + 1) % len(s.backends)
+	}
+
+	for i := range s.backends {
+		order = append(order, (start+i)%len(s.backends))
+	}
+	if health := s.options.HealthChecker; health != nil {
+		var unhealthy []int
+		order = slices.DeleteFunc(order, func(backend int) bool {
+			if health.Healthy(s.backends[backend].Host) {
+				return false
+			}
+			unhealthy = append(unhealthy, backend)
+			return true
+		})
+		order = append(order, unhealthy...)
+	}
+	return order, pinned, value
+}
+
+func (s *StickySession) update(backend int, resp *http.Response) {
+	value := ""
+	if s.options.Cookie != "" {
+		for _, cookie := range resp.Cookies() {
+			if cookie.Name == s.options.Cookie {
+				value = cookie.Value
+			}
+		}
+	}
+	if value == "" && s.options.Header != "" {
+		value = resp.Header.Get(s.options.Header)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if value != "" {
+		s.pinned, s.value = backend, value
+	}
+	if s.pinned == backend {
+		s.expires = s.options.Now().Add(s.options.TTL)
+	}
+}
+
+func (s *StickySession) unpin(backend int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pinned == backend {
+		s.pinned, s.value = -1, ""
+	}
+}
+
+// Middleware returns middleware that sends each request to the pinned
+// backend, or picks one round-robin. When a backend fails with a network
+// error or 5xx, the pin is dropped and the request is replayed on the other
+// backends in turn; the first one answering with the affinity cookie or
+// header becomes the new pin. The request body is buffered for replays.
+func (s *StickySession) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			body, err := readRequestBody(req)
+			if err != nil {
+				return nil, err
+			}
+
+			order, pinned, value := s.order()
+
+			var resp *http.Response
+			for _, backend := range order {
+				attempt := req.Clone(req.Context())
+				if body != nil {
+					setBufferedBody(attempt, body)
+				}
+				attempt.URL.Scheme = s.backends[backend].Scheme
+				attempt.URL.Host = s.backends[backend].Host
+				attempt.Host = ""
+				joinBasePath(attempt.URL, s.backends[backend])
+				if backend == pinned && value != "" {
+					s.applyAffinity(attempt, value)
+				}
+
+				drainAndClose(resp)
+				resp, err = next.Handle(client, attempt)
+				if s.options.HealthChecker != nil {
+					s.options.HealthChecker.Observe(attempt, resp, err)
+				}
+				if err == nil && resp.StatusCode < 500 {
+					s.update(backend, resp)
+					return resp, nil
+				}
+				s.unpin(backend)
+			}
+
+			return resp, err
+		})
+	}
+}
+
+// joinBasePath prefixes the path of u with the path of base.
+func joinBasePath(u, base *url.URL) {
+	if base.Path == "" || base.Path == "/" {
+		return
+	}
+
+	escaped := u.EscapedPath()
+	u.Path = strings.TrimSuffix(base.Path, "/") + "/" + strings.TrimPrefix(u.Path, "/")
+	if base.RawPath != "" || u.RawPath != "" {
+		u.RawPath = strings.TrimSuffix(base.EscapedPath(), "/") + "/" + strings.TrimPrefix(escaped, "/")
+	}
+}
+
+func (s *StickySession) applyAffinity(req *http.Request, value string) {
+	if s.options.Cookie != "" {
+		req.AddCookie(&http.Cookie{Name: s.options.Cookie, Value: value})
+	}
+	if s.options.Header != "" {
+		req.Header.Set(s.options.Header, value)
+	}
+}
@@ -0,0 +1,167 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStickyBackend(name string, healthy *bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !*healthy {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if cookie, err := r.Cookie("SERVERID"); err == nil && cookie.Value != name {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "SERVERID", Value: name})
+		w.Write([]byte(name))
+	}))
+}
+
+func TestStickySession(t *testing.T) {
+	healthyA, healthyB := true, true
+	a := newStickyBackend("a", &healthyA)
+	defer a.Close()
+	b := newStickyBackend("b", &healthyB)
+	defer b.Close()
+
+	now := time.Unix(0, 0)
+	session, err := NewStickySession([]string{a.URL, b.URL}, func(o *StickyOptions) {
+		o.Cookie = "SERVERID"
+		o.TTL = time.Minute
+		o.Now = func() time.Time { return now }
+	})
+	require.NoError(t, err)
+
+	dispatcher := NewDispatcher(nil, session.Middleware())
+	get := func() string {
+		resp := dispatcher.NewRequest().Get("/cart")
+		require.NoError(t, resp.Error)
+		return resp.String()
+	}
+
+	// Pinned to the first backend answering with the affinity cookie.
+	assert.Equal(t, "a", get())
+	assert.Equal(t, a.URL, session.Pinned())
+	assert.Equal(t, "a", get())
+	assert.Equal(t, "a", get())
+
+	// Failover re-pins to the other backend.
+	healthyA = false
+	assert.Equal(t, "b", get())
+	assert.Equal(t, b.URL, session.Pinned())
+	healthyA = true
+	assert.Equal(t, "b", get())
+
+	// The pin expires after the TTL without traffic.
+	now = now.Add(2 * time.Minute)
+	assert.Empty(t, session.Pinned())
+}
+
+func TestStickySession_UnhealthyPinnedBackend(t *testing.T) {
+	healthyA, healthyB := true, true
+	a := newStickyBackend("a", &healthyA)
+	defer a.Close()
+	b := newStickyBackend("b", &healthyB)
+	defer b.Close()
+
+	health := NewHealthChecker(func(o *HealthCheckOptions) {
+		o.FailureThreshold = 1
+		o.Interval = time.Hour
+	})
+	defer health.Close()
+
+	session, err := NewStickySession([]string{a.URL, b.URL}, func(o *StickyOptions) {
+		o.Cookie = "SERVERID"
+		o.HealthChecker = health
+	})
+	require.NoError(t, err)
+
+	dispatcher := NewDispatcher(nil, session.Middleware())
+	resp := dispatcher.NewRequest().Get("/cart")
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "a", resp.String())
+
+	// The pinned backend moves to the end, so its affinity cookie must not
+	// be sent to the backend now tried first.
+	failed, err := http.NewRequest(http.MethodGet, a.URL, nil)
+	require.NoError(t, err)
+	health.Observe(failed, &http.Response{StatusCode: http.StatusBadGateway}, nil)
+	require.False(t, health.Healthy(failed.URL.Host))
+
+	resp = dispatcher.NewRequest().Get("/cart")
+	require.NoError(t, resp.Error)
+	assert.Equal(t, http.StatusOK, resp.RawResponse.StatusCode)
+	assert.Equal(t, "b", resp.String())
+	assert.Equal(t, b.URL, session.Pinned())
+}
+
+func TestStickySession_RoundRobinWithoutAffinity(t *testing.T) {
+	healthy := true
+	a := newStickyBackend("a", &healthy)
+	defer a.Close()
+	b := newStickyBackend("b", &healthy)
+	defer b.Close()
+
+	session, err := NewStickySession([]string{a.URL, b.URL})
+	require.NoError(t, err)
+	dispatcher := NewDispatcher(nil, session.Middleware())
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		resp := dispatcher.NewRequest().Get("/")
+		require.NoError(t, resp.Error)
+		got = append(got, resp.String())
+	}
+	assert.Equal(t, []string{"a", "b", "a", "b"}, got)
+	assert.Empty(t, session.Pinned())
+
+	_, err = NewStickySession(nil)
+	assert.Error(t, err)
+}
+
+func TestStickySession_Routing(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("target"))
+	}))
+	defer target.Close()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/away" {
+			http.Redirect(w, r, target.URL, http.StatusFound)
+			return
+		}
+		w.Write([]byte(r.URL.EscapedPath()))
+	}))
+	defer backend.Close()
+
+	tests := []struct {
+		name string
+		base string
+		path string
+		want string
+	}{
+		{name: "base path is kept", base: backend.URL + "/api", path: "/cart", want: "/api/cart"},
+		{name: "trailing slash", base: backend.URL + "/api/", path: "/cart", want: "/api/cart"},
+		{name: "escaped path", base: backend.URL + "/api", path: "/a%2Fb", want: "/api/a%2Fb"},
+		{name: "no base path", base: backend.URL, path: "/cart", want: "/cart"},
+		{name: "redirect to another host", base: backend.URL + "/api", path: "/away", want: "target"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, err := NewStickySession([]string{tt.base})
+			require.NoError(t, err)
+
+			resp := NewDispatcher(nil, session.Middleware()).NewRequest().Get("http://service" + tt.path)
+			require.NoError(t, resp.Error)
+			assert.Equal(t, tt.want, resp.String())
+		})
+	}
+}
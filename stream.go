@@ -1,7 +1,9 @@
 package fetch
 
 import (
+	"fmt"
 	"io"
+	"net/http"
 	"time"
 )
 
@@ -30,3 +32,65 @@ func (w *callbackWriter) Write(p []byte) (n int, err error) {
 	}
 	return
 }
+
+// DefaultChunkSize is the chunk size used by ResponseChunks when none is given.
+const DefaultChunkSize = 32 * 1024
+
+// ResponseChunks returns middleware that delivers the response body to handler
+// in chunks of chunkSize bytes as it arrives; only the last chunk may be
+// shorter. The body is consumed and closed by the middleware, so memory use is
+// bounded by chunkSize regardless of the response size, and a slow handler
+// naturally applies backpressure to the connection.
+//
+// The chunk slice is reused between calls and must not be retained.
+// A handler error aborts the transfer and is returned as the request error.
+// A chunkSize <= 0 uses DefaultChunkSize.
+func ResponseChunks(handler func(chunk []byte) error, chunkSize int) Middleware {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			resp, err := next.Handle(client, req)
+			if err != nil {
+				return resp, err
+			}
+
+			defer resp.Body.Close()
+			if err := readChunks(resp.Body, make([]byte, chunkSize), handler); err != nil {
+				return nil, err
+			}
+
+			resp.Body = http.NoBody
+			return resp, nil
+		})
+	}
+}
+
+// readChunks passes r to handler in chunks of len(buf) bytes; only the last
+// chunk may be shorter. A body ending before io.EOF, e.g. with
+// io.ErrUnexpectedEOF when the connection drops, fails.
+func readChunks(r io.Reader, buf []byte, handler func(chunk []byte) error) error {
+	for {
+		n := 0
+		var err error
+		for n < len(buf) && err == nil {
+			var read int
+			read, err = r.Read(buf[n:])
+			n += read
+		}
+
+		if n > 0 {
+			if herr := handler(buf[:n]); herr != nil {
+				return herr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read response chunk: %w", err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package fetch
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseChunks(t *testing.T) {
+	body := strings.Repeat("0123456789", 10) // 100 bytes
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	errStop := errors.New("stop")
+
+	tests := []struct {
+		name      string
+		chunkSize int
+		failAfter int
+		wantSizes []int
+		wantErr   error
+	}{
+		{name: "fixed chunks", chunkSize: 30, wantSizes: []int{30, 30, 30, 10}},
+		{name: "single chunk", chunkSize: 0, wantSizes: []int{100}},
+		{name: "exact multiple", chunkSize: 50, wantSizes: []int{50, 50}},
+		{name: "handler aborts", chunkSize: 10, failAfter: 2, wantSizes: []int{10, 10}, wantErr: errStop},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sizes []int
+			var received strings.Builder
+
+			resp := NewDispatcher(nil).NewRequest().
+				SetResponseChunkHandler(func(chunk []byte) error {
+					sizes = append(sizes, len(chunk))
+					received.Write(chunk)
+					if tt.failAfter > 0 && len(sizes) == tt.failAfter {
+						return errStop
+					}
+					return nil
+				}, tt.chunkSize).
+				Get(server.URL)
+			defer resp.Close()
+
+			assert.Equal(t, tt.wantSizes, sizes)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, resp.Error, tt.wantErr)
+				return
+			}
+			require.NoError(t, resp.Error)
+			assert.Equal(t, body, received.String())
+			assert.Empty(t, resp.String())
+		})
+	}
+}
+
+func TestReadChunks(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       io.Reader
+		wantChunks []string
+		wantErr    error
+	}{
+		{name: "short last chunk", body: strings.NewReader("abcdefg"), wantChunks: []string{"abc", "def", "g"}},
+		{name: "exact chunks", body: strings.NewReader("abcdef"), wantChunks: []string{"abc", "def"}},
+		{name: "small reads are joined", body: iotest.OneByteReader(strings.NewReader("abcd")), wantChunks: []string{"abc", "d"}},
+		{
+			name:       "truncated body",
+			body:       io.MultiReader(strings.NewReader("abcde"), iotest.ErrReader(io.ErrUnexpectedEOF)),
+			wantChunks: []string{"abc", "de"},
+			wantErr:    io.ErrUnexpectedEOF,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var chunks []string
+			err := readChunks(tt.body, make([]byte, 3), func(chunk []byte) error {
+				chunks = append(chunks, string(chunk))
+				return nil
+			})
+			assert.Equal(t, tt.wantChunks, chunks)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
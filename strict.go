@@ -0,0 +1,78 @@
+package fetch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/rockcookies/go-fetch/internal/utils"
+)
+
+var strictJSONKey = utils.NewContextKey[bool]("strict_json")
+
+// JSONFieldError reports a JSON field that does not match the target type in
+// strict decoding mode: either a field the type does not declare or a value
+// of the wrong type. Field is the dotted path for type mismatches and the
+// field name for unknown fields.
+type JSONFieldError struct {
+	Field string
+	Err   error
+}
+
+// Error returns the error message.
+func (e *JSONFieldError) Error() string {
+	return fmt.Sprintf("strict json: field %q: %v", e.Field, e.Err)
+}
+
+// Unwrap returns the underlying decode error.
+func (e *JSONFieldError) Unwrap() error {
+	return e.Err
+}
+
+// SetStrictJSONDecoding creates middleware that toggles strict decoding for
+// Response.JSON: unknown fields and type mismatches become JSONFieldError
+// values, and numbers decoded into interface values keep their exact text as
+// json.Number. Added to a dispatcher it sets the default; added to a request
+// it overrides the dispatcher, which allows enabling it one endpoint at a time.
+func SetStrictJSONDecoding(strict bool) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			req = req.WithContext(strictJSONKey.WithValue(req.Context(), strict))
+			return next.Handle(client, req)
+		})
+	}
+}
+
+func (r *Response) strictJSON() bool {
+	if r.RawResponse == nil || r.RawResponse.Request == nil {
+		return false
+	}
+	strict, _ := strictJSONKey.GetValue(r.RawResponse.Request.Context())
+	return strict
+}
+
+func decodeJSON(reader io.Reader, v any, strict bool) error {
+	decoder := json.NewDecoder(reader)
+	if strict {
+		decoder.DisallowUnknownFields()
+		decoder.UseNumber()
+	}
+
+	err := decoder.Decode(v)
+	if err == nil || err == io.EOF || !strict {
+		return err
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &JSONFieldError{Field: typeErr.Field, Err: err}
+	}
+	// encoding/json reports unknown fields only by message.
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return &JSONFieldError{Field: strings.Trim(name, `"`), Err: err}
+	}
+	return err
+}
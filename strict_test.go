@@ -0,0 +1,72 @@
+package fetch
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetStrictJSONDecoding(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+	}
+	type user struct {
+		Name    string  `json:"name"`
+		Age     int     `json:"age"`
+		Address address `json:"address"`
+	}
+
+	tests := []struct {
+		name      string
+		body      string
+		strict    []Middleware
+		wantField string
+	}{
+		{name: "lenient ignores unknown field", body: `{"name":"a","extra":1}`},
+		{name: "strict accepts known fields", body: `{"name":"a","age":3}`, strict: []Middleware{SetStrictJSONDecoding(true)}},
+		{name: "strict rejects unknown field", body: `{"name":"a","extra":1}`, strict: []Middleware{SetStrictJSONDecoding(true)}, wantField: "extra"},
+		{name: "strict reports type mismatch path", body: `{"address":{"city":5}}`, strict: []Middleware{SetStrictJSONDecoding(true)}, wantField: "address.city"},
+		{name: "request overrides dispatcher", body: `{"extra":1}`, strict: []Middleware{SetStrictJSONDecoding(true), SetStrictJSONDecoding(false)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			resp := NewDispatcher(nil, tt.strict...).NewRequest().Get(server.URL)
+			require.NoError(t, resp.Error)
+
+			var u user
+			err := resp.JSON(&u)
+			if tt.wantField == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var fieldErr *JSONFieldError
+			require.ErrorAs(t, err, &fieldErr)
+			assert.Equal(t, tt.wantField, fieldErr.Field)
+		})
+	}
+}
+
+func TestSetStrictJSONDecoding_UseNumber(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":9007199254740993}`))
+	}))
+	defer server.Close()
+
+	resp := NewDispatcher(nil).NewRequest().Use(SetStrictJSONDecoding(true)).Get(server.URL)
+	require.NoError(t, resp.Error)
+
+	var data map[string]any
+	require.NoError(t, resp.JSON(&data))
+	assert.Equal(t, json.Number("9007199254740993"), data["id"])
+}
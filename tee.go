@@ -0,0 +1,116 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// teeChunkSize is the size of the chunks read from the body.
+	teeChunkSize = 32 * 1024
+	// teeBufferedChunks bounds the chunks buffered for each writer, so a
+	// slow writer holds back the others by at most this much data.
+	teeBufferedChunks = 4
+)
+
+// TeeWriteError reports a writer of TeeTo that failed. Index is the position
+// of the writer in the TeeTo call.
+type TeeWriteError struct {
+	Index int
+	Err   error
+}
+
+// Error returns the error message.
+func (e *TeeWriteError) Error() string {
+	return fmt.Sprintf("tee writer %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the error of the writer.
+func (e *TeeWriteError) Unwrap() error {
+	return e.Err
+}
+
+// TeeTo streams the response body to all writers at once, e.g. a file, a
+// hash and a progress meter, and closes the body. Each writer runs on its own
+// goroutine with a bounded buffer, so a slow writer does not stall the others
+// until its buffer is full. A failing writer is skipped for the rest of the
+// body while the others continue; reading stops early once every writer
+// failed. TeeTo returns the number of bytes read and the read error joined
+// with a TeeWriteError per failed writer.
+//
+// Example:
+//
+//	file, _ := os.Create("release.tar.gz")
+//	defer file.Close()
+//	hash := sha256.New()
+//	n, err := dispatcher.NewRequest().Get(url).TeeTo(file, hash, progress)
+//	if err != nil {
+//	    var writeErr *fetch.TeeWriteError
+//	    if errors.As(err, &writeErr) {
+//	        log.Printf("writer %d failed: %v", writeErr.Index, writeErr.Err)
+//	    }
+//	}
+func (r *Response) TeeTo(writers ...io.Writer) (int64, error) {
+	if r.Error != nil {
+		return 0, r.Error
+	}
+	defer r.Close()
+
+	var (
+		wg       sync.WaitGroup
+		failed   atomic.Int32
+		errs     = make([]error, len(writers))
+		channels = make([]chan []byte, len(writers))
+	)
+	for i, w := range writers {
+		channels[i] = make(chan []byte, teeBufferedChunks)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range channels[i] {
+				if errs[i] != nil {
+					continue
+				}
+				n, err := w.Write(chunk)
+				if err == nil && n < len(chunk) {
+					err = io.ErrShortWrite
+				}
+				if err != nil {
+					errs[i] = &TeeWriteError{Index: i, Err: err}
+					failed.Add(1)
+				}
+			}
+		}()
+	}
+
+	var total int64
+	var readErr error
+	src := r.getInternalReader()
+	for len(writers) > 0 && int(failed.Load()) < len(writers) {
+		// Writers keep references to the chunk, so every read gets a new one.
+		chunk := make([]byte, teeChunkSize)
+		n, err := src.Read(chunk)
+		if n > 0 {
+			total += int64(n)
+			for _, ch := range channels {
+				ch <- chunk[:n]
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				readErr = err
+			}
+			break
+		}
+	}
+
+	for _, ch := range channels {
+		close(ch)
+	}
+	wg.Wait()
+
+	return total, errors.Join(append([]error{readErr}, errs...)...)
+}
@@ -0,0 +1,89 @@
+package fetch
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingWriter accepts limit bytes and fails afterwards.
+type failingWriter struct {
+	limit int
+	err   error
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		return 0, w.err
+	}
+	w.limit -= len(p)
+	return len(p), nil
+}
+
+// shortWriter reports writing fewer bytes than it was given.
+type shortWriter struct{}
+
+func (shortWriter) Write(p []byte) (int, error) { return len(p) / 2, nil }
+
+func TestResponse_TeeTo(t *testing.T) {
+	content := strings.Repeat("0123456789", 200_000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, content)
+	}))
+	defer server.Close()
+	sum := sha256.Sum256([]byte(content))
+	diskFull := errors.New("disk full")
+
+	t.Run("all writers", func(t *testing.T) {
+		var buf bytes.Buffer
+		hash := sha256.New()
+		n, err := NewDispatcher(nil).NewRequest().Get(server.URL).TeeTo(&buf, hash)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(content)), n)
+		assert.Equal(t, content, buf.String())
+		assert.Equal(t, sum[:], hash.Sum(nil))
+	})
+
+	t.Run("failing writer does not stop others", func(t *testing.T) {
+		var buf bytes.Buffer
+		n, err := NewDispatcher(nil).NewRequest().Get(server.URL).TeeTo(&failingWriter{limit: 1000, err: diskFull}, &buf, shortWriter{})
+		assert.Equal(t, int64(len(content)), n)
+		assert.Equal(t, content, buf.String())
+
+		assert.ErrorIs(t, err, diskFull)
+		assert.ErrorIs(t, err, io.ErrShortWrite)
+		var writeErr *TeeWriteError
+		require.ErrorAs(t, err, &writeErr)
+		assert.Equal(t, 0, writeErr.Index)
+	})
+
+	t.Run("stops reading when all writers failed", func(t *testing.T) {
+		n, err := NewDispatcher(nil).NewRequest().Get(server.URL).TeeTo(&failingWriter{err: diskFull})
+		assert.ErrorIs(t, err, diskFull)
+		assert.Less(t, n, int64(len(content)))
+	})
+
+	t.Run("buffered body", func(t *testing.T) {
+		resp := NewDispatcher(nil).NewRequest().Get(server.URL)
+		require.Equal(t, content, resp.String())
+		var buf bytes.Buffer
+		n, err := resp.TeeTo(&buf)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(content)), n)
+		assert.Equal(t, content, buf.String())
+	})
+
+	t.Run("request error", func(t *testing.T) {
+		resp := &Response{Error: diskFull}
+		_, err := resp.TeeTo(io.Discard)
+		assert.ErrorIs(t, err, diskFull)
+	})
+}
@@ -0,0 +1,166 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rockcookies/go-fetch/internal/utils"
+)
+
+// ErrURLRejected is matched by errors.Is for every URLRejectedError.
+var ErrURLRejected = errors.New("url rejected")
+
+var tenantKey = utils.NewContextKey[string]("tenant")
+
+// URLRejectedError reports an outgoing URL rejected by a URLValidator.
+type URLRejectedError struct {
+	URL    string
+	Tenant string
+	Err    error
+}
+
+// Error returns the error message.
+func (e *URLRejectedError) Error() string {
+	return fmt.Sprintf("url %s rejected for tenant %q: %v", e.URL, e.Tenant, e.Err)
+}
+
+// Is reports whether target is ErrURLRejected.
+func (e *URLRejectedError) Is(target error) bool {
+	return target == ErrURLRejected
+}
+
+// Unwrap returns the validator's error.
+func (e *URLRejectedError) Unwrap() error {
+	return e.Err
+}
+
+// URLValidator checks the final URL of an outgoing request for tenant, which
+// is "" when the request carries none. It may rewrite u in place; returning
+// an error rejects the request before it is dialed.
+type URLValidator func(ctx context.Context, tenant string, u *url.URL) error
+
+// WithTenant returns a context carrying the tenant of the requests made with it.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return tenantKey.WithValue(ctx, tenant)
+}
+
+// TenantFromContext returns the tenant stored by WithTenant.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	return tenantKey.GetValue(ctx)
+}
+
+// SetTenant creates middleware that assigns the request to tenant.
+func SetTenant(tenant string) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			req = req.WithContext(WithTenant(req.Context(), tenant))
+			return next.Handle(client, req)
+		})
+	}
+}
+
+// ValidateURL creates middleware that runs validators on the final URL of
+// every outgoing request, including each redirect hop, right before it is
+// sent. Validators run in order, each seeing the rewrites of the previous
+// ones; the first error fails the request with a URLRejectedError. It works
+// at transport level, so it sees URLs built by later middlewares; place it
+// after middlewares that replace the client's transport.
+//
+// Example:
+//
+//	dispatcher.Use(fetch.ValidateURL(fetch.TenantSubdomain("api.example.com")))
+//	resp := dispatcher.NewRequest().Use(fetch.SetTenant("acme")).Get("https://api.example.com/v1/users")
+//	// sent to https://acme.api.example.com/v1/users
+func ValidateURL(validators ...URLValidator) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			client.Transport = &urlValidatingTransport{base: client.Transport, validators: validators}
+			return next.Handle(client, req)
+		})
+	}
+}
+
+type urlValidatingTransport struct {
+	base       http.RoundTripper
+	validators []URLValidator
+}
+
+func (t *urlValidatingTransport) unwrap() http.RoundTripper { return t.base }
+
+func (t *urlValidatingTransport) rewrap(base http.RoundTripper) http.RoundTripper {
+	clone := *t
+	clone.base = base
+	return &clone
+}
+
+func (t *urlValidatingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	tenant, _ := TenantFromContext(req.Context())
+	u := *req.URL
+	if req.URL.User != nil {
+		user := *req.URL.User
+		u.User = &user
+	}
+
+	for _, validate := range t.validators {
+		if err := validate(req.Context(), tenant, &u); err != nil {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			var rejected *URLRejectedError
+			if !errors.As(err, &rejected) {
+				err = &URLRejectedError{URL: u.Redacted(), Tenant: tenant, Err: err}
+			}
+			return nil, err
+		}
+	}
+
+	if u.String() != req.URL.String() {
+		original := req.URL.Host
+		req = req.Clone(req.Context())
+		req.URL = &u
+		if req.Host == original {
+			req.Host = u.Host
+		}
+	}
+	return base.RoundTrip(req)
+}
+
+// TenantSubdomain returns a URLValidator isolating tenants on subdomains of
+// domain: a request to domain itself is rewritten to <tenant>.domain, and a
+// request to a subdomain belonging to another tenant, or made without a
+// tenant, is rejected. Hosts outside domain are left alone.
+func TenantSubdomain(domain string) URLValidator {
+	domain = strings.ToLower(domain)
+
+	return func(ctx context.Context, tenant string, u *url.URL) error {
+		host := strings.ToLower(u.Hostname())
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			return nil
+		}
+		if tenant == "" {
+			return errors.New("request has no tenant")
+		}
+
+		want := strings.ToLower(tenant) + "." + domain
+		if host == domain {
+			if port := u.Port(); port != "" {
+				want += ":" + port
+			}
+			u.Host = want
+			return nil
+		}
+		if host != want {
+			return fmt.Errorf("host %s belongs to another tenant", host)
+		}
+		return nil
+	}
+}
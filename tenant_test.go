@@ -0,0 +1,75 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateURL(t *testing.T) {
+	mock, err := NewMockTransport(
+		MockRule{Path: "/redirect", Responses: []MockResponse{{Status: http.StatusFound, Headers: map[string]string{"Location": "https://globex.api.example.com/data"}}}},
+		MockRule{Responses: []MockResponse{{Body: "{{.Host}}{{.Path}}"}}},
+	)
+	require.NoError(t, err)
+
+	blockAdmin := func(ctx context.Context, tenant string, u *url.URL) error {
+		if u.Path == "/admin" {
+			return errors.New("admin api is not reachable from tenant calls")
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name     string
+		tenant   string
+		url      string
+		expected string
+		wantErr  string
+	}{
+		{name: "bare domain rewritten to tenant subdomain", tenant: "acme", url: "https://api.example.com/users", expected: "acme.api.example.com/users"},
+		{name: "own subdomain allowed", tenant: "Acme", url: "https://acme.api.example.com/users", expected: "acme.api.example.com/users"},
+		{name: "port kept", tenant: "acme", url: "https://api.example.com:8443/users", expected: "acme.api.example.com:8443/users"},
+		{name: "cross tenant rejected", tenant: "acme", url: "https://globex.api.example.com/users", wantErr: "belongs to another tenant"},
+		{name: "missing tenant rejected", url: "https://api.example.com/users", wantErr: "request has no tenant"},
+		{name: "other hosts untouched", url: "https://status.example.org/", expected: "status.example.org/"},
+		{name: "validators chained", tenant: "acme", url: "https://api.example.com/admin", wantErr: "admin api"},
+		{name: "redirect hop validated", tenant: "acme", url: "https://api.example.com/redirect", wantErr: "belongs to another tenant"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dispatcher := NewDispatcherWithTransport(mock, ValidateURL(TenantSubdomain("api.example.com"), blockAdmin))
+
+			req := dispatcher.NewRequest()
+			if tt.tenant != "" {
+				req.Use(SetTenant(tt.tenant))
+			}
+			resp := req.Get(tt.url)
+			defer resp.Close()
+
+			if tt.wantErr != "" {
+				require.ErrorIs(t, resp.Error, ErrURLRejected)
+				assert.ErrorContains(t, resp.Error, tt.wantErr)
+				return
+			}
+			require.NoError(t, resp.Error)
+			assert.Equal(t, tt.expected, resp.String())
+			assert.Equal(t, tt.url, resp.RawRequest.URL.String(), "caller's request must not be modified")
+		})
+	}
+}
+
+func TestTenantFromContext(t *testing.T) {
+	_, ok := TenantFromContext(context.Background())
+	assert.False(t, ok)
+
+	tenant, ok := TenantFromContext(WithTenant(context.Background(), "acme"))
+	assert.True(t, ok)
+	assert.Equal(t, "acme", tenant)
+}
@@ -0,0 +1,185 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrPhaseTimeout is matched by errors.Is for every PhaseTimeoutError.
+var ErrPhaseTimeout = errors.New("phase timeout exceeded")
+
+// Connection phases reported by PhaseTimeoutError.
+const (
+	// PhaseDial is establishing the TCP connection, including name resolution.
+	PhaseDial = "dial"
+	// PhaseTLSHandshake is the TLS handshake of a new connection.
+	PhaseTLSHandshake = "tls_handshake"
+	// PhaseResponseHeader is waiting for the response headers after the
+	// request was written.
+	PhaseResponseHeader = "response_header"
+)
+
+// PhaseTimeoutError reports a request that exceeded one of the phase
+// timeouts of the Timeout middleware.
+type PhaseTimeoutError struct {
+	Phase string
+	Limit time.Duration
+	Err   error
+}
+
+// Error returns the error message.
+func (e *PhaseTimeoutError) Error() string {
+	return fmt.Sprintf("%s timeout of %s exceeded: %v", e.Phase, e.Limit, e.Err)
+}
+
+// Is reports whether target is ErrPhaseTimeout.
+func (e *PhaseTimeoutError) Is(target error) bool {
+	return target == ErrPhaseTimeout
+}
+
+// Unwrap returns the underlying error.
+func (e *PhaseTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports true, so the error satisfies net.Error-style checks.
+func (e *PhaseTimeoutError) Timeout() bool {
+	return true
+}
+
+// TimeoutOptions configures the phase timeouts of the Timeout middleware.
+// Zero leaves the setting of the client's transport unchanged.
+type TimeoutOptions struct {
+	// Dial bounds establishing a new connection.
+	Dial time.Duration
+	// TLSHandshake bounds the TLS handshake of a new connection, see
+	// http.Transport.TLSHandshakeTimeout.
+	TLSHandshake time.Duration
+	// ResponseHeader bounds the wait for the response headers after the
+	// request was written, see http.Transport.ResponseHeaderTimeout.
+	ResponseHeader time.Duration
+}
+
+// Timeout creates middleware bounding a request. A positive total deadline
+// wraps the request context as TotalTimeout does, covering the middlewares
+// after it, the round trips and reading the body. The options bound the
+// phases of the connection separately; exceeding one fails the request with
+// a PhaseTimeoutError naming the phase.
+//
+// The phase timeouts are applied to a copy of the client's *http.Transport
+// that is shared by all requests through this middleware with the same base
+// transport, so connections are still pooled. They have no effect on other
+// transports; add the middleware before middlewares wrapping the transport.
+//
+// Example:
+//
+//	dispatcher.Use(fetch.Timeout(10*time.Second, func(o *fetch.TimeoutOptions) {
+//	    o.Dial = time.Second
+//	    o.TLSHandshake = 2 * time.Second
+//	    o.ResponseHeader = 5 * time.Second
+//	}))
+//	resp := dispatcher.NewRequest().Get(url)
+//	if errors.Is(resp.Error, fetch.ErrPhaseTimeout) {
+//	    var timeoutErr *fetch.PhaseTimeoutError
+//	    errors.As(resp.Error, &timeoutErr)
+//	    log.Printf("%s took longer than %s", timeoutErr.Phase, timeoutErr.Limit)
+//	}
+func Timeout(total time.Duration, opts ...func(*TimeoutOptions)) Middleware {
+	options := applyOptions(&TimeoutOptions{}, opts...)
+	phases := options.Dial > 0 || options.TLSHandshake > 0 || options.ResponseHeader > 0
+
+	var (
+		mu         sync.Mutex
+		transports = map[*http.Transport]http.RoundTripper{}
+	)
+	phaseTransport := func(base *http.Transport) http.RoundTripper {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if transport, ok := transports[base]; ok {
+			return transport
+		}
+		transport := newPhaseTimeoutTransport(base, options)
+		transports[base] = transport
+		return transport
+	}
+
+	var totalTimeout Middleware
+	if total > 0 {
+		totalTimeout = TotalTimeout(total)
+	}
+
+	return func(next Handler) Handler {
+		if totalTimeout != nil {
+			next = totalTimeout(next)
+		}
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			if phases {
+				base := client.Transport
+				if base == nil {
+					base = http.DefaultTransport
+				}
+				if t, ok := base.(*http.Transport); ok {
+					client.Transport = phaseTransport(t)
+				}
+			}
+			return next.Handle(client, req)
+		})
+	}
+}
+
+// phaseTimeoutTransport reports the timeouts of its transport as
+// PhaseTimeoutError.
+type phaseTimeoutTransport struct {
+	transport *http.Transport
+	options   *TimeoutOptions
+}
+
+func newPhaseTimeoutTransport(base *http.Transport, options *TimeoutOptions) *phaseTimeoutTransport {
+	transport := base.Clone()
+	if options.TLSHandshake > 0 {
+		transport.TLSHandshakeTimeout = options.TLSHandshake
+	}
+	if options.ResponseHeader > 0 {
+		transport.ResponseHeaderTimeout = options.ResponseHeader
+	}
+	if options.Dial > 0 {
+		dial := transport.DialContext
+		if dial == nil {
+			dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+		}
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dialCtx, cancel := context.WithTimeout(ctx, options.Dial)
+			defer cancel()
+
+			conn, err := dial(dialCtx, network, addr)
+			if err != nil && ctx.Err() == nil && errors.Is(dialCtx.Err(), context.DeadlineExceeded) {
+				return nil, &PhaseTimeoutError{Phase: PhaseDial, Limit: options.Dial, Err: err}
+			}
+			return conn, err
+		}
+	}
+	return &phaseTimeoutTransport{transport: transport, options: options}
+}
+
+func (t *phaseTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.transport.RoundTrip(req)
+	if err == nil || errors.Is(err, ErrPhaseTimeout) {
+		return resp, err
+	}
+
+	// The transport reports these timeouts with unexported errors.
+	switch message := err.Error(); {
+	case t.options.TLSHandshake > 0 && strings.Contains(message, "TLS handshake timeout"):
+		err = &PhaseTimeoutError{Phase: PhaseTLSHandshake, Limit: t.options.TLSHandshake, Err: err}
+	case t.options.ResponseHeader > 0 && strings.Contains(message, "timeout awaiting response headers"):
+		err = &PhaseTimeoutError{Phase: PhaseResponseHeader, Limit: t.options.ResponseHeader, Err: err}
+	}
+	return resp, err
+}
@@ -0,0 +1,126 @@
+package fetch
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeout_Phases(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+
+	// A listener that accepts connections but never answers the handshake.
+	silent, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer silent.Close()
+	go func() {
+		for {
+			conn, err := silent.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	blockingDial := &http.Transport{DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}}
+
+	tests := []struct {
+		name      string
+		transport http.RoundTripper
+		url       string
+		opts      func(*TimeoutOptions)
+		wantPhase string
+	}{
+		{
+			name:      "dial",
+			transport: blockingDial,
+			url:       "http://example.com/",
+			opts:      func(o *TimeoutOptions) { o.Dial = 20 * time.Millisecond },
+			wantPhase: PhaseDial,
+		},
+		{
+			name:      "tls handshake",
+			transport: &http.Transport{},
+			url:       "https://" + silent.Addr().String() + "/",
+			opts:      func(o *TimeoutOptions) { o.TLSHandshake = 20 * time.Millisecond },
+			wantPhase: PhaseTLSHandshake,
+		},
+		{
+			name:      "response header",
+			transport: &http.Transport{},
+			url:       slow.URL,
+			opts:      func(o *TimeoutOptions) { o.ResponseHeader = 20 * time.Millisecond },
+			wantPhase: PhaseResponseHeader,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dispatcher := NewDispatcherWithTransport(tt.transport, Timeout(0, tt.opts))
+			start := time.Now()
+			resp := dispatcher.NewRequest().Get(tt.url)
+			assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+			var timeoutErr *PhaseTimeoutError
+			require.ErrorAs(t, resp.Error, &timeoutErr)
+			assert.ErrorIs(t, resp.Error, ErrPhaseTimeout)
+			assert.Equal(t, tt.wantPhase, timeoutErr.Phase)
+			assert.Equal(t, 20*time.Millisecond, timeoutErr.Limit)
+		})
+	}
+}
+
+func TestTimeout_Total(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	resp := NewDispatcher(nil, Timeout(20*time.Millisecond)).NewRequest().Get(server.URL)
+	assert.ErrorIs(t, resp.Error, ErrTotalTimeout)
+	assert.NotErrorIs(t, resp.Error, ErrPhaseTimeout)
+}
+
+func TestTimeout_PoolsConnections(t *testing.T) {
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	dispatcher := NewDispatcherWithTransport(&http.Transport{}, Timeout(time.Second, func(o *TimeoutOptions) {
+		o.ResponseHeader = time.Second
+	}))
+	for i := 0; i < 3; i++ {
+		resp := dispatcher.NewRequest().Get(server.URL)
+		require.NoError(t, resp.Error)
+		assert.Equal(t, "ok", resp.String())
+	}
+	assert.Equal(t, int32(1), conns.Load())
+}
@@ -0,0 +1,159 @@
+package fetch
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rockcookies/go-fetch/internal/utils"
+)
+
+var (
+	timingRecorderKey = utils.NewContextKey[*timingRecorder]("timing_recorder")
+	timingSpanKey     = utils.NewContextKey[*timingSpan]("timing_span")
+)
+
+// MiddlewareTiming is the time a named middleware spent on a request.
+// Before is the time from entering the middleware until it called the next
+// handler and After the time from the next handler returning until the
+// middleware returned. Total includes the time spent in the rest of the chain.
+// A middleware that short-circuits the chain reports its whole time as Before.
+type MiddlewareTiming struct {
+	Name   string
+	Before time.Duration
+	After  time.Duration
+	Total  time.Duration
+}
+
+// Own returns the time spent in the middleware itself, excluding the rest of the chain.
+func (t MiddlewareTiming) Own() time.Duration {
+	return t.Before + t.After
+}
+
+// TimingOptions configures the Timings middleware.
+type TimingOptions struct {
+	// OnComplete is called with the timings of every request once the chain
+	// returned, e.g. to feed a metrics system.
+	OnComplete func(req *http.Request, timings []MiddlewareTiming)
+}
+
+type timingRecorder struct {
+	mu      sync.Mutex
+	timings []MiddlewareTiming
+}
+
+func (r *timingRecorder) add(t MiddlewareTiming) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timings = append(r.timings, t)
+}
+
+func (r *timingRecorder) snapshot() []MiddlewareTiming {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]MiddlewareTiming(nil), r.timings...)
+}
+
+type timingSpan struct {
+	mu        sync.Mutex
+	nextStart time.Time
+	nextEnd   time.Time
+}
+
+// Timings creates middleware that records the time contribution of every
+// middleware wrapped with Named further down the chain. Add it first so
+// all named middlewares are covered. The result is available from
+// Response.Timings and TimingOptions.OnComplete, ordered by completion,
+// i.e. innermost middleware first.
+//
+// Example:
+//
+//	dispatcher := fetch.NewDispatcher(nil,
+//	    fetch.Timings(),
+//	    fetch.Named("auth", fetch.Reauth(orchestrator)),
+//	    fetch.Named("cache", fetch.Cache()),
+//	)
+func Timings(opts ...func(*TimingOptions)) Middleware {
+	options := applyOptions(&TimingOptions{}, opts...)
+
+	return bypassDryRun(func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			recorder := &timingRecorder{}
+			req = req.WithContext(timingRecorderKey.WithValue(req.Context(), recorder))
+
+			resp, err := next.Handle(client, req)
+
+			if options.OnComplete != nil {
+				options.OnComplete(req, recorder.snapshot())
+			}
+			return resp, err
+		})
+	})
+}
+
+// Named labels a middleware for the Timings breakdown. Without Timings in the
+// chain it adds no overhead besides a context lookup.
+func Named(name string, middleware Middleware) Middleware {
+	return func(next Handler) Handler {
+		inner := middleware(HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			span, ok := timingSpanKey.GetValue(req.Context())
+			if !ok {
+				return next.Handle(client, req)
+			}
+
+			start := time.Now()
+			resp, err := next.Handle(client, req)
+			end := time.Now()
+
+			span.mu.Lock()
+			if span.nextStart.IsZero() {
+				span.nextStart = start
+			}
+			span.nextEnd = end
+			span.mu.Unlock()
+
+			return resp, err
+		}))
+
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			recorder, ok := timingRecorderKey.GetValue(req.Context())
+			if !ok {
+				return inner.Handle(client, req)
+			}
+
+			span := &timingSpan{}
+			req = req.WithContext(timingSpanKey.WithValue(req.Context(), span))
+
+			start := time.Now()
+			resp, err := inner.Handle(client, req)
+			end := time.Now()
+
+			timing := MiddlewareTiming{Name: name, Total: end.Sub(start)}
+			span.mu.Lock()
+			if span.nextStart.IsZero() {
+				timing.Before = timing.Total
+			} else {
+				timing.Before = span.nextStart.Sub(start)
+				timing.After = end.Sub(span.nextEnd)
+			}
+			span.mu.Unlock()
+
+			recorder.add(timing)
+			return resp, err
+		})
+	}
+}
+
+// Timings returns the middleware timings recorded by the Timings middleware,
+// or nil if it was not used.
+func (r *Response) Timings() []MiddlewareTiming {
+	if r.RawResponse == nil || r.RawResponse.Request == nil {
+		return nil
+	}
+
+	recorder, ok := timingRecorderKey.GetValue(r.RawResponse.Request.Context())
+	if !ok {
+		return nil
+	}
+	return recorder.snapshot()
+}
@@ -0,0 +1,83 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sleepMiddleware(before, after time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			time.Sleep(before)
+			resp, err := next.Handle(client, req)
+			time.Sleep(after)
+			return resp, err
+		})
+	}
+}
+
+func TestTimings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	var hooked []MiddlewareTiming
+	dispatcher := NewDispatcher(nil,
+		Timings(func(o *TimingOptions) {
+			o.OnComplete = func(req *http.Request, timings []MiddlewareTiming) { hooked = timings }
+		}),
+		Named("outer", sleepMiddleware(20*time.Millisecond, 0)),
+		sleepMiddleware(0, 0),
+		Named("inner", sleepMiddleware(0, 20*time.Millisecond)),
+	)
+
+	resp := dispatcher.NewRequest().Get(server.URL)
+	defer resp.Close()
+	require.NoError(t, resp.Error)
+
+	timings := resp.Timings()
+	require.Len(t, timings, 2)
+	assert.Equal(t, timings, hooked)
+
+	inner, outer := timings[0], timings[1]
+	assert.Equal(t, "inner", inner.Name)
+	assert.Equal(t, "outer", outer.Name)
+
+	assert.Less(t, inner.Before, 10*time.Millisecond)
+	assert.GreaterOrEqual(t, inner.After, 20*time.Millisecond)
+	assert.GreaterOrEqual(t, outer.Before, 20*time.Millisecond)
+	assert.Less(t, outer.After, 10*time.Millisecond)
+	assert.GreaterOrEqual(t, outer.Total, outer.Own()+inner.Total)
+}
+
+func TestTimings_ShortCircuit(t *testing.T) {
+	shortCircuit := func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody, Request: req}, nil
+		})
+	}
+
+	resp := NewDispatcher(nil, Timings(), Named("stub", shortCircuit)).NewRequest().Get("http://example.invalid")
+	defer resp.Close()
+	require.NoError(t, resp.Error)
+
+	timings := resp.Timings()
+	require.Len(t, timings, 1)
+	assert.Equal(t, timings[0].Total, timings[0].Before)
+	assert.Zero(t, timings[0].After)
+}
+
+func TestTimings_Disabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	resp := NewDispatcher(nil, Named("noop", Skip())).NewRequest().Get(server.URL)
+	defer resp.Close()
+
+	require.NoError(t, resp.Error)
+	assert.Nil(t, resp.Timings())
+}
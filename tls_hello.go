@@ -0,0 +1,113 @@
+package fetch
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+)
+
+// TLSClientHello customizes the TLS ClientHello sent to a host.
+// Zero fields keep the transport's settings.
+type TLSClientHello struct {
+	// CipherSuites limits the TLS 1.0-1.2 cipher suites. crypto/tls chooses
+	// their order itself; use Handshake for full control.
+	CipherSuites []uint16
+	// CurvePreferences sets the offered key exchange groups.
+	CurvePreferences []tls.CurveID
+	// NextProtos sets the ALPN protocols, e.g. []string{"http/1.1"}.
+	NextProtos []string
+	// MinVersion and MaxVersion bound the offered TLS versions.
+	MinVersion uint16
+	MaxVersion uint16
+	// Handshake replaces the crypto/tls handshake, e.g. with a uTLS client
+	// that mimics a browser fingerprint. config is the merged configuration
+	// including ServerName. The returned connection should implement
+	// ConnectionState() tls.ConnectionState for HTTP/2 negotiation.
+	Handshake func(ctx context.Context, conn net.Conn, config *tls.Config) (net.Conn, error)
+}
+
+// PerHostTLS returns a client option that performs TLS handshakes itself so
+// the ClientHello can differ per host. hellos is keyed by host name without
+// port; the "*" entry applies to all other hosts, which otherwise use the
+// transport's TLS configuration.
+//
+// Example:
+//
+//	dispatcher.Use(fetch.SetClientOptions(fetch.PerHostTLS(map[string]fetch.TLSClientHello{
+//	    "legacy.example.com": {MaxVersion: tls.VersionTLS12, NextProtos: []string{"http/1.1"}},
+//	    "*":                  {CurvePreferences: []tls.CurveID{tls.X25519}},
+//	})), fetch.PrepareClientMiddleware())
+func PerHostTLS(hellos map[string]TLSClientHello) func(*http.Client) {
+	var transports transportCache
+
+	return func(c *http.Client) {
+		transports.apply(c, func(transport *http.Transport) {
+			dial := transport.DialContext
+			if dial == nil {
+				dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+			}
+
+			transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				host, _, err := net.SplitHostPort(addr)
+				if err != nil {
+					return nil, err
+				}
+
+				conn, err := dial(ctx, network, addr)
+				if err != nil {
+					return nil, err
+				}
+
+				hello, ok := hellos[host]
+				if !ok {
+					hello = hellos["*"]
+				}
+				// Read lazily: the transport adds its HTTP/2 ALPN settings on first use.
+				config := hello.apply(transport.TLSClientConfig, host)
+
+				if hello.Handshake != nil {
+					tlsConn, err := hello.Handshake(ctx, conn, config)
+					if err != nil {
+						conn.Close()
+					}
+					return tlsConn, err
+				}
+
+				tlsConn := tls.Client(conn, config)
+				if err := tlsConn.HandshakeContext(ctx); err != nil {
+					conn.Close()
+					return nil, err
+				}
+				return tlsConn, nil
+			}
+		})
+	}
+}
+
+func (h TLSClientHello) apply(base *tls.Config, host string) *tls.Config {
+	config := &tls.Config{}
+	if base != nil {
+		config = base.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+	if h.CipherSuites != nil {
+		config.CipherSuites = h.CipherSuites
+	}
+	if h.CurvePreferences != nil {
+		config.CurvePreferences = h.CurvePreferences
+	}
+	if h.NextProtos != nil {
+		config.NextProtos = h.NextProtos
+	}
+	if h.MinVersion != 0 {
+		config.MinVersion = h.MinVersion
+	}
+	if h.MaxVersion != 0 {
+		config.MaxVersion = h.MaxVersion
+	}
+	return config
+}
@@ -0,0 +1,78 @@
+package fetch
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPerHostTLS(t *testing.T) {
+	var hello *tls.ClientHelloInfo
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	server.TLS = &tls.Config{GetConfigForClient: func(info *tls.ClientHelloInfo) (*tls.Config, error) {
+		hello = info
+		return nil, nil
+	}}
+	server.StartTLS()
+	defer server.Close()
+
+	var handshakes int
+	tests := []struct {
+		name       string
+		hellos     map[string]TLSClientHello
+		wantProtos []string
+		wantMaxTLS uint16
+		wantCurves []tls.CurveID
+	}{
+		{
+			name:       "exact host",
+			hellos:     map[string]TLSClientHello{"127.0.0.1": {NextProtos: []string{"http/1.1"}, MaxVersion: tls.VersionTLS12}},
+			wantProtos: []string{"http/1.1"},
+			wantMaxTLS: tls.VersionTLS12,
+		},
+		{
+			name:       "wildcard",
+			hellos:     map[string]TLSClientHello{"other": {MaxVersion: tls.VersionTLS12}, "*": {CurvePreferences: []tls.CurveID{tls.CurveP256}}},
+			wantMaxTLS: tls.VersionTLS13,
+			wantCurves: []tls.CurveID{tls.CurveP256},
+		},
+		{
+			name: "custom handshake",
+			hellos: map[string]TLSClientHello{"*": {Handshake: func(ctx context.Context, conn net.Conn, config *tls.Config) (net.Conn, error) {
+				handshakes++
+				config.MaxVersion = tls.VersionTLS12
+				tlsConn := tls.Client(conn, config)
+				return tlsConn, tlsConn.HandshakeContext(ctx)
+			}}},
+			wantMaxTLS: tls.VersionTLS12,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hello = nil
+			resp := NewDispatcher(server.Client(), SetClientOptions(PerHostTLS(tt.hellos)), PrepareClientMiddleware()).
+				NewRequest().Get(server.URL)
+			defer resp.Close()
+
+			require.NoError(t, resp.Error)
+			require.NotNil(t, hello)
+			if tt.wantProtos != nil {
+				assert.Equal(t, tt.wantProtos, hello.SupportedProtos)
+			}
+			assert.Equal(t, tt.wantMaxTLS, hello.SupportedVersions[0])
+			if tt.wantCurves != nil {
+				assert.Equal(t, tt.wantCurves, hello.SupportedCurves)
+			}
+		})
+	}
+	assert.Equal(t, 1, handshakes)
+}
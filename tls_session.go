@@ -0,0 +1,193 @@
+package fetch
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"sync"
+	"time"
+)
+
+// TLSSessionOptions configures TLS session resumption.
+type TLSSessionOptions struct {
+	// CacheSize is the number of sessions kept per host group. Defaults to 64.
+	CacheSize int
+	// HostGroup maps a host to the group whose session cache it shares.
+	// Defaults to one group per host.
+	HostGroup func(host string) string
+	// DisabledHosts never resume sessions, e.g. hosts with strict security
+	// requirements that demand a full handshake on every connection.
+	DisabledHosts []string
+}
+
+// TLSSessionStats holds the handshake statistics of one host.
+type TLSSessionStats struct {
+	Handshakes        uint64        `json:"handshakes"`
+	Resumed           uint64        `json:"resumed"`
+	HandshakeDuration time.Duration `json:"handshake_duration"`
+}
+
+// ResumptionRate returns the fraction of handshakes that resumed a session.
+func (s TLSSessionStats) ResumptionRate() float64 {
+	if s.Handshakes == 0 {
+		return 0
+	}
+	return float64(s.Resumed) / float64(s.Handshakes)
+}
+
+// AverageHandshake returns the mean handshake duration.
+func (s TLSSessionStats) AverageHandshake() time.Duration {
+	if s.Handshakes == 0 {
+		return 0
+	}
+	return s.HandshakeDuration / time.Duration(s.Handshakes)
+}
+
+// TLSSessions shares TLS session caches per host group and records how often
+// handshakes resume a session. It is safe for concurrent use.
+type TLSSessions struct {
+	options    *TLSSessionOptions
+	transports transportCache
+
+	mu     sync.Mutex
+	caches map[string]tls.ClientSessionCache
+	stats  map[string]*TLSSessionStats
+}
+
+// NewTLSSessions creates TLS session caches with the given options.
+//
+// Example:
+//
+//	sessions := fetch.NewTLSSessions(func(o *fetch.TLSSessionOptions) {
+//	    o.DisabledHosts = []string{"payments.example.com"}
+//	})
+//	dispatcher.Use(
+//	    sessions.Middleware(),
+//	    fetch.SetClientOptions(sessions.ClientOption()),
+//	    fetch.PrepareClientMiddleware(),
+//	)
+func NewTLSSessions(opts ...func(*TLSSessionOptions)) *TLSSessions {
+	options := applyOptions(&TLSSessionOptions{
+		CacheSize: 64,
+		HostGroup: func(host string) string { return host },
+	}, opts...)
+
+	return &TLSSessions{
+		options: options,
+		caches:  map[string]tls.ClientSessionCache{},
+		stats:   map[string]*TLSSessionStats{},
+	}
+}
+
+// ClientOption returns a client option that installs the session caches on
+// the client's transport.
+func (s *TLSSessions) ClientOption() func(*http.Client) {
+	return func(c *http.Client) {
+		s.transports.apply(c, func(transport *http.Transport) {
+			config := transport.TLSClientConfig
+			if config == nil {
+				config = &tls.Config{}
+			} else {
+				config = config.Clone()
+			}
+			config.ClientSessionCache = (*groupSessionCache)(s)
+
+			transport.TLSClientConfig = config
+		})
+	}
+}
+
+// Middleware returns middleware that records TLS handshake durations and
+// session resumption per host.
+func (s *TLSSessions) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			host := req.URL.Hostname()
+
+			var start time.Time
+			trace := &httptrace.ClientTrace{
+				TLSHandshakeStart: func() { start = time.Now() },
+				TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+					if err != nil || start.IsZero() {
+						return
+					}
+					s.record(host, time.Since(start), state.DidResume)
+				},
+			}
+
+			req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+			return next.Handle(client, req)
+		})
+	}
+}
+
+// Stats returns a copy of the handshake statistics per host.
+func (s *TLSSessions) Stats() map[string]TLSSessionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make(map[string]TLSSessionStats, len(s.stats))
+	for host, st := range s.stats {
+		stats[host] = *st
+	}
+	return stats
+}
+
+func (s *TLSSessions) record(host string, duration time.Duration, resumed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.stats[host]
+	if st == nil {
+		st = &TLSSessionStats{}
+		s.stats[host] = st
+	}
+	st.Handshakes++
+	st.HandshakeDuration += duration
+	if resumed {
+		st.Resumed++
+	}
+}
+
+// cache returns the session cache of the host's group, or nil when resumption
+// is disabled for the host.
+func (s *TLSSessions) cache(sessionKey string) tls.ClientSessionCache {
+	host := sessionKey
+	if h, _, err := net.SplitHostPort(sessionKey); err == nil {
+		host = h
+	}
+	if slices.Contains(s.options.DisabledHosts, host) {
+		return nil
+	}
+
+	group := s.options.HostGroup(host)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cache := s.caches[group]
+	if cache == nil {
+		cache = tls.NewLRUClientSessionCache(s.options.CacheSize)
+		s.caches[group] = cache
+	}
+	return cache
+}
+
+// groupSessionCache routes sessions to the cache of their host group.
+type groupSessionCache TLSSessions
+
+func (c *groupSessionCache) Get(sessionKey string) (*tls.ClientSessionState, bool) {
+	cache := (*TLSSessions)(c).cache(sessionKey)
+	if cache == nil {
+		return nil, false
+	}
+	return cache.Get(sessionKey)
+}
+
+func (c *groupSessionCache) Put(sessionKey string, cs *tls.ClientSessionState) {
+	if cache := (*TLSSessions)(c).cache(sessionKey); cache != nil {
+		cache.Put(sessionKey, cs)
+	}
+}
@@ -0,0 +1,64 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSSessions(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Close every connection so each request needs a new handshake.
+		w.Header().Set("Connection", "close")
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		disabled    []string
+		wantResumed uint64
+	}{
+		{name: "resumes sessions", wantResumed: 2},
+		{name: "disabled host", disabled: []string{"127.0.0.1"}, wantResumed: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions := NewTLSSessions(func(o *TLSSessionOptions) { o.DisabledHosts = tt.disabled })
+			dispatcher := NewDispatcher(server.Client(),
+				sessions.Middleware(),
+				SetClientOptions(sessions.ClientOption()),
+				PrepareClientMiddleware(),
+			)
+
+			for i := 0; i < 3; i++ {
+				resp := dispatcher.NewRequest().Get(server.URL)
+				require.NoError(t, resp.Error)
+				assert.Equal(t, "ok", resp.String())
+			}
+
+			stats := sessions.Stats()["127.0.0.1"]
+			assert.Equal(t, uint64(3), stats.Handshakes)
+			assert.Equal(t, tt.wantResumed, stats.Resumed)
+			assert.InDelta(t, float64(tt.wantResumed)/3, stats.ResumptionRate(), 0.001)
+			assert.Greater(t, stats.AverageHandshake(), time.Duration(0))
+		})
+	}
+}
+
+func TestTLSSessions_HostGroup(t *testing.T) {
+	sessions := NewTLSSessions(func(o *TLSSessionOptions) {
+		o.HostGroup = func(host string) string { return "shared" }
+	})
+
+	a := sessions.cache("a.example.com:443")
+	b := sessions.cache("b.example.com")
+	assert.NotNil(t, a)
+	assert.Same(t, a, b)
+	assert.Zero(t, TLSSessionStats{}.ResumptionRate())
+}
@@ -0,0 +1,165 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ErrTotalTimeout is matched by errors.Is for every TotalTimeoutError.
+var ErrTotalTimeout = errors.New("total timeout exceeded")
+
+// Phases reported by TotalTimeoutError.
+const (
+	// PhaseMiddleware is the time spent in middlewares before the request
+	// reached the transport, e.g. building the URL or the body.
+	PhaseMiddleware = "middleware"
+	// PhaseNetwork is the time from sending the request until the response
+	// headers arrived, including redirects.
+	PhaseNetwork = "network"
+	// PhaseBody is the time spent reading the response body, including
+	// decompression and decoding.
+	PhaseBody = "body"
+)
+
+// TotalTimeoutError reports a request that exceeded the deadline of
+// TotalTimeout. Phase is the phase that was running when the budget ran out.
+type TotalTimeoutError struct {
+	Limit   time.Duration
+	Phase   string
+	Elapsed time.Duration
+	Err     error
+}
+
+// Error returns the error message.
+func (e *TotalTimeoutError) Error() string {
+	return fmt.Sprintf("total timeout of %s exceeded after %s in %s phase", e.Limit, e.Elapsed.Round(time.Millisecond), e.Phase)
+}
+
+// Is reports whether target is ErrTotalTimeout.
+func (e *TotalTimeoutError) Is(target error) bool {
+	return target == ErrTotalTimeout
+}
+
+// Unwrap returns the underlying error, which matches context.DeadlineExceeded.
+func (e *TotalTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports true, so the error satisfies net.Error-style checks.
+func (e *TotalTimeoutError) Timeout() bool {
+	return true
+}
+
+// TotalTimeout creates middleware enforcing one deadline over the whole
+// exchange: the middlewares after it, the network round trips and reading
+// the response body, so a slow body can no longer outlive the timeout.
+// Reads past the deadline are canceled mid-stream. Exceeding the deadline
+// yields a TotalTimeoutError naming the phase that consumed the budget; add
+// the middleware first so that all middlewares are covered.
+//
+// The deadline is released once the body was read to the end or closed.
+//
+// Example:
+//
+//	var users []User
+//	err := dispatcher.NewRequest().Use(fetch.TotalTimeout(2 * time.Second)).Get(url).JSON(&users)
+//	if errors.Is(err, fetch.ErrTotalTimeout) {
+//	    var timeoutErr *fetch.TotalTimeoutError
+//	    errors.As(err, &timeoutErr)
+//	    log.Printf("timed out while in %s phase", timeoutErr.Phase)
+//	}
+func TotalTimeout(limit time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			parent := req.Context()
+			ctx, cancel := context.WithTimeout(parent, limit)
+			budget := &timeoutBudget{ctx: ctx, parent: parent, limit: limit, start: time.Now()}
+			budget.phase.Store(PhaseMiddleware)
+
+			client.Transport = &phaseTransport{base: client.Transport, phase: &budget.phase}
+			resp, err := next.Handle(client, req.WithContext(ctx))
+			if err != nil {
+				cancel()
+				return resp, budget.wrap(err)
+			}
+
+			budget.phase.Store(PhaseBody)
+			if resp.Body == nil || resp.Body == http.NoBody {
+				cancel()
+				return resp, nil
+			}
+			resp.Body = &deadlineBody{ReadCloser: resp.Body, budget: budget, cancel: cancel}
+			return resp, nil
+		})
+	}
+}
+
+type timeoutBudget struct {
+	ctx    context.Context
+	parent context.Context
+	limit  time.Duration
+	start  time.Time
+	phase  atomic.Value
+}
+
+// wrap converts err into a TotalTimeoutError when this budget's deadline,
+// rather than the caller's context, ended the request.
+func (b *timeoutBudget) wrap(err error) error {
+	if err == nil || b.parent.Err() != nil || !errors.Is(b.ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return &TotalTimeoutError{Limit: b.limit, Phase: b.phase.Load().(string), Elapsed: time.Since(b.start), Err: err}
+}
+
+// phaseTransport marks the start of the network phase.
+type phaseTransport struct {
+	base  http.RoundTripper
+	phase *atomic.Value
+}
+
+func (t *phaseTransport) unwrap() http.RoundTripper { return t.base }
+
+func (t *phaseTransport) rewrap(base http.RoundTripper) http.RoundTripper {
+	clone := *t
+	clone.base = base
+	return &clone
+}
+
+func (t *phaseTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	// A deadline already exceeded was consumed by the middlewares.
+	if req.Context().Err() == nil {
+		t.phase.Store(PhaseNetwork)
+	}
+	return base.RoundTrip(req)
+}
+
+// deadlineBody reports reads canceled by the budget's deadline and releases
+// the deadline once the body is done.
+type deadlineBody struct {
+	io.ReadCloser
+	budget *timeoutBudget
+	cancel context.CancelFunc
+}
+
+func (b *deadlineBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.cancel()
+		return n, err
+	}
+	return n, b.budget.wrap(err)
+}
+
+func (b *deadlineBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
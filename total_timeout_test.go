@@ -0,0 +1,88 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTotalTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow-headers" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		w.Write([]byte(`{"items":[`))
+		w.(http.Flusher).Flush()
+		if r.URL.Path == "/slow-body" {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.Write([]byte(`1]}`))
+	}))
+	defer server.Close()
+
+	slowMiddleware := func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			time.Sleep(100 * time.Millisecond)
+			return next.Handle(client, req)
+		})
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		extra     []Middleware
+		wantPhase string
+	}{
+		{name: "fast", path: "/fast"},
+		{name: "slow middleware", path: "/fast", extra: []Middleware{slowMiddleware}, wantPhase: PhaseMiddleware},
+		{name: "slow headers", path: "/slow-headers", wantPhase: PhaseNetwork},
+		{name: "slow body", path: "/slow-body", wantPhase: PhaseBody},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dispatcher := NewDispatcher(nil, TotalTimeout(50*time.Millisecond))
+
+			var result struct{ Items []int }
+			err := dispatcher.NewRequest().Use(tt.extra...).Get(server.URL + tt.path).JSON(&result)
+			if tt.wantPhase == "" {
+				require.NoError(t, err)
+				assert.Equal(t, []int{1}, result.Items)
+				return
+			}
+
+			require.ErrorIs(t, err, ErrTotalTimeout)
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+			var timeoutErr *TotalTimeoutError
+			require.ErrorAs(t, err, &timeoutErr)
+			assert.Equal(t, tt.wantPhase, timeoutErr.Phase)
+			assert.Equal(t, 50*time.Millisecond, timeoutErr.Limit)
+			assert.Contains(t, timeoutErr.Error(), tt.wantPhase+" phase")
+		})
+	}
+}
+
+func TestTotalTimeout_CallerCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	_, err = NewDispatcher(nil, TotalTimeout(time.Second)).Do(req)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, ErrTotalTimeout)
+}
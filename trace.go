@@ -0,0 +1,115 @@
+package fetch
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/rockcookies/go-fetch/internal/utils"
+)
+
+var traceRecorderKey = utils.NewContextKey[*traceRecorder]("trace_recorder")
+
+// Address families reported by TraceInfo.
+const (
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+// DialAttempt is one connection attempt made by DialFanOut.
+type DialAttempt struct {
+	Addr     string
+	Duration time.Duration
+	Err      error
+}
+
+// TraceInfo describes the connection that served a request.
+type TraceInfo struct {
+	// RemoteAddr is the address the connection was made to.
+	RemoteAddr string
+	// Family is FamilyIPv4 or FamilyIPv6, the address family that won.
+	Family string
+	// Reused reports whether the connection came from the pool.
+	Reused bool
+	// DialAttempts lists the attempts of DialFanOut for new connections of
+	// the request and its redirects in the order they finished. It is empty
+	// for reused connections and clients without DialFanOut.
+	DialAttempts []DialAttempt
+}
+
+type traceRecorder struct {
+	mu   sync.Mutex
+	info TraceInfo
+}
+
+func (r *traceRecorder) addAttempt(attempt DialAttempt) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.info.DialAttempts = append(r.info.DialAttempts, attempt)
+}
+
+func (r *traceRecorder) snapshot() *TraceInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info := r.info
+	info.DialAttempts = append([]DialAttempt(nil), r.info.DialAttempts...)
+	return &info
+}
+
+// Trace creates middleware recording which connection served the request,
+// available from Response.TraceInfo. Combined with DialFanOut it shows every
+// address attempted and whether IPv6 or IPv4 won, e.g. to diagnose hosts
+// whose IPv6 connectivity is broken.
+//
+// Example:
+//
+//	resp := dispatcher.NewRequest().Use(fetch.Trace()).Get(url)
+//	if info := resp.TraceInfo(); info != nil {
+//	    log.Printf("connected over %s to %s after %d attempts", info.Family, info.RemoteAddr, len(info.DialAttempts))
+//	}
+func Trace() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			recorder := &traceRecorder{}
+			trace := &httptrace.ClientTrace{
+				GotConn: func(info httptrace.GotConnInfo) {
+					recorder.mu.Lock()
+					defer recorder.mu.Unlock()
+					recorder.info.Reused = info.Reused
+					if info.Reused {
+						recorder.info.DialAttempts = nil
+					}
+					if addr, ok := info.Conn.RemoteAddr().(*net.TCPAddr); ok {
+						recorder.info.RemoteAddr = addr.String()
+						recorder.info.Family = FamilyIPv6
+						if addr.IP.To4() != nil {
+							recorder.info.Family = FamilyIPv4
+						}
+					} else if addr := info.Conn.RemoteAddr(); addr != nil {
+						recorder.info.RemoteAddr = addr.String()
+					}
+				},
+			}
+
+			ctx := traceRecorderKey.WithValue(req.Context(), recorder)
+			req = req.WithContext(httptrace.WithClientTrace(ctx, trace))
+			return next.Handle(client, req)
+		})
+	}
+}
+
+// TraceInfo returns the connection details recorded by the Trace middleware,
+// or nil if it was not used.
+func (r *Response) TraceInfo() *TraceInfo {
+	if r.RawResponse == nil || r.RawResponse.Request == nil {
+		return nil
+	}
+
+	recorder, ok := traceRecorderKey.GetValue(r.RawResponse.Request.Context())
+	if !ok {
+		return nil
+	}
+	return recorder.snapshot()
+}
@@ -0,0 +1,38 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrace(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	dispatcher := NewDispatcher(nil, Trace())
+
+	first := dispatcher.NewRequest().Get(server.URL)
+	require.NoError(t, first.Error)
+	assert.Equal(t, "ok", first.String())
+	second := dispatcher.NewRequest().Get(server.URL)
+	require.NoError(t, second.Error)
+	assert.Equal(t, "ok", second.String())
+
+	for i, resp := range []*Response{first, second} {
+		info := resp.TraceInfo()
+		require.NotNil(t, info)
+		assert.Equal(t, serverURL.Host, info.RemoteAddr)
+		assert.Equal(t, FamilyIPv4, info.Family)
+		assert.Equal(t, i == 1, info.Reused)
+		assert.Empty(t, info.DialAttempts)
+	}
+}
@@ -0,0 +1,84 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrTransferEncoding is returned, wrapped, when the requested transfer
+// encoding cannot be used for the request.
+var ErrTransferEncoding = errors.New("unsupported transfer encoding")
+
+// TransferEncoding selects how a request body is framed on the wire.
+type TransferEncoding int
+
+const (
+	// TransferEncodingIdentity sends the body with a fixed Content-Length,
+	// buffering bodies of unknown length in memory first.
+	TransferEncodingIdentity TransferEncoding = iota + 1
+	// TransferEncodingChunked streams the body with chunked encoding.
+	TransferEncodingChunked
+)
+
+// String returns the name used in the Transfer-Encoding header.
+func (te TransferEncoding) String() string {
+	switch te {
+	case TransferEncodingIdentity:
+		return "identity"
+	case TransferEncodingChunked:
+		return "chunked"
+	}
+	return fmt.Sprintf("TransferEncoding(%d)", int(te))
+}
+
+// SetTransferEncoding creates middleware that forces the framing of the
+// request body: TransferEncodingIdentity for servers that reject chunked
+// uploads and TransferEncodingChunked for streaming. It fails with an error
+// wrapping ErrTransferEncoding when the choice is impossible, such as chunked
+// encoding for a request without a body or for HTTP/1.0. With HTTP/2, which
+// has no chunked encoding, the body is streamed as data frames either way.
+//
+// The body is inspected when the request reaches the middleware, so place it
+// after the middleware that sets the body.
+func SetTransferEncoding(te TransferEncoding) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			hasBody := (req.Body != nil && req.Body != http.NoBody) || req.GetBody != nil
+
+			switch te {
+			case TransferEncodingIdentity:
+				if hasBody && req.ContentLength <= 0 {
+					body, err := readRequestBody(req)
+					if err != nil {
+						return nil, err
+					}
+					closeReplacedBody(req)
+					setBufferedBody(req, body)
+				}
+				req.TransferEncoding = nil
+
+			case TransferEncodingChunked:
+				if !hasBody {
+					return nil, fmt.Errorf("%w: chunked encoding requires a request body", ErrTransferEncoding)
+				}
+				if req.ProtoMajor == 1 && req.ProtoMinor == 0 {
+					return nil, fmt.Errorf("%w: chunked encoding is not available in HTTP/1.0", ErrTransferEncoding)
+				}
+				req.ContentLength = -1
+				req.TransferEncoding = []string{"chunked"}
+
+			default:
+				return nil, fmt.Errorf("%w: %v", ErrTransferEncoding, te)
+			}
+
+			return next.Handle(client, req)
+		})
+	}
+}
+
+// SetTransferEncoding forces the framing of the request body.
+// Call it after setting the body. See SetTransferEncoding.
+func (r *Request) SetTransferEncoding(te TransferEncoding) *Request {
+	return r.Use(SetTransferEncoding(te))
+}
@@ -0,0 +1,112 @@
+package fetch
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequest_SetTransferEncoding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte(strings.Join(r.TransferEncoding, ",") + "|" + strconv.FormatInt(r.ContentLength, 10) + "|" + string(body)))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		te       TransferEncoding
+		build    func(r *Request) *Request
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "identity buffers streamed body",
+			te:       TransferEncodingIdentity,
+			build:    func(r *Request) *Request { return r.Body(io.MultiReader(strings.NewReader("stream"))) },
+			expected: "|6|stream",
+		},
+		{
+			name:     "identity with lazy body",
+			te:       TransferEncodingIdentity,
+			build:    func(r *Request) *Request { return r.JSON("{}") },
+			expected: "|2|{}",
+		},
+		{
+			name:     "identity without body",
+			te:       TransferEncodingIdentity,
+			build:    func(r *Request) *Request { return r },
+			expected: "|0|",
+		},
+		{
+			name: "chunked with known length",
+			te:   TransferEncodingChunked,
+			build: func(r *Request) *Request {
+				return r.Body(strings.NewReader("sized"), func(o *BodyOptions) { o.AutoSetContentLength = true })
+			},
+			expected: "chunked|-1|sized",
+		},
+		{
+			name:    "chunked without body",
+			te:      TransferEncodingChunked,
+			build:   func(r *Request) *Request { return r },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := tt.build(NewDispatcher(nil).NewRequest()).SetTransferEncoding(tt.te).Post(server.URL)
+			defer resp.Close()
+
+			if tt.wantErr {
+				assert.ErrorIs(t, resp.Error, ErrTransferEncoding)
+				return
+			}
+			require.NoError(t, resp.Error)
+			assert.Equal(t, tt.expected, resp.String())
+		})
+	}
+}
+
+func TestSetTransferEncoding_HTTP10(t *testing.T) {
+	handler := SetTransferEncoding(TransferEncodingChunked)(HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+		return nil, nil
+	}))
+
+	req, err := http.NewRequest(http.MethodPost, "http://example.com", strings.NewReader("x"))
+	require.NoError(t, err)
+	req.ProtoMinor = 0
+
+	_, err = handler.Handle(http.DefaultClient, req)
+	assert.ErrorIs(t, err, ErrTransferEncoding)
+}
+
+func TestSetTransferEncoding_ClosesOriginalBody(t *testing.T) {
+	var gotLength int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotLength = r.ContentLength
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	original := &closeTrackingBody{Reader: strings.NewReader("payload")}
+	req, err := http.NewRequest(http.MethodPost, server.URL, original)
+	require.NoError(t, err)
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("payload")), nil
+	}
+
+	resp, err := NewDispatcher(nil, SetTransferEncoding(TransferEncodingIdentity)).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, int64(len("payload")), gotLength)
+	assert.True(t, original.closed.Load())
+}
@@ -0,0 +1,76 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrTruncatedBody is matched by errors.Is for every TruncatedBodyError.
+var ErrTruncatedBody = errors.New("truncated response body")
+
+// TruncatedBodyError reports a response body that ended early.
+// Expected is -1 when the response had no Content-Length, e.g. a chunked
+// stream that terminated abnormally.
+type TruncatedBodyError struct {
+	Expected int64
+	Actual   int64
+	Err      error
+}
+
+// Error returns the error message.
+func (e *TruncatedBodyError) Error() string {
+	if e.Expected < 0 {
+		return fmt.Sprintf("truncated response body: stream ended abnormally after %d bytes", e.Actual)
+	}
+	return fmt.Sprintf("truncated response body: got %d of %d bytes", e.Actual, e.Expected)
+}
+
+// Is reports whether target is ErrTruncatedBody.
+func (e *TruncatedBodyError) Is(target error) bool {
+	return target == ErrTruncatedBody
+}
+
+// Unwrap returns the underlying read error, if any.
+func (e *TruncatedBodyError) Unwrap() error {
+	return e.Err
+}
+
+// VerifyContentLength creates middleware that checks the response body
+// against the advertised Content-Length while it is read. A body that ends
+// early, or a chunked stream that terminates abnormally, yields a
+// TruncatedBodyError from Read instead of a silent EOF, so decoders and
+// Response helpers fail rather than work on a short body.
+func VerifyContentLength() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			resp, err := next.Handle(client, req)
+			if err != nil || resp.Body == nil || resp.Body == http.NoBody || req.Method == http.MethodHead {
+				return resp, err
+			}
+
+			resp.Body = &lengthCheckingBody{ReadCloser: resp.Body, expected: resp.ContentLength}
+			return resp, nil
+		})
+	}
+}
+
+type lengthCheckingBody struct {
+	io.ReadCloser
+	expected int64
+	read     int64
+}
+
+func (b *lengthCheckingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+
+	switch {
+	case err == io.EOF && b.expected >= 0 && b.read < b.expected:
+		return n, &TruncatedBodyError{Expected: b.expected, Actual: b.read}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return n, &TruncatedBodyError{Expected: b.expected, Actual: b.read, Err: err}
+	}
+	return n, err
+}
@@ -0,0 +1,87 @@
+package fetch
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRawServer serves every connection with the given raw HTTP response and closes it.
+func newRawServer(t *testing.T, raw string) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				http.ReadRequest(bufio.NewReader(conn))
+				io.WriteString(conn, raw)
+			}()
+		}
+	}()
+
+	return "http://" + listener.Addr().String()
+}
+
+func TestVerifyContentLength(t *testing.T) {
+	tests := []struct {
+		name         string
+		raw          string
+		wantBody     string
+		wantExpected int64
+		wantActual   int64
+	}{
+		{
+			name:     "complete body",
+			raw:      "HTTP/1.1 200 OK\r\nContent-Length: 5\r\nConnection: close\r\n\r\nhello",
+			wantBody: "hello",
+		},
+		{
+			name:         "short content length",
+			raw:          "HTTP/1.1 200 OK\r\nContent-Length: 10\r\nConnection: close\r\n\r\nhello",
+			wantExpected: 10,
+			wantActual:   5,
+		},
+		{
+			name:         "aborted chunked stream",
+			raw:          "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\nConnection: close\r\n\r\n5\r\nhello\r\n",
+			wantExpected: -1,
+			wantActual:   5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := newRawServer(t, tt.raw)
+
+			resp := NewDispatcher(nil, VerifyContentLength()).NewRequest().Get(url)
+			require.NoError(t, resp.Error)
+
+			body, err := io.ReadAll(resp)
+			if tt.wantBody != "" {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantBody, string(body))
+				return
+			}
+
+			assert.ErrorIs(t, err, ErrTruncatedBody)
+			var truncated *TruncatedBodyError
+			require.ErrorAs(t, err, &truncated)
+			assert.Equal(t, tt.wantExpected, truncated.Expected)
+			assert.Equal(t, tt.wantActual, truncated.Actual)
+		})
+	}
+}
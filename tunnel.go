@@ -0,0 +1,30 @@
+package fetch
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// ProxyHop is one proxy of a CONNECT tunnel chain.
+// Credentials in the URL user info are sent as Basic Proxy-Authorization;
+// Header adds arbitrary headers to the CONNECT request of this hop.
+// For https:// proxies TLSConfig is used for the connection to the proxy itself.
+type ProxyHop struct {
+	URL       *url.URL
+	Header    http.Header
+	TLSConfig *tls.Config
+}
+
+// TunnelError reports a proxy that refused a CONNECT request.
+type TunnelError struct {
+	Proxy      string
+	Target     string
+	StatusCode int
+}
+
+// Error returns the error message.
+func (e *TunnelError) Error() string {
+	return fmt.Sprintf("proxy %s refused CONNECT to %s: %d %s", e.Proxy, e.Target, e.StatusCode, http.StatusText(e.StatusCode))
+}
@@ -0,0 +1,35 @@
+//go:build js
+
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// DialTunnel is not supported on js/wasm, where requests go through the
+// browser's Fetch API and raw connections cannot be opened.
+// It always returns an error matching errors.ErrUnsupported.
+func DialTunnel(ctx context.Context, dialer *net.Dialer, hops []ProxyHop, addr string) (net.Conn, error) {
+	return nil, errUnsupportedTunnel
+}
+
+// ProxyChain is not supported on js/wasm. Rather than silently bypassing the
+// proxies, the returned option makes every request of the client fail with an
+// error matching errors.ErrUnsupported.
+func ProxyChain(hops ...ProxyHop) func(*http.Client) {
+	return func(c *http.Client) {
+		c.Transport = unsupportedTransport{}
+	}
+}
+
+var errUnsupportedTunnel = fmt.Errorf("proxy tunnels require raw connections: %w", errors.ErrUnsupported)
+
+type unsupportedTransport struct{}
+
+func (unsupportedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, errUnsupportedTunnel
+}
@@ -0,0 +1,163 @@
+//go:build !js
+
+package fetch
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newConnectProxy starts a minimal CONNECT proxy. When auth is not empty the
+// Proxy-Authorization header must equal it.
+func newConnectProxy(t *testing.T, auth string, hits *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if auth != "" && r.Header.Get("Proxy-Authorization") != auth {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		hits.Add(1)
+
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+
+		go func() {
+			defer upstream.Close()
+			defer conn.Close()
+			go io.Copy(upstream, conn)
+			io.Copy(conn, upstream)
+		}()
+	}))
+}
+
+func TestProxyChain(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("through the tunnel"))
+	}))
+	defer target.Close()
+
+	var hitsA, hitsB atomic.Int32
+	proxyA := newConnectProxy(t, "Basic dXNlcjpwYXNz", &hitsA) // user:pass
+	defer proxyA.Close()
+	proxyB := newConnectProxy(t, "", &hitsB)
+	defer proxyB.Close()
+
+	urlA, err := url.Parse(proxyA.URL)
+	require.NoError(t, err)
+	urlA.User = url.UserPassword("user", "pass")
+	urlB, err := url.Parse(proxyB.URL)
+	require.NoError(t, err)
+
+	dispatcher := NewDispatcher(nil, SetClientOptions(ProxyChain(
+		ProxyHop{URL: urlA},
+		ProxyHop{URL: urlB},
+	)), PrepareClientMiddleware())
+
+	resp := dispatcher.NewRequest().Get(target.URL)
+	defer resp.Close()
+
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "through the tunnel", resp.String())
+	assert.Equal(t, int32(1), hitsA.Load())
+	assert.Equal(t, int32(1), hitsB.Load())
+}
+
+func TestDialTunnel_Errors(t *testing.T) {
+	var hits atomic.Int32
+	proxy := newConnectProxy(t, "Basic secret", &hits)
+	defer proxy.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+
+	t.Run("no hops", func(t *testing.T) {
+		_, err := DialTunnel(context.Background(), nil, nil, "example.com:80")
+		assert.Error(t, err)
+	})
+
+	t.Run("proxy refuses", func(t *testing.T) {
+		_, err := DialTunnel(context.Background(), nil, []ProxyHop{{URL: proxyURL}}, "example.com:80")
+		var tunnelErr *TunnelError
+		require.ErrorAs(t, err, &tunnelErr)
+		assert.Equal(t, http.StatusProxyAuthRequired, tunnelErr.StatusCode)
+		assert.Equal(t, "example.com:80", tunnelErr.Target)
+	})
+
+	t.Run("custom hop header", func(t *testing.T) {
+		target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer target.Close()
+
+		hop := ProxyHop{URL: proxyURL, Header: http.Header{"Proxy-Authorization": {"Basic secret"}}}
+		conn, err := DialTunnel(context.Background(), nil, []ProxyHop{hop}, target.Listener.Addr().String())
+		require.NoError(t, err)
+		conn.Close()
+		assert.Equal(t, int32(1), hits.Load())
+	})
+}
+
+func TestHostPort(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+	}{
+		{raw: "http://proxy:3128", expected: "proxy:3128"},
+		{raw: "http://proxy", expected: "proxy:80"},
+		{raw: "https://proxy", expected: "proxy:443"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			u, err := url.Parse(tt.raw)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, hostPort(u))
+		})
+	}
+}
+
+func TestProxyChain_ReusesTunnel(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer target.Close()
+
+	var hits atomic.Int32
+	proxy := newConnectProxy(t, "", &hits)
+	defer proxy.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+
+	dispatcher := NewDispatcher(nil, SetClientOptions(ProxyChain(ProxyHop{URL: proxyURL})), PrepareClientMiddleware())
+	for range 5 {
+		resp := dispatcher.NewRequest().Get(target.URL)
+		require.NoError(t, resp.Error)
+		assert.Equal(t, "ok", resp.String())
+		resp.Close()
+	}
+
+	assert.Equal(t, int32(1), hits.Load())
+}
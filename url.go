@@ -88,8 +88,10 @@ func normalizePath(path string) string {
 	return path
 }
 
+// normalize prefixes uri with http:// unless it has a scheme, such as
+// https:// or the consul:// of a Discovery resolver.
 func normalize(uri string) string {
-	match, _ := regexp.MatchString("^http[s]?://", uri)
+	match, _ := regexp.MatchString("^[a-zA-Z][a-zA-Z0-9+.-]*://", uri)
 	if match {
 		return uri
 	}
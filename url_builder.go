@@ -0,0 +1,178 @@
+package fetch
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// URLBuilder builds URLs from a base and individually escaped path segments
+// and query parameters, replacing URLs assembled with fmt.Sprintf where a
+// value containing "/", "?" or "#" silently changes the target. Methods
+// record the first validation error, which Build returns.
+type URLBuilder struct {
+	base     *url.URL
+	segments []string
+	query    url.Values
+	fragment string
+	err      error
+}
+
+// NewURLBuilder returns an empty URLBuilder.
+//
+// Example:
+//
+//	u, err := fetch.NewURLBuilder().
+//	    Base("https://api.example.com/v1").
+//	    Path("users", userID, "files", fileName).
+//	    Query("expand", "owner").
+//	    Build()
+func NewURLBuilder() *URLBuilder {
+	return &URLBuilder{query: url.Values{}}
+}
+
+// Base sets the absolute http or https URL the path is appended to. Its path,
+// query and fragment are kept.
+func (b *URLBuilder) Base(u string) *URLBuilder {
+	base, err := url.Parse(u)
+	switch {
+	case err != nil:
+		b.fail(err)
+	case base.Scheme != "http" && base.Scheme != "https":
+		b.fail(fmt.Errorf("base %q must be an http or https URL", u))
+	case base.Host == "":
+		b.fail(fmt.Errorf("base %q has no host", u))
+	default:
+		b.base = base
+	}
+	return b
+}
+
+// Path appends segments to the path. Each segment is escaped on its own, so
+// "a/b" stays one segment; empty, "." and ".." segments are rejected.
+func (b *URLBuilder) Path(segments ...string) *URLBuilder {
+	for _, segment := range segments {
+		if segment == "" || segment == "." || segment == ".." {
+			b.fail(fmt.Errorf("invalid path segment %q", segment))
+			return b
+		}
+	}
+	b.segments = append(b.segments, segments...)
+	return b
+}
+
+// Query adds values for the query parameter key.
+func (b *URLBuilder) Query(key string, values ...string) *URLBuilder {
+	if key == "" {
+		b.fail(errors.New("empty query parameter name"))
+		return b
+	}
+	b.query[key] = append(b.query[key], values...)
+	return b
+}
+
+// Fragment sets the fragment.
+func (b *URLBuilder) Fragment(fragment string) *URLBuilder {
+	b.fragment = fragment
+	return b
+}
+
+// Clone returns a copy of b that can be extended independently, e.g. to
+// share a base between requests.
+func (b *URLBuilder) Clone() *URLBuilder {
+	clone := &URLBuilder{
+		segments: slices.Clone(b.segments),
+		query:    url.Values{},
+		fragment: b.fragment,
+		err:      b.err,
+	}
+	if b.base != nil {
+		base := *b.base
+		clone.base = &base
+	}
+	for key, values := range b.query {
+		clone.query[key] = slices.Clone(values)
+	}
+	return clone
+}
+
+// Build returns the URL or the first validation error.
+func (b *URLBuilder) Build() (*url.URL, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if b.base == nil {
+		return nil, errors.New("url builder: missing base URL")
+	}
+
+	u := *b.base
+
+	if len(b.segments) > 0 {
+		var path strings.Builder
+		path.WriteString(strings.TrimSuffix(b.base.EscapedPath(), "/"))
+		for _, segment := range b.segments {
+			path.WriteString("/")
+			path.WriteString(url.PathEscape(segment))
+		}
+		u.RawPath = path.String()
+		unescaped, err := url.PathUnescape(u.RawPath)
+		if err != nil {
+			return nil, fmt.Errorf("url builder: unescape path: %w", err)
+		}
+		u.Path = unescaped
+	}
+
+	if len(b.query) > 0 {
+		query := b.base.Query()
+		for key, values := range b.query {
+			query[key] = append(query[key], values...)
+		}
+		u.RawQuery = query.Encode()
+	}
+
+	if b.fragment != "" {
+		u.Fragment = b.fragment
+		u.RawFragment = ""
+	}
+	return &u, nil
+}
+
+// String returns the built URL, or an empty string when it is invalid.
+func (b *URLBuilder) String() string {
+	u, err := b.Build()
+	if err != nil {
+		return ""
+	}
+	return u.String()
+}
+
+func (b *URLBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = fmt.Errorf("url builder: %w", err)
+	}
+}
+
+// SetURLBuilder sets the request URL from b, replacing the URL passed to
+// Send. A validation error fails the request with an InvalidRequestError.
+//
+// Example:
+//
+//	resp := dispatcher.NewRequest().
+//	    SetURLBuilder(api.Clone().Path("users", userID)).
+//	    Send(http.MethodGet, "")
+func (r *Request) SetURLBuilder(b *URLBuilder) *Request {
+	return r.Use(func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			u, err := b.Build()
+			if err != nil {
+				return nil, &InvalidRequestError{err: err}
+			}
+			req.URL = u
+			req.Host = ""
+			return next.Handle(client, req)
+		})
+	})
+}
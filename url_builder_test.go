@@ -0,0 +1,95 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLBuilder(t *testing.T) {
+	tests := []struct {
+		name     string
+		build    func() *URLBuilder
+		expected string
+		wantErr  string
+	}{
+		{
+			name: "segments escaped individually",
+			build: func() *URLBuilder {
+				return NewURLBuilder().Base("https://api.example.com/v1/").Path("users", "a/b?c#d", "files", "report 2024.pdf")
+			},
+			expected: "https://api.example.com/v1/users/a%2Fb%3Fc%23d/files/report%202024.pdf",
+		},
+		{
+			name: "query merged with base",
+			build: func() *URLBuilder {
+				return NewURLBuilder().Base("https://api.example.com/search?lang=en").Query("q", "a&b=c").Query("tag", "x", "y")
+			},
+			expected: "https://api.example.com/search?lang=en&q=a%26b%3Dc&tag=x&tag=y",
+		},
+		{
+			name: "fragment",
+			build: func() *URLBuilder {
+				return NewURLBuilder().Base("http://example.com").Path("docs").Fragment("section 1")
+			},
+			expected: "http://example.com/docs#section%201",
+		},
+		{
+			name:     "escaped base path kept",
+			build:    func() *URLBuilder { return NewURLBuilder().Base("https://example.com/a%2Fb").Path("c") },
+			expected: "https://example.com/a%2Fb/c",
+		},
+		{name: "missing base", build: func() *URLBuilder { return NewURLBuilder().Path("users") }, wantErr: "missing base URL"},
+		{name: "relative base", build: func() *URLBuilder { return NewURLBuilder().Base("/users") }, wantErr: "must be an http or https URL"},
+		{name: "no host", build: func() *URLBuilder { return NewURLBuilder().Base("https:///users") }, wantErr: "has no host"},
+		{name: "dot dot segment", build: func() *URLBuilder { return NewURLBuilder().Base("https://example.com").Path("..", "admin") }, wantErr: `invalid path segment ".."`},
+		{name: "empty segment", build: func() *URLBuilder { return NewURLBuilder().Base("https://example.com").Path("") }, wantErr: `invalid path segment ""`},
+		{name: "empty query name", build: func() *URLBuilder { return NewURLBuilder().Base("https://example.com").Query("", "x") }, wantErr: "empty query parameter name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := tt.build().Build()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				assert.Empty(t, tt.build().String())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, u.String())
+		})
+	}
+}
+
+func TestURLBuilder_Clone(t *testing.T) {
+	api := NewURLBuilder().Base("https://api.example.com/v1").Query("key", "k")
+
+	users := api.Clone().Path("users").Query("page", "2")
+	teams := api.Clone().Path("teams")
+
+	assert.Equal(t, "https://api.example.com/v1/users?key=k&page=2", users.String())
+	assert.Equal(t, "https://api.example.com/v1/teams?key=k", teams.String())
+	assert.Equal(t, "https://api.example.com/v1?key=k", api.String())
+}
+
+func TestRequest_SetURLBuilder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.EscapedPath() + "?" + r.URL.RawQuery))
+	}))
+	defer server.Close()
+
+	dispatcher := NewDispatcher(nil)
+
+	resp := dispatcher.NewRequest().
+		SetURLBuilder(NewURLBuilder().Base(server.URL).Path("users", "42/../admin").Query("q", "x y")).
+		Send(http.MethodGet, "")
+	require.NoError(t, resp.Error)
+	assert.Equal(t, "/users/42%2F..%2Fadmin?q=x+y", resp.String())
+
+	invalid := dispatcher.NewRequest().SetURLBuilder(NewURLBuilder().Base("ftp://example.com")).Send(http.MethodGet, "")
+	var invalidErr *InvalidRequestError
+	assert.ErrorAs(t, invalid.Error, &invalidErr)
+}
@@ -151,6 +151,11 @@ func TestNormalize(t *testing.T) {
 			uri:      "https://example.com",
 			expected: "https://example.com",
 		},
+		{
+			name:     "discovery scheme",
+			uri:      "consul://payments",
+			expected: "consul://payments",
+		},
 		{
 			name:     "domain without protocol",
 			uri:      "example.com",
@@ -0,0 +1,193 @@
+package fetch
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UsageSnapshot is a point-in-time copy of the accumulated usage.
+// Limit, Remaining and Reset reflect the most recent response that carried
+// rate limit headers; Remaining is -1 until one has been seen.
+type UsageSnapshot struct {
+	Requests     uint64    `json:"requests"`
+	Cost         float64   `json:"cost"`
+	InputTokens  int64     `json:"input_tokens"`
+	OutputTokens int64     `json:"output_tokens"`
+	Limit        int64     `json:"limit"`
+	Remaining    int64     `json:"remaining"`
+	Reset        time.Time `json:"reset"`
+}
+
+// UsageOptions configures a Usage collector. Every header field can be set
+// to "" to ignore that header.
+type UsageOptions struct {
+	// LimitHeader defaults to "X-RateLimit-Limit".
+	LimitHeader string
+	// RemainingHeader defaults to "X-RateLimit-Remaining".
+	RemainingHeader string
+	// ResetHeader holds either seconds until the reset or a Unix timestamp.
+	// Defaults to "X-RateLimit-Reset".
+	ResetHeader string
+	// CostHeader defaults to "X-Request-Cost".
+	CostHeader string
+	// InputTokensHeader defaults to "X-Usage-Input-Tokens".
+	InputTokensHeader string
+	// OutputTokensHeader defaults to "X-Usage-Output-Tokens".
+	OutputTokensHeader string
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+type usageListener struct {
+	threshold int64
+	fn        func(UsageSnapshot)
+	fired     bool
+}
+
+// Usage accumulates request cost, token usage and rate limit quota reported
+// by response headers. It is safe for concurrent use.
+type Usage struct {
+	options   *UsageOptions
+	mu        sync.Mutex
+	snapshot  UsageSnapshot
+	listeners []*usageListener
+}
+
+// NewUsage creates an empty Usage collector.
+func NewUsage(opts ...func(*UsageOptions)) *Usage {
+	options := applyOptions(&UsageOptions{
+		LimitHeader:        "X-RateLimit-Limit",
+		RemainingHeader:    "X-RateLimit-Remaining",
+		ResetHeader:        "X-RateLimit-Reset",
+		CostHeader:         "X-Request-Cost",
+		InputTokensHeader:  "X-Usage-Input-Tokens",
+		OutputTokensHeader: "X-Usage-Output-Tokens",
+		Now:                time.Now,
+	}, opts...)
+
+	u := &Usage{options: options}
+	u.Reset()
+	return u
+}
+
+// Reset clears the accumulated usage and re-arms all listeners.
+func (u *Usage) Reset() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.snapshot = UsageSnapshot{Remaining: -1}
+	for _, l := range u.listeners {
+		l.fired = false
+	}
+}
+
+// Snapshot returns a copy of the usage accumulated since the last Reset.
+func (u *Usage) Snapshot() UsageSnapshot {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.snapshot
+}
+
+// OnRemainingBelow registers fn to be called when the remaining quota drops
+// below threshold. It fires once per crossing and is re-armed when the
+// remaining quota is back at or above threshold, e.g. after the window reset.
+func (u *Usage) OnRemainingBelow(threshold int64, fn func(UsageSnapshot)) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.listeners = append(u.listeners, &usageListener{threshold: threshold, fn: fn})
+}
+
+// Middleware returns middleware that records the usage headers of every response.
+//
+// Example:
+//
+//	usage := fetch.NewUsage()
+//	usage.OnRemainingBelow(100, func(s fetch.UsageSnapshot) {
+//	    log.Printf("only %d requests left until %s", s.Remaining, s.Reset)
+//	})
+//	dispatcher.Use(usage.Middleware())
+func (u *Usage) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			resp, err := next.Handle(client, req)
+			if err != nil {
+				return resp, err
+			}
+
+			for _, fn := range u.record(resp.Header) {
+				fn()
+			}
+			return resp, nil
+		})
+	}
+}
+
+// record accumulates the headers and returns the listener calls to make
+// once the lock is released.
+func (u *Usage) record(header http.Header) []func() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	o := u.options
+	s := &u.snapshot
+	s.Requests++
+	if cost, ok := usageFloat(header, o.CostHeader); ok {
+		s.Cost += cost
+	}
+	if tokens, ok := usageInt(header, o.InputTokensHeader); ok {
+		s.InputTokens += tokens
+	}
+	if tokens, ok := usageInt(header, o.OutputTokensHeader); ok {
+		s.OutputTokens += tokens
+	}
+	if limit, ok := usageInt(header, o.LimitHeader); ok {
+		s.Limit = limit
+	}
+	if reset, ok := usageInt(header, o.ResetHeader); ok {
+		// Values this large cannot be a delay in seconds.
+		if reset > 1_000_000_000 {
+			s.Reset = time.Unix(reset, 0)
+		} else {
+			s.Reset = o.Now().Add(time.Duration(reset) * time.Second)
+		}
+	}
+
+	remaining, ok := usageInt(header, o.RemainingHeader)
+	if !ok {
+		return nil
+	}
+	s.Remaining = remaining
+
+	var calls []func()
+	snapshot := *s
+	for _, l := range u.listeners {
+		switch {
+		case remaining >= l.threshold:
+			l.fired = false
+		case !l.fired:
+			l.fired = true
+			fn := l.fn
+			calls = append(calls, func() { fn(snapshot) })
+		}
+	}
+	return calls
+}
+
+func usageInt(header http.Header, name string) (int64, bool) {
+	if name == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(header.Get(name)), 10, 64)
+	return n, err == nil
+}
+
+func usageFloat(header http.Header, name string) (float64, bool) {
+	if name == "" {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(header.Get(name)), 64)
+	return f, err == nil
+}
@@ -0,0 +1,86 @@
+package fetch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsage(t *testing.T) {
+	responses := []http.Header{
+		{"X-Ratelimit-Limit": {"100"}, "X-Ratelimit-Remaining": {"60"}, "X-Ratelimit-Reset": {"30"}, "X-Request-Cost": {"1.5"}},
+		{"X-Ratelimit-Remaining": {"40"}, "X-Usage-Input-Tokens": {"120"}, "X-Usage-Output-Tokens": {"80"}},
+		{"X-Ratelimit-Remaining": {"5"}, "X-Request-Cost": {"0.25"}},
+		{"X-Ratelimit-Remaining": {"3"}, "X-Ratelimit-Reset": {"1700000000"}},
+		{"X-Ratelimit-Remaining": {"100"}},
+		{"X-Ratelimit-Remaining": {"30"}},
+	}
+
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, values := range responses[calls] {
+			w.Header()[name] = values
+		}
+		calls++
+	}))
+	defer server.Close()
+
+	now := time.Unix(1000, 0)
+	usage := NewUsage(func(o *UsageOptions) { o.Now = func() time.Time { return now } })
+
+	var below50, below10 []int64
+	usage.OnRemainingBelow(50, func(s UsageSnapshot) { below50 = append(below50, s.Remaining) })
+	usage.OnRemainingBelow(10, func(s UsageSnapshot) { below10 = append(below10, s.Remaining) })
+
+	dispatcher := NewDispatcher(nil, usage.Middleware())
+
+	tests := []struct {
+		name  string
+		check func(t *testing.T, s UsageSnapshot)
+	}{
+		{name: "rate limit headers", check: func(t *testing.T, s UsageSnapshot) {
+			assert.Equal(t, int64(100), s.Limit)
+			assert.Equal(t, int64(60), s.Remaining)
+			assert.Equal(t, now.Add(30*time.Second), s.Reset)
+			assert.Empty(t, below50)
+		}},
+		{name: "token headers", check: func(t *testing.T, s UsageSnapshot) {
+			assert.Equal(t, int64(120), s.InputTokens)
+			assert.Equal(t, int64(80), s.OutputTokens)
+			assert.Equal(t, []int64{40}, below50)
+		}},
+		{name: "cost accumulates", check: func(t *testing.T, s UsageSnapshot) {
+			assert.Equal(t, 1.75, s.Cost)
+			assert.Equal(t, []int64{40}, below50)
+			assert.Equal(t, []int64{5}, below10)
+		}},
+		{name: "unix reset", check: func(t *testing.T, s UsageSnapshot) {
+			assert.Equal(t, time.Unix(1700000000, 0), s.Reset)
+			assert.Equal(t, []int64{5}, below10)
+		}},
+		{name: "listeners re-arm", check: func(t *testing.T, s UsageSnapshot) {
+			assert.Equal(t, int64(100), s.Remaining)
+		}},
+		{name: "fires again after re-arm", check: func(t *testing.T, s UsageSnapshot) {
+			assert.Equal(t, uint64(6), s.Requests)
+			assert.Equal(t, []int64{40, 30}, below50)
+			assert.Equal(t, []int64{5}, below10)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := dispatcher.NewRequest().Get(server.URL)
+			defer resp.Close()
+			require.NoError(t, resp.Error)
+			tt.check(t, usage.Snapshot())
+		})
+	}
+
+	usage.Reset()
+	assert.Equal(t, UsageSnapshot{Remaining: -1}, usage.Snapshot())
+}
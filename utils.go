@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"slices"
+	"sync"
 
 	"github.com/rockcookies/go-fetch/internal/utils"
 )
@@ -72,3 +73,93 @@ func compose(middlewares ...Middleware) Middleware {
 		return handler
 	}
 }
+
+// maxCachedTransports bounds the transports a transportCache keeps; the
+// oldest is evicted and its idle connections closed.
+const maxCachedTransports = 16
+
+// transportWrapper is implemented by transports that wrap another one, such
+// as those installed by Retry or Hedge, so client options can configure the
+// transport at the bottom of the chain without dropping the wrappers.
+type transportWrapper interface {
+	http.RoundTripper
+	// unwrap returns the wrapped transport.
+	unwrap() http.RoundTripper
+	// rewrap returns a copy of the wrapper wrapping base instead.
+	rewrap(base http.RoundTripper) http.RoundTripper
+}
+
+// transportCache derives a transport once per base transport. Client options
+// run for every request on a copy of the client, so cloning the transport
+// each time would discard its pool of idle connections.
+type transportCache struct {
+	mu         sync.Mutex
+	transports map[*http.Transport]*http.Transport
+	derived    map[*http.Transport]bool
+	order      []*http.Transport
+}
+
+// apply configures the *http.Transport of c with build, which runs once per
+// base. Wrappers such as Retry are kept in front of the derived transport and
+// a nil transport stands for http.DefaultTransport. Other transports, e.g. a
+// MockTransport, do not dial and are left unchanged.
+func (tc *transportCache) apply(c *http.Client, build func(transport *http.Transport)) {
+	var wrappers []transportWrapper
+	rt := c.Transport
+	for {
+		wrapper, ok := rt.(transportWrapper)
+		if !ok {
+			break
+		}
+		wrappers = append(wrappers, wrapper)
+		rt = wrapper.unwrap()
+	}
+
+	var base *http.Transport
+	switch t := rt.(type) {
+	case nil:
+		base = http.DefaultTransport.(*http.Transport)
+	case *http.Transport:
+		base = t
+	default:
+		return
+	}
+
+	rt = tc.derive(base, build)
+	for i := len(wrappers) - 1; i >= 0; i-- {
+		rt = wrappers[i].rewrap(rt)
+	}
+	c.Transport = rt
+}
+
+// derive returns the transport derived from base, building it on first use.
+func (tc *transportCache) derive(base *http.Transport, build func(transport *http.Transport)) *http.Transport {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	if tc.derived[base] {
+		return base
+	}
+	if transport, ok := tc.transports[base]; ok {
+		return transport
+	}
+
+	if tc.transports == nil {
+		tc.transports = map[*http.Transport]*http.Transport{}
+		tc.derived = map[*http.Transport]bool{}
+	}
+	if len(tc.order) >= maxCachedTransports {
+		oldest := tc.transports[tc.order[0]]
+		delete(tc.transports, tc.order[0])
+		delete(tc.derived, oldest)
+		tc.order = tc.order[1:]
+		oldest.CloseIdleConnections()
+	}
+
+	transport := base.Clone()
+	build(transport)
+	tc.transports[base] = transport
+	tc.derived[transport] = true
+	tc.order = append(tc.order, base)
+	return transport
+}
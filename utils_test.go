@@ -1,9 +1,15 @@
 package fetch
 
 import (
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestComposeOrder(t *testing.T) {
@@ -79,3 +85,124 @@ func TestComposeOrder(t *testing.T) {
 
 	t.Logf("Execution order: %v", executionOrder)
 }
+
+// newConnCountingServer starts a server counting the connections it accepts.
+func newConnCountingServer(t *testing.T, conns *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClientOptions_ReuseConnections(t *testing.T) {
+	tests := []struct {
+		name   string
+		option func(*http.Client)
+	}{
+		{
+			name: "SetPAC",
+			option: SetPAC(PACEvaluatorFunc(func(u *url.URL, host string) (string, error) {
+				return "DIRECT", nil
+			})),
+		},
+		{name: "TLSSessions", option: NewTLSSessions().ClientOption()},
+		{name: "PerHostTLS", option: PerHostTLS(map[string]TLSClientHello{"*": {}})},
+		{name: "ResolveDNS", option: ResolveDNS()},
+		{name: "DialFanOut", option: DialFanOut()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var conns atomic.Int32
+			server := newConnCountingServer(t, &conns)
+
+			client := &http.Client{Transport: &http.Transport{}}
+			dispatcher := NewDispatcher(client, SetClientOptions(tt.option), PrepareClientMiddleware())
+			for range 5 {
+				resp := dispatcher.NewRequest().Get(server.URL)
+				require.NoError(t, resp.Error)
+				assert.Equal(t, "ok", resp.String())
+				resp.Close()
+			}
+
+			assert.Equal(t, int32(1), conns.Load())
+		})
+	}
+}
+
+func TestClientOptions_KeepWrappers(t *testing.T) {
+	tests := []struct {
+		name   string
+		option func(*http.Client)
+	}{
+		{
+			name: "SetPAC",
+			option: SetPAC(PACEvaluatorFunc(func(u *url.URL, host string) (string, error) {
+				return "DIRECT", nil
+			})),
+		},
+		{name: "TLSSessions", option: NewTLSSessions().ClientOption()},
+		{name: "PerHostTLS", option: PerHostTLS(map[string]TLSClientHello{"*": {}})},
+		{name: "ResolveDNS", option: ResolveDNS()},
+		{name: "DialFanOut", option: DialFanOut()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer server.Close()
+
+			dispatcher := NewDispatcher(nil)
+			dispatcher.Use(Retry(func(o *RetryOptions) {
+				o.Count = 2
+				o.Backoff = ConstantBackoff(0)
+			}))
+			dispatcher.Use(SetClientOptions(tt.option), PrepareClientMiddleware())
+
+			resp := dispatcher.NewRequest().Get(server.URL)
+			require.NoError(t, resp.Error)
+			resp.Close()
+
+			assert.Equal(t, http.StatusInternalServerError, resp.RawResponse.StatusCode)
+			assert.Equal(t, int32(3), requests.Load(), "retries should survive the client option")
+		})
+	}
+}
+
+func TestClientOptions_KeepMockTransport(t *testing.T) {
+	mock := transportFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusTeapot, Body: http.NoBody, Request: req}, nil
+	})
+	dispatcher := NewDispatcher(&http.Client{Transport: mock}, SetClientOptions(ResolveDNS()), PrepareClientMiddleware())
+
+	resp := dispatcher.NewRequest().Get("http://unreachable.invalid/")
+	require.NoError(t, resp.Error)
+	defer resp.Close()
+	assert.Equal(t, http.StatusTeapot, resp.RawResponse.StatusCode)
+}
+
+func TestTransportCache_Bounded(t *testing.T) {
+	var cache transportCache
+	first := &http.Transport{}
+	cache.apply(&http.Client{Transport: first}, func(*http.Transport) {})
+	for range maxCachedTransports {
+		cache.apply(&http.Client{Transport: &http.Transport{}}, func(*http.Transport) {})
+	}
+
+	assert.Len(t, cache.transports, maxCachedTransports)
+	assert.Len(t, cache.derived, maxCachedTransports)
+	assert.NotContains(t, cache.transports, first, "oldest base should be evicted")
+}
@@ -0,0 +1,128 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/rockcookies/go-fetch/internal/utils"
+)
+
+// errValidated stops the middleware chain once Validate reached the transport.
+var errValidated = errors.New("fetch: request validated")
+
+var dryRunKey = utils.NewContextKey[bool]("dry_run")
+
+var unresolvedParamRegexp = regexp.MustCompile(`\{[^{}/]+\}`)
+
+// Validate runs the full request-building pipeline for the given method and
+// URL without sending anything and returns every problem found, joined with
+// errors.Join, or nil when the request is ready to send.
+//
+// All dispatcher and request middlewares run up to the point where the
+// request would be handed to the client, so base URLs, path parameters,
+// headers and bodies are built exactly as Send would build them. The result
+// is then checked for a non-absolute URL, unresolved {param} placeholders,
+// a body that fails to encode, and invalid header names or values.
+//
+// The request is marked as a dry run, see IsDryRun. Middlewares with side
+// effects, such as CircuitBreaker, Reauth, Stats or Preflight, pass it
+// through without recording an outcome or sending requests of their own.
+//
+// Example:
+//
+//	func TestCreateUserRequest(t *testing.T) {
+//	    req := client.NewRequest().JSON(user)
+//	    require.NoError(t, req.Validate(http.MethodPost, "/users/{id}"))
+//	}
+func (r *Request) Validate(method string, u string) error {
+	req, err := newHTTPRequest(method, u)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(dryRunKey.WithValue(req.Context(), true))
+
+	var problems []error
+	check := func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			problems = validateHTTPRequest(req)
+			return nil, errValidated
+		})
+	}
+
+	_, err = r.dispatcher.Do(req, append(r.middlewares[:len(r.middlewares):len(r.middlewares)], check)...)
+	if err != nil && !errors.Is(err, errValidated) {
+		problems = append([]error{err}, problems...)
+	}
+
+	return errors.Join(problems...)
+}
+
+// IsDryRun reports whether ctx belongs to a request built by Request.Validate,
+// which is never sent. Middlewares with side effects, such as recording the
+// outcome of requests or sending requests of their own, skip them for such
+// requests.
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := dryRunKey.GetValue(ctx)
+	return dryRun
+}
+
+// bypassDryRun makes requests built by Validate skip middleware.
+func bypassDryRun(middleware Middleware) Middleware {
+	return func(next Handler) Handler {
+		handler := middleware(next)
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			if IsDryRun(req.Context()) {
+				return next.Handle(client, req)
+			}
+			return handler.Handle(client, req)
+		})
+	}
+}
+
+func validateHTTPRequest(req *http.Request) []error {
+	var problems []error
+
+	if !req.URL.IsAbs() || req.URL.Host == "" {
+		problems = append(problems, fmt.Errorf("url %q is not absolute", req.URL.String()))
+	}
+	for _, param := range unresolvedParamRegexp.FindAllString(req.URL.Path, -1) {
+		problems = append(problems, fmt.Errorf("url path parameter %s is not set", param))
+	}
+
+	if _, err := readRequestBody(req); err != nil {
+		problems = append(problems, fmt.Errorf("request body: %w", err))
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(req.Header)) {
+		values := req.Header[name]
+		if !validHeaderName(name) {
+			problems = append(problems, fmt.Errorf("invalid header name %q", name))
+			continue
+		}
+		for _, value := range values {
+			if strings.ContainsAny(value, "\r\n\x00") {
+				problems = append(problems, fmt.Errorf("invalid value for header %q", name))
+			}
+		}
+	}
+
+	return problems
+}
+
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if c <= ' ' || c >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, c) {
+			return false
+		}
+	}
+	return true
+}
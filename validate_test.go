@@ -0,0 +1,113 @@
+package fetch
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		build    func(r *Request) *Request
+		url      string
+		wantErrs []string
+	}{
+		{
+			name:  "valid request",
+			build: func(r *Request) *Request { return r.JSON(map[string]string{"a": "b"}) },
+			url:   "http://api.example.com/users",
+		},
+		{
+			name: "base url and path params",
+			build: func(r *Request) *Request {
+				return r.Use(SetURLOptions(func(o *URLOptions) {
+					o.BaseURL = "http://api.example.com"
+					o.PathParams["id"] = "42"
+				}), PrepareURLMiddleware())
+			},
+			url: "/users/{id}",
+		},
+		{
+			name:     "relative url and missing param",
+			build:    func(r *Request) *Request { return r },
+			url:      "/users/{id}/posts/{post}",
+			wantErrs: []string{`url "/users/%7Bid%7D/posts/%7Bpost%7D" is not absolute`, "url path parameter {id} is not set", "url path parameter {post} is not set"},
+		},
+		{
+			name:     "body encoding fails",
+			build:    func(r *Request) *Request { return r.JSON(make(chan int)) },
+			url:      "http://api.example.com",
+			wantErrs: []string{"json: unsupported type: chan int"},
+		},
+		{
+			name: "invalid headers",
+			build: func(r *Request) *Request {
+				return r.UseFuncs(func(req *http.Request) {
+					req.Header["Bad Name"] = []string{"x"}
+					req.Header["X-Inject"] = []string{"a\r\nb"}
+				})
+			},
+			url:      "http://api.example.com",
+			wantErrs: []string{`invalid header name "Bad Name"`, `invalid value for header "X-Inject"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.build(NewDispatcher(nil).NewRequest()).Validate(http.MethodPost, tt.url)
+
+			if len(tt.wantErrs) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			for _, want := range tt.wantErrs {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
+
+func TestRequestValidate_DoesNotSend(t *testing.T) {
+	var sent atomic.Bool
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		sent.Store(true)
+		return nil
+	}}
+
+	req := NewDispatcher(client).NewRequest()
+	require.NoError(t, req.Validate(http.MethodGet, "http://127.0.0.1:1/"))
+	assert.False(t, sent.Load())
+
+	// Validate must not leave its checking middleware on the request.
+	resp := req.Get("http://127.0.0.1:1/")
+	assert.Error(t, resp.Error)
+	assert.NotErrorIs(t, resp.Error, errValidated)
+}
+
+func TestRequestValidate_SkipsSideEffects(t *testing.T) {
+	breaker := NewCircuitBreaker()
+	stats := NewStats()
+	var observed atomic.Int32
+	observe := func(next Handler) Handler {
+		return HandlerFunc(func(client *http.Client, req *http.Request) (*http.Response, error) {
+			if !IsDryRun(req.Context()) {
+				observed.Add(1)
+			}
+			return next.Handle(client, req)
+		})
+	}
+
+	dispatcher := NewDispatcher(nil, breaker.Middleware(), stats.Middleware(), observe)
+	for range 10 {
+		require.NoError(t, dispatcher.NewRequest().Validate(http.MethodGet, "http://example.com/users"))
+	}
+
+	assert.Equal(t, CircuitClosed, breaker.State("example.com"))
+	assert.Zero(t, stats.Snapshot().Requests)
+	assert.Zero(t, observed.Load())
+}
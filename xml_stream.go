@@ -0,0 +1,63 @@
+package fetch
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// XMLStream returns an XML decoder reading directly from the response body,
+// so large documents can be processed token by token. The caller must Close
+// the response when done.
+func (r *Response) XMLStream() (*xml.Decoder, error) {
+	if r.Error != nil {
+		return nil, r.Error
+	}
+	return xml.NewDecoder(r.getInternalReader()), nil
+}
+
+// ForEachElement streams the response body and calls fn for every element
+// with the given local name, at any depth. fn should consume the element,
+// typically with decoder.DecodeElement(&v, &start) or decoder.Skip();
+// otherwise its children are scanned as well. Returning an error from fn
+// stops the iteration and returns that error. The response is closed.
+//
+// Example:
+//
+//	err := resp.ForEachElement("url", func(d *xml.Decoder, start xml.StartElement) error {
+//	    var entry struct {
+//	        Loc string `xml:"loc"`
+//	    }
+//	    if err := d.DecodeElement(&entry, &start); err != nil {
+//	        return err
+//	    }
+//	    return index(entry.Loc)
+//	})
+func (r *Response) ForEachElement(name string, fn func(*xml.Decoder, xml.StartElement) error) error {
+	decoder, err := r.XMLStream()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return ForEachElement(decoder, name, fn)
+}
+
+// ForEachElement calls fn for every element with the given local name read
+// from decoder. See Response.ForEachElement.
+func ForEachElement(decoder *xml.Decoder, name string, fn func(*xml.Decoder, xml.StartElement) error) error {
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == name {
+			if err := fn(decoder, start); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package fetch
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseForEachElement(t *testing.T) {
+	const sitemap = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/a</loc></url>
+  <url><loc>https://example.com/b</loc></url>
+  <group><url><loc>https://example.com/c</loc></url></group>
+</urlset>`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(sitemap))
+	}))
+	defer server.Close()
+
+	errStop := errors.New("stop")
+
+	tests := []struct {
+		name     string
+		element  string
+		stopAt   int
+		expected []string
+		wantErr  error
+	}{
+		{name: "all elements at any depth", element: "url", expected: []string{"https://example.com/a", "https://example.com/b", "https://example.com/c"}},
+		{name: "callback error stops", element: "url", stopAt: 2, expected: []string{"https://example.com/a", "https://example.com/b"}, wantErr: errStop},
+		{name: "no matches", element: "item"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := NewDispatcher(nil).NewRequest().Get(server.URL)
+			require.NoError(t, resp.Error)
+
+			var locs []string
+			err := resp.ForEachElement(tt.element, func(d *xml.Decoder, start xml.StartElement) error {
+				var entry struct {
+					Loc string `xml:"loc"`
+				}
+				if err := d.DecodeElement(&entry, &start); err != nil {
+					return err
+				}
+				locs = append(locs, entry.Loc)
+				if len(locs) == tt.stopAt {
+					return errStop
+				}
+				return nil
+			})
+
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expected, locs)
+		})
+	}
+}
+
+func TestResponseXMLStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<root><a/></root>`))
+	}))
+	defer server.Close()
+
+	resp := NewDispatcher(nil).NewRequest().Get(server.URL)
+	defer resp.Close()
+
+	decoder, err := resp.XMLStream()
+	require.NoError(t, err)
+
+	token, err := decoder.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "root", token.(xml.StartElement).Name.Local)
+
+	_, err = (&Response{Error: errors.New("boom")}).XMLStream()
+	assert.Error(t, err)
+}